@@ -23,7 +23,16 @@
 //   - DELETE /v1/share/common/auth/tokens - Revoke token (logout)
 //   - GET /v1/share/common/auth/tokens/validate - Validate token
 //   - POST /v1/share/common/auth/tokens/refresh - Refresh token
+//   - POST /v1/share/common/auth/tokens/mfa - Complete login with a TOTP or recovery code
 //   - GET /v1/share/common/auth/tokens/user - Get user info from token
+//   - GET /v1/share/common/auth/.well-known/jwks.json - Public signing keys (JWKS)
+//   - POST /v1/share/common/auth/oauth/token - Service account token (client_credentials grant)
+//
+// When oidc.enabled is set, Locky also acts as an OpenID Connect provider:
+//   - GET /.well-known/openid-configuration - Provider metadata
+//   - GET|POST /v1/share/common/oidc/authorize - Login / consent (authorization code + PKCE)
+//   - POST /v1/share/common/oidc/token - Code and refresh token exchange
+//   - GET|POST /v1/share/common/oidc/userinfo - Claims of the access token owner
 //
 // # Configuration
 //
@@ -74,6 +74,36 @@ func ClientForAdminUser(conf config.BaseConfig) {
 	baseCmdForAdminUser.Create.AddCommand(controller.InitCreateRoleCmdForAdmin(conf))
 	baseCmdForAdminUser.Update.AddCommand(controller.InitUpdateRoleCmdForAdmin(conf))
 	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteRoleCmdForAdmin(conf))
+	baseCmdForAdminUser.Get.AddCommand(controller.InitGetRoleHierarchyCmdForAdmin(conf))
+	baseCmdForAdminUser.Create.AddCommand(controller.InitCreateRoleParentCmdForAdmin(conf))
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteRoleParentCmdForAdmin(conf))
+
+	// user-role: global roles granted to users
+	baseCmdForAdminUser.Get.AddCommand(controller.InitGetUserRoleCmdForAdminUser(conf))
+	baseCmdForAdminUser.Create.AddCommand(controller.InitCreateUserRoleCmdForAdminUser(conf))
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteUserRoleCmdForAdminUser(conf))
+
+	// policy-status: Casbin policy revision loaded by a server node
+	baseCmdForAdminUser.Get.AddCommand(controller.InitGetPolicyStatusCmdForAdminUser(conf))
+
+	// session: login sessions of any user
+	baseCmdForAdminUser.Get.AddCommand(controller.InitGetSessionCmdForAdminUser(conf))
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteSessionCmdForAdminUser(conf))
+
+	// mfa: reset a user's second factor (e.g. lost device)
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteMFACmdForAdminUser(conf))
+
+	// lockout: accounts and client IPs blocked after failed logins
+	baseCmdForAdminUser.Get.AddCommand(controller.InitGetLoginLockoutCmdForAdminUser(conf))
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteLoginLockoutCmdForAdminUser(conf))
+
+	// service-account: non-human principals for the client_credentials grant
+	baseCmdForAdminUser.Get.AddCommand(controller.InitGetServiceAccountCmdForAdminUser(conf))
+	baseCmdForAdminUser.Create.AddCommand(controller.InitCreateServiceAccountCmdForAdminUser(conf))
+	baseCmdForAdminUser.Update.AddCommand(controller.InitUpdateServiceAccountCmdForAdminUser(conf))
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteServiceAccountCmdForAdminUser(conf))
+	baseCmdForAdminUser.Create.AddCommand(controller.InitCreateServiceAccountSecretCmdForAdminUser(conf))
+	baseCmdForAdminUser.Delete.AddCommand(controller.InitDeleteServiceAccountSecretCmdForAdminUser(conf))
 
 	//bootstrap
 	bootstrapUserCmdForAdminUser := controller.InitBootstrapUserCmdForAdminUser(conf)
@@ -82,6 +112,10 @@ func ClientForAdminUser(conf config.BaseConfig) {
 	baseCmdForAdminUser.Bootstrap.AddCommand(bootstrapGroupCmdForAdminUser)
 	bootstrapMemberCmdForAdminUser := controller.InitBootstrapMemberCmdForAdminUser(conf)
 	baseCmdForAdminUser.Bootstrap.AddCommand(bootstrapMemberCmdForAdminUser)
+	baseCmdForAdminUser.Bootstrap.AddCommand(controller.InitBootstrapServiceAccountCmdForAdminUser(conf))
+	baseCmdForAdminUser.Bootstrap.AddCommand(controller.InitBootstrapPersonalAccessTokenCmdForAdminUser(conf))
+	baseCmdForAdminUser.Bootstrap.AddCommand(controller.InitBootstrapMFACmdForAdminUser(conf))
+	baseCmdForAdminUser.Bootstrap.AddCommand(controller.InitBootstrapPolicyCmdForAdminUser(conf))
 	rootCmdForAdminUser.AddCommand(baseCmdForAdminUser.Bootstrap)
 
 	//create
@@ -71,6 +71,10 @@ func ClientForAnonymousUser(conf config.BaseConfig) {
 	baseCmdForAnonymousUser.Common.AddCommand(refreshCmd)
 	logoutCmd := controller.InitCommonLogoutCmd(conf)
 	baseCmdForAnonymousUser.Common.AddCommand(logoutCmd)
+	baseCmdForAnonymousUser.Common.AddCommand(controller.InitCommonForgotPasswordCmd(conf))
+	baseCmdForAnonymousUser.Common.AddCommand(controller.InitCommonResetPasswordCmd(conf))
+	baseCmdForAnonymousUser.Common.AddCommand(controller.InitCommonVerifyEmailCmd(conf))
+	baseCmdForAnonymousUser.Common.AddCommand(controller.InitCommonResendVerificationCmd(conf))
 	rootCmdForAnonymousUser.AddCommand(baseCmdForAnonymousUser.Common)
 
 	rootCmdForAnonymousUser.Execute()
@@ -64,6 +64,21 @@ func ClientForAppUser(conf config.BaseConfig) {
 
 	// role (read-only) under get command
 	baseCmdForAppUser.Get.AddCommand(controller.InitGetRoleCmdForApp(conf))
+	baseCmdForAppUser.Get.AddCommand(controller.InitGetRoleHierarchyCmdForApp(conf))
+
+	// session: own login sessions
+	baseCmdForAppUser.Get.AddCommand(controller.InitGetSessionCmdForAppUser(conf))
+	baseCmdForAppUser.Delete.AddCommand(controller.InitDeleteSessionCmdForAppUser(conf))
+
+	// token: personal access tokens for scripts and CI
+	baseCmdForAppUser.Get.AddCommand(controller.InitGetTokenCmdForAppUser(conf))
+	baseCmdForAppUser.Create.AddCommand(controller.InitCreateTokenCmdForAppUser(conf))
+	baseCmdForAppUser.Delete.AddCommand(controller.InitDeleteTokenCmdForAppUser(conf))
+
+	// mfa: TOTP enrollment for the own account
+	baseCmdForAppUser.Get.AddCommand(controller.InitGetMFACmdForAppUser(conf))
+	baseCmdForAppUser.Create.AddCommand(controller.InitCreateMFACmdForAppUser(conf))
+	baseCmdForAppUser.Delete.AddCommand(controller.InitDeleteMFACmdForAppUser(conf))
 
 	//create
 	createGroupCmdForAppUser := controller.InitCreateGroupCmdForAppUser(conf)
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bufio"
 	"fmt"
 	"log"
 	"os"
@@ -105,6 +106,21 @@ func InitCommonLoginCmd(conf config.BaseConfig) *cobra.Command {
 				Password: password,
 			})
 
+			// Second step for users with MFA enabled
+			if loginResponse.Code == "MFA_REQUIRED" {
+				code, err := cmd.Flags().GetString("code")
+				if err != nil {
+					log.Fatal(err)
+				}
+				if code == "" {
+					code = promptMFACode()
+				}
+				loginResponse = uc.VerifyMFA(request.MFALoginRequest{
+					MFAToken: loginResponse.MFAToken,
+					Code:     code,
+				})
+			}
+
 			// Save tokens to environment variables or files for later use
 			if loginResponse.TokenPair != nil {
 				os.Setenv("LOCKY_ACCESS_TOKEN", loginResponse.TokenPair.AccessToken)
@@ -121,11 +137,20 @@ func InitCommonLoginCmd(conf config.BaseConfig) *cobra.Command {
 	}
 	loginCmd.Flags().StringP("email", "e", "", "user email")
 	loginCmd.Flags().StringP("password", "p", "", "user password")
+	loginCmd.Flags().String("code", "", "authentication code or recovery code (prompted when MFA is enabled)")
 	loginCmd.MarkFlagRequired("email")
 	loginCmd.MarkFlagRequired("password")
 	return loginCmd
 }
 
+// promptMFACode reads the authentication code from stdin; the prompt goes to
+// stderr so that formatted output on stdout stays clean
+func promptMFACode() string {
+	fmt.Fprint(os.Stderr, "Authentication code: ")
+	code, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(code)
+}
+
 // InitRefreshTokenCmd creates a refresh token command
 func InitCommonRefreshTokenCmd(conf config.BaseConfig) *cobra.Command {
 	uc := usecase.NewCommonUsecase(conf)
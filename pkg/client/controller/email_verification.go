@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+// Anonymous user: confirm the email address with the token from the verification email
+func InitCommonVerifyEmailCmd(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewEmailVerificationUsecase(conf)
+	var token string
+	cmd := &cobra.Command{Use: "verify-email", Short: "Verify an email address with a verification token", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Verify(request.VerifyEmailRequest{Token: token}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&token, "token", "t", "", "token from the verification email (required)")
+	cmd.MarkFlagRequired("token")
+	return cmd
+}
+
+// Anonymous user: request a new verification email
+func InitCommonResendVerificationCmd(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewEmailVerificationUsecase(conf)
+	var email string
+	cmd := &cobra.Command{Use: "resend-verification", Short: "Send a new verification email", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Resend(request.ResendVerificationRequest{Email: email}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&email, "email", "e", "", "account email (required)")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// Admin: list accounts and client IPs blocked from logging in
+func InitGetLoginLockoutCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewLoginLockoutUsecase(conf)
+	var scope string
+	cmd := &cobra.Command{Use: "lockouts", Aliases: []string{"lockout"}, Short: "Get active login lockouts (admin)", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.List(scope, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVar(&scope, "scope", "", "only show lockouts of this scope (account|ip)")
+	return cmd
+}
+
+// Admin: lift a lockout early, e.g. <account> <email> or <ip> <address>
+func InitDeleteLoginLockoutCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewLoginLockoutUsecase(conf)
+	cmd := &cobra.Command{Use: "lockout <account|ip> <key>", Aliases: []string{"lockouts"}, Short: "Clear a login lockout (admin)", Args: cobra.ExactArgs(2), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Clear(args[0], args[1], GetOutputFormat()))
+	}}
+	return cmd
+}
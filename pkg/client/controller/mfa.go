@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+func InitBootstrapMFACmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewMFAUsecase(conf)
+	cmd := &cobra.Command{
+		Use:   "mfa",
+		Short: "Initialize the MFA tables in the database.",
+		Long:  "This command drops the existing MFA enrollment and recovery code tables and recreates them based on the current model.",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(uc.Bootstrap(GetOutputFormat()))
+		},
+	}
+	return cmd
+}
+
+// App user: show whether MFA is enabled
+func InitGetMFACmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewMFAUsecase(conf)
+	cmd := &cobra.Command{Use: "mfa", Short: "Get my MFA status", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.GetMine(GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// App user: start MFA enrollment, or confirm it with --code
+func InitCreateMFACmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewMFAUsecase(conf)
+	var code string
+	cmd := &cobra.Command{Use: "mfa", Short: "Enroll in MFA (run again with --code to confirm)", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		if code == "" {
+			fmt.Print(uc.EnrollMine(GetOutputFormat()))
+			return
+		}
+		fmt.Print(uc.ConfirmMine(request.MFACodeRequest{Code: code}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVar(&code, "code", "", "Code from the authenticator app, to confirm enrollment")
+	return cmd
+}
+
+// App user: disable MFA with a current code or recovery code
+func InitDeleteMFACmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewMFAUsecase(conf)
+	var code string
+	cmd := &cobra.Command{Use: "mfa", Short: "Disable MFA", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.DisableMine(request.MFACodeRequest{Code: code}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVar(&code, "code", "", "Authentication code or recovery code (required)")
+	cmd.MarkFlagRequired("code")
+	return cmd
+}
+
+// Admin: reset a user's MFA (numeric ID or UUID)
+func InitDeleteMFACmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewMFAUsecase(conf)
+	cmd := &cobra.Command{Use: "mfa <user-id>", Short: "Reset a user's MFA", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.ResetUser(args[0], GetOutputFormat()))
+	}}
+	return cmd
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+// Anonymous user: request a password reset email
+func InitCommonForgotPasswordCmd(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPasswordUsecase(conf)
+	var email string
+	cmd := &cobra.Command{Use: "forgot-password", Short: "Send a password reset email", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Forgot(request.ForgotPasswordRequest{Email: email}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&email, "email", "e", "", "account email (required)")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+// Anonymous user: set a new password with the token from the reset email
+func InitCommonResetPasswordCmd(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPasswordUsecase(conf)
+	var token, password string
+	cmd := &cobra.Command{Use: "reset-password", Short: "Set a new password with a reset token", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Reset(request.ResetPasswordRequest{Token: token, Password: password}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&token, "token", "t", "", "token from the reset email (required)")
+	cmd.Flags().StringVarP(&password, "password", "p", "", "new password (required)")
+	cmd.MarkFlagRequired("token")
+	cmd.MarkFlagRequired("password")
+	return cmd
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+func InitBootstrapPersonalAccessTokenCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPersonalAccessTokenUsecase(conf)
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Initialize the personal access tokens table in the database.",
+		Long:  "This command drops the existing personal access tokens table and recreates it based on the current model.",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(uc.Bootstrap(GetOutputFormat()))
+		},
+	}
+	return cmd
+}
+
+// App user: list own personal access tokens
+func InitGetTokenCmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPersonalAccessTokenUsecase(conf)
+	cmd := &cobra.Command{Use: "tokens", Aliases: []string{"token"}, Short: "Get my personal access tokens", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.ListMine(GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// App user: create a personal access token (printed once; use it as LOCKY_ACCESS_TOKEN)
+func InitCreateTokenCmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPersonalAccessTokenUsecase(conf)
+	var name string
+	var scopes []string
+	var days int
+	cmd := &cobra.Command{Use: "token", Short: "Create a personal access token", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.CreateMine(request.PersonalAccessTokenRequest{Name: name, Scopes: scopes, ExpiresInDays: days}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Token name (required)")
+	cmd.Flags().StringSliceVarP(&scopes, "scopes", "s", nil, "Scopes as resource:action, comma separated (required, e.g. users:read,groups:write)")
+	cmd.Flags().IntVar(&days, "expires-in-days", 30, "Days until the token expires (max 365)")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("scopes")
+	return cmd
+}
+
+// App user: revoke one of own personal access tokens (numeric ID or UUID)
+func InitDeleteTokenCmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPersonalAccessTokenUsecase(conf)
+	cmd := &cobra.Command{Use: "token <id>", Aliases: []string{"tokens"}, Short: "Revoke a personal access token", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.RevokeMine(args[0], GetOutputFormat()))
+	}}
+	return cmd
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// Admin: one-time import of the policy.csv files into the database
+func InitBootstrapPolicyCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPolicyUsecase(conf)
+	var appPolicy, resourcePolicy string
+	var replace bool
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Import the Casbin policy files into the database.",
+		Long:  "This command creates the casbin_rules table and copies the app and resource policy.csv files into it, for servers running with casbin.storage: database. Policy sets already stored are only overwritten with --replace.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(uc.Import(appPolicy, resourcePolicy, replace, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().StringVar(&appPolicy, "app-policy", "etc/casbin/locky/policy.csv", "App-wide policy file")
+	cmd.Flags().StringVar(&resourcePolicy, "resource-policy", "etc/casbin/resources/policy.csv", "Group-scoped (resource) policy file")
+	cmd.Flags().BoolVar(&replace, "replace", false, "Overwrite the policies already stored in the database")
+	return cmd
+}
+
+// Admin: policy revision loaded by the server node that answers
+func InitGetPolicyStatusCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewPolicyUsecase(conf)
+	cmd := &cobra.Command{Use: "policy-status", Short: "Get the Casbin policy revision of a server node (admin)", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Status(GetOutputFormat()))
+	}}
+	return cmd
+}
@@ -30,13 +30,15 @@ func (p *permItems) Type() string { return "perm" }
 // Admin role get subcommand (under get to match other resources)
 func InitGetRoleCmdForAdmin(conf config.BaseConfig) *cobra.Command {
 	uc := usecase.NewRoleUsecase(conf)
+	effective := false
 	cmd := &cobra.Command{Use: "roles", Aliases: []string{"role"}, Short: "Get roles (admin)", Args: cobra.MaximumNArgs(1), Run: func(cmd *cobra.Command, args []string) {
 		id := ""
 		if len(args) == 1 {
 			id = args[0]
 		}
-		fmt.Print(uc.ListPrivate(id, GetOutputFormat()))
+		fmt.Print(uc.ListPrivate(id, effective, GetOutputFormat()))
 	}}
+	cmd.Flags().BoolVar(&effective, "effective", false, "with a role: also list permissions inherited from parent roles")
 	return cmd
 }
 
@@ -71,15 +73,53 @@ func InitDeleteRoleCmdForAdmin(conf config.BaseConfig) *cobra.Command {
 	return cmd
 }
 
+// Admin role hierarchy: parents, ancestors and descendants
+func InitGetRoleHierarchyCmdForAdmin(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "role-hierarchy <role>", Short: "Get parent, ancestor and descendant roles (admin)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.HierarchyPrivate(args[0], GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin role parent add: <role> inherits the permissions of <parent>
+func InitCreateRoleParentCmdForAdmin(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "role-parent <role> <parent>", Short: "Make a role inherit the permissions of a parent role (admin)", Args: cobra.ExactArgs(2), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.AddParent(args[0], args[1], GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin role parent remove
+func InitDeleteRoleParentCmdForAdmin(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "role-parent <role> <parent>", Short: "Stop a role inheriting from a parent role (admin)", Args: cobra.ExactArgs(2), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.RemoveParent(args[0], args[1], GetOutputFormat()))
+	}}
+	return cmd
+}
+
 // App (internal read-only)
 func InitGetRoleCmdForApp(conf config.BaseConfig) *cobra.Command {
 	uc := usecase.NewRoleUsecase(conf)
+	effective := false
 	cmd := &cobra.Command{Use: "roles", Aliases: []string{"role"}, Short: "Get roles (internal)", Args: cobra.MaximumNArgs(1), Run: func(cmd *cobra.Command, args []string) {
 		id := ""
 		if len(args) == 1 {
 			id = args[0]
 		}
-		fmt.Print(uc.ListInternal(id, GetOutputFormat()))
+		fmt.Print(uc.ListInternal(id, effective, GetOutputFormat()))
+	}}
+	cmd.Flags().BoolVar(&effective, "effective", false, "with a role: also list permissions inherited from parent roles")
+	return cmd
+}
+
+// App role hierarchy (internal read-only)
+func InitGetRoleHierarchyCmdForApp(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "role-hierarchy <role>", Short: "Get parent, ancestor and descendant roles (internal)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.HierarchyInternal(args[0], GetOutputFormat()))
 	}}
 	return cmd
 }
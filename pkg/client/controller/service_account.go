@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+func InitBootstrapServiceAccountCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	cmd := &cobra.Command{
+		Use:   "service-account",
+		Short: "Initialize the service account tables in the database.",
+		Long:  "This command drops the existing service account tables and recreates them based on the current model.",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(uc.Bootstrap(GetOutputFormat()))
+		},
+	}
+	return cmd
+}
+
+// Admin: list service accounts and their client IDs
+func InitGetServiceAccountCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	cmd := &cobra.Command{Use: "service-accounts", Aliases: []string{"service-account"}, Short: "Get service accounts (admin)", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.List(GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin: create a service account; the first client secret is printed once
+func InitCreateServiceAccountCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	var name, description, role string
+	cmd := &cobra.Command{Use: "service-account", Short: "Create a service account (admin)", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Create(request.ServiceAccountRequest{Name: name, Description: description, Role: role}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Service account name (required)")
+	cmd.Flags().StringVarP(&description, "description", "d", "", "Description")
+	cmd.Flags().StringVarP(&role, "role", "r", "", "App-level role (required)")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("role")
+	return cmd
+}
+
+// Admin: update a service account (numeric ID or UUID)
+func InitUpdateServiceAccountCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	var name, description, role string
+	cmd := &cobra.Command{Use: "service-account <id>", Short: "Update a service account (admin)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Update(args[0], request.ServiceAccountRequest{Name: name, Description: description, Role: role}, GetOutputFormat()))
+	}}
+	cmd.Flags().StringVarP(&name, "name", "n", "", "New name")
+	cmd.Flags().StringVarP(&description, "description", "d", "", "New description")
+	cmd.Flags().StringVarP(&role, "role", "r", "", "New app-level role")
+	return cmd
+}
+
+// Admin: delete a service account (numeric ID or UUID)
+func InitDeleteServiceAccountCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	cmd := &cobra.Command{Use: "service-account <id>", Short: "Delete a service account (admin)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Delete(args[0], GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin: issue a new client secret; existing secrets expire after --grace-seconds
+func InitCreateServiceAccountSecretCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	var graceSeconds int
+	cmd := &cobra.Command{Use: "service-account-secret <id>", Short: "Rotate the client secret of a service account (admin)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.RotateSecret(args[0], graceSeconds, GetOutputFormat()))
+	}}
+	cmd.Flags().IntVar(&graceSeconds, "grace-seconds", 0, "seconds the existing secrets stay valid")
+	return cmd
+}
+
+// Admin: revoke one client ID of a service account
+func InitDeleteServiceAccountSecretCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewServiceAccountUsecase(conf)
+	cmd := &cobra.Command{Use: "service-account-secret <id> <client-id>", Short: "Revoke a client secret of a service account (admin)", Args: cobra.ExactArgs(2), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.DeleteSecret(args[0], args[1], GetOutputFormat()))
+	}}
+	return cmd
+}
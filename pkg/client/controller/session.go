@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// App user: list own sessions
+func InitGetSessionCmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewSessionUsecase(conf)
+	cmd := &cobra.Command{Use: "sessions", Aliases: []string{"session"}, Short: "Get my login sessions", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.ListMine(GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// App user: revoke one own session, or all of them with --all
+func InitDeleteSessionCmdForAppUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewSessionUsecase(conf)
+	var all, exceptCurrent bool
+	cmd := &cobra.Command{Use: "session [session-id]", Aliases: []string{"sessions"}, Short: "Revoke my login session(s)", Args: cobra.MaximumNArgs(1), RunE: func(cmd *cobra.Command, args []string) error {
+		if all {
+			fmt.Print(uc.RevokeAllMine(exceptCurrent, GetOutputFormat()))
+			return nil
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("session id required (or use --all)")
+		}
+		fmt.Print(uc.RevokeMine(args[0], GetOutputFormat()))
+		return nil
+	}}
+	cmd.Flags().BoolVar(&all, "all", false, "revoke all sessions (log out everywhere)")
+	cmd.Flags().BoolVar(&exceptCurrent, "except-current", false, "with --all, keep the current session")
+	return cmd
+}
+
+// Admin: list sessions of a user (numeric ID or UUID)
+func InitGetSessionCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewSessionUsecase(conf)
+	cmd := &cobra.Command{Use: "sessions <user-id>", Aliases: []string{"session"}, Short: "Get login sessions of a user (admin)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.ListForUser(args[0], GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin: revoke one session of a user, or all of them with --all
+func InitDeleteSessionCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewSessionUsecase(conf)
+	var all bool
+	cmd := &cobra.Command{Use: "session <user-id> [session-id]", Aliases: []string{"sessions"}, Short: "Revoke login session(s) of a user (admin)", Args: cobra.RangeArgs(1, 2), RunE: func(cmd *cobra.Command, args []string) error {
+		if all {
+			fmt.Print(uc.RevokeAllForUser(args[0], GetOutputFormat()))
+			return nil
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("session id required (or use --all)")
+		}
+		fmt.Print(uc.RevokeForUser(args[0], args[1], GetOutputFormat()))
+		return nil
+	}}
+	cmd.Flags().BoolVar(&all, "all", false, "revoke all sessions of the user")
+	return cmd
+}
@@ -13,15 +13,21 @@ import (
 
 func InitBootstrapUserCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
 	uc := usecase.NewUserUsecase(conf)
+	var migrate bool
 	bootstrapUserCmd := &cobra.Command{
 		Use:   "user",
 		Short: "Initialize the users table in the database.",
-		Long:  "This command drops the existing users table and recreates it based on the current model.",
+		Long:  "This command drops the existing users table and recreates it based on the current model. With --migrate the existing users are kept and the tables are upgraded instead.",
 		Run: func(cmd *cobra.Command, args []string) {
+			if migrate {
+				fmt.Print(uc.Migrate(request.UserRequest{}, GetOutputFormat()))
+				return
+			}
 			out := uc.Bootstrap(request.UserRequest{}, GetOutputFormat())
 			fmt.Print(out)
 		},
 	}
+	bootstrapUserCmd.Flags().BoolVar(&migrate, "migrate", false, "Keep the existing users and upgrade the tables to the current model")
 	return bootstrapUserCmd
 }
 
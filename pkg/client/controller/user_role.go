@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/locky/pkg/client/usecase"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// Admin: list the global roles of a user (<user> is the user ID or UUID)
+func InitGetUserRoleCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewUserRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "user-roles <user>", Aliases: []string{"user-role"}, Short: "Get the global roles of a user (admin)", Args: cobra.ExactArgs(1), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.List(args[0], GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin: grant a global role to a user; it applies from the user's next login
+func InitCreateUserRoleCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewUserRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "user-role <user> <role>", Aliases: []string{"user-roles"}, Short: "Grant a global role to a user (admin)", Args: cobra.ExactArgs(2), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Grant(args[0], args[1], GetOutputFormat()))
+	}}
+	return cmd
+}
+
+// Admin: revoke a granted role; the user's sessions are ended
+func InitDeleteUserRoleCmdForAdminUser(conf config.BaseConfig) *cobra.Command {
+	uc := usecase.NewUserRoleUsecase(conf)
+	cmd := &cobra.Command{Use: "user-role <user> <role>", Aliases: []string{"user-roles"}, Short: "Revoke a global role from a user (admin)", Args: cobra.ExactArgs(2), Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(uc.Revoke(args[0], args[1], GetOutputFormat()))
+	}}
+	return cmd
+}
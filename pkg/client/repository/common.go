@@ -16,6 +16,7 @@ import (
 
 type CommonRepository interface {
 	Login(request request.LoginRequest) (response response.LoginResponse)
+	VerifyMFA(request request.MFALoginRequest) (response response.LoginResponse)
 	RefreshToken(refreshToken string) (response response.RefreshTokenResponse)
 	Logout(accessToken string) (response response.CommonResponse)
 	ValidateToken(accessToken string) (response response.ValidateTokenResponse)
@@ -115,6 +116,51 @@ func (rcvr commonRepository) Login(loginRequest request.LoginRequest) (response
 	return response
 }
 
+// VerifyMFA completes a login that returned MFA_REQUIRED and returns JWT tokens
+func (rcvr commonRepository) VerifyMFA(mfaRequest request.MFALoginRequest) (response response.LoginResponse) {
+	// POST /v1/share/common/auth/tokens/mfa
+	endpoint := rcvr.BaseConfig.YamlConfig.Application.Client.ServerEndpoint + "/v1/share/common/auth/tokens/mfa"
+
+	jsonData, err := json.Marshal(mfaRequest)
+	if err != nil {
+		response.Code = "CLIENT_AUTH_MFA_001"
+		response.Message = "Failed to marshal MFA request"
+		return response
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		response.Code = "CLIENT_AUTH_MFA_002"
+		response.Message = "Failed to create HTTP request"
+		return response
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		response.Code = "CLIENT_AUTH_MFA_003"
+		response.Message = "Failed to send HTTP request"
+		return response
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		response.Code = "CLIENT_AUTH_MFA_004"
+		response.Message = "Failed to decode response"
+		return response
+	}
+
+	if resp.StatusCode == http.StatusOK && response.TokenPair != nil {
+		os.Setenv("LOCKY_ACCESS_TOKEN", response.TokenPair.AccessToken)
+		os.Setenv("LOCKY_REFRESH_TOKEN", response.TokenPair.RefreshToken)
+		saveTokenPair(response.TokenPair.AccessToken, response.TokenPair.RefreshToken)
+	}
+
+	return response
+}
+
 // RefreshToken refreshes the access token using refresh token
 func (rcvr commonRepository) RefreshToken(refreshToken string) (response response.RefreshTokenResponse) {
 	// Updated to match server router: POST /v1/share/common/auth/tokens/refresh
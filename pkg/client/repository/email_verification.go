@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type EmailVerificationRepository interface {
+	VerifyEmail(req request.VerifyEmailRequest) response.CommonResponse
+	ResendVerification(req request.ResendVerificationRequest) response.CommonResponse
+}
+
+type emailVerificationRepository struct {
+	base config.BaseConfig
+}
+
+func NewEmailVerificationRepository(base config.BaseConfig) EmailVerificationRepository {
+	return &emailVerificationRepository{base: base}
+}
+
+func (r *emailVerificationRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *emailVerificationRepository) do(method, endpoint string, body interface{}, errCode string) response.CommonResponse {
+	var resp response.CommonResponse
+	if err := sendRequest(method, endpoint, body, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+func (r *emailVerificationRepository) VerifyEmail(req request.VerifyEmailRequest) response.CommonResponse {
+	if req.Token == "" {
+		return response.CommonResponse{Code: "USER_VERIFY_VALIDATION_ERROR", Message: "token required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/public/user/verify"), req, "USER_VERIFY_ERROR")
+}
+
+func (r *emailVerificationRepository) ResendVerification(req request.ResendVerificationRequest) response.CommonResponse {
+	if req.Email == "" {
+		return response.CommonResponse{Code: "USER_VERIFY_RESEND_VALIDATION_ERROR", Message: "email required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/public/user/verify/resend"), req, "USER_VERIFY_RESEND_ERROR")
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type LoginLockoutRepository interface {
+	ListLockouts(scope string) response.LoginLockoutResponse
+	ClearLockout(scope, key string) response.LoginLockoutResponse
+}
+
+type loginLockoutRepository struct {
+	base config.BaseConfig
+}
+
+func NewLoginLockoutRepository(base config.BaseConfig) LoginLockoutRepository {
+	return &loginLockoutRepository{base: base}
+}
+
+func (r *loginLockoutRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *loginLockoutRepository) do(method, endpoint, errCode string) response.LoginLockoutResponse {
+	var resp response.LoginLockoutResponse
+	if err := sendRequest(method, endpoint, nil, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+func (r *loginLockoutRepository) ListLockouts(scope string) response.LoginLockoutResponse {
+	endpoint := r.endpoint("/v1/private/lockouts")
+	if scope != "" {
+		endpoint += "?scope=" + url.QueryEscape(scope)
+	}
+	return r.do(http.MethodGet, endpoint, "LOCKOUT_LIST_ERROR")
+}
+
+func (r *loginLockoutRepository) ClearLockout(scope, key string) response.LoginLockoutResponse {
+	if scope == "" || key == "" {
+		return response.LoginLockoutResponse{Code: "LOCKOUT_CLEAR_VALIDATION_ERROR", Message: "scope and key required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/private/lockout/"+url.PathEscape(scope)+"/"+url.PathEscape(key)), "LOCKOUT_CLEAR_ERROR")
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type MFARepository interface {
+	BootstrapMFAForDB() response.MFAResponse
+	GetMyMFA() response.MFAResponse
+	EnrollMyMFA() response.MFAResponse
+	ConfirmMyMFA(req request.MFACodeRequest) response.MFAResponse
+	DisableMyMFA(req request.MFACodeRequest) response.MFAResponse
+	ResetUserMFA(userID string) response.MFAResponse
+}
+
+type mfaRepository struct {
+	base config.BaseConfig
+}
+
+func NewMFARepository(base config.BaseConfig) MFARepository {
+	return &mfaRepository{base: base}
+}
+
+func (r *mfaRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *mfaRepository) do(method, endpoint string, body interface{}, errCode string) response.MFAResponse {
+	var resp response.MFAResponse
+	if err := sendRequest(method, endpoint, body, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+// Bootstrap
+func (r *mfaRepository) BootstrapMFAForDB() response.MFAResponse {
+	var resp response.MFAResponse
+	fmt.Println("BootstrapMFAForDB")
+
+	if r.base.DBConnection == nil {
+		if err := r.base.ConnectDB(); err != nil {
+			resp.Code = "CLIENT_MFA_BOOTSTRAP_000"
+			resp.Message = "Failed to connect database"
+			return resp
+		}
+	}
+
+	for _, table := range []interface{}{&model.UserMFAs{}, &model.MFARecoveryCodes{}} {
+		if r.base.DBConnection.Migrator().HasTable(table) {
+			if err := r.base.DBConnection.Migrator().DropTable(table); err != nil {
+				resp.Code = "CLIENT_MFA_BOOTSTRAP_001"
+				resp.Message = fmt.Sprintf("Failed to drop existing table: %v", err)
+				return resp
+			}
+		}
+	}
+
+	if err := r.base.DBConnection.AutoMigrate(&model.UserMFAs{}, &model.MFARecoveryCodes{}); err != nil {
+		resp.Code = "CLIENT_MFA_BOOTSTRAP_002"
+		resp.Message = fmt.Sprintf("Failed to create MFA tables: %v", err)
+		return resp
+	}
+
+	resp.Code = "SUCCESS"
+	resp.Message = "Bootstrap for MFA completed successfully"
+	return resp
+}
+
+func (r *mfaRepository) GetMyMFA() response.MFAResponse {
+	return r.do(http.MethodGet, r.endpoint("/v1/internal/me/mfa"), nil, "MFA_GET_ERROR")
+}
+
+func (r *mfaRepository) EnrollMyMFA() response.MFAResponse {
+	return r.do(http.MethodPost, r.endpoint("/v1/internal/me/mfa"), nil, "MFA_ENROLL_ERROR")
+}
+
+func (r *mfaRepository) ConfirmMyMFA(req request.MFACodeRequest) response.MFAResponse {
+	if req.Code == "" {
+		return response.MFAResponse{Code: "MFA_CONFIRM_VALIDATION_ERROR", Message: "code required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/internal/me/mfa/confirm"), req, "MFA_CONFIRM_ERROR")
+}
+
+func (r *mfaRepository) DisableMyMFA(req request.MFACodeRequest) response.MFAResponse {
+	if req.Code == "" {
+		return response.MFAResponse{Code: "MFA_DISABLE_VALIDATION_ERROR", Message: "code required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/internal/me/mfa"), req, "MFA_DISABLE_ERROR")
+}
+
+func (r *mfaRepository) ResetUserMFA(userID string) response.MFAResponse {
+	if userID == "" {
+		return response.MFAResponse{Code: "MFA_RESET_VALIDATION_ERROR", Message: "user id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/private/users/"+url.PathEscape(userID)+"/mfa"), nil, "MFA_RESET_ERROR")
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type PasswordRepository interface {
+	ForgotPassword(req request.ForgotPasswordRequest) response.CommonResponse
+	ResetPassword(req request.ResetPasswordRequest) response.CommonResponse
+}
+
+type passwordRepository struct {
+	base config.BaseConfig
+}
+
+func NewPasswordRepository(base config.BaseConfig) PasswordRepository {
+	return &passwordRepository{base: base}
+}
+
+func (r *passwordRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *passwordRepository) do(method, endpoint string, body interface{}, errCode string) response.CommonResponse {
+	var resp response.CommonResponse
+	if err := sendRequest(method, endpoint, body, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+func (r *passwordRepository) ForgotPassword(req request.ForgotPasswordRequest) response.CommonResponse {
+	if req.Email == "" {
+		return response.CommonResponse{Code: "PASSWORD_FORGOT_VALIDATION_ERROR", Message: "email required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/public/password/forgot"), req, "PASSWORD_FORGOT_ERROR")
+}
+
+func (r *passwordRepository) ResetPassword(req request.ResetPasswordRequest) response.CommonResponse {
+	if req.Token == "" || req.Password == "" {
+		return response.CommonResponse{Code: "PASSWORD_RESET_VALIDATION_ERROR", Message: "token and password required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/public/password/reset"), req, "PASSWORD_RESET_ERROR")
+}
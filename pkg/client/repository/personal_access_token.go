@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type PersonalAccessTokenRepository interface {
+	BootstrapPersonalAccessTokenForDB() response.PersonalAccessTokenResponse
+	ListMyTokens() response.PersonalAccessTokenResponse
+	CreateMyToken(req request.PersonalAccessTokenRequest) response.PersonalAccessTokenResponse
+	RevokeMyToken(id string) response.PersonalAccessTokenResponse
+}
+
+type personalAccessTokenRepository struct {
+	base config.BaseConfig
+}
+
+func NewPersonalAccessTokenRepository(base config.BaseConfig) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{base: base}
+}
+
+func (r *personalAccessTokenRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *personalAccessTokenRepository) do(method, endpoint string, body interface{}, errCode string) response.PersonalAccessTokenResponse {
+	var resp response.PersonalAccessTokenResponse
+	if err := sendRequest(method, endpoint, body, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+// Bootstrap
+func (r *personalAccessTokenRepository) BootstrapPersonalAccessTokenForDB() response.PersonalAccessTokenResponse {
+	var resp response.PersonalAccessTokenResponse
+	fmt.Println("BootstrapPersonalAccessTokenForDB")
+
+	if r.base.DBConnection == nil {
+		if err := r.base.ConnectDB(); err != nil {
+			resp.Code = "CLIENT_TOKEN_BOOTSTRAP_000"
+			resp.Message = "Failed to connect database"
+			return resp
+		}
+	}
+
+	if r.base.DBConnection.Migrator().HasTable(&model.PersonalAccessTokens{}) {
+		if err := r.base.DBConnection.Migrator().DropTable(&model.PersonalAccessTokens{}); err != nil {
+			resp.Code = "CLIENT_TOKEN_BOOTSTRAP_001"
+			resp.Message = fmt.Sprintf("Failed to drop existing table: %v", err)
+			return resp
+		}
+	}
+
+	if err := r.base.DBConnection.AutoMigrate(&model.PersonalAccessTokens{}); err != nil {
+		resp.Code = "CLIENT_TOKEN_BOOTSTRAP_002"
+		resp.Message = fmt.Sprintf("Failed to create PersonalAccessTokens table: %v", err)
+		return resp
+	}
+
+	resp.Code = "SUCCESS"
+	resp.Message = "Bootstrap for PersonalAccessToken completed successfully"
+	return resp
+}
+
+func (r *personalAccessTokenRepository) ListMyTokens() response.PersonalAccessTokenResponse {
+	return r.do(http.MethodGet, r.endpoint("/v1/internal/me/tokens"), nil, "TOKEN_LIST_ERROR")
+}
+
+func (r *personalAccessTokenRepository) CreateMyToken(req request.PersonalAccessTokenRequest) response.PersonalAccessTokenResponse {
+	if req.Name == "" || len(req.Scopes) == 0 {
+		return response.PersonalAccessTokenResponse{Code: "TOKEN_CREATE_VALIDATION_ERROR", Message: "name and scopes required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/internal/me/token"), req, "TOKEN_CREATE_ERROR")
+}
+
+func (r *personalAccessTokenRepository) RevokeMyToken(id string) response.PersonalAccessTokenResponse {
+	if id == "" {
+		return response.PersonalAccessTokenResponse{Code: "TOKEN_REVOKE_VALIDATION_ERROR", Message: "token id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/internal/me/token/"+url.PathEscape(id)), nil, "TOKEN_REVOKE_ERROR")
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"gorm.io/gorm"
+)
+
+type PolicyRepository interface {
+	ImportPolicyForDB(appPolicy, resourcePolicy string, replace bool) response.PolicyResponse
+	GetPolicyStatus() response.PolicyResponse
+}
+
+type policyRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// ImportPolicyForDB copies the app and resource policy.csv files into the
+// casbin_rules table once, for servers running with casbin.storage: database.
+// Policy sets that already have lines are only overwritten with replace.
+func (rcvr policyRepository) ImportPolicyForDB(appPolicy, resourcePolicy string, replace bool) response.PolicyResponse {
+	resp := response.PolicyResponse{PolicySets: []response.PolicySet{}}
+	fmt.Println("ImportPolicyForDB")
+
+	sources := []response.PolicySet{
+		{Name: model.PolicySetApp, Source: appPolicy},
+		{Name: model.PolicySetResources, Source: resourcePolicy},
+	}
+	rules := map[string][]model.CasbinRules{}
+	for i, set := range sources {
+		lines, err := readPolicyFile(set.Name, set.Source)
+		if err != nil {
+			resp.Code = "CLIENT_POLICY_IMPORT_001"
+			resp.Message = err.Error()
+			return resp
+		}
+		rules[set.Name] = lines
+		sources[i].Rules = len(lines)
+	}
+
+	if rcvr.BaseConfig.DBConnection == nil {
+		if err := rcvr.BaseConfig.ConnectDB(); err != nil {
+			resp.Code = "CLIENT_POLICY_IMPORT_000"
+			resp.Message = "Failed to connect database"
+			return resp
+		}
+	}
+	db := rcvr.BaseConfig.DBConnection
+	if err := db.AutoMigrate(&model.CasbinRules{}); err != nil {
+		resp.Code = "CLIENT_POLICY_IMPORT_002"
+		resp.Message = fmt.Sprintf("Failed to create casbin_rules table: %v", err)
+		return resp
+	}
+	if !replace {
+		for _, set := range sources {
+			var count int64
+			if err := db.Model(&model.CasbinRules{}).Where("policy_set = ?", set.Name).Count(&count).Error; err != nil {
+				resp.Code = "CLIENT_POLICY_IMPORT_002"
+				resp.Message = err.Error()
+				return resp
+			}
+			if count > 0 {
+				resp.Code = "CLIENT_POLICY_IMPORT_003"
+				resp.Message = fmt.Sprintf("policy set %s already has %d rules in the database; use --replace to overwrite them", set.Name, count)
+				return resp
+			}
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, set := range sources {
+			if err := tx.Where("policy_set = ?", set.Name).Delete(&model.CasbinRules{}).Error; err != nil {
+				return err
+			}
+			if lines := rules[set.Name]; len(lines) > 0 {
+				if err := tx.CreateInBatches(&lines, 100).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		resp.Code = "CLIENT_POLICY_IMPORT_004"
+		resp.Message = fmt.Sprintf("Failed to import policies: %v", err)
+		return resp
+	}
+
+	resp.Code = "SUCCESS"
+	resp.Message = "Policies imported successfully"
+	resp.PolicySets = sources
+	return resp
+}
+
+// GetPolicyStatus reports the policy revision of the server node that answers
+func (rcvr policyRepository) GetPolicyStatus() response.PolicyResponse {
+	var resp response.PolicyResponse
+	endpoint := strings.TrimRight(rcvr.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, "/") + "/v1/private/policy/status"
+	if err := sendRequest(http.MethodGet, endpoint, nil, &resp); err != nil {
+		resp.Code = "POLICY_STATUS_ERROR"
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+// readPolicyFile parses the lines of a policy.csv the way Casbin's file adapter
+// does: blank lines and # comments are skipped, values are trimmed and repeated
+// lines are imported once.
+func readPolicyFile(policySet, path string) ([]model.CasbinRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := []model.CasbinRules{}
+	seen := map[model.CasbinRules]bool{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(line))
+		r.Comment = '#'
+		r.TrimLeadingSpace = true
+		tokens, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		for i := range tokens {
+			tokens[i] = strings.TrimSpace(tokens[i])
+		}
+		if len(tokens) < 2 || tokens[0] == "" {
+			return nil, fmt.Errorf("%s:%d: policy type and values are required", path, n)
+		}
+		rule := model.NewCasbinRule(policySet, tokens[0], tokens[1:])
+		if !seen[rule] {
+			seen[rule] = true
+			lines = append(lines, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func NewPolicyRepository(conf config.BaseConfig) PolicyRepository {
+	return &policyRepository{BaseConfig: conf}
+}
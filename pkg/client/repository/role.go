@@ -17,13 +17,20 @@ type RoleRepository interface {
 	CreateRole(req request.RolePermissionRequest) response.RoleResponse
 	UpdateRole(role string, req request.RolePermissionRequest) response.RoleResponse
 	DeleteRole(role string) response.RoleResponse
+	GetRoleHierarchyInternal(role string) response.RoleResponse
+	GetRoleHierarchyPrivate(role string) response.RoleResponse
+	AddRoleParent(role, parent string) response.RoleResponse
+	RemoveRoleParent(role, parent string) response.RoleResponse
 }
 
 type roleRepository struct {
 	base config.BaseConfig
 }
 
-type RoleFilter struct{ ID string }
+type RoleFilter struct {
+	ID        string
+	Effective bool // with ID: include permissions inherited from parent roles
+}
 
 func NewRoleRepository(base config.BaseConfig) RoleRepository { return &roleRepository{base: base} }
 
@@ -39,6 +46,9 @@ func (r *roleRepository) ListRolesInternal(filter RoleFilter) response.RoleRespo
 	url := r.endpoint("/v1/internal/roles")
 	if filter.ID != "" {
 		url += "?id=" + filter.ID
+		if filter.Effective {
+			url += "&effective=true"
+		}
 	}
 	var resp response.RoleResponse
 	if err := r.authReq(http.MethodGet, url, nil, &resp); err != nil {
@@ -51,6 +61,9 @@ func (r *roleRepository) ListRolesPrivate(filter RoleFilter) response.RoleRespon
 	url := r.endpoint("/v1/private/roles")
 	if filter.ID != "" {
 		url += "?id=" + filter.ID
+		if filter.Effective {
+			url += "&effective=true"
+		}
 	}
 	var resp response.RoleResponse
 	if err := r.authReq(http.MethodGet, url, nil, &resp); err != nil {
@@ -102,11 +115,65 @@ func (r *roleRepository) DeleteRole(role string) response.RoleResponse {
 	return resp
 }
 
+func (r *roleRepository) getRoleHierarchy(scope, role string) response.RoleResponse {
+	var resp response.RoleResponse
+	if role == "" {
+		resp.Code = "ROLE_HIERARCHY_VALIDATION_ERROR"
+		resp.Message = "role id required"
+		return resp
+	}
+	url := r.endpoint("/v1/" + scope + "/role/" + role + "/hierarchy")
+	if err := r.authReq(http.MethodGet, url, nil, &resp); err != nil {
+		resp.Code = "ROLE_HIERARCHY_ERROR"
+		resp.Message = err.Error()
+	}
+	return resp
+}
+func (r *roleRepository) GetRoleHierarchyInternal(role string) response.RoleResponse {
+	return r.getRoleHierarchy("internal", role)
+}
+func (r *roleRepository) GetRoleHierarchyPrivate(role string) response.RoleResponse {
+	return r.getRoleHierarchy("private", role)
+}
+func (r *roleRepository) AddRoleParent(role, parent string) response.RoleResponse {
+	var resp response.RoleResponse
+	if role == "" || parent == "" {
+		resp.Code = "ROLE_PARENT_ADD_VALIDATION_ERROR"
+		resp.Message = "role id and parent required"
+		return resp
+	}
+	url := r.endpoint("/v1/private/role/" + role + "/parents")
+	if err := r.authReq(http.MethodPost, url, request.RoleParentRequest{Parent: parent}, &resp); err != nil {
+		resp.Code = "ROLE_PARENT_ADD_ERROR"
+		resp.Message = err.Error()
+	}
+	return resp
+}
+func (r *roleRepository) RemoveRoleParent(role, parent string) response.RoleResponse {
+	var resp response.RoleResponse
+	if role == "" || parent == "" {
+		resp.Code = "ROLE_PARENT_REMOVE_VALIDATION_ERROR"
+		resp.Message = "role id and parent required"
+		return resp
+	}
+	url := r.endpoint("/v1/private/role/" + role + "/parents/" + parent)
+	if err := r.authReq(http.MethodDelete, url, nil, &resp); err != nil {
+		resp.Code = "ROLE_PARENT_REMOVE_ERROR"
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
 // Table formatting helper (used by usecase)
 func rolesTableString(res response.RoleResponse) string {
 	if res.Code != "SUCCESS" {
 		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
 	}
+	// Inheritance of one role
+	if h := res.Hierarchy; h != nil {
+		return fmt.Sprintf("Role: %s\nParents: %s\nAncestors: %s\nDescendants: %s\n",
+			h.Role, strings.Join(h.Parents, ", "), strings.Join(h.Ancestors, ", "), strings.Join(h.Descendants, ", "))
+	}
 	// Single role retrieval (Detail contains permissions)
 	if res.Detail != nil {
 		// Format permissions display
@@ -116,7 +183,15 @@ func rolesTableString(res response.RoleResponse) string {
 				permLines = append(permLines, fmt.Sprintf("  - %v", p))
 			}
 		}
-		return fmt.Sprintf("Role: %v\nPermissions:\n%v\n", res.Roles, strings.Join(permLines, "\n"))
+		out := fmt.Sprintf("Role: %v\nPermissions:\n%v\n", res.Roles, strings.Join(permLines, "\n"))
+		if len(res.Effective) > 0 {
+			effLines := []string{}
+			for _, p := range res.Effective {
+				effLines = append(effLines, fmt.Sprintf("  - %s:%s (from %s)", p.Resource, p.Action, p.Role))
+			}
+			out += fmt.Sprintf("Effective permissions:\n%v\n", strings.Join(effLines, "\n"))
+		}
+		return out
 	}
 	// List: Sort and enumerate Roles alphabetically
 	switch v := res.Roles.(type) {
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type ServiceAccountRepository interface {
+	BootstrapServiceAccountForDB() response.ServiceAccountResponse
+	ListServiceAccounts() response.ServiceAccountResponse
+	CreateServiceAccount(req request.ServiceAccountRequest) response.ServiceAccountResponse
+	UpdateServiceAccount(id string, req request.ServiceAccountRequest) response.ServiceAccountResponse
+	DeleteServiceAccount(id string) response.ServiceAccountResponse
+	RotateSecret(id string, req request.ServiceAccountSecretRequest) response.ServiceAccountResponse
+	DeleteSecret(id, clientID string) response.ServiceAccountResponse
+}
+
+type serviceAccountRepository struct {
+	base config.BaseConfig
+}
+
+func NewServiceAccountRepository(base config.BaseConfig) ServiceAccountRepository {
+	return &serviceAccountRepository{base: base}
+}
+
+func (r *serviceAccountRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *serviceAccountRepository) do(method, endpoint string, body interface{}, errCode string) response.ServiceAccountResponse {
+	var resp response.ServiceAccountResponse
+	if err := sendRequest(method, endpoint, body, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+// Bootstrap
+func (r *serviceAccountRepository) BootstrapServiceAccountForDB() response.ServiceAccountResponse {
+	var resp response.ServiceAccountResponse
+	fmt.Println("BootstrapServiceAccountForDB")
+
+	if r.base.DBConnection == nil {
+		if err := r.base.ConnectDB(); err != nil {
+			resp.Code = "CLIENT_SERVICE_ACCOUNT_BOOTSTRAP_000"
+			resp.Message = "Failed to connect database"
+			return resp
+		}
+	}
+
+	tables := []interface{}{&model.ServiceAccountCredentials{}, &model.ServiceAccounts{}}
+	for _, table := range tables {
+		if r.base.DBConnection.Migrator().HasTable(table) {
+			if err := r.base.DBConnection.Migrator().DropTable(table); err != nil {
+				resp.Code = "CLIENT_SERVICE_ACCOUNT_BOOTSTRAP_001"
+				resp.Message = fmt.Sprintf("Failed to drop existing table: %v", err)
+				return resp
+			}
+		}
+	}
+
+	if err := r.base.DBConnection.AutoMigrate(&model.ServiceAccounts{}, &model.ServiceAccountCredentials{}); err != nil {
+		resp.Code = "CLIENT_SERVICE_ACCOUNT_BOOTSTRAP_002"
+		resp.Message = fmt.Sprintf("Failed to create ServiceAccounts tables: %v", err)
+		return resp
+	}
+
+	resp.Code = "SUCCESS"
+	resp.Message = "Bootstrap for ServiceAccount completed successfully"
+	return resp
+}
+
+func (r *serviceAccountRepository) ListServiceAccounts() response.ServiceAccountResponse {
+	return r.do(http.MethodGet, r.endpoint("/v1/private/service-accounts"), nil, "SERVICE_ACCOUNT_LIST_ERROR")
+}
+
+func (r *serviceAccountRepository) CreateServiceAccount(req request.ServiceAccountRequest) response.ServiceAccountResponse {
+	if req.Name == "" || req.Role == "" {
+		return response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_CREATE_VALIDATION_ERROR", Message: "name and role required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/private/service-account"), req, "SERVICE_ACCOUNT_CREATE_ERROR")
+}
+
+func (r *serviceAccountRepository) UpdateServiceAccount(id string, req request.ServiceAccountRequest) response.ServiceAccountResponse {
+	if id == "" {
+		return response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_UPDATE_VALIDATION_ERROR", Message: "service account id required"}
+	}
+	return r.do(http.MethodPut, r.endpoint("/v1/private/service-account/"+url.PathEscape(id)), req, "SERVICE_ACCOUNT_UPDATE_ERROR")
+}
+
+func (r *serviceAccountRepository) DeleteServiceAccount(id string) response.ServiceAccountResponse {
+	if id == "" {
+		return response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_DELETE_VALIDATION_ERROR", Message: "service account id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/private/service-account/"+url.PathEscape(id)), nil, "SERVICE_ACCOUNT_DELETE_ERROR")
+}
+
+func (r *serviceAccountRepository) RotateSecret(id string, req request.ServiceAccountSecretRequest) response.ServiceAccountResponse {
+	if id == "" {
+		return response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_VALIDATION_ERROR", Message: "service account id required"}
+	}
+	return r.do(http.MethodPost, r.endpoint("/v1/private/service-account/"+url.PathEscape(id)+"/secret"), req, "SERVICE_ACCOUNT_SECRET_ERROR")
+}
+
+func (r *serviceAccountRepository) DeleteSecret(id, clientID string) response.ServiceAccountResponse {
+	if id == "" || clientID == "" {
+		return response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_VALIDATION_ERROR", Message: "service account id and client id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/private/service-account/"+url.PathEscape(id)+"/secret/"+url.PathEscape(clientID)), nil, "SERVICE_ACCOUNT_SECRET_ERROR")
+}
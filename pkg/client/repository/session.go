@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type SessionRepository interface {
+	ListMySessions() response.SessionResponse
+	RevokeMySession(sessionID string) response.SessionResponse
+	RevokeMySessions(exceptCurrent bool) response.SessionResponse
+	ListUserSessions(userID string) response.SessionResponse
+	RevokeUserSession(userID, sessionID string) response.SessionResponse
+	RevokeUserSessions(userID string) response.SessionResponse
+}
+
+type sessionRepository struct {
+	base config.BaseConfig
+}
+
+func NewSessionRepository(base config.BaseConfig) SessionRepository {
+	return &sessionRepository{base: base}
+}
+
+func (r *sessionRepository) endpoint(path string) string {
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *sessionRepository) do(method, endpoint, errCode string) response.SessionResponse {
+	var resp response.SessionResponse
+	if err := sendRequest(method, endpoint, nil, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+func (r *sessionRepository) ListMySessions() response.SessionResponse {
+	return r.do(http.MethodGet, r.endpoint("/v1/internal/me/sessions"), "SESSION_LIST_ERROR")
+}
+
+func (r *sessionRepository) RevokeMySession(sessionID string) response.SessionResponse {
+	if sessionID == "" {
+		return response.SessionResponse{Code: "SESSION_REVOKE_VALIDATION_ERROR", Message: "session id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/internal/me/sessions/"+url.PathEscape(sessionID)), "SESSION_REVOKE_ERROR")
+}
+
+func (r *sessionRepository) RevokeMySessions(exceptCurrent bool) response.SessionResponse {
+	endpoint := r.endpoint("/v1/internal/me/sessions")
+	if exceptCurrent {
+		endpoint += "?except_current=true"
+	}
+	return r.do(http.MethodDelete, endpoint, "SESSION_REVOKE_ERROR")
+}
+
+func (r *sessionRepository) ListUserSessions(userID string) response.SessionResponse {
+	if userID == "" {
+		return response.SessionResponse{Code: "SESSION_LIST_VALIDATION_ERROR", Message: "user id required"}
+	}
+	return r.do(http.MethodGet, r.endpoint("/v1/private/users/"+url.PathEscape(userID)+"/sessions"), "SESSION_LIST_ERROR")
+}
+
+func (r *sessionRepository) RevokeUserSession(userID, sessionID string) response.SessionResponse {
+	if userID == "" || sessionID == "" {
+		return response.SessionResponse{Code: "SESSION_REVOKE_VALIDATION_ERROR", Message: "user id and session id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/private/users/"+url.PathEscape(userID)+"/sessions/"+url.PathEscape(sessionID)), "SESSION_REVOKE_ERROR")
+}
+
+func (r *sessionRepository) RevokeUserSessions(userID string) response.SessionResponse {
+	if userID == "" {
+		return response.SessionResponse{Code: "SESSION_REVOKE_VALIDATION_ERROR", Message: "user id required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint("/v1/private/users/"+url.PathEscape(userID)+"/sessions"), "SESSION_REVOKE_ERROR")
+}
@@ -2,15 +2,18 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ryo-arima/locky/pkg/config"
 	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/entity/request"
 	"github.com/ryo-arima/locky/pkg/entity/response"
+	"gorm.io/gorm"
 )
 
 type UserRepository interface {
 	BootstrapUserForDB(request request.UserRequest) response.UserResponse
+	MigrateUserForDB(request request.UserRequest) response.UserResponse
 	GetUserForInternal(request request.UserRequest) response.UserResponse
 	GetUserForPrivate(request request.UserRequest) response.UserResponse
 	CreateUserForPublic(request request.UserRequest) response.UserResponse
@@ -38,17 +41,19 @@ func (rcvr userRepository) BootstrapUserForDB(request request.UserRequest) respo
 		}
 	}
 
-	if rcvr.BaseConfig.DBConnection.Migrator().HasTable(&model.Users{}) {
-		if err := rcvr.BaseConfig.DBConnection.Migrator().DropTable(&model.Users{}); err != nil {
-			resp.Code = "CLIENT_USER_BOOTSTRAP_001"
-			resp.Message = fmt.Sprintf("Failed to drop existing table: %v", err)
-			return resp
+	for _, table := range []interface{}{&model.Users{}, &model.PasswordHistories{}, &model.IdentityLinks{}, &model.RoleAssignments{}} {
+		if rcvr.BaseConfig.DBConnection.Migrator().HasTable(table) {
+			if err := rcvr.BaseConfig.DBConnection.Migrator().DropTable(table); err != nil {
+				resp.Code = "CLIENT_USER_BOOTSTRAP_001"
+				resp.Message = fmt.Sprintf("Failed to drop existing table: %v", err)
+				return resp
+			}
 		}
 	}
 
-	if err := rcvr.BaseConfig.DBConnection.AutoMigrate(&model.Users{}); err != nil {
+	if err := rcvr.BaseConfig.DBConnection.AutoMigrate(&model.Users{}, &model.PasswordHistories{}, &model.IdentityLinks{}, &model.RoleAssignments{}); err != nil {
 		resp.Code = "CLIENT_USER_BOOTSTRAP_002"
-		resp.Message = fmt.Sprintf("Failed to create Users table: %v", err)
+		resp.Message = fmt.Sprintf("Failed to create Users tables: %v", err)
 		return resp
 	}
 
@@ -57,6 +62,66 @@ func (rcvr userRepository) BootstrapUserForDB(request request.UserRequest) respo
 	return resp
 }
 
+// MigrateUserForDB upgrades the users tables to the current model and keeps
+// their rows. email_normalized is filled before its unique index is created;
+// emails that differ only in case stop the migration until they are merged.
+func (rcvr userRepository) MigrateUserForDB(request request.UserRequest) response.UserResponse {
+	var resp response.UserResponse
+	fmt.Println("MigrateUserForDB")
+
+	if rcvr.BaseConfig.DBConnection == nil {
+		if err := rcvr.BaseConfig.ConnectDB(); err != nil {
+			resp.Code = "CLIENT_USER_MIGRATE_000"
+			resp.Message = "Failed to connect database"
+			return resp
+		}
+	}
+	db := rcvr.BaseConfig.DBConnection
+
+	if db.Migrator().HasTable(&model.Users{}) {
+		if err := backfillEmailNormalized(db); err != nil {
+			resp.Code = "CLIENT_USER_MIGRATE_001"
+			resp.Message = fmt.Sprintf("Failed to fill email_normalized: %v", err)
+			return resp
+		}
+		var duplicates []string
+		if err := db.Model(&model.Users{}).Group("email_normalized").Having("COUNT(*) > 1").Pluck("email_normalized", &duplicates).Error; err != nil {
+			resp.Code = "CLIENT_USER_MIGRATE_001"
+			resp.Message = fmt.Sprintf("Failed to check for duplicate emails: %v", err)
+			return resp
+		}
+		if len(duplicates) > 0 {
+			resp.Code = "CLIENT_USER_MIGRATE_002"
+			resp.Message = fmt.Sprintf("Users share these emails, differing only in case; merge them before migrating: %s", strings.Join(duplicates, ", "))
+			return resp
+		}
+	}
+
+	if err := db.AutoMigrate(&model.Users{}, &model.PasswordHistories{}, &model.IdentityLinks{}, &model.RoleAssignments{}); err != nil {
+		resp.Code = "CLIENT_USER_MIGRATE_003"
+		resp.Message = fmt.Sprintf("Failed to migrate Users tables: %v", err)
+		return resp
+	}
+
+	resp.Code = "SUCCESS"
+	resp.Message = "Migration for User completed successfully"
+	return resp
+}
+
+// backfillEmailNormalized adds email_normalized to a users table created
+// before it existed, without the index, and fills it like the server does
+// (trimmed and lower-cased).
+func backfillEmailNormalized(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&model.Users{}, "EmailNormalized") {
+		if err := db.Migrator().AddColumn(&model.Users{}, "EmailNormalized"); err != nil {
+			return err
+		}
+	}
+	return db.Model(&model.Users{}).
+		Where("email_normalized IS NULL OR email_normalized = ''").
+		Update("email_normalized", gorm.Expr("LOWER(TRIM(email))")).Error
+}
+
 // GET
 func (rcvr userRepository) GetUserForInternal(request request.UserRequest) response.UserResponse {
 	var resp response.UserResponse
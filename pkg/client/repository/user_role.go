@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type UserRoleRepository interface {
+	ListUserRoles(user string) response.UserRoleResponse
+	GrantUserRole(user, role string) response.UserRoleResponse
+	RevokeUserRole(user, role string) response.UserRoleResponse
+}
+
+type userRoleRepository struct {
+	base config.BaseConfig
+}
+
+func NewUserRoleRepository(base config.BaseConfig) UserRoleRepository {
+	return &userRoleRepository{base: base}
+}
+
+func (r *userRoleRepository) endpoint(user string, rest ...string) string {
+	path := "/v1/private/users/" + url.PathEscape(user) + "/roles"
+	for _, part := range rest {
+		path += "/" + url.PathEscape(part)
+	}
+	return strings.TrimRight(r.base.YamlConfig.Application.Client.ServerEndpoint, "/") + path
+}
+
+func (r *userRoleRepository) do(method, endpoint string, body interface{}, errCode string) response.UserRoleResponse {
+	var resp response.UserRoleResponse
+	if err := sendRequest(method, endpoint, body, &resp); err != nil {
+		resp.Code = errCode
+		resp.Message = err.Error()
+	}
+	return resp
+}
+
+func (r *userRoleRepository) ListUserRoles(user string) response.UserRoleResponse {
+	if user == "" {
+		return response.UserRoleResponse{Code: "USER_ROLE_LIST_VALIDATION_ERROR", Message: "user required"}
+	}
+	return r.do(http.MethodGet, r.endpoint(user), nil, "USER_ROLE_LIST_ERROR")
+}
+
+func (r *userRoleRepository) GrantUserRole(user, role string) response.UserRoleResponse {
+	if user == "" || role == "" {
+		return response.UserRoleResponse{Code: "USER_ROLE_GRANT_VALIDATION_ERROR", Message: "user and role required"}
+	}
+	return r.do(http.MethodPost, r.endpoint(user), request.RoleAssignmentRequest{Role: role}, "USER_ROLE_GRANT_ERROR")
+}
+
+func (r *userRoleRepository) RevokeUserRole(user, role string) response.UserRoleResponse {
+	if user == "" || role == "" {
+		return response.UserRoleResponse{Code: "USER_ROLE_REVOKE_VALIDATION_ERROR", Message: "user and role required"}
+	}
+	return r.do(http.MethodDelete, r.endpoint(user, role), nil, "USER_ROLE_REVOKE_ERROR")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 	"text/tabwriter"
 
@@ -17,6 +18,7 @@ import (
 
 type CommonUsecase interface {
 	Login(request request.LoginRequest) response.LoginResponse
+	VerifyMFA(request request.MFALoginRequest) response.LoginResponse
 	RefreshToken(refreshToken string) response.RefreshTokenResponse
 	Logout(accessToken string) response.CommonResponse
 	ValidateToken(accessToken string) response.ValidateTokenResponse
@@ -34,6 +36,9 @@ func NewCommonUsecase(conf config.BaseConfig) CommonUsecase {
 func (u *commonUsecase) Login(req request.LoginRequest) response.LoginResponse {
 	return u.repo.Login(req)
 }
+func (u *commonUsecase) VerifyMFA(req request.MFALoginRequest) response.LoginResponse {
+	return u.repo.VerifyMFA(req)
+}
 func (u *commonUsecase) RefreshToken(refreshToken string) response.RefreshTokenResponse {
 	return u.repo.RefreshToken(refreshToken)
 }
@@ -79,6 +84,30 @@ func tableString(v interface{}) string {
 		return repository.RolesTableStringAlias(data)
 	case *response.RoleResponse:
 		return repository.RolesTableStringAlias(*data)
+	case response.UserRoleResponse:
+		return userRolesTableString(data)
+	case *response.UserRoleResponse:
+		return userRolesTableString(*data)
+	case response.SessionResponse:
+		return sessionsTableString(data)
+	case *response.SessionResponse:
+		return sessionsTableString(*data)
+	case response.ServiceAccountResponse:
+		return serviceAccountsTableString(data)
+	case *response.ServiceAccountResponse:
+		return serviceAccountsTableString(*data)
+	case response.PersonalAccessTokenResponse:
+		return personalAccessTokensTableString(data)
+	case *response.PersonalAccessTokenResponse:
+		return personalAccessTokensTableString(*data)
+	case response.MFAResponse:
+		return mfaTableString(data)
+	case *response.MFAResponse:
+		return mfaTableString(*data)
+	case response.LoginLockoutResponse:
+		return lockoutsTableString(data)
+	case *response.LoginLockoutResponse:
+		return lockoutsTableString(*data)
 	case response.LoginResponse:
 		return loginTableString(data)
 	case *response.LoginResponse:
@@ -87,6 +116,10 @@ func tableString(v interface{}) string {
 		return refreshTableString(data)
 	case *response.RefreshTokenResponse:
 		return refreshTableString(*data)
+	case response.PolicyResponse:
+		return policiesTableString(data)
+	case *response.PolicyResponse:
+		return policiesTableString(*data)
 	case response.CommonResponse:
 		return commonTableString(data)
 	case *response.CommonResponse:
@@ -108,6 +141,9 @@ func loginTableString(res response.LoginResponse) string {
 	fmt.Fprintln(w, strings.Join([]string{"FIELD", "VALUE"}, "\t"))
 	fmt.Fprintf(w, "Code\t%s\n", res.Code)
 	fmt.Fprintf(w, "Message\t%s\n", res.Message)
+	if res.MFAToken != "" {
+		fmt.Fprintf(w, "MFAToken\t%s\n", res.MFAToken)
+	}
 	if res.User != nil {
 		fmt.Fprintf(w, "User\t%s (%s)\n", res.User.Name, res.User.Email)
 	}
@@ -151,6 +187,19 @@ func commonTableString(res response.CommonResponse) string {
 			fmt.Fprintf(w, "%d\t%s\t%s\n", c.ID, c.UUID, created)
 		}
 	}
+	writeFieldErrors(w, res.Errors)
 	w.Flush()
 	return buf.String()
 }
+
+// writeFieldErrors lists the per-field validation errors of a rejected request
+func writeFieldErrors(w io.Writer, errs []response.FieldError) {
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Join([]string{"FIELD", "ERROR", "DETAIL"}, "\t"))
+	for _, e := range errs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Field, e.Code, e.Message)
+	}
+}
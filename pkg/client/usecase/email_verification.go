@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+)
+
+type EmailVerificationUsecase interface {
+	Verify(req request.VerifyEmailRequest, format string) string
+	Resend(req request.ResendVerificationRequest, format string) string
+}
+
+type emailVerificationUsecase struct {
+	repo repository.EmailVerificationRepository
+}
+
+func NewEmailVerificationUsecase(conf config.BaseConfig) EmailVerificationUsecase {
+	return &emailVerificationUsecase{repo: repository.NewEmailVerificationRepository(conf)}
+}
+
+func (u *emailVerificationUsecase) Verify(req request.VerifyEmailRequest, format string) string {
+	return Format(format, u.repo.VerifyEmail(req))
+}
+func (u *emailVerificationUsecase) Resend(req request.ResendVerificationRequest, format string) string {
+	return Format(format, u.repo.ResendVerification(req))
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type LoginLockoutUsecase interface {
+	List(scope string, format string) string
+	Clear(scope, key string, format string) string
+}
+
+type loginLockoutUsecase struct {
+	repo repository.LoginLockoutRepository
+}
+
+func NewLoginLockoutUsecase(conf config.BaseConfig) LoginLockoutUsecase {
+	return &loginLockoutUsecase{repo: repository.NewLoginLockoutRepository(conf)}
+}
+
+func (u *loginLockoutUsecase) List(scope string, format string) string {
+	return Format(format, u.repo.ListLockouts(scope))
+}
+func (u *loginLockoutUsecase) Clear(scope, key string, format string) string {
+	return Format(format, u.repo.ClearLockout(scope, key))
+}
+
+func lockoutsTableString(res response.LoginLockoutResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	if len(res.Lockouts) == 0 {
+		return res.Message + "\n"
+	}
+	w, buf := newTabWriterBuf()
+	fmt.Fprintln(w, strings.Join([]string{"SCOPE", "KEY", "REASON", "FAILURES", "LEVEL", "LOCKED_UNTIL", "RETRY_AFTER"}, "\t"))
+	for _, l := range res.Lockouts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", l.Scope, l.Key, l.Reason, l.Failures, l.Level, formatUnix(l.LockedUntil), strconv.FormatInt(l.RetryAfter, 10)+"s")
+	}
+	w.Flush()
+	return buf.String()
+}
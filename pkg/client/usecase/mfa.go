@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type MFAUsecase interface {
+	Bootstrap(format string) string
+	GetMine(format string) string
+	EnrollMine(format string) string
+	ConfirmMine(req request.MFACodeRequest, format string) string
+	DisableMine(req request.MFACodeRequest, format string) string
+	ResetUser(userID string, format string) string
+}
+
+type mfaUsecase struct {
+	repo repository.MFARepository
+}
+
+func NewMFAUsecase(conf config.BaseConfig) MFAUsecase {
+	return &mfaUsecase{repo: repository.NewMFARepository(conf)}
+}
+
+func (u *mfaUsecase) Bootstrap(format string) string {
+	return Format(format, u.repo.BootstrapMFAForDB())
+}
+func (u *mfaUsecase) GetMine(format string) string {
+	return Format(format, u.repo.GetMyMFA())
+}
+func (u *mfaUsecase) EnrollMine(format string) string {
+	return Format(format, u.repo.EnrollMyMFA())
+}
+func (u *mfaUsecase) ConfirmMine(req request.MFACodeRequest, format string) string {
+	return Format(format, u.repo.ConfirmMyMFA(req))
+}
+func (u *mfaUsecase) DisableMine(req request.MFACodeRequest, format string) string {
+	return Format(format, u.repo.DisableMyMFA(req))
+}
+func (u *mfaUsecase) ResetUser(userID string, format string) string {
+	return Format(format, u.repo.ResetUserMFA(userID))
+}
+
+func mfaTableString(res response.MFAResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	w, buf := newTabWriterBuf()
+	fmt.Fprintln(w, strings.Join([]string{"FIELD", "VALUE"}, "\t"))
+	fmt.Fprintf(w, "Message\t%s\n", res.Message)
+	fmt.Fprintf(w, "Enabled\t%t\n", res.Enabled)
+	if res.Secret != "" {
+		fmt.Fprintf(w, "Secret\t%s\n", res.Secret)
+		fmt.Fprintf(w, "ProvisioningURI\t%s\n", res.ProvisioningURI)
+	}
+	w.Flush()
+	if len(res.RecoveryCodes) > 0 {
+		fmt.Fprintln(buf, "\nRecovery codes (shown only once):")
+		for _, code := range res.RecoveryCodes {
+			fmt.Fprintf(buf, "  %s\n", code)
+		}
+	}
+	return buf.String()
+}
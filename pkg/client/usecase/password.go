@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+)
+
+type PasswordUsecase interface {
+	Forgot(req request.ForgotPasswordRequest, format string) string
+	Reset(req request.ResetPasswordRequest, format string) string
+}
+
+type passwordUsecase struct {
+	repo repository.PasswordRepository
+}
+
+func NewPasswordUsecase(conf config.BaseConfig) PasswordUsecase {
+	return &passwordUsecase{repo: repository.NewPasswordRepository(conf)}
+}
+
+func (u *passwordUsecase) Forgot(req request.ForgotPasswordRequest, format string) string {
+	return Format(format, u.repo.ForgotPassword(req))
+}
+func (u *passwordUsecase) Reset(req request.ResetPasswordRequest, format string) string {
+	return Format(format, u.repo.ResetPassword(req))
+}
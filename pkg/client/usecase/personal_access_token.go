@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type PersonalAccessTokenUsecase interface {
+	Bootstrap(format string) string
+	ListMine(format string) string
+	CreateMine(req request.PersonalAccessTokenRequest, format string) string
+	RevokeMine(id string, format string) string
+}
+
+type personalAccessTokenUsecase struct {
+	repo repository.PersonalAccessTokenRepository
+}
+
+func NewPersonalAccessTokenUsecase(conf config.BaseConfig) PersonalAccessTokenUsecase {
+	return &personalAccessTokenUsecase{repo: repository.NewPersonalAccessTokenRepository(conf)}
+}
+
+func (u *personalAccessTokenUsecase) Bootstrap(format string) string {
+	return Format(format, u.repo.BootstrapPersonalAccessTokenForDB())
+}
+func (u *personalAccessTokenUsecase) ListMine(format string) string {
+	return Format(format, u.repo.ListMyTokens())
+}
+func (u *personalAccessTokenUsecase) CreateMine(req request.PersonalAccessTokenRequest, format string) string {
+	return Format(format, u.repo.CreateMyToken(req))
+}
+func (u *personalAccessTokenUsecase) RevokeMine(id string, format string) string {
+	return Format(format, u.repo.RevokeMyToken(id))
+}
+
+// personalAccessTokensTableString shows the token value only right after creation
+func personalAccessTokensTableString(res response.PersonalAccessTokenResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	if len(res.Tokens) == 0 {
+		return res.Message + "\n"
+	}
+	w, buf := newTabWriterBuf()
+	fmt.Fprintln(w, strings.Join([]string{"ID", "NAME", "PREFIX", "SCOPES", "EXPIRES_AT", "LAST_USED_AT"}, "\t"))
+	for _, t := range res.Tokens {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Prefix, strings.Join(t.Scopes, ","), formatTimePtr(t.ExpiresAt), formatTimePtr(t.LastUsedAt))
+	}
+	w.Flush()
+	for _, t := range res.Tokens {
+		if t.Token != "" {
+			fmt.Fprintf(buf, "\nToken (shown only once): %s\n", t.Token)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type PolicyUsecase interface {
+	Import(appPolicy, resourcePolicy string, replace bool, format string) string
+	Status(format string) string
+}
+
+type policyUsecase struct {
+	repo repository.PolicyRepository
+}
+
+func NewPolicyUsecase(conf config.BaseConfig) PolicyUsecase {
+	return &policyUsecase{repo: repository.NewPolicyRepository(conf)}
+}
+
+func (u *policyUsecase) Import(appPolicy, resourcePolicy string, replace bool, format string) string {
+	return Format(format, u.repo.ImportPolicyForDB(appPolicy, resourcePolicy, replace))
+}
+
+func (u *policyUsecase) Status(format string) string {
+	return Format(format, u.repo.GetPolicyStatus())
+}
+
+func policiesTableString(res response.PolicyResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	if len(res.PolicySets) == 0 {
+		return res.Message + "\n"
+	}
+	w, buf := newTabWriterBuf()
+	if res.Node == "" {
+		fmt.Fprintln(w, strings.Join([]string{"POLICY_SET", "SOURCE", "RULES"}, "\t"))
+		for _, s := range res.PolicySets {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", s.Name, s.Source, s.Rules)
+		}
+		w.Flush()
+		return buf.String()
+	}
+	fmt.Fprintf(buf, "Node: %s\n", res.Node)
+	fmt.Fprintln(w, strings.Join([]string{"POLICY_SET", "STORAGE", "WATCHER", "REVISION", "RULES", "DIGEST", "LOADED_AT"}, "\t"))
+	for _, s := range res.PolicySets {
+		var loadedAt int64
+		if s.LoadedAt != nil {
+			loadedAt = s.LoadedAt.Unix()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", s.Name, s.Storage, s.Watcher, s.Revision, s.Rules, shortDigest(s.Digest), formatUnix(loadedAt))
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// shortDigest keeps enough of a policy digest to compare nodes by eye
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
@@ -7,11 +7,15 @@ import (
 )
 
 type RoleUsecase interface {
-	ListInternal(id, format string) string
-	ListPrivate(id, format string) string
+	ListInternal(id string, effective bool, format string) string
+	ListPrivate(id string, effective bool, format string) string
 	Create(role string, perms []request.RolePermissionItem, format string) string
 	Update(role string, perms []request.RolePermissionItem, format string) string
 	Delete(role string, format string) string
+	HierarchyInternal(role string, format string) string
+	HierarchyPrivate(role string, format string) string
+	AddParent(role, parent string, format string) string
+	RemoveParent(role, parent string, format string) string
 }
 
 type roleUsecase struct{ repo repository.RoleRepository }
@@ -20,12 +24,12 @@ func NewRoleUsecase(conf config.BaseConfig) RoleUsecase {
 	return &roleUsecase{repo: repository.NewRoleRepository(conf)}
 }
 
-func (u *roleUsecase) ListInternal(id, format string) string {
-	resp := u.repo.ListRolesInternal(repository.RoleFilter{ID: id})
+func (u *roleUsecase) ListInternal(id string, effective bool, format string) string {
+	resp := u.repo.ListRolesInternal(repository.RoleFilter{ID: id, Effective: effective})
 	return Format(format, resp)
 }
-func (u *roleUsecase) ListPrivate(id, format string) string {
-	resp := u.repo.ListRolesPrivate(repository.RoleFilter{ID: id})
+func (u *roleUsecase) ListPrivate(id string, effective bool, format string) string {
+	resp := u.repo.ListRolesPrivate(repository.RoleFilter{ID: id, Effective: effective})
 	return Format(format, resp)
 }
 func (u *roleUsecase) Create(role string, perms []request.RolePermissionItem, format string) string {
@@ -40,3 +44,19 @@ func (u *roleUsecase) Delete(role string, format string) string {
 	resp := u.repo.DeleteRole(role)
 	return Format(format, resp)
 }
+func (u *roleUsecase) HierarchyInternal(role string, format string) string {
+	resp := u.repo.GetRoleHierarchyInternal(role)
+	return Format(format, resp)
+}
+func (u *roleUsecase) HierarchyPrivate(role string, format string) string {
+	resp := u.repo.GetRoleHierarchyPrivate(role)
+	return Format(format, resp)
+}
+func (u *roleUsecase) AddParent(role, parent string, format string) string {
+	resp := u.repo.AddRoleParent(role, parent)
+	return Format(format, resp)
+}
+func (u *roleUsecase) RemoveParent(role, parent string, format string) string {
+	resp := u.repo.RemoveRoleParent(role, parent)
+	return Format(format, resp)
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type ServiceAccountUsecase interface {
+	Bootstrap(format string) string
+	List(format string) string
+	Create(req request.ServiceAccountRequest, format string) string
+	Update(id string, req request.ServiceAccountRequest, format string) string
+	Delete(id string, format string) string
+	RotateSecret(id string, graceSeconds int, format string) string
+	DeleteSecret(id, clientID string, format string) string
+}
+
+type serviceAccountUsecase struct {
+	repo repository.ServiceAccountRepository
+}
+
+func NewServiceAccountUsecase(conf config.BaseConfig) ServiceAccountUsecase {
+	return &serviceAccountUsecase{repo: repository.NewServiceAccountRepository(conf)}
+}
+
+func (u *serviceAccountUsecase) Bootstrap(format string) string {
+	return Format(format, u.repo.BootstrapServiceAccountForDB())
+}
+func (u *serviceAccountUsecase) List(format string) string {
+	return Format(format, u.repo.ListServiceAccounts())
+}
+func (u *serviceAccountUsecase) Create(req request.ServiceAccountRequest, format string) string {
+	return Format(format, u.repo.CreateServiceAccount(req))
+}
+func (u *serviceAccountUsecase) Update(id string, req request.ServiceAccountRequest, format string) string {
+	return Format(format, u.repo.UpdateServiceAccount(id, req))
+}
+func (u *serviceAccountUsecase) Delete(id string, format string) string {
+	return Format(format, u.repo.DeleteServiceAccount(id))
+}
+func (u *serviceAccountUsecase) RotateSecret(id string, graceSeconds int, format string) string {
+	return Format(format, u.repo.RotateSecret(id, request.ServiceAccountSecretRequest{GraceSeconds: graceSeconds}))
+}
+func (u *serviceAccountUsecase) DeleteSecret(id, clientID string, format string) string {
+	return Format(format, u.repo.DeleteSecret(id, clientID))
+}
+
+// serviceAccountsTableString prints one row per credential; a newly issued
+// client secret is shown in its own column since it cannot be fetched again.
+func serviceAccountsTableString(res response.ServiceAccountResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	if len(res.ServiceAccounts) == 0 {
+		return res.Message + "\n"
+	}
+	w, buf := newTabWriterBuf()
+	fmt.Fprintln(w, strings.Join([]string{"ID", "UUID", "NAME", "ROLE", "CLIENT_ID", "CLIENT_SECRET", "EXPIRES_AT", "LAST_USED_AT"}, "\t"))
+	for _, sa := range res.ServiceAccounts {
+		if len(sa.Credentials) == 0 {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t-\t-\t-\t-\n", sa.ID, sa.UUID, sa.Name, sa.Role)
+			continue
+		}
+		for _, cred := range sa.Credentials {
+			secret := cred.ClientSecret
+			if secret == "" {
+				secret = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", sa.ID, sa.UUID, sa.Name, sa.Role, cred.ClientID, secret, formatTimePtr(cred.ExpiresAt), formatTimePtr(cred.LastUsedAt))
+		}
+	}
+	w.Flush()
+	return buf.String()
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type SessionUsecase interface {
+	ListMine(format string) string
+	RevokeMine(sessionID string, format string) string
+	RevokeAllMine(exceptCurrent bool, format string) string
+	ListForUser(userID string, format string) string
+	RevokeForUser(userID, sessionID string, format string) string
+	RevokeAllForUser(userID string, format string) string
+}
+
+type sessionUsecase struct{ repo repository.SessionRepository }
+
+func NewSessionUsecase(conf config.BaseConfig) SessionUsecase {
+	return &sessionUsecase{repo: repository.NewSessionRepository(conf)}
+}
+
+func (u *sessionUsecase) ListMine(format string) string {
+	return Format(format, u.repo.ListMySessions())
+}
+func (u *sessionUsecase) RevokeMine(sessionID string, format string) string {
+	return Format(format, u.repo.RevokeMySession(sessionID))
+}
+func (u *sessionUsecase) RevokeAllMine(exceptCurrent bool, format string) string {
+	return Format(format, u.repo.RevokeMySessions(exceptCurrent))
+}
+func (u *sessionUsecase) ListForUser(userID string, format string) string {
+	return Format(format, u.repo.ListUserSessions(userID))
+}
+func (u *sessionUsecase) RevokeForUser(userID, sessionID string, format string) string {
+	return Format(format, u.repo.RevokeUserSession(userID, sessionID))
+}
+func (u *sessionUsecase) RevokeAllForUser(userID string, format string) string {
+	return Format(format, u.repo.RevokeUserSessions(userID))
+}
+
+func sessionsTableString(res response.SessionResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	if len(res.Sessions) == 0 {
+		return res.Message + "\n"
+	}
+	w, buf := newTabWriterBuf()
+	fmt.Fprintln(w, strings.Join([]string{"ID", "CURRENT", "IP_ADDRESS", "USER_AGENT", "ISSUED_AT", "LAST_SEEN_AT"}, "\t"))
+	for _, s := range res.Sessions {
+		current := ""
+		if s.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, current, s.IPAddress, s.UserAgent, formatUnix(s.IssuedAt), formatUnix(s.LastSeenAt))
+	}
+	w.Flush()
+	return buf.String()
+}
+
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format(time.RFC3339)
+}
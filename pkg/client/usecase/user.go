@@ -12,6 +12,7 @@ import (
 
 type UserUsecase interface {
 	Bootstrap(request request.UserRequest, format string) string
+	Migrate(request request.UserRequest, format string) string
 	GetInternal(request request.UserRequest, format string) string
 	GetPrivate(request request.UserRequest, format string) string
 	CreatePublic(request request.UserRequest, format string) string
@@ -35,6 +36,11 @@ func (u *userUsecase) Bootstrap(req request.UserRequest, format string) string {
 	return Format(format, resp)
 }
 
+func (u *userUsecase) Migrate(req request.UserRequest, format string) string {
+	resp := u.repo.MigrateUserForDB(req)
+	return Format(format, resp)
+}
+
 func (u *userUsecase) GetInternal(req request.UserRequest, format string) string {
 	resp := u.repo.GetUserForInternal(req)
 	return Format(format, resp)
@@ -82,6 +88,7 @@ func usersTableString(res response.UserResponse) string {
 	for _, u := range res.Users {
 		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.ID, u.UUID, u.Email, u.Name)
 	}
+	writeFieldErrors(w, res.Errors)
 	w.Flush()
 	return buf.String()
 }
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/client/repository"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+)
+
+type UserRoleUsecase interface {
+	List(user string, format string) string
+	Grant(user, role string, format string) string
+	Revoke(user, role string, format string) string
+}
+
+type userRoleUsecase struct {
+	repo repository.UserRoleRepository
+}
+
+func NewUserRoleUsecase(conf config.BaseConfig) UserRoleUsecase {
+	return &userRoleUsecase{repo: repository.NewUserRoleRepository(conf)}
+}
+
+func (u *userRoleUsecase) List(user string, format string) string {
+	return Format(format, u.repo.ListUserRoles(user))
+}
+func (u *userRoleUsecase) Grant(user, role string, format string) string {
+	return Format(format, u.repo.GrantUserRole(user, role))
+}
+func (u *userRoleUsecase) Revoke(user, role string, format string) string {
+	return Format(format, u.repo.RevokeUserRole(user, role))
+}
+
+func userRolesTableString(res response.UserRoleResponse) string {
+	if res.Code != "SUCCESS" {
+		return fmt.Sprintf("Code: %s\nMessage: %s\n", res.Code, res.Message)
+	}
+	if len(res.Roles) == 0 {
+		return res.Message + "\n"
+	}
+	w, buf := newTabWriterBuf()
+	fmt.Fprintln(w, strings.Join([]string{"ROLE", "SOURCE", "GRANTED_BY", "CREATED_AT"}, "\t"))
+	for _, r := range res.Roles {
+		var createdAt int64
+		if r.CreatedAt != nil {
+			createdAt = r.CreatedAt.Unix()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Role, r.Source, r.GrantedBy, formatUnix(createdAt))
+	}
+	w.Flush()
+	return buf.String()
+}
@@ -32,19 +32,62 @@ func calculateMaxCodeLength() int {
 		// Middleware Logger With Config codes
 		MLWC1, MLWC2, MLWC3, MLWC4, MLWC5,
 
+		// Middleware Rate Limit codes
+		MRL1, MRL2,
+
+		// Middleware Group Authorization codes
+		MGA1, MGA2,
+
 		// Config codes
 		CNDBC1, CNDBC2, CNDBC3,
 
 		// Repository codes
 		RCHK1,
-		RURP1, RUCR1, RUUP1, RUDL1, RULS1, RUCT1,
+		RURP1, RUCR1, RUUP1, RUDL1, RULS1, RUCT1, RUGE1,
+		RJKR1, RJKR2, RJKR3,
+		RACA1, RLDP1, RLDP2, RLDP3, RRAL1,
+		RPWR1, RPWR2, RPWR3,
 
 		// Usecase codes
 		UUGU1, UUCR1, UUCR2, UUUP1, UUUP2, UUDL1, UULS1, UUCT1,
+		USCU1, USUU1, USDU1, USCG1, USUG1, USDG1, USWN1,
 
 		// Controller codes - User Public
-		UCPCU0, UCPCU1, UCPCU2, UCPCU3, UCPCU4, UCPCU5, UCPCU6,
+		UCPCU0, UCPCU1, UCPCU2, UCPCU3, UCPCU4, UCPCU5, UCPCU6, UCPCU7,
 		UCPGU0, UCPGU1, UCPGU2, UCPGU3,
+
+		// Controller codes - Password Public
+		PCPFP1, PCPFP2, PCPRP1, PCPRP2,
+
+		// Controller codes - Email Verification Public
+		VCPVE1, VCPVE2, VCPRV1, VCPSE1,
+
+		// Controller codes - Login lockout
+		CCPLO1, CCPLO2,
+
+		// Controller codes - Password rehash
+		CCPRH1, CCPRH2,
+
+		// Controller codes - Password policy
+		CPPCP1, CPPRP1,
+
+		// Controller codes - Token introspection / revocation
+		TCPIN1, TCPRV1, TCPRV2,
+
+		// Controller codes - Impersonation
+		ICPET1, ICPET2, ICPET3,
+
+		// Controller codes - Federated login
+		FCPCB1, FCPCB2, FCPCB3, FCPCB4, FCIUL1,
+
+		// Controller codes - SCIM provisioning
+		SCSRQ1, SCSER1,
+
+		// Controller codes - Role assignments
+		RCPGR1, RCPRR1, RCPRR2,
+
+		// Controller codes - Users
+		UCDLU1,
 	}
 
 	maxLen := 0
@@ -76,6 +119,18 @@ var (
 	MLWC5 = MCode{"MLWC5", "Request server error"}
 )
 
+// Middleware Rate Limit codes
+var (
+	MRL1 = MCode{"MRL1", "Rate limit storage failed"}
+	MRL2 = MCode{"MRL2", "Rate limit exceeded"}
+)
+
+// Middleware Group Authorization codes
+var (
+	MGA1 = MCode{"MGA1", "Group membership could not be loaded"}
+	MGA2 = MCode{"MGA2", "Group permission denied"}
+)
+
 // Config codes
 var (
 	CNDBC1 = MCode{"C-NDBC-1", "Attempting database connection"}
@@ -92,6 +147,18 @@ var (
 	RUDL1 = MCode{"R-UDL-1", "User delete operation"}
 	RULS1 = MCode{"R-ULS-1", "User list operation"}
 	RUCT1 = MCode{"R-UCT-1", "User count operation"}
+	RUGE1 = MCode{"R-UGE-1", "User get by email operation"}
+	RJKR1 = MCode{"R-JKR-1", "JWT keyring loaded"}
+	RJKR2 = MCode{"R-JKR-2", "JWT signing key rotated"}
+	RJKR3 = MCode{"R-JKR-3", "JWT keyring error"}
+	RACA1 = MCode{"R-ACA-1", "Authenticator unavailable"}
+	RLDP1 = MCode{"R-LDP-1", "Directory user provisioned"}
+	RLDP2 = MCode{"R-LDP-2", "Existing user adopted by the directory"}
+	RLDP3 = MCode{"R-LDP-3", "Existing user not managed by the directory"}
+	RRAL1 = MCode{"R-RAL-1", "Role assignments could not be loaded"}
+	RPWR1 = MCode{"R-PWR-1", "Policy reloaded"}
+	RPWR2 = MCode{"R-PWR-2", "Policy change could not be broadcast"}
+	RPWR3 = MCode{"R-PWR-3", "Policy change could not be applied"}
 )
 
 // Usecase codes - User
@@ -106,6 +173,17 @@ var (
 	UUCT1 = MCode{"U-UCT-1", "Usecase count users"}
 )
 
+// Usecase codes - SCIM provisioning
+var (
+	USCU1 = MCode{"U-SCU-1", "SCIM user created"}
+	USUU1 = MCode{"U-SUU-1", "SCIM user updated"}
+	USDU1 = MCode{"U-SDU-1", "SCIM user deleted"}
+	USCG1 = MCode{"U-SCG-1", "SCIM group created"}
+	USUG1 = MCode{"U-SUG-1", "SCIM group updated"}
+	USDG1 = MCode{"U-SDG-1", "SCIM group deleted"}
+	USWN1 = MCode{"U-SWN-1", "SCIM provisioning side effect failed"}
+)
+
 // Gin Log codes
 var (
 	GINLOG = MCode{"GINLOG", "Gin framework log"}
@@ -120,6 +198,7 @@ var (
 	UCPCU4 = MCode{"UCPCU4", "Password hash failed"}
 	UCPCU5 = MCode{"UCPCU5", "User created"}
 	UCPCU6 = MCode{"UCPCU6", "Response sent"}
+	UCPCU7 = MCode{"UCPCU7", "User create failed"}
 )
 
 // Controller codes - User Public Get Users
@@ -129,3 +208,78 @@ var (
 	UCPGU2 = MCode{"UCPGU2", "Users retrieved"}
 	UCPGU3 = MCode{"UCPGU3", "Response sent"}
 )
+
+// Controller codes - Password Public
+var (
+	PCPFP1 = MCode{"PCPFP1", "Password reset requested"}
+	PCPFP2 = MCode{"PCPFP2", "Password reset email failed"}
+	PCPRP1 = MCode{"PCPRP1", "Password reset completed"}
+	PCPRP2 = MCode{"PCPRP2", "Token revocation after password reset failed"}
+)
+
+// Controller codes - Email Verification Public
+var (
+	VCPVE1 = MCode{"VCPVE1", "Email address verified"}
+	VCPVE2 = MCode{"VCPVE2", "Welcome email failed"}
+	VCPRV1 = MCode{"VCPRV1", "Verification email resent"}
+	VCPSE1 = MCode{"VCPSE1", "Verification email failed"}
+)
+
+// Controller codes - Login lockout
+var (
+	CCPLO1 = MCode{"CCPLO1", "Login lockout storage failed"}
+	CCPLO2 = MCode{"CCPLO2", "Login locked out"}
+)
+
+// Controller codes - Password rehash
+var (
+	CCPRH1 = MCode{"CCPRH1", "Password hash upgraded"}
+	CCPRH2 = MCode{"CCPRH2", "Password hash upgrade failed"}
+)
+
+// Controller codes - Password policy
+var (
+	CPPCP1 = MCode{"CPPCP1", "Password rejected by policy"}
+	CPPRP1 = MCode{"CPPRP1", "Password history update failed"}
+)
+
+// Controller codes - Token introspection / revocation
+var (
+	TCPIN1 = MCode{"TCPIN1", "Token introspection failed"}
+	TCPRV1 = MCode{"TCPRV1", "Token revoked"}
+	TCPRV2 = MCode{"TCPRV2", "Token revocation failed"}
+)
+
+// Controller codes - Impersonation
+var (
+	ICPET1 = MCode{"ICPET1", "Impersonation token issued"}
+	ICPET2 = MCode{"ICPET2", "Impersonation denied"}
+	ICPET3 = MCode{"ICPET3", "Impersonation token exchange failed"}
+)
+
+// Controller codes - Federated login
+var (
+	FCPCB1 = MCode{"FCPCB1", "Federated login"}
+	FCPCB2 = MCode{"FCPCB2", "Federated identity linked"}
+	FCPCB3 = MCode{"FCPCB3", "Federated login failed"}
+	FCPCB4 = MCode{"FCPCB4", "Federated user provisioned"}
+	FCIUL1 = MCode{"FCIUL1", "Federated identity unlinked"}
+)
+
+// Controller codes - SCIM provisioning
+var (
+	SCSRQ1 = MCode{"SCSRQ1", "SCIM request"}
+	SCSER1 = MCode{"SCSER1", "SCIM request failed"}
+)
+
+// Controller codes - Role assignments
+var (
+	RCPGR1 = MCode{"RCPGR1", "Role granted"}
+	RCPRR1 = MCode{"RCPRR1", "Role revoked"}
+	RCPRR2 = MCode{"RCPRR2", "Sessions of a revoked role holder could not be ended"}
+)
+
+// Controller codes - Users
+var (
+	UCDLU1 = MCode{"UCDLU1", "Sessions of a deleted user could not be ended"}
+)
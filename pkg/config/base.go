@@ -98,9 +98,253 @@ type Redis struct {
 }
 
 type Server struct {
-	Admin     Admin  `yaml:"admin"`
-	JWTSecret string `yaml:"jwt_secret"`
-	LogLevel  string `yaml:"log_level"` // Added: debug / info / warn / error
+	Admin             Admin             `yaml:"admin"`
+	JWTSecret         string            `yaml:"jwt_secret"`
+	JWT               JWT               `yaml:"jwt"`
+	OIDC              OIDC              `yaml:"oidc"`
+	MFA               MFA               `yaml:"mfa"`
+	PasswordReset     PasswordReset     `yaml:"password_reset"`
+	EmailVerification EmailVerification `yaml:"email_verification"`
+	LoginLockout      LoginLockout      `yaml:"login_lockout"`
+	RateLimit         RateLimit         `yaml:"rate_limit"`
+	TrustedProxies    []string          `yaml:"trusted_proxies"` // IPs / CIDRs whose X-Forwarded-For is used as the client IP, default none
+	PasswordHashing   PasswordHashing   `yaml:"password_hashing"`
+	PasswordPolicy    PasswordPolicy    `yaml:"password_policy"`
+	Impersonation     Impersonation     `yaml:"impersonation"`
+	Authenticators    []string          `yaml:"authenticators"` // password login backends tried in order: local / ldap, default [local]
+	LDAP              LDAP              `yaml:"ldap"`
+	Federation        Federation        `yaml:"federation"`
+	SCIM              SCIM              `yaml:"scim"`
+	Casbin            Casbin            `yaml:"casbin"`
+	LogLevel          string            `yaml:"log_level"` // Added: debug / info / warn / error
+}
+
+// JWT holds token signing and verification settings.
+// When algorithm is empty, HS256 with jwt_secret is used (legacy behaviour).
+// With an asymmetric algorithm, kid-less tokens signed with jwt_secret are only
+// accepted when accept_legacy_hs256 is set, and only until legacy_hs256_until.
+type JWT struct {
+	Algorithm             string   `yaml:"algorithm"`               // HS256 / RS256 / ES256 / EdDSA
+	Issuer                string   `yaml:"issuer"`                  // iss claim, verified when set
+	Audience              []string `yaml:"audience"`                // aud claim, verified when set
+	ClockSkewSeconds      int      `yaml:"clock_skew_seconds"`      // leeway applied to exp / nbf
+	RotationIntervalHours int      `yaml:"rotation_interval_hours"` // 0 disables scheduled rotation
+	RetiredKeyTTLHours    int      `yaml:"retired_key_ttl_hours"`   // how long rotated keys keep verifying
+	Keys                  []JWTKey `yaml:"keys"`                    // statically configured keys
+	AcceptLegacyHS256     bool     `yaml:"accept_legacy_hs256"`     // keep verifying jwt_secret tokens after leaving HS256
+	LegacyHS256Until      string   `yaml:"legacy_hs256_until"`      // RFC 3339 retirement deadline, required with accept_legacy_hs256
+	KeyEncryptionKey      string   `yaml:"key_encryption_key"`      // base64 AES-256 key encrypting generated keys stored in Redis
+}
+
+// JWTKey is a statically configured signing key.
+// Asymmetric keys are loaded from a PEM file, HS256 keys use secret.
+type JWTKey struct {
+	Kid            string `yaml:"kid"`
+	Algorithm      string `yaml:"algorithm"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	Secret         string `yaml:"secret"`
+	Status         string `yaml:"status"` // active (default) / retired
+}
+
+// OIDC enables OpenID Connect provider mode (authorization code + PKCE).
+// ID tokens are signed with the JWT keyring, so an asymmetric algorithm is
+// required for relying parties to verify them through the JWKS endpoint.
+type OIDC struct {
+	Enabled            bool         `yaml:"enabled"`
+	Issuer             string       `yaml:"issuer"`                // defaults to jwt.issuer
+	AuthCodeTTLSeconds int          `yaml:"auth_code_ttl_seconds"` // default 60
+	IDTokenTTLSeconds  int          `yaml:"id_token_ttl_seconds"`  // default 3600
+	Clients            []OIDCClient `yaml:"clients"`
+}
+
+// OIDCClient is a registered relying party.
+// Public clients (SPA, native) have no secret and must use PKCE.
+type OIDCClient struct {
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"` // plain or bcrypt hash ($2...)
+	Name         string   `yaml:"name"`
+	RedirectURIs []string `yaml:"redirect_uris"`
+	Scopes       []string `yaml:"scopes"` // allowed scopes, empty = openid profile email
+	Public       bool     `yaml:"public"`
+	SkipConsent  bool     `yaml:"skip_consent"` // first-party clients
+}
+
+// MFA configures TOTP multi-factor authentication.
+// Users opt in individually; the challenge between password and code is kept in Redis.
+type MFA struct {
+	Issuer              string `yaml:"issuer"`                // issuer label in authenticator apps, default "Locky"
+	ChallengeTTLSeconds int    `yaml:"challenge_ttl_seconds"` // default 300
+}
+
+// PasswordReset configures forgotten-password emails.
+// Reset tokens and request counters are kept in Redis.
+type PasswordReset struct {
+	ResetURL        string `yaml:"reset_url"`         // page that receives ?token=..., e.g. https://app.example.com/reset-password
+	TokenTTLSeconds int    `yaml:"token_ttl_seconds"` // default 1800
+	MaxRequests     int    `yaml:"max_requests"`      // per client IP and per email within the window, default 5
+	WindowSeconds   int    `yaml:"window_seconds"`    // default 900
+}
+
+// EmailVerification configures confirmation of self-registered email addresses.
+// Verification links carry a signed token, resends are throttled in Redis.
+type EmailVerification struct {
+	RequireForLogin       bool   `yaml:"require_for_login"`       // reject logins until the address is verified
+	VerifyURL             string `yaml:"verify_url"`              // page that receives ?token=..., e.g. https://app.example.com/verify-email
+	TokenTTLSeconds       int    `yaml:"token_ttl_seconds"`       // default 86400
+	ResendIntervalSeconds int    `yaml:"resend_interval_seconds"` // minimum time between two emails per user, default 60
+}
+
+// LoginLockout configures brute-force protection of password logins.
+// Failure counters and lockouts are kept in Redis; protection is on unless disabled.
+type LoginLockout struct {
+	Disabled          bool `yaml:"disabled"`
+	MaxAttempts       int  `yaml:"max_attempts"`        // failures per account within the window before a lockout, default 5
+	IPMaxAttempts     int  `yaml:"ip_max_attempts"`     // failures per client IP within the window before a lockout, default 20
+	WindowSeconds     int  `yaml:"window_seconds"`      // how long failures are counted, default 900
+	BackoffSeconds    int  `yaml:"backoff_seconds"`     // delay after the second failure of an account, doubled per failure, default 1, -1 disables
+	LockoutSeconds    int  `yaml:"lockout_seconds"`     // first lockout, doubled for each repeated lockout, default 900
+	MaxLockoutSeconds int  `yaml:"max_lockout_seconds"` // upper bound of a lockout, default 86400
+}
+
+// RateLimit configures request limits of the public, internal and private API groups
+// and of the credential endpoints (auth: login, MFA, refresh and client_credentials). Counters are kept in Redis so all instances share
+// them; store "memory" keeps them per process for single-node runs. A tier without
+// requests is not limited, except auth, which then uses a built-in default.
+type RateLimit struct {
+	Store    string           `yaml:"store"` // redis (default) / memory
+	Auth     RateLimitRule    `yaml:"auth"`  // default 60 per minute per ip, requests < 0 disables
+	Public   RateLimitRule    `yaml:"public"`
+	Internal RateLimitRule    `yaml:"internal"`
+	Private  RateLimitRule    `yaml:"private"`
+	Routes   []RateLimitRoute `yaml:"routes"` // override the tier limit of single routes
+}
+
+// RateLimitRule allows requests per key within a sliding window of window_seconds.
+type RateLimitRule struct {
+	Requests      int    `yaml:"requests"`       // 0 = unlimited
+	WindowSeconds int    `yaml:"window_seconds"` // default 60
+	KeyBy         string `yaml:"key_by"`         // ip (default) / user / role, unauthenticated requests fall back to ip
+}
+
+// RateLimitRoute is a rule for one route with its own counters.
+type RateLimitRoute struct {
+	Method        string `yaml:"method"` // empty matches every method
+	Path          string `yaml:"path"`   // route pattern, e.g. /v1/internal/user/:id
+	RateLimitRule `yaml:",inline"`
+}
+
+// PasswordHashing selects how user passwords are hashed.
+// Hashes carry their algorithm and parameters, so older hashes keep verifying
+// and are replaced with the configured ones on the next successful login.
+type PasswordHashing struct {
+	Algorithm  string   `yaml:"algorithm"`   // argon2id (default) / bcrypt
+	BcryptCost int      `yaml:"bcrypt_cost"` // default 10
+	Argon2id   Argon2id `yaml:"argon2id"`
+}
+
+// Argon2id parameters, see RFC 9106.
+type Argon2id struct {
+	MemoryKiB   uint32 `yaml:"memory_kib"`  // default 19456 (19 MiB)
+	Iterations  uint32 `yaml:"iterations"`  // default 2
+	Parallelism uint8  `yaml:"parallelism"` // default 1
+	SaltLength  uint32 `yaml:"salt_length"` // bytes, default 16
+	KeyLength   uint32 `yaml:"key_length"`  // bytes, default 32
+}
+
+// PasswordPolicy defines the rules for new passwords. It is enforced on registration,
+// admin-created users, user updates and password resets.
+type PasswordPolicy struct {
+	MinLength            int      `yaml:"min_length"`             // default 8
+	MaxLength            int      `yaml:"max_length"`             // default 128
+	CharacterClasses     []string `yaml:"character_classes"`      // required classes: uppercase / lowercase / number / special; omitted = all four, [] = none
+	MaxAgeDays           int      `yaml:"max_age_days"`           // logins with an older password are refused until it is reset, 0 = no expiry
+	HistorySize          int      `yaml:"history_size"`           // reject the current and previous passwords up to this count, 0 = off
+	BreachedPasswordsDir string   `yaml:"breached_passwords_dir"` // offline k-anonymity range files: <SHA-1 prefix>[.txt] with SUFFIX:COUNT lines
+	BreachedMinCount     int      `yaml:"breached_min_count"`     // breach occurrences before a password is rejected, default 1
+}
+
+// Impersonation lets admins obtain a short-lived token for another user through
+// token exchange (RFC 8693). The token carries an act claim naming the admin.
+type Impersonation struct {
+	Enabled         bool                `yaml:"enabled"`
+	TokenTTLSeconds int                 `yaml:"token_ttl_seconds"` // default 900
+	Rules           []ImpersonationRule `yaml:"rules"`             // omitted = admins may impersonate non-admin users
+}
+
+// ImpersonationRule allows the actors to impersonate the targets.
+// Entries are email addresses, "role:<name>" or "*" for anyone.
+type ImpersonationRule struct {
+	Actors  []string `yaml:"actors"`
+	Targets []string `yaml:"targets"`
+}
+
+// LDAP authenticates password logins against a directory. The user is searched
+// by email with the service account, then bound with the submitted password.
+// Users are provisioned on their first login.
+type LDAP struct {
+	URL                string          `yaml:"url"` // ldap://host:389 or ldaps://host:636
+	StartTLS           bool            `yaml:"start_tls"`
+	InsecureSkipVerify bool            `yaml:"insecure_skip_verify"`
+	BindDN             string          `yaml:"bind_dn"` // service account used for the search, empty = anonymous
+	BindPassword       string          `yaml:"bind_password"`
+	BaseDN             string          `yaml:"base_dn"`
+	UserFilter         string          `yaml:"user_filter"`       // %s is replaced by the escaped email, default (&(objectClass=person)(mail=%s))
+	EmailAttribute     string          `yaml:"email_attribute"`   // default mail
+	NameAttribute      string          `yaml:"name_attribute"`    // default cn
+	GroupAttribute     string          `yaml:"group_attribute"`   // group DNs of the user, default memberOf
+	GroupRoles         []LDAPGroupRole `yaml:"group_roles"`       // first matching group decides the role
+	TimeoutSeconds     int             `yaml:"timeout_seconds"`   // default 5
+	AdoptLocalUsers    bool            `yaml:"adopt_local_users"` // migration: turn local users with a directory email into ldap users
+}
+
+// LDAPGroupRole maps members of a directory group to a role
+type LDAPGroupRole struct {
+	Group string `yaml:"group"` // group DN, compared case-insensitively
+	Role  string `yaml:"role"`
+}
+
+// Federation lets users sign in through upstream OpenID Connect providers.
+// The flow state is kept in Redis; external identities are linked to users
+// in the identity_links table.
+type Federation struct {
+	StateTTLSeconds int                `yaml:"state_ttl_seconds"` // default 600
+	Providers       []UpstreamProvider `yaml:"providers"`
+}
+
+// UpstreamProvider is an OpenID Connect provider users can sign in with.
+// Its endpoints and signing keys are discovered from the issuer.
+type UpstreamProvider struct {
+	Name          string   `yaml:"name"` // used in URLs and identity links
+	DisplayName   string   `yaml:"display_name"`
+	Issuer        string   `yaml:"issuer"` // <issuer>/.well-known/openid-configuration
+	ClientID      string   `yaml:"client_id"`
+	ClientSecret  string   `yaml:"client_secret"`
+	RedirectURL   string   `yaml:"redirect_url"`   // .../v1/share/common/federation/<name>/callback
+	Scopes        []string `yaml:"scopes"`         // default openid email profile
+	LinkByEmail   bool     `yaml:"link_by_email"`  // link unknown identities to the user with the same verified email
+	AutoProvision bool     `yaml:"auto_provision"` // create users for unknown identities with a verified email
+}
+
+// SCIM enables the SCIM 2.0 provisioning API under /scim/v2. Provisioning
+// clients authenticate with their own bearer tokens instead of user JWTs.
+type SCIM struct {
+	Enabled       bool        `yaml:"enabled"`
+	Tokens        []SCIMToken `yaml:"tokens"`
+	MemberRole    string      `yaml:"member_role"`     // group role of members added through SCIM, default member
+	MaxFilterScan int         `yaml:"max_filter_scan"` // rows examined for filters the database cannot evaluate, default 10000
+}
+
+// SCIMToken is the bearer credential of a provisioning client.
+// Only the SHA-256 hash of the token is configured.
+type SCIMToken struct {
+	Name        string `yaml:"name"`
+	TokenSHA256 string `yaml:"token_sha256"` // hex, e.g. printf %s "$TOKEN" | sha256sum
+}
+
+// Casbin selects where the app and resource policies are stored.
+type Casbin struct {
+	Storage              string `yaml:"storage"`                // file (default, etc/casbin/*/policy.csv) / database (casbin_rules table)
+	WatchIntervalSeconds int    `yaml:"watch_interval_seconds"` // how often file storage checks policy.csv for edits, default 5, negative disables
 }
 
 type Mail struct {
@@ -290,7 +534,8 @@ func NewDBConnection(conf YamlConfig, logger LoggerInterface) *gorm.DB {
 		"db":   conf.MySQL.Db,
 	})
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError maps driver errors such as duplicate keys to gorm.ErrDuplicatedKey
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		logger.ERROR(MCode{Code: "C-NDBC-3", Message: "Failed to connect"}, fmt.Sprintf("%v", err), map[string]interface{}{
 			"host": conf.MySQL.Host,
@@ -0,0 +1,43 @@
+package model
+
+// Casbin policy sets stored in the casbin_rules table
+const (
+	PolicySetApp       = "locky"     // app-wide permissions (etc/casbin/locky)
+	PolicySetResources = "resources" // group-scoped permissions (etc/casbin/resources)
+)
+
+// CasbinRules is one policy line (p or g) of a policy set, e.g.
+// "p, owner, member, write" is stored as PType p, V0 owner, V1 member, V2 write.
+type CasbinRules struct {
+	ID        uint   `gorm:"primaryKey,autoIncrement"`
+	PolicySet string `gorm:"uniqueIndex:idx_casbin_rules_line;size:32"`
+	PType     string `gorm:"column:ptype;uniqueIndex:idx_casbin_rules_line;size:16"`
+	V0        string `gorm:"uniqueIndex:idx_casbin_rules_line;size:100"`
+	V1        string `gorm:"uniqueIndex:idx_casbin_rules_line;size:100"`
+	V2        string `gorm:"uniqueIndex:idx_casbin_rules_line;size:100"`
+	V3        string `gorm:"uniqueIndex:idx_casbin_rules_line;size:100"`
+	V4        string `gorm:"uniqueIndex:idx_casbin_rules_line;size:100"`
+	V5        string `gorm:"uniqueIndex:idx_casbin_rules_line;size:100"`
+}
+
+// NewCasbinRule builds the row of a policy line. Values beyond V5 are dropped.
+func NewCasbinRule(policySet, ptype string, rule []string) CasbinRules {
+	line := CasbinRules{PolicySet: policySet, PType: ptype}
+	values := []*string{&line.V0, &line.V1, &line.V2, &line.V3, &line.V4, &line.V5}
+	for i, v := range rule {
+		if i >= len(values) {
+			break
+		}
+		*values[i] = v
+	}
+	return line
+}
+
+// Rule returns the values of the policy line without trailing empty ones
+func (r CasbinRules) Rule() []string {
+	rule := []string{r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
+	for len(rule) > 0 && rule[len(rule)-1] == "" {
+		rule = rule[:len(rule)-1]
+	}
+	return rule
+}
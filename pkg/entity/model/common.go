@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type Commons struct {
 	ID        uint       `json:"id"`
@@ -13,14 +17,123 @@ type Commons struct {
 
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
-	Jti       string `json:"jti"`
-	UserID    uint   `json:"user_id"`
-	UUID      string `json:"uuid"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	Role      string `json:"role,omitempty"`
-	IssuedAt  int64  `json:"iat"`
-	ExpiresAt int64  `json:"exp"`
+	Jti             string   `json:"jti"`
+	Subject         string   `json:"sub,omitempty"` // user UUID
+	UserID          uint     `json:"user_id"`
+	UUID            string   `json:"uuid"`
+	Email           string   `json:"email"`
+	Name            string   `json:"name"`
+	Role            string   `json:"role,omitempty"`      // primary role, kept for clients reading a single role
+	Roles           []string `json:"roles,omitempty"`     // all global roles, evaluated by CasbinAuthorization
+	ClientID        string   `json:"client_id,omitempty"` // service account client (client_credentials grant)
+	TokenUse        string   `json:"token_use,omitempty"` // access / refresh / id / pat / email_verify
+	Scope           string   `json:"scope,omitempty"`     // personal access token scopes ("resource:action", space-separated)
+	FamilyID        string   `json:"fid,omitempty"`       // refresh token family shared by rotated pairs
+	Issuer          string   `json:"iss,omitempty"`
+	Audience        Audience `json:"aud,omitempty"`
+	IssuedAt        int64    `json:"iat"`
+	NotBefore       int64    `json:"nbf,omitempty"`
+	ExpiresAt       int64    `json:"exp"`
+	Nonce           string   `json:"nonce,omitempty"`     // OIDC ID token
+	AuthTime        int64    `json:"auth_time,omitempty"` // OIDC ID token
+	AuthorizedParty string   `json:"azp,omitempty"`       // OAuth client the token was issued to (OIDC)
+	Actor           *Actor   `json:"act,omitempty"`       // admin acting as the subject (impersonation)
+}
+
+// AllRoles returns the roles of the token. Tokens issued before the roles
+// claim existed, and service account tokens, only carry role.
+func (c JWTClaims) AllRoles() []string {
+	if len(c.Roles) > 0 {
+		return c.Roles
+	}
+	if c.Role != "" {
+		return []string{c.Role}
+	}
+	return nil
+}
+
+// IsAccessToken reports whether the token may be used for API access. Only
+// access tokens are, and tokens issued before the token_use claim existed;
+// any other use, including ones added later, is refused.
+func (c JWTClaims) IsAccessToken() bool {
+	return c.TokenUse == TokenUseAccess || c.TokenUse == ""
+}
+
+// HasRole reports whether the token carries the role
+func (c JWTClaims) HasRole(role string) bool {
+	for _, have := range c.AllRoles() {
+		if have == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether a personal access token carries the scope ("resource:action")
+func (c JWTClaims) HasScope(scope string) bool {
+	for _, have := range strings.Fields(c.Scope) {
+		if have == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor identifies who is acting on behalf of the token subject (RFC 8693 "act" claim)
+type Actor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+// token_use claim values
+const (
+	TokenUseAccess      = "access"
+	TokenUseRefresh     = "refresh"
+	TokenUseID          = "id"
+	TokenUsePAT         = "pat" // personal access token (never a JWT)
+	TokenUseEmailVerify = "email_verify"
+)
+
+// Audience is the aud claim. RFC 7519 allows either a single string or an array.
+type Audience []string
+
+// MarshalJSON emits a single audience as a plain string
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON accepts both string and array forms
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single == "" {
+			*a = nil
+		} else {
+			*a = Audience{single}
+		}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return err
+	}
+	*a = Audience(multi)
+	return nil
+}
+
+// Contains reports whether the audience includes any of the given values
+func (a Audience) Contains(values ...string) bool {
+	for _, have := range a {
+		for _, want := range values {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // TokenPair represents access and refresh tokens
@@ -30,3 +143,21 @@ type TokenPair struct {
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
 }
+
+// JWK is a public JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at the JWKS endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// IdentityLinks connects a user to an account at an upstream OpenID Connect
+// provider. A user can have several links; a provider account belongs to one user.
+type IdentityLinks struct {
+	ID          uint   `gorm:"primaryKey,autoIncrement"`
+	UUID        string `gorm:"size:36"`
+	UserUUID    string `gorm:"index;size:36"`
+	Provider    string `gorm:"uniqueIndex:idx_identity_links_provider_subject;size:64"`
+	Subject     string `gorm:"uniqueIndex:idx_identity_links_provider_subject;size:255"` // sub claim of the provider
+	Email       string // email reported by the provider when the link was made
+	LastLoginAt *time.Time
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+// FederationStates is an in-flight login at an upstream provider, stored in
+// Redis under the state parameter until the provider redirects back.
+// LinkUserUUID is set when a signed-in user links a new identity. BindingHash
+// is the hash of the cookie that ties the state to the browser that started it.
+type FederationStates struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	LinkUserUUID string `json:"link_user_uuid,omitempty"`
+	BindingHash  string `json:"binding_hash"`
+}
+
+// FederatedIdentity holds the claims of a verified upstream ID token
+type FederatedIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
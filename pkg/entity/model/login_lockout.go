@@ -0,0 +1,20 @@
+package model
+
+// LoginLockouts is an active login block of an account (email) or client IP.
+// It is stored in Redis and expires at LockedUntil.
+type LoginLockouts struct {
+	Scope       string `json:"scope"`  // account / ip
+	Key         string `json:"key"`    // email or IP address
+	Reason      string `json:"reason"` // backoff / lockout
+	Failures    int    `json:"failures"`
+	Level       int    `json:"level"` // number of lockouts in a row
+	LockedUntil int64  `json:"locked_until"`
+}
+
+// login lockout scopes and reasons
+const (
+	LoginLockoutScopeAccount  = "account"
+	LoginLockoutScopeIP       = "ip"
+	LoginLockoutReasonBackoff = "backoff"
+	LoginLockoutReasonLockout = "lockout"
+)
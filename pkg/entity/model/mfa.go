@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// UserMFAs holds the TOTP enrollment of a user.
+// Enabled turns true once the first code has been confirmed.
+type UserMFAs struct {
+	ID           uint   `gorm:"primaryKey,autoIncrement"`
+	UserUUID     string `gorm:"uniqueIndex;size:36"`
+	Secret       string // base32 TOTP secret
+	Enabled      bool
+	LastUsedStep int64 // time step of the last accepted code, rejects replays
+	ConfirmedAt  *time.Time
+	CreatedAt    *time.Time
+	UpdatedAt    *time.Time
+}
+
+// MFARecoveryCodes are one-time codes that replace a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of each code is stored.
+type MFARecoveryCodes struct {
+	ID        uint   `gorm:"primaryKey,autoIncrement"`
+	UserUUID  string `gorm:"index;size:36"`
+	CodeHash  string `gorm:"uniqueIndex;size:64"`
+	UsedAt    *time.Time
+	CreatedAt *time.Time
+}
+
+// TableName keeps the acronym intact (the default naming would give "user_mf_as")
+func (UserMFAs) TableName() string { return "user_mfas" }
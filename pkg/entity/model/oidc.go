@@ -0,0 +1,22 @@
+package model
+
+// OIDCAuthorizations is an in-flight OIDC authorization request.
+// It is stored in Redis twice during the flow: as a login ticket between
+// the login and consent pages, and as the authorization code handed to
+// the client. User fields are empty until the user has signed in.
+type OIDCAuthorizations struct {
+	ClientID            string   `json:"client_id"`
+	RedirectURI         string   `json:"redirect_uri"`
+	Scope               string   `json:"scope"`
+	State               string   `json:"state"`
+	Nonce               string   `json:"nonce"`
+	CodeChallenge       string   `json:"code_challenge"`
+	CodeChallengeMethod string   `json:"code_challenge_method"`
+	UserID              uint     `json:"user_id"`
+	UserUUID            string   `json:"user_uuid"`
+	Email               string   `json:"email"`
+	Name                string   `json:"name"`
+	Role                string   `json:"role"`
+	Roles               []string `json:"roles,omitempty"`
+	AuthTime            int64    `json:"auth_time"`
+}
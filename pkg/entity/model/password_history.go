@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// PasswordHistories keeps the hashes of the passwords a user has set, newest
+// last, so password_policy.history_size can reject reusing one of them.
+type PasswordHistories struct {
+	ID           uint   `gorm:"primaryKey,autoIncrement"`
+	UserUUID     string `gorm:"index;size:36"`
+	PasswordHash string
+	CreatedAt    *time.Time
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// PersonalAccessTokens is a long-lived bearer token created by a user for scripts and CI.
+// Only the SHA-256 hash of the token is stored; Prefix keeps the first characters for display.
+type PersonalAccessTokens struct {
+	ID         uint `gorm:"primaryKey,autoIncrement"`
+	UUID       string
+	UserUUID   string `gorm:"index;size:36"`
+	Name       string
+	Prefix     string
+	TokenHash  string `gorm:"uniqueIndex;size:64"`
+	Scopes     string // space-separated "resource:action" pairs
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  *time.Time
+	UpdatedAt  *time.Time
+	DeletedAt  *time.Time
+}
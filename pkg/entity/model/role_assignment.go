@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RoleAssignments grants a global (app-level) role to a user in addition to
+// the role derived from admin.emails or the directory. A user holds each role once.
+type RoleAssignments struct {
+	ID        uint   `gorm:"primaryKey,autoIncrement"`
+	UUID      string `gorm:"size:36"`
+	UserUUID  string `gorm:"uniqueIndex:idx_role_assignments_user_role;size:36"`
+	Role      string `gorm:"uniqueIndex:idx_role_assignments_user_role;size:64"`
+	GrantedBy string `gorm:"size:36"` // UUID of the admin who granted the role
+	CreatedAt *time.Time
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// ServiceAccounts is a non-human principal used by backend jobs.
+// It authenticates with client credentials and holds a single app-level Casbin role.
+type ServiceAccounts struct {
+	ID          uint `gorm:"primaryKey,autoIncrement"`
+	UUID        string
+	Name        string
+	Description string
+	Role        string
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+	DeletedAt   *time.Time
+}
+
+// ServiceAccountCredentials is a client ID / hashed secret pair of a service account.
+// Rotation adds a new pair and lets the previous ones expire after a grace period.
+type ServiceAccountCredentials struct {
+	ID                 uint   `gorm:"primaryKey,autoIncrement"`
+	ServiceAccountUUID string `gorm:"index;size:36"`
+	ClientID           string `gorm:"uniqueIndex;size:64"`
+	SecretHash         string
+	ExpiresAt          *time.Time
+	LastUsedAt         *time.Time
+	CreatedAt          *time.Time
+}
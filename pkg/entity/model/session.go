@@ -0,0 +1,15 @@
+package model
+
+// Sessions is a login session. Each session is one refresh token family
+// and is stored in Redis, indexed per user.
+type Sessions struct {
+	ID         string `json:"id"`
+	UserUUID   string `json:"user_uuid"`
+	AccessJti  string `json:"access_jti"`
+	RefreshJti string `json:"refresh_jti"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	IssuedAt   int64  `json:"issued_at"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	Status     string `json:"status"`
+}
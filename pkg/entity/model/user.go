@@ -3,12 +3,17 @@ package model
 import "time"
 
 type Users struct {
-	ID        uint `gorm:"primaryKey,autoIncrement"`
-	UUID      string
-	Email     string
-	Password  string
-	Name      string
-	CreatedAt *time.Time
-	UpdatedAt *time.Time
-	DeletedAt *time.Time
+	ID                uint `gorm:"primaryKey,autoIncrement"`
+	UUID              string
+	Email             string
+	EmailNormalized   string `gorm:"uniqueIndex;size:255"` // lower-cased email, unique across users
+	Password          string
+	Name              string
+	EmailVerifiedAt   *time.Time // nil until the address is confirmed
+	PasswordChangedAt *time.Time // start of the password_policy.max_age_days period
+	AuthSource        string     // authenticator that provisioned the user, empty for local accounts
+	DirectoryRole     string     // role mapped from directory groups at the last directory login
+	CreatedAt         *time.Time
+	UpdatedAt         *time.Time
+	DeletedAt         *time.Time // set while the user is deactivated (SCIM active=false)
 }
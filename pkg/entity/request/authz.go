@@ -0,0 +1,18 @@
+package request
+
+// AuthzCheckRequest: may the subject perform action on object. The subject is
+// user_uuid, the holder of token, or the caller when both are empty.
+// swagger:model AuthzCheckRequest
+type AuthzCheckRequest struct {
+	UserUUID  string `json:"user_uuid,omitempty"`
+	Token     string `json:"token,omitempty"` // access or personal access token of the subject
+	Object    string `json:"object"`
+	Action    string `json:"action"`
+	GroupUUID string `json:"group_uuid,omitempty"` // also evaluate the subject's member roles in this group
+}
+
+// AuthzBatchCheckRequest: several checks answered in one request
+// swagger:model AuthzBatchCheckRequest
+type AuthzBatchCheckRequest struct {
+	Checks []AuthzCheckRequest `json:"checks"`
+}
@@ -0,0 +1,20 @@
+package request
+
+// VerifyEmailRequest confirms an email address with the token from the verification email.
+// swagger:model VerifyEmailRequest
+type VerifyEmailRequest struct {
+	// The token from the verification email.
+	//
+	// required: true
+	Token string `json:"token"`
+}
+
+// ResendVerificationRequest asks for a new verification email.
+// swagger:model ResendVerificationRequest
+type ResendVerificationRequest struct {
+	// The email address of the account.
+	//
+	// required: true
+	// example: "jhon.doe@example.com"
+	Email string `json:"email"`
+}
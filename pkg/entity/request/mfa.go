@@ -0,0 +1,26 @@
+package request
+
+// MFACodeRequest carries a one-time code from the caller's authenticator app
+// (or a recovery code).
+// swagger:model MFACodeRequest
+type MFACodeRequest struct {
+	// The six-digit TOTP code or a recovery code.
+	//
+	// required: true
+	// example: "123456"
+	Code string `json:"code"`
+}
+
+// MFALoginRequest completes a login that requires a second factor.
+// swagger:model MFALoginRequest
+type MFALoginRequest struct {
+	// The challenge token returned by the password step.
+	//
+	// required: true
+	MFAToken string `json:"mfa_token"`
+	// The six-digit TOTP code or a recovery code.
+	//
+	// required: true
+	// example: "123456"
+	Code string `json:"code"`
+}
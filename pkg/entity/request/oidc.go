@@ -0,0 +1,60 @@
+package request
+
+// OIDCAuthorizeRequest represents the parameters of the authorization endpoint.
+// They arrive as query parameters on GET and as form fields on POST.
+type OIDCAuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// OIDCLoginRequest is the login / consent form posted back to the authorization endpoint
+type OIDCLoginRequest struct {
+	OIDCAuthorizeRequest
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	MFACode  string `form:"mfa_code"` // required when the user has MFA enabled
+	Ticket   string `form:"ticket"`
+	Consent  string `form:"consent"` // allow / deny
+}
+
+// OIDCTokenRequest represents the token endpoint form (application/x-www-form-urlencoded)
+type OIDCTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// TokenIntrospectionRequest represents the introspection endpoint form (RFC 7662 section 2.1)
+type TokenIntrospectionRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// TokenRevocationRequest represents the revocation endpoint form (RFC 7009 section 2.1)
+type TokenRevocationRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// TokenExchangeRequest represents the impersonation token exchange (RFC 8693 section 2.1).
+// The caller's bearer token is the actor; requested_subject names the user to impersonate
+// by ID, UUID or email.
+type TokenExchangeRequest struct {
+	GrantType          string `form:"grant_type" json:"grant_type"`
+	RequestedSubject   string `form:"requested_subject" json:"requested_subject"`
+	RequestedTokenType string `form:"requested_token_type" json:"requested_token_type"`
+}
@@ -0,0 +1,25 @@
+package request
+
+// ForgotPasswordRequest starts a password reset.
+// swagger:model ForgotPasswordRequest
+type ForgotPasswordRequest struct {
+	// The email address of the account.
+	//
+	// required: true
+	// example: "jhon.doe@example.com"
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password with a reset token.
+// swagger:model ResetPasswordRequest
+type ResetPasswordRequest struct {
+	// The token from the password reset email.
+	//
+	// required: true
+	Token string `json:"token"`
+	// The new password.
+	//
+	// required: true
+	// example: "N3w-Passw0rd!"
+	Password string `json:"password"`
+}
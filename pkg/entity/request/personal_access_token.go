@@ -0,0 +1,22 @@
+package request
+
+// PersonalAccessTokenRequest represents the request body for creating a personal access token.
+// swagger:model PersonalAccessTokenRequest
+type PersonalAccessTokenRequest struct {
+	// A name that identifies the token (e.g. the CI job using it).
+	//
+	// required: true
+	// example: "ci-deploy"
+	Name string `json:"name"`
+	// Permissions granted to the token, as "resource:action" pairs.
+	// Each scope must be allowed for the caller's role.
+	//
+	// required: true
+	// example: ["users:read", "groups:read"]
+	Scopes []string `json:"scopes"`
+	// Days until the token expires (default 30, maximum 365).
+	//
+	// required: false
+	// example: 90
+	ExpiresInDays int `json:"expires_in_days"`
+}
@@ -13,3 +13,15 @@ type RolePermissionRequest struct {
 	Role        string               `json:"role"`        // role name
 	Permissions []RolePermissionItem `json:"permissions"` // permissions list
 }
+
+// RoleParentRequest: request body adding a parent role, whose permissions the role inherits
+// swagger:model RoleParentRequest
+type RoleParentRequest struct {
+	Parent string `json:"parent"` // role to inherit from
+}
+
+// RoleAssignmentRequest: request body granting a global role to a user
+// swagger:model RoleAssignmentRequest
+type RoleAssignmentRequest struct {
+	Role string `json:"role"` // role defined in the app-level policy
+}
@@ -0,0 +1,17 @@
+package request
+
+import "encoding/json"
+
+// SCIMPatchRequest is the body of PATCH /scim/v2/Users/{id} and /scim/v2/Groups/{id} (RFC 7644 section 3.5.2)
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is one add, replace or remove operation. Without a path,
+// value is an object of attributes to set.
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
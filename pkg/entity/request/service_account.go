@@ -0,0 +1,40 @@
+package request
+
+// ServiceAccountRequest represents the request body for service account operations.
+// swagger:model ServiceAccountRequest
+type ServiceAccountRequest struct {
+	// The name of the service account.
+	//
+	// required: true
+	// example: "nightly-report"
+	Name string `json:"name"`
+	// A free-form description.
+	//
+	// required: false
+	// example: "Generates the nightly usage report"
+	Description string `json:"description"`
+	// The app-level Casbin role of the service account.
+	//
+	// required: true
+	// example: "user"
+	Role string `json:"role"`
+}
+
+// ServiceAccountSecretRequest represents a secret rotation request.
+// swagger:model ServiceAccountSecretRequest
+type ServiceAccountSecretRequest struct {
+	// Seconds the existing secrets stay valid after rotation (0 revokes them immediately).
+	//
+	// required: false
+	// example: 3600
+	GraceSeconds int `json:"grace_seconds"`
+}
+
+// ClientCredentialsRequest represents the OAuth 2.0 client_credentials grant
+// (application/x-www-form-urlencoded, RFC 6749 section 4.4)
+type ClientCredentialsRequest struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
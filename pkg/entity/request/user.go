@@ -30,6 +30,8 @@ type UserRequest struct {
 	// required: true
 	// example: "password"
 	Password string `json:"password"`
+	// Set by the server for accounts created by an admin; never read from the body.
+	EmailVerified bool `json:"-"`
 	// The timestamp of when the user was created.
 	//
 	// required: false
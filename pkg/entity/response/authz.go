@@ -0,0 +1,24 @@
+package response
+
+// AuthzResponse: authorization decisions (result for a single check, results for a batch)
+// swagger:model AuthzResponse
+type AuthzResponse struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Result  *AuthzDecision  `json:"result,omitempty"`
+	Results []AuthzDecision `json:"results,omitempty"`
+}
+
+// AuthzDecision: whether the subject may perform action on object, and the policy line that allowed it
+// swagger:model AuthzDecision
+type AuthzDecision struct {
+	Allowed   bool     `json:"allowed"`
+	Subject   string   `json:"subject,omitempty"` // UUID of the user or service account
+	Object    string   `json:"object"`
+	Action    string   `json:"action"`
+	GroupUUID string   `json:"group_uuid,omitempty"`
+	PolicySet string   `json:"policy_set,omitempty"` // locky (global roles) / resources (member roles in the group)
+	Role      string   `json:"role,omitempty"`       // role of the subject the policy line applies to
+	Policy    []string `json:"policy,omitempty"`     // matched policy line, e.g. ["maintainer", "member", "write"]
+	Reason    string   `json:"reason,omitempty"`     // why the check was denied
+}
@@ -7,9 +7,17 @@ import (
 )
 
 type CommonResponse struct {
-	Code    string   `json:"code"`
-	Message string   `json:"message"`
-	Commons []Common `json:"commons,omitempty"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Commons []Common     `json:"commons,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why the value of one request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type Common struct {
@@ -20,12 +28,15 @@ type Common struct {
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-// LoginResponse represents successful login response
+// LoginResponse represents successful login response.
+// When the user has MFA enabled, Code is "MFA_REQUIRED" and MFAToken must be
+// exchanged together with a code at /tokens/mfa to obtain the TokenPair.
 type LoginResponse struct {
 	Code      string           `json:"code"`
 	Message   string           `json:"message"`
 	TokenPair *model.TokenPair `json:"token_pair,omitempty"`
 	User      *User            `json:"user,omitempty"`
+	MFAToken  string           `json:"mfa_token,omitempty"`
 }
 
 // RefreshTokenResponse represents refresh token response
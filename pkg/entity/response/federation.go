@@ -0,0 +1,87 @@
+package response
+
+import "time"
+
+// FederationProvidersResponse lists the upstream identity providers users can sign in with.
+// swagger:model FederationProvidersResponse
+type FederationProvidersResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "Identity providers retrieved successfully"
+	Message string `json:"message"`
+	// The configured providers.
+	//
+	// required: true
+	Providers []FederationProvider `json:"providers"`
+}
+
+// FederationProvider is an upstream identity provider.
+// swagger:model FederationProvider
+type FederationProvider struct {
+	// The provider name used in URLs.
+	//
+	// required: true
+	// example: "google"
+	Name string `json:"name"`
+	// The name shown on login buttons.
+	//
+	// example: "Google"
+	DisplayName string `json:"display_name"`
+	// The path that starts a login with this provider.
+	//
+	// required: true
+	// example: "/v1/share/common/federation/google/login"
+	LoginURL string `json:"login_url"`
+}
+
+// IdentityLinkResponse represents the response body for linked identity operations.
+// swagger:model IdentityLinkResponse
+type IdentityLinkResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "Identities retrieved successfully"
+	Message string `json:"message"`
+	// The provider URL to open in the browser to link a new identity.
+	AuthorizationURL string `json:"authorization_url,omitempty"`
+	// The linked identities.
+	Identities []IdentityLink `json:"identities,omitempty"`
+}
+
+// IdentityLink is an account at an upstream identity provider linked to a user.
+// swagger:model IdentityLink
+type IdentityLink struct {
+	// The UUID of the link.
+	//
+	// required: true
+	// example: "f3b3b3b3-3b3b-3b3b-3b3b-3b3b3b3b3b3b"
+	UUID string `json:"uuid"`
+	// The provider name.
+	//
+	// required: true
+	// example: "google"
+	Provider string `json:"provider"`
+	// The account identifier (sub claim) at the provider.
+	//
+	// required: true
+	Subject string `json:"subject"`
+	// The email reported by the provider when the link was made.
+	//
+	// example: "jhon.doe@example.com"
+	Email string `json:"email,omitempty"`
+	// When the identity was last used to sign in.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	// The timestamp of when the link was created.
+	CreatedAt *time.Time `json:"created_at"`
+}
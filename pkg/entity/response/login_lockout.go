@@ -0,0 +1,47 @@
+package response
+
+// LoginLockoutResponse represents the response body for login lockout operations.
+// swagger:model LoginLockoutResponse
+type LoginLockoutResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "Lockouts retrieved successfully"
+	Message string `json:"message"`
+	// The list of active lockouts.
+	//
+	// required: true
+	Lockouts []LoginLockout `json:"lockouts"`
+}
+
+// LoginLockout represents an account or client IP that is blocked from logging in.
+// swagger:model LoginLockout
+type LoginLockout struct {
+	// What is blocked: account or ip.
+	//
+	// required: true
+	// example: "account"
+	Scope string `json:"scope"`
+	// The email address or client IP address.
+	//
+	// required: true
+	// example: "jhon.doe@example.com"
+	Key string `json:"key"`
+	// Why it is blocked: backoff (short delay after failures) or lockout.
+	//
+	// example: "lockout"
+	Reason string `json:"reason"`
+	// Failed attempts counted when the block started.
+	Failures int `json:"failures"`
+	// Number of lockouts in a row; each one doubles the duration.
+	Level int `json:"level"`
+	// Unix time when the block ends.
+	LockedUntil int64 `json:"locked_until"`
+	// Seconds until the block ends.
+	RetryAfter int64 `json:"retry_after"`
+}
@@ -0,0 +1,26 @@
+package response
+
+// MFAResponse represents the response body for multi-factor authentication operations.
+// swagger:model MFAResponse
+type MFAResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "MFA status retrieved successfully"
+	Message string `json:"message"`
+	// Whether MFA is enabled for the user.
+	//
+	// required: true
+	Enabled bool `json:"enabled"`
+	// The base32 TOTP secret. Only returned when enrollment starts.
+	Secret string `json:"secret,omitempty"`
+	// The otpauth:// URI to render as a QR code. Only returned when enrollment starts.
+	ProvisioningURI string `json:"provisioning_uri,omitempty"`
+	// One-time recovery codes. Only returned once, when enrollment is confirmed.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
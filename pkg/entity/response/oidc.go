@@ -0,0 +1,81 @@
+package response
+
+import "github.com/ryo-arima/locky/pkg/entity/model"
+
+// OIDCDiscoveryResponse is the OpenID Provider metadata document.
+// swagger:model OIDCDiscoveryResponse
+type OIDCDiscoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// OAuthTokenResponse is the token endpoint response (RFC 6749 section 5.1).
+// swagger:model OAuthTokenResponse
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// TokenExchangeResponse is the token exchange response (RFC 8693 section 2.2.1).
+// swagger:model TokenExchangeResponse
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// OAuthErrorResponse is the OAuth 2.0 error body (RFC 6749 section 5.2).
+// swagger:model OAuthErrorResponse
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OIDCUserInfoResponse is the UserInfo endpoint response.
+// swagger:model OIDCUserInfoResponse
+type OIDCUserInfoResponse struct {
+	Subject string `json:"sub"`
+	Name    string `json:"name,omitempty"`
+	Email   string `json:"email,omitempty"`
+}
+
+// TokenIntrospectionResponse is the introspection endpoint response (RFC 7662 section 2.2).
+// Only active is set for inactive tokens. token_use, role and email are Locky extensions;
+// token_use tells access, refresh and personal access (pat) tokens apart.
+// swagger:model TokenIntrospectionResponse
+type TokenIntrospectionResponse struct {
+	Active    bool           `json:"active"`
+	Scope     string         `json:"scope,omitempty"`
+	ClientID  string         `json:"client_id,omitempty"`
+	Username  string         `json:"username,omitempty"`
+	TokenType string         `json:"token_type,omitempty"`
+	Exp       int64          `json:"exp,omitempty"`
+	Iat       int64          `json:"iat,omitempty"`
+	Nbf       int64          `json:"nbf,omitempty"`
+	Sub       string         `json:"sub,omitempty"`
+	Aud       model.Audience `json:"aud,omitempty"`
+	Iss       string         `json:"iss,omitempty"`
+	Jti       string         `json:"jti,omitempty"`
+	TokenUse  string         `json:"token_use,omitempty"`
+	Role      string         `json:"role,omitempty"`
+	Roles     []string       `json:"roles,omitempty"`
+	Email     string         `json:"email,omitempty"`
+}
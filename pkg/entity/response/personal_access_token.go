@@ -0,0 +1,58 @@
+package response
+
+import "time"
+
+// PersonalAccessTokenResponse represents the response body for personal access token operations.
+// swagger:model PersonalAccessTokenResponse
+type PersonalAccessTokenResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "Tokens retrieved successfully"
+	Message string `json:"message"`
+	// The list of personal access tokens.
+	//
+	// required: true
+	Tokens []PersonalAccessToken `json:"tokens"`
+}
+
+// PersonalAccessToken represents a personal access token.
+// swagger:model PersonalAccessToken
+type PersonalAccessToken struct {
+	// The ID of the token.
+	//
+	// required: true
+	// example: 1
+	ID uint `json:"id"`
+	// The UUID of the token.
+	//
+	// required: true
+	// example: "f3b3b3b3-3b3b-3b3b-3b3b-3b3b3b3b3b3b"
+	UUID string `json:"uuid"`
+	// The name of the token.
+	//
+	// required: true
+	// example: "ci-deploy"
+	Name string `json:"name"`
+	// The first characters of the token, for identification.
+	//
+	// example: "lky_pat_Xk3f"
+	Prefix string `json:"prefix"`
+	// The token itself. Only returned once, when the token is created.
+	Token string `json:"token,omitempty"`
+	// The scopes granted to the token.
+	//
+	// example: ["users:read"]
+	Scopes []string `json:"scopes"`
+	// When the token expires.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// When the token was last used.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// The timestamp of when the token was created.
+	CreatedAt *time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package response
+
+import "time"
+
+// PolicyResponse: Casbin policy storage operations
+// swagger:model PolicyResponse
+type PolicyResponse struct {
+	Code       string      `json:"code"`
+	Message    string      `json:"message"`
+	Node       string      `json:"node,omitempty"` // server process that answered
+	PolicySets []PolicySet `json:"policy_sets"`
+}
+
+// PolicySet: one Casbin policy set (locky for app-wide, resources for group-scoped permissions)
+// swagger:model PolicySet
+type PolicySet struct {
+	Name     string     `json:"name"`
+	Source   string     `json:"source,omitempty"`  // policy.csv the lines were imported from
+	Storage  string     `json:"storage,omitempty"` // file / database
+	Watcher  string     `json:"watcher,omitempty"` // redis / file / none
+	Revision int64      `json:"revision,omitempty"`
+	Digest   string     `json:"digest,omitempty"` // SHA-256 of the loaded policy lines
+	Rules    int        `json:"rules"`
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+}
@@ -1,10 +1,48 @@
 package response
 
+import "time"
+
 // RoleResponse: role operation response
 // swagger:model RoleResponse
 type RoleResponse struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Roles   interface{} `json:"roles"`
-	Detail  interface{} `json:"detail,omitempty"`
+	Code      string                    `json:"code"`
+	Message   string                    `json:"message"`
+	Roles     interface{}               `json:"roles"`
+	Detail    interface{}               `json:"detail,omitempty"`
+	Effective []RoleEffectivePermission `json:"effective,omitempty"` // with ?effective=true: direct and inherited permissions
+	Hierarchy *RoleHierarchy            `json:"hierarchy,omitempty"`
+}
+
+// RoleEffectivePermission: permission a role holds directly or by inheritance
+// swagger:model RoleEffectivePermission
+type RoleEffectivePermission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Role     string `json:"role"` // role whose policy line grants the permission
+}
+
+// RoleHierarchy: inheritance of a role (g, <role>, <parent>)
+// swagger:model RoleHierarchy
+type RoleHierarchy struct {
+	Role        string   `json:"role"`
+	Parents     []string `json:"parents"`
+	Ancestors   []string `json:"ancestors"`
+	Descendants []string `json:"descendants"`
+}
+
+// UserRoleResponse: global roles of a user
+// swagger:model UserRoleResponse
+type UserRoleResponse struct {
+	Code    string     `json:"code"`
+	Message string     `json:"message"`
+	Roles   []UserRole `json:"roles"`
+}
+
+// UserRole: one global role of a user and where it comes from
+// swagger:model UserRole
+type UserRole struct {
+	Role      string     `json:"role"`
+	Source    string     `json:"source"` // "config" (admin.emails / directory / default) or "assigned"
+	GrantedBy string     `json:"granted_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
 }
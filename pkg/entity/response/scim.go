@@ -0,0 +1,105 @@
+package response
+
+// SCIMListResponse is the result of a SCIM query (RFC 7644 section 3.4.2).
+// swagger:model SCIMListResponse
+type SCIMListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// SCIMError is the error body of the SCIM API (RFC 7644 section 3.12).
+// swagger:model SCIMError
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// SCIMServiceProviderConfig describes the SCIM features Locky supports (RFC 7643 section 5).
+// swagger:model SCIMServiceProviderConfig
+type SCIMServiceProviderConfig struct {
+	Schemas               []string                 `json:"schemas"`
+	DocumentationURI      string                   `json:"documentationUri,omitempty"`
+	Patch                 SCIMSupported            `json:"patch"`
+	Bulk                  SCIMBulkSupport          `json:"bulk"`
+	Filter                SCIMFilterSupport        `json:"filter"`
+	ChangePassword        SCIMSupported            `json:"changePassword"`
+	Sort                  SCIMSupported            `json:"sort"`
+	ETag                  SCIMSupported            `json:"etag"`
+	AuthenticationSchemes []SCIMAuthenticationType `json:"authenticationSchemes"`
+	Meta                  SCIMResourceMeta         `json:"meta"`
+}
+
+// SCIMSupported flags an optional SCIM feature
+type SCIMSupported struct {
+	Supported bool `json:"supported"`
+}
+
+// SCIMBulkSupport describes bulk operation support
+type SCIMBulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// SCIMFilterSupport describes filter support
+type SCIMFilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// SCIMAuthenticationType is an authentication scheme accepted by the SCIM API
+type SCIMAuthenticationType struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary,omitempty"`
+}
+
+// SCIMResourceMeta is the metadata of the discovery resources
+type SCIMResourceMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+// SCIMResourceType describes an endpoint of the SCIM API (RFC 7643 section 6).
+// swagger:model SCIMResourceType
+type SCIMResourceType struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Endpoint    string           `json:"endpoint"`
+	Description string           `json:"description"`
+	Schema      string           `json:"schema"`
+	Meta        SCIMResourceMeta `json:"meta"`
+}
+
+// SCIMSchema describes the attributes of a resource (RFC 7643 section 7).
+// swagger:model SCIMSchema
+type SCIMSchema struct {
+	Schemas     []string              `json:"schemas"`
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Attributes  []SCIMSchemaAttribute `json:"attributes"`
+	Meta        SCIMResourceMeta      `json:"meta"`
+}
+
+// SCIMSchemaAttribute is an attribute definition of a SCIM schema
+type SCIMSchemaAttribute struct {
+	Name           string                `json:"name"`
+	Type           string                `json:"type"`
+	MultiValued    bool                  `json:"multiValued"`
+	Description    string                `json:"description"`
+	Required       bool                  `json:"required"`
+	CaseExact      bool                  `json:"caseExact"`
+	Mutability     string                `json:"mutability"`
+	Returned       string                `json:"returned"`
+	Uniqueness     string                `json:"uniqueness"`
+	ReferenceTypes []string              `json:"referenceTypes,omitempty"`
+	SubAttributes  []SCIMSchemaAttribute `json:"subAttributes,omitempty"`
+}
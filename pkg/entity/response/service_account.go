@@ -0,0 +1,72 @@
+package response
+
+import "time"
+
+// ServiceAccountResponse represents the response body for service account operations.
+// swagger:model ServiceAccountResponse
+type ServiceAccountResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "Service accounts retrieved successfully"
+	Message string `json:"message"`
+	// The list of service accounts.
+	//
+	// required: true
+	ServiceAccounts []ServiceAccount `json:"service_accounts"`
+}
+
+// ServiceAccount represents a service account.
+// swagger:model ServiceAccount
+type ServiceAccount struct {
+	// The ID of the service account.
+	//
+	// required: true
+	// example: 1
+	ID uint `json:"id"`
+	// The UUID of the service account.
+	//
+	// required: true
+	// example: "f3b3b3b3-3b3b-3b3b-3b3b-3b3b3b3b3b3b"
+	UUID string `json:"uuid"`
+	// The name of the service account.
+	//
+	// required: true
+	// example: "nightly-report"
+	Name string `json:"name"`
+	// A free-form description.
+	Description string `json:"description"`
+	// The app-level Casbin role.
+	//
+	// example: "user"
+	Role string `json:"role"`
+	// The client credentials of the service account.
+	Credentials []ServiceAccountCredential `json:"credentials"`
+	// The timestamp of when the service account was created.
+	CreatedAt *time.Time `json:"created_at"`
+	// The timestamp of when the service account was last updated.
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// ServiceAccountCredential represents a client ID / secret pair.
+// swagger:model ServiceAccountCredential
+type ServiceAccountCredential struct {
+	// The client ID.
+	//
+	// required: true
+	// example: "sa-9f86d081884c7d65"
+	ClientID string `json:"client_id"`
+	// The client secret. Only returned once, when the credential is created.
+	ClientSecret string `json:"client_secret,omitempty"`
+	// When the credential stops being accepted (rotated credentials only).
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// When the credential was last used to obtain a token.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// The timestamp of when the credential was created.
+	CreatedAt *time.Time `json:"created_at"`
+}
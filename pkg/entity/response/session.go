@@ -0,0 +1,53 @@
+package response
+
+// SessionResponse represents the response body for session operations.
+// swagger:model SessionResponse
+type SessionResponse struct {
+	// The response code.
+	//
+	// required: true
+	// example: "SUCCESS"
+	Code string `json:"code"`
+	// The response message.
+	//
+	// required: true
+	// example: "Sessions retrieved successfully"
+	Message string `json:"message"`
+	// The list of sessions.
+	//
+	// required: true
+	Sessions []Session `json:"sessions"`
+}
+
+// Session represents a login session.
+// swagger:model Session
+type Session struct {
+	// The session ID (token family ID).
+	//
+	// required: true
+	// example: "f3b3b3b3-3b3b-3b3b-3b3b-3b3b3b3b3b3b"
+	ID string `json:"id"`
+	// The UUID of the user.
+	//
+	// required: true
+	// example: "f3b3b3b3-3b3b-3b3b-3b3b-3b3b3b3b3b3b"
+	UserUUID string `json:"user_uuid"`
+	// The JTI of the latest access token.
+	AccessJti string `json:"access_jti"`
+	// The JTI of the latest refresh token.
+	RefreshJti string `json:"refresh_jti"`
+	// The User-Agent seen at login or last use.
+	//
+	// example: "locky-cli/1.0"
+	UserAgent string `json:"user_agent"`
+	// The client IP address seen at login or last use.
+	//
+	// example: "192.0.2.10"
+	IPAddress string `json:"ip_address"`
+	// Unix time of the login.
+	IssuedAt int64 `json:"issued_at"`
+	// Unix time of the last authenticated request.
+	LastSeenAt int64 `json:"last_seen_at"`
+	// Whether this is the session of the calling token.
+	Current bool `json:"current"`
+}
@@ -19,6 +19,8 @@ type UserResponse struct {
 	//
 	// required: true
 	Users []User `json:"users"`
+	// The rejected fields, e.g. password policy violations.
+	Errors []FieldError `json:"errors,omitempty"`
 }
 
 // User represents a user in the system.
@@ -44,6 +46,11 @@ type User struct {
 	// required: true
 	// example: "John Doe"
 	Name string `json:"name"`
+	// The timestamp of when the email address was verified, absent while unverified.
+	//
+	// required: false
+	// example: "2023-01-01T00:00:00Z"
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 	// The timestamp of when the user was created.
 	//
 	// required: false
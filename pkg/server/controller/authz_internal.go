@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// MaxAuthzBatchChecks limits the checks of one batch request
+const MaxAuthzBatchChecks = 100
+
+// AuthzControllerForInternal answers authorization checks for other services.
+//
+//   - Check: One check (POST /v1/internal/authz/check)
+//   - CheckBatch: Up to MaxAuthzBatchChecks checks (POST /v1/internal/authz/check/batch)
+//
+// Callers may check themselves. Checking another user (user_uuid) or the holder
+// of another token (token) requires the app permission authz read.
+type AuthzControllerForInternal interface {
+	Check(c *gin.Context)
+	CheckBatch(c *gin.Context)
+}
+
+type authzControllerForInternal struct {
+	AuthorizationRepository      repository.AuthorizationRepository
+	TokenIntrospectionRepository repository.TokenIntrospectionRepository
+	UserRepository               repository.UserRepository
+	CommonRepository             repository.CommonRepository
+	AppEnforcer                  *casbin.SyncedEnforcer
+}
+
+// Check decides whether the subject may perform action on object.
+//
+// Route: POST /v1/internal/authz/check
+// Security: Bearer token
+func (rcvr authzControllerForInternal) Check(c *gin.Context) {
+	var req request.AuthzCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &response.AuthzResponse{Code: "AUTHZ_CHECK_001", Message: "Invalid request body"})
+		return
+	}
+	if !rcvr.validate(c, []request.AuthzCheckRequest{req}) {
+		return
+	}
+	decision, ok := rcvr.check(c, req)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, &response.AuthzResponse{Code: "SUCCESS", Message: "Authorization checked", Result: &decision})
+}
+
+// CheckBatch decides several checks; results are in the order of the checks.
+//
+// Route: POST /v1/internal/authz/check/batch
+// Security: Bearer token
+func (rcvr authzControllerForInternal) CheckBatch(c *gin.Context) {
+	var req request.AuthzBatchCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &response.AuthzResponse{Code: "AUTHZ_CHECK_001", Message: "Invalid request body"})
+		return
+	}
+	if len(req.Checks) == 0 || len(req.Checks) > MaxAuthzBatchChecks {
+		c.JSON(http.StatusBadRequest, &response.AuthzResponse{Code: "AUTHZ_CHECK_005", Message: fmt.Sprintf("between 1 and %d checks are required", MaxAuthzBatchChecks)})
+		return
+	}
+	if !rcvr.validate(c, req.Checks) {
+		return
+	}
+	results := make([]response.AuthzDecision, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		decision, ok := rcvr.check(c, check)
+		if !ok {
+			return
+		}
+		results = append(results, decision)
+	}
+	c.JSON(http.StatusOK, &response.AuthzResponse{Code: "SUCCESS", Message: "Authorization checked", Results: results})
+}
+
+// validate checks the fields of the checks and that the caller may ask about
+// the subjects named in them
+func (rcvr authzControllerForInternal) validate(c *gin.Context, checks []request.AuthzCheckRequest) bool {
+	others := false
+	for _, check := range checks {
+		if strings.TrimSpace(check.Object) == "" || strings.TrimSpace(check.Action) == "" {
+			c.JSON(http.StatusBadRequest, &response.AuthzResponse{Code: "AUTHZ_CHECK_002", Message: "object and action are required"})
+			return false
+		}
+		if check.UserUUID != "" && check.Token != "" {
+			c.JSON(http.StatusBadRequest, &response.AuthzResponse{Code: "AUTHZ_CHECK_002", Message: "user_uuid and token cannot be combined"})
+			return false
+		}
+		others = others || check.UserUUID != "" || check.Token != ""
+	}
+	if !others {
+		return true
+	}
+	caller, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.AuthzResponse{Code: "AUTHZ_CHECK_003", Message: "Authentication required"})
+		return false
+	}
+	allowed, err := middleware.EnforceAnyRole(rcvr.AppEnforcer, caller.AllRoles(), "authz", "read")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.AuthzResponse{Code: "AUTHZ_CHECK_004", Message: err.Error()})
+		return false
+	}
+	if !allowed || (caller.TokenUse == model.TokenUsePAT && !caller.HasScope("authz:read")) {
+		c.JSON(http.StatusForbidden, &response.AuthzResponse{Code: "AUTHZ_CHECK_003", Message: "checking other subjects requires the authz read permission"})
+		return false
+	}
+	return true
+}
+
+// check resolves the subject of one check and evaluates it. Unknown users and
+// inactive tokens are denied, not rejected, so a batch is answered as a whole.
+func (rcvr authzControllerForInternal) check(c *gin.Context, req request.AuthzCheckRequest) (response.AuthzDecision, bool) {
+	decision := response.AuthzDecision{Object: req.Object, Action: req.Action, GroupUUID: req.GroupUUID}
+	var subject model.JWTClaims
+	switch {
+	case req.Token != "":
+		claims, err := rcvr.TokenIntrospectionRepository.Introspect(c, req.Token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &response.AuthzResponse{Code: "AUTHZ_CHECK_004", Message: err.Error()})
+			return decision, false
+		}
+		if claims == nil || claims.TokenUse == model.TokenUseRefresh {
+			decision.Reason = repository.AuthzReasonTokenInactive
+			return decision, true
+		}
+		subject = *claims
+	case req.UserUUID != "":
+		users, err := rcvr.UserRepository.ListUsers(c, repository.UserQueryFilter{UUID: &req.UserUUID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &response.AuthzResponse{Code: "AUTHZ_CHECK_004", Message: err.Error()})
+			return decision, false
+		}
+		if len(users) == 0 {
+			decision.Reason = repository.AuthzReasonUserNotFound
+			return decision, true
+		}
+		if users[0].DeletedAt != nil {
+			decision.Reason = repository.AuthzReasonUserInactive
+			return decision, true
+		}
+		subject = model.JWTClaims{UUID: users[0].UUID, Roles: rcvr.CommonRepository.LoadUserRoles(users[0])}
+	default:
+		claims, ok := middleware.GetUserClaims(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, &response.AuthzResponse{Code: "AUTHZ_CHECK_003", Message: "Authentication required"})
+			return decision, false
+		}
+		subject = *claims
+	}
+
+	decision.Subject = subject.UUID
+	result, err := rcvr.AuthorizationRepository.Check(c, subject, req.Object, req.Action, req.GroupUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.AuthzResponse{Code: "AUTHZ_CHECK_004", Message: err.Error()})
+		return decision, false
+	}
+	decision.Allowed = result.Allowed
+	decision.PolicySet = result.PolicySet
+	decision.Role = result.Role
+	decision.Policy = result.Policy
+	decision.Reason = result.Reason
+	return decision, true
+}
+
+// NewAuthzControllerForInternal creates the authorization check controller
+func NewAuthzControllerForInternal(authorizationRepository repository.AuthorizationRepository, tokenIntrospectionRepository repository.TokenIntrospectionRepository, userRepository repository.UserRepository, commonRepository repository.CommonRepository, appEnforcer *casbin.SyncedEnforcer) AuthzControllerForInternal {
+	return authzControllerForInternal{
+		AuthorizationRepository:      authorizationRepository,
+		TokenIntrospectionRepository: tokenIntrospectionRepository,
+		UserRepository:               userRepository,
+		CommonRepository:             commonRepository,
+		AppEnforcer:                  appEnforcer,
+	}
+}
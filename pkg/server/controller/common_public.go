@@ -7,14 +7,18 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 
+	"github.com/ryo-arima/locky/pkg/code"
 	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/entity/request"
 	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
 	"github.com/ryo-arima/locky/pkg/server/middleware"
 	"github.com/ryo-arima/locky/pkg/server/repository"
 )
@@ -28,25 +32,34 @@ import (
 //   - ValidateToken: Validates JWT tokens and returns user information
 //   - GetUserInfo: Returns user information from authenticated context
 //   - Login: Handles user login and JWT token issuance
+//   - VerifyMFA: Completes a login that requires a second factor
 //   - RefreshToken: Refreshes JWT tokens using refresh tokens
 //   - Logout: Handles user logout (token invalidation)
+//   - GetJWKS: Publishes the public token signing keys
 type CommonControllerForPublic interface {
 	ValidateToken(c *gin.Context)
 	GetUserInfo(c *gin.Context)
 	Login(c *gin.Context)
+	VerifyMFA(c *gin.Context)
 	RefreshToken(c *gin.Context)
 	Logout(c *gin.Context)
+	GetJWKS(c *gin.Context)
 }
 
 type commonControllerForPublic struct {
-	UserRepository   repository.UserRepository
-	CommonRepository repository.CommonRepository
+	UserRepository         repository.UserRepository
+	CommonRepository       repository.CommonRepository
+	MFARepository          repository.MFARepository
+	LoginLockoutRepository repository.LoginLockoutRepository
+	AuthenticatorChain     repository.AuthenticatorChain
 }
 
 // ValidateToken validates JWT token and returns user information.
 //
 // This endpoint validates a JWT token provided in the Authorization header
-// and returns the user information contained within the token.
+// and returns the user information contained within the token. Resource servers
+// should prefer the RFC 7662 introspection endpoint (TokenIntrospectionPath),
+// which also covers refresh and personal access tokens.
 //
 // Route: GET /v1/share/common/auth/tokens/validate
 // Security: Bearer token required
@@ -93,6 +106,15 @@ func (rcvr commonControllerForPublic) ValidateToken(c *gin.Context) {
 		})
 		return
 	}
+	// Refresh, ID and other tokens are not valid for API access
+	if !claims.IsAccessToken() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "COMMON_VALIDATE_002",
+			"message": "Invalid token",
+			"error":   claims.TokenUse + " token cannot be used for API access",
+		})
+		return
+	}
 
 	// Check if token is in denylist (logged out) - use JTI not the full token
 	isInvalidated, err := rcvr.CommonRepository.IsTokenInvalidated(c.Request.Context(), claims.Jti)
@@ -122,6 +144,7 @@ func (rcvr commonControllerForPublic) ValidateToken(c *gin.Context) {
 			"email":      claims.Email,
 			"name":       claims.Name,
 			"role":       claims.Role,
+			"roles":      claims.AllRoles(),
 			"expires_at": claims.ExpiresAt,
 		},
 	})
@@ -177,6 +200,12 @@ func (rcvr commonControllerForPublic) GetUserInfo(c *gin.Context) {
 //
 // This endpoint authenticates users with email and password,
 // then returns JWT access and refresh tokens upon successful authentication.
+// For users with MFA enabled it instead returns code "MFA_REQUIRED" and an
+// mfa_token to be completed at /tokens/mfa. When email_verification.require_for_login
+// is set, users who have not confirmed their email address are rejected, and
+// users whose password is older than password_policy.max_age_days get AUTH_LOGIN_010.
+// Repeated failures lock the account or client IP out for a while; locked
+// attempts are answered with 429, code AUTH_LOGIN_009 and Retry-After.
 //
 // Route: POST /v1/share/common/auth/tokens
 // Security: No authentication required
@@ -192,6 +221,8 @@ func (rcvr commonControllerForPublic) GetUserInfo(c *gin.Context) {
 //	200: loginResponse
 //	400: errorResponse
 //	401: errorResponse
+//	403: errorResponse
+//	429: errorResponse
 //	500: errorResponse
 func (rcvr commonControllerForPublic) Login(c *gin.Context) {
 	var loginRequest request.LoginRequest
@@ -212,66 +243,276 @@ func (rcvr commonControllerForPublic) Login(c *gin.Context) {
 		return
 	}
 
-	// Get all users to find matching email
-	users := rcvr.UserRepository.GetUsers(c)
-	var foundUser *model.Users
+	// Locked accounts and IPs are rejected before any password comparison
+	if lockout := rcvr.checkLoginLockout(c, loginRequest.Email); lockout != nil {
+		respondLoginLockout(c, lockout)
+		return
+	}
+
+	// Verify the password with the configured authenticators (local users table, ldap)
+	foundUser, err := rcvr.AuthenticatorChain.Authenticate(c, loginRequest.Email, loginRequest.Password)
+	switch {
+	case errors.Is(err, repository.ErrAuthenticatorSkipped):
+		rcvr.loginFailed(c, loginRequest.Email, "AUTH_LOGIN_003", "Invalid email or password")
+		return
+	case errors.Is(err, repository.ErrInvalidCredentials):
+		rcvr.loginFailed(c, loginRequest.Email, "AUTH_LOGIN_004", "Invalid email or password")
+		return
+	case errors.Is(err, repository.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, &response.LoginResponse{
+			Code:    "AUTH_LOGIN_012",
+			Message: "Account is disabled",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusServiceUnavailable, &response.LoginResponse{
+			Code:    "AUTH_LOGIN_011",
+			Message: "Authentication is temporarily unavailable, try again later",
+		})
+		return
+	}
+	if foundUser.AuthSource == "" || foundUser.AuthSource == repository.AuthenticatorLocal {
+		upgradePasswordHash(c, rcvr.CommonRepository, rcvr.UserRepository, *foundUser, loginRequest.Password)
+	}
 
-	for _, user := range users {
-		if user.Email == loginRequest.Email {
-			foundUser = &user
-			break
+	if foundUser.EmailVerifiedAt == nil && rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server.EmailVerification.RequireForLogin {
+		c.JSON(http.StatusForbidden, &response.LoginResponse{
+			Code:    "AUTH_LOGIN_008",
+			Message: "Email address not verified",
+		})
+		return
+	}
+	if repository.PasswordExpired(rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server.PasswordPolicy, *foundUser, time.Now()) {
+		c.JSON(http.StatusForbidden, &response.LoginResponse{
+			Code:    "AUTH_LOGIN_010",
+			Message: "Password has expired, reset it to sign in",
+		})
+		return
+	}
+
+	rcvr.completeLogin(c, foundUser, loginRequest.Email)
+}
+
+// completeLogin issues the token pair of an authenticated user. Users with MFA
+// enabled get a short-lived challenge instead of tokens, and the failed logins
+// of email are only reset once the second factor is verified as well.
+func (rcvr commonControllerForPublic) completeLogin(c *gin.Context, user *model.Users, email string) {
+	mfaEnabled, err := rcvr.MFARepository.IsEnabled(c, user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.LoginResponse{
+			Code:    "AUTH_LOGIN_006",
+			Message: "Failed to check multi-factor authentication: " + err.Error(),
+		})
+		return
+	}
+	if mfaEnabled {
+		challenge, err := rcvr.MFARepository.CreateChallenge(c.Request.Context(), user.UUID, email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &response.LoginResponse{
+				Code:    "AUTH_LOGIN_007",
+				Message: "Failed to start multi-factor authentication: " + err.Error(),
+			})
+			return
 		}
+		c.JSON(http.StatusOK, &response.LoginResponse{
+			Code:     "MFA_REQUIRED",
+			Message:  "Multi-factor authentication code required",
+			MFAToken: challenge,
+		})
+		return
 	}
 
-	if foundUser == nil {
-		c.JSON(http.StatusUnauthorized, &response.LoginResponse{
-			Code:    "AUTH_LOGIN_003",
-			Message: "Invalid email or password",
+	rcvr.loginSucceeded(c, email)
+	rcvr.issueLoginTokens(c, user, "AUTH_LOGIN_005")
+}
+
+// upgradePasswordHash replaces a hash made with an outdated algorithm or parameters once
+// the password is known to be correct. Failures are logged and the old hash is kept.
+func upgradePasswordHash(c *gin.Context, commonRepository repository.CommonRepository, userRepository repository.UserRepository, user model.Users, password string) {
+	if !commonRepository.NeedsPasswordRehash(user.Password) {
+		return
+	}
+	requestID := middleware.GetRequestID(c)
+	hashedPassword, err := commonRepository.HashPassword(password)
+	if err != nil {
+		logger.Warn(code.CCPRH2, requestID, err.Error())
+		return
+	}
+	user.Password = hashedPassword
+	if _, err := userRepository.UpdateUser(c, user); err != nil {
+		logger.Warn(code.CCPRH2, requestID, user.UUID+": "+err.Error())
+		return
+	}
+	logger.Info(code.CCPRH1, requestID, user.UUID)
+}
+
+// checkLoginLockout returns the active lockout of the account or client IP.
+// Lockout storage errors are logged and do not block the login.
+func (rcvr commonControllerForPublic) checkLoginLockout(c *gin.Context, email string) *model.LoginLockouts {
+	lockout, err := rcvr.LoginLockoutRepository.Check(c.Request.Context(), email, c.ClientIP())
+	if err != nil {
+		logger.Warn(code.CCPLO1, middleware.GetRequestID(c), err.Error())
+		return nil
+	}
+	return lockout
+}
+
+// loginFailed counts a failed login and answers 401, or 429 when the failure locks the account out
+func (rcvr commonControllerForPublic) loginFailed(c *gin.Context, email, failureCode, message string) {
+	lockout, err := rcvr.LoginLockoutRepository.RegisterFailure(c.Request.Context(), email, c.ClientIP())
+	if err != nil {
+		logger.Warn(code.CCPLO1, middleware.GetRequestID(c), err.Error())
+	}
+	if lockout != nil && lockout.Reason == model.LoginLockoutReasonLockout {
+		logger.Warn(code.CCPLO2, middleware.GetRequestID(c), lockout.Scope+" "+lockout.Key)
+		respondLoginLockout(c, lockout)
+		return
+	}
+	c.JSON(http.StatusUnauthorized, &response.LoginResponse{
+		Code:    failureCode,
+		Message: message,
+	})
+}
+
+// loginSucceeded resets the failed login counters of the account
+func (rcvr commonControllerForPublic) loginSucceeded(c *gin.Context, email string) {
+	if err := rcvr.LoginLockoutRepository.RegisterSuccess(c.Request.Context(), email); err != nil {
+		logger.Warn(code.CCPLO1, middleware.GetRequestID(c), err.Error())
+	}
+}
+
+// respondLoginLockout answers 429 with the seconds until the lockout ends in Retry-After
+func respondLoginLockout(c *gin.Context, lockout *model.LoginLockouts) {
+	c.Header("Retry-After", strconv.FormatInt(loginLockoutRetryAfter(lockout), 10))
+	c.JSON(http.StatusTooManyRequests, &response.LoginResponse{
+		Code:    "AUTH_LOGIN_009",
+		Message: "Too many failed login attempts, try again later",
+	})
+}
+
+func loginLockoutRetryAfter(lockout *model.LoginLockouts) int64 {
+	if retryAfter := lockout.LockedUntil - time.Now().Unix(); retryAfter > 1 {
+		return retryAfter
+	}
+	return 1
+}
+
+// VerifyMFA completes a two-step login.
+//
+// This endpoint exchanges the challenge token returned by Login together
+// with a TOTP code or an unused recovery code for a token pair. A challenge
+// can be used once and is discarded after repeated wrong codes. Wrong codes
+// count toward the login lockout of the account like wrong passwords, and
+// locked accounts are answered with 429, code AUTH_LOGIN_009 and Retry-After.
+//
+// Route: POST /v1/share/common/auth/tokens/mfa
+// Security: None (MFA challenge token required)
+//
+// swagger:route POST /share/common/auth/tokens/mfa Authentication verifyMFA
+//
+// # Complete login with a second factor
+//
+// Exchanges an MFA challenge token and code for JWT tokens.
+//
+// Responses:
+//
+//	200: loginResponse
+//	400: errorResponse
+//	401: errorResponse
+//	429: errorResponse
+//	500: errorResponse
+func (rcvr commonControllerForPublic) VerifyMFA(c *gin.Context) {
+	var mfaRequest request.MFALoginRequest
+	if err := c.ShouldBindJSON(&mfaRequest); err != nil {
+		c.JSON(http.StatusBadRequest, &response.LoginResponse{
+			Code:    "AUTH_MFA_001",
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+	if mfaRequest.MFAToken == "" || mfaRequest.Code == "" {
+		c.JSON(http.StatusBadRequest, &response.LoginResponse{
+			Code:    "AUTH_MFA_002",
+			Message: "mfa_token and code are required",
 		})
 		return
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(foundUser.Password), []byte(loginRequest.Password)); err != nil {
+	email, err := rcvr.MFARepository.ChallengeEmail(c.Request.Context(), mfaRequest.MFAToken)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	if lockout := rcvr.checkLoginLockout(c, email); lockout != nil {
+		respondLoginLockout(c, lockout)
+		return
+	}
+	userUUID, err := rcvr.MFARepository.CompleteChallenge(c, mfaRequest.MFAToken, mfaRequest.Code)
+	if errors.Is(err, repository.ErrMFAInvalidCode) {
+		rcvr.loginFailed(c, email, "AUTH_MFA_003", err.Error())
+		return
+	}
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	rcvr.loginSucceeded(c, email)
+
+	users, err := rcvr.UserRepository.ListUsers(c, repository.UserQueryFilter{UUID: &userUUID, Limit: 1})
+	if err != nil || len(users) == 0 {
 		c.JSON(http.StatusUnauthorized, &response.LoginResponse{
-			Code:    "AUTH_LOGIN_004",
-			Message: "Invalid email or password",
+			Code:    "AUTH_MFA_005",
+			Message: "User not found",
 		})
 		return
 	}
 
-	// Determine user role (simple logic - can be enhanced)
-	role := "user"
-	baseConfig := rcvr.CommonRepository.GetBaseConfig()
-	for _, adminEmail := range baseConfig.YamlConfig.Application.Server.Admin.Emails {
-		if foundUser.Email == adminEmail {
-			role = "admin"
-			break
-		}
+	rcvr.issueLoginTokens(c, &users[0], "AUTH_MFA_006")
+}
+
+// respondMFAError answers 401 for unknown challenges and 500 for storage errors
+func respondMFAError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrMFAChallengeInvalid) || errors.Is(err, repository.ErrMFANotEnrolled) {
+		c.JSON(http.StatusUnauthorized, &response.LoginResponse{
+			Code:    "AUTH_MFA_003",
+			Message: err.Error(),
+		})
+		return
 	}
+	c.JSON(http.StatusInternalServerError, &response.LoginResponse{
+		Code:    "AUTH_MFA_004",
+		Message: "Failed to verify code: " + err.Error(),
+	})
+}
+
+// issueLoginTokens generates a token pair for an authenticated user and writes the login response
+func (rcvr commonControllerForPublic) issueLoginTokens(c *gin.Context, user *model.Users, failureCode string) {
+	// Determine user roles (admin.emails or the role mapped from the directory, then granted roles)
+	roles := rcvr.CommonRepository.LoadUserRoles(*user)
 
 	// Generate token pair
 	tokenPair, err := rcvr.CommonRepository.GenerateTokenPair(
-		foundUser.ID,
-		foundUser.UUID,
-		foundUser.Email,
-		foundUser.Name,
-		role,
+		user.ID,
+		user.UUID,
+		user.Email,
+		user.Name,
+		roles...,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, &response.LoginResponse{
-			Code:    "AUTH_LOGIN_005",
+			Code:    failureCode,
 			Message: "Failed to generate tokens: " + err.Error(),
 		})
 		return
 	}
+	rcvr.touchSession(c, tokenPair.AccessToken)
 
 	// Prepare user response
 	userResponse := &response.User{
-		ID:    foundUser.ID,
-		UUID:  foundUser.UUID,
-		Email: foundUser.Email,
-		Name:  foundUser.Name,
+		ID:    user.ID,
+		UUID:  user.UUID,
+		Email: user.Email,
+		Name:  user.Name,
 	}
 
 	c.JSON(http.StatusOK, &response.LoginResponse{
@@ -285,7 +526,9 @@ func (rcvr commonControllerForPublic) Login(c *gin.Context) {
 // RefreshToken handles JWT token refresh.
 //
 // This endpoint accepts a valid refresh token and returns a new
-// access token and refresh token pair.
+// access token and refresh token pair. Refresh tokens are single-use:
+// the consumed token is retired, and presenting it again revokes every
+// token issued in the same family.
 //
 // Route: POST /v1/share/common/auth/tokens/refresh
 // Security: Refresh token required
@@ -312,31 +555,40 @@ func (rcvr commonControllerForPublic) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Validate refresh token
-	claims, err := rcvr.CommonRepository.ValidateJWTToken(refreshRequest.RefreshToken)
+	// Rotate refresh token (single use, reuse revokes the whole token family)
+	tokenPair, err := rcvr.CommonRepository.RotateRefreshToken(c.Request.Context(), refreshRequest.RefreshToken, "")
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, &response.RefreshTokenResponse{
-			Code:    "AUTH_REFRESH_002",
-			Message: "Invalid refresh token: " + err.Error(),
-		})
+		switch {
+		case errors.Is(err, repository.ErrInvalidTokenUse):
+			c.JSON(http.StatusUnauthorized, &response.RefreshTokenResponse{
+				Code:    "AUTH_REFRESH_004",
+				Message: "Token is not a refresh token",
+			})
+		case errors.Is(err, repository.ErrTokenClientMismatch):
+			c.JSON(http.StatusUnauthorized, &response.RefreshTokenResponse{
+				Code:    "AUTH_REFRESH_006",
+				Message: "Refresh token was issued to an OAuth client, use its token endpoint",
+			})
+		case errors.Is(err, repository.ErrRefreshTokenReused), errors.Is(err, repository.ErrTokenFamilyRevoked):
+			c.JSON(http.StatusUnauthorized, &response.RefreshTokenResponse{
+				Code:    "AUTH_REFRESH_005",
+				Message: "Refresh token has already been used or revoked",
+			})
+		case errors.Is(err, repository.ErrTokenGeneration):
+			c.JSON(http.StatusInternalServerError, &response.RefreshTokenResponse{
+				Code:    "AUTH_REFRESH_003",
+				Message: "Failed to generate new tokens: " + err.Error(),
+			})
+		default:
+			c.JSON(http.StatusUnauthorized, &response.RefreshTokenResponse{
+				Code:    "AUTH_REFRESH_002",
+				Message: "Invalid refresh token: " + err.Error(),
+			})
+		}
 		return
 	}
 
-	// Generate new token pair
-	tokenPair, err := rcvr.CommonRepository.GenerateTokenPair(
-		claims.UserID,
-		claims.UUID,
-		claims.Email,
-		claims.Name,
-		claims.Role,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, &response.RefreshTokenResponse{
-			Code:    "AUTH_REFRESH_003",
-			Message: "Failed to generate new tokens: " + err.Error(),
-		})
-		return
-	}
+	rcvr.touchSession(c, tokenPair.AccessToken)
 
 	c.JSON(http.StatusOK, &response.RefreshTokenResponse{
 		Code:      "SUCCESS",
@@ -348,7 +600,8 @@ func (rcvr commonControllerForPublic) RefreshToken(c *gin.Context) {
 // Logout handles user logout by invalidating the token.
 //
 // This endpoint invalidates a JWT token by adding it to a Redis denylist.
-// The token will be rejected in subsequent validation attempts.
+// The token will be rejected in subsequent validation attempts. Its token
+// family is revoked too, so the paired refresh token can no longer be used.
 //
 // Route: DELETE /v1/share/common/auth/tokens
 // Security: Bearer token required
@@ -402,6 +655,18 @@ func (rcvr commonControllerForPublic) Logout(c *gin.Context) {
 		return
 	}
 
+	// Revoke the token family so that the paired refresh token dies as well
+	if claims, err := rcvr.CommonRepository.ValidateJWTToken(tokenString); err == nil && claims.FamilyID != "" {
+		if err := rcvr.CommonRepository.RevokeTokenFamily(c.Request.Context(), claims.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "AUTH_LOGOUT_004",
+				"message": "Failed to revoke refresh token",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
 	// Delete cache (interface method)
 	rcvr.CommonRepository.DeleteTokenCache(tokenString)
 
@@ -411,6 +676,38 @@ func (rcvr commonControllerForPublic) Logout(c *gin.Context) {
 	})
 }
 
+// GetJWKS returns the public keys used to sign JWT tokens.
+//
+// Other services use this JSON Web Key Set to verify tokens issued by Locky
+// without sharing a secret. The key is selected by the "kid" token header.
+// HS256 keys are never published.
+//
+// Route: GET /v1/share/common/auth/.well-known/jwks.json
+// Security: None
+//
+// swagger:route GET /share/common/auth/.well-known/jwks.json Authentication getJWKS
+//
+// # Public token signing keys
+//
+// Returns the JSON Web Key Set containing active and retired verification keys.
+//
+// Responses:
+//
+//	200: jwksResponse
+func (rcvr commonControllerForPublic) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, rcvr.CommonRepository.GetJWKS())
+}
+
+// touchSession records client information on the session of a newly issued token
+func (rcvr commonControllerForPublic) touchSession(c *gin.Context, accessToken string) {
+	claims, err := rcvr.CommonRepository.ParseTokenUnverified(accessToken)
+	if err != nil {
+		return
+	}
+	_ = rcvr.CommonRepository.TouchSession(c.Request.Context(), claims.FamilyID, c.Request.UserAgent(), c.ClientIP())
+}
+
 // NewCommonControllerForPublic creates a new instance of CommonControllerForPublic.
 //
 // This constructor function initializes a new CommonControllerForPublic with the
@@ -419,12 +716,17 @@ func (rcvr commonControllerForPublic) Logout(c *gin.Context) {
 // Parameters:
 //   - userRepository: Repository for user data operations
 //   - commonRepository: Repository for common operations including JWT token management
+//   - mfaRepository: Repository for TOTP enrollment and login challenges
+//   - loginLockoutRepository: Repository for failed login counters and lockouts
 //
 // Returns:
 //   - CommonControllerForPublic: Configured controller instance ready for use
-func NewCommonControllerForPublic(userRepository repository.UserRepository, commonRepository repository.CommonRepository) CommonControllerForPublic {
+func NewCommonControllerForPublic(userRepository repository.UserRepository, commonRepository repository.CommonRepository, mfaRepository repository.MFARepository, loginLockoutRepository repository.LoginLockoutRepository, authenticatorChain repository.AuthenticatorChain) CommonControllerForPublic {
 	return &commonControllerForPublic{
-		UserRepository:   userRepository,
-		CommonRepository: commonRepository,
+		UserRepository:         userRepository,
+		CommonRepository:       commonRepository,
+		MFARepository:          mfaRepository,
+		LoginLockoutRepository: loginLockoutRepository,
+		AuthenticatorChain:     authenticatorChain,
 	}
 }
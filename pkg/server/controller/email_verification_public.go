@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// verificationResendMessage is returned whether or not the email is registered
+const verificationResendMessage = "If the email address is registered and not yet verified, a verification link has been sent"
+
+// EmailVerificationControllerForPublic confirms the email address of self-registered users.
+//
+//   - VerifyEmail: Mark the address as verified (POST /v1/public/user/verify)
+//   - ResendVerification: Email a new verification link (POST /v1/public/user/verify/resend)
+//
+// ResendVerification answers the same way whether or not the email exists.
+type EmailVerificationControllerForPublic interface {
+	VerifyEmail(c *gin.Context)
+	ResendVerification(c *gin.Context)
+}
+
+type emailVerificationControllerForPublic struct {
+	UserRepository              repository.UserRepository
+	CommonRepository            repository.CommonRepository
+	EmailVerificationRepository repository.EmailVerificationRepository
+}
+
+// VerifyEmail marks the address in a verification token as verified and sends the welcome email.
+//
+// Route: POST /v1/public/user/verify
+// Security: None (verification token required)
+func (rcvr emailVerificationControllerForPublic) VerifyEmail(c *gin.Context) {
+	var req request.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "USER_VERIFY_001", Message: "token is required"})
+		return
+	}
+	claims, err := rcvr.EmailVerificationRepository.ParseVerificationToken(req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "USER_VERIFY_002", Message: "Invalid or expired verification token"})
+		return
+	}
+	updated, err := rcvr.EmailVerificationRepository.MarkVerified(c, claims.UUID, claims.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "USER_VERIFY_003", Message: "Failed to verify email address: " + err.Error()})
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+	if !updated {
+		// Opening the link twice is fine; a link for a changed or deleted address is not
+		users, err := rcvr.UserRepository.ListUsers(c, repository.UserQueryFilter{UUID: &claims.UUID, Limit: 1})
+		if err == nil && len(users) > 0 && users[0].Email == claims.Email && users[0].EmailVerifiedAt != nil {
+			c.JSON(http.StatusOK, &response.CommonResponse{Code: "SUCCESS", Message: "Email address already verified"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "USER_VERIFY_002", Message: "Invalid or expired verification token"})
+		return
+	}
+
+	logger.Info(code.VCPVE1, requestID, claims.UUID)
+	email, name := claims.Email, claims.Name
+	go func() {
+		if err := rcvr.CommonRepository.SendWelcomeEmail(context.Background(), email, name); err != nil {
+			logger.Error(code.VCPVE2, requestID, err.Error())
+		}
+	}()
+	c.JSON(http.StatusOK, &response.CommonResponse{Code: "SUCCESS", Message: "Email address verified"})
+}
+
+// ResendVerification sends a new verification link to an unverified address.
+// Repeated requests for the same user within resend_interval_seconds are ignored.
+//
+// Route: POST /v1/public/user/verify/resend
+// Security: None
+func (rcvr emailVerificationControllerForPublic) ResendVerification(c *gin.Context) {
+	var req request.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "USER_VERIFY_RESEND_001", Message: "email is required"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	user, err := rcvr.UserRepository.GetUserByEmail(c, email)
+	if err == nil && user.EmailVerifiedAt == nil {
+		allowed, err := rcvr.EmailVerificationRepository.AllowResend(c.Request.Context(), user.UUID)
+		if err != nil {
+			logger.Error(code.VCPSE1, middleware.GetRequestID(c), err.Error())
+		} else if allowed {
+			logger.Info(code.VCPRV1, middleware.GetRequestID(c), user.UUID)
+			sendVerificationEmail(c, rcvr.CommonRepository, rcvr.EmailVerificationRepository, user)
+		}
+	}
+	c.JSON(http.StatusOK, &response.CommonResponse{Code: "SUCCESS", Message: verificationResendMessage})
+}
+
+// sendVerificationEmail signs a verification link for the user and mails it in the background
+func sendVerificationEmail(c *gin.Context, commonRepository repository.CommonRepository, emailVerificationRepository repository.EmailVerificationRepository, user model.Users) {
+	requestID := middleware.GetRequestID(c)
+	token, err := emailVerificationRepository.CreateVerificationToken(user)
+	if err != nil {
+		logger.Error(code.VCPSE1, requestID, err.Error())
+		return
+	}
+	verifyURL := emailVerificationRepository.VerifyURL(token)
+	go func() {
+		if err := commonRepository.SendVerificationEmail(context.Background(), user.Email, user.Name, verifyURL); err != nil {
+			logger.Error(code.VCPSE1, requestID, err.Error())
+		}
+	}()
+}
+
+// NewEmailVerificationControllerForPublic creates a new public email verification controller.
+func NewEmailVerificationControllerForPublic(userRepository repository.UserRepository, commonRepository repository.CommonRepository, emailVerificationRepository repository.EmailVerificationRepository) EmailVerificationControllerForPublic {
+	return &emailVerificationControllerForPublic{
+		UserRepository:              userRepository,
+		CommonRepository:            commonRepository,
+		EmailVerificationRepository: emailVerificationRepository,
+	}
+}
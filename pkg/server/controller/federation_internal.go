@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// FederationControllerForInternal lets the caller manage the upstream
+// identities linked to their account.
+//
+//   - ListMyIdentities: Linked identities (GET /v1/internal/me/identities)
+//   - LinkMyIdentity: Start linking, returns the provider URL (POST /v1/internal/me/identity/:provider)
+//   - UnlinkMyIdentity: Remove a link (DELETE /v1/internal/me/identity/:id)
+//
+// Personal access tokens and service accounts cannot manage identities.
+type FederationControllerForInternal interface {
+	ListMyIdentities(c *gin.Context)
+	LinkMyIdentity(c *gin.Context)
+	UnlinkMyIdentity(c *gin.Context)
+}
+
+type federationControllerForInternal struct {
+	FederationRepository   repository.FederationRepository
+	IdentityLinkRepository repository.IdentityLinkRepository
+	UserRepository         repository.UserRepository
+}
+
+// ListMyIdentities returns the identities linked to the caller.
+//
+// Route: GET /v1/internal/me/identities
+// Security: Bearer token
+func (rcvr federationControllerForInternal) ListMyIdentities(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	links, err := rcvr.IdentityLinkRepository.ListLinks(c, claims.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.IdentityLinkResponse{Code: "IDENTITY_LIST_001", Message: err.Error()})
+		return
+	}
+	identities := make([]response.IdentityLink, 0, len(links))
+	for _, link := range links {
+		identities = append(identities, toIdentityLinkResponse(link))
+	}
+	c.JSON(http.StatusOK, &response.IdentityLinkResponse{Code: "SUCCESS", Message: "Identities retrieved successfully", Identities: identities})
+}
+
+// LinkMyIdentity starts linking an identity of the provider to the caller.
+// The returned URL is opened in the same browser, which receives the state
+// cookie with this response; the provider redirects back to the federation
+// callback, which stores the link.
+//
+// Route: POST /v1/internal/me/identity/:provider
+// Security: Bearer token
+func (rcvr federationControllerForInternal) LinkMyIdentity(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	authorizationURL, binding, err := rcvr.FederationRepository.AuthorizationURL(c.Request.Context(), c.Param("provider"), claims.UUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrFederationProviderNotFound):
+			c.JSON(http.StatusNotFound, &response.IdentityLinkResponse{Code: "IDENTITY_LINK_001", Message: err.Error()})
+		case errors.Is(err, repository.ErrFederationUpstream):
+			c.JSON(http.StatusBadGateway, &response.IdentityLinkResponse{Code: "IDENTITY_LINK_002", Message: "The identity provider is unavailable, try again later"})
+		default:
+			c.JSON(http.StatusInternalServerError, &response.IdentityLinkResponse{Code: "IDENTITY_LINK_003", Message: err.Error()})
+		}
+		return
+	}
+	// The callback only completes the link in the browser that received the cookie
+	setFederationStateCookie(c, binding)
+	c.JSON(http.StatusOK, &response.IdentityLinkResponse{
+		Code:             "SUCCESS",
+		Message:          "Open the authorization URL to link the identity",
+		AuthorizationURL: authorizationURL,
+	})
+}
+
+// UnlinkMyIdentity removes one of the caller's links. Users created through a
+// provider keep at least one link, since they have no usable password.
+//
+// Route: DELETE /v1/internal/me/identity/:id
+// Security: Bearer token
+func (rcvr federationControllerForInternal) UnlinkMyIdentity(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	linkUUID := c.Param("id")
+	links, err := rcvr.IdentityLinkRepository.ListLinks(c, claims.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.IdentityLinkResponse{Code: "IDENTITY_UNLINK_001", Message: err.Error()})
+		return
+	}
+	found := false
+	for _, link := range links {
+		found = found || link.UUID == linkUUID
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, &response.IdentityLinkResponse{Code: "IDENTITY_UNLINK_002", Message: repository.ErrIdentityLinkNotFound.Error()})
+		return
+	}
+	if len(links) == 1 {
+		users, err := rcvr.UserRepository.ListUsers(c, repository.UserQueryFilter{UUID: &claims.UUID, Limit: 1})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &response.IdentityLinkResponse{Code: "IDENTITY_UNLINK_001", Message: err.Error()})
+			return
+		}
+		for _, user := range users {
+			if user.UUID == claims.UUID && strings.HasPrefix(user.AuthSource, repository.FederatedAuthSource("")) {
+				c.JSON(http.StatusConflict, &response.IdentityLinkResponse{Code: "IDENTITY_UNLINK_003", Message: "The last linked identity is the only way to sign in to this account"})
+				return
+			}
+		}
+	}
+	if err := rcvr.IdentityLinkRepository.DeleteLink(c, claims.UUID, linkUUID); err != nil {
+		if errors.Is(err, repository.ErrIdentityLinkNotFound) {
+			c.JSON(http.StatusNotFound, &response.IdentityLinkResponse{Code: "IDENTITY_UNLINK_002", Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.IdentityLinkResponse{Code: "IDENTITY_UNLINK_001", Message: err.Error()})
+		return
+	}
+	logger.Info(code.FCIUL1, middleware.GetRequestID(c), claims.UUID+" "+linkUUID)
+	c.JSON(http.StatusOK, &response.IdentityLinkResponse{Code: "SUCCESS", Message: "Identity unlinked successfully"})
+}
+
+// sessionClaims returns the caller's claims, rejecting personal access tokens
+// and service accounts
+func (rcvr federationControllerForInternal) sessionClaims(c *gin.Context) (*model.JWTClaims, bool) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.IdentityLinkResponse{Code: "IDENTITY_AUTH_001", Message: "User not authenticated"})
+		return nil, false
+	}
+	if claims.TokenUse == model.TokenUsePAT || claims.ClientID != "" {
+		c.JSON(http.StatusForbidden, &response.IdentityLinkResponse{Code: "IDENTITY_AUTH_002", Message: "Identities can only be managed with a login session"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// NewFederationControllerForInternal creates the linked identity controller
+func NewFederationControllerForInternal(federationRepository repository.FederationRepository, identityLinkRepository repository.IdentityLinkRepository, userRepository repository.UserRepository) FederationControllerForInternal {
+	return &federationControllerForInternal{
+		FederationRepository:   federationRepository,
+		IdentityLinkRepository: identityLinkRepository,
+		UserRepository:         userRepository,
+	}
+}
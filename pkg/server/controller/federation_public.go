@@ -0,0 +1,346 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// FederationBasePath is the prefix of the federated login endpoints
+const FederationBasePath = "/v1/share/common/federation"
+
+// FederationStateCookie ties a login or link state to the browser that started
+// it. It is only sent to the federation endpoints.
+const FederationStateCookie = "locky_federation_state"
+
+// errFederationNotLinked means no user could be found or created for an identity
+var errFederationNotLinked = errors.New("no account is linked to this identity")
+
+// FederationControllerForPublic signs users in through upstream OpenID Connect
+// providers and answers with a regular Locky token pair.
+//
+//   - ListProviders: Configured providers (GET /v1/share/common/federation/providers)
+//   - Login: Redirect to the provider (GET /v1/share/common/federation/:provider/login)
+//   - Callback: Provider redirect target (GET /v1/share/common/federation/:provider/callback)
+type FederationControllerForPublic interface {
+	ListProviders(c *gin.Context)
+	Login(c *gin.Context)
+	Callback(c *gin.Context)
+}
+
+type federationControllerForPublic struct {
+	FederationRepository   repository.FederationRepository
+	IdentityLinkRepository repository.IdentityLinkRepository
+	UserRepository         repository.UserRepository
+	CommonRepository       repository.CommonRepository
+	// LoginController issues the tokens (or the MFA challenge) once the user is known
+	LoginController commonControllerForPublic
+}
+
+// ListProviders returns the providers users can sign in with.
+//
+// Route: GET /v1/share/common/federation/providers
+// Security: No authentication required
+func (rcvr federationControllerForPublic) ListProviders(c *gin.Context) {
+	providers := []response.FederationProvider{}
+	for _, provider := range rcvr.FederationRepository.ListProviders() {
+		providers = append(providers, response.FederationProvider{
+			Name:        provider.Name,
+			DisplayName: provider.DisplayName,
+			LoginURL:    FederationBasePath + "/" + url.PathEscape(provider.Name) + "/login",
+		})
+	}
+	c.JSON(http.StatusOK, &response.FederationProvidersResponse{
+		Code:      "SUCCESS",
+		Message:   "Identity providers retrieved successfully",
+		Providers: providers,
+	})
+}
+
+// Login redirects the browser to the provider's authorization endpoint.
+//
+// Route: GET /v1/share/common/federation/:provider/login
+// Security: No authentication required
+func (rcvr federationControllerForPublic) Login(c *gin.Context) {
+	authorizationURL, binding, err := rcvr.FederationRepository.AuthorizationURL(c.Request.Context(), c.Param("provider"), "")
+	if err != nil {
+		respondFederationError(c, err)
+		return
+	}
+	setFederationStateCookie(c, binding)
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// Callback completes a login (or an identity link started through the
+// internal API) when the provider redirects back. A login answers like
+// POST /tokens: a token pair, or an MFA challenge for users with MFA enabled.
+//
+// Route: GET /v1/share/common/federation/:provider/callback
+// Security: No authentication required (state parameter and state cookie)
+func (rcvr federationControllerForPublic) Callback(c *gin.Context) {
+	requestID := middleware.GetRequestID(c)
+	providerName := c.Param("provider")
+	if upstreamError := c.Query("error"); upstreamError != "" {
+		logger.Warn(code.FCPCB3, requestID, providerName+": "+upstreamError)
+		c.JSON(http.StatusUnauthorized, &response.LoginResponse{
+			Code:    "AUTH_FEDERATION_006",
+			Message: "Sign-in was refused by the identity provider: " + upstreamError,
+		})
+		return
+	}
+
+	// A missing cookie fails like a wrong one: the state cannot be redeemed
+	binding, _ := c.Cookie(FederationStateCookie)
+	identity, state, err := rcvr.FederationRepository.CompleteLogin(c.Request.Context(), providerName, c.Query("state"), c.Query("code"), binding)
+	if err != nil {
+		logger.Warn(code.FCPCB3, requestID, providerName+": "+err.Error())
+		respondFederationError(c, err)
+		return
+	}
+	setFederationStateCookie(c, "")
+	if state.LinkUserUUID != "" {
+		rcvr.linkIdentity(c, identity, state.LinkUserUUID)
+		return
+	}
+
+	user, err := rcvr.resolveUser(c, identity)
+	if err != nil {
+		logger.Warn(code.FCPCB3, requestID, providerName+" "+identity.Subject+": "+err.Error())
+		if errors.Is(err, errFederationNotLinked) {
+			c.JSON(http.StatusForbidden, &response.LoginResponse{
+				Code:    "AUTH_FEDERATION_007",
+				Message: "No account is linked to this identity, sign in and link it first",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.LoginResponse{
+			Code:    "AUTH_FEDERATION_005",
+			Message: "Failed to sign in: " + err.Error(),
+		})
+		return
+	}
+	if user.DeletedAt != nil {
+		c.JSON(http.StatusForbidden, &response.LoginResponse{
+			Code:    "AUTH_LOGIN_012",
+			Message: "Account is disabled",
+		})
+		return
+	}
+	logger.Info(code.FCPCB1, requestID, providerName+" "+user.UUID)
+	rcvr.LoginController.completeLogin(c, user, user.Email)
+}
+
+// resolveUser returns the user linked to the identity. Unknown identities are
+// linked to the user with the same verified email (link_by_email), or get a
+// new user (auto_provision).
+func (rcvr federationControllerForPublic) resolveUser(c *gin.Context, identity *model.FederatedIdentity) (*model.Users, error) {
+	link, err := rcvr.IdentityLinkRepository.GetLink(c, identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := rcvr.findUser(c, repository.UserQueryFilter{UUID: &link.UserUUID, Limit: 1}, func(u model.Users) bool { return u.UUID == link.UserUUID })
+		if err != nil {
+			return nil, err
+		}
+		if err := rcvr.IdentityLinkRepository.TouchLink(c, *link); err != nil {
+			logger.Warn(code.FCPCB3, middleware.GetRequestID(c), err.Error())
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrIdentityLinkNotFound) {
+		return nil, err
+	}
+
+	provider, err := rcvr.FederationRepository.GetProvider(identity.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if !identity.EmailVerified || (!provider.LinkByEmail && !provider.AutoProvision) {
+		return nil, errFederationNotLinked
+	}
+	user, err := rcvr.findUserByEmail(c, identity.Email)
+	switch {
+	case err == nil && provider.LinkByEmail:
+	case errors.Is(err, errFederationNotLinked) && provider.AutoProvision:
+		if user, err = rcvr.provisionUser(c, provider, identity); err != nil {
+			return nil, err
+		}
+	case err == nil:
+		// The email belongs to an existing account that has to link the identity itself
+		return nil, errFederationNotLinked
+	default:
+		return nil, err
+	}
+
+	if _, err := rcvr.IdentityLinkRepository.CreateLink(c, model.IdentityLinks{
+		UserUUID: user.UUID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return nil, err
+	}
+	logger.Info(code.FCPCB2, middleware.GetRequestID(c), identity.Provider+" "+user.UUID)
+	return user, nil
+}
+
+// findUser returns the user of the filter, or errFederationNotLinked
+func (rcvr federationControllerForPublic) findUser(c *gin.Context, filter repository.UserQueryFilter, match func(model.Users) bool) (*model.Users, error) {
+	users, err := rcvr.UserRepository.ListUsers(c, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if match(users[i]) {
+			return &users[i], nil
+		}
+	}
+	return nil, errFederationNotLinked
+}
+
+// findUserByEmail returns the user with the email, or errFederationNotLinked
+func (rcvr federationControllerForPublic) findUserByEmail(c *gin.Context, email string) (*model.Users, error) {
+	user, err := rcvr.UserRepository.GetUserByEmail(c, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, errFederationNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// provisionUser creates a user for an identity with a verified email. The user
+// signs in through the provider only: the local password is unusable.
+func (rcvr federationControllerForPublic) provisionUser(c *gin.Context, provider *config.UpstreamProvider, identity *model.FederatedIdentity) (*model.Users, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	hash, err := rcvr.CommonRepository.HashPassword(base64.RawURLEncoding.EncodeToString(secret))
+	if err != nil {
+		return nil, err
+	}
+	name := identity.Name
+	if name == "" {
+		name = identity.Email
+	}
+	now := time.Now()
+	created, err := rcvr.UserRepository.CreateUser(c, model.Users{
+		UUID:              uuid.New().String(),
+		Email:             identity.Email,
+		Password:          hash,
+		Name:              name,
+		EmailVerifiedAt:   &now,
+		PasswordChangedAt: &now,
+		AuthSource:        repository.FederatedAuthSource(provider.Name),
+		CreatedAt:         &now,
+		UpdatedAt:         &now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision federated user: %w", err)
+	}
+	logger.Info(code.FCPCB4, middleware.GetRequestID(c), provider.Name+" "+created.UUID)
+	return &created, nil
+}
+
+// linkIdentity finishes a link started with POST /v1/internal/me/identity/:provider
+func (rcvr federationControllerForPublic) linkIdentity(c *gin.Context, identity *model.FederatedIdentity, userUUID string) {
+	link, err := rcvr.IdentityLinkRepository.GetLink(c, identity.Provider, identity.Subject)
+	if err == nil {
+		if link.UserUUID != userUUID {
+			c.JSON(http.StatusConflict, &response.IdentityLinkResponse{Code: "AUTH_FEDERATION_008", Message: repository.ErrIdentityLinkExists.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, &response.IdentityLinkResponse{Code: "SUCCESS", Message: "Identity is already linked", Identities: []response.IdentityLink{toIdentityLinkResponse(*link)}})
+		return
+	}
+	if errors.Is(err, repository.ErrIdentityLinkNotFound) {
+		link, err = rcvr.IdentityLinkRepository.CreateLink(c, model.IdentityLinks{
+			UserUUID: userUUID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrIdentityLinkExists) {
+			c.JSON(http.StatusConflict, &response.IdentityLinkResponse{Code: "AUTH_FEDERATION_008", Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.IdentityLinkResponse{Code: "AUTH_FEDERATION_005", Message: "Failed to link identity: " + err.Error()})
+		return
+	}
+	logger.Info(code.FCPCB2, middleware.GetRequestID(c), identity.Provider+" "+userUUID)
+	c.JSON(http.StatusOK, &response.IdentityLinkResponse{Code: "SUCCESS", Message: "Identity linked successfully", Identities: []response.IdentityLink{toIdentityLinkResponse(*link)}})
+}
+
+// setFederationStateCookie hands the state binding to the browser, or clears it
+// when binding is empty. SameSite=Lax still sends it on the provider's
+// top-level redirect back to the callback.
+func setFederationStateCookie(c *gin.Context, binding string) {
+	maxAge := -1
+	if binding != "" {
+		maxAge = 0
+	}
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(FederationStateCookie, binding, maxAge, FederationBasePath, "", secure, true)
+}
+
+// respondFederationError maps repository errors of the federated login flow
+func respondFederationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrFederationProviderNotFound):
+		c.JSON(http.StatusNotFound, &response.LoginResponse{Code: "AUTH_FEDERATION_001", Message: err.Error()})
+	case errors.Is(err, repository.ErrFederationInvalidState):
+		c.JSON(http.StatusBadRequest, &response.LoginResponse{Code: "AUTH_FEDERATION_002", Message: err.Error()})
+	case errors.Is(err, repository.ErrFederationInvalidIDToken):
+		c.JSON(http.StatusUnauthorized, &response.LoginResponse{Code: "AUTH_FEDERATION_003", Message: "The identity provider returned an invalid ID token"})
+	case errors.Is(err, repository.ErrFederationUpstream):
+		c.JSON(http.StatusBadGateway, &response.LoginResponse{Code: "AUTH_FEDERATION_004", Message: "The identity provider is unavailable, try again later"})
+	case errors.Is(err, repository.ErrFederationStorageUnavailable):
+		c.JSON(http.StatusServiceUnavailable, &response.LoginResponse{Code: "AUTH_FEDERATION_005", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, &response.LoginResponse{Code: "AUTH_FEDERATION_005", Message: err.Error()})
+	}
+}
+
+func toIdentityLinkResponse(link model.IdentityLinks) response.IdentityLink {
+	return response.IdentityLink{
+		UUID:        link.UUID,
+		Provider:    link.Provider,
+		Subject:     link.Subject,
+		Email:       link.Email,
+		LastLoginAt: link.LastLoginAt,
+		CreatedAt:   link.CreatedAt,
+	}
+}
+
+// NewFederationControllerForPublic creates the federated login controller
+func NewFederationControllerForPublic(federationRepository repository.FederationRepository, identityLinkRepository repository.IdentityLinkRepository, userRepository repository.UserRepository, commonRepository repository.CommonRepository, mfaRepository repository.MFARepository, loginLockoutRepository repository.LoginLockoutRepository) FederationControllerForPublic {
+	return &federationControllerForPublic{
+		FederationRepository:   federationRepository,
+		IdentityLinkRepository: identityLinkRepository,
+		UserRepository:         userRepository,
+		CommonRepository:       commonRepository,
+		LoginController: commonControllerForPublic{
+			UserRepository:         userRepository,
+			CommonRepository:       commonRepository,
+			MFARepository:          mfaRepository,
+			LoginLockoutRepository: loginLockoutRepository,
+		},
+	}
+}
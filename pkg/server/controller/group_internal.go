@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/entity/request"
@@ -181,7 +182,7 @@ func (rcvr groupControllerForInternal) UpdateGroup(c *gin.Context) {
 	//     schema:
 	//       $ref: "#/definitions/GroupResponse"
 	var groupRequest request.GroupRequest
-	if err := c.Bind(&groupRequest); err != nil {
+	if err := c.ShouldBindBodyWith(&groupRequest, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, &response.GroupResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__001", Message: err.Error(), Groups: []response.Group{}})
 		return
 	}
@@ -232,7 +233,7 @@ func (rcvr groupControllerForInternal) DeleteGroup(c *gin.Context) {
 	//     schema:
 	//       $ref: "#/definitions/GroupResponse"
 	var groupRequest request.GroupRequest
-	if err := c.Bind(&groupRequest); err != nil {
+	if err := c.ShouldBindBodyWith(&groupRequest, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, &response.GroupResponse{Code: "SERVER_CONTROLLER_DELETE__FOR__001", Message: err.Error(), Groups: []response.Group{}})
 		return
 	}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// Token exchange (RFC 8693) grant and token type identifiers
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// ImpersonationControllerForPrivate lets admins act as another user.
+//
+//   - ExchangeToken: Issue an impersonation token for a user (POST /v1/private/token/exchange)
+//
+// Requests made with the issued token are flagged in the access log, and the
+// token cannot be used to change credentials.
+type ImpersonationControllerForPrivate interface {
+	ExchangeToken(c *gin.Context)
+}
+
+type impersonationControllerForPrivate struct {
+	ImpersonationRepository repository.ImpersonationRepository
+	UserRepository          repository.UserRepository
+}
+
+// ExchangeToken issues a short-lived access token for requested_subject carrying an
+// act claim that names the caller (admin only, form or JSON body).
+//
+// Route: POST /v1/private/token/exchange
+// Security: Bearer token (admin)
+func (rcvr impersonationControllerForPrivate) ExchangeToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req request.TokenExchangeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "malformed token exchange request")
+		return
+	}
+	if req.GrantType != GrantTypeTokenExchange {
+		oauthJSONError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be "+GrantTypeTokenExchange)
+		return
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "only access tokens can be requested")
+		return
+	}
+	subject := strings.TrimSpace(req.RequestedSubject)
+	if subject == "" {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "requested_subject is required")
+		return
+	}
+	actor, ok := middleware.GetUserClaims(c)
+	if !ok {
+		oauthJSONError(c, http.StatusUnauthorized, "invalid_token", "authentication required")
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+	target, err := rcvr.findUser(c, subject)
+	if err != nil {
+		logger.Error(code.ICPET3, requestID, err.Error())
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to look up requested_subject")
+		return
+	}
+	if target == nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_target", "requested_subject not found")
+		return
+	}
+
+	token, expiresIn, err := rcvr.ImpersonationRepository.IssueToken(*actor, *target)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrImpersonationDisabled):
+			oauthJSONError(c, http.StatusForbidden, "access_denied", "impersonation is disabled")
+		case errors.Is(err, repository.ErrImpersonationForbidden):
+			logger.Warn(code.ICPET2, requestID, actor.Email+" as "+target.Email)
+			oauthJSONError(c, http.StatusForbidden, "access_denied", "not allowed to impersonate this user")
+		default:
+			logger.Error(code.ICPET3, requestID, err.Error())
+			oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to issue token")
+		}
+		return
+	}
+
+	logger.Info(code.ICPET1, requestID, actor.Email+" as "+target.Email)
+	c.JSON(http.StatusOK, &response.TokenExchangeResponse{
+		AccessToken:     token,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       expiresIn,
+	})
+}
+
+// findUser looks a user up by ID, email or UUID
+func (rcvr impersonationControllerForPrivate) findUser(c *gin.Context, subject string) (*model.Users, error) {
+	filter := repository.UserQueryFilter{Limit: 1}
+	if id64, err := strconv.ParseUint(subject, 10, 64); err == nil {
+		id := uint(id64)
+		filter.ID = &id
+	} else if strings.Contains(subject, "@") {
+		filter.Email = &subject
+	} else {
+		filter.UUID = &subject
+	}
+	users, err := rcvr.UserRepository.ListUsers(c, filter)
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	return &users[0], nil
+}
+
+// NewImpersonationControllerForPrivate creates a new private (admin) impersonation controller.
+func NewImpersonationControllerForPrivate(impersonationRepository repository.ImpersonationRepository, userRepository repository.UserRepository) ImpersonationControllerForPrivate {
+	return &impersonationControllerForPrivate{
+		ImpersonationRepository: impersonationRepository,
+		UserRepository:          userRepository,
+	}
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// LoginLockoutControllerForPrivate lets admins inspect and lift login lockouts.
+//
+//   - ListLockouts: List active lockouts (GET /v1/private/lockouts?scope=account|ip)
+//   - ClearLockout: Lift a lockout and reset its counters (DELETE /v1/private/lockout/{scope}/{key})
+//
+// {key} is the email address for the account scope and the client IP for the ip scope.
+type LoginLockoutControllerForPrivate interface {
+	ListLockouts(c *gin.Context)
+	ClearLockout(c *gin.Context)
+}
+
+type loginLockoutControllerForPrivate struct {
+	LoginLockoutRepository repository.LoginLockoutRepository
+}
+
+// ListLockouts lists the accounts and client IPs that currently cannot log in (admin only).
+//
+// Route: GET /v1/private/lockouts
+// Security: Bearer token (admin)
+func (rcvr loginLockoutControllerForPrivate) ListLockouts(c *gin.Context) {
+	scope := c.Query("scope")
+	if scope != "" && !repository.ValidLoginLockoutScope(scope) {
+		c.JSON(http.StatusBadRequest, &response.LoginLockoutResponse{Code: "LOCKOUT_LIST_001", Message: "scope must be account or ip", Lockouts: []response.LoginLockout{}})
+		return
+	}
+	lockouts, err := rcvr.LoginLockoutRepository.ListLockouts(c.Request.Context(), scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.LoginLockoutResponse{Code: "LOCKOUT_LIST_002", Message: err.Error(), Lockouts: []response.LoginLockout{}})
+		return
+	}
+	res := make([]response.LoginLockout, 0, len(lockouts))
+	for i := range lockouts {
+		res = append(res, toLoginLockoutResponse(&lockouts[i]))
+	}
+	c.JSON(http.StatusOK, &response.LoginLockoutResponse{Code: "SUCCESS", Message: "Lockouts retrieved successfully", Lockouts: res})
+}
+
+// ClearLockout lifts the lockout of an account or client IP (admin only).
+//
+// Route: DELETE /v1/private/lockout/{scope}/{key}
+// Security: Bearer token (admin)
+func (rcvr loginLockoutControllerForPrivate) ClearLockout(c *gin.Context) {
+	scope, key := c.Param("scope"), c.Param("key")
+	if !repository.ValidLoginLockoutScope(scope) || key == "" {
+		c.JSON(http.StatusBadRequest, &response.LoginLockoutResponse{Code: "LOCKOUT_CLEAR_001", Message: "scope must be account or ip and key is required", Lockouts: []response.LoginLockout{}})
+		return
+	}
+	cleared, err := rcvr.LoginLockoutRepository.ClearLockout(c.Request.Context(), scope, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.LoginLockoutResponse{Code: "LOCKOUT_CLEAR_002", Message: err.Error(), Lockouts: []response.LoginLockout{}})
+		return
+	}
+	if !cleared {
+		// Counters are reset either way, but report that nothing was blocked
+		c.JSON(http.StatusNotFound, &response.LoginLockoutResponse{Code: "LOCKOUT_CLEAR_003", Message: "No active lockout", Lockouts: []response.LoginLockout{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.LoginLockoutResponse{Code: "SUCCESS", Message: "Lockout cleared successfully", Lockouts: []response.LoginLockout{}})
+}
+
+func toLoginLockoutResponse(lockout *model.LoginLockouts) response.LoginLockout {
+	return response.LoginLockout{
+		Scope:       lockout.Scope,
+		Key:         lockout.Key,
+		Reason:      lockout.Reason,
+		Failures:    lockout.Failures,
+		Level:       lockout.Level,
+		LockedUntil: lockout.LockedUntil,
+		RetryAfter:  loginLockoutRetryAfter(lockout),
+	}
+}
+
+// NewLoginLockoutControllerForPrivate creates a new private (admin) login lockout controller.
+func NewLoginLockoutControllerForPrivate(loginLockoutRepository repository.LoginLockoutRepository) LoginLockoutControllerForPrivate {
+	return &loginLockoutControllerForPrivate{LoginLockoutRepository: loginLockoutRepository}
+}
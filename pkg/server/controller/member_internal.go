@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/entity/request"
@@ -111,7 +112,7 @@ func (rcvr memberControllerForInternal) CreateMember(c *gin.Context) {
 	//     schema:
 	//       $ref: "#/definitions/MemberResponse"
 	var memberRequest request.MemberRequest
-	if err := c.Bind(&memberRequest); err != nil {
+	if err := c.ShouldBindBodyWith(&memberRequest, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, &response.MemberResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__001", Message: err.Error(), Members: []response.Member{}})
 		return
 	}
@@ -160,7 +161,7 @@ func (rcvr memberControllerForInternal) UpdateMember(c *gin.Context) {
 	//     schema:
 	//       $ref: "#/definitions/MemberResponse"
 	var memberRequest request.MemberRequest
-	if err := c.Bind(&memberRequest); err != nil {
+	if err := c.ShouldBindBodyWith(&memberRequest, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, &response.MemberResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__001", Message: err.Error(), Members: []response.Member{}})
 		return
 	}
@@ -203,7 +204,7 @@ func (rcvr memberControllerForInternal) DeleteMember(c *gin.Context) {
 	//     schema:
 	//       $ref: "#/definitions/MemberResponse"
 	var memberRequest request.MemberRequest
-	if err := c.Bind(&memberRequest); err != nil {
+	if err := c.ShouldBindBodyWith(&memberRequest, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, &response.MemberResponse{Code: "SERVER_CONTROLLER_DELETE__FOR__001", Message: err.Error(), Members: []response.Member{}})
 		return
 	}
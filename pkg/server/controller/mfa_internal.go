@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// MFAControllerForInternal lets the caller manage their own TOTP enrollment.
+//
+//   - GetMyMFA: Enrollment status (GET /v1/internal/me/mfa)
+//   - EnrollMyMFA: Start enrollment, returns the secret (POST /v1/internal/me/mfa)
+//   - ConfirmMyMFA: Enable with a first code, returns recovery codes (POST /v1/internal/me/mfa/confirm)
+//   - DisableMyMFA: Disable with a current code (DELETE /v1/internal/me/mfa)
+//
+// Personal access tokens and service accounts cannot manage MFA.
+type MFAControllerForInternal interface {
+	GetMyMFA(c *gin.Context)
+	EnrollMyMFA(c *gin.Context)
+	ConfirmMyMFA(c *gin.Context)
+	DisableMyMFA(c *gin.Context)
+}
+
+type mfaControllerForInternal struct {
+	MFARepository repository.MFARepository
+}
+
+// GetMyMFA returns whether MFA is enabled for the caller.
+//
+// Route: GET /v1/internal/me/mfa
+// Security: Bearer token
+func (rcvr mfaControllerForInternal) GetMyMFA(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	enabled, err := rcvr.MFARepository.IsEnabled(c, claims.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_GET_001", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &response.MFAResponse{Code: "SUCCESS", Message: "MFA status retrieved successfully", Enabled: enabled})
+}
+
+// EnrollMyMFA starts enrollment and returns the secret and provisioning URI.
+// MFA stays disabled until the enrollment is confirmed with a code.
+//
+// Route: POST /v1/internal/me/mfa
+// Security: Bearer token
+func (rcvr mfaControllerForInternal) EnrollMyMFA(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	secret, err := rcvr.MFARepository.BeginEnrollment(c, claims.UUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, &response.MFAResponse{Code: "MFA_ENROLL_001", Message: err.Error(), Enabled: true})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_ENROLL_002", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &response.MFAResponse{
+		Code:            "SUCCESS",
+		Message:         "Scan the provisioning URI and confirm with a code",
+		Secret:          secret,
+		ProvisioningURI: repository.TOTPProvisioningURI(rcvr.MFARepository.Issuer(), claims.Email, secret),
+	})
+}
+
+// ConfirmMyMFA enables MFA after checking a first code, and returns the
+// recovery codes. They are shown only once.
+//
+// Route: POST /v1/internal/me/mfa/confirm
+// Security: Bearer token
+func (rcvr mfaControllerForInternal) ConfirmMyMFA(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	var req request.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, &response.MFAResponse{Code: "MFA_CONFIRM_001", Message: "code is required"})
+		return
+	}
+	codes, err := rcvr.MFARepository.ConfirmEnrollment(c, claims.UUID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrMFANotEnrolled):
+			c.JSON(http.StatusNotFound, &response.MFAResponse{Code: "MFA_CONFIRM_002", Message: err.Error()})
+		case errors.Is(err, repository.ErrMFAAlreadyEnabled):
+			c.JSON(http.StatusConflict, &response.MFAResponse{Code: "MFA_CONFIRM_003", Message: err.Error(), Enabled: true})
+		case errors.Is(err, repository.ErrMFAInvalidCode):
+			c.JSON(http.StatusBadRequest, &response.MFAResponse{Code: "MFA_CONFIRM_004", Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_CONFIRM_005", Message: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, &response.MFAResponse{Code: "SUCCESS", Message: "MFA enabled successfully", Enabled: true, RecoveryCodes: codes})
+}
+
+// DisableMyMFA disables MFA. A current code (or recovery code) is required.
+//
+// Route: DELETE /v1/internal/me/mfa
+// Security: Bearer token
+func (rcvr mfaControllerForInternal) DisableMyMFA(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	var req request.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, &response.MFAResponse{Code: "MFA_DISABLE_001", Message: "code is required"})
+		return
+	}
+	if err := rcvr.MFARepository.Verify(c, claims.UUID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrMFANotEnrolled):
+			c.JSON(http.StatusNotFound, &response.MFAResponse{Code: "MFA_DISABLE_002", Message: err.Error()})
+		case errors.Is(err, repository.ErrMFAInvalidCode):
+			c.JSON(http.StatusBadRequest, &response.MFAResponse{Code: "MFA_DISABLE_003", Message: err.Error(), Enabled: true})
+		default:
+			c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_DISABLE_004", Message: err.Error()})
+		}
+		return
+	}
+	if err := rcvr.MFARepository.Reset(c, claims.UUID); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_DISABLE_005", Message: err.Error(), Enabled: true})
+		return
+	}
+	c.JSON(http.StatusOK, &response.MFAResponse{Code: "SUCCESS", Message: "MFA disabled successfully"})
+}
+
+// sessionClaims returns the caller's claims, rejecting personal access tokens
+// and service accounts
+func (rcvr mfaControllerForInternal) sessionClaims(c *gin.Context) (*model.JWTClaims, bool) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.MFAResponse{Code: "MFA_AUTH_001", Message: "User not authenticated"})
+		return nil, false
+	}
+	if claims.TokenUse == model.TokenUsePAT || claims.ClientID != "" {
+		c.JSON(http.StatusForbidden, &response.MFAResponse{Code: "MFA_AUTH_002", Message: "MFA can only be managed with a login session"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// NewMFAControllerForInternal creates a new internal MFA controller.
+func NewMFAControllerForInternal(mfaRepository repository.MFARepository) MFAControllerForInternal {
+	return &mfaControllerForInternal{MFARepository: mfaRepository}
+}
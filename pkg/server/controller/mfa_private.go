@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// MFAControllerForPrivate lets admins reset a user's MFA, e.g. after a lost device.
+//
+//   - ResetUserMFA: Remove the enrollment and recovery codes (DELETE /v1/private/users/{id}/mfa)
+//
+// {id} accepts either the numeric user ID or the user UUID.
+type MFAControllerForPrivate interface {
+	ResetUserMFA(c *gin.Context)
+}
+
+type mfaControllerForPrivate struct {
+	MFARepository  repository.MFARepository
+	UserRepository repository.UserRepository
+}
+
+// ResetUserMFA disables MFA for a user (admin only). The user can enroll again afterwards.
+//
+// Route: DELETE /v1/private/users/{id}/mfa
+// Security: Bearer token (admin)
+func (rcvr mfaControllerForPrivate) ResetUserMFA(c *gin.Context) {
+	idParam := c.Param("id")
+	filter := repository.UserQueryFilter{Limit: 1}
+	if id64, err := strconv.ParseUint(idParam, 10, 64); err == nil {
+		id := uint(id64)
+		filter.ID = &id
+	} else {
+		filter.UUID = &idParam
+	}
+	users, err := rcvr.UserRepository.ListUsers(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_RESET_001", Message: err.Error()})
+		return
+	}
+	if len(users) == 0 {
+		c.JSON(http.StatusNotFound, &response.MFAResponse{Code: "MFA_RESET_002", Message: "User not found"})
+		return
+	}
+	if err := rcvr.MFARepository.Reset(c, users[0].UUID); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.MFAResponse{Code: "MFA_RESET_003", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &response.MFAResponse{Code: "SUCCESS", Message: "MFA reset successfully"})
+}
+
+// NewMFAControllerForPrivate creates a new private (admin) MFA controller.
+func NewMFAControllerForPrivate(mfaRepository repository.MFARepository, userRepository repository.UserRepository) MFAControllerForPrivate {
+	return &mfaControllerForPrivate{MFARepository: mfaRepository, UserRepository: userRepository}
+}
@@ -0,0 +1,591 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// OIDC endpoint paths, relative to the issuer
+const (
+	OIDCDiscoveryPath = "/.well-known/openid-configuration"
+	OIDCAuthorizePath = "/v1/share/common/oidc/authorize"
+	OIDCTokenPath     = "/v1/share/common/oidc/token"
+	OIDCUserInfoPath  = "/v1/share/common/oidc/userinfo"
+	OIDCJWKSPath      = "/v1/share/common/auth/.well-known/jwks.json"
+)
+
+// OIDCControllerForPublic implements OpenID Connect provider mode
+// (authorization code flow with PKCE).
+//
+//   - Discovery: Provider metadata (GET /.well-known/openid-configuration)
+//   - Authorize: Login page (GET /v1/share/common/oidc/authorize)
+//   - AuthorizeSubmit: Login and consent form (POST /v1/share/common/oidc/authorize)
+//   - Token: Code / refresh token exchange (POST /v1/share/common/oidc/token)
+//   - UserInfo: Claims of the token owner (GET|POST /v1/share/common/oidc/userinfo)
+type OIDCControllerForPublic interface {
+	Discovery(c *gin.Context)
+	Authorize(c *gin.Context)
+	AuthorizeSubmit(c *gin.Context)
+	Token(c *gin.Context)
+	UserInfo(c *gin.Context)
+}
+
+type oidcControllerForPublic struct {
+	OIDCRepository         repository.OIDCRepository
+	UserRepository         repository.UserRepository
+	CommonRepository       repository.CommonRepository
+	MFARepository          repository.MFARepository
+	LoginLockoutRepository repository.LoginLockoutRepository
+	AuthenticatorChain     repository.AuthenticatorChain
+}
+
+var (
+	errOIDCMFARequired     = errors.New("authentication code required")
+	errOIDCMFAInvalid      = errors.New("invalid authentication code")
+	errOIDCEmailUnverified = errors.New("email address not verified")
+	errOIDCLockedOut       = errors.New("too many failed login attempts")
+	errOIDCPasswordExpired = errors.New("password has expired")
+)
+
+// oauthError is an OAuth 2.0 error (RFC 6749 section 4.1.2.1 / 5.2)
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+// Discovery returns the OpenID Provider metadata.
+//
+// Route: GET /.well-known/openid-configuration
+// Security: No authentication required
+func (rcvr oidcControllerForPublic) Discovery(c *gin.Context) {
+	issuer := rcvr.issuer(c)
+	// ValidateOIDCConfig keeps HS256 out of provider mode at startup
+	alg, _ := repository.NormalizeJWTAlgorithm(rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server.JWT.Algorithm)
+	c.JSON(http.StatusOK, &response.OIDCDiscoveryResponse{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + OIDCAuthorizePath,
+		TokenEndpoint:                     issuer + OIDCTokenPath,
+		UserinfoEndpoint:                  issuer + OIDCUserInfoPath,
+		JwksURI:                           issuer + OIDCJWKSPath,
+		IntrospectionEndpoint:             issuer + TokenIntrospectionPath,
+		RevocationEndpoint:                issuer + TokenRevocationPath,
+		ScopesSupported:                   repository.DefaultOIDCScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{alg},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "azp", "name", "email"},
+	})
+}
+
+// Authorize validates the authorization request and renders the login page.
+//
+// Route: GET /v1/share/common/oidc/authorize
+// Security: No authentication required
+func (rcvr oidcControllerForPublic) Authorize(c *gin.Context) {
+	var req request.OIDCAuthorizeRequest
+	_ = c.ShouldBindQuery(&req)
+
+	client, oerr, redirectable := rcvr.validateAuthorizeRequest(req)
+	if oerr != nil {
+		rcvr.authorizeError(c, req, oerr, redirectable)
+		return
+	}
+	renderOIDCPage(c, http.StatusOK, oidcLoginTemplate, oidcPageData{Client: client, Request: req})
+}
+
+// AuthorizeSubmit handles the login form and, for clients that require it, the consent form.
+// On success the browser is redirected to the client with an authorization code.
+//
+// Route: POST /v1/share/common/oidc/authorize
+// Security: No authentication required (user credentials in form)
+func (rcvr oidcControllerForPublic) AuthorizeSubmit(c *gin.Context) {
+	var form request.OIDCLoginRequest
+	_ = c.ShouldBind(&form)
+
+	// Consent step
+	if form.Ticket != "" {
+		auth, err := rcvr.OIDCRepository.ConsumeLoginTicket(c.Request.Context(), form.Ticket)
+		if err != nil {
+			renderOIDCPage(c, http.StatusBadRequest, oidcErrorTemplate, oidcPageData{Error: "The sign-in request has expired. Please start again from the application."})
+			return
+		}
+		if form.Consent != "allow" {
+			rcvr.redirectToClient(c, auth.RedirectURI, url.Values{"error": {"access_denied"}, "state": {auth.State}})
+			return
+		}
+		rcvr.issueCode(c, *auth)
+		return
+	}
+
+	// Login step
+	req := form.OIDCAuthorizeRequest
+	client, oerr, redirectable := rcvr.validateAuthorizeRequest(req)
+	if oerr != nil {
+		rcvr.authorizeError(c, req, oerr, redirectable)
+		return
+	}
+
+	user, err := rcvr.authenticateUser(c, form.Email, form.Password, form.MFACode)
+	if err != nil {
+		status, message := http.StatusUnauthorized, "Invalid email or password"
+		switch {
+		case errors.Is(err, errOIDCMFARequired):
+			message = "Enter the code from your authenticator app"
+		case errors.Is(err, errOIDCMFAInvalid):
+			message = "Invalid authentication code"
+		case errors.Is(err, errOIDCEmailUnverified):
+			message = "Please verify your email address before signing in"
+		case errors.Is(err, errOIDCPasswordExpired):
+			message = "Your password has expired. Reset it to sign in"
+		case errors.Is(err, repository.ErrAccountDisabled):
+			status, message = http.StatusForbidden, "This account is disabled"
+		case errors.Is(err, errOIDCLockedOut):
+			status, message = http.StatusTooManyRequests, "Too many failed attempts. Please try again later"
+		}
+		renderOIDCPage(c, status, oidcLoginTemplate, oidcPageData{Client: client, Request: req, Email: form.Email, Error: message})
+		return
+	}
+
+	auth := model.OIDCAuthorizations{
+		ClientID:            client.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		UserID:              user.ID,
+		UserUUID:            user.UUID,
+		Email:               user.Email,
+		Name:                user.Name,
+		AuthTime:            time.Now().Unix(),
+	}
+	auth.Roles = rcvr.CommonRepository.LoadUserRoles(*user)
+	auth.Role = auth.Roles[0]
+	if client.SkipConsent {
+		rcvr.issueCode(c, auth)
+		return
+	}
+
+	ticket, err := rcvr.OIDCRepository.SaveLoginTicket(c.Request.Context(), auth)
+	if err != nil {
+		renderOIDCPage(c, http.StatusInternalServerError, oidcErrorTemplate, oidcPageData{Error: "Sign-in is temporarily unavailable."})
+		return
+	}
+	renderOIDCPage(c, http.StatusOK, oidcConsentTemplate, oidcPageData{Client: client, Request: req, Ticket: ticket, Scopes: strings.Fields(req.Scope), Email: user.Email})
+}
+
+// Token exchanges an authorization code (with PKCE verifier) or a refresh token for tokens.
+//
+// Route: POST /v1/share/common/oidc/token
+// Security: Client authentication (client_secret_basic / client_secret_post / none for public clients)
+func (rcvr oidcControllerForPublic) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req request.OIDCTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "malformed token request")
+		return
+	}
+
+	clientID, clientSecret, basic := c.Request.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = req.ClientID, req.ClientSecret
+	}
+	client, err := rcvr.OIDCRepository.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="locky"`)
+		}
+		oauthJSONError(c, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		rcvr.exchangeCode(c, client, req)
+	case "refresh_token":
+		rcvr.exchangeRefreshToken(c, client, req)
+	default:
+		oauthJSONError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+// UserInfo returns the claims of the access token owner.
+//
+// Route: GET|POST /v1/share/common/oidc/userinfo
+// Security: Bearer access token
+func (rcvr oidcControllerForPublic) UserInfo(c *gin.Context) {
+	authHeader := c.GetHeader("Authorization")
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if authHeader == "" || token == authHeader {
+		c.Header("WWW-Authenticate", `Bearer realm="locky"`)
+		oauthJSONError(c, http.StatusUnauthorized, "invalid_token", "bearer token required")
+		return
+	}
+
+	claims, err := rcvr.CommonRepository.ValidateJWTToken(token)
+	if err == nil && claims.TokenUse != "" && claims.TokenUse != model.TokenUseAccess {
+		err = repository.ErrInvalidTokenUse
+	}
+	if err == nil {
+		var invalidated bool
+		if invalidated, err = rcvr.CommonRepository.IsTokenInvalidated(c.Request.Context(), claims.Jti); err == nil && invalidated {
+			err = errors.New("token has been invalidated")
+		}
+	}
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="locky", error="invalid_token"`)
+		oauthJSONError(c, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, &response.OIDCUserInfoResponse{
+		Subject: claims.UUID,
+		Name:    claims.Name,
+		Email:   claims.Email,
+	})
+}
+
+func (rcvr oidcControllerForPublic) exchangeCode(c *gin.Context, client *config.OIDCClient, req request.OIDCTokenRequest) {
+	auth, err := rcvr.OIDCRepository.ConsumeAuthorizationCode(c.Request.Context(), req.Code)
+	if err != nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_grant", "authorization code is invalid or expired")
+		return
+	}
+	if auth.ClientID != client.ClientID || auth.RedirectURI != req.RedirectURI {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_grant", "authorization code was issued to another client or redirect_uri")
+		return
+	}
+	if !verifyPKCE(auth.CodeChallenge, auth.CodeChallengeMethod, req.CodeVerifier) {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+		return
+	}
+
+	tokenPair, err := rcvr.CommonRepository.GenerateAuthorizedTokenPair(client.ClientID, auth.UserID, auth.UserUUID, auth.Email, auth.Name, authorizationRoles(*auth)...)
+	if err != nil {
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to issue tokens")
+		return
+	}
+	if claims, err := rcvr.CommonRepository.ParseTokenUnverified(tokenPair.AccessToken); err == nil {
+		_ = rcvr.CommonRepository.TouchSession(c.Request.Context(), claims.FamilyID, c.Request.UserAgent(), c.ClientIP())
+	}
+
+	idToken, err := rcvr.OIDCRepository.GenerateIDToken(rcvr.issuer(c), *auth)
+	if err != nil {
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to issue id token")
+		return
+	}
+
+	c.JSON(http.StatusOK, &response.OAuthTokenResponse{
+		AccessToken:  tokenPair.AccessToken,
+		TokenType:    tokenPair.TokenType,
+		ExpiresIn:    tokenPair.ExpiresIn,
+		RefreshToken: tokenPair.RefreshToken,
+		IDToken:      idToken,
+		Scope:        auth.Scope,
+	})
+}
+
+// exchangeRefreshToken rotates a refresh token issued to the authenticated client (RFC 6749 section 6)
+func (rcvr oidcControllerForPublic) exchangeRefreshToken(c *gin.Context, client *config.OIDCClient, req request.OIDCTokenRequest) {
+	tokenPair, err := rcvr.CommonRepository.RotateRefreshToken(c.Request.Context(), req.RefreshToken, client.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenGeneration) {
+			oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to issue tokens")
+			return
+		}
+		oauthJSONError(c, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, &response.OAuthTokenResponse{
+		AccessToken:  tokenPair.AccessToken,
+		TokenType:    tokenPair.TokenType,
+		ExpiresIn:    tokenPair.ExpiresIn,
+		RefreshToken: tokenPair.RefreshToken,
+	})
+}
+
+// validateAuthorizeRequest checks the client, redirect_uri, response_type, scope and PKCE.
+// redirectable is false while the client or redirect_uri cannot be trusted: such
+// errors must be shown to the user instead of being sent to the redirect_uri.
+func (rcvr oidcControllerForPublic) validateAuthorizeRequest(req request.OIDCAuthorizeRequest) (*config.OIDCClient, *oauthError, bool) {
+	client, err := rcvr.OIDCRepository.GetClient(req.ClientID)
+	if err != nil {
+		return nil, &oauthError{Code: "invalid_request", Description: "Unknown client"}, false
+	}
+	if !containsString(client.RedirectURIs, req.RedirectURI) {
+		return nil, &oauthError{Code: "invalid_request", Description: "The redirect_uri is not registered for this client"}, false
+	}
+	if req.ResponseType != "code" {
+		return client, &oauthError{Code: "unsupported_response_type", Description: "response_type must be code"}, true
+	}
+	scopes := strings.Fields(req.Scope)
+	if !containsString(scopes, "openid") {
+		return client, &oauthError{Code: "invalid_scope", Description: "scope must include openid"}, true
+	}
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) {
+			return client, &oauthError{Code: "invalid_scope", Description: "scope not allowed: " + scope}, true
+		}
+	}
+	if req.CodeChallenge == "" {
+		if client.Public {
+			return client, &oauthError{Code: "invalid_request", Description: "code_challenge is required"}, true
+		}
+	} else if req.CodeChallengeMethod != "S256" {
+		return client, &oauthError{Code: "invalid_request", Description: "code_challenge_method must be S256"}, true
+	}
+	return client, nil, false
+}
+
+func (rcvr oidcControllerForPublic) authorizeError(c *gin.Context, req request.OIDCAuthorizeRequest, oerr *oauthError, redirectable bool) {
+	if !redirectable {
+		renderOIDCPage(c, http.StatusBadRequest, oidcErrorTemplate, oidcPageData{Error: oerr.Description})
+		return
+	}
+	rcvr.redirectToClient(c, req.RedirectURI, url.Values{
+		"error":             {oerr.Code},
+		"error_description": {oerr.Description},
+		"state":             {req.State},
+	})
+}
+
+func (rcvr oidcControllerForPublic) issueCode(c *gin.Context, auth model.OIDCAuthorizations) {
+	code, err := rcvr.OIDCRepository.SaveAuthorizationCode(c.Request.Context(), auth)
+	if err != nil {
+		rcvr.redirectToClient(c, auth.RedirectURI, url.Values{"error": {"server_error"}, "state": {auth.State}})
+		return
+	}
+	rcvr.redirectToClient(c, auth.RedirectURI, url.Values{"code": {code}, "state": {auth.State}})
+}
+
+func (rcvr oidcControllerForPublic) redirectToClient(c *gin.Context, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		renderOIDCPage(c, http.StatusBadRequest, oidcErrorTemplate, oidcPageData{Error: "Invalid redirect_uri"})
+		return
+	}
+	query := target.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			query.Set(k, v[0])
+		}
+	}
+	target.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
+
+// authenticateUser checks the password and, for users with MFA enabled, the one-time code
+func (rcvr oidcControllerForPublic) authenticateUser(c *gin.Context, email, password, mfaCode string) (*model.Users, error) {
+	if email == "" || password == "" {
+		return nil, errors.New("email and password are required")
+	}
+	lockout, err := rcvr.LoginLockoutRepository.Check(c.Request.Context(), email, c.ClientIP())
+	if err != nil {
+		logger.Warn(code.CCPLO1, middleware.GetRequestID(c), err.Error())
+	}
+	if lockout != nil {
+		c.Header("Retry-After", strconv.FormatInt(loginLockoutRetryAfter(lockout), 10))
+		return nil, errOIDCLockedOut
+	}
+	user, err := rcvr.AuthenticatorChain.Authenticate(c, email, password)
+	if errors.Is(err, repository.ErrAuthenticatorUnavailable) {
+		return nil, errors.New("sign-in is temporarily unavailable, try again later")
+	}
+	if errors.Is(err, repository.ErrAccountDisabled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, rcvr.loginFailed(c, email, errors.New("invalid email or password"))
+	}
+	if user.AuthSource == "" || user.AuthSource == repository.AuthenticatorLocal {
+		upgradePasswordHash(c, rcvr.CommonRepository, rcvr.UserRepository, *user, password)
+	}
+	if user.EmailVerifiedAt == nil && rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server.EmailVerification.RequireForLogin {
+		return nil, errOIDCEmailUnverified
+	}
+	if repository.PasswordExpired(rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server.PasswordPolicy, *user, time.Now()) {
+		return nil, errOIDCPasswordExpired
+	}
+	mfaEnabled, err := rcvr.MFARepository.IsEnabled(c, user.UUID)
+	if err != nil {
+		return nil, errors.New("unable to verify authentication code")
+	}
+	if mfaEnabled {
+		if mfaCode == "" {
+			return nil, errOIDCMFARequired
+		}
+		if err := rcvr.MFARepository.Verify(c, user.UUID, mfaCode); err != nil {
+			return nil, rcvr.loginFailed(c, email, errOIDCMFAInvalid)
+		}
+	}
+	if err := rcvr.LoginLockoutRepository.RegisterSuccess(c.Request.Context(), email); err != nil {
+		logger.Warn(code.CCPLO1, middleware.GetRequestID(c), err.Error())
+	}
+	return user, nil
+}
+
+// loginFailed counts a failed login and returns errOIDCLockedOut when it locks the account out, err otherwise
+func (rcvr oidcControllerForPublic) loginFailed(c *gin.Context, email string, err error) error {
+	lockout, lerr := rcvr.LoginLockoutRepository.RegisterFailure(c.Request.Context(), email, c.ClientIP())
+	if lerr != nil {
+		logger.Warn(code.CCPLO1, middleware.GetRequestID(c), lerr.Error())
+	}
+	if lockout != nil && lockout.Reason == model.LoginLockoutReasonLockout {
+		logger.Warn(code.CCPLO2, middleware.GetRequestID(c), lockout.Scope+" "+lockout.Key)
+		c.Header("Retry-After", strconv.FormatInt(loginLockoutRetryAfter(lockout), 10))
+		return errOIDCLockedOut
+	}
+	return err
+}
+
+// issuer returns the configured issuer (oidc.issuer, then jwt.issuer) or derives it from the request
+func (rcvr oidcControllerForPublic) issuer(c *gin.Context) string {
+	server := rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server
+	if server.OIDC.Issuer != "" {
+		return strings.TrimRight(server.OIDC.Issuer, "/")
+	}
+	if server.JWT.Issuer != "" {
+		return strings.TrimRight(server.JWT.Issuer, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// verifyPKCE checks code_verifier against the S256 challenge (RFC 7636)
+func verifyPKCE(challenge, method, verifier string) bool {
+	if challenge == "" {
+		return verifier == ""
+	}
+	if method != "S256" || len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
+
+func oauthJSONError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, &response.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// authorizationRoles returns the roles of a signed-in authorization. Codes
+// issued before the roles field existed only carry role.
+func authorizationRoles(auth model.OIDCAuthorizations) []string {
+	if len(auth.Roles) > 0 {
+		return auth.Roles
+	}
+	return []string{auth.Role}
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// NewOIDCControllerForPublic creates a new OIDC provider controller
+func NewOIDCControllerForPublic(oidcRepository repository.OIDCRepository, userRepository repository.UserRepository, commonRepository repository.CommonRepository, mfaRepository repository.MFARepository, loginLockoutRepository repository.LoginLockoutRepository, authenticatorChain repository.AuthenticatorChain) OIDCControllerForPublic {
+	return &oidcControllerForPublic{
+		OIDCRepository:         oidcRepository,
+		UserRepository:         userRepository,
+		CommonRepository:       commonRepository,
+		MFARepository:          mfaRepository,
+		LoginLockoutRepository: loginLockoutRepository,
+		AuthenticatorChain:     authenticatorChain,
+	}
+}
+
+// ===== Server-rendered pages =====
+
+type oidcPageData struct {
+	Client  *config.OIDCClient
+	Request request.OIDCAuthorizeRequest
+	Email   string
+	Error   string
+	Ticket  string
+	Scopes  []string
+}
+
+func renderOIDCPage(c *gin.Context, status int, tmpl *template.Template, data oidcPageData) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = tmpl.Execute(c.Writer, data)
+}
+
+const oidcPageHead = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Locky</title>
+<style>body{font-family:sans-serif;max-width:24rem;margin:4rem auto}input,button{display:block;width:100%;margin:.5rem 0;padding:.5rem}.error{color:#b00}</style>
+</head><body>`
+
+var oidcLoginTemplate = template.Must(template.New("login").Parse(oidcPageHead + `
+<h1>Sign in</h1>
+<p>to continue to <strong>{{if .Client.Name}}{{.Client.Name}}{{else}}{{.Client.ClientID}}{{end}}</strong></p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="email" name="email" placeholder="Email" value="{{.Email}}" required autofocus>
+<input type="password" name="password" placeholder="Password" required>
+<input type="text" name="mfa_code" placeholder="Authentication code (if enabled)" inputmode="numeric" autocomplete="one-time-code">
+<button type="submit">Sign in</button>
+</form>
+</body></html>`))
+
+var oidcConsentTemplate = template.Must(template.New("consent").Parse(oidcPageHead + `
+<h1>Allow access?</h1>
+<p><strong>{{if .Client.Name}}{{.Client.Name}}{{else}}{{.Client.ClientID}}{{end}}</strong> wants to access your account ({{.Email}}):</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>
+<form method="post">
+<input type="hidden" name="ticket" value="{{.Ticket}}">
+<button type="submit" name="consent" value="allow">Allow</button>
+<button type="submit" name="consent" value="deny">Deny</button>
+</form>
+</body></html>`))
+
+var oidcErrorTemplate = template.Must(template.New("error").Parse(oidcPageHead + `
+<h1>Sign-in error</h1>
+<p class="error">{{.Error}}</p>
+</body></html>`))
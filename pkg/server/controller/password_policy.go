@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// passwordPolicyMessage is returned with the field errors of a rejected password
+const passwordPolicyMessage = "password does not meet the password policy"
+
+// checkPasswordPolicy validates a new password against password_policy and returns the
+// violated rules as field errors. The password history is checked when userUUID is set.
+func checkPasswordPolicy(c *gin.Context, passwordPolicyRepository repository.PasswordPolicyRepository, password, userUUID string) ([]response.FieldError, error) {
+	violations, err := passwordPolicyRepository.Validate(c, password, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	fieldErrors := []response.FieldError{}
+	for _, v := range violations {
+		fieldErrors = append(fieldErrors, response.FieldError{Field: v.Field, Code: v.Code, Message: v.Message})
+	}
+	if len(fieldErrors) > 0 {
+		logger.Info(code.CPPCP1, middleware.GetRequestID(c), fieldErrors[0].Code)
+	}
+	return fieldErrors, nil
+}
+
+// hashUserRequestPassword enforces password_policy on the password of a create or update
+// request and replaces it with its hash. It answers the request itself and returns false
+// when the password is rejected (rejectCode) or cannot be checked or hashed (failCode).
+// Passwords cannot be set with an impersonation token.
+func hashUserRequestPassword(c *gin.Context, commonRepository repository.CommonRepository, passwordPolicyRepository repository.PasswordPolicyRepository, userRequest *request.UserRequest, rejectCode, failCode string) bool {
+	if _, impersonated := middleware.GetImpersonator(c); impersonated {
+		c.JSON(http.StatusForbidden, &response.UserResponse{Code: "MIDDLEWARE_IMPERSONATION_001", Message: "credentials cannot be changed while impersonating", Users: []response.User{}})
+		return false
+	}
+	fieldErrors, err := checkPasswordPolicy(c, passwordPolicyRepository, userRequest.Password, userRequest.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: failCode, Message: "failed to check password policy: " + err.Error(), Users: []response.User{}})
+		return false
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, &response.UserResponse{Code: rejectCode, Message: passwordPolicyMessage, Users: []response.User{}, Errors: fieldErrors})
+		return false
+	}
+	hashedPassword, err := commonRepository.HashPassword(userRequest.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: failCode, Message: "failed to hash password", Users: []response.User{}})
+		return false
+	}
+	userRequest.Password = hashedPassword
+	return true
+}
+
+// recordPassword adds a newly set password hash to the user's password history.
+// Failures are logged; the password change itself has already succeeded.
+func recordPassword(c *gin.Context, passwordPolicyRepository repository.PasswordPolicyRepository, userUUID, passwordHash string) {
+	if err := passwordPolicyRepository.RecordPassword(c, userUUID, passwordHash); err != nil {
+		logger.Warn(code.CPPRP1, middleware.GetRequestID(c), err.Error())
+	}
+}
@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// passwordForgotMessage is returned whether or not the email is registered
+const passwordForgotMessage = "If the email address is registered, a password reset link has been sent"
+
+// PasswordControllerForPublic implements the self-service password reset flow.
+//
+//   - ForgotPassword: Email a single-use reset token (POST /v1/public/password/forgot)
+//   - ResetPassword: Set a new password with the token (POST /v1/public/password/reset)
+//
+// Both endpoints are rate limited per client IP, and ForgotPassword answers
+// the same way whether or not the email exists.
+type PasswordControllerForPublic interface {
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+}
+
+type passwordControllerForPublic struct {
+	UserRepository                repository.UserRepository
+	CommonRepository              repository.CommonRepository
+	PasswordResetRepository       repository.PasswordResetRepository
+	SessionRepository             repository.SessionRepository
+	PersonalAccessTokenRepository repository.PersonalAccessTokenRepository
+	PasswordPolicyRepository      repository.PasswordPolicyRepository
+}
+
+// ForgotPassword sends a password reset email.
+//
+// Route: POST /v1/public/password/forgot
+// Security: None
+func (rcvr passwordControllerForPublic) ForgotPassword(c *gin.Context) {
+	var req request.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "PASSWORD_FORGOT_001", Message: "email is required"})
+		return
+	}
+	if !rcvr.allow(c, "forgot-ip", c.ClientIP(), "PASSWORD_FORGOT_002") {
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// A flooded mailbox is throttled silently so the answer does not depend on the email
+	if allowed, _, err := rcvr.PasswordResetRepository.AllowRequest(c.Request.Context(), "forgot-email", email); err != nil || !allowed {
+		c.JSON(http.StatusOK, &response.CommonResponse{Code: "SUCCESS", Message: passwordForgotMessage})
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+	user, err := rcvr.UserRepository.GetUserByEmail(c, email)
+	// Passwords of directory users are reset in the directory, not here
+	if err == nil && (user.AuthSource == "" || user.AuthSource == repository.AuthenticatorLocal) {
+		logger.Info(code.PCPFP1, requestID, user.UUID)
+		token, err := rcvr.PasswordResetRepository.CreateResetToken(c.Request.Context(), user.UUID)
+		if err != nil {
+			logger.Error(code.PCPFP2, requestID, err.Error())
+		} else {
+			// Sent in the background so the response time does not reveal the account
+			resetURL := rcvr.resetURL(token)
+			go func() {
+				if err := rcvr.CommonRepository.SendPasswordResetEmail(context.Background(), user.Email, user.Name, resetURL); err != nil {
+					logger.Error(code.PCPFP2, requestID, err.Error())
+				}
+			}()
+		}
+	}
+	c.JSON(http.StatusOK, &response.CommonResponse{Code: "SUCCESS", Message: passwordForgotMessage})
+}
+
+// ResetPassword sets a new password and revokes every existing session and
+// personal access token of the user.
+//
+// Route: POST /v1/public/password/reset
+// Security: None (reset token required)
+func (rcvr passwordControllerForPublic) ResetPassword(c *gin.Context) {
+	var req request.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "PASSWORD_RESET_001", Message: "token and password are required"})
+		return
+	}
+	if !rcvr.allow(c, "reset-ip", c.ClientIP(), "PASSWORD_RESET_002") {
+		return
+	}
+	// The token is only looked up here so a rejected password does not burn it
+	userUUID, err := rcvr.PasswordResetRepository.LookupResetToken(c.Request.Context(), req.Token)
+	if err != nil {
+		rcvr.resetTokenError(c, err)
+		return
+	}
+	fieldErrors, err := checkPasswordPolicy(c, rcvr.PasswordPolicyRepository, req.Password, userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "PASSWORD_RESET_005", Message: err.Error()})
+		return
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "PASSWORD_RESET_003", Message: passwordPolicyMessage, Errors: fieldErrors})
+		return
+	}
+
+	consumedUUID, err := rcvr.PasswordResetRepository.ConsumeResetToken(c.Request.Context(), req.Token)
+	if err != nil {
+		rcvr.resetTokenError(c, err)
+		return
+	}
+	if consumedUUID != userUUID {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "PASSWORD_RESET_004", Message: repository.ErrPasswordResetTokenInvalid.Error()})
+		return
+	}
+	users, err := rcvr.UserRepository.ListUsers(c, repository.UserQueryFilter{UUID: &userUUID, Limit: 1})
+	if err != nil || len(users) == 0 {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "PASSWORD_RESET_004", Message: repository.ErrPasswordResetTokenInvalid.Error()})
+		return
+	}
+
+	hashedPassword, err := rcvr.CommonRepository.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "PASSWORD_RESET_006", Message: "Failed to hash password"})
+		return
+	}
+	user := users[0]
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	if user.EmailVerifiedAt == nil {
+		// Following the emailed link proves ownership of the address
+		user.EmailVerifiedAt = &now
+	}
+	if _, err := rcvr.UserRepository.UpdateUser(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "PASSWORD_RESET_007", Message: "Failed to update password"})
+		return
+	}
+
+	recordPassword(c, rcvr.PasswordPolicyRepository, user.UUID, hashedPassword)
+
+	requestID := middleware.GetRequestID(c)
+	logger.Info(code.PCPRP1, requestID, user.UUID)
+	if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), user.UUID, ""); err != nil {
+		logger.Error(code.PCPRP2, requestID, err.Error())
+		c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "PASSWORD_RESET_008", Message: "Password was changed but existing sessions could not be revoked"})
+		return
+	}
+	if _, err := rcvr.PersonalAccessTokenRepository.RevokeAllTokens(c, user.UUID); err != nil {
+		logger.Error(code.PCPRP2, requestID, err.Error())
+		c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "PASSWORD_RESET_009", Message: "Password was changed but personal access tokens could not be revoked"})
+		return
+	}
+	c.JSON(http.StatusOK, &response.CommonResponse{Code: "SUCCESS", Message: "Password has been reset"})
+}
+
+// resetTokenError answers a failed reset token lookup
+func (rcvr passwordControllerForPublic) resetTokenError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrPasswordResetTokenInvalid) {
+		c.JSON(http.StatusBadRequest, &response.CommonResponse{Code: "PASSWORD_RESET_004", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, &response.CommonResponse{Code: "PASSWORD_RESET_005", Message: err.Error()})
+}
+
+// allow applies the per-client rate limit and writes a 429 response when exceeded
+func (rcvr passwordControllerForPublic) allow(c *gin.Context, scope, key, errCode string) bool {
+	allowed, retryAfter, err := rcvr.PasswordResetRepository.AllowRequest(c.Request.Context(), scope, key)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, &response.CommonResponse{Code: errCode, Message: "Password reset is temporarily unavailable"})
+		return false
+	}
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, &response.CommonResponse{Code: errCode, Message: "Too many requests, try again in " + retryAfter.Round(time.Second).String()})
+		return false
+	}
+	return true
+}
+
+// resetURL builds the link sent by email (reset_url?token=...)
+func (rcvr passwordControllerForPublic) resetURL(token string) string {
+	base := rcvr.CommonRepository.GetBaseConfig().YamlConfig.Application.Server.PasswordReset.ResetURL
+	u, err := url.Parse(base)
+	if base == "" || err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// NewPasswordControllerForPublic creates a new public password reset controller.
+func NewPasswordControllerForPublic(userRepository repository.UserRepository, commonRepository repository.CommonRepository, passwordResetRepository repository.PasswordResetRepository, sessionRepository repository.SessionRepository, personalAccessTokenRepository repository.PersonalAccessTokenRepository, passwordPolicyRepository repository.PasswordPolicyRepository) PasswordControllerForPublic {
+	return &passwordControllerForPublic{
+		UserRepository:                userRepository,
+		CommonRepository:              commonRepository,
+		PasswordResetRepository:       passwordResetRepository,
+		SessionRepository:             sessionRepository,
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+		PasswordPolicyRepository:      passwordPolicyRepository,
+	}
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// Personal access token lifetime limits
+const (
+	DefaultPersonalAccessTokenDays = 30
+	MaxPersonalAccessTokenDays     = 365
+)
+
+// PersonalAccessTokenControllerForInternal lets the caller manage their own personal access tokens.
+//
+//   - ListMyTokens: List own tokens (GET /v1/internal/me/tokens)
+//   - CreateMyToken: Create a token (POST /v1/internal/me/token)
+//   - RevokeMyToken: Revoke a token (DELETE /v1/internal/me/token/{id})
+//
+// A personal access token cannot be used to manage personal access tokens.
+type PersonalAccessTokenControllerForInternal interface {
+	ListMyTokens(c *gin.Context)
+	CreateMyToken(c *gin.Context)
+	RevokeMyToken(c *gin.Context)
+}
+
+type personalAccessTokenControllerForInternal struct {
+	PersonalAccessTokenRepository repository.PersonalAccessTokenRepository
+	AppEnforcer                   *casbin.SyncedEnforcer
+}
+
+// ListMyTokens lists the caller's personal access tokens.
+//
+// Route: GET /v1/internal/me/tokens
+// Security: Bearer token
+func (rcvr personalAccessTokenControllerForInternal) ListMyTokens(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	tokens, err := rcvr.PersonalAccessTokenRepository.ListTokens(c, claims.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.PersonalAccessTokenResponse{Code: "TOKEN_LIST_001", Message: err.Error(), Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+	list := make([]response.PersonalAccessToken, 0, len(tokens))
+	for _, pat := range tokens {
+		list = append(list, toPersonalAccessTokenResponse(pat))
+	}
+	c.JSON(http.StatusOK, &response.PersonalAccessTokenResponse{Code: "SUCCESS", Message: "Tokens retrieved successfully", Tokens: list})
+}
+
+// CreateMyToken creates a personal access token. Scopes are "resource:action"
+// pairs and must be allowed for the caller's role. The token is returned once.
+//
+// Route: POST /v1/internal/me/token
+// Security: Bearer token
+func (rcvr personalAccessTokenControllerForInternal) CreateMyToken(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	var req request.PersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &response.PersonalAccessTokenResponse{Code: "TOKEN_CREATE_001", Message: "Invalid request body", Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+	if req.Name == "" || len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, &response.PersonalAccessTokenResponse{Code: "TOKEN_CREATE_002", Message: "name and scopes are required", Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = DefaultPersonalAccessTokenDays
+	}
+	if days < 0 || days > MaxPersonalAccessTokenDays {
+		c.JSON(http.StatusBadRequest, &response.PersonalAccessTokenResponse{Code: "TOKEN_CREATE_003", Message: "expires_in_days must be between 1 and 365", Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+	scopes, err := rcvr.validateScopes(claims.AllRoles(), req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &response.PersonalAccessTokenResponse{Code: "TOKEN_CREATE_004", Message: err.Error(), Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	pat := model.PersonalAccessTokens{
+		UserUUID:  claims.UUID,
+		Name:      req.Name,
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: &expiresAt,
+	}
+	token, err := rcvr.PersonalAccessTokenRepository.CreateToken(c, &pat)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.PersonalAccessTokenResponse{Code: "TOKEN_CREATE_005", Message: err.Error(), Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+	res := toPersonalAccessTokenResponse(pat)
+	res.Token = token
+	c.JSON(http.StatusOK, &response.PersonalAccessTokenResponse{Code: "SUCCESS", Message: "Token created successfully", Tokens: []response.PersonalAccessToken{res}})
+}
+
+// RevokeMyToken revokes one of the caller's personal access tokens.
+//
+// Route: DELETE /v1/internal/me/token/{id}
+// Security: Bearer token
+func (rcvr personalAccessTokenControllerForInternal) RevokeMyToken(c *gin.Context) {
+	claims, ok := rcvr.sessionClaims(c)
+	if !ok {
+		return
+	}
+	if err := rcvr.PersonalAccessTokenRepository.RevokeToken(c, claims.UUID, c.Param("id")); err != nil {
+		if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, &response.PersonalAccessTokenResponse{Code: "TOKEN_REVOKE_001", Message: err.Error(), Tokens: []response.PersonalAccessToken{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.PersonalAccessTokenResponse{Code: "TOKEN_REVOKE_002", Message: err.Error(), Tokens: []response.PersonalAccessToken{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.PersonalAccessTokenResponse{Code: "SUCCESS", Message: "Token revoked successfully", Tokens: []response.PersonalAccessToken{}})
+}
+
+// sessionClaims returns the caller's claims, rejecting personal access tokens
+// so that a leaked token cannot mint further tokens
+func (rcvr personalAccessTokenControllerForInternal) sessionClaims(c *gin.Context) (*model.JWTClaims, bool) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.PersonalAccessTokenResponse{Code: "TOKEN_AUTH_001", Message: "User not authenticated", Tokens: []response.PersonalAccessToken{}})
+		return nil, false
+	}
+	if claims.TokenUse == model.TokenUsePAT || claims.ClientID != "" {
+		c.JSON(http.StatusForbidden, &response.PersonalAccessTokenResponse{Code: "TOKEN_AUTH_002", Message: "Personal access tokens can only be managed with a login session", Tokens: []response.PersonalAccessToken{}})
+		return nil, false
+	}
+	return claims, true
+}
+
+// validateScopes checks that every scope is a "resource:action" pair one of the roles may perform
+func (rcvr personalAccessTokenControllerForInternal) validateScopes(roles []string, scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		resource, action, found := strings.Cut(scope, ":")
+		if !found || resource == "" || (action != "read" && action != "write") {
+			return nil, errors.New("invalid scope " + scope + ": expected resource:read or resource:write")
+		}
+		allowed, err := middleware.EnforceAnyRole(rcvr.AppEnforcer, roles, resource, action)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.New("scope " + scope + " exceeds your permissions")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func toPersonalAccessTokenResponse(pat model.PersonalAccessTokens) response.PersonalAccessToken {
+	return response.PersonalAccessToken{
+		ID:         pat.ID,
+		UUID:       pat.UUID,
+		Name:       pat.Name,
+		Prefix:     pat.Prefix,
+		Scopes:     strings.Fields(pat.Scopes),
+		ExpiresAt:  pat.ExpiresAt,
+		LastUsedAt: pat.LastUsedAt,
+		CreatedAt:  pat.CreatedAt,
+	}
+}
+
+// NewPersonalAccessTokenControllerForInternal creates a new internal personal access token controller.
+func NewPersonalAccessTokenControllerForInternal(personalAccessTokenRepository repository.PersonalAccessTokenRepository, appEnforcer *casbin.SyncedEnforcer) PersonalAccessTokenControllerForInternal {
+	return &personalAccessTokenControllerForInternal{
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+		AppEnforcer:                   appEnforcer,
+	}
+}
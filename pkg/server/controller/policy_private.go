@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// PolicyControllerForPrivate reports the Casbin policies loaded by a server process.
+//
+//   - GetPolicyStatus: Revision of each policy set on this node (GET /v1/private/policy/status)
+type PolicyControllerForPrivate interface {
+	GetPolicyStatus(c *gin.Context)
+}
+
+type policyControllerForPrivate struct {
+	PolicyWatcherRepository repository.PolicyWatcherRepository
+}
+
+// GetPolicyStatus reports the policy revision and digest loaded by the node that
+// answers. Nodes with the same digest enforce the same policy.
+//
+// Route: GET /v1/private/policy/status
+// Security: Bearer token (admin)
+func (rcvr policyControllerForPrivate) GetPolicyStatus(c *gin.Context) {
+	sets := []response.PolicySet{}
+	for _, status := range rcvr.PolicyWatcherRepository.GetPolicyStatus() {
+		loadedAt := status.LoadedAt
+		sets = append(sets, response.PolicySet{
+			Name:     status.PolicySet,
+			Storage:  status.Storage,
+			Watcher:  status.Watcher,
+			Revision: status.Revision,
+			Digest:   status.Digest,
+			Rules:    status.Rules,
+			LoadedAt: &loadedAt,
+		})
+	}
+	c.JSON(http.StatusOK, &response.PolicyResponse{Code: "SUCCESS", Message: "Policy status retrieved successfully", Node: rcvr.PolicyWatcherRepository.NodeID(), PolicySets: sets})
+}
+
+// NewPolicyControllerForPrivate creates a new private (admin) policy controller.
+func NewPolicyControllerForPrivate(policyWatcherRepository repository.PolicyWatcherRepository) PolicyControllerForPrivate {
+	return &policyControllerForPrivate{PolicyWatcherRepository: policyWatcherRepository}
+}
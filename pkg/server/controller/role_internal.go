@@ -12,28 +12,25 @@ import (
 // RoleControllerForInternal: internal (read-only) role operations
 // ListRoles also returns single details when specified with ?id=
 // GetRole is deprecated as it's integrated into query-based approach
+// ?effective=true adds the permissions inherited from parent roles
 type RoleControllerForInternal interface {
 	ListRoles(c *gin.Context)
+	GetRoleHierarchy(c *gin.Context)
 }
 
 type roleControllerForInternal struct {
 	repo     repository.RoleRepository
-	enforcer *casbin.Enforcer
+	enforcer *casbin.SyncedEnforcer
 }
 
-func NewRoleControllerForInternal(repo repository.RoleRepository, enf *casbin.Enforcer) RoleControllerForInternal {
+func NewRoleControllerForInternal(repo repository.RoleRepository, enf *casbin.SyncedEnforcer) RoleControllerForInternal {
 	return &roleControllerForInternal{repo: repo, enforcer: enf}
 }
 
 // ListRoles (internal) - read-only
 func (rc *roleControllerForInternal) ListRoles(c *gin.Context) {
 	if id := c.Query("id"); id != "" {
-		perms, err := rc.repo.GetRolePermissions(c, id)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_GET_ERROR", Message: err.Error(), Roles: []string{}})
-			return
-		}
-		c.JSON(http.StatusOK, response.RoleResponse{Code: "SUCCESS", Message: "Role permissions retrieved", Roles: []string{id}, Detail: perms})
+		getRolePermissions(c, rc.repo, id)
 		return
 	}
 	roles, err := rc.repo.ListRoles(c)
@@ -43,3 +40,47 @@ func (rc *roleControllerForInternal) ListRoles(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, response.RoleResponse{Code: "SUCCESS", Message: "Roles retrieved", Roles: roles})
 }
+
+// GetRoleHierarchy (internal) - parents, ancestors and descendants of a role
+func (rc *roleControllerForInternal) GetRoleHierarchy(c *gin.Context) {
+	getRoleHierarchy(c, rc.repo)
+}
+
+// getRolePermissions responds with the direct permissions of a role, and with
+// ?effective=true also the inherited ones
+func getRolePermissions(c *gin.Context, repo repository.RoleRepository, id string) {
+	perms, err := repo.GetRolePermissions(c, id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_GET_ERROR", Message: err.Error(), Roles: []string{}})
+		return
+	}
+	res := response.RoleResponse{Code: "SUCCESS", Message: "Role permissions retrieved", Roles: []string{id}, Detail: perms}
+	if c.Query("effective") == "true" {
+		effective, err := repo.GetEffectiveRolePermissions(c, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.RoleResponse{Code: "ROLE_GET_ERROR", Message: err.Error(), Roles: []string{}})
+			return
+		}
+		res.Effective = make([]response.RoleEffectivePermission, 0, len(effective))
+		for _, pm := range effective {
+			res.Effective = append(res.Effective, response.RoleEffectivePermission{Resource: pm.Resource, Action: pm.Action, Role: pm.Role})
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// getRoleHierarchy responds with the inheritance of the role in the path
+func getRoleHierarchy(c *gin.Context, repo repository.RoleRepository) {
+	role := c.Param("id")
+	h, err := repo.GetRoleHierarchy(c, role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_HIERARCHY_ERROR", Message: err.Error(), Roles: []string{}})
+		return
+	}
+	c.JSON(http.StatusOK, response.RoleResponse{Code: "SUCCESS", Message: "Role hierarchy retrieved", Roles: []string{role}, Hierarchy: &response.RoleHierarchy{
+		Role:        h.Role,
+		Parents:     h.Parents,
+		Ancestors:   h.Ancestors,
+		Descendants: h.Descendants,
+	}})
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/casbin/casbin/v2"
@@ -16,25 +17,23 @@ type RoleControllerForPrivate interface {
 	CreateRole(c *gin.Context)
 	UpdateRole(c *gin.Context)
 	DeleteRole(c *gin.Context)
+	GetRoleHierarchy(c *gin.Context)
+	AddRoleParent(c *gin.Context)
+	RemoveRoleParent(c *gin.Context)
 }
 
 type roleControllerForPrivate struct {
 	repo     repository.RoleRepository
-	enforcer *casbin.Enforcer
+	enforcer *casbin.SyncedEnforcer
 }
 
-func NewRoleControllerForPrivate(repo repository.RoleRepository, enf *casbin.Enforcer) RoleControllerForPrivate {
+func NewRoleControllerForPrivate(repo repository.RoleRepository, enf *casbin.SyncedEnforcer) RoleControllerForPrivate {
 	return &roleControllerForPrivate{repo: repo, enforcer: enf}
 }
 
 func (rc *roleControllerForPrivate) ListRoles(c *gin.Context) {
 	if id := c.Query("id"); id != "" {
-		perms, err := rc.repo.GetRolePermissions(c, id)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_GET_ERROR", Message: err.Error(), Roles: []string{}})
-			return
-		}
-		c.JSON(http.StatusOK, response.RoleResponse{Code: "SUCCESS", Message: "Role permissions retrieved", Roles: []string{id}, Detail: perms})
+		getRolePermissions(c, rc.repo, id)
 		return
 	}
 	roles, err := rc.repo.ListRoles(c)
@@ -100,3 +99,54 @@ func (rc *roleControllerForPrivate) DeleteRole(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, response.RoleResponse{Code: "SUCCESS", Message: "Role deleted", Roles: []string{role}})
 }
+
+// GetRoleHierarchy: parents, ancestors and descendants of a role
+func (rc *roleControllerForPrivate) GetRoleHierarchy(c *gin.Context) {
+	getRoleHierarchy(c, rc.repo)
+}
+
+// AddRoleParent: the role in the path inherits the permissions of body.parent
+func (rc *roleControllerForPrivate) AddRoleParent(c *gin.Context) {
+	role := c.Param("id")
+	var req request.RoleParentRequest
+	if err := c.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_PARENT_ADD_BIND_ERROR", Message: err.Error(), Roles: []string{}})
+		return
+	}
+	if role == "" || req.Parent == "" {
+		c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_PARENT_ADD_VALIDATION_ERROR", Message: "role id(path) and parent required", Roles: []string{}})
+		return
+	}
+	if err := rc.repo.AddRoleParent(c, role, req.Parent); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRoleNotFound):
+			c.JSON(http.StatusNotFound, response.RoleResponse{Code: "ROLE_PARENT_ADD_NOT_FOUND", Message: err.Error(), Roles: []string{}})
+		case errors.Is(err, repository.ErrRoleParentExists):
+			c.JSON(http.StatusConflict, response.RoleResponse{Code: "ROLE_PARENT_ADD_CONFLICT", Message: err.Error(), Roles: []string{}})
+		case errors.Is(err, repository.ErrRoleInheritanceCycle):
+			c.JSON(http.StatusConflict, response.RoleResponse{Code: "ROLE_PARENT_ADD_CYCLE", Message: err.Error(), Roles: []string{}})
+		default:
+			c.JSON(http.StatusInternalServerError, response.RoleResponse{Code: "ROLE_PARENT_ADD_ERROR", Message: err.Error(), Roles: []string{}})
+		}
+		return
+	}
+	getRoleHierarchy(c, rc.repo)
+}
+
+// RemoveRoleParent: the role in the path stops inheriting from :parent
+func (rc *roleControllerForPrivate) RemoveRoleParent(c *gin.Context) {
+	role, parent := c.Param("id"), c.Param("parent")
+	if role == "" || parent == "" {
+		c.JSON(http.StatusBadRequest, response.RoleResponse{Code: "ROLE_PARENT_REMOVE_VALIDATION_ERROR", Message: "role id and parent(path) required", Roles: []string{}})
+		return
+	}
+	if err := rc.repo.RemoveRoleParent(c, role, parent); err != nil {
+		if errors.Is(err, repository.ErrRoleParentNotFound) {
+			c.JSON(http.StatusNotFound, response.RoleResponse{Code: "ROLE_PARENT_REMOVE_NOT_FOUND", Message: err.Error(), Roles: []string{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, response.RoleResponse{Code: "ROLE_PARENT_REMOVE_ERROR", Message: err.Error(), Roles: []string{}})
+		return
+	}
+	getRoleHierarchy(c, rc.repo)
+}
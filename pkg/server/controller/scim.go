@@ -0,0 +1,637 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/usecase"
+)
+
+// SCIMBasePath is the prefix of the SCIM 2.0 API
+const SCIMBasePath = "/scim/v2"
+
+// scimContentType is the media type of SCIM messages (RFC 7644 section 3.1)
+const scimContentType = "application/scim+json"
+
+// SCIMController serves the SCIM 2.0 provisioning API (RFC 7643, RFC 7644).
+// Requests are authenticated with a scim.tokens bearer token.
+//
+//   - ListUsers / GetUser / CreateUser / ReplaceUser / PatchUser / DeleteUser: /scim/v2/Users
+//   - ListGroups / GetGroup / CreateGroup / ReplaceGroup / PatchGroup / DeleteGroup: /scim/v2/Groups
+//   - ServiceProviderConfig: Supported features (GET /scim/v2/ServiceProviderConfig)
+//   - ListResourceTypes / GetResourceType: Endpoints (GET /scim/v2/ResourceTypes)
+//   - ListSchemas / GetSchema: Attribute definitions (GET /scim/v2/Schemas)
+type SCIMController interface {
+	ListUsers(c *gin.Context)
+	GetUser(c *gin.Context)
+	CreateUser(c *gin.Context)
+	ReplaceUser(c *gin.Context)
+	PatchUser(c *gin.Context)
+	DeleteUser(c *gin.Context)
+	ListGroups(c *gin.Context)
+	GetGroup(c *gin.Context)
+	CreateGroup(c *gin.Context)
+	ReplaceGroup(c *gin.Context)
+	PatchGroup(c *gin.Context)
+	DeleteGroup(c *gin.Context)
+	ServiceProviderConfig(c *gin.Context)
+	ListResourceTypes(c *gin.Context)
+	GetResourceType(c *gin.Context)
+	ListSchemas(c *gin.Context)
+	GetSchema(c *gin.Context)
+}
+
+type scimController struct {
+	SCIMUsecase usecase.SCIMUsecase
+}
+
+// ListUsers returns the users matching the filter query parameter.
+//
+// Route: GET /scim/v2/Users?filter=&startIndex=&count=&attributes=&excludedAttributes=
+// Security: SCIM bearer token
+func (rcvr scimController) ListUsers(c *gin.Context) {
+	query, projection, ok := parseSCIMListQuery(c, "groups")
+	if !ok {
+		return
+	}
+	users, total, err := rcvr.SCIMUsecase.ListUsers(c, query)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	base := scimBaseURL(c)
+	resources := make([]interface{}, 0, len(users))
+	for i := range users {
+		resources = append(resources, projection.apply(scimUserLocations(base, &users[i])))
+	}
+	writeSCIM(c, http.StatusOK, scimListResponse(total, query.StartIndex, resources))
+}
+
+// GetUser returns a user.
+//
+// Route: GET /scim/v2/Users/:id
+// Security: SCIM bearer token
+func (rcvr scimController) GetUser(c *gin.Context) {
+	projection, ok := parseSCIMProjection(c)
+	if !ok {
+		return
+	}
+	user, err := rcvr.SCIMUsecase.GetUser(c, c.Param("id"), projection.includes("groups"))
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, projection.apply(scimUserLocations(scimBaseURL(c), user)))
+}
+
+// CreateUser provisions a user.
+//
+// Route: POST /scim/v2/Users
+// Security: SCIM bearer token
+func (rcvr scimController) CreateUser(c *gin.Context) {
+	var body model.SCIMUser
+	if !bindSCIM(c, &body) {
+		return
+	}
+	user, err := rcvr.SCIMUsecase.CreateUser(c, body)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, user.ID)
+	user = scimUserLocations(scimBaseURL(c), user)
+	c.Header("Location", user.Meta.Location)
+	writeSCIM(c, http.StatusCreated, user)
+}
+
+// ReplaceUser replaces the attributes of a user.
+//
+// Route: PUT /scim/v2/Users/:id
+// Security: SCIM bearer token
+func (rcvr scimController) ReplaceUser(c *gin.Context) {
+	var body model.SCIMUser
+	if !bindSCIM(c, &body) {
+		return
+	}
+	user, err := rcvr.SCIMUsecase.ReplaceUser(c, c.Param("id"), body)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, user.ID)
+	writeSCIM(c, http.StatusOK, scimUserLocations(scimBaseURL(c), user))
+}
+
+// PatchUser modifies a user, e.g. to deactivate it with active=false.
+//
+// Route: PATCH /scim/v2/Users/:id
+// Security: SCIM bearer token
+func (rcvr scimController) PatchUser(c *gin.Context) {
+	var body request.SCIMPatchRequest
+	if !bindSCIMPatch(c, &body) {
+		return
+	}
+	user, err := rcvr.SCIMUsecase.PatchUser(c, c.Param("id"), body.Operations)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, user.ID)
+	writeSCIM(c, http.StatusOK, scimUserLocations(scimBaseURL(c), user))
+}
+
+// DeleteUser deletes a user.
+//
+// Route: DELETE /scim/v2/Users/:id
+// Security: SCIM bearer token
+func (rcvr scimController) DeleteUser(c *gin.Context) {
+	if err := rcvr.SCIMUsecase.DeleteUser(c, c.Param("id")); err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, c.Param("id"))
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups returns the groups matching the filter query parameter.
+//
+// Route: GET /scim/v2/Groups?filter=&startIndex=&count=&attributes=&excludedAttributes=
+// Security: SCIM bearer token
+func (rcvr scimController) ListGroups(c *gin.Context) {
+	query, projection, ok := parseSCIMListQuery(c, "members")
+	if !ok {
+		return
+	}
+	groups, total, err := rcvr.SCIMUsecase.ListGroups(c, query)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	base := scimBaseURL(c)
+	resources := make([]interface{}, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, projection.apply(scimGroupLocations(base, &groups[i])))
+	}
+	writeSCIM(c, http.StatusOK, scimListResponse(total, query.StartIndex, resources))
+}
+
+// GetGroup returns a group.
+//
+// Route: GET /scim/v2/Groups/:id
+// Security: SCIM bearer token
+func (rcvr scimController) GetGroup(c *gin.Context) {
+	projection, ok := parseSCIMProjection(c)
+	if !ok {
+		return
+	}
+	group, err := rcvr.SCIMUsecase.GetGroup(c, c.Param("id"), projection.includes("members"))
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, projection.apply(scimGroupLocations(scimBaseURL(c), group)))
+}
+
+// CreateGroup creates a group with its members.
+//
+// Route: POST /scim/v2/Groups
+// Security: SCIM bearer token
+func (rcvr scimController) CreateGroup(c *gin.Context) {
+	var body model.SCIMGroup
+	if !bindSCIM(c, &body) {
+		return
+	}
+	group, err := rcvr.SCIMUsecase.CreateGroup(c, body)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, group.ID)
+	group = scimGroupLocations(scimBaseURL(c), group)
+	c.Header("Location", group.Meta.Location)
+	writeSCIM(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup replaces the name and the members of a group.
+//
+// Route: PUT /scim/v2/Groups/:id
+// Security: SCIM bearer token
+func (rcvr scimController) ReplaceGroup(c *gin.Context) {
+	var body model.SCIMGroup
+	if !bindSCIM(c, &body) {
+		return
+	}
+	group, err := rcvr.SCIMUsecase.ReplaceGroup(c, c.Param("id"), body)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, group.ID)
+	writeSCIM(c, http.StatusOK, scimGroupLocations(scimBaseURL(c), group))
+}
+
+// PatchGroup renames a group or adds and removes members.
+//
+// Route: PATCH /scim/v2/Groups/:id
+// Security: SCIM bearer token
+func (rcvr scimController) PatchGroup(c *gin.Context) {
+	var body request.SCIMPatchRequest
+	if !bindSCIMPatch(c, &body) {
+		return
+	}
+	group, err := rcvr.SCIMUsecase.PatchGroup(c, c.Param("id"), body.Operations)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, group.ID)
+	writeSCIM(c, http.StatusOK, scimGroupLocations(scimBaseURL(c), group))
+}
+
+// DeleteGroup deletes a group and its memberships.
+//
+// Route: DELETE /scim/v2/Groups/:id
+// Security: SCIM bearer token
+func (rcvr scimController) DeleteGroup(c *gin.Context) {
+	if err := rcvr.SCIMUsecase.DeleteGroup(c, c.Param("id")); err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	logSCIMRequest(c, c.Param("id"))
+	c.Status(http.StatusNoContent)
+}
+
+// ServiceProviderConfig describes the supported SCIM features.
+//
+// Route: GET /scim/v2/ServiceProviderConfig
+// Security: SCIM bearer token
+func (rcvr scimController) ServiceProviderConfig(c *gin.Context) {
+	writeSCIM(c, http.StatusOK, &response.SCIMServiceProviderConfig{
+		Schemas:        []string{model.SCIMSchemaServiceProviderConfig},
+		Patch:          response.SCIMSupported{Supported: true},
+		Bulk:           response.SCIMBulkSupport{Supported: false},
+		Filter:         response.SCIMFilterSupport{Supported: true, MaxResults: usecase.SCIMMaxCount},
+		ChangePassword: response.SCIMSupported{Supported: true},
+		Sort:           response.SCIMSupported{Supported: false},
+		ETag:           response.SCIMSupported{Supported: false},
+		AuthenticationSchemes: []response.SCIMAuthenticationType{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer Token",
+			Description: "Authentication with a token of scim.tokens in the Authorization header",
+			Primary:     true,
+		}},
+		Meta: response.SCIMResourceMeta{ResourceType: "ServiceProviderConfig", Location: scimBaseURL(c) + "/ServiceProviderConfig"},
+	})
+}
+
+// ListResourceTypes returns the Users and Groups resource types.
+//
+// Route: GET /scim/v2/ResourceTypes
+// Security: SCIM bearer token
+func (rcvr scimController) ListResourceTypes(c *gin.Context) {
+	resources := []interface{}{}
+	for _, resourceType := range scimResourceTypes(scimBaseURL(c)) {
+		resources = append(resources, resourceType)
+	}
+	writeSCIM(c, http.StatusOK, scimListResponse(int64(len(resources)), 1, resources))
+}
+
+// GetResourceType returns a resource type by name.
+//
+// Route: GET /scim/v2/ResourceTypes/:id
+// Security: SCIM bearer token
+func (rcvr scimController) GetResourceType(c *gin.Context) {
+	for _, resourceType := range scimResourceTypes(scimBaseURL(c)) {
+		if resourceType.ID == c.Param("id") {
+			writeSCIM(c, http.StatusOK, resourceType)
+			return
+		}
+	}
+	respondSCIMError(c, &usecase.SCIMError{Status: http.StatusNotFound, Detail: "Resource type " + c.Param("id") + " not found"})
+}
+
+// ListSchemas returns the User and Group schemas.
+//
+// Route: GET /scim/v2/Schemas
+// Security: SCIM bearer token
+func (rcvr scimController) ListSchemas(c *gin.Context) {
+	resources := []interface{}{}
+	for _, schema := range scimSchemas(scimBaseURL(c)) {
+		resources = append(resources, schema)
+	}
+	writeSCIM(c, http.StatusOK, scimListResponse(int64(len(resources)), 1, resources))
+}
+
+// GetSchema returns a schema by URN.
+//
+// Route: GET /scim/v2/Schemas/:id
+// Security: SCIM bearer token
+func (rcvr scimController) GetSchema(c *gin.Context) {
+	for _, schema := range scimSchemas(scimBaseURL(c)) {
+		if schema.ID == c.Param("id") {
+			writeSCIM(c, http.StatusOK, schema)
+			return
+		}
+	}
+	respondSCIMError(c, &usecase.SCIMError{Status: http.StatusNotFound, Detail: "Schema " + c.Param("id") + " not found"})
+}
+
+// scimProjection is the attributes / excludedAttributes selection of a
+// request, on top-level attributes. id and schemas are always returned.
+type scimProjection struct {
+	attributes map[string]bool
+	excluded   map[string]bool
+}
+
+func (p scimProjection) includes(attr string) bool {
+	if len(p.attributes) > 0 {
+		return p.attributes[attr]
+	}
+	return !p.excluded[attr]
+}
+
+func (p scimProjection) apply(resource interface{}) interface{} {
+	if len(p.attributes) == 0 && len(p.excluded) == 0 {
+		return resource
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return resource
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return resource
+	}
+	for key := range m {
+		attr := strings.ToLower(key)
+		if attr == "id" || attr == "schemas" {
+			continue
+		}
+		if !p.includes(attr) {
+			delete(m, key)
+		}
+	}
+	return m
+}
+
+func parseSCIMProjection(c *gin.Context) (scimProjection, bool) {
+	projection := scimProjection{
+		attributes: scimAttributeSet(c.Query("attributes")),
+		excluded:   scimAttributeSet(c.Query("excludedAttributes")),
+	}
+	if len(projection.attributes) > 0 && len(projection.excluded) > 0 {
+		respondSCIMError(c, &usecase.SCIMError{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: "attributes and excludedAttributes cannot be combined"})
+		return projection, false
+	}
+	return projection, true
+}
+
+// scimAttributeSet parses a comma separated attribute list into top-level attribute names
+func scimAttributeSet(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, attr := range strings.Split(list, ",") {
+		attr = strings.ToLower(strings.TrimSpace(attr))
+		if strings.HasPrefix(attr, "urn:") {
+			attr = attr[strings.LastIndex(attr, ":")+1:]
+		}
+		if attr, _, _ = strings.Cut(attr, "."); attr != "" {
+			set[attr] = true
+		}
+	}
+	return set
+}
+
+// parseSCIMListQuery reads filter, startIndex, count and the projection of a
+// list request. expand is the attribute that needs extra queries (groups / members).
+func parseSCIMListQuery(c *gin.Context, expand string) (usecase.SCIMQuery, scimProjection, bool) {
+	query := usecase.SCIMQuery{StartIndex: 1, Count: usecase.SCIMDefaultCount}
+	projection, ok := parseSCIMProjection(c)
+	if !ok {
+		return query, projection, false
+	}
+	query.Expand = projection.includes(expand)
+	if filter := strings.TrimSpace(c.Query("filter")); filter != "" {
+		parsed, err := usecase.ParseSCIMFilter(filter)
+		if err != nil {
+			respondSCIMError(c, err)
+			return query, projection, false
+		}
+		query.Filter = parsed
+	}
+	if value := c.Query("startIndex"); value != "" {
+		startIndex, err := strconv.Atoi(value)
+		if err != nil {
+			respondSCIMError(c, &usecase.SCIMError{Status: http.StatusBadRequest, ScimType: "invalidValue", Detail: "startIndex must be an integer"})
+			return query, projection, false
+		}
+		query.StartIndex = max(startIndex, 1)
+	}
+	if value := c.Query("count"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			respondSCIMError(c, &usecase.SCIMError{Status: http.StatusBadRequest, ScimType: "invalidValue", Detail: "count must be an integer"})
+			return query, projection, false
+		}
+		query.Count = min(max(count, 0), usecase.SCIMMaxCount)
+	}
+	return query, projection, true
+}
+
+func bindSCIM(c *gin.Context, body interface{}) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(body); err != nil {
+		respondSCIMError(c, &usecase.SCIMError{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func bindSCIMPatch(c *gin.Context, body *request.SCIMPatchRequest) bool {
+	if !bindSCIM(c, body) {
+		return false
+	}
+	for _, schema := range body.Schemas {
+		if schema == model.SCIMSchemaPatchOp {
+			return true
+		}
+	}
+	respondSCIMError(c, &usecase.SCIMError{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: "schemas must contain " + model.SCIMSchemaPatchOp})
+	return false
+}
+
+// scimBaseURL is the absolute URL of the SCIM API, used for meta.location and $ref
+func scimBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + SCIMBasePath
+}
+
+func scimUserLocations(base string, user *model.SCIMUser) *model.SCIMUser {
+	if user.Meta != nil {
+		user.Meta.Location = base + "/Users/" + user.ID
+	}
+	for i := range user.Groups {
+		user.Groups[i].Ref = base + "/Groups/" + user.Groups[i].Value
+	}
+	return user
+}
+
+func scimGroupLocations(base string, group *model.SCIMGroup) *model.SCIMGroup {
+	if group.Meta != nil {
+		group.Meta.Location = base + "/Groups/" + group.ID
+	}
+	for i := range group.Members {
+		group.Members[i].Ref = base + "/Users/" + group.Members[i].Value
+	}
+	return group
+}
+
+func scimListResponse(total int64, startIndex int, resources []interface{}) *response.SCIMListResponse {
+	return &response.SCIMListResponse{
+		Schemas:      []string{model.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func writeSCIM(c *gin.Context, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		respondSCIMError(c, err)
+		return
+	}
+	c.Data(status, scimContentType, data)
+}
+
+// respondSCIMError answers with a SCIM error body. Errors other than
+// usecase.SCIMError are logged and reported as 500.
+func respondSCIMError(c *gin.Context, err error) {
+	var scimErr *usecase.SCIMError
+	if !errors.As(err, &scimErr) {
+		logger.Error(code.SCSER1, middleware.GetRequestID(c), c.Request.Method+" "+c.Request.URL.Path+": "+err.Error())
+		scimErr = &usecase.SCIMError{Status: http.StatusInternalServerError, Detail: "internal error"}
+	}
+	data, _ := json.Marshal(&response.SCIMError{
+		Schemas:  []string{model.SCIMSchemaError},
+		Status:   strconv.Itoa(scimErr.Status),
+		ScimType: scimErr.ScimType,
+		Detail:   scimErr.Detail,
+	})
+	c.Data(scimErr.Status, scimContentType, data)
+}
+
+// logSCIMRequest records a change made by a SCIM client
+func logSCIMRequest(c *gin.Context, id string) {
+	client, _ := middleware.GetSCIMClient(c)
+	logger.Info(code.SCSRQ1, middleware.GetRequestID(c), client+" "+c.Request.Method+" "+c.FullPath()+" "+id)
+}
+
+func scimResourceTypes(base string) []response.SCIMResourceType {
+	return []response.SCIMResourceType{
+		{
+			Schemas:     []string{model.SCIMSchemaResourceType},
+			ID:          "User",
+			Name:        "User",
+			Endpoint:    "/Users",
+			Description: "Locky user account",
+			Schema:      model.SCIMSchemaUser,
+			Meta:        response.SCIMResourceMeta{ResourceType: "ResourceType", Location: base + "/ResourceTypes/User"},
+		},
+		{
+			Schemas:     []string{model.SCIMSchemaResourceType},
+			ID:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Locky group",
+			Schema:      model.SCIMSchemaGroup,
+			Meta:        response.SCIMResourceMeta{ResourceType: "ResourceType", Location: base + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+func scimSchemas(base string) []response.SCIMSchema {
+	str := func(name, description string, required bool, mutability, returned, uniqueness string) response.SCIMSchemaAttribute {
+		return response.SCIMSchemaAttribute{Name: name, Type: "string", Description: description, Required: required, Mutability: mutability, Returned: returned, Uniqueness: uniqueness}
+	}
+	ref := func(types ...string) response.SCIMSchemaAttribute {
+		return response.SCIMSchemaAttribute{Name: "$ref", Type: "reference", Description: "URI of the resource", Mutability: "readOnly", Returned: "default", Uniqueness: "none", ReferenceTypes: types}
+	}
+	return []response.SCIMSchema{
+		{
+			Schemas:     []string{model.SCIMSchemaSchema},
+			ID:          model.SCIMSchemaUser,
+			Name:        "User",
+			Description: "User account. userName is the email address; Locky keeps a single name.",
+			Attributes: []response.SCIMSchemaAttribute{
+				str("userName", "Email address the user signs in with", true, "readWrite", "default", "server"),
+				{
+					Name: "name", Type: "complex", Description: "Name of the user", Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []response.SCIMSchemaAttribute{
+						str("formatted", "Full name", false, "readWrite", "default", "none"),
+						str("givenName", "Given name, combined with familyName when no formatted name is sent", false, "writeOnly", "never", "none"),
+						str("familyName", "Family name, combined with givenName when no formatted name is sent", false, "writeOnly", "never", "none"),
+					},
+				},
+				str("displayName", "Name of the user", false, "readWrite", "default", "none"),
+				{
+					Name: "emails", Type: "complex", MultiValued: true, Description: "The userName address", Mutability: "readOnly", Returned: "default", Uniqueness: "none",
+					SubAttributes: []response.SCIMSchemaAttribute{
+						str("value", "Email address", false, "readOnly", "default", "none"),
+						str("type", "Always work", false, "readOnly", "default", "none"),
+						{Name: "primary", Type: "boolean", Description: "Always true", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+					},
+				},
+				{Name: "active", Type: "boolean", Description: "false while the user is deactivated", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				str("password", "Password, checked against the password policy", false, "writeOnly", "never", "none"),
+				{
+					Name: "groups", Type: "complex", MultiValued: true, Description: "Groups the user is a member of", Mutability: "readOnly", Returned: "default", Uniqueness: "none",
+					SubAttributes: []response.SCIMSchemaAttribute{
+						str("value", "Group id", false, "readOnly", "default", "none"),
+						ref("Group"),
+						str("display", "Group name", false, "readOnly", "default", "none"),
+						str("type", "Always direct", false, "readOnly", "default", "none"),
+					},
+				},
+			},
+			Meta: response.SCIMResourceMeta{ResourceType: "Schema", Location: base + "/Schemas/" + model.SCIMSchemaUser},
+		},
+		{
+			Schemas:     []string{model.SCIMSchemaSchema},
+			ID:          model.SCIMSchemaGroup,
+			Name:        "Group",
+			Description: "Group. Members are users; nested groups are not supported.",
+			Attributes: []response.SCIMSchemaAttribute{
+				str("displayName", "Name of the group", true, "readWrite", "default", "server"),
+				{
+					Name: "members", Type: "complex", MultiValued: true, Description: "Users with a membership in the group", Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []response.SCIMSchemaAttribute{
+						str("value", "User id", false, "immutable", "default", "none"),
+						ref("User"),
+						str("type", "Always User", false, "immutable", "default", "none"),
+					},
+				},
+			},
+			Meta: response.SCIMResourceMeta{ResourceType: "Schema", Location: base + "/Schemas/" + model.SCIMSchemaGroup},
+		},
+	}
+}
+
+func NewSCIMController(scimUsecase usecase.SCIMUsecase) SCIMController {
+	return &scimController{SCIMUsecase: scimUsecase}
+}
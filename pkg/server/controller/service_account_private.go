@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// ServiceAccountControllerForPrivate manages service accounts and their client credentials (admin only).
+//
+//   - GetServiceAccounts: List service accounts (GET /v1/private/service-accounts)
+//   - CreateServiceAccount: Create a service account and its first secret (POST /v1/private/service-account)
+//   - UpdateServiceAccount: Update name, description or role (PUT /v1/private/service-account/{id})
+//   - DeleteServiceAccount: Delete a service account (DELETE /v1/private/service-account/{id})
+//   - RotateSecret: Issue a new client secret (POST /v1/private/service-account/{id}/secret)
+//   - DeleteSecret: Revoke a client secret (DELETE /v1/private/service-account/{id}/secret/{client_id})
+//
+// {id} accepts either the numeric ID or the UUID of the service account.
+type ServiceAccountControllerForPrivate interface {
+	GetServiceAccounts(c *gin.Context)
+	CreateServiceAccount(c *gin.Context)
+	UpdateServiceAccount(c *gin.Context)
+	DeleteServiceAccount(c *gin.Context)
+	RotateSecret(c *gin.Context)
+	DeleteSecret(c *gin.Context)
+}
+
+type serviceAccountControllerForPrivate struct {
+	ServiceAccountRepository repository.ServiceAccountRepository
+	SessionRepository        repository.SessionRepository
+	AppEnforcer              *casbin.SyncedEnforcer
+}
+
+// GetServiceAccounts lists service accounts with their credentials.
+//
+// Route: GET /v1/private/service-accounts
+// Security: Bearer token (admin)
+func (rcvr serviceAccountControllerForPrivate) GetServiceAccounts(c *gin.Context) {
+	accounts, err := rcvr.ServiceAccountRepository.ListServiceAccounts(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_LIST_001", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	list := make([]response.ServiceAccount, 0, len(accounts))
+	for _, sa := range accounts {
+		creds, err := rcvr.ServiceAccountRepository.ListCredentials(c, sa.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_LIST_002", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+			return
+		}
+		list = append(list, toServiceAccountResponse(sa, creds))
+	}
+	c.JSON(http.StatusOK, &response.ServiceAccountResponse{Code: "SUCCESS", Message: "Service accounts retrieved successfully", ServiceAccounts: list})
+}
+
+// CreateServiceAccount creates a service account. The response carries the
+// first client secret, which cannot be retrieved again.
+//
+// Route: POST /v1/private/service-account
+// Security: Bearer token (admin)
+func (rcvr serviceAccountControllerForPrivate) CreateServiceAccount(c *gin.Context) {
+	var req request.ServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_CREATE_001", Message: "Invalid request body", ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	if req.Name == "" || req.Role == "" {
+		c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_CREATE_002", Message: "name and role are required", ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	if !rcvr.roleExists(c, req.Role) {
+		c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_CREATE_003", Message: "Unknown role", ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+
+	sa := model.ServiceAccounts{Name: req.Name, Description: req.Description, Role: req.Role}
+	if err := rcvr.ServiceAccountRepository.CreateServiceAccount(c, &sa); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_CREATE_004", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	cred, secret, err := rcvr.ServiceAccountRepository.RotateSecret(c, sa.UUID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_CREATE_005", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	res := toServiceAccountResponse(sa, []model.ServiceAccountCredentials{*cred})
+	res.Credentials[0].ClientSecret = secret
+	c.JSON(http.StatusOK, &response.ServiceAccountResponse{Code: "SUCCESS", Message: "Service account created successfully", ServiceAccounts: []response.ServiceAccount{res}})
+}
+
+// UpdateServiceAccount updates a service account. A role change applies to newly issued tokens.
+//
+// Route: PUT /v1/private/service-account/{id}
+// Security: Bearer token (admin)
+func (rcvr serviceAccountControllerForPrivate) UpdateServiceAccount(c *gin.Context) {
+	var req request.ServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_UPDATE_001", Message: "Invalid request body", ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	sa, ok := rcvr.resolveServiceAccount(c)
+	if !ok {
+		return
+	}
+	if req.Role != "" && !rcvr.roleExists(c, req.Role) {
+		c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_UPDATE_002", Message: "Unknown role", ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	if req.Name != "" {
+		sa.Name = req.Name
+	}
+	if req.Description != "" {
+		sa.Description = req.Description
+	}
+	if req.Role != "" {
+		sa.Role = req.Role
+	}
+	if err := rcvr.ServiceAccountRepository.UpdateServiceAccount(c, &sa); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_UPDATE_003", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.ServiceAccountResponse{Code: "SUCCESS", Message: "Service account updated successfully", ServiceAccounts: []response.ServiceAccount{toServiceAccountResponse(sa, nil)}})
+}
+
+// DeleteServiceAccount deletes a service account, its credentials and its outstanding tokens.
+//
+// Route: DELETE /v1/private/service-account/{id}
+// Security: Bearer token (admin)
+func (rcvr serviceAccountControllerForPrivate) DeleteServiceAccount(c *gin.Context) {
+	sa, ok := rcvr.resolveServiceAccount(c)
+	if !ok {
+		return
+	}
+	if err := rcvr.ServiceAccountRepository.DeleteServiceAccount(c, sa.UUID); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_DELETE_001", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), sa.UUID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_DELETE_002", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.ServiceAccountResponse{Code: "SUCCESS", Message: "Service account deleted successfully", ServiceAccounts: []response.ServiceAccount{}})
+}
+
+// RotateSecret issues a new client ID / secret pair. Existing secrets stay
+// valid for grace_seconds so that callers can switch over without downtime.
+//
+// Route: POST /v1/private/service-account/{id}/secret
+// Security: Bearer token (admin)
+func (rcvr serviceAccountControllerForPrivate) RotateSecret(c *gin.Context) {
+	var req request.ServiceAccountSecretRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_001", Message: "Invalid request body", ServiceAccounts: []response.ServiceAccount{}})
+			return
+		}
+	}
+	if req.GraceSeconds < 0 {
+		c.JSON(http.StatusBadRequest, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_002", Message: "grace_seconds must not be negative", ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	sa, ok := rcvr.resolveServiceAccount(c)
+	if !ok {
+		return
+	}
+	cred, secret, err := rcvr.ServiceAccountRepository.RotateSecret(c, sa.UUID, time.Duration(req.GraceSeconds)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_003", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	res := toServiceAccountResponse(sa, []model.ServiceAccountCredentials{*cred})
+	res.Credentials[0].ClientSecret = secret
+	c.JSON(http.StatusOK, &response.ServiceAccountResponse{Code: "SUCCESS", Message: "Service account secret rotated successfully", ServiceAccounts: []response.ServiceAccount{res}})
+}
+
+// DeleteSecret revokes a client ID immediately. Tokens issued to the service
+// account are revoked as well, since they do not record which secret was used.
+//
+// Route: DELETE /v1/private/service-account/{id}/secret/{client_id}
+// Security: Bearer token (admin)
+func (rcvr serviceAccountControllerForPrivate) DeleteSecret(c *gin.Context) {
+	sa, ok := rcvr.resolveServiceAccount(c)
+	if !ok {
+		return
+	}
+	if err := rcvr.ServiceAccountRepository.DeleteCredential(c, sa.UUID, c.Param("client_id")); err != nil {
+		if errors.Is(err, repository.ErrServiceAccountCredentialNotFound) {
+			c.JSON(http.StatusNotFound, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_DELETE_001", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_DELETE_002", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), sa.UUID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_SECRET_DELETE_003", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.ServiceAccountResponse{Code: "SUCCESS", Message: "Service account secret deleted successfully", ServiceAccounts: []response.ServiceAccount{}})
+}
+
+// resolveServiceAccount looks up the target service account from the {id} path parameter
+func (rcvr serviceAccountControllerForPrivate) resolveServiceAccount(c *gin.Context) (model.ServiceAccounts, bool) {
+	sa, err := rcvr.ServiceAccountRepository.GetServiceAccount(c, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrServiceAccountNotFound) {
+			c.JSON(http.StatusNotFound, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_GET_001", Message: "Service account not found", ServiceAccounts: []response.ServiceAccount{}})
+			return model.ServiceAccounts{}, false
+		}
+		c.JSON(http.StatusInternalServerError, &response.ServiceAccountResponse{Code: "SERVICE_ACCOUNT_GET_002", Message: err.Error(), ServiceAccounts: []response.ServiceAccount{}})
+		return model.ServiceAccounts{}, false
+	}
+	return sa, true
+}
+
+// roleExists reports whether the role is defined in the app-level policy
+func (rcvr serviceAccountControllerForPrivate) roleExists(c *gin.Context, role string) bool {
+	roles, err := rcvr.AppEnforcer.GetAllSubjects()
+	if err != nil {
+		return false
+	}
+	return containsString(roles, role)
+}
+
+func toServiceAccountResponse(sa model.ServiceAccounts, creds []model.ServiceAccountCredentials) response.ServiceAccount {
+	res := response.ServiceAccount{
+		ID:          sa.ID,
+		UUID:        sa.UUID,
+		Name:        sa.Name,
+		Description: sa.Description,
+		Role:        sa.Role,
+		Credentials: make([]response.ServiceAccountCredential, 0, len(creds)),
+		CreatedAt:   sa.CreatedAt,
+		UpdatedAt:   sa.UpdatedAt,
+	}
+	for _, cred := range creds {
+		res.Credentials = append(res.Credentials, response.ServiceAccountCredential{
+			ClientID:   cred.ClientID,
+			ExpiresAt:  cred.ExpiresAt,
+			LastUsedAt: cred.LastUsedAt,
+			CreatedAt:  cred.CreatedAt,
+		})
+	}
+	return res
+}
+
+// NewServiceAccountControllerForPrivate creates a new private (admin) service account controller.
+func NewServiceAccountControllerForPrivate(serviceAccountRepository repository.ServiceAccountRepository, sessionRepository repository.SessionRepository, appEnforcer *casbin.SyncedEnforcer) ServiceAccountControllerForPrivate {
+	return &serviceAccountControllerForPrivate{
+		ServiceAccountRepository: serviceAccountRepository,
+		SessionRepository:        sessionRepository,
+		AppEnforcer:              appEnforcer,
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// ServiceAccountControllerForPublic issues tokens to service accounts.
+//
+//   - Token: OAuth 2.0 client_credentials grant (POST /v1/share/common/auth/oauth/token)
+type ServiceAccountControllerForPublic interface {
+	Token(c *gin.Context)
+}
+
+type serviceAccountControllerForPublic struct {
+	ServiceAccountRepository repository.ServiceAccountRepository
+	CommonRepository         repository.CommonRepository
+}
+
+// Token exchanges service account client credentials for an access token.
+// No refresh token is returned (RFC 6749 section 4.4.3); callers request a new token instead.
+//
+// Route: POST /v1/share/common/auth/oauth/token
+// Security: Client authentication (client_secret_basic / client_secret_post)
+func (rcvr serviceAccountControllerForPublic) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req request.ClientCredentialsRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "malformed token request")
+		return
+	}
+	if req.GrantType != "client_credentials" {
+		oauthJSONError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be client_credentials")
+		return
+	}
+
+	clientID, clientSecret, basic := c.Request.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = req.ClientID, req.ClientSecret
+	}
+	sa, err := rcvr.ServiceAccountRepository.Authenticate(c, clientID, clientSecret)
+	if err != nil {
+		if !errors.Is(err, repository.ErrServiceAccountInvalidClient) {
+			oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to authenticate client")
+			return
+		}
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="locky"`)
+		}
+		oauthJSONError(c, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	tokenPair, err := rcvr.CommonRepository.GenerateClientTokenPair(clientID, sa.UUID, sa.Name, sa.Role)
+	if err != nil {
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to issue tokens")
+		return
+	}
+	c.JSON(http.StatusOK, &response.OAuthTokenResponse{
+		AccessToken: tokenPair.AccessToken,
+		TokenType:   tokenPair.TokenType,
+		ExpiresIn:   tokenPair.ExpiresIn,
+	})
+}
+
+// NewServiceAccountControllerForPublic creates a new public service account controller.
+func NewServiceAccountControllerForPublic(serviceAccountRepository repository.ServiceAccountRepository, commonRepository repository.CommonRepository) ServiceAccountControllerForPublic {
+	return &serviceAccountControllerForPublic{
+		ServiceAccountRepository: serviceAccountRepository,
+		CommonRepository:         commonRepository,
+	}
+}
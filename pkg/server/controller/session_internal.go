@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// SessionControllerForInternal lets the caller manage their own login sessions.
+//
+//   - ListMySessions: List own sessions (GET /v1/internal/me/sessions)
+//   - RevokeMySession: Revoke one own session (DELETE /v1/internal/me/sessions/{session_id})
+//   - RevokeMySessions: Log out everywhere (DELETE /v1/internal/me/sessions)
+type SessionControllerForInternal interface {
+	ListMySessions(c *gin.Context)
+	RevokeMySession(c *gin.Context)
+	RevokeMySessions(c *gin.Context)
+}
+
+type sessionControllerForInternal struct {
+	SessionRepository repository.SessionRepository
+}
+
+// ListMySessions lists the caller's active sessions.
+//
+// Route: GET /v1/internal/me/sessions
+// Security: Bearer token
+func (rcvr sessionControllerForInternal) ListMySessions(c *gin.Context) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.SessionResponse{Code: "SESSION_LIST_001", Message: "User not authenticated", Sessions: []response.Session{}})
+		return
+	}
+	sessions, err := rcvr.SessionRepository.ListSessions(c.Request.Context(), claims.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_LIST_002", Message: err.Error(), Sessions: []response.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.SessionResponse{Code: "SUCCESS", Message: "Sessions retrieved successfully", Sessions: toSessionResponses(sessions, claims.FamilyID)})
+}
+
+// RevokeMySession revokes one of the caller's sessions (may be the current one).
+//
+// Route: DELETE /v1/internal/me/sessions/{session_id}
+// Security: Bearer token
+func (rcvr sessionControllerForInternal) RevokeMySession(c *gin.Context) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.SessionResponse{Code: "SESSION_REVOKE_001", Message: "User not authenticated", Sessions: []response.Session{}})
+		return
+	}
+	if err := rcvr.SessionRepository.RevokeSession(c.Request.Context(), claims.UUID, c.Param("session_id")); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, &response.SessionResponse{Code: "SESSION_REVOKE_002", Message: err.Error(), Sessions: []response.Session{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_REVOKE_003", Message: err.Error(), Sessions: []response.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.SessionResponse{Code: "SUCCESS", Message: "Session revoked successfully", Sessions: []response.Session{}})
+}
+
+// RevokeMySessions revokes all of the caller's sessions.
+// With ?except_current=true the session of the calling token is kept.
+//
+// Route: DELETE /v1/internal/me/sessions
+// Security: Bearer token
+func (rcvr sessionControllerForInternal) RevokeMySessions(c *gin.Context) {
+	claims, ok := middleware.GetUserClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, &response.SessionResponse{Code: "SESSION_REVOKE_ALL_001", Message: "User not authenticated", Sessions: []response.Session{}})
+		return
+	}
+	except := ""
+	if c.Query("except_current") == "true" {
+		except = claims.FamilyID
+	}
+	if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), claims.UUID, except); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_REVOKE_ALL_002", Message: err.Error(), Sessions: []response.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.SessionResponse{Code: "SUCCESS", Message: "Sessions revoked successfully", Sessions: []response.Session{}})
+}
+
+func toSessionResponses(sessions []model.Sessions, currentID string) []response.Session {
+	out := make([]response.Session, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, response.Session{
+			ID:         s.ID,
+			UserUUID:   s.UserUUID,
+			AccessJti:  s.AccessJti,
+			RefreshJti: s.RefreshJti,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			IssuedAt:   s.IssuedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    currentID != "" && s.ID == currentID,
+		})
+	}
+	return out
+}
+
+// NewSessionControllerForInternal creates a new internal session controller.
+func NewSessionControllerForInternal(sessionRepository repository.SessionRepository) SessionControllerForInternal {
+	return &sessionControllerForInternal{SessionRepository: sessionRepository}
+}
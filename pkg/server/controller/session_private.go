@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// SessionControllerForPrivate lets admins manage any user's login sessions.
+//
+//   - ListUserSessions: List sessions (GET /v1/private/users/{id}/sessions)
+//   - RevokeUserSession: Revoke one session (DELETE /v1/private/users/{id}/sessions/{session_id})
+//   - RevokeUserSessions: Revoke all sessions (DELETE /v1/private/users/{id}/sessions)
+//
+// {id} accepts either the numeric user ID or the user UUID.
+type SessionControllerForPrivate interface {
+	ListUserSessions(c *gin.Context)
+	RevokeUserSession(c *gin.Context)
+	RevokeUserSessions(c *gin.Context)
+}
+
+type sessionControllerForPrivate struct {
+	SessionRepository repository.SessionRepository
+	UserRepository    repository.UserRepository
+}
+
+// ListUserSessions lists a user's active sessions (admin only).
+//
+// Route: GET /v1/private/users/{id}/sessions
+// Security: Bearer token (admin)
+func (rcvr sessionControllerForPrivate) ListUserSessions(c *gin.Context) {
+	userUUID, ok := rcvr.resolveUserUUID(c)
+	if !ok {
+		return
+	}
+	sessions, err := rcvr.SessionRepository.ListSessions(c.Request.Context(), userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_LIST_002", Message: err.Error(), Sessions: []response.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.SessionResponse{Code: "SUCCESS", Message: "Sessions retrieved successfully", Sessions: toSessionResponses(sessions, "")})
+}
+
+// RevokeUserSession revokes one session of a user (admin only).
+//
+// Route: DELETE /v1/private/users/{id}/sessions/{session_id}
+// Security: Bearer token (admin)
+func (rcvr sessionControllerForPrivate) RevokeUserSession(c *gin.Context) {
+	userUUID, ok := rcvr.resolveUserUUID(c)
+	if !ok {
+		return
+	}
+	if err := rcvr.SessionRepository.RevokeSession(c.Request.Context(), userUUID, c.Param("session_id")); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, &response.SessionResponse{Code: "SESSION_REVOKE_002", Message: err.Error(), Sessions: []response.Session{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_REVOKE_003", Message: err.Error(), Sessions: []response.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.SessionResponse{Code: "SUCCESS", Message: "Session revoked successfully", Sessions: []response.Session{}})
+}
+
+// RevokeUserSessions revokes all sessions of a user (admin only).
+//
+// Route: DELETE /v1/private/users/{id}/sessions
+// Security: Bearer token (admin)
+func (rcvr sessionControllerForPrivate) RevokeUserSessions(c *gin.Context) {
+	userUUID, ok := rcvr.resolveUserUUID(c)
+	if !ok {
+		return
+	}
+	if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), userUUID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_REVOKE_ALL_002", Message: err.Error(), Sessions: []response.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.SessionResponse{Code: "SUCCESS", Message: "Sessions revoked successfully", Sessions: []response.Session{}})
+}
+
+// resolveUserUUID looks up the target user from the {id} path parameter
+func (rcvr sessionControllerForPrivate) resolveUserUUID(c *gin.Context) (string, bool) {
+	idParam := c.Param("id")
+	filter := repository.UserQueryFilter{Limit: 1}
+	if id64, err := strconv.ParseUint(idParam, 10, 64); err == nil {
+		id := uint(id64)
+		filter.ID = &id
+	} else {
+		filter.UUID = &idParam
+	}
+	users, err := rcvr.UserRepository.ListUsers(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.SessionResponse{Code: "SESSION_USER_001", Message: err.Error(), Sessions: []response.Session{}})
+		return "", false
+	}
+	if len(users) == 0 {
+		c.JSON(http.StatusNotFound, &response.SessionResponse{Code: "SESSION_USER_002", Message: "User not found", Sessions: []response.Session{}})
+		return "", false
+	}
+	return users[0].UUID, true
+}
+
+// NewSessionControllerForPrivate creates a new private (admin) session controller.
+func NewSessionControllerForPrivate(sessionRepository repository.SessionRepository, userRepository repository.UserRepository) SessionControllerForPrivate {
+	return &sessionControllerForPrivate{SessionRepository: sessionRepository, UserRepository: userRepository}
+}
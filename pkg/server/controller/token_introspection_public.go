@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// Token introspection and revocation endpoint paths
+const (
+	TokenIntrospectionPath = "/v1/share/common/auth/oauth/introspect"
+	TokenRevocationPath    = "/v1/share/common/auth/oauth/revoke"
+)
+
+// TokenIntrospectionControllerForPublic lets resource servers check and revoke tokens.
+//
+//   - Introspect: RFC 7662 token introspection (POST /v1/share/common/auth/oauth/introspect)
+//   - Revoke: RFC 7009 token revocation (POST /v1/share/common/auth/oauth/revoke)
+//
+// Callers authenticate as a service account or a confidential OIDC client.
+type TokenIntrospectionControllerForPublic interface {
+	Introspect(c *gin.Context)
+	Revoke(c *gin.Context)
+}
+
+type tokenIntrospectionControllerForPublic struct {
+	TokenIntrospectionRepository repository.TokenIntrospectionRepository
+	ServiceAccountRepository     repository.ServiceAccountRepository
+	OIDCRepository               repository.OIDCRepository
+}
+
+// tokenClient is the authenticated caller of the introspection and revocation endpoints
+type tokenClient struct {
+	ClientID           string
+	ServiceAccountUUID string // empty for OIDC clients
+}
+
+// Introspect returns whether a token is active and, if so, its claims.
+// Invalid, expired, revoked and already rotated refresh tokens are answered with
+// {"active": false} and no further detail.
+//
+// Route: POST /v1/share/common/auth/oauth/introspect
+// Security: Client authentication (client_secret_basic / client_secret_post)
+func (rcvr tokenIntrospectionControllerForPublic) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req request.TokenIntrospectionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "malformed introspection request")
+		return
+	}
+	if _, ok := rcvr.authenticateClient(c, req.ClientID, req.ClientSecret); !ok {
+		return
+	}
+	if req.Token == "" {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	claims, err := rcvr.TokenIntrospectionRepository.Introspect(c, req.Token)
+	if err != nil {
+		logger.Error(code.TCPIN1, middleware.GetRequestID(c), err.Error())
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to introspect token")
+		return
+	}
+	if claims == nil {
+		c.JSON(http.StatusOK, &response.TokenIntrospectionResponse{Active: false})
+		return
+	}
+
+	res := &response.TokenIntrospectionResponse{
+		Active:   true,
+		Scope:    claims.Scope,
+		ClientID: tokenClientID(*claims),
+		Username: claims.Email,
+		Exp:      claims.ExpiresAt,
+		Iat:      claims.IssuedAt,
+		Nbf:      claims.NotBefore,
+		Sub:      claims.UUID,
+		Aud:      claims.Audience,
+		Iss:      claims.Issuer,
+		Jti:      claims.Jti,
+		TokenUse: claims.TokenUse,
+		Role:     claims.Role,
+		Roles:    claims.AllRoles(),
+		Email:    claims.Email,
+	}
+	if claims.TokenUse != model.TokenUseRefresh {
+		res.TokenType = "Bearer"
+	}
+	if res.TokenUse == "" {
+		res.TokenUse = model.TokenUseAccess
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Revoke invalidates a token. Unknown, invalid and already revoked tokens are
+// answered with 200 as well (RFC 7009 section 2.2). A token can only be revoked
+// by the client it was issued to (RFC 7009 section 2.1): service account tokens
+// by their service account, user tokens by the OIDC client named in azp.
+// Tokens from password login and personal access tokens belong to no client.
+//
+// Route: POST /v1/share/common/auth/oauth/revoke
+// Security: Client authentication (client_secret_basic / client_secret_post)
+func (rcvr tokenIntrospectionControllerForPublic) Revoke(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req request.TokenRevocationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "malformed revocation request")
+		return
+	}
+	client, ok := rcvr.authenticateClient(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		return
+	}
+	if req.Token == "" {
+		oauthJSONError(c, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+	claims, err := rcvr.TokenIntrospectionRepository.Introspect(c, req.Token)
+	if err != nil {
+		logger.Error(code.TCPRV2, requestID, err.Error())
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to revoke token")
+		return
+	}
+	if claims == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	if !client.issued(*claims) {
+		oauthJSONError(c, http.StatusBadRequest, "unauthorized_client", "token was issued to another client")
+		return
+	}
+	if err := rcvr.TokenIntrospectionRepository.Revoke(c, *claims); err != nil {
+		logger.Error(code.TCPRV2, requestID, err.Error())
+		if errors.Is(err, repository.ErrTokenRevocationUnavailable) {
+			oauthJSONError(c, http.StatusServiceUnavailable, "temporarily_unavailable", "token revocation is unavailable")
+			return
+		}
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to revoke token")
+		return
+	}
+	logger.Info(code.TCPRV1, requestID, client.ClientID+" revoked "+claims.Jti)
+	c.Status(http.StatusOK)
+}
+
+// issued reports whether the token was issued to this client
+func (client tokenClient) issued(claims model.JWTClaims) bool {
+	if claims.ClientID != "" {
+		return client.ServiceAccountUUID != "" && claims.UUID == client.ServiceAccountUUID
+	}
+	return client.ServiceAccountUUID == "" && claims.AuthorizedParty != "" && claims.AuthorizedParty == client.ClientID
+}
+
+// tokenClientID returns the client a token was issued to: the service account
+// client or, for user tokens, the OIDC client (azp)
+func tokenClientID(claims model.JWTClaims) string {
+	if claims.ClientID != "" {
+		return claims.ClientID
+	}
+	return claims.AuthorizedParty
+}
+
+// authenticateClient checks the caller's client credentials (HTTP Basic or form fields)
+// against service accounts first and confidential OIDC clients second. It answers
+// the request itself and returns false when authentication fails.
+func (rcvr tokenIntrospectionControllerForPublic) authenticateClient(c *gin.Context, formClientID, formClientSecret string) (*tokenClient, bool) {
+	clientID, clientSecret, basic := c.Request.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = formClientID, formClientSecret
+	}
+
+	sa, err := rcvr.ServiceAccountRepository.Authenticate(c, clientID, clientSecret)
+	if err == nil {
+		return &tokenClient{ClientID: clientID, ServiceAccountUUID: sa.UUID}, true
+	}
+	if !errors.Is(err, repository.ErrServiceAccountInvalidClient) {
+		oauthJSONError(c, http.StatusInternalServerError, "server_error", "failed to authenticate client")
+		return nil, false
+	}
+	// Public OIDC clients have no secret and cannot call these endpoints
+	if clientSecret != "" {
+		if client, err := rcvr.OIDCRepository.AuthenticateClient(clientID, clientSecret); err == nil && !client.Public {
+			return &tokenClient{ClientID: client.ClientID}, true
+		}
+	}
+
+	if basic {
+		c.Header("WWW-Authenticate", `Basic realm="locky"`)
+	}
+	oauthJSONError(c, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	return nil, false
+}
+
+// NewTokenIntrospectionControllerForPublic creates a new token introspection and revocation controller.
+func NewTokenIntrospectionControllerForPublic(tokenIntrospectionRepository repository.TokenIntrospectionRepository, serviceAccountRepository repository.ServiceAccountRepository, oidcRepository repository.OIDCRepository) TokenIntrospectionControllerForPublic {
+	return &tokenIntrospectionControllerForPublic{
+		TokenIntrospectionRepository: tokenIntrospectionRepository,
+		ServiceAccountRepository:     serviceAccountRepository,
+		OIDCRepository:               oidcRepository,
+	}
+}
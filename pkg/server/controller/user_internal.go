@@ -5,8 +5,11 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
 	"github.com/ryo-arima/locky/pkg/entity/request"
 	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
 	"github.com/ryo-arima/locky/pkg/server/repository"
 	"github.com/ryo-arima/locky/pkg/server/usecase"
 )
@@ -27,8 +30,10 @@ type UserControllerForInternal interface {
 }
 
 type userControllerForInternal struct {
-	UserUsecase      usecase.UserUsecase
-	CommonRepository repository.CommonRepository
+	UserUsecase              usecase.UserUsecase
+	CommonRepository         repository.CommonRepository
+	PasswordPolicyRepository repository.PasswordPolicyRepository
+	SessionRepository        repository.SessionRepository
 }
 
 // GetUsers lists users (authenticated).
@@ -136,12 +141,18 @@ func (rcvr userControllerForInternal) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, &response.UserResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__001", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	if userRequest.Password != "" && !hashUserRequestPassword(c, rcvr.CommonRepository, rcvr.PasswordPolicyRepository, &userRequest, "SERVER_CONTROLLER_UPDATE__FOR__003", "SERVER_CONTROLLER_UPDATE__FOR__004") {
+		return
+	}
 
 	updatedUser, err := rcvr.UserUsecase.UpdateUser(c, userRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__002", Message: err.Error(), Users: []response.User{}})
+		c.JSON(userWriteStatus(err), &response.UserResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__002", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	if userRequest.Password != "" {
+		recordPassword(c, rcvr.PasswordPolicyRepository, updatedUser.UUID, userRequest.Password)
+	}
 	c.JSON(http.StatusOK, &response.UserResponse{Code: "SUCCESS", Message: "User updated successfully", Users: []response.User{*updatedUser}})
 }
 
@@ -180,6 +191,14 @@ func (rcvr userControllerForInternal) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_DELETE__FOR__002", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	// End the sessions and refresh token families of the deleted user
+	if userRequest.UUID != "" {
+		if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), userRequest.UUID, ""); err != nil {
+			logger.Error(code.UCDLU1, middleware.GetRequestID(c), userRequest.UUID+": "+err.Error())
+			c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_DELETE__FOR__003", Message: err.Error(), Users: []response.User{}})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, &response.UserResponse{Code: "SUCCESS", Message: "User deleted successfully", Users: []response.User{}})
 }
 
@@ -216,7 +235,7 @@ func (rcvr userControllerForInternal) CreateUser(c *gin.Context) {
 
 	createdUser, err := rcvr.UserUsecase.CreateUser(c, userRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__002", Message: err.Error(), Users: []response.User{}})
+		c.JSON(userWriteStatus(err), &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__002", Message: err.Error(), Users: []response.User{}})
 		return
 	}
 	c.JSON(http.StatusOK, &response.UserResponse{Code: "SUCCESS", Message: "User created successfully", Users: []response.User{*createdUser}})
@@ -284,9 +303,11 @@ func (rcvr userControllerForInternal) CountUsers(c *gin.Context) {
 // Parameters:
 //   - userRepository: User data repository
 //   - commonRepository: Common services repository (e.g., auth)
+//   - passwordPolicyRepository: Password policy and history
+//   - sessionRepository: Sessions ended when a user is deleted
 //
 // Returns:
 //   - UserControllerForInternal: Configured internal controller instance
-func NewUserControllerForInternal(userUsecase usecase.UserUsecase, commonRepository repository.CommonRepository) UserControllerForInternal {
-	return &userControllerForInternal{UserUsecase: userUsecase, CommonRepository: commonRepository}
+func NewUserControllerForInternal(userUsecase usecase.UserUsecase, commonRepository repository.CommonRepository, passwordPolicyRepository repository.PasswordPolicyRepository, sessionRepository repository.SessionRepository) UserControllerForInternal {
+	return &userControllerForInternal{UserUsecase: userUsecase, CommonRepository: commonRepository, PasswordPolicyRepository: passwordPolicyRepository, SessionRepository: sessionRepository}
 }
@@ -5,8 +5,12 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/code"
 	"github.com/ryo-arima/locky/pkg/entity/request"
 	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
 	"github.com/ryo-arima/locky/pkg/server/repository"
 	"github.com/ryo-arima/locky/pkg/server/usecase"
 )
@@ -28,8 +32,10 @@ type UserControllerForPrivate interface {
 }
 
 type userControllerForPrivate struct {
-	UserUsecase      usecase.UserUsecase
-	CommonRepository repository.CommonRepository
+	UserUsecase              usecase.UserUsecase
+	CommonRepository         repository.CommonRepository
+	PasswordPolicyRepository repository.PasswordPolicyRepository
+	SessionRepository        repository.SessionRepository
 }
 
 // GetUsers lists all users (admin only).
@@ -114,11 +120,21 @@ func (rcvr userControllerForPrivate) CreateUser(c *gin.Context) {
 		return
 	}
 
+	if userRequest.UUID == "" {
+		userRequest.UUID = uuid.New().String()
+	}
+	if !hashUserRequestPassword(c, rcvr.CommonRepository, rcvr.PasswordPolicyRepository, &userRequest, "SERVER_CONTROLLER_CREATE__FOR__003", "SERVER_CONTROLLER_CREATE__FOR__004") {
+		return
+	}
+
+	// Accounts created by an admin do not go through email verification
+	userRequest.EmailVerified = true
 	createdUser, err := rcvr.UserUsecase.CreateUser(c, userRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__002", Message: err.Error(), Users: []response.User{}})
+		c.JSON(userWriteStatus(err), &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__002", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	recordPassword(c, rcvr.PasswordPolicyRepository, createdUser.UUID, userRequest.Password)
 	c.JSON(http.StatusOK, &response.UserResponse{Code: "SUCCESS", Message: "User created successfully", Users: []response.User{*createdUser}})
 }
 
@@ -157,12 +173,18 @@ func (rcvr userControllerForPrivate) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, &response.UserResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__001", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	if userRequest.Password != "" && !hashUserRequestPassword(c, rcvr.CommonRepository, rcvr.PasswordPolicyRepository, &userRequest, "SERVER_CONTROLLER_UPDATE__FOR__003", "SERVER_CONTROLLER_UPDATE__FOR__004") {
+		return
+	}
 
 	updatedUser, err := rcvr.UserUsecase.UpdateUser(c, userRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__002", Message: err.Error(), Users: []response.User{}})
+		c.JSON(userWriteStatus(err), &response.UserResponse{Code: "SERVER_CONTROLLER_UPDATE__FOR__002", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	if userRequest.Password != "" {
+		recordPassword(c, rcvr.PasswordPolicyRepository, updatedUser.UUID, userRequest.Password)
+	}
 	c.JSON(http.StatusOK, &response.UserResponse{Code: "SUCCESS", Message: "User updated successfully", Users: []response.User{*updatedUser}})
 }
 
@@ -201,6 +223,14 @@ func (rcvr userControllerForPrivate) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_DELETE__FOR__002", Message: err.Error(), Users: []response.User{}})
 		return
 	}
+	// End the sessions and refresh token families of the deleted user
+	if userRequest.UUID != "" {
+		if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), userRequest.UUID, ""); err != nil {
+			logger.Error(code.UCDLU1, middleware.GetRequestID(c), userRequest.UUID+": "+err.Error())
+			c.JSON(http.StatusInternalServerError, &response.UserResponse{Code: "SERVER_CONTROLLER_DELETE__FOR__003", Message: err.Error(), Users: []response.User{}})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, &response.UserResponse{Code: "SUCCESS", Message: "User deleted successfully", Users: []response.User{}})
 }
 
@@ -250,9 +280,11 @@ func (rcvr userControllerForPrivate) CountUsers(c *gin.Context) {
 // Parameters:
 //   - userRepository: User data repository
 //   - commonRepository: Common services repository (e.g., auth)
+//   - passwordPolicyRepository: Password policy and history
+//   - sessionRepository: Sessions ended when a user is deleted
 //
 // Returns:
 //   - UserControllerForPrivate: Configured private controller instance
-func NewUserControllerForPrivate(userUsecase usecase.UserUsecase, commonRepository repository.CommonRepository) UserControllerForPrivate {
-	return &userControllerForPrivate{UserUsecase: userUsecase, CommonRepository: commonRepository}
+func NewUserControllerForPrivate(userUsecase usecase.UserUsecase, commonRepository repository.CommonRepository, passwordPolicyRepository repository.PasswordPolicyRepository, sessionRepository repository.SessionRepository) UserControllerForPrivate {
+	return &userControllerForPrivate{UserUsecase: userUsecase, CommonRepository: commonRepository, PasswordPolicyRepository: passwordPolicyRepository, SessionRepository: sessionRepository}
 }
@@ -1,15 +1,15 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/ryo-arima/locky/pkg/code"
 	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/entity/request"
 	"github.com/ryo-arima/locky/pkg/entity/response"
 	"github.com/ryo-arima/locky/pkg/logger"
@@ -32,16 +32,19 @@ type UserControllerForPublic interface {
 }
 
 type userControllerForPublic struct {
-	UserUsecase      usecase.UserUsecase
-	CommonRepository repository.CommonRepository
-	conf             config.BaseConfig
+	UserUsecase                 usecase.UserUsecase
+	CommonRepository            repository.CommonRepository
+	EmailVerificationRepository repository.EmailVerificationRepository
+	PasswordPolicyRepository    repository.PasswordPolicyRepository
+	conf                        config.BaseConfig
 }
 
 // CreateUser handles new user registration.
 //
 // This endpoint allows anonymous users to register new accounts.
-// It validates the input, checks for duplicate emails, generates UUIDs,
-// hashes passwords using bcrypt, and creates the user record.
+// It validates the input and the password policy, checks for duplicate emails, generates UUIDs,
+// hashes passwords (argon2id by default), and creates the user record. The new account
+// starts unverified and a verification link is emailed to it.
 //
 // Route: POST /v1/public/users
 // Security: No authentication required
@@ -102,23 +105,38 @@ func (rcvr userControllerForPublic) CreateUser(c *gin.Context) {
 		return
 	}
 
-	// Check for duplicate email
-	users, _ := rcvr.UserUsecase.GetUsers(c)
-	for _, user := range users {
-		if user.Email == userRequest.Email {
-			logger.Warn(code.UCPCU3, requestID, "Email already exists")
-			res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__003", Message: "email already exists", Users: []response.User{}}
-			logger.Warn(code.UCPCU6, requestID, fmt.Sprintf("%d", http.StatusBadRequest))
-			c.JSON(http.StatusBadRequest, res)
-			return
-		}
+	// Check the password policy before anything is stored
+	fieldErrors, err := checkPasswordPolicy(c, rcvr.PasswordPolicyRepository, userRequest.Password, "")
+	if err != nil {
+		res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__006", Message: "failed to check password policy", Users: []response.User{}}
+		logger.Error(code.UCPCU6, requestID, fmt.Sprintf("%d", http.StatusInternalServerError))
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	if len(fieldErrors) > 0 {
+		res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__005", Message: passwordPolicyMessage, Users: []response.User{}, Errors: fieldErrors}
+		logger.Warn(code.UCPCU6, requestID, fmt.Sprintf("%d", http.StatusBadRequest))
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	// Check for duplicate email; the unique index also catches concurrent registrations below
+	if _, err := rcvr.UserUsecase.GetUserByEmail(c, userRequest.Email); err == nil {
+		rcvr.emailAlreadyExists(c, requestID)
+		return
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		logger.Error(code.UCPCU7, requestID, "Email check failed: "+err.Error())
+		res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__007", Message: "failed to check email", Users: []response.User{}}
+		logger.Error(code.UCPCU6, requestID, fmt.Sprintf("%d", http.StatusInternalServerError))
+		c.JSON(http.StatusInternalServerError, res)
+		return
 	}
 
 	// Generate UUID locally
 	userRequest.UUID = uuid.New().String()
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userRequest.Password), bcrypt.DefaultCost)
+	hashedPassword, err := rcvr.CommonRepository.HashPassword(userRequest.Password)
 	if err != nil {
 		logger.Error(code.UCPCU4, requestID, "Password hash failed")
 		res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__004", Message: "failed to hash password", Users: []response.User{}}
@@ -126,9 +144,22 @@ func (rcvr userControllerForPublic) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, res)
 		return
 	}
-	userRequest.Password = string(hashedPassword)
+	userRequest.Password = hashedPassword
 
-	createdUserPtr, _ := rcvr.UserUsecase.CreateUser(c, userRequest)
+	createdUserPtr, err := rcvr.UserUsecase.CreateUser(c, userRequest)
+	if errors.Is(err, repository.ErrEmailAlreadyExists) {
+		rcvr.emailAlreadyExists(c, requestID)
+		return
+	}
+	if err != nil {
+		logger.Error(code.UCPCU7, requestID, "User create failed: "+err.Error())
+		res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__008", Message: "failed to create user", Users: []response.User{}}
+		logger.Error(code.UCPCU6, requestID, fmt.Sprintf("%d", http.StatusInternalServerError))
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	recordPassword(c, rcvr.PasswordPolicyRepository, createdUserPtr.UUID, userRequest.Password)
+	sendVerificationEmail(c, rcvr.CommonRepository, rcvr.EmailVerificationRepository, model.Users{UUID: createdUserPtr.UUID, Email: createdUserPtr.Email, Name: createdUserPtr.Name})
 
 	// Convert to response format
 	userResponse := response.UserResponse{
@@ -205,10 +236,29 @@ func (rcvr userControllerForPublic) GetUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, userResponse)
 }
 
-func NewUserControllerForPublic(userUsecase usecase.UserUsecase, commonRepository repository.CommonRepository, conf config.BaseConfig) UserControllerForPublic {
+// emailAlreadyExists answers a registration for an email that is already taken
+func (rcvr userControllerForPublic) emailAlreadyExists(c *gin.Context, requestID string) {
+	logger.Warn(code.UCPCU3, requestID, "Email already exists")
+	res := &response.UserResponse{Code: "SERVER_CONTROLLER_CREATE__FOR__003", Message: "email already exists", Users: []response.User{}}
+	logger.Warn(code.UCPCU6, requestID, fmt.Sprintf("%d", http.StatusBadRequest))
+	c.JSON(http.StatusBadRequest, res)
+}
+
+// userWriteStatus is the HTTP status of a failed user create or update: a taken
+// email is the client's mistake
+func userWriteStatus(err error) int {
+	if errors.Is(err, repository.ErrEmailAlreadyExists) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func NewUserControllerForPublic(userUsecase usecase.UserUsecase, commonRepository repository.CommonRepository, emailVerificationRepository repository.EmailVerificationRepository, passwordPolicyRepository repository.PasswordPolicyRepository, conf config.BaseConfig) UserControllerForPublic {
 	return &userControllerForPublic{
-		UserUsecase:      userUsecase,
-		CommonRepository: commonRepository,
-		conf:             conf,
+		UserUsecase:                 userUsecase,
+		CommonRepository:            commonRepository,
+		EmailVerificationRepository: emailVerificationRepository,
+		PasswordPolicyRepository:    passwordPolicyRepository,
+		conf:                        conf,
 	}
 }
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// Sources of a user's global roles
+const (
+	UserRoleSourceConfig   = "config"
+	UserRoleSourceAssigned = "assigned"
+)
+
+// UserRoleControllerForPrivate lets admins manage the global roles of users.
+//
+//   - ListUserRoles: List a user's roles (GET /v1/private/users/{id}/roles)
+//   - GrantUserRole: Grant a role (POST /v1/private/users/{id}/roles)
+//   - RevokeUserRole: Revoke a granted role (DELETE /v1/private/users/{id}/roles/{role})
+//
+// {id} accepts either the numeric user ID or the user UUID. Granted roles are
+// added to the role from admin.emails or the directory and are carried in the
+// roles claim of tokens issued from the next login on.
+type UserRoleControllerForPrivate interface {
+	ListUserRoles(c *gin.Context)
+	GrantUserRole(c *gin.Context)
+	RevokeUserRole(c *gin.Context)
+}
+
+type userRoleControllerForPrivate struct {
+	RoleAssignmentRepository repository.RoleAssignmentRepository
+	UserRepository           repository.UserRepository
+	SessionRepository        repository.SessionRepository
+	CommonRepository         repository.CommonRepository
+	AppEnforcer              *casbin.SyncedEnforcer
+}
+
+// ListUserRoles lists the global roles of a user: the configured role first,
+// then the granted ones.
+//
+// Route: GET /v1/private/users/{id}/roles
+// Security: Bearer token
+func (rcvr userRoleControllerForPrivate) ListUserRoles(c *gin.Context) {
+	user, ok := rcvr.resolveUser(c)
+	if !ok {
+		return
+	}
+	assignments, err := rcvr.RoleAssignmentRepository.ListRoleAssignments(c, user.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.UserRoleResponse{Code: "USER_ROLE_LIST_001", Message: err.Error(), Roles: []response.UserRole{}})
+		return
+	}
+	roles := []response.UserRole{{Role: repository.UserRole(rcvr.CommonRepository.GetBaseConfig(), user), Source: UserRoleSourceConfig}}
+	for _, assignment := range assignments {
+		roles = append(roles, toUserRoleResponse(assignment))
+	}
+	c.JSON(http.StatusOK, &response.UserRoleResponse{Code: "SUCCESS", Message: "Roles retrieved successfully", Roles: roles})
+}
+
+// GrantUserRole grants a role defined in the app-level policy to a user (admin only).
+//
+// Route: POST /v1/private/users/{id}/roles
+// Security: Bearer token (admin)
+func (rcvr userRoleControllerForPrivate) GrantUserRole(c *gin.Context) {
+	var req request.RoleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &response.UserRoleResponse{Code: "USER_ROLE_GRANT_001", Message: "Invalid request body", Roles: []response.UserRole{}})
+		return
+	}
+	role := strings.TrimSpace(req.Role)
+	if !rcvr.roleExists(role) {
+		c.JSON(http.StatusBadRequest, &response.UserRoleResponse{Code: "USER_ROLE_GRANT_002", Message: "role is not defined in the app policy: " + role, Roles: []response.UserRole{}})
+		return
+	}
+	user, ok := rcvr.resolveUser(c)
+	if !ok {
+		return
+	}
+	grantedBy := ""
+	if claims, ok := middleware.GetUserClaims(c); ok {
+		grantedBy = claims.UUID
+	}
+	assignment, err := rcvr.RoleAssignmentRepository.GrantRole(c, model.RoleAssignments{UserUUID: user.UUID, Role: role, GrantedBy: grantedBy})
+	if err != nil {
+		if errors.Is(err, repository.ErrRoleAlreadyAssigned) {
+			c.JSON(http.StatusConflict, &response.UserRoleResponse{Code: "USER_ROLE_GRANT_003", Message: err.Error(), Roles: []response.UserRole{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.UserRoleResponse{Code: "USER_ROLE_GRANT_004", Message: err.Error(), Roles: []response.UserRole{}})
+		return
+	}
+	logger.Info(code.RCPGR1, middleware.GetRequestID(c), role+" to "+user.Email+" by "+grantedBy)
+	c.JSON(http.StatusOK, &response.UserRoleResponse{Code: "SUCCESS", Message: "Role granted successfully", Roles: []response.UserRole{toUserRoleResponse(*assignment)}})
+}
+
+// RevokeUserRole revokes a granted role and ends the user's sessions, so no
+// token carrying the role stays valid (admin only). Configured roles cannot be
+// revoked here.
+//
+// Route: DELETE /v1/private/users/{id}/roles/{role}
+// Security: Bearer token (admin)
+func (rcvr userRoleControllerForPrivate) RevokeUserRole(c *gin.Context) {
+	user, ok := rcvr.resolveUser(c)
+	if !ok {
+		return
+	}
+	role := c.Param("role")
+	if err := rcvr.RoleAssignmentRepository.RevokeRole(c, user.UUID, role); err != nil {
+		if errors.Is(err, repository.ErrRoleAssignmentNotFound) {
+			c.JSON(http.StatusNotFound, &response.UserRoleResponse{Code: "USER_ROLE_REVOKE_001", Message: err.Error(), Roles: []response.UserRole{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &response.UserRoleResponse{Code: "USER_ROLE_REVOKE_002", Message: err.Error(), Roles: []response.UserRole{}})
+		return
+	}
+	requestID := middleware.GetRequestID(c)
+	logger.Info(code.RCPRR1, requestID, role+" from "+user.Email)
+	if _, err := rcvr.SessionRepository.RevokeAllSessions(c.Request.Context(), user.UUID, ""); err != nil {
+		logger.Error(code.RCPRR2, requestID, user.UUID+": "+err.Error())
+		c.JSON(http.StatusInternalServerError, &response.UserRoleResponse{Code: "USER_ROLE_REVOKE_003", Message: err.Error(), Roles: []response.UserRole{}})
+		return
+	}
+	c.JSON(http.StatusOK, &response.UserRoleResponse{Code: "SUCCESS", Message: "Role revoked successfully", Roles: []response.UserRole{}})
+}
+
+// resolveUser looks up the target user from the {id} path parameter
+func (rcvr userRoleControllerForPrivate) resolveUser(c *gin.Context) (model.Users, bool) {
+	idParam := c.Param("id")
+	filter := repository.UserQueryFilter{Limit: 1}
+	if id64, err := strconv.ParseUint(idParam, 10, 64); err == nil {
+		id := uint(id64)
+		filter.ID = &id
+	} else {
+		filter.UUID = &idParam
+	}
+	users, err := rcvr.UserRepository.ListUsers(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &response.UserRoleResponse{Code: "USER_ROLE_USER_001", Message: err.Error(), Roles: []response.UserRole{}})
+		return model.Users{}, false
+	}
+	if len(users) == 0 {
+		c.JSON(http.StatusNotFound, &response.UserRoleResponse{Code: "USER_ROLE_USER_002", Message: "User not found", Roles: []response.UserRole{}})
+		return model.Users{}, false
+	}
+	return users[0], true
+}
+
+// roleExists reports whether the role is defined in the app-level policy
+func (rcvr userRoleControllerForPrivate) roleExists(role string) bool {
+	if role == "" {
+		return false
+	}
+	roles, err := rcvr.AppEnforcer.GetAllSubjects()
+	if err != nil {
+		return false
+	}
+	return containsString(roles, role)
+}
+
+func toUserRoleResponse(assignment model.RoleAssignments) response.UserRole {
+	return response.UserRole{
+		Role:      assignment.Role,
+		Source:    UserRoleSourceAssigned,
+		GrantedBy: assignment.GrantedBy,
+		CreatedAt: assignment.CreatedAt,
+	}
+}
+
+// NewUserRoleControllerForPrivate creates a new private (admin) user role controller.
+func NewUserRoleControllerForPrivate(roleAssignmentRepository repository.RoleAssignmentRepository, userRepository repository.UserRepository, sessionRepository repository.SessionRepository, commonRepository repository.CommonRepository, appEnforcer *casbin.SyncedEnforcer) UserRoleControllerForPrivate {
+	return &userRoleControllerForPrivate{
+		RoleAssignmentRepository: roleAssignmentRepository,
+		UserRepository:           userRepository,
+		SessionRepository:        sessionRepository,
+		CommonRepository:         commonRepository,
+		AppEnforcer:              appEnforcer,
+	}
+}
@@ -20,7 +20,7 @@ func ForPublic(conf config.BaseConfig) gin.HandlerFunc {
 	}
 }
 
-func ForInternal(commonRepo repository.CommonRepository, enforcer *casbin.Enforcer) gin.HandlerFunc {
+func ForInternal(commonRepo repository.CommonRepository, enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := validateJWTToken(c, commonRepo); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -37,7 +37,7 @@ func ForInternal(commonRepo repository.CommonRepository, enforcer *casbin.Enforc
 }
 
 // ForPrivate: determine if email is included in admin.emails (not dependent solely on role claims)
-func ForPrivate(commonRepo repository.CommonRepository, enforcer *casbin.Enforcer) gin.HandlerFunc {
+func ForPrivate(commonRepo repository.CommonRepository, enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := validateJWTToken(c, commonRepo); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -55,8 +55,9 @@ func ForPrivate(commonRepo repository.CommonRepository, enforcer *casbin.Enforce
 	}
 }
 
-// CasbinAuthorization: evaluate role(obj=resource, act=methodMapping) for each request
-func CasbinAuthorization(enforcer *casbin.Enforcer, resource string, action string) gin.HandlerFunc {
+// CasbinAuthorization: evaluate role(obj=resource, act=methodMapping) for each request.
+// Tokens with several roles are allowed when any of their roles is.
+func CasbinAuthorization(enforcer *casbin.SyncedEnforcer, resource string, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, ok := getUserFromContext(c)
 		if !ok {
@@ -64,7 +65,7 @@ func CasbinAuthorization(enforcer *casbin.Enforcer, resource string, action stri
 			c.Abort()
 			return
 		}
-		allowed, err := enforcer.Enforce(claims.Role, resource, action)
+		allowed, err := EnforceAnyRole(enforcer, claims.AllRoles(), resource, action)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"code": "MIDDLEWARE_AUTH_004", "message": "authorization error", "error": err.Error()})
 			c.Abort()
@@ -75,10 +76,75 @@ func CasbinAuthorization(enforcer *casbin.Enforcer, resource string, action stri
 			c.Abort()
 			return
 		}
+		// Personal access tokens are further limited to their scopes
+		if claims.TokenUse == model.TokenUsePAT && !claims.HasScope(resource+":"+action) {
+			c.JSON(http.StatusForbidden, gin.H{"code": "MIDDLEWARE_AUTH_006", "message": "token scope does not allow this operation"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// EnforceAnyRole reports whether any of the roles may perform action on resource
+func EnforceAnyRole(enforcer *casbin.SyncedEnforcer, roles []string, resource, action string) (bool, error) {
+	for _, role := range roles {
+		allowed, err := enforcer.Enforce(role, resource, action)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// DenyImpersonation rejects requests made with an impersonation token. It guards
+// routes that change credentials (passwords, MFA, access tokens and secrets).
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonated := GetImpersonator(c); impersonated {
+			c.JSON(http.StatusForbidden, gin.H{"code": "MIDDLEWARE_IMPERSONATION_001", "message": "credentials cannot be changed while impersonating"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ForSCIM authenticates SCIM provisioning clients with one of the bearer tokens
+// of scim.tokens. User tokens are not accepted on the SCIM API.
+func ForSCIM(scimRepo repository.SCIMRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := ""
+		if scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(value)
+		}
+		client, err := scimRepo.AuthenticateToken(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			c.Header("Content-Type", "application/scim+json")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"schemas": []string{model.SCIMSchemaError},
+				"status":  "401",
+				"detail":  "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+		c.Set("scim_client", client.Name)
 		c.Next()
 	}
 }
 
+// GetSCIMClient returns the name of the SCIM token the request was made with
+func GetSCIMClient(c *gin.Context) (string, bool) {
+	client, exists := c.Get("scim_client")
+	if !exists {
+		return "", false
+	}
+	name, ok := client.(string)
+	return name, ok
+}
+
 // validateJWTToken validates JWT token and sets user context
 func validateJWTToken(c *gin.Context, commonRepo repository.CommonRepository) error {
 	// Get token from Authorization header
@@ -98,6 +164,16 @@ func validateJWTToken(c *gin.Context, commonRepo repository.CommonRepository) er
 		return errors.New("token required")
 	}
 
+	// Personal access tokens are opaque and looked up by hash instead of verified as JWTs
+	if repository.IsPersonalAccessToken(tokenString) {
+		claims, err := commonRepo.ValidatePersonalAccessToken(c.Request.Context(), tokenString)
+		if err != nil {
+			return err
+		}
+		setUserContext(c, claims)
+		return nil
+	}
+
 	// First, parse the token to get the JTI without full validation
 	unverifiedClaims, err := commonRepo.ParseTokenUnverified(tokenString)
 	if err != nil {
@@ -120,8 +196,17 @@ func validateJWTToken(c *gin.Context, commonRepo repository.CommonRepository) er
 		return err
 	}
 
+	// Refresh tokens are only accepted by the refresh endpoint, ID tokens by
+	// relying parties, and so on: only access tokens reach the API
+	if !claims.IsAccessToken() {
+		return fmt.Errorf("%s token cannot be used for API access", claims.TokenUse)
+	}
+
 	// Set user context for use in controllers
 	setUserContext(c, claims)
+
+	// Update session last-seen (best effort)
+	_ = commonRepo.TouchSession(c.Request.Context(), claims.FamilyID, c.Request.UserAgent(), c.ClientIP())
 	return nil
 }
 
@@ -209,3 +294,12 @@ func GetUserRole(c *gin.Context) (string, bool) {
 func GetUserClaims(c *gin.Context) (*model.JWTClaims, bool) {
 	return getUserFromContext(c)
 }
+
+// GetImpersonator returns the admin acting through an impersonation token
+func GetImpersonator(c *gin.Context) (*model.Actor, bool) {
+	claims, ok := getUserFromContext(c)
+	if !ok || claims.Actor == nil {
+		return nil, false
+	}
+	return claims.Actor, true
+}
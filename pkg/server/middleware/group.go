@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"gorm.io/gorm"
+)
+
+// GroupAuthorization enforces the group-scoped resource policy on changes to a
+// group. The groups are taken from the :id path parameter and from the id or
+// uuid of the JSON body, which the group controllers re-read with the same
+// binding, and the caller's member roles in each of them must allow
+// object/action (e.g. group_info write). It must run after ForInternal.
+func GroupAuthorization(groupPermissionRepo repository.GroupPermissionRepository, groupRepo repository.GroupRepository, object, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.GroupRequest
+		pathID, err := bindGroupScopedRequest(c, &req)
+		if err != nil || (pathID == 0 && req.ID == 0 && req.UUID == "") {
+			abortGroupAuthorization(c, http.StatusBadRequest, "MIDDLEWARE_GROUP_001", "group id or uuid is required")
+			return
+		}
+		groups := []model.Groups{}
+		for _, id := range []uint{pathID, req.ID} {
+			if id == 0 {
+				continue
+			}
+			group, err := groupRepo.GetGroupByID(c, id)
+			if !groupFound(c, err) {
+				return
+			}
+			groups = append(groups, group)
+		}
+		if req.UUID != "" {
+			group, err := groupRepo.GetGroupByUUID(c, req.UUID)
+			if !groupFound(c, err) {
+				return
+			}
+			groups = append(groups, group)
+		}
+		for _, group := range groups {
+			if _, ok := authorizeInGroup(c, groupPermissionRepo, group.UUID, object, action); !ok {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// MemberAuthorization enforces the group-scoped resource policy on membership
+// changes. The caller's member roles must allow object/action (e.g. member
+// write) in the group of every membership being changed (by the :id path
+// parameter and the id or uuid of the JSON body) and in the group named by
+// group_uuid, and must cover every permission of the role being handed out
+// or taken away, so maintainers cannot create or remove owners.
+// It must run after ForInternal.
+func MemberAuthorization(groupPermissionRepo repository.GroupPermissionRepository, memberRepo repository.MemberRepository, object, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.MemberRequest
+		pathID, err := bindGroupScopedRequest(c, &req)
+		if err != nil {
+			abortGroupAuthorization(c, http.StatusBadRequest, "MIDDLEWARE_GROUP_001", "invalid request body")
+			return
+		}
+		if req.Role != "" && !groupPermissionRepo.IsRoleDefined(req.Role) {
+			abortGroupAuthorization(c, http.StatusBadRequest, "MIDDLEWARE_GROUP_005", "role is not defined in the group policy: "+req.Role)
+			return
+		}
+
+		// Memberships being updated (by id) or deleted (by uuid)
+		current := []model.Members{}
+		filters := []repository.MemberQueryFilter{}
+		for _, id := range []uint{pathID, req.ID} {
+			if id != 0 {
+				filters = append(filters, repository.MemberQueryFilter{ID: &id, ExcludeDeleted: true})
+			}
+		}
+		if req.UUID != "" {
+			filters = append(filters, repository.MemberQueryFilter{UUID: &req.UUID, ExcludeDeleted: true})
+		}
+		for _, filter := range filters {
+			members, err := memberRepo.ListMembers(c, filter)
+			if err != nil {
+				logger.Error(code.MGA1, GetRequestID(c), err.Error())
+				abortGroupAuthorization(c, http.StatusInternalServerError, "MIDDLEWARE_GROUP_003", "authorization error")
+				return
+			}
+			if len(members) == 0 {
+				abortGroupAuthorization(c, http.StatusNotFound, "MIDDLEWARE_GROUP_002", "member not found")
+				return
+			}
+			current = append(current, members[0])
+		}
+		if len(current) == 0 && req.GroupUUID == "" {
+			abortGroupAuthorization(c, http.StatusBadRequest, "MIDDLEWARE_GROUP_001", "group_uuid is required")
+			return
+		}
+
+		for _, m := range current {
+			roles, ok := authorizeInGroup(c, groupPermissionRepo, m.GroupUUID, object, action)
+			if !ok || !authorizeRole(c, groupPermissionRepo, roles, m.Role) {
+				return
+			}
+			if req.GroupUUID == "" && req.Role != "" && !authorizeRole(c, groupPermissionRepo, roles, req.Role) {
+				return
+			}
+		}
+		if req.GroupUUID != "" {
+			roles, ok := authorizeInGroup(c, groupPermissionRepo, req.GroupUUID, object, action)
+			if !ok {
+				return
+			}
+			role := req.Role
+			if role == "" && len(current) > 0 {
+				role = current[0].Role
+			}
+			if !authorizeRole(c, groupPermissionRepo, roles, role) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// authorizeInGroup loads the caller's member roles in the group and enforces
+// object/action with them. The request is aborted when it is not allowed.
+func authorizeInGroup(c *gin.Context, groupPermissionRepo repository.GroupPermissionRepository, groupUUID, object, action string) ([]string, bool) {
+	claims, ok := getUserFromContext(c)
+	if !ok {
+		abortGroupAuthorization(c, http.StatusUnauthorized, "MIDDLEWARE_AUTH_003", "Authentication required")
+		return nil, false
+	}
+	requestID := GetRequestID(c)
+	roles, err := groupPermissionRepo.GetMemberRoles(c, groupUUID, claims.UUID)
+	if err != nil {
+		logger.Error(code.MGA1, requestID, err.Error())
+		abortGroupAuthorization(c, http.StatusInternalServerError, "MIDDLEWARE_GROUP_003", "authorization error")
+		return nil, false
+	}
+	allowed, err := groupPermissionRepo.Enforce(roles, object, action)
+	if err != nil {
+		logger.Error(code.MGA1, requestID, err.Error())
+		abortGroupAuthorization(c, http.StatusInternalServerError, "MIDDLEWARE_GROUP_003", "authorization error")
+		return nil, false
+	}
+	if !allowed {
+		logger.Info(code.MGA2, requestID, claims.UUID+" "+object+":"+action+" in "+groupUUID)
+		abortGroupAuthorization(c, http.StatusForbidden, "MIDDLEWARE_GROUP_004", "forbidden in this group")
+		return nil, false
+	}
+	return roles, true
+}
+
+// authorizeRole checks that the caller's member roles may hand out or take away role
+func authorizeRole(c *gin.Context, groupPermissionRepo repository.GroupPermissionRepository, roles []string, role string) bool {
+	allowed, err := groupPermissionRepo.CanAssignRole(roles, role)
+	if err != nil {
+		logger.Error(code.MGA1, GetRequestID(c), err.Error())
+		abortGroupAuthorization(c, http.StatusInternalServerError, "MIDDLEWARE_GROUP_003", "authorization error")
+		return false
+	}
+	if !allowed {
+		abortGroupAuthorization(c, http.StatusForbidden, "MIDDLEWARE_GROUP_006", "role exceeds the caller's permissions in this group: "+role)
+		return false
+	}
+	return true
+}
+
+// groupFound aborts the request when the group lookup failed
+func groupFound(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		abortGroupAuthorization(c, http.StatusNotFound, "MIDDLEWARE_GROUP_002", "group not found")
+		return false
+	}
+	logger.Error(code.MGA1, GetRequestID(c), err.Error())
+	abortGroupAuthorization(c, http.StatusInternalServerError, "MIDDLEWARE_GROUP_003", "authorization error")
+	return false
+}
+
+func abortGroupAuthorization(c *gin.Context, status int, errCode, message string) {
+	c.JSON(status, gin.H{"code": errCode, "message": message})
+	c.Abort()
+}
+
+// bindGroupScopedRequest binds the JSON body into obj and parses the :id path
+// parameter. The body is bound with ShouldBindBodyWith so the controller reads
+// the very same JSON; query strings and form fields are never consulted, whatever
+// the Content-Type. An empty body or a missing :id are not errors.
+func bindGroupScopedRequest(c *gin.Context, obj interface{}) (uint, error) {
+	if c.Request.Body != nil {
+		if err := c.ShouldBindBodyWith(obj, binding.JSON); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+	}
+	param := c.Param("id")
+	if param == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(param, 10, 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
@@ -364,6 +364,13 @@ func LoggerWithConfig(conf config.BaseConfig) gin.HandlerFunc {
 		// Format: "METHOD /path STATUS" (e.g., "POST /v1/public/user 200")
 		requestInfo := fmt.Sprintf("%s %s %d", c.Request.Method, path, status)
 
+		// Flag requests made with an impersonation token
+		if claims, ok := getUserFromContext(c); ok && claims.Actor != nil {
+			fields["impersonated_by"] = claims.Actor.Subject
+			fields["impersonated_user"] = claims.UUID
+			requestInfo += fmt.Sprintf(" (impersonation: %s as %s)", claims.Actor.Email, claims.Email)
+		}
+
 		if status >= 500 {
 			conf.Logger.ERROR(ToConfigMCode(MLWC5), requestInfo, fields)
 		} else if status >= 400 {
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// API groups with separate rate limits
+const (
+	RateLimitTierAuth     = "auth"
+	RateLimitTierPublic   = "public"
+	RateLimitTierInternal = "internal"
+	RateLimitTierPrivate  = "private"
+)
+
+// Rate limit keys
+const (
+	RateLimitKeyByIP   = "ip"
+	RateLimitKeyByUser = "user"
+	RateLimitKeyByRole = "role"
+)
+
+const defaultRateLimitWindow = time.Minute
+
+// DefaultAuthRateLimit applies to the auth tier when rate_limit.auth sets no requests
+var DefaultAuthRateLimit = config.RateLimitRule{Requests: 60, WindowSeconds: 60, KeyBy: RateLimitKeyByIP}
+
+// RateLimit limits the requests of an API group per client IP, user or role as configured
+// in rate_limit.<tier>; rate_limit.routes give single routes their own limit. Every limited
+// response carries RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy,
+// and requests beyond the limit are rejected with 429 and Retry-After.
+// It must run after ForInternal/ForPrivate to key by user or role.
+// If the counter store fails the request is let through.
+func RateLimit(conf config.BaseConfig, rateLimitRepo repository.RateLimitRepository, tier string) gin.HandlerFunc {
+	rateLimitConf := conf.YamlConfig.Application.Server.RateLimit
+	var tierRule config.RateLimitRule
+	switch tier {
+	case RateLimitTierAuth:
+		tierRule = rateLimitConf.Auth
+		if tierRule.Requests == 0 {
+			tierRule = DefaultAuthRateLimit
+		}
+	case RateLimitTierPublic:
+		tierRule = rateLimitConf.Public
+	case RateLimitTierInternal:
+		tierRule = rateLimitConf.Internal
+	case RateLimitTierPrivate:
+		tierRule = rateLimitConf.Private
+	}
+
+	return func(c *gin.Context) {
+		rule, scope := tierRule, tier
+		for _, route := range rateLimitConf.Routes {
+			if route.Path == c.FullPath() && (route.Method == "" || strings.EqualFold(route.Method, c.Request.Method)) {
+				rule, scope = route.RateLimitRule, tier+":"+strings.ToUpper(route.Method)+" "+route.Path
+				break
+			}
+		}
+		if rule.Requests <= 0 {
+			c.Next()
+			return
+		}
+		window := defaultRateLimitWindow
+		if rule.WindowSeconds > 0 {
+			window = time.Duration(rule.WindowSeconds) * time.Second
+		}
+
+		result, err := rateLimitRepo.Allow(c.Request.Context(), scope+":"+rateLimitPrincipal(c, rule.KeyBy), rule.Requests, window)
+		if err != nil {
+			logger.Warn(code.MRL1, GetRequestID(c), err.Error())
+			c.Next()
+			return
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rule.Requests, int(window/time.Second)))
+		if !result.Allowed {
+			logger.Info(code.MRL2, GetRequestID(c), scope)
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"code": "MIDDLEWARE_RATE_001", "message": "Too many requests, try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitPrincipal returns who a request is counted for. Requests without
+// a user (public endpoints, failed authentication) are counted per client IP,
+// which only comes from X-Forwarded-For behind one of the trusted_proxies.
+func rateLimitPrincipal(c *gin.Context, keyBy string) string {
+	switch keyBy {
+	case RateLimitKeyByUser:
+		if uuid, ok := GetUserUUID(c); ok && uuid != "" {
+			return "user:" + uuid
+		}
+	case RateLimitKeyByRole:
+		if role, ok := GetUserRole(c); ok && role != "" {
+			return "role:" + role
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/logger"
+)
+
+// Authenticator names (authenticators config and model.Users.AuthSource)
+const (
+	AuthenticatorLocal = "local"
+	AuthenticatorLDAP  = "ldap"
+)
+
+var (
+	// ErrAuthenticatorSkipped means the authenticator does not know the user and the next one is tried
+	ErrAuthenticatorSkipped = errors.New("user is not known to this authenticator")
+	// ErrInvalidCredentials means the user is known but the password is wrong
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrAuthenticatorUnavailable means no authenticator accepted the user and at least one failed
+	ErrAuthenticatorUnavailable = errors.New("authentication backend unavailable")
+	// ErrAccountDisabled means the password is correct but the user is deactivated
+	ErrAccountDisabled = errors.New("account is disabled")
+)
+
+// Authenticator verifies an email and password against one identity source.
+// It returns the matching model.Users row, provisioning it when needed.
+type Authenticator interface {
+	Name() string
+	Authenticate(c *gin.Context, email, password string) (*model.Users, error)
+}
+
+// AuthenticatorChain tries its authenticators in order. The first one that knows
+// the user decides: a wrong password is not retried against later authenticators.
+// Backend errors are logged and the next authenticator is tried.
+type AuthenticatorChain interface {
+	Authenticate(c *gin.Context, email, password string) (*model.Users, error)
+}
+
+type authenticatorChain struct {
+	Authenticators []Authenticator
+}
+
+// Authenticate returns the authenticated user, ErrAuthenticatorSkipped when no
+// authenticator knows the email, ErrInvalidCredentials for a wrong password and
+// ErrAuthenticatorUnavailable when the user could not be checked. Deactivated
+// users get ErrAccountDisabled once their password is verified.
+func (rcvr authenticatorChain) Authenticate(c *gin.Context, email, password string) (*model.Users, error) {
+	if email == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	var backendErr error
+	for _, authenticator := range rcvr.Authenticators {
+		user, err := authenticator.Authenticate(c, email, password)
+		switch {
+		case err == nil:
+			if user.DeletedAt != nil {
+				return nil, ErrAccountDisabled
+			}
+			return user, nil
+		case errors.Is(err, ErrAuthenticatorSkipped):
+			continue
+		case errors.Is(err, ErrInvalidCredentials):
+			return nil, ErrInvalidCredentials
+		default:
+			logger.Warn(code.RACA1, c.GetString("requestID"), authenticator.Name()+": "+err.Error())
+			backendErr = err
+		}
+	}
+	if backendErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthenticatorUnavailable, backendErr)
+	}
+	return nil, ErrAuthenticatorSkipped
+}
+
+// localAuthenticator checks the password hash in the users table. Users
+// provisioned by another authenticator are skipped.
+type localAuthenticator struct {
+	UserRepository   UserRepository
+	CommonRepository CommonRepository
+}
+
+func (rcvr localAuthenticator) Name() string {
+	return AuthenticatorLocal
+}
+
+func (rcvr localAuthenticator) Authenticate(c *gin.Context, email, password string) (*model.Users, error) {
+	user, err := rcvr.UserRepository.GetUserByEmail(c, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrAuthenticatorSkipped
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.AuthSource != "" && user.AuthSource != AuthenticatorLocal {
+		return nil, ErrAuthenticatorSkipped
+	}
+	if err := rcvr.CommonRepository.VerifyPassword(user.Password, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
+// NewAuthenticatorChain creates a chain that tries the authenticators in order
+func NewAuthenticatorChain(authenticators ...Authenticator) AuthenticatorChain {
+	return authenticatorChain{Authenticators: authenticators}
+}
+
+// NewConfiguredAuthenticatorChain creates the chain listed in the authenticators
+// config (default: local only). Unknown names are rejected.
+func NewConfiguredAuthenticatorChain(conf config.BaseConfig, userRepository UserRepository, commonRepository CommonRepository) (AuthenticatorChain, error) {
+	names := conf.YamlConfig.Application.Server.Authenticators
+	if len(names) == 0 {
+		names = []string{AuthenticatorLocal}
+	}
+	authenticators := make([]Authenticator, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case AuthenticatorLocal:
+			authenticators = append(authenticators, NewLocalAuthenticator(userRepository, commonRepository))
+		case AuthenticatorLDAP:
+			authenticators = append(authenticators, NewLDAPAuthenticator(conf, userRepository, commonRepository))
+		default:
+			return nil, fmt.Errorf("unknown authenticator %q", name)
+		}
+	}
+	return NewAuthenticatorChain(authenticators...), nil
+}
+
+func NewLocalAuthenticator(userRepository UserRepository, commonRepository CommonRepository) Authenticator {
+	return localAuthenticator{UserRepository: userRepository, CommonRepository: commonRepository}
+}
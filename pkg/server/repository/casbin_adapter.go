@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2"
+	casbinmodel "github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Where the Casbin policies are stored (casbin.storage)
+const (
+	CasbinStorageFile     = "file"     // etc/casbin/<set>/policy.csv, rewritten on every change
+	CasbinStorageDatabase = "database" // casbin_rules table, changed line by line
+)
+
+// casbinAdapter keeps one policy set in the casbin_rules table. Besides loading
+// and saving the whole set it supports Casbin's auto-save, so AddPolicy,
+// RemovePolicy and RemoveFilteredPolicy on the enforcer only insert or delete
+// the affected lines and every replica sees the same policy.
+type casbinAdapter struct {
+	BaseConfig config.BaseConfig
+	PolicySet  string
+}
+
+// LoadPolicy loads all lines of the policy set into the model
+func (rcvr casbinAdapter) LoadPolicy(m casbinmodel.Model) error {
+	var rules []model.CasbinRules
+	if err := rcvr.BaseConfig.DBConnection.Where("policy_set = ?", rcvr.PolicySet).Order("id").Find(&rules).Error; err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if err := persist.LoadPolicyArray(append([]string{r.PType}, r.Rule()...), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SavePolicy replaces all lines of the policy set with the model's
+func (rcvr casbinAdapter) SavePolicy(m casbinmodel.Model) error {
+	rules := []model.CasbinRules{}
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				rules = append(rules, model.NewCasbinRule(rcvr.PolicySet, ptype, rule))
+			}
+		}
+	}
+	return rcvr.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_set = ?", rcvr.PolicySet).Delete(&model.CasbinRules{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&rules, 100).Error
+	})
+}
+
+// AddPolicy inserts one line. A line that is already stored is left as is.
+func (rcvr casbinAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return rcvr.AddPolicies(sec, ptype, [][]string{rule})
+}
+
+// AddPolicies inserts the lines. Lines that are already stored are left as is.
+func (rcvr casbinAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	rows := make([]model.CasbinRules, 0, len(rules))
+	for _, rule := range rules {
+		rows = append(rows, model.NewCasbinRule(rcvr.PolicySet, ptype, rule))
+	}
+	return rcvr.BaseConfig.DBConnection.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// RemovePolicy deletes one line
+func (rcvr casbinAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return rcvr.RemovePolicies(sec, ptype, [][]string{rule})
+}
+
+// RemovePolicies deletes the lines
+func (rcvr casbinAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return rcvr.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		for _, rule := range rules {
+			line := model.NewCasbinRule(rcvr.PolicySet, ptype, rule)
+			err := tx.Where("policy_set = ? AND ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ? AND v5 = ?",
+				line.PolicySet, line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5).Delete(&model.CasbinRules{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveFilteredPolicy deletes the lines whose values from fieldIndex on match
+// fieldValues; empty values match anything.
+func (rcvr casbinAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	q := rcvr.BaseConfig.DBConnection.Where("policy_set = ?", rcvr.PolicySet).Where("ptype = ?", ptype)
+	for i, v := range fieldValues {
+		idx := fieldIndex + i
+		if v == "" || idx < 0 || idx > 5 {
+			continue
+		}
+		q = q.Where(fmt.Sprintf("v%d = ?", idx), v)
+	}
+	return q.Delete(&model.CasbinRules{}).Error
+}
+
+// NewCasbinAdapter creates a Casbin adapter for one policy set of the casbin_rules table
+func NewCasbinAdapter(conf config.BaseConfig, policySet string) persist.BatchAdapter {
+	return casbinAdapter{BaseConfig: conf, PolicySet: policySet}
+}
+
+// NewEnforcer creates the enforcer of a policy set (model.PolicySetApp or
+// model.PolicySetResources) from etc/casbin/<set>/model.conf and the storage
+// selected by casbin.storage. The enforcer is synchronized: requests are
+// enforced while the policy watcher and the admin API change the policy.
+func NewEnforcer(conf config.BaseConfig, policySet string) (*casbin.SyncedEnforcer, error) {
+	modelPath := "etc/casbin/" + policySet + "/model.conf"
+	switch storage := conf.YamlConfig.Application.Server.Casbin.Storage; storage {
+	case "", CasbinStorageFile:
+		return casbin.NewSyncedEnforcer(modelPath, policyFilePath(policySet))
+	case CasbinStorageDatabase:
+		if conf.DBConnection == nil {
+			return nil, fmt.Errorf("casbin storage %q requires a database connection", storage)
+		}
+		return casbin.NewSyncedEnforcer(modelPath, NewCasbinAdapter(conf, policySet))
+	default:
+		return nil, fmt.Errorf("unknown casbin storage: %s", storage)
+	}
+}
+
+// savePolicy persists the enforcer's policy where auto-save does not: the
+// file adapter can only rewrite the whole file. Database-backed enforcers have
+// already stored each change.
+func savePolicy(e *casbin.SyncedEnforcer) error {
+	if _, ok := e.GetAdapter().(*fileadapter.Adapter); ok {
+		return e.SavePolicy()
+	}
+	return nil
+}
@@ -2,10 +2,10 @@ package repository
 
 import (
 	"context"
-	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,66 +13,144 @@ import (
 	"os"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/code"
 	"github.com/ryo-arima/locky/pkg/config"
 	"github.com/ryo-arima/locky/pkg/entity/model"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ryo-arima/locky/pkg/logger"
 )
 
 type CommonRepository interface {
 	GetBaseConfig() config.BaseConfig
+	LoadUserRoles(user model.Users) []string
 	GenerateJWTToken(claims model.JWTClaims) (string, error)
 	ValidateJWTToken(tokenString string) (*model.JWTClaims, error)
+	ValidateJWTTokenForAudience(tokenString, audience string) (*model.JWTClaims, error)
 	ParseTokenUnverified(tokenString string) (*model.JWTClaims, error)
 	IsTokenInvalidated(ctx context.Context, jti string) (bool, error)
 	InvalidateToken(ctx context.Context, tokenString string) error
-	GenerateTokenPair(userID uint, userUUID, email, name, role string) (*model.TokenPair, error)
+	GenerateTokenPair(userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error)
+	GenerateAuthorizedTokenPair(clientID string, userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error)
+	GenerateClientTokenPair(clientID, subjectUUID, name, role string) (*model.TokenPair, error)
+	RotateRefreshToken(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	TouchSession(ctx context.Context, familyID, userAgent, ipAddress string) error
+	ValidatePersonalAccessToken(ctx context.Context, token string) (*model.JWTClaims, error)
 	GenerateJWTSecret() (string, error)
 	ValidateJWTSecretStrength(secret string) error
 	HashPassword(password string) (string, error)
 	VerifyPassword(hashedPassword, password string) error
+	NeedsPasswordRehash(hashedPassword string) bool
 	ValidatePasswordStrength(password string) error
 	DeleteTokenCache(token string) // Added: Cache deletion on logout
 	SendEmail(ctx context.Context, to, subject, body string, isHTML bool) error
 	SendWelcomeEmail(ctx context.Context, to, name string) error
 	SendPasswordResetEmail(ctx context.Context, to, name, resetURL string) error
+	SendVerificationEmail(ctx context.Context, to, name, verifyURL string) error
+	GetJWKS() model.JWKSet
+	StartKeyRotation(ctx context.Context)
 }
 
 type commonRepository struct {
-	BaseConfig  config.BaseConfig
-	RedisClient *redis.Client
-	MailConfig  *config.Mail
+	BaseConfig     config.BaseConfig
+	RedisClient    *redis.Client
+	MailConfig     *config.Mail
+	Keyring        JWTKeyring
+	PasswordHasher PasswordHasher
 }
 
 func (commonRepository *commonRepository) GetBaseConfig() config.BaseConfig {
 	return commonRepository.BaseConfig
 }
 
-// GetJWTSecret returns the JWT secret key from config, environment variable, or a default value
+// ResolveRole returns the app-level role of a user: admin when the email is listed in admin.emails
+func ResolveRole(baseConfig config.BaseConfig, email string) string {
+	for _, adminEmail := range baseConfig.YamlConfig.Application.Server.Admin.Emails {
+		if email == adminEmail {
+			return "admin"
+		}
+	}
+	return "user"
+}
+
+// UserRole returns the global role for a user: "admin" for configured admin emails,
+// otherwise the role mapped from the directory at login, otherwise "user"
+func UserRole(baseConfig config.BaseConfig, user model.Users) string {
+	if role := ResolveRole(baseConfig, user.Email); role == "admin" || user.DirectoryRole == "" {
+		return role
+	}
+	return user.DirectoryRole
+}
+
+// UserRoles returns all global roles of a user: the role from UserRole first,
+// then the roles granted through the API. An "admin" assignment becomes the
+// primary role, so clients reading the single role claim see it.
+func UserRoles(baseConfig config.BaseConfig, user model.Users, assignments []model.RoleAssignments) []string {
+	roles := []string{UserRole(baseConfig, user)}
+	for _, assignment := range assignments {
+		if assignment.Role == "admin" && roles[0] != "admin" {
+			roles = append([]string{"admin"}, roles...)
+		} else if !containsRole(roles, assignment.Role) {
+			roles = append(roles, assignment.Role)
+		}
+	}
+	return roles
+}
+
+// hashSecret returns the hex SHA-256 of a high-entropy secret or of a
+// Redis key part that must not be stored as is (tokens, codes, emails, IPs)
+func hashSecret(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, have := range roles {
+		if have == role {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadUserRoles returns the roles of a user including the assignments stored in
+// the database. When they cannot be loaded only the configured role is returned.
+func (cr *commonRepository) LoadUserRoles(user model.Users) []string {
+	assignments, err := listRoleAssignments(cr.BaseConfig.DBConnection, user.UUID)
+	if err != nil {
+		logger.Error(code.RRAL1, "", user.UUID+": "+err.Error())
+	}
+	return UserRoles(cr.BaseConfig, user, assignments)
+}
+
+// DefaultJWTSecret is the placeholder secret that older configs fell back to.
+// The keyring refuses to start with it.
+const DefaultJWTSecret = "your-256-bit-secret-key-change-this-in-production"
+
+// getJWTSecret returns the JWT secret key from the environment variable or config
 func (cr *commonRepository) getJWTSecret() string {
 	// First try environment variable
 	if envSecret := os.Getenv("JWT_SECRET"); envSecret != "" {
 		return envSecret
 	}
 
-	// Then try config file
-	if cr.BaseConfig.YamlConfig.Application.Server.JWTSecret != "" {
-		return cr.BaseConfig.YamlConfig.Application.Server.JWTSecret
-	}
-
-	// Finally, use default (should be changed in production)
-	return "your-256-bit-secret-key-change-this-in-production"
+	// Then the config file; there is no built-in default
+	return cr.BaseConfig.YamlConfig.Application.Server.JWTSecret
 }
 
-// GenerateJWTToken creates a JWT token with the given claims
+// GenerateJWTToken creates a JWT token with the given claims, signed by the keyring's primary key
 func (cr *commonRepository) GenerateJWTToken(claims model.JWTClaims) (string, error) {
+	kid, alg, err := cr.Keyring.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
 	// Create header
 	header := map[string]interface{}{
-		"alg": "HS256",
+		"alg": alg,
 		"typ": "JWT",
+		"kid": kid,
 	}
 
 	headerJSON, err := json.Marshal(header)
@@ -81,6 +159,18 @@ func (cr *commonRepository) GenerateJWTToken(claims model.JWTClaims) (string, er
 	}
 	headerEncoded := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(headerJSON)
 
+	// Fill registered claims from config when the caller did not set them
+	jwtConf := cr.BaseConfig.YamlConfig.Application.Server.JWT
+	if claims.Issuer == "" {
+		claims.Issuer = jwtConf.Issuer
+	}
+	if len(claims.Audience) == 0 && len(jwtConf.Audience) > 0 {
+		claims.Audience = model.Audience(jwtConf.Audience)
+	}
+	if claims.NotBefore == 0 {
+		claims.NotBefore = claims.IssuedAt
+	}
+
 	// Create payload
 	payloadJSON, err := json.Marshal(claims)
 	if err != nil {
@@ -90,20 +180,33 @@ func (cr *commonRepository) GenerateJWTToken(claims model.JWTClaims) (string, er
 
 	// Create signature
 	message := headerEncoded + "." + payloadEncoded
-	signature := cr.createSignature(message)
+	signature, err := cr.Keyring.Sign(kid, message)
+	if err != nil {
+		return "", err
+	}
 
 	// Combine all parts
-	token := message + "." + signature
+	token := message + "." + base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(signature)
 	return token, nil
 }
 
 // ValidateJWTToken validates and parses a JWT token
 func (cr *commonRepository) ValidateJWTToken(tokenString string) (*model.JWTClaims, error) {
+	return cr.validateJWTToken(tokenString, cr.BaseConfig.YamlConfig.Application.Server.JWT.Audience)
+}
+
+// ValidateJWTTokenForAudience validates a token issued for one of Locky's own
+// flows, whose aud is that flow instead of the configured jwt.audience
+func (cr *commonRepository) ValidateJWTTokenForAudience(tokenString, audience string) (*model.JWTClaims, error) {
+	return cr.validateJWTToken(tokenString, []string{audience})
+}
+
+func (cr *commonRepository) validateJWTToken(tokenString string, audience []string) (*model.JWTClaims, error) {
 	// 1. Try cache first
 	if cr.RedisClient != nil {
 		if cached, err := cr.getCachedTokenClaims(tokenString); err == nil && cached != nil {
-			// Ensure not expired
-			if cached.ExpiresAt >= time.Now().Unix() {
+			// Ensure still valid
+			if cr.validateRegisteredClaims(cached, audience) == nil {
 				return cached, nil
 			}
 		}
@@ -114,12 +217,28 @@ func (cr *commonRepository) ValidateJWTToken(tokenString string) (*model.JWTClai
 		return nil, errors.New("invalid token format")
 	}
 
+	// Decode header to find the verification key
+	headerBytes, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("invalid token header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, errors.New("invalid token header")
+	}
+
 	// Verify signature
-	message := parts[0] + "." + parts[1]
-	expectedSignature := cr.createSignature(message)
-	if parts[2] != expectedSignature {
+	signature, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(parts[2])
+	if err != nil {
 		return nil, errors.New("invalid token signature")
 	}
+	message := parts[0] + "." + parts[1]
+	if err := cr.Keyring.Verify(header.Kid, header.Alg, message, signature); err != nil {
+		return nil, err
+	}
 
 	// Decode payload
 	payloadBytes, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(parts[1])
@@ -132,10 +251,9 @@ func (cr *commonRepository) ValidateJWTToken(tokenString string) (*model.JWTClai
 		return nil, errors.New("invalid token claims")
 	}
 
-	// Check expiration
-	now := time.Now().Unix()
-	if claims.ExpiresAt < now {
-		return nil, errors.New("token expired")
+	// Check exp / nbf / iss / aud
+	if err := cr.validateRegisteredClaims(&claims, audience); err != nil {
+		return nil, err
 	}
 
 	// Cache claims (TTL = min(30m, remaining lifetime))
@@ -146,122 +264,59 @@ func (cr *commonRepository) ValidateJWTToken(tokenString string) (*model.JWTClai
 	return &claims, nil
 }
 
-// GenerateTokenPair creates both access and refresh tokens
-func (cr *commonRepository) GenerateTokenPair(userID uint, userUUID, email, name, role string) (*model.TokenPair, error) {
-	now := time.Now()
-	accessTokenExpiry := now.Add(24 * time.Hour).Unix()      // 24 hours
-	refreshTokenExpiry := now.Add(7 * 24 * time.Hour).Unix() // 7 days
-
-	// Generate a unique ID for the token
-	jti := uuid.New().String()
-
-	// Create access token claims
-	accessClaims := model.JWTClaims{
-		Jti:       jti,
-		UserID:    userID,
-		UUID:      userUUID,
-		Email:     email,
-		Name:      name,
-		Role:      role,
-		IssuedAt:  now.Unix(),
-		ExpiresAt: accessTokenExpiry,
-	}
-
-	// Generate access token
-	accessToken, err := cr.GenerateJWTToken(accessClaims)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
-	}
+// validateRegisteredClaims checks exp and nbf with the configured clock skew,
+// iss when it is configured, and aud when audience is not empty
+func (cr *commonRepository) validateRegisteredClaims(claims *model.JWTClaims, audience []string) error {
+	jwtConf := cr.BaseConfig.YamlConfig.Application.Server.JWT
+	skew := int64(jwtConf.ClockSkewSeconds)
+	now := time.Now().Unix()
 
-	// Create refresh token claims (longer expiry, different JTI)
-	refreshJti := uuid.New().String()
-	refreshClaims := model.JWTClaims{
-		Jti:       refreshJti,
-		UserID:    userID,
-		UUID:      userUUID,
-		Email:     email,
-		Name:      name,
-		Role:      role,
-		IssuedAt:  now.Unix(),
-		ExpiresAt: refreshTokenExpiry,
+	if claims.ExpiresAt+skew < now {
+		return errors.New("token expired")
 	}
-
-	// Generate refresh token
-	refreshToken, err := cr.GenerateJWTToken(refreshClaims)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	if claims.NotBefore != 0 && claims.NotBefore-skew > now {
+		return errors.New("token not yet valid")
+	}
+	if jwtConf.Issuer != "" && claims.Issuer != jwtConf.Issuer {
+		return errors.New("invalid token issuer")
 	}
+	if len(audience) > 0 && !claims.Audience.Contains(audience...) {
+		return errors.New("invalid token audience")
+	}
+	return nil
+}
 
-	return &model.TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    accessTokenExpiry - now.Unix(),
-	}, nil
+// GetJWKS returns the public signing keys for token verification by other services
+func (cr *commonRepository) GetJWKS() model.JWKSet {
+	return cr.Keyring.JWKS()
 }
 
-// createSignature creates HMAC-SHA256 signature for JWT
-func (cr *commonRepository) createSignature(message string) string {
-	secret := cr.getJWTSecret()
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write([]byte(message))
-	signature := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(h.Sum(nil))
-	return signature
+// StartKeyRotation starts scheduled signing key rotation (no-op when disabled)
+func (cr *commonRepository) StartKeyRotation(ctx context.Context) {
+	cr.Keyring.StartRotation(ctx)
 }
 
-// HashPassword hashes a password using bcrypt
+// HashPassword hashes a password with the configured password_hashing algorithm
 func (cr *commonRepository) HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPassword), nil
+	return cr.PasswordHasher.Hash(password)
 }
 
-// VerifyPassword verifies a password against its hash
+// VerifyPassword verifies a password against its hash, whatever algorithm made it
 func (cr *commonRepository) VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return cr.PasswordHasher.Verify(hashedPassword, password)
 }
 
-// ValidatePasswordStrength validates password strength
-func (cr *commonRepository) ValidatePasswordStrength(password string) error {
-	if len(password) < 8 {
-		return errors.New("password must be at least 8 characters long")
-	}
-
-	var (
-		hasUpper   = false
-		hasLower   = false
-		hasNumber  = false
-		hasSpecial = false
-	)
-
-	for _, char := range password {
-		switch {
-		case unicode.IsUpper(char):
-			hasUpper = true
-		case unicode.IsLower(char):
-			hasLower = true
-		case unicode.IsNumber(char):
-			hasNumber = true
-		case unicode.IsPunct(char) || unicode.IsSymbol(char):
-			hasSpecial = true
-		}
-	}
+// NeedsPasswordRehash reports whether a hash should be replaced after a successful login
+func (cr *commonRepository) NeedsPasswordRehash(hashedPassword string) bool {
+	return cr.PasswordHasher.NeedsRehash(hashedPassword)
+}
 
-	if !hasUpper {
-		return errors.New("password must contain at least one uppercase letter")
-	}
-	if !hasLower {
-		return errors.New("password must contain at least one lowercase letter")
-	}
-	if !hasNumber {
-		return errors.New("password must contain at least one number")
-	}
-	if !hasSpecial {
-		return errors.New("password must contain at least one special character")
+// ValidatePasswordStrength checks a password against the stateless rules of
+// password_policy and returns the first violation
+func (cr *commonRepository) ValidatePasswordStrength(password string) error {
+	if violations := CheckPasswordRules(cr.BaseConfig.YamlConfig.Application.Server.PasswordPolicy, password); len(violations) > 0 {
+		return errors.New(violations[0].Message)
 	}
-
 	return nil
 }
 
@@ -291,7 +346,7 @@ func (cr *commonRepository) ValidateJWTSecretStrength(secret string) error {
 		"jwt-secret",
 		"your-secret-key",
 		"change-me",
-		"your-256-bit-secret-key-change-this-in-production",
+		DefaultJWTSecret,
 	}
 
 	for _, weak := range weakSecrets {
@@ -356,6 +411,9 @@ func (cr *commonRepository) ParseTokenUnverified(tokenString string) (*model.JWT
 
 // IsTokenInvalidated checks if a token's JTI exists in the Redis denylist.
 func (cr *commonRepository) IsTokenInvalidated(ctx context.Context, jti string) (bool, error) {
+	if cr.RedisClient == nil {
+		return false, nil
+	}
 	result, err := cr.RedisClient.Exists(ctx, jti).Result()
 	if err != nil {
 		return true, fmt.Errorf("error checking token in redis: %w", err)
@@ -461,6 +519,18 @@ func (cr *commonRepository) SendPasswordResetEmail(ctx context.Context, to, name
 	return cr.SendEmail(ctx, to, subject, body, false)
 }
 
+// SendVerificationEmail sends the link confirming a newly registered email address
+func (cr *commonRepository) SendVerificationEmail(ctx context.Context, to, name, verifyURL string) error {
+	if cr.MailConfig == nil || cr.MailConfig.Host == "" {
+		return errors.New("mail sender not configured")
+	}
+
+	subject := "Verify your email address"
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nIf you did not create a Locky account, please ignore this email.\n\nBest regards,\nThe Locky Team", name, verifyURL)
+
+	return cr.SendEmail(ctx, to, subject, body, false)
+}
+
 func NewCommonRepository(baseConfig config.BaseConfig, redisClient *redis.Client) CommonRepository {
 	// Initialize mail config reference from base config
 	var mailConfig *config.Mail
@@ -468,9 +538,18 @@ func NewCommonRepository(baseConfig config.BaseConfig, redisClient *redis.Client
 		mailConfig = &baseConfig.YamlConfig.Application.Mail
 	}
 
-	return &commonRepository{
-		BaseConfig:  baseConfig,
-		RedisClient: redisClient,
-		MailConfig:  mailConfig,
+	repo := &commonRepository{
+		BaseConfig:     baseConfig,
+		RedisClient:    redisClient,
+		MailConfig:     mailConfig,
+		PasswordHasher: NewPasswordHasher(baseConfig.YamlConfig.Application.Server.PasswordHashing),
 	}
+
+	keyring, err := NewJWTKeyring(baseConfig.YamlConfig.Application.Server.JWT, repo.getJWTSecret(), redisClient)
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize JWT keyring: %v", err))
+	}
+	repo.Keyring = keyring
+
+	return repo
 }
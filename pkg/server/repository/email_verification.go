@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+// Email verification defaults
+const (
+	DefaultEmailVerificationTokenTTL       = 24 * time.Hour
+	DefaultEmailVerificationResendInterval = time.Minute
+)
+
+// EmailVerificationAudience is the aud of verification tokens. It is never one
+// of jwt.audience, so services verifying Locky tokens refuse them.
+const EmailVerificationAudience = "locky:email_verification"
+
+var ErrEmailVerificationTokenInvalid = errors.New("email verification token is invalid or expired")
+
+// EmailVerificationRepository issues the signed links sent to newly registered
+// addresses and records when an address has been confirmed.
+type EmailVerificationRepository interface {
+	CreateVerificationToken(user model.Users) (string, error)
+	ParseVerificationToken(token string) (*model.JWTClaims, error)
+	MarkVerified(c *gin.Context, userUUID, email string) (bool, error)
+	AllowResend(ctx context.Context, userUUID string) (bool, error)
+	VerifyURL(token string) string
+}
+
+type emailVerificationRepository struct {
+	BaseConfig       config.BaseConfig
+	CommonRepository CommonRepository
+	RedisClient      *redis.Client
+}
+
+func emailVerificationResendKey(userUUID string) string {
+	return "email_verification:resend:" + userUUID
+}
+
+func (rcvr emailVerificationRepository) tokenTTL() time.Duration {
+	if sec := rcvr.BaseConfig.YamlConfig.Application.Server.EmailVerification.TokenTTLSeconds; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return DefaultEmailVerificationTokenTTL
+}
+
+// CreateVerificationToken signs a token bound to the user's UUID and current email
+// for EmailVerificationAudience. Changing the email before the link is used invalidates it.
+func (rcvr emailVerificationRepository) CreateVerificationToken(user model.Users) (string, error) {
+	now := time.Now()
+	return rcvr.CommonRepository.GenerateJWTToken(model.JWTClaims{
+		Jti:       uuid.New().String(),
+		Subject:   user.UUID,
+		UUID:      user.UUID,
+		Email:     user.Email,
+		Name:      user.Name,
+		TokenUse:  model.TokenUseEmailVerify,
+		Audience:  model.Audience{EmailVerificationAudience},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(rcvr.tokenTTL()).Unix(),
+	})
+}
+
+// ParseVerificationToken checks the signature, expiry, audience and token use of a verification token
+func (rcvr emailVerificationRepository) ParseVerificationToken(token string) (*model.JWTClaims, error) {
+	claims, err := rcvr.CommonRepository.ValidateJWTTokenForAudience(token, EmailVerificationAudience)
+	if err != nil || claims.TokenUse != model.TokenUseEmailVerify || claims.UUID == "" {
+		return nil, ErrEmailVerificationTokenInvalid
+	}
+	return claims, nil
+}
+
+// MarkVerified sets email_verified_at when the user still has the given email.
+// It reports false when nothing was updated (unknown user, changed email or already verified).
+func (rcvr emailVerificationRepository) MarkVerified(c *gin.Context, userUUID, email string) (bool, error) {
+	result := rcvr.BaseConfig.DBConnection.Model(&model.Users{}).
+		Where("uuid = ? AND email = ? AND email_verified_at IS NULL AND deleted_at IS NULL", userUUID, email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AllowResend reports whether another verification email may be sent to the user.
+// Without Redis resends are not throttled.
+func (rcvr emailVerificationRepository) AllowResend(ctx context.Context, userUUID string) (bool, error) {
+	if rcvr.RedisClient == nil {
+		return true, nil
+	}
+	interval := DefaultEmailVerificationResendInterval
+	if sec := rcvr.BaseConfig.YamlConfig.Application.Server.EmailVerification.ResendIntervalSeconds; sec > 0 {
+		interval = time.Duration(sec) * time.Second
+	}
+	ok, err := rcvr.RedisClient.SetNX(ctx, emailVerificationResendKey(userUUID), 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check verification resend interval: %w", err)
+	}
+	return ok, nil
+}
+
+// VerifyURL builds the link sent in the verification email
+func (rcvr emailVerificationRepository) VerifyURL(token string) string {
+	base := rcvr.BaseConfig.YamlConfig.Application.Server.EmailVerification.VerifyURL
+	u, err := url.Parse(base)
+	if base == "" || err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func NewEmailVerificationRepository(conf config.BaseConfig, commonRepository CommonRepository, redisClient *redis.Client) EmailVerificationRepository {
+	return emailVerificationRepository{BaseConfig: conf, CommonRepository: commonRepository, RedisClient: redisClient}
+}
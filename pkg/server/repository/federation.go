@@ -0,0 +1,485 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+// Federation defaults
+const (
+	DefaultFederationStateTTL = 10 * time.Minute
+	federationMetadataTTL     = time.Hour
+	federationJWKSRefreshGap  = time.Minute // unknown kids refetch the JWKS at most this often
+	federationHTTPTimeout     = 10 * time.Second
+	federationMaxResponseSize = 1 << 20
+)
+
+// DefaultFederationScopes are requested from providers that do not configure scopes
+var DefaultFederationScopes = []string{"openid", "email", "profile"}
+
+var (
+	ErrFederationProviderNotFound   = errors.New("identity provider not found")
+	ErrFederationInvalidState       = errors.New("invalid or expired login state")
+	ErrFederationInvalidIDToken     = errors.New("invalid id token")
+	ErrFederationUpstream           = errors.New("identity provider request failed")
+	ErrFederationStorageUnavailable = errors.New("federated login requires redis")
+)
+
+// FederationRepository runs the authorization code flow (with PKCE) against
+// upstream OpenID Connect providers and validates the returned ID tokens
+// with the provider's JWKS. Provider metadata and keys are cached in memory.
+type FederationRepository interface {
+	GetProvider(name string) (*config.UpstreamProvider, error)
+	ListProviders() []config.UpstreamProvider
+	AuthorizationURL(ctx context.Context, provider, linkUserUUID string) (string, string, error)
+	CompleteLogin(ctx context.Context, provider, state, code, binding string) (*model.FederatedIdentity, *model.FederationStates, error)
+}
+
+type federationRepository struct {
+	BaseConfig  config.BaseConfig
+	RedisClient *redis.Client
+	HTTPClient  *http.Client
+
+	mu       sync.Mutex
+	metadata map[string]*upstreamMetadata
+}
+
+// upstreamMetadata is the discovery document of a provider and its signing keys
+type upstreamMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	fetchedAt     time.Time
+	keys          map[string]model.JWK
+	keysFetchedAt time.Time
+}
+
+// upstreamTokenResponse is the token endpoint answer (RFC 6749 section 5)
+type upstreamTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// upstreamIDTokenClaims are the ID token claims Locky reads
+type upstreamIDTokenClaims struct {
+	Issuer          string         `json:"iss"`
+	Subject         string         `json:"sub"`
+	Audience        model.Audience `json:"aud"`
+	AuthorizedParty string         `json:"azp"`
+	ExpiresAt       int64          `json:"exp"`
+	NotBefore       int64          `json:"nbf"`
+	Nonce           string         `json:"nonce"`
+	Email           string         `json:"email"`
+	EmailVerified   interface{}    `json:"email_verified"` // some providers send "true"
+	Name            string         `json:"name"`
+}
+
+// FederatedAuthSource is the model.Users.AuthSource of users provisioned through a provider
+func FederatedAuthSource(provider string) string {
+	return "oidc:" + provider
+}
+
+// helper: state keys are looked up by hash, never by the raw value
+func federationStateKey(state string) string {
+	return "federation:state:" + hashSecret(state)
+}
+
+// GetProvider returns the configured provider with defaults applied
+func (rcvr *federationRepository) GetProvider(name string) (*config.UpstreamProvider, error) {
+	for _, provider := range rcvr.ListProviders() {
+		if name != "" && provider.Name == name {
+			return &provider, nil
+		}
+	}
+	return nil, ErrFederationProviderNotFound
+}
+
+// ListProviders returns the configured providers with defaults applied
+func (rcvr *federationRepository) ListProviders() []config.UpstreamProvider {
+	providers := make([]config.UpstreamProvider, 0, len(rcvr.BaseConfig.YamlConfig.Application.Server.Federation.Providers))
+	for _, provider := range rcvr.BaseConfig.YamlConfig.Application.Server.Federation.Providers {
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = DefaultFederationScopes
+		}
+		if provider.DisplayName == "" {
+			provider.DisplayName = provider.Name
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// AuthorizationURL stores a new login state and returns the provider URL the
+// browser is sent to, and the binding secret the caller hands to that browser
+// (as an HttpOnly cookie). The state can only be redeemed with the binding, so
+// a callback URL cannot be completed in another browser. linkUserUUID links the
+// identity to a signed-in user instead of signing in.
+func (rcvr *federationRepository) AuthorizationURL(ctx context.Context, providerName, linkUserUUID string) (string, string, error) {
+	provider, err := rcvr.GetProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+	if rcvr.RedisClient == nil {
+		return "", "", ErrFederationStorageUnavailable
+	}
+	meta, err := rcvr.loadMetadata(ctx, provider)
+	if err != nil {
+		return "", "", err
+	}
+	endpoint, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: invalid authorization endpoint", ErrFederationUpstream)
+	}
+
+	values := make([]string, 4)
+	for i := range values {
+		if values[i], err = randomURLToken(); err != nil {
+			return "", "", err
+		}
+	}
+	stateValue, nonce, verifier, binding := values[0], values[1], values[2], values[3]
+	data, err := json.Marshal(model.FederationStates{
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserUUID: linkUserUUID,
+		BindingHash:  hashSecret(binding),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	ttl := DefaultFederationStateTTL
+	if seconds := rcvr.BaseConfig.YamlConfig.Application.Server.Federation.StateTTLSeconds; seconds > 0 {
+		ttl = time.Duration(seconds) * time.Second
+	}
+	if err := rcvr.RedisClient.Set(ctx, federationStateKey(stateValue), data, ttl).Err(); err != nil {
+		return "", "", fmt.Errorf("failed to store login state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", provider.RedirectURL)
+	query.Set("scope", strings.Join(provider.Scopes, " "))
+	query.Set("state", stateValue)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), binding, nil
+}
+
+// CompleteLogin redeems the state and authorization code of a callback and
+// returns the identity from the verified ID token. binding is the secret
+// AuthorizationURL handed to the browser; a callback without it leaves the
+// state untouched. A state can be used once.
+func (rcvr *federationRepository) CompleteLogin(ctx context.Context, providerName, stateValue, code, binding string) (*model.FederatedIdentity, *model.FederationStates, error) {
+	provider, err := rcvr.GetProvider(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rcvr.RedisClient == nil {
+		return nil, nil, ErrFederationStorageUnavailable
+	}
+	if stateValue == "" || code == "" || binding == "" {
+		return nil, nil, ErrFederationInvalidState
+	}
+	key := federationStateKey(stateValue)
+	data, err := rcvr.RedisClient.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil, ErrFederationInvalidState
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load login state: %w", err)
+	}
+	var state model.FederationStates
+	if err := json.Unmarshal(data, &state); err != nil || state.Provider != provider.Name {
+		return nil, nil, ErrFederationInvalidState
+	}
+	if subtle.ConstantTimeCompare([]byte(state.BindingHash), []byte(hashSecret(binding))) != 1 {
+		return nil, nil, ErrFederationInvalidState
+	}
+	// Only one callback may redeem the state
+	if deleted, err := rcvr.RedisClient.Del(ctx, key).Result(); err != nil || deleted == 0 {
+		return nil, nil, ErrFederationInvalidState
+	}
+
+	meta, err := rcvr.loadMetadata(ctx, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	idToken, err := rcvr.exchangeCode(ctx, provider, meta, code, state.CodeVerifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	identity, err := rcvr.verifyIDToken(ctx, provider, meta, idToken, state.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	return identity, &state, nil
+}
+
+// exchangeCode redeems the authorization code at the token endpoint
+// (client_secret_basic, or client_id only for public clients)
+func (rcvr *federationRepository) exchangeCode(ctx context.Context, provider *config.UpstreamProvider, meta *upstreamMetadata, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {provider.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if provider.ClientSecret == "" {
+		form.Set("client_id", provider.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFederationUpstream, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if provider.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+	}
+	resp, err := rcvr.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFederationUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	var token upstreamTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, federationMaxResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("%w: token endpoint answered %d", ErrFederationUpstream, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("%w: %s %s", ErrFederationUpstream, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token in the token response", ErrFederationInvalidIDToken)
+	}
+	return token.IDToken, nil
+}
+
+// verifyIDToken checks the signature with the provider's JWKS and the iss,
+// aud, exp, nbf and nonce claims
+func (rcvr *federationRepository) verifyIDToken(ctx context.Context, provider *config.UpstreamProvider, meta *upstreamMetadata, idToken, nonce string) (*model.FederatedIdentity, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrFederationInvalidIDToken, reason)
+	}
+	enc := base64.RawURLEncoding
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed token")
+	}
+	headerBytes, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, invalid("malformed header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, invalid("malformed header")
+	}
+	// Only asymmetric algorithms: "none" and HS256 are refused
+	if header.Alg != JWTAlgRS256 && header.Alg != JWTAlgES256 && header.Alg != JWTAlgEdDSA {
+		return nil, invalid("unsupported algorithm " + header.Alg)
+	}
+	signature, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed signature")
+	}
+	jwk, err := rcvr.signingKey(ctx, provider, meta, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Alg != "" && jwk.Alg != header.Alg {
+		return nil, invalid("algorithm does not match the key")
+	}
+	public, err := parsePublicJWK(*jwk)
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+	if err := verifyJWTSignature(header.Alg, public, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, invalid(err.Error())
+	}
+
+	payload, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, invalid("malformed payload")
+	}
+	var claims upstreamIDTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, invalid("malformed claims")
+	}
+	skew := int64(rcvr.BaseConfig.YamlConfig.Application.Server.JWT.ClockSkewSeconds)
+	now := time.Now().Unix()
+	switch {
+	case claims.Issuer != meta.Issuer:
+		return nil, invalid("unexpected issuer")
+	case !claims.Audience.Contains(provider.ClientID):
+		return nil, invalid("unexpected audience")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != provider.ClientID:
+		return nil, invalid("unexpected authorized party")
+	case claims.ExpiresAt+skew < now:
+		return nil, invalid("token expired")
+	case claims.NotBefore != 0 && claims.NotBefore-skew > now:
+		return nil, invalid("token not yet valid")
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, invalid("nonce mismatch")
+	case claims.Subject == "":
+		return nil, invalid("missing subject")
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = strings.EqualFold(v, "true")
+	}
+	return &model.FederatedIdentity{
+		Provider:      provider.Name,
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: verified && claims.Email != "",
+		Name:          claims.Name,
+	}, nil
+}
+
+// loadMetadata returns the cached discovery document, fetching it when missing or stale
+func (rcvr *federationRepository) loadMetadata(ctx context.Context, provider *config.UpstreamProvider) (*upstreamMetadata, error) {
+	rcvr.mu.Lock()
+	cached := rcvr.metadata[provider.Name]
+	rcvr.mu.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < federationMetadataTTL {
+		return cached, nil
+	}
+
+	var meta upstreamMetadata
+	if err := rcvr.getJSON(ctx, strings.TrimRight(provider.Issuer, "/")+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(meta.Issuer, "/") != strings.TrimRight(provider.Issuer, "/") {
+		return nil, fmt.Errorf("%w: discovery issuer %q does not match %q", ErrFederationUpstream, meta.Issuer, provider.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrFederationUpstream)
+	}
+	meta.fetchedAt = time.Now()
+
+	rcvr.mu.Lock()
+	defer rcvr.mu.Unlock()
+	// Keep the keys across metadata refreshes of the same JWKS
+	if cached != nil && cached.JWKSURI == meta.JWKSURI {
+		meta.keys = cached.keys
+		meta.keysFetchedAt = cached.keysFetchedAt
+	}
+	rcvr.metadata[provider.Name] = &meta
+	return &meta, nil
+}
+
+// signingKey returns the provider key with the kid. An unknown kid refetches
+// the JWKS (the provider may have rotated), at most once per federationJWKSRefreshGap.
+func (rcvr *federationRepository) signingKey(ctx context.Context, provider *config.UpstreamProvider, meta *upstreamMetadata, kid string) (*model.JWK, error) {
+	rcvr.mu.Lock()
+	key, found := lookupUpstreamKey(meta.keys, kid)
+	stale := time.Since(meta.keysFetchedAt) >= federationJWKSRefreshGap
+	rcvr.mu.Unlock()
+	if found {
+		return &key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrFederationInvalidIDToken, kid)
+	}
+
+	var set model.JWKSet
+	if err := rcvr.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]model.JWK, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use == "" || jwk.Use == "sig" {
+			keys[jwk.Kid] = jwk
+		}
+	}
+
+	rcvr.mu.Lock()
+	meta.keys = keys
+	meta.keysFetchedAt = time.Now()
+	key, found = lookupUpstreamKey(keys, kid)
+	rcvr.mu.Unlock()
+	if !found {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrFederationInvalidIDToken, kid)
+	}
+	return &key, nil
+}
+
+// lookupUpstreamKey finds a key by kid; tokens without kid need a JWKS with a single key
+func lookupUpstreamKey(keys map[string]model.JWK, kid string) (model.JWK, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+func (rcvr *federationRepository) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFederationUpstream, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := rcvr.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFederationUpstream, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s answered %d", ErrFederationUpstream, endpoint, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, federationMaxResponseSize)).Decode(out); err != nil {
+		return fmt.Errorf("%w: invalid JSON from %s", ErrFederationUpstream, endpoint)
+	}
+	return nil
+}
+
+func randomURLToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewFederationRepository creates the upstream OIDC provider repository
+func NewFederationRepository(conf config.BaseConfig, redisClient *redis.Client) FederationRepository {
+	return &federationRepository{
+		BaseConfig:  conf,
+		RedisClient: redisClient,
+		HTTPClient:  &http.Client{Timeout: federationHTTPTimeout},
+		metadata:    map[string]*upstreamMetadata{},
+	}
+}
@@ -77,6 +77,8 @@ type GroupQueryFilter struct {
 	NameLike   *string
 	Limit      int
 	Offset     int
+	// ExcludeDeleted skips soft-deleted rows
+	ExcludeDeleted bool
 }
 
 func (f *GroupQueryFilter) normalize() {
@@ -91,6 +93,9 @@ func (f *GroupQueryFilter) normalize() {
 func (rcvr groupRepository) ListGroups(c *gin.Context, filter GroupQueryFilter) ([]model.Groups, error) {
 	filter.normalize()
 	q := rcvr.BaseConfig.DBConnection.Model(&model.Groups{})
+	if filter.ExcludeDeleted {
+		q = q.Where("deleted_at IS NULL")
+	}
 	if filter.ID != nil {
 		q = q.Where("id = ?", *filter.ID)
 	}
@@ -116,6 +121,9 @@ func (rcvr groupRepository) ListGroups(c *gin.Context, filter GroupQueryFilter)
 
 func (rcvr groupRepository) CountGroups(c *gin.Context, filter GroupQueryFilter) (int64, error) {
 	q := rcvr.BaseConfig.DBConnection.Model(&model.Groups{})
+	if filter.ExcludeDeleted {
+		q = q.Where("deleted_at IS NULL")
+	}
 	if filter.ID != nil {
 		q = q.Where("id = ?", *filter.ID)
 	}
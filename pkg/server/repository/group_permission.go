@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// GroupPermissionRepository evaluates the group-scoped resource policy
+// (etc/casbin/resources) with the roles a user holds as a member of a group.
+// Global roles play no part here: a user who is not a member of a group holds
+// no group roles in it.
+type GroupPermissionRepository interface {
+	GetMemberRoles(c *gin.Context, groupUUID, userUUID string) ([]string, error)
+	Enforce(roles []string, object, action string) (bool, error)
+	IsRoleDefined(role string) bool
+	CanAssignRole(roles []string, role string) (bool, error)
+}
+
+type groupPermissionRepository struct {
+	MemberRepository MemberRepository
+	ResourceEnforcer *casbin.SyncedEnforcer
+}
+
+// GetMemberRoles returns the roles of the user's memberships in the group
+func (rcvr groupPermissionRepository) GetMemberRoles(c *gin.Context, groupUUID, userUUID string) ([]string, error) {
+	members, err := rcvr.MemberRepository.ListMembers(c, MemberQueryFilter{GroupUUID: &groupUUID, UserUUID: &userUUID, ExcludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	roles := []string{}
+	for _, m := range members {
+		if m.Role != "" && !containsRole(roles, m.Role) {
+			roles = append(roles, m.Role)
+		}
+	}
+	return roles, nil
+}
+
+// Enforce reports whether any of the member roles allows the action on the object
+func (rcvr groupPermissionRepository) Enforce(roles []string, object, action string) (bool, error) {
+	for _, role := range roles {
+		ok, err := rcvr.ResourceEnforcer.Enforce(role, object, action)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsRoleDefined reports whether the resource policy grants the role any permission
+func (rcvr groupPermissionRepository) IsRoleDefined(role string) bool {
+	if role == "" {
+		return false
+	}
+	perms, err := rcvr.ResourceEnforcer.GetPermissionsForUser(role)
+	return err == nil && len(perms) > 0
+}
+
+// CanAssignRole reports whether member roles cover every permission of role,
+// inherited ones included, so that nobody can hand out more than they hold
+// (e.g. a maintainer making someone an owner).
+func (rcvr groupPermissionRepository) CanAssignRole(roles []string, role string) (bool, error) {
+	perms, err := rcvr.ResourceEnforcer.GetImplicitPermissionsForUser(role)
+	if err != nil {
+		return false, err
+	}
+	for _, perm := range perms {
+		if len(perm) < 3 {
+			continue
+		}
+		ok, err := rcvr.Enforce(roles, perm[1], perm[2])
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// NewGroupPermissionRepository creates the group permission repository
+func NewGroupPermissionRepository(memberRepository MemberRepository, resourceEnforcer *casbin.SyncedEnforcer) GroupPermissionRepository {
+	return groupPermissionRepository{MemberRepository: memberRepository, ResourceEnforcer: resourceEnforcer}
+}
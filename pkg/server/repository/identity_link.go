@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityLinkNotFound = errors.New("identity link not found")
+	ErrIdentityLinkExists   = errors.New("identity is already linked to a user")
+)
+
+// IdentityLinkRepository stores the links between users and their accounts at
+// upstream OpenID Connect providers.
+type IdentityLinkRepository interface {
+	GetLink(c *gin.Context, provider, subject string) (*model.IdentityLinks, error)
+	ListLinks(c *gin.Context, userUUID string) ([]model.IdentityLinks, error)
+	CreateLink(c *gin.Context, link model.IdentityLinks) (*model.IdentityLinks, error)
+	TouchLink(c *gin.Context, link model.IdentityLinks) error
+	DeleteLink(c *gin.Context, userUUID, linkUUID string) error
+}
+
+type identityLinkRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// GetLink returns the link of a provider account
+func (rcvr identityLinkRepository) GetLink(c *gin.Context, provider, subject string) (*model.IdentityLinks, error) {
+	var link model.IdentityLinks
+	if err := rcvr.BaseConfig.DBConnection.Where("provider = ? AND subject = ?", provider, subject).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityLinkNotFound
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListLinks returns the links of a user, oldest first
+func (rcvr identityLinkRepository) ListLinks(c *gin.Context, userUUID string) ([]model.IdentityLinks, error) {
+	links := []model.IdentityLinks{}
+	if err := rcvr.BaseConfig.DBConnection.Where("user_uuid = ?", userUUID).Order("id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// CreateLink links a provider account to a user. A provider account can only
+// be linked once.
+func (rcvr identityLinkRepository) CreateLink(c *gin.Context, link model.IdentityLinks) (*model.IdentityLinks, error) {
+	if _, err := rcvr.GetLink(c, link.Provider, link.Subject); err == nil {
+		return nil, ErrIdentityLinkExists
+	} else if !errors.Is(err, ErrIdentityLinkNotFound) {
+		return nil, err
+	}
+	now := time.Now()
+	link.ID = 0
+	link.UUID = uuid.New().String()
+	link.CreatedAt = &now
+	link.UpdatedAt = &now
+	if err := rcvr.BaseConfig.DBConnection.Create(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrIdentityLinkExists
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// TouchLink records a login through the link
+func (rcvr identityLinkRepository) TouchLink(c *gin.Context, link model.IdentityLinks) error {
+	now := time.Now()
+	return rcvr.BaseConfig.DBConnection.Model(&model.IdentityLinks{}).Where("id = ?", link.ID).
+		Updates(map[string]interface{}{"last_login_at": &now, "updated_at": &now}).Error
+}
+
+// DeleteLink removes one of the user's links
+func (rcvr identityLinkRepository) DeleteLink(c *gin.Context, userUUID, linkUUID string) error {
+	result := rcvr.BaseConfig.DBConnection.Where("user_uuid = ? AND uuid = ?", userUUID, linkUUID).Delete(&model.IdentityLinks{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityLinkNotFound
+	}
+	return nil
+}
+
+// NewIdentityLinkRepository creates the identity link repository
+func NewIdentityLinkRepository(conf config.BaseConfig) IdentityLinkRepository {
+	return identityLinkRepository{BaseConfig: conf}
+}
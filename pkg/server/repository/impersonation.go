@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+// DefaultImpersonationTokenTTL is the lifetime of impersonation tokens when token_ttl_seconds is not set
+const DefaultImpersonationTokenTTL = 15 * time.Minute
+
+var (
+	ErrImpersonationDisabled  = errors.New("impersonation is disabled")
+	ErrImpersonationForbidden = errors.New("not allowed to impersonate this user")
+)
+
+// ImpersonationRepository issues short-lived access tokens that let an admin act
+// as another user. The tokens carry an act claim naming the admin, belong to no
+// token family (they cannot be refreshed) and are never issued for an impersonated,
+// personal access or service account token.
+type ImpersonationRepository interface {
+	CanImpersonate(actor model.JWTClaims, target model.Users) error
+	IssueToken(actor model.JWTClaims, target model.Users) (string, int64, error)
+}
+
+type impersonationRepository struct {
+	BaseConfig       config.BaseConfig
+	CommonRepository CommonRepository
+}
+
+// CanImpersonate checks the impersonation rules. Without rules, admins may
+// impersonate users that are not admins themselves.
+func (rcvr impersonationRepository) CanImpersonate(actor model.JWTClaims, target model.Users) error {
+	impConf := rcvr.BaseConfig.YamlConfig.Application.Server.Impersonation
+	if !impConf.Enabled {
+		return ErrImpersonationDisabled
+	}
+	if actor.Actor != nil || actor.ClientID != "" || actor.TokenUse == model.TokenUsePAT {
+		return ErrImpersonationForbidden
+	}
+	if actor.UUID == "" || actor.UUID == target.UUID {
+		return ErrImpersonationForbidden
+	}
+
+	targetRoles := rcvr.CommonRepository.LoadUserRoles(target)
+	if len(impConf.Rules) == 0 {
+		if actor.HasRole("admin") && !containsRole(targetRoles, "admin") {
+			return nil
+		}
+		return ErrImpersonationForbidden
+	}
+	for _, rule := range impConf.Rules {
+		if matchesImpersonationEntry(rule.Actors, actor.Email, actor.AllRoles()) && matchesImpersonationEntry(rule.Targets, target.Email, targetRoles) {
+			return nil
+		}
+	}
+	return ErrImpersonationForbidden
+}
+
+// matchesImpersonationEntry reports whether an email or one of the roles matches
+// one of the rule entries (email address, "role:<name>" or "*")
+func matchesImpersonationEntry(entries []string, email string, roles []string) bool {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "*":
+			return true
+		case strings.HasPrefix(entry, "role:"):
+			if containsRole(roles, strings.TrimPrefix(entry, "role:")) {
+				return true
+			}
+		case email != "" && strings.EqualFold(entry, email):
+			return true
+		}
+	}
+	return false
+}
+
+// IssueToken returns an access token for target with an act claim naming actor,
+// and its lifetime in seconds
+func (rcvr impersonationRepository) IssueToken(actor model.JWTClaims, target model.Users) (string, int64, error) {
+	if err := rcvr.CanImpersonate(actor, target); err != nil {
+		return "", 0, err
+	}
+
+	ttl := DefaultImpersonationTokenTTL
+	if seconds := rcvr.BaseConfig.YamlConfig.Application.Server.Impersonation.TokenTTLSeconds; seconds > 0 {
+		ttl = time.Duration(seconds) * time.Second
+	}
+	roles := rcvr.CommonRepository.LoadUserRoles(target)
+	now := time.Now()
+	token, err := rcvr.CommonRepository.GenerateJWTToken(model.JWTClaims{
+		Jti:       uuid.New().String(),
+		Subject:   target.UUID,
+		UserID:    target.ID,
+		UUID:      target.UUID,
+		Email:     target.Email,
+		Name:      target.Name,
+		Role:      roles[0],
+		Roles:     roles,
+		TokenUse:  model.TokenUseAccess,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Actor:     &model.Actor{Subject: actor.UUID, Email: actor.Email},
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: impersonation token: %v", ErrTokenGeneration, err)
+	}
+	return token, int64(ttl.Seconds()), nil
+}
+
+func NewImpersonationRepository(conf config.BaseConfig, commonRepository CommonRepository) ImpersonationRepository {
+	return impersonationRepository{
+		BaseConfig:       conf,
+		CommonRepository: commonRepository,
+	}
+}
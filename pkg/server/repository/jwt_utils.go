@@ -1 +1,968 @@
 package repository
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/logger"
+)
+
+// Supported JWT signing algorithms
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// JWT key status
+const (
+	JWTKeyStatusActive  = "active"
+	JWTKeyStatusRetired = "retired"
+)
+
+const (
+	jwtKeyringRedisKey    = "auth:jwt:keyring"
+	jwtKeyringLockKey     = "auth:jwt:keyring:lock"
+	jwtKeyringLockTTL     = 30 * time.Second
+	jwtKeyringReloadGap   = 10 * time.Second
+	defaultRetiredKeyTTL  = 8 * 24 * time.Hour // refresh token lifetime (7d) + margin
+	maxRotationCheckEvery = time.Minute
+)
+
+// JWTKeyring holds the signing keys used for access and refresh tokens.
+// The newest active key of the configured algorithm signs; every active
+// or retired key that has not been pruned is accepted for verification.
+type JWTKeyring interface {
+	SigningKey() (kid string, alg string, err error)
+	Sign(kid, signingInput string) ([]byte, error)
+	Verify(kid, alg, signingInput string, signature []byte) error
+	JWKS() model.JWKSet
+	Rotate(ctx context.Context) error
+	RotateIfDue(ctx context.Context) (bool, error)
+	StartRotation(ctx context.Context)
+}
+
+// jwtKey is a single key held by the keyring
+type jwtKey struct {
+	Kid       string
+	Algorithm string
+	Status    string
+	CreatedAt time.Time
+	RetiredAt time.Time
+	Generated bool      // created by rotation and shared through Redis
+	Legacy    bool      // derived from jwt_secret, also accepts tokens without kid
+	ExpiresAt time.Time // stops verifying after this time when set
+	plaintext bool      // stored in Redis without encryption
+	secret    []byte
+	private   crypto.Signer
+}
+
+// storedJWTKey is the Redis representation of a generated key
+type storedJWTKey struct {
+	Kid        string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Status     string `json:"status"`
+	CreatedAt  int64  `json:"created_at"`
+	RetiredAt  int64  `json:"retired_at,omitempty"`
+	Secret     string `json:"secret,omitempty"`      // HS256 only, without key_encryption_key
+	PrivateKey string `json:"private_key,omitempty"` // PKCS#8 PEM, without key_encryption_key
+	Sealed     string `json:"sealed,omitempty"`      // AES-GCM sealed HS256 secret or PKCS#8 DER
+}
+
+type jwtKeyring struct {
+	mu          sync.RWMutex
+	conf        config.JWT
+	algorithm   string
+	retiredTTL  time.Duration
+	keys        map[string]*jwtKey
+	order       []string
+	primary     string
+	legacyKid   string
+	redisClient *redis.Client
+	kek         cipher.AEAD // encrypts generated keys stored in Redis, nil stores them as is
+	lastReload  time.Time
+}
+
+// NormalizeJWTAlgorithm returns the canonical algorithm name (HS256 when empty)
+func NormalizeJWTAlgorithm(alg string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(alg)) {
+	case "", JWTAlgHS256:
+		return JWTAlgHS256, nil
+	case JWTAlgRS256:
+		return JWTAlgRS256, nil
+	case JWTAlgES256:
+		return JWTAlgES256, nil
+	case "EDDSA", "ED25519":
+		return JWTAlgEdDSA, nil
+	}
+	return "", fmt.Errorf("unsupported JWT algorithm: %s", alg)
+}
+
+// SigningKey returns the kid and algorithm of the current primary key
+func (k *jwtKeyring) SigningKey() (string, string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[k.primary]
+	if !ok {
+		return "", "", errors.New("no active signing key")
+	}
+	return key.Kid, key.Algorithm, nil
+}
+
+// Sign signs the JWT signing input with the given key
+func (k *jwtKeyring) Sign(kid, signingInput string) ([]byte, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return signJWT(key, []byte(signingInput))
+}
+
+// Verify checks the signature with the key identified by kid.
+// Tokens without kid are only accepted for the legacy jwt_secret key,
+// which is registered for HS256 or the accept_legacy_hs256 opt-in.
+func (k *jwtKeyring) Verify(kid, alg, signingInput string, signature []byte) error {
+	if alg == "" || strings.EqualFold(alg, "none") {
+		return errors.New("invalid token algorithm")
+	}
+	if kid == "" {
+		if k.legacyKid == "" {
+			return errors.New("token key id is required")
+		}
+		kid = k.legacyKid
+	}
+
+	key := k.lookup(kid)
+	if key == nil && k.redisClient != nil {
+		// Another instance may have rotated; refresh shared keys and retry
+		k.reloadIfStale(context.Background())
+		key = k.lookup(kid)
+	}
+	if key == nil {
+		return errors.New("unknown token key id")
+	}
+	if key.Algorithm != alg {
+		return errors.New("token algorithm does not match key")
+	}
+	if key.Status == JWTKeyStatusRetired && !key.RetiredAt.IsZero() && time.Since(key.RetiredAt) > k.retiredTTL {
+		return errors.New("token signing key has expired")
+	}
+	if !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt) {
+		return errors.New("token signing key has expired")
+	}
+	return verifyJWT(key, []byte(signingInput), signature)
+}
+
+// JWKS returns the public part of every asymmetric key
+func (k *jwtKeyring) JWKS() model.JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := model.JWKSet{Keys: []model.JWK{}}
+	for _, kid := range k.order {
+		key := k.keys[kid]
+		if key.private == nil {
+			continue
+		}
+		if jwk, err := publicJWK(key); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// Rotate generates a new primary key and retires the previous generated ones.
+// With Redis, a short lock makes sure only one instance rotates at a time.
+func (k *jwtKeyring) Rotate(ctx context.Context) error {
+	if k.redisClient != nil {
+		ok, err := k.redisClient.SetNX(ctx, jwtKeyringLockKey, "1", jwtKeyringLockTTL).Result()
+		if err != nil {
+			return fmt.Errorf("failed to acquire keyring lock: %w", err)
+		}
+		if !ok {
+			// Someone else is rotating, pick up their result later
+			return nil
+		}
+		defer k.redisClient.Del(ctx, jwtKeyringLockKey)
+		if err := k.reload(ctx); err != nil {
+			return err
+		}
+	}
+
+	key, err := generateJWTKey(k.algorithm)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	now := time.Now()
+	var pruned []string
+	for _, kid := range k.order {
+		existing := k.keys[kid]
+		if !existing.Generated {
+			continue
+		}
+		if existing.Status == JWTKeyStatusActive {
+			existing.Status = JWTKeyStatusRetired
+			existing.RetiredAt = now
+		}
+		if now.Sub(existing.RetiredAt) > k.retiredTTL {
+			pruned = append(pruned, kid)
+		}
+	}
+	for _, kid := range pruned {
+		k.removeLocked(kid)
+	}
+	k.addLocked(key)
+	k.selectPrimaryLocked()
+	generated := k.generatedLocked()
+	k.mu.Unlock()
+
+	if k.redisClient != nil {
+		if err := k.persist(ctx, generated, pruned); err != nil {
+			return err
+		}
+	}
+
+	logger.Info(code.RJKR2, "", fmt.Sprintf("JWT signing key rotated: kid=%s alg=%s", key.Kid, key.Algorithm))
+	return nil
+}
+
+// RotateIfDue rotates when the generated primary key is older than
+// rotation_interval_hours. Configured keys (jwt.keys and jwt_secret) are
+// never due: the operator replaces them, rotation does not.
+func (k *jwtKeyring) RotateIfDue(ctx context.Context) (bool, error) {
+	if k.conf.RotationIntervalHours <= 0 {
+		return false, nil
+	}
+	if k.redisClient != nil {
+		if err := k.reload(ctx); err != nil {
+			return false, err
+		}
+	}
+	k.mu.RLock()
+	key, ok := k.keys[k.primary]
+	k.mu.RUnlock()
+	if !ok || !key.Generated || time.Since(key.CreatedAt) < time.Duration(k.conf.RotationIntervalHours)*time.Hour {
+		return false, nil
+	}
+	return true, k.Rotate(ctx)
+}
+
+// StartRotation checks every minute (or every rotation_interval_hours, if
+// shorter) whether the primary key is due. It also reloads shared keys so
+// that every instance follows the newest primary.
+func (k *jwtKeyring) StartRotation(ctx context.Context) {
+	if k.conf.RotationIntervalHours <= 0 {
+		return
+	}
+	interval := time.Duration(k.conf.RotationIntervalHours) * time.Hour
+	checkEvery := interval
+	if checkEvery > maxRotationCheckEvery {
+		checkEvery = maxRotationCheckEvery
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := k.RotateIfDue(ctx); err != nil {
+					logger.Error(code.RJKR3, "", "Failed to rotate JWT signing key: "+err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (k *jwtKeyring) lookup(kid string) *jwtKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[kid]
+}
+
+func (k *jwtKeyring) addLocked(key *jwtKey) {
+	if _, exists := k.keys[key.Kid]; !exists {
+		k.order = append(k.order, key.Kid)
+	}
+	k.keys[key.Kid] = key
+}
+
+func (k *jwtKeyring) removeLocked(kid string) {
+	delete(k.keys, kid)
+	for i, v := range k.order {
+		if v == kid {
+			k.order = append(k.order[:i], k.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (k *jwtKeyring) generatedLocked() []*jwtKey {
+	var keys []*jwtKey
+	for _, kid := range k.order {
+		if key := k.keys[kid]; key.Generated {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	return keys
+}
+
+// selectPrimaryLocked picks the newest active key of the configured algorithm.
+// Generated keys are newer than static ones; among static keys the last one listed wins.
+// The legacy jwt_secret key is only used when nothing else qualifies.
+func (k *jwtKeyring) selectPrimaryLocked() {
+	var best *jwtKey
+	for _, kid := range k.order {
+		key := k.keys[kid]
+		if key.Status != JWTKeyStatusActive || key.Algorithm != k.algorithm {
+			continue
+		}
+		if key.Legacy && best != nil {
+			continue
+		}
+		if best == nil || best.Legacy || !best.Generated || !key.CreatedAt.Before(best.CreatedAt) {
+			best = key
+		}
+	}
+	if best == nil {
+		k.primary = ""
+		return
+	}
+	k.primary = best.Kid
+}
+
+func (k *jwtKeyring) reloadIfStale(ctx context.Context) {
+	k.mu.RLock()
+	stale := time.Since(k.lastReload) > jwtKeyringReloadGap
+	k.mu.RUnlock()
+	if stale {
+		_ = k.reload(ctx)
+	}
+}
+
+// reload replaces generated keys with the set stored in Redis
+func (k *jwtKeyring) reload(ctx context.Context) error {
+	values, err := k.redisClient.HGetAll(ctx, jwtKeyringRedisKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keyring: %w", err)
+	}
+
+	loaded := make([]*jwtKey, 0, len(values))
+	for _, raw := range values {
+		var stored storedJWTKey
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			return fmt.Errorf("invalid stored JWT key: %w", err)
+		}
+		key, err := stored.toKey(k.kek)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, key)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].CreatedAt.Before(loaded[j].CreatedAt) })
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, key := range k.generatedLocked() {
+		k.removeLocked(key.Kid)
+	}
+	for _, key := range loaded {
+		k.addLocked(key)
+	}
+	k.selectPrimaryLocked()
+	k.lastReload = time.Now()
+	return nil
+}
+
+func (k *jwtKeyring) persist(ctx context.Context, keys []*jwtKey, pruned []string) error {
+	fields := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		stored, err := newStoredJWTKey(key, k.kek)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		fields[key.Kid] = string(data)
+	}
+	pipe := k.redisClient.TxPipeline()
+	if len(fields) > 0 {
+		pipe.HSet(ctx, jwtKeyringRedisKey, fields)
+	}
+	if len(pruned) > 0 {
+		pipe.HDel(ctx, jwtKeyringRedisKey, pruned...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store JWT keyring: %w", err)
+	}
+	return nil
+}
+
+// newStoredJWTKey encodes a generated key for Redis. With a key encryption
+// key the secret or private key is sealed, bound to its kid.
+func newStoredJWTKey(key *jwtKey, kek cipher.AEAD) (*storedJWTKey, error) {
+	stored := &storedJWTKey{
+		Kid:       key.Kid,
+		Algorithm: key.Algorithm,
+		Status:    key.Status,
+		CreatedAt: key.CreatedAt.Unix(),
+	}
+	if !key.RetiredAt.IsZero() {
+		stored.RetiredAt = key.RetiredAt.Unix()
+	}
+	material := key.secret
+	if key.private != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(key.private)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JWT key %s: %w", key.Kid, err)
+		}
+		material = der
+	}
+	if kek != nil {
+		nonce := make([]byte, kek.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		stored.Sealed = base64.StdEncoding.EncodeToString(kek.Seal(nonce, nonce, material, []byte(key.Kid)))
+		return stored, nil
+	}
+	if key.private == nil {
+		stored.Secret = base64.StdEncoding.EncodeToString(material)
+		return stored, nil
+	}
+	stored.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: material}))
+	return stored, nil
+}
+
+func (s storedJWTKey) toKey(kek cipher.AEAD) (*jwtKey, error) {
+	key := &jwtKey{
+		Kid:       s.Kid,
+		Algorithm: s.Algorithm,
+		Status:    s.Status,
+		CreatedAt: time.Unix(s.CreatedAt, 0),
+		Generated: true,
+	}
+	if s.RetiredAt > 0 {
+		key.RetiredAt = time.Unix(s.RetiredAt, 0)
+	}
+	if s.Sealed != "" {
+		if kek == nil {
+			return nil, fmt.Errorf("stored JWT key %s is encrypted but jwt.key_encryption_key is not set", s.Kid)
+		}
+		sealed, err := base64.StdEncoding.DecodeString(s.Sealed)
+		if err != nil || len(sealed) < kek.NonceSize() {
+			return nil, fmt.Errorf("invalid stored JWT key %s", s.Kid)
+		}
+		material, err := kek.Open(nil, sealed[:kek.NonceSize()], sealed[kek.NonceSize():], []byte(s.Kid))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt stored JWT key %s: %w", s.Kid, err)
+		}
+		if s.Algorithm == JWTAlgHS256 {
+			key.secret = material
+			return key, nil
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(material)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stored JWT key %s: %w", s.Kid, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("invalid stored JWT key %s: not a signing key", s.Kid)
+		}
+		key.private = signer
+		return key, nil
+	}
+
+	// Stored before jwt.key_encryption_key was set, or without it
+	key.plaintext = true
+	if s.Algorithm == JWTAlgHS256 {
+		secret, err := base64.StdEncoding.DecodeString(s.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stored JWT key %s: %w", s.Kid, err)
+		}
+		key.secret = secret
+		return key, nil
+	}
+	signer, err := parsePrivateKeyPEM([]byte(s.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored JWT key %s: %w", s.Kid, err)
+	}
+	key.private = signer
+	return key, nil
+}
+
+// sealStoredKeys encrypts the keys that were stored in Redis before
+// jwt.key_encryption_key was set
+func (k *jwtKeyring) sealStoredKeys(ctx context.Context) error {
+	ok, err := k.redisClient.SetNX(ctx, jwtKeyringLockKey, "1", jwtKeyringLockTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to acquire keyring lock: %w", err)
+	}
+	if !ok {
+		// The rotating instance stores every key again, encrypted
+		return nil
+	}
+	defer k.redisClient.Del(ctx, jwtKeyringLockKey)
+	if err := k.reload(ctx); err != nil {
+		return err
+	}
+
+	k.mu.RLock()
+	var plaintext []*jwtKey
+	for _, key := range k.generatedLocked() {
+		if key.plaintext {
+			plaintext = append(plaintext, key)
+		}
+	}
+	k.mu.RUnlock()
+	if len(plaintext) == 0 {
+		return nil
+	}
+	if err := k.persist(ctx, plaintext, nil); err != nil {
+		return err
+	}
+	return k.reload(ctx)
+}
+
+// newKeyEncryptionKey parses jwt.key_encryption_key (base64, 32 bytes)
+func newKeyEncryptionKey(encoded string) (cipher.AEAD, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("jwt.key_encryption_key must be 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// generateJWTKey creates a fresh key for the given algorithm
+func generateJWTKey(alg string) (*jwtKey, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key := &jwtKey{
+		Kid:       now.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix),
+		Algorithm: alg,
+		Status:    JWTKeyStatusActive,
+		CreatedAt: now,
+		Generated: true,
+	}
+
+	var err error
+	switch alg {
+	case JWTAlgHS256:
+		key.secret = make([]byte, 32)
+		_, err = rand.Read(key.secret)
+	case JWTAlgRS256:
+		key.private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case JWTAlgES256:
+		key.private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case JWTAlgEdDSA:
+		_, key.private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported JWT algorithm: %s", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT key: %w", err)
+	}
+	return key, nil
+}
+
+// loadConfiguredJWTKey builds a key from a static config entry
+func loadConfiguredJWTKey(conf config.JWTKey, defaultAlg string) (*jwtKey, error) {
+	if conf.Kid == "" {
+		return nil, errors.New("jwt key is missing kid")
+	}
+	alg := defaultAlg
+	if conf.Algorithm != "" {
+		var err error
+		if alg, err = NormalizeJWTAlgorithm(conf.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+	status := strings.ToLower(conf.Status)
+	switch status {
+	case "":
+		status = JWTKeyStatusActive
+	case JWTKeyStatusActive, JWTKeyStatusRetired:
+	default:
+		return nil, fmt.Errorf("jwt key %s has invalid status: %s", conf.Kid, conf.Status)
+	}
+
+	key := &jwtKey{Kid: conf.Kid, Algorithm: alg, Status: status}
+	if alg == JWTAlgHS256 {
+		if conf.Secret == "" {
+			return nil, fmt.Errorf("jwt key %s requires secret", conf.Kid)
+		}
+		key.secret = []byte(conf.Secret)
+		return key, nil
+	}
+
+	data, err := os.ReadFile(conf.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key %s: %w", conf.Kid, err)
+	}
+	signer, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwt key %s: %w", conf.Kid, err)
+	}
+	if !keyMatchesAlgorithm(signer, alg) {
+		return nil, fmt.Errorf("jwt key %s does not match algorithm %s", conf.Kid, alg)
+	}
+	key.private = signer
+	return key, nil
+}
+
+// parsePrivateKeyPEM accepts PKCS#8, PKCS#1 (RSA) and SEC 1 (EC) PEM blocks
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+func keyMatchesAlgorithm(signer crypto.Signer, alg string) bool {
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		return alg == JWTAlgRS256
+	case *ecdsa.PrivateKey:
+		return alg == JWTAlgES256 && key.Curve == elliptic.P256()
+	case ed25519.PrivateKey:
+		return alg == JWTAlgEdDSA
+	}
+	return false
+}
+
+func signJWT(key *jwtKey, input []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case JWTAlgHS256:
+		h := hmac.New(sha256.New, key.secret)
+		h.Write(input)
+		return h.Sum(nil), nil
+	case JWTAlgRS256:
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, key.private.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	case JWTAlgES256:
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, key.private.(*ecdsa.PrivateKey), digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed-size r||s encoding instead of ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	case JWTAlgEdDSA:
+		return ed25519.Sign(key.private.(ed25519.PrivateKey), input), nil
+	}
+	return nil, fmt.Errorf("unsupported JWT algorithm: %s", key.Algorithm)
+}
+
+func verifyJWT(key *jwtKey, input, signature []byte) error {
+	if key.Algorithm == JWTAlgHS256 {
+		expected, _ := signJWT(key, input)
+		if !hmac.Equal(expected, signature) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return verifyJWTSignature(key.Algorithm, key.private.Public(), input, signature)
+}
+
+// verifyJWTSignature checks an asymmetric JWS signature with a public key
+func verifyJWTSignature(alg string, public crypto.PublicKey, input, signature []byte) error {
+	invalid := errors.New("invalid token signature")
+	switch alg {
+	case JWTAlgRS256:
+		pub, ok := public.(*rsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		digest := sha256.Sum256(input)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return invalid
+		}
+		return nil
+	case JWTAlgES256:
+		pub, ok := public.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return invalid
+		}
+		digest := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return invalid
+		}
+		return nil
+	case JWTAlgEdDSA:
+		pub, ok := public.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, input, signature) {
+			return invalid
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported JWT algorithm: %s", alg)
+}
+
+// parsePublicJWK returns the public key of a JWK published by another issuer
+func parsePublicJWK(jwk model.JWK) (crypto.PublicKey, error) {
+	enc := base64.RawURLEncoding
+	switch jwk.Kty {
+	case "RSA":
+		n, err := enc.DecodeString(jwk.N)
+		if err != nil {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := enc.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, errX := enc.DecodeString(jwk.X)
+		y, errY := enc.DecodeString(jwk.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid EC point")
+		}
+		// Uncompressed point: 0x04 || X || Y
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, errors.New("invalid EC point")
+		}
+		return pub, nil
+	case "OKP":
+		x, err := enc.DecodeString(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+}
+
+func publicJWK(key *jwtKey) (model.JWK, error) {
+	enc := base64.RawURLEncoding
+	jwk := model.JWK{Kid: key.Kid, Use: "sig", Alg: key.Algorithm}
+	switch pub := key.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(pub.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return jwk, err
+		}
+		// Uncompressed point: 0x04 || X || Y
+		point := ecdhKey.Bytes()
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = enc.EncodeToString(point[1:33])
+		jwk.Y = enc.EncodeToString(point[33:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(pub)
+	default:
+		return jwk, errors.New("unsupported public key type")
+	}
+	return jwk, nil
+}
+
+// NewJWTKeyring builds the keyring from config. With HS256 the jwt_secret is
+// registered as a key so that tokens issued before kid was introduced keep
+// verifying. With an asymmetric algorithm it is only registered, as a retired
+// verification key, under accept_legacy_hs256 until legacy_hs256_until.
+// When the configured algorithm has no usable key, one is generated (and
+// shared through Redis when available).
+func NewJWTKeyring(conf config.JWT, secret string, redisClient *redis.Client) (JWTKeyring, error) {
+	alg, err := NormalizeJWTAlgorithm(conf.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	retiredTTL := defaultRetiredKeyTTL
+	if conf.RetiredKeyTTLHours > 0 {
+		retiredTTL = time.Duration(conf.RetiredKeyTTLHours) * time.Hour
+	}
+	kek, err := newKeyEncryptionKey(conf.KeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &jwtKeyring{
+		conf:        conf,
+		algorithm:   alg,
+		retiredTTL:  retiredTTL,
+		keys:        map[string]*jwtKey{},
+		redisClient: redisClient,
+		kek:         kek,
+	}
+
+	legacy, err := newLegacyJWTKey(conf, alg, secret)
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		k.legacyKid = legacy.Kid
+		k.addLocked(legacy)
+	}
+
+	for _, keyConf := range conf.Keys {
+		key, err := loadConfiguredJWTKey(keyConf, alg)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := k.keys[key.Kid]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id: %s", key.Kid)
+		}
+		k.addLocked(key)
+	}
+
+	ctx := context.Background()
+	if redisClient != nil {
+		if err := k.reload(ctx); err != nil {
+			// Keep working with static keys; rotation retries against Redis later
+			logger.Error(code.RJKR3, "", err.Error())
+		} else if kek != nil {
+			if err := k.sealStoredKeys(ctx); err != nil {
+				logger.Error(code.RJKR3, "", "Failed to encrypt stored JWT keys: "+err.Error())
+			}
+		}
+	}
+	k.selectPrimaryLocked()
+
+	if k.primary == "" {
+		if redisClient != nil {
+			if err := k.Rotate(ctx); err != nil {
+				logger.Error(code.RJKR3, "", err.Error())
+			} else {
+				// Another instance may be generating the first key, wait for it
+				for i := 0; i < 5 && k.primary == ""; i++ {
+					time.Sleep(time.Second)
+					_ = k.reload(ctx)
+				}
+			}
+		}
+		if k.primary == "" {
+			// No Redis, or another instance holds the rotation lock
+			key, err := generateJWTKey(alg)
+			if err != nil {
+				return nil, err
+			}
+			k.addLocked(key)
+			k.selectPrimaryLocked()
+		}
+	}
+
+	if redisClient != nil && kek == nil && k.keys[k.primary].Generated {
+		logger.Warn(code.RJKR3, "", "jwt.key_encryption_key is not set, generated signing keys are stored in Redis unencrypted")
+	}
+	logger.Info(code.RJKR1, "", fmt.Sprintf("JWT keyring loaded: alg=%s primary=%s keys=%d", alg, k.primary, len(k.keys)))
+	return k, nil
+}
+
+// newLegacyJWTKey derives the HS256 key from jwt_secret. It returns nil when
+// the secret must not verify tokens: an asymmetric algorithm without the
+// accept_legacy_hs256 opt-in, or an opt-in whose deadline has passed.
+func newLegacyJWTKey(conf config.JWT, alg, secret string) (*jwtKey, error) {
+	status := JWTKeyStatusActive
+	var expiresAt time.Time
+	if alg != JWTAlgHS256 {
+		if !conf.AcceptLegacyHS256 {
+			return nil, nil
+		}
+		if conf.LegacyHS256Until == "" {
+			return nil, errors.New("accept_legacy_hs256 requires legacy_hs256_until")
+		}
+		until, err := time.Parse(time.RFC3339, conf.LegacyHS256Until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid legacy_hs256_until: %w", err)
+		}
+		if time.Now().After(until) {
+			logger.Info(code.RJKR1, "", "legacy_hs256_until has passed, jwt_secret tokens are no longer accepted")
+			return nil, nil
+		}
+		status = JWTKeyStatusRetired
+		expiresAt = until
+	}
+	if secret == "" || secret == DefaultJWTSecret {
+		return nil, errors.New("jwt_secret (or JWT_SECRET) must be set to a non-default value")
+	}
+
+	sum := sha256.Sum256([]byte(secret))
+	return &jwtKey{
+		Kid:       "hs-" + hex.EncodeToString(sum[:6]),
+		Algorithm: JWTAlgHS256,
+		Status:    status,
+		Legacy:    true,
+		ExpiresAt: expiresAt,
+		secret:    []byte(secret),
+	}, nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/logger"
+)
+
+// LDAP authenticator defaults
+const (
+	DefaultLDAPUserFilter     = "(&(objectClass=person)(mail=%s))"
+	DefaultLDAPEmailAttribute = "mail"
+	DefaultLDAPNameAttribute  = "cn"
+	DefaultLDAPGroupAttribute = "memberOf"
+	DefaultLDAPTimeout        = 5 * time.Second
+)
+
+// ldapAuthenticator searches the user by email with the service account, binds
+// as the user with the submitted password and provisions a users row on the
+// first login. The role is mapped from the user's group DNs.
+type ldapAuthenticator struct {
+	BaseConfig       config.BaseConfig
+	UserRepository   UserRepository
+	CommonRepository CommonRepository
+}
+
+// ldapEntry is the directory user matching a login
+type ldapEntry struct {
+	DN     string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+func (rcvr ldapAuthenticator) Name() string {
+	return AuthenticatorLDAP
+}
+
+func (rcvr ldapAuthenticator) Authenticate(c *gin.Context, email, password string) (*model.Users, error) {
+	ldapConf := rcvr.BaseConfig.YamlConfig.Application.Server.LDAP
+	if ldapConf.URL == "" {
+		return nil, errors.New("ldap.url is not configured")
+	}
+	// An empty password would be an unauthenticated bind that always succeeds
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := rcvr.dial(ldapConf)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := rcvr.search(conn, ldapConf, email)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := rcvr.existingUser(c, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind failed: %w", err)
+	}
+
+	return rcvr.provision(c, entry, existing, ldapGroupRole(ldapConf.GroupRoles, entry.Groups))
+}
+
+// dial connects to the directory and binds as the service account
+func (rcvr ldapAuthenticator) dial(ldapConf config.LDAP) (*ldap.Conn, error) {
+	timeout := DefaultLDAPTimeout
+	if ldapConf.TimeoutSeconds > 0 {
+		timeout = time.Duration(ldapConf.TimeoutSeconds) * time.Second
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: ldapConf.InsecureSkipVerify}
+	conn, err := ldap.DialURL(ldapConf.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connection failed: %w", err)
+	}
+	conn.SetTimeout(timeout)
+	if ldapConf.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+	if ldapConf.BindDN != "" {
+		err = conn.Bind(ldapConf.BindDN, ldapConf.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ldap service bind failed: %w", err)
+	}
+	return conn, nil
+}
+
+// search finds exactly one directory user with the email. No match skips the
+// authenticator; several matches are refused.
+func (rcvr ldapAuthenticator) search(conn *ldap.Conn, ldapConf config.LDAP, email string) (*ldapEntry, error) {
+	filter := ldapConf.UserFilter
+	if filter == "" {
+		filter = DefaultLDAPUserFilter
+	}
+	emailAttribute := ldapDefault(ldapConf.EmailAttribute, DefaultLDAPEmailAttribute)
+	nameAttribute := ldapDefault(ldapConf.NameAttribute, DefaultLDAPNameAttribute)
+	groupAttribute := ldapDefault(ldapConf.GroupAttribute, DefaultLDAPGroupAttribute)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		ldapConf.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		strings.ReplaceAll(filter, "%s", ldap.EscapeFilter(email)),
+		[]string{emailAttribute, nameAttribute, groupAttribute},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrAuthenticatorSkipped
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("ldap search returned several users for %s", email)
+	}
+	entry := result.Entries[0]
+	found := &ldapEntry{
+		DN:     entry.DN,
+		Email:  entry.GetAttributeValue(emailAttribute),
+		Name:   entry.GetAttributeValue(nameAttribute),
+		Groups: entry.GetAttributeValues(groupAttribute),
+	}
+	if found.Email == "" {
+		found.Email = email
+	}
+	if found.Name == "" {
+		found.Name = found.Email
+	}
+	return found, nil
+}
+
+// existingUser returns the users row with the email of the entry, or nil when
+// there is none yet. Rows of other sources are not taken over: the authenticator
+// is skipped, so local users keep logging in with their own password. With
+// ldap.adopt_local_users, local rows are adopted instead; federated rows never are.
+func (rcvr ldapAuthenticator) existingUser(c *gin.Context, entry *ldapEntry) (*model.Users, error) {
+	user, err := rcvr.UserRepository.GetUserByEmail(c, entry.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.AuthSource == AuthenticatorLDAP {
+		return &user, nil
+	}
+	local := user.AuthSource == "" || user.AuthSource == AuthenticatorLocal
+	if !local || !rcvr.BaseConfig.YamlConfig.Application.Server.LDAP.AdoptLocalUsers {
+		logger.Warn(code.RLDP3, c.GetString("requestID"), user.UUID+" "+entry.DN)
+		return nil, ErrAuthenticatorSkipped
+	}
+	return &user, nil
+}
+
+// provision returns the users row of a directory user, creating it on the first
+// login and keeping the name and mapped role up to date. An adopted local row
+// becomes a directory user.
+func (rcvr ldapAuthenticator) provision(c *gin.Context, entry *ldapEntry, existing *model.Users, role string) (*model.Users, error) {
+	if existing != nil {
+		user := *existing
+		adopt := user.AuthSource != AuthenticatorLDAP
+		if !adopt && user.Name == entry.Name && user.DirectoryRole == role {
+			return &user, nil
+		}
+		user.AuthSource = AuthenticatorLDAP
+		user.Name = entry.Name
+		user.DirectoryRole = role
+		if _, err := rcvr.UserRepository.UpdateUser(c, user); err != nil {
+			return nil, fmt.Errorf("failed to update directory user: %w", err)
+		}
+		if adopt {
+			logger.Info(code.RLDP2, c.GetString("requestID"), user.UUID+" "+entry.DN)
+		}
+		return &user, nil
+	}
+
+	// Directory users never log in with a local password
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	hash, err := rcvr.CommonRepository.HashPassword(base64.RawURLEncoding.EncodeToString(secret))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	created, err := rcvr.UserRepository.CreateUser(c, model.Users{
+		UUID:              uuid.New().String(),
+		Email:             entry.Email,
+		Password:          hash,
+		Name:              entry.Name,
+		EmailVerifiedAt:   &now,
+		PasswordChangedAt: &now,
+		AuthSource:        AuthenticatorLDAP,
+		DirectoryRole:     role,
+		CreatedAt:         &now,
+		UpdatedAt:         &now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision directory user: %w", err)
+	}
+	logger.Info(code.RLDP1, c.GetString("requestID"), created.UUID+" "+entry.DN)
+	return &created, nil
+}
+
+// ldapGroupRole returns the role of the first group_roles entry the user is a member of
+func ldapGroupRole(groupRoles []config.LDAPGroupRole, groups []string) string {
+	for _, groupRole := range groupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(strings.TrimSpace(group), strings.TrimSpace(groupRole.Group)) {
+				return groupRole.Role
+			}
+		}
+	}
+	return ""
+}
+
+func ldapDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func NewLDAPAuthenticator(conf config.BaseConfig, userRepository UserRepository, commonRepository CommonRepository) Authenticator {
+	return ldapAuthenticator{
+		BaseConfig:       conf,
+		UserRepository:   userRepository,
+		CommonRepository: commonRepository,
+	}
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+// Login lockout defaults
+const (
+	DefaultLoginLockoutMaxAttempts   = 5
+	DefaultLoginLockoutIPMaxAttempts = 20
+	DefaultLoginLockoutWindow        = 15 * time.Minute
+	DefaultLoginLockoutBackoff       = time.Second
+	DefaultLoginLockoutDuration      = 15 * time.Minute
+	DefaultLoginLockoutMaxDuration   = 24 * time.Hour
+)
+
+// LoginLockoutRepository counts failed logins per account and per client IP
+// and blocks further attempts with a progressive backoff and lockouts.
+// Without Redis (or when disabled) nothing is counted or blocked.
+type LoginLockoutRepository interface {
+	Check(ctx context.Context, email, ip string) (*model.LoginLockouts, error)
+	RegisterFailure(ctx context.Context, email, ip string) (*model.LoginLockouts, error)
+	RegisterSuccess(ctx context.Context, email string) error
+	ListLockouts(ctx context.Context, scope string) ([]model.LoginLockouts, error)
+	ClearLockout(ctx context.Context, scope, key string) (bool, error)
+}
+
+type loginLockoutRepository struct {
+	BaseConfig  config.BaseConfig
+	RedisClient *redis.Client
+}
+
+func loginLockoutID(scope, key string) string {
+	return scope + ":" + hashSecret(strings.ToLower(strings.TrimSpace(key)))
+}
+
+func loginFailuresKey(scope, key string) string {
+	return "login_lockout:failures:" + loginLockoutID(scope, key)
+}
+
+func loginLockKey(scope, key string) string {
+	return "login_lockout:lock:" + loginLockoutID(scope, key)
+}
+
+func loginLockLevelKey(scope, key string) string {
+	return "login_lockout:level:" + loginLockoutID(scope, key)
+}
+
+// ValidLoginLockoutScope reports whether scope is account or ip
+func ValidLoginLockoutScope(scope string) bool {
+	return scope == model.LoginLockoutScopeAccount || scope == model.LoginLockoutScopeIP
+}
+
+func (rcvr loginLockoutRepository) enabled() bool {
+	return rcvr.RedisClient != nil && !rcvr.BaseConfig.YamlConfig.Application.Server.LoginLockout.Disabled
+}
+
+func secondsOr(value int, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return time.Duration(value) * time.Second
+	}
+	return fallback
+}
+
+func (rcvr loginLockoutRepository) maxAttempts(scope string) int {
+	conf := rcvr.BaseConfig.YamlConfig.Application.Server.LoginLockout
+	if scope == model.LoginLockoutScopeIP {
+		if conf.IPMaxAttempts > 0 {
+			return conf.IPMaxAttempts
+		}
+		return DefaultLoginLockoutIPMaxAttempts
+	}
+	if conf.MaxAttempts > 0 {
+		return conf.MaxAttempts
+	}
+	return DefaultLoginLockoutMaxAttempts
+}
+
+// doubledDuration returns base * 2^n, capped at limit
+func doubledDuration(base time.Duration, n int, limit time.Duration) time.Duration {
+	d := base
+	for i := 0; i < n && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		return limit
+	}
+	return d
+}
+
+// Check returns the active block with the longest remaining time for the account or IP, or nil
+func (rcvr loginLockoutRepository) Check(ctx context.Context, email, ip string) (*model.LoginLockouts, error) {
+	if !rcvr.enabled() {
+		return nil, nil
+	}
+	var found *model.LoginLockouts
+	for scope, key := range map[string]string{model.LoginLockoutScopeAccount: email, model.LoginLockoutScopeIP: ip} {
+		if key == "" {
+			continue
+		}
+		lockout, err := rcvr.getLock(ctx, loginLockKey(scope, key))
+		if err != nil {
+			return nil, err
+		}
+		if lockout != nil && (found == nil || lockout.LockedUntil > found.LockedUntil) {
+			found = lockout
+		}
+	}
+	return found, nil
+}
+
+// RegisterFailure counts a failed login and returns the block it triggers, if any.
+// Accounts get a growing backoff from the second failure and a lockout at max_attempts;
+// client IPs are only locked out at ip_max_attempts.
+func (rcvr loginLockoutRepository) RegisterFailure(ctx context.Context, email, ip string) (*model.LoginLockouts, error) {
+	if !rcvr.enabled() {
+		return nil, nil
+	}
+	var found *model.LoginLockouts
+	for scope, key := range map[string]string{model.LoginLockoutScopeAccount: email, model.LoginLockoutScopeIP: ip} {
+		if key == "" {
+			continue
+		}
+		lockout, err := rcvr.registerFailure(ctx, scope, key)
+		if err != nil {
+			return nil, err
+		}
+		if lockout != nil && (found == nil || lockout.LockedUntil > found.LockedUntil) {
+			found = lockout
+		}
+	}
+	return found, nil
+}
+
+func (rcvr loginLockoutRepository) registerFailure(ctx context.Context, scope, key string) (*model.LoginLockouts, error) {
+	conf := rcvr.BaseConfig.YamlConfig.Application.Server.LoginLockout
+	failuresKey := loginFailuresKey(scope, key)
+	failures, err := rcvr.RedisClient.Incr(ctx, failuresKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count login failure: %w", err)
+	}
+	if failures == 1 {
+		if err := rcvr.RedisClient.Expire(ctx, failuresKey, secondsOr(conf.WindowSeconds, DefaultLoginLockoutWindow)).Err(); err != nil {
+			return nil, fmt.Errorf("failed to count login failure: %w", err)
+		}
+	}
+
+	lockoutDuration := secondsOr(conf.LockoutSeconds, DefaultLoginLockoutDuration)
+	maxDuration := secondsOr(conf.MaxLockoutSeconds, DefaultLoginLockoutMaxDuration)
+	lockout := &model.LoginLockouts{Scope: scope, Key: strings.ToLower(strings.TrimSpace(key)), Failures: int(failures)}
+	var duration time.Duration
+	switch {
+	case int(failures) >= rcvr.maxAttempts(scope):
+		levelKey := loginLockLevelKey(scope, key)
+		level, err := rcvr.RedisClient.Incr(ctx, levelKey).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to record lockout: %w", err)
+		}
+		duration = doubledDuration(lockoutDuration, int(level)-1, maxDuration)
+		// Repeated lockouts keep doubling until the account stays quiet for max_lockout_seconds
+		if err := rcvr.RedisClient.Expire(ctx, levelKey, duration+maxDuration).Err(); err != nil {
+			return nil, fmt.Errorf("failed to record lockout: %w", err)
+		}
+		if err := rcvr.RedisClient.Del(ctx, failuresKey).Err(); err != nil {
+			return nil, fmt.Errorf("failed to record lockout: %w", err)
+		}
+		lockout.Reason = model.LoginLockoutReasonLockout
+		lockout.Level = int(level)
+	case scope == model.LoginLockoutScopeAccount && failures >= 2 && conf.BackoffSeconds >= 0:
+		duration = doubledDuration(secondsOr(conf.BackoffSeconds, DefaultLoginLockoutBackoff), int(failures)-2, lockoutDuration)
+		lockout.Reason = model.LoginLockoutReasonBackoff
+	default:
+		return nil, nil
+	}
+
+	lockout.LockedUntil = time.Now().Add(duration).Unix()
+	lockKey := loginLockKey(scope, key)
+	pipe := rcvr.RedisClient.TxPipeline()
+	pipe.HSet(ctx, lockKey,
+		"scope", lockout.Scope,
+		"key", lockout.Key,
+		"reason", lockout.Reason,
+		"failures", lockout.Failures,
+		"level", lockout.Level,
+		"locked_until", lockout.LockedUntil,
+	)
+	pipe.PExpire(ctx, lockKey, duration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to store lockout: %w", err)
+	}
+	return lockout, nil
+}
+
+// RegisterSuccess resets the failure counters of an account after a successful login.
+// Client IP counters are kept so one valid account cannot reset them.
+func (rcvr loginLockoutRepository) RegisterSuccess(ctx context.Context, email string) error {
+	if !rcvr.enabled() || email == "" {
+		return nil
+	}
+	scope := model.LoginLockoutScopeAccount
+	return rcvr.RedisClient.Del(ctx, loginFailuresKey(scope, email), loginLockLevelKey(scope, email)).Err()
+}
+
+// ListLockouts returns the active blocks, optionally of one scope, ending last first
+func (rcvr loginLockoutRepository) ListLockouts(ctx context.Context, scope string) ([]model.LoginLockouts, error) {
+	lockouts := []model.LoginLockouts{}
+	if rcvr.RedisClient == nil {
+		return lockouts, nil
+	}
+	pattern := "login_lockout:lock:*"
+	if scope != "" {
+		pattern = "login_lockout:lock:" + scope + ":*"
+	}
+	iter := rcvr.RedisClient.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		lockout, err := rcvr.getLock(ctx, iter.Val())
+		if err != nil {
+			return nil, err
+		}
+		if lockout != nil {
+			lockouts = append(lockouts, *lockout)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %w", err)
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].LockedUntil > lockouts[j].LockedUntil })
+	return lockouts, nil
+}
+
+// ClearLockout lifts a block and resets its counters. It reports whether a block was active.
+func (rcvr loginLockoutRepository) ClearLockout(ctx context.Context, scope, key string) (bool, error) {
+	if rcvr.RedisClient == nil {
+		return false, nil
+	}
+	lockKey := loginLockKey(scope, key)
+	existed, err := rcvr.RedisClient.Exists(ctx, lockKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear lockout: %w", err)
+	}
+	if err := rcvr.RedisClient.Del(ctx, lockKey, loginFailuresKey(scope, key), loginLockLevelKey(scope, key)).Err(); err != nil {
+		return false, fmt.Errorf("failed to clear lockout: %w", err)
+	}
+	return existed > 0, nil
+}
+
+func (rcvr loginLockoutRepository) getLock(ctx context.Context, lockKey string) (*model.LoginLockouts, error) {
+	fields, err := rcvr.RedisClient.HGetAll(ctx, lockKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockout: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	failures, _ := strconv.Atoi(fields["failures"])
+	level, _ := strconv.Atoi(fields["level"])
+	lockedUntil, _ := strconv.ParseInt(fields["locked_until"], 10, 64)
+	return &model.LoginLockouts{
+		Scope:       fields["scope"],
+		Key:         fields["key"],
+		Reason:      fields["reason"],
+		Failures:    failures,
+		Level:       level,
+		LockedUntil: lockedUntil,
+	}, nil
+}
+
+func NewLoginLockoutRepository(conf config.BaseConfig, redisClient *redis.Client) LoginLockoutRepository {
+	return loginLockoutRepository{BaseConfig: conf, RedisClient: redisClient}
+}
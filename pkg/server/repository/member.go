@@ -69,6 +69,8 @@ type MemberQueryFilter struct {
 	RoleLike   *string
 	Limit      int
 	Offset     int
+	// ExcludeDeleted skips soft-deleted rows
+	ExcludeDeleted bool
 }
 
 func (f *MemberQueryFilter) normalize() {
@@ -84,6 +86,9 @@ func (f *MemberQueryFilter) normalize() {
 func (rcvr memberRepository) ListMembers(c *gin.Context, filter MemberQueryFilter) ([]model.Members, error) {
 	filter.normalize()
 	q := rcvr.BaseConfig.DBConnection.Model(&model.Members{})
+	if filter.ExcludeDeleted {
+		q = q.Where("deleted_at IS NULL")
+	}
 	if filter.ID != nil {
 		q = q.Where("id = ?", *filter.ID)
 	}
@@ -116,6 +121,9 @@ func (rcvr memberRepository) ListMembers(c *gin.Context, filter MemberQueryFilte
 // CountMembers get count
 func (rcvr memberRepository) CountMembers(c *gin.Context, filter MemberQueryFilter) (int64, error) {
 	q := rcvr.BaseConfig.DBConnection.Model(&model.Members{})
+	if filter.ExcludeDeleted {
+		q = q.Where("deleted_at IS NULL")
+	}
 	if filter.ID != nil {
 		q = q.Where("id = ?", *filter.ID)
 	}
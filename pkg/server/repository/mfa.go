@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+// MFA defaults
+const (
+	DefaultMFAIssuer       = "Locky"
+	DefaultMFAChallengeTTL = 5 * time.Minute
+	MFARecoveryCodeCount   = 10
+	// MFAChallengeMaxAttempts is how many wrong codes a challenge survives
+	MFAChallengeMaxAttempts = 5
+)
+
+var (
+	ErrMFANotEnrolled         = errors.New("multi-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled      = errors.New("multi-factor authentication is already enabled")
+	ErrMFAInvalidCode         = errors.New("invalid authentication code")
+	ErrMFAChallengeInvalid    = errors.New("mfa challenge is invalid or expired")
+	ErrMFAStorageUnavailable  = errors.New("multi-factor authentication requires redis")
+	errMFAChallengeIncomplete = errors.New("mfa challenge has no user")
+)
+
+// MFARepository stores TOTP enrollments and recovery codes, and the short-lived
+// challenge that links the password step of a login to the code step.
+type MFARepository interface {
+	GetMFA(c *gin.Context, userUUID string) (*model.UserMFAs, error)
+	IsEnabled(c *gin.Context, userUUID string) (bool, error)
+	BeginEnrollment(c *gin.Context, userUUID string) (string, error)
+	ConfirmEnrollment(c *gin.Context, userUUID, code string) ([]string, error)
+	Verify(c *gin.Context, userUUID, code string) error
+	Reset(c *gin.Context, userUUID string) error
+	CreateChallenge(ctx context.Context, userUUID, email string) (string, error)
+	ChallengeEmail(ctx context.Context, challenge string) (string, error)
+	CompleteChallenge(c *gin.Context, challenge, code string) (string, error)
+	Issuer() string
+}
+
+type mfaRepository struct {
+	BaseConfig  config.BaseConfig
+	RedisClient *redis.Client
+}
+
+// helper: challenge keys are looked up by hash, never by the raw value
+func mfaChallengeKey(challenge string) string {
+	return "mfa:challenge:" + hashSecret(challenge)
+}
+
+// normalizeRecoveryCode strips separators so "abcd-efgh" and "ABCDEFGH" match
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// Issuer returns the label shown in authenticator apps
+func (rcvr mfaRepository) Issuer() string {
+	if issuer := rcvr.BaseConfig.YamlConfig.Application.Server.MFA.Issuer; issuer != "" {
+		return issuer
+	}
+	return DefaultMFAIssuer
+}
+
+// GetMFA returns the enrollment of a user
+func (rcvr mfaRepository) GetMFA(c *gin.Context, userUUID string) (*model.UserMFAs, error) {
+	var mfa model.UserMFAs
+	if err := rcvr.BaseConfig.DBConnection.Where("user_uuid = ?", userUUID).First(&mfa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+// IsEnabled reports whether login requires a second factor for the user
+func (rcvr mfaRepository) IsEnabled(c *gin.Context, userUUID string) (bool, error) {
+	mfa, err := rcvr.GetMFA(c, userUUID)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return mfa.Enabled, nil
+}
+
+// BeginEnrollment generates a new secret. It replaces a pending (unconfirmed)
+// enrollment but never an enabled one; that needs Reset first.
+func (rcvr mfaRepository) BeginEnrollment(c *gin.Context, userUUID string) (string, error) {
+	existing, err := rcvr.GetMFA(c, userUUID)
+	if err != nil && !errors.Is(err, ErrMFANotEnrolled) {
+		return "", err
+	}
+	if existing != nil && existing.Enabled {
+		return "", ErrMFAAlreadyEnabled
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		err = rcvr.BaseConfig.DBConnection.Model(&model.UserMFAs{}).Where("id = ?", existing.ID).
+			Updates(map[string]interface{}{"secret": secret, "last_used_step": 0}).Error
+	} else {
+		err = rcvr.BaseConfig.DBConnection.Create(&model.UserMFAs{UserUUID: userUUID, Secret: secret}).Error
+	}
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmEnrollment enables MFA once the user proves the authenticator works,
+// and returns freshly generated recovery codes (shown to the user once).
+func (rcvr mfaRepository) ConfirmEnrollment(c *gin.Context, userUUID, code string) ([]string, error) {
+	mfa, err := rcvr.GetMFA(c, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := MatchTOTP(mfa.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrMFAInvalidCode
+	}
+
+	codes := make([]string, 0, MFARecoveryCodeCount)
+	rows := make([]model.MFARecoveryCodes, 0, MFARecoveryCodeCount)
+	for i := 0; i < MFARecoveryCodeCount; i++ {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := totpEncoding.EncodeToString(buf) // 16 characters, 80 bits
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		rows = append(rows, model.MFARecoveryCodes{UserUUID: userUUID, CodeHash: hashSecret(raw)})
+	}
+
+	now := time.Now()
+	err = rcvr.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&model.MFARecoveryCodes{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.UserMFAs{}).Where("id = ?", mfa.ID).
+			Updates(map[string]interface{}{"enabled": true, "confirmed_at": now, "last_used_step": step}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts a current TOTP code or an unused recovery code.
+// Each TOTP step and each recovery code is accepted only once.
+func (rcvr mfaRepository) Verify(c *gin.Context, userUUID, code string) error {
+	mfa, err := rcvr.GetMFA(c, userUUID)
+	if err != nil {
+		return err
+	}
+	if !mfa.Enabled {
+		return ErrMFANotEnrolled
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := MatchTOTP(mfa.Secret, code, time.Now()); ok {
+		res := rcvr.BaseConfig.DBConnection.Model(&model.UserMFAs{}).
+			Where("id = ? AND last_used_step < ?", mfa.ID, step).
+			Update("last_used_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrMFAInvalidCode
+		}
+		return nil
+	}
+
+	res := rcvr.BaseConfig.DBConnection.Model(&model.MFARecoveryCodes{}).
+		Where("user_uuid = ? AND code_hash = ? AND used_at IS NULL", userUUID, hashSecret(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+// Reset removes the enrollment and recovery codes of a user
+func (rcvr mfaRepository) Reset(c *gin.Context, userUUID string) error {
+	return rcvr.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&model.MFARecoveryCodes{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_uuid = ?", userUUID).Delete(&model.UserMFAs{}).Error
+	})
+}
+
+// CreateChallenge issues the token returned after a correct password. email is
+// the account the login lockout counts wrong codes for.
+func (rcvr mfaRepository) CreateChallenge(ctx context.Context, userUUID, email string) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrMFAStorageUnavailable
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(buf)
+	ttl := DefaultMFAChallengeTTL
+	if sec := rcvr.BaseConfig.YamlConfig.Application.Server.MFA.ChallengeTTLSeconds; sec > 0 {
+		ttl = time.Duration(sec) * time.Second
+	}
+	key := mfaChallengeKey(challenge)
+	pipe := rcvr.RedisClient.TxPipeline()
+	pipe.HSet(ctx, key, "user_uuid", userUUID, "email", email, "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// ChallengeEmail returns the account a pending challenge was issued for
+func (rcvr mfaRepository) ChallengeEmail(ctx context.Context, challenge string) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrMFAStorageUnavailable
+	}
+	email, err := rcvr.RedisClient.HGet(ctx, mfaChallengeKey(challenge), "email").Result()
+	if err == redis.Nil {
+		return "", ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+	return email, nil
+}
+
+// CompleteChallenge checks the code for a challenge and returns the user UUID.
+// The attempt is counted before the code is checked, so concurrent requests
+// cannot try more than MFAChallengeMaxAttempts codes. The challenge is
+// single-use and is dropped after too many wrong codes.
+func (rcvr mfaRepository) CompleteChallenge(c *gin.Context, challenge, code string) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrMFAStorageUnavailable
+	}
+	ctx := c.Request.Context()
+	key := mfaChallengeKey(challenge)
+	pipe := rcvr.RedisClient.TxPipeline()
+	attemptsCmd := pipe.HIncrBy(ctx, key, "attempts", 1)
+	userUUIDCmd := pipe.HGet(ctx, key, "user_uuid")
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return "", fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+	userUUID, err := userUUIDCmd.Result()
+	attempts := attemptsCmd.Val()
+	if err == redis.Nil || attempts > MFAChallengeMaxAttempts {
+		// Also drops the hash HINCRBY created for an unknown challenge
+		_ = rcvr.RedisClient.Del(ctx, key).Err()
+		return "", ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+	if userUUID == "" {
+		return "", errMFAChallengeIncomplete
+	}
+
+	if err := rcvr.Verify(c, userUUID, code); err != nil {
+		if errors.Is(err, ErrMFAInvalidCode) && attempts >= MFAChallengeMaxAttempts {
+			_ = rcvr.RedisClient.Del(ctx, key).Err()
+		}
+		return "", err
+	}
+	// Only one caller may turn the challenge into tokens
+	if deleted, err := rcvr.RedisClient.Del(ctx, key).Result(); err != nil || deleted == 0 {
+		return "", ErrMFAChallengeInvalid
+	}
+	return userUUID, nil
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(conf config.BaseConfig, redisClient *redis.Client) MFARepository {
+	return &mfaRepository{BaseConfig: conf, RedisClient: redisClient}
+}
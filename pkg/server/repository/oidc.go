@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// OIDC defaults
+const (
+	DefaultOIDCAuthCodeTTL = 60 * time.Second
+	DefaultOIDCIDTokenTTL  = time.Hour
+	OIDCLoginTicketTTL     = 10 * time.Minute
+)
+
+// DefaultOIDCScopes are allowed for clients that do not configure scopes
+var DefaultOIDCScopes = []string{"openid", "profile", "email"}
+
+var (
+	ErrOIDCClientNotFound     = errors.New("oidc client not found")
+	ErrOIDCInvalidClient      = errors.New("invalid client credentials")
+	ErrOIDCInvalidGrant       = errors.New("invalid or expired authorization grant")
+	ErrOIDCStorageUnavailable = errors.New("oidc provider requires redis")
+	ErrOIDCSymmetricKeyring   = errors.New("oidc provider requires an asymmetric jwt.algorithm (RS256, ES256 or EdDSA)")
+)
+
+// OIDCRepository holds the client registry and the short-lived state of
+// the authorization code flow (login tickets and authorization codes).
+type OIDCRepository interface {
+	GetClient(clientID string) (*config.OIDCClient, error)
+	AuthenticateClient(clientID, clientSecret string) (*config.OIDCClient, error)
+	SaveLoginTicket(ctx context.Context, auth model.OIDCAuthorizations) (string, error)
+	ConsumeLoginTicket(ctx context.Context, ticket string) (*model.OIDCAuthorizations, error)
+	SaveAuthorizationCode(ctx context.Context, auth model.OIDCAuthorizations) (string, error)
+	ConsumeAuthorizationCode(ctx context.Context, code string) (*model.OIDCAuthorizations, error)
+	GenerateIDToken(issuer string, auth model.OIDCAuthorizations) (string, error)
+}
+
+type oidcRepository struct {
+	Conf             config.OIDC
+	CommonRepository CommonRepository
+	RedisClient      *redis.Client
+}
+
+// helper: oidc key builders (values are looked up by hash, never by the raw secret)
+func oidcLoginTicketKey(ticket string) string {
+	return "oidc:ticket:" + hashSecret(ticket)
+}
+
+func oidcAuthCodeKey(code string) string {
+	return "oidc:code:" + hashSecret(code)
+}
+
+// GetClient returns the registered client
+func (rcvr oidcRepository) GetClient(clientID string) (*config.OIDCClient, error) {
+	for i := range rcvr.Conf.Clients {
+		if clientID != "" && rcvr.Conf.Clients[i].ClientID == clientID {
+			client := rcvr.Conf.Clients[i]
+			if len(client.Scopes) == 0 {
+				client.Scopes = DefaultOIDCScopes
+			}
+			return &client, nil
+		}
+	}
+	return nil, ErrOIDCClientNotFound
+}
+
+// AuthenticateClient checks the client secret. Public clients authenticate with client_id only.
+func (rcvr oidcRepository) AuthenticateClient(clientID, clientSecret string) (*config.OIDCClient, error) {
+	client, err := rcvr.GetClient(clientID)
+	if err != nil {
+		return nil, ErrOIDCInvalidClient
+	}
+	if client.Public {
+		if clientSecret != "" {
+			return nil, ErrOIDCInvalidClient
+		}
+		return client, nil
+	}
+	if clientSecret == "" || client.ClientSecret == "" {
+		return nil, ErrOIDCInvalidClient
+	}
+	if strings.HasPrefix(client.ClientSecret, "$2") {
+		if bcrypt.CompareHashAndPassword([]byte(client.ClientSecret), []byte(clientSecret)) != nil {
+			return nil, ErrOIDCInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) != 1 {
+		return nil, ErrOIDCInvalidClient
+	}
+	return client, nil
+}
+
+// SaveLoginTicket stores a signed-in authorization request until the user consents
+func (rcvr oidcRepository) SaveLoginTicket(ctx context.Context, auth model.OIDCAuthorizations) (string, error) {
+	return rcvr.save(ctx, oidcLoginTicketKey, auth, OIDCLoginTicketTTL)
+}
+
+// ConsumeLoginTicket returns and deletes a login ticket
+func (rcvr oidcRepository) ConsumeLoginTicket(ctx context.Context, ticket string) (*model.OIDCAuthorizations, error) {
+	return rcvr.consume(ctx, oidcLoginTicketKey(ticket))
+}
+
+// SaveAuthorizationCode issues a single-use authorization code
+func (rcvr oidcRepository) SaveAuthorizationCode(ctx context.Context, auth model.OIDCAuthorizations) (string, error) {
+	ttl := DefaultOIDCAuthCodeTTL
+	if rcvr.Conf.AuthCodeTTLSeconds > 0 {
+		ttl = time.Duration(rcvr.Conf.AuthCodeTTLSeconds) * time.Second
+	}
+	return rcvr.save(ctx, oidcAuthCodeKey, auth, ttl)
+}
+
+// ConsumeAuthorizationCode redeems an authorization code; a second redemption fails
+func (rcvr oidcRepository) ConsumeAuthorizationCode(ctx context.Context, code string) (*model.OIDCAuthorizations, error) {
+	return rcvr.consume(ctx, oidcAuthCodeKey(code))
+}
+
+// GenerateIDToken signs an ID token for the authorized user with the JWT keyring
+func (rcvr oidcRepository) GenerateIDToken(issuer string, auth model.OIDCAuthorizations) (string, error) {
+	ttl := DefaultOIDCIDTokenTTL
+	if rcvr.Conf.IDTokenTTLSeconds > 0 {
+		ttl = time.Duration(rcvr.Conf.IDTokenTTLSeconds) * time.Second
+	}
+	now := time.Now()
+	claims := model.JWTClaims{
+		Jti:             uuid.New().String(),
+		Subject:         auth.UserUUID,
+		UserID:          auth.UserID,
+		UUID:            auth.UserUUID,
+		TokenUse:        model.TokenUseID,
+		Issuer:          issuer,
+		Audience:        model.Audience{auth.ClientID},
+		IssuedAt:        now.Unix(),
+		ExpiresAt:       now.Add(ttl).Unix(),
+		Nonce:           auth.Nonce,
+		AuthTime:        auth.AuthTime,
+		AuthorizedParty: auth.ClientID,
+	}
+	scopes := strings.Fields(auth.Scope)
+	if containsScope(scopes, "email") {
+		claims.Email = auth.Email
+	}
+	if containsScope(scopes, "profile") {
+		claims.Name = auth.Name
+	}
+	return rcvr.CommonRepository.GenerateJWTToken(claims)
+}
+
+func (rcvr oidcRepository) save(ctx context.Context, keyFn func(string) string, auth model.OIDCAuthorizations, ttl time.Duration) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrOIDCStorageUnavailable
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(buf)
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	if err := rcvr.RedisClient.Set(ctx, keyFn(value), data, ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to store authorization: %w", err)
+	}
+	return value, nil
+}
+
+func (rcvr oidcRepository) consume(ctx context.Context, key string) (*model.OIDCAuthorizations, error) {
+	if rcvr.RedisClient == nil {
+		return nil, ErrOIDCStorageUnavailable
+	}
+	data, err := rcvr.RedisClient.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrOIDCInvalidGrant
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load authorization: %w", err)
+	}
+	var auth model.OIDCAuthorizations
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return nil, ErrOIDCInvalidGrant
+	}
+	return &auth, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOIDCConfig checks that ID tokens can be verified by relying parties
+// through the JWKS endpoint, i.e. that the keyring does not sign with HS256.
+func ValidateOIDCConfig(server config.Server) error {
+	if !server.OIDC.Enabled {
+		return nil
+	}
+	alg, err := NormalizeJWTAlgorithm(server.JWT.Algorithm)
+	if err != nil {
+		return err
+	}
+	if alg == JWTAlgHS256 {
+		return ErrOIDCSymmetricKeyring
+	}
+	return nil
+}
+
+// NewOIDCRepository creates the OIDC provider repository
+func NewOIDCRepository(conf config.BaseConfig, commonRepository CommonRepository, redisClient *redis.Client) OIDCRepository {
+	return &oidcRepository{
+		Conf:             conf.YamlConfig.Application.Server.OIDC,
+		CommonRepository: commonRepository,
+		RedisClient:      redisClient,
+	}
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// Password hashing defaults (OWASP recommendations for argon2id)
+const (
+	DefaultArgon2idMemoryKiB   = 19 * 1024
+	DefaultArgon2idIterations  = 2
+	DefaultArgon2idParallelism = 1
+	DefaultArgon2idSaltLength  = 16
+	DefaultArgon2idKeyLength   = 32
+)
+
+var (
+	ErrPasswordMismatch        = errors.New("password does not match")
+	ErrPasswordHashUnsupported = errors.New("unsupported password hash format")
+)
+
+// PasswordHasher hashes passwords with the configured algorithm and verifies
+// hashes of every supported algorithm. Argon2id hashes use the PHC string format
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash), bcrypt hashes the usual $2a$/$2b$ one.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encodedHash, password string) error
+	NeedsRehash(encodedHash string) bool
+}
+
+type passwordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2id   config.Argon2id
+}
+
+type argon2idHash struct {
+	params config.Argon2id
+	salt   []byte
+	key    []byte
+}
+
+func (rcvr passwordHasher) Hash(password string) (string, error) {
+	if rcvr.Algorithm == PasswordHashBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), rcvr.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+	salt := make([]byte, rcvr.Argon2id.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := rcvr.Argon2id
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.MemoryKiB, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.MemoryKiB, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify returns nil when password matches the hash, ErrPasswordMismatch when it does not
+// and ErrPasswordHashUnsupported when the hash is of an unknown format
+func (rcvr passwordHasher) Verify(encodedHash, password string) error {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		hash, err := parseArgon2idHash(encodedHash)
+		if err != nil {
+			return err
+		}
+		p := hash.params
+		key := argon2.IDKey([]byte(password), hash.salt, p.Iterations, p.MemoryKiB, p.Parallelism, uint32(len(hash.key)))
+		if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	case isBcryptHash(encodedHash):
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrPasswordHashUnsupported, err)
+		}
+		return nil
+	}
+	return ErrPasswordHashUnsupported
+}
+
+// NeedsRehash reports whether a hash was made with another algorithm or weaker
+// parameters than configured
+func (rcvr passwordHasher) NeedsRehash(encodedHash string) bool {
+	if rcvr.Algorithm == PasswordHashBcrypt {
+		if !isBcryptHash(encodedHash) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost < rcvr.BcryptCost
+	}
+	hash, err := parseArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	p := hash.params
+	return p.MemoryKiB < rcvr.Argon2id.MemoryKiB || p.Iterations < rcvr.Argon2id.Iterations ||
+		p.Parallelism != rcvr.Argon2id.Parallelism || uint32(len(hash.salt)) < rcvr.Argon2id.SaltLength ||
+		uint32(len(hash.key)) < rcvr.Argon2id.KeyLength
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func parseArgon2idHash(encodedHash string) (*argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return nil, ErrPasswordHashUnsupported
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrPasswordHashUnsupported
+	}
+	hash := &argon2idHash{}
+	p := &hash.params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.MemoryKiB, &p.Iterations, &p.Parallelism); err != nil {
+		return nil, ErrPasswordHashUnsupported
+	}
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrPasswordHashUnsupported
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return nil, ErrPasswordHashUnsupported
+	}
+	if p.Iterations == 0 || p.Parallelism == 0 {
+		return nil, ErrPasswordHashUnsupported
+	}
+	return hash, nil
+}
+
+// NewPasswordHasher fills unset parameters with the defaults; argon2id is used unless bcrypt is configured
+func NewPasswordHasher(conf config.PasswordHashing) PasswordHasher {
+	hasher := passwordHasher{Algorithm: PasswordHashArgon2id, BcryptCost: bcrypt.DefaultCost, Argon2id: conf.Argon2id}
+	if strings.EqualFold(conf.Algorithm, PasswordHashBcrypt) {
+		hasher.Algorithm = PasswordHashBcrypt
+	}
+	if conf.BcryptCost >= bcrypt.MinCost && conf.BcryptCost <= bcrypt.MaxCost {
+		hasher.BcryptCost = conf.BcryptCost
+	}
+	if hasher.Argon2id.MemoryKiB == 0 {
+		hasher.Argon2id.MemoryKiB = DefaultArgon2idMemoryKiB
+	}
+	if hasher.Argon2id.Iterations == 0 {
+		hasher.Argon2id.Iterations = DefaultArgon2idIterations
+	}
+	if hasher.Argon2id.Parallelism == 0 {
+		hasher.Argon2id.Parallelism = DefaultArgon2idParallelism
+	}
+	if hasher.Argon2id.SaltLength == 0 {
+		hasher.Argon2id.SaltLength = DefaultArgon2idSaltLength
+	}
+	if hasher.Argon2id.KeyLength == 0 {
+		hasher.Argon2id.KeyLength = DefaultArgon2idKeyLength
+	}
+	return hasher
+}
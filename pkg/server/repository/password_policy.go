@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+// Password policy defaults
+const (
+	DefaultPasswordMinLength = 8
+	DefaultPasswordMaxLength = 128
+)
+
+// Password character classes
+const (
+	PasswordClassUppercase = "uppercase"
+	PasswordClassLowercase = "lowercase"
+	PasswordClassNumber    = "number"
+	PasswordClassSpecial   = "special"
+)
+
+// Password policy violation codes
+const (
+	PasswordTooShort         = "PASSWORD_TOO_SHORT"
+	PasswordTooLong          = "PASSWORD_TOO_LONG"
+	PasswordMissingUppercase = "PASSWORD_MISSING_UPPERCASE"
+	PasswordMissingLowercase = "PASSWORD_MISSING_LOWERCASE"
+	PasswordMissingNumber    = "PASSWORD_MISSING_NUMBER"
+	PasswordMissingSpecial   = "PASSWORD_MISSING_SPECIAL"
+	PasswordBreached         = "PASSWORD_BREACHED"
+	PasswordReused           = "PASSWORD_REUSED"
+)
+
+// PasswordPolicyViolation is one rule a password does not meet
+type PasswordPolicyViolation struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// PasswordPolicyRepository enforces password_policy. Validate reports every
+// violated rule; history is only checked when the user is known.
+type PasswordPolicyRepository interface {
+	Validate(c *gin.Context, password, userUUID string) ([]PasswordPolicyViolation, error)
+	RecordPassword(c *gin.Context, userUUID, passwordHash string) error
+}
+
+type passwordPolicyRepository struct {
+	BaseConfig       config.BaseConfig
+	CommonRepository CommonRepository
+}
+
+var passwordClassRules = []struct {
+	class   string
+	code    string
+	message string
+	match   func(rune) bool
+}{
+	{PasswordClassUppercase, PasswordMissingUppercase, "password must contain at least one uppercase letter", unicode.IsUpper},
+	{PasswordClassLowercase, PasswordMissingLowercase, "password must contain at least one lowercase letter", unicode.IsLower},
+	{PasswordClassNumber, PasswordMissingNumber, "password must contain at least one number", unicode.IsNumber},
+	{PasswordClassSpecial, PasswordMissingSpecial, "password must contain at least one special character", func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}},
+}
+
+func passwordViolation(code, message string) PasswordPolicyViolation {
+	return PasswordPolicyViolation{Field: "password", Code: code, Message: message}
+}
+
+// CheckPasswordRules applies the rules that only need the password itself:
+// length, character classes and the breached password list
+func CheckPasswordRules(policy config.PasswordPolicy, password string) []PasswordPolicyViolation {
+	violations := []PasswordPolicyViolation{}
+	minLength, maxLength := policy.MinLength, policy.MaxLength
+	if minLength <= 0 {
+		minLength = DefaultPasswordMinLength
+	}
+	if maxLength <= 0 {
+		maxLength = DefaultPasswordMaxLength
+	}
+	length := len([]rune(password))
+	if length < minLength {
+		violations = append(violations, passwordViolation(PasswordTooShort, "password must be at least "+strconv.Itoa(minLength)+" characters long"))
+	}
+	if length > maxLength {
+		violations = append(violations, passwordViolation(PasswordTooLong, "password must be at most "+strconv.Itoa(maxLength)+" characters long"))
+	}
+
+	classes := policy.CharacterClasses
+	if classes == nil {
+		classes = []string{PasswordClassUppercase, PasswordClassLowercase, PasswordClassNumber, PasswordClassSpecial}
+	}
+	for _, rule := range passwordClassRules {
+		if !containsFold(classes, rule.class) {
+			continue
+		}
+		if strings.IndexFunc(password, rule.match) < 0 {
+			violations = append(violations, passwordViolation(rule.code, rule.message))
+		}
+	}
+
+	if isBreachedPassword(policy, password) {
+		violations = append(violations, passwordViolation(PasswordBreached, "password has appeared in a data breach, choose another one"))
+	}
+	return violations
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBreachedPassword looks the SHA-1 of the password up in the range file of its
+// 5 character prefix, as served by the Pwned Passwords range API. Missing or
+// unreadable files count as not breached.
+func isBreachedPassword(policy config.PasswordPolicy, password string) bool {
+	if policy.BreachedPasswordsDir == "" {
+		return false
+	}
+	minCount := policy.BreachedMinCount
+	if minCount <= 0 {
+		minCount = 1
+	}
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(policy.BreachedPasswordsDir, prefix+".txt"))
+	if err != nil {
+		if file, err = os.Open(filepath.Join(policy.BreachedPasswordsDir, prefix)); err != nil {
+			return false
+		}
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		return err != nil || n >= minCount
+	}
+	return false
+}
+
+// PasswordExpired reports whether the password of a user is older than max_age_days.
+// Users without a recorded change count from their creation.
+func PasswordExpired(policy config.PasswordPolicy, user model.Users, now time.Time) bool {
+	// Passwords kept by another authenticator (ldap) are not managed here
+	if policy.MaxAgeDays <= 0 || (user.AuthSource != "" && user.AuthSource != AuthenticatorLocal) {
+		return false
+	}
+	changedAt := user.PasswordChangedAt
+	if changedAt == nil {
+		changedAt = user.CreatedAt
+	}
+	return changedAt != nil && now.Sub(*changedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour
+}
+
+// Validate returns every rule of password_policy the password violates. With a
+// history_size and a known user it also rejects the current and recent passwords.
+func (rcvr passwordPolicyRepository) Validate(c *gin.Context, password, userUUID string) ([]PasswordPolicyViolation, error) {
+	policy := rcvr.BaseConfig.YamlConfig.Application.Server.PasswordPolicy
+	violations := CheckPasswordRules(policy, password)
+	if policy.HistorySize <= 0 || userUUID == "" {
+		return violations, nil
+	}
+
+	hashes, err := rcvr.recentPasswordHashes(c, userUUID, policy.HistorySize)
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range hashes {
+		if rcvr.CommonRepository.VerifyPassword(hash, password) == nil {
+			violations = append(violations, passwordViolation(PasswordReused, "password must differ from the last "+strconv.Itoa(policy.HistorySize)+" passwords"))
+			break
+		}
+	}
+	return violations, nil
+}
+
+// recentPasswordHashes returns the current hash of the user followed by the
+// newest history entries, at most limit in total
+func (rcvr passwordPolicyRepository) recentPasswordHashes(c *gin.Context, userUUID string, limit int) ([]string, error) {
+	db := rcvr.BaseConfig.DBConnection
+	var users []model.Users
+	if err := db.Where("uuid = ? AND deleted_at IS NULL", userUUID).Limit(1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var history []model.PasswordHistories
+	if err := db.Where("user_uuid = ?", userUUID).Order("id DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, err
+	}
+	hashes := []string{}
+	if len(users) > 0 && users[0].Password != "" {
+		hashes = append(hashes, users[0].Password)
+	}
+	for _, entry := range history {
+		if len(hashes) >= limit {
+			break
+		}
+		if len(hashes) == 0 || entry.PasswordHash != hashes[0] {
+			hashes = append(hashes, entry.PasswordHash)
+		}
+	}
+	return hashes, nil
+}
+
+// RecordPassword adds a newly set password hash to the history and drops entries
+// beyond history_size
+func (rcvr passwordPolicyRepository) RecordPassword(c *gin.Context, userUUID, passwordHash string) error {
+	historySize := rcvr.BaseConfig.YamlConfig.Application.Server.PasswordPolicy.HistorySize
+	if historySize <= 0 || userUUID == "" {
+		return nil
+	}
+	db := rcvr.BaseConfig.DBConnection
+	now := time.Now()
+	if err := db.Create(&model.PasswordHistories{UserUUID: userUUID, PasswordHash: passwordHash, CreatedAt: &now}).Error; err != nil {
+		return err
+	}
+	var keep []uint
+	if err := db.Model(&model.PasswordHistories{}).Where("user_uuid = ?", userUUID).Order("id DESC").Limit(historySize).Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return db.Where("user_uuid = ? AND id NOT IN ?", userUUID, keep).Delete(&model.PasswordHistories{}).Error
+}
+
+func NewPasswordPolicyRepository(conf config.BaseConfig, commonRepository CommonRepository) PasswordPolicyRepository {
+	return passwordPolicyRepository{BaseConfig: conf, CommonRepository: commonRepository}
+}
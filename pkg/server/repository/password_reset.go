@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+)
+
+// Password reset defaults
+const (
+	DefaultPasswordResetTokenTTL    = 30 * time.Minute
+	DefaultPasswordResetMaxRequests = 5
+	DefaultPasswordResetWindow      = 15 * time.Minute
+)
+
+var (
+	ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+	ErrPasswordResetUnavailable  = errors.New("password reset requires redis")
+)
+
+// PasswordResetRepository stores single-use password reset tokens and the
+// request counters used to rate limit the forgot/reset endpoints.
+type PasswordResetRepository interface {
+	CreateResetToken(ctx context.Context, userUUID string) (string, error)
+	LookupResetToken(ctx context.Context, token string) (string, error)
+	ConsumeResetToken(ctx context.Context, token string) (string, error)
+	AllowRequest(ctx context.Context, scope, key string) (bool, time.Duration, error)
+	TokenTTL() time.Duration
+}
+
+type passwordResetRepository struct {
+	BaseConfig  config.BaseConfig
+	RedisClient *redis.Client
+}
+
+func passwordResetTokenKey(tokenHash string) string {
+	return "password_reset:token:" + tokenHash
+}
+
+func passwordResetUserKey(userUUID string) string {
+	return "password_reset:user:" + userUUID
+}
+
+// TokenTTL returns how long a reset token stays valid
+func (rcvr passwordResetRepository) TokenTTL() time.Duration {
+	if sec := rcvr.BaseConfig.YamlConfig.Application.Server.PasswordReset.TokenTTLSeconds; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return DefaultPasswordResetTokenTTL
+}
+
+// CreateResetToken issues a reset token for the user. Only the latest token
+// of a user is valid; issuing a new one invalidates the previous one.
+func (rcvr passwordResetRepository) CreateResetToken(ctx context.Context, userUUID string) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrPasswordResetUnavailable
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	tokenHash := hashSecret(token)
+	ttl := rcvr.TokenTTL()
+
+	previous, err := rcvr.RedisClient.Get(ctx, passwordResetUserKey(userUUID)).Result()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("failed to load password reset token: %w", err)
+	}
+	pipe := rcvr.RedisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, passwordResetTokenKey(previous))
+	}
+	pipe.Set(ctx, passwordResetTokenKey(tokenHash), userUUID, ttl)
+	pipe.Set(ctx, passwordResetUserKey(userUUID), tokenHash, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store password reset token: %w", err)
+	}
+	return token, nil
+}
+
+// LookupResetToken returns the user UUID of a reset token without consuming it
+func (rcvr passwordResetRepository) LookupResetToken(ctx context.Context, token string) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrPasswordResetUnavailable
+	}
+	userUUID, err := rcvr.RedisClient.Get(ctx, passwordResetTokenKey(hashSecret(token))).Result()
+	if err == redis.Nil {
+		return "", ErrPasswordResetTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load password reset token: %w", err)
+	}
+	return userUUID, nil
+}
+
+// ConsumeResetToken returns the user UUID of a reset token and deletes the
+// token, so it cannot be used twice
+func (rcvr passwordResetRepository) ConsumeResetToken(ctx context.Context, token string) (string, error) {
+	if rcvr.RedisClient == nil {
+		return "", ErrPasswordResetUnavailable
+	}
+	tokenHash := hashSecret(token)
+	userUUID, err := rcvr.RedisClient.GetDel(ctx, passwordResetTokenKey(tokenHash)).Result()
+	if err == redis.Nil {
+		return "", ErrPasswordResetTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load password reset token: %w", err)
+	}
+	if current, _ := rcvr.RedisClient.Get(ctx, passwordResetUserKey(userUUID)).Result(); current == tokenHash {
+		_ = rcvr.RedisClient.Del(ctx, passwordResetUserKey(userUUID)).Err()
+	}
+	return userUUID, nil
+}
+
+// AllowRequest counts a request for key within the configured window
+// (fixed window). It returns false and the time until the window resets once
+// max_requests is exceeded.
+func (rcvr passwordResetRepository) AllowRequest(ctx context.Context, scope, key string) (bool, time.Duration, error) {
+	if rcvr.RedisClient == nil {
+		return false, 0, ErrPasswordResetUnavailable
+	}
+	conf := rcvr.BaseConfig.YamlConfig.Application.Server.PasswordReset
+	limit := conf.MaxRequests
+	if limit <= 0 {
+		limit = DefaultPasswordResetMaxRequests
+	}
+	window := DefaultPasswordResetWindow
+	if conf.WindowSeconds > 0 {
+		window = time.Duration(conf.WindowSeconds) * time.Second
+	}
+
+	counterKey := "password_reset:rate:" + scope + ":" + hashSecret(key)
+	count, err := rcvr.RedisClient.Incr(ctx, counterKey).Result()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to count password reset request: %w", err)
+	}
+	if count == 1 {
+		if err := rcvr.RedisClient.Expire(ctx, counterKey, window).Err(); err != nil {
+			return false, 0, fmt.Errorf("failed to count password reset request: %w", err)
+		}
+	}
+	if count > int64(limit) {
+		retryAfter, _ := rcvr.RedisClient.TTL(ctx, counterKey).Result()
+		if retryAfter <= 0 {
+			retryAfter = window
+		}
+		return false, retryAfter, nil
+	}
+	return true, 0, nil
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(conf config.BaseConfig, redisClient *redis.Client) PasswordResetRepository {
+	return &passwordResetRepository{BaseConfig: conf, RedisClient: redisClient}
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix marks personal access tokens so that the
+// middleware can tell them apart from JWTs without parsing
+const PersonalAccessTokenPrefix = "lky_pat_"
+
+// personalAccessTokenDisplayLength is how much of a token is kept in clear for listings
+const personalAccessTokenDisplayLength = len(PersonalAccessTokenPrefix) + 4
+
+var (
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+	ErrPersonalAccessTokenInvalid  = errors.New("personal access token is invalid or expired")
+)
+
+// PersonalAccessTokenRepository stores the personal access tokens of users.
+// Tokens are verified by CommonRepository.ValidatePersonalAccessToken.
+type PersonalAccessTokenRepository interface {
+	ListTokens(c *gin.Context, userUUID string) ([]model.PersonalAccessTokens, error)
+	CreateToken(c *gin.Context, pat *model.PersonalAccessTokens) (string, error)
+	RevokeToken(c *gin.Context, userUUID, idOrUUID string) error
+	RevokeAllTokens(c *gin.Context, userUUID string) (int64, error)
+}
+
+type personalAccessTokenRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// hashPersonalAccessToken returns the lookup key of a token. The token has 256 bits
+// of entropy, so a plain SHA-256 (unlike passwords) is enough.
+func hashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListTokens returns the user's tokens that have not been revoked, newest first
+func (rcvr personalAccessTokenRepository) ListTokens(c *gin.Context, userUUID string) ([]model.PersonalAccessTokens, error) {
+	var list []model.PersonalAccessTokens
+	if err := rcvr.BaseConfig.DBConnection.Where("user_uuid = ? AND deleted_at IS NULL", userUUID).Order("id DESC").Find(&list).Error; err != nil {
+		return []model.PersonalAccessTokens{}, err
+	}
+	return list, nil
+}
+
+// CreateToken generates a token, stores its hash and returns the plain value
+func (rcvr personalAccessTokenRepository) CreateToken(c *gin.Context, pat *model.PersonalAccessTokens) (string, error) {
+	if pat == nil {
+		return "", errors.New("personal access token is nil")
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	pat.UUID = uuid.New().String()
+	pat.Prefix = token[:personalAccessTokenDisplayLength]
+	pat.TokenHash = hashPersonalAccessToken(token)
+	if err := rcvr.BaseConfig.DBConnection.Create(pat).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeToken revokes one of the user's tokens by numeric ID or UUID
+func (rcvr personalAccessTokenRepository) RevokeToken(c *gin.Context, userUUID, idOrUUID string) error {
+	q := rcvr.BaseConfig.DBConnection.Model(&model.PersonalAccessTokens{}).Where("user_uuid = ? AND deleted_at IS NULL", userUUID)
+	if _, err := uuid.Parse(idOrUUID); err == nil {
+		q = q.Where("uuid = ?", idOrUUID)
+	} else {
+		q = q.Where("id = ?", idOrUUID)
+	}
+	res := q.Update("deleted_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAllTokens revokes every personal access token of the user (e.g. after a password reset)
+func (rcvr personalAccessTokenRepository) RevokeAllTokens(c *gin.Context, userUUID string) (int64, error) {
+	res := rcvr.BaseConfig.DBConnection.Model(&model.PersonalAccessTokens{}).
+		Where("user_uuid = ? AND deleted_at IS NULL", userUUID).
+		Update("deleted_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+// ValidatePersonalAccessToken resolves a personal access token to claims of its owner.
+// The role is resolved from the current user record, and the token's scopes are
+// returned in Scope so that authorization can narrow the role further.
+func (cr *commonRepository) ValidatePersonalAccessToken(ctx context.Context, token string) (*model.JWTClaims, error) {
+	db := cr.BaseConfig.DBConnection
+	if db == nil {
+		return nil, fmt.Errorf("personal access tokens require a database connection")
+	}
+	db = db.WithContext(ctx)
+
+	var pat model.PersonalAccessTokens
+	if err := db.Where("token_hash = ? AND deleted_at IS NULL", hashPersonalAccessToken(token)).First(&pat).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonalAccessTokenInvalid
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if pat.ExpiresAt == nil || !pat.ExpiresAt.After(now) {
+		return nil, ErrPersonalAccessTokenInvalid
+	}
+
+	var user model.Users
+	if err := db.Where("uuid = ? AND deleted_at IS NULL", pat.UserUUID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonalAccessTokenInvalid
+		}
+		return nil, err
+	}
+	_ = db.Model(&model.PersonalAccessTokens{}).Where("id = ?", pat.ID).Update("last_used_at", now).Error
+
+	claims := &model.JWTClaims{
+		Jti:       "pat:" + pat.UUID,
+		Subject:   user.UUID,
+		UserID:    user.ID,
+		UUID:      user.UUID,
+		Email:     user.Email,
+		Name:      user.Name,
+		TokenUse:  model.TokenUsePAT,
+		Scope:     pat.Scopes,
+		ExpiresAt: pat.ExpiresAt.Unix(),
+	}
+	claims.Roles = cr.LoadUserRoles(user)
+	claims.Role = claims.Roles[0]
+	if pat.CreatedAt != nil {
+		claims.IssuedAt = pat.CreatedAt.Unix()
+	}
+	return claims, nil
+}
+
+// NewPersonalAccessTokenRepository creates a new personal access token repository
+func NewPersonalAccessTokenRepository(conf config.BaseConfig) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{BaseConfig: conf}
+}
@@ -0,0 +1,417 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	casbinmodel "github.com/casbin/casbin/v2/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/logger"
+)
+
+// How a server process learns about policy changes
+const (
+	PolicyWatcherRedis = "redis" // database storage: changes are broadcast over Redis pub/sub
+	PolicyWatcherFile  = "file"  // file storage: policy.csv is checked for edits
+	PolicyWatcherNone  = "none"
+)
+
+// Operations of a broadcast policy change
+const (
+	policyChangeAdd            = "add"
+	policyChangeRemove         = "remove"
+	policyChangeRemoveFiltered = "remove_filtered"
+	policyChangeReload         = "reload"
+)
+
+const (
+	defaultPolicyWatchInterval = 5 * time.Second
+	policyChannelPrefix        = "locky:casbin:policy:"
+	policyRevisionKeyPrefix    = "locky:casbin:revision:"
+)
+
+// PolicyStatus describes the policy a server process has loaded for a policy set.
+// With database storage Revision is shared by all processes and counts the
+// changes made through any of them; with file storage it counts the loads of
+// policy.csv by this process. Processes with the same Digest enforce the same
+// policy lines.
+type PolicyStatus struct {
+	PolicySet string
+	Storage   string
+	Watcher   string
+	Revision  int64
+	Digest    string
+	Rules     int
+	LoadedAt  time.Time
+}
+
+// PolicyWatcherRepository keeps the in-memory enforcers of all server processes
+// in step with the stored policy. With casbin.storage database, every change
+// made through an enforcer is broadcast over Redis pub/sub and applied line by
+// line on the other processes; a process that missed a change reloads the whole
+// policy set. With file storage, policy.csv is reloaded when it is edited.
+type PolicyWatcherRepository interface {
+	Watch(ctx context.Context, enforcer *casbin.SyncedEnforcer, policySet string) error
+	GetPolicyStatus() []PolicyStatus
+	NodeID() string
+}
+
+type policyWatcherRepository struct {
+	BaseConfig  config.BaseConfig
+	RedisClient *redis.Client
+	Node        string
+
+	mu       sync.Mutex
+	policies []*watchedPolicy
+}
+
+// policyChange is the message broadcast for one change of a policy set
+type policyChange struct {
+	Node        string     `json:"node"`
+	Revision    int64      `json:"revision"`
+	Op          string     `json:"op"`
+	Sec         string     `json:"sec,omitempty"`
+	PType       string     `json:"ptype,omitempty"`
+	Rules       [][]string `json:"rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+}
+
+// watchedPolicy is the Casbin watcher of one enforcer. Casbin calls it while the
+// enforcer is locked for a change, so the enforcer's lock is always taken
+// before mu, and the policy is changed through the unsynchronized Enforcer.
+type watchedPolicy struct {
+	repo      *policyWatcherRepository
+	enforcer  *casbin.SyncedEnforcer
+	policySet string
+	watcher   string
+
+	mu       sync.Mutex
+	revision int64
+	loadedAt time.Time
+}
+
+// Watch starts following the changes of the enforcer's policy set until ctx is done
+func (rcvr *policyWatcherRepository) Watch(ctx context.Context, enforcer *casbin.SyncedEnforcer, policySet string) error {
+	p := &watchedPolicy{repo: rcvr, enforcer: enforcer, policySet: policySet, watcher: PolicyWatcherNone, loadedAt: time.Now()}
+	if rcvr.BaseConfig.YamlConfig.Application.Server.Casbin.Storage == CasbinStorageDatabase {
+		if rcvr.RedisClient != nil {
+			if err := p.watchRedis(ctx); err != nil {
+				return err
+			}
+		}
+	} else {
+		p.watchFile(ctx)
+	}
+	rcvr.mu.Lock()
+	rcvr.policies = append(rcvr.policies, p)
+	rcvr.mu.Unlock()
+	return nil
+}
+
+// GetPolicyStatus reports the policy loaded by this process for each watched policy set
+func (rcvr *policyWatcherRepository) GetPolicyStatus() []PolicyStatus {
+	rcvr.mu.Lock()
+	policies := append([]*watchedPolicy{}, rcvr.policies...)
+	rcvr.mu.Unlock()
+
+	storage := rcvr.BaseConfig.YamlConfig.Application.Server.Casbin.Storage
+	if storage == "" {
+		storage = CasbinStorageFile
+	}
+	statuses := make([]PolicyStatus, 0, len(policies))
+	for _, p := range policies {
+		p.enforcer.GetLock().RLock()
+		p.mu.Lock()
+		digest, rules := policyDigest(p.enforcer)
+		statuses = append(statuses, PolicyStatus{
+			PolicySet: p.policySet,
+			Storage:   storage,
+			Watcher:   p.watcher,
+			Revision:  p.revision,
+			Digest:    digest,
+			Rules:     rules,
+			LoadedAt:  p.loadedAt,
+		})
+		p.mu.Unlock()
+		p.enforcer.GetLock().RUnlock()
+	}
+	return statuses
+}
+
+// NodeID identifies this server process in broadcast changes and status reports
+func (rcvr *policyWatcherRepository) NodeID() string {
+	return rcvr.Node
+}
+
+// watchRedis subscribes to the policy channel, then loads the policy with the
+// current revision so no change published in between is lost.
+func (p *watchedPolicy) watchRedis(ctx context.Context) error {
+	client := p.repo.RedisClient
+	sub := client.Subscribe(ctx, policyChannelPrefix+p.policySet)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return fmt.Errorf("failed to subscribe to policy changes: %w", err)
+	}
+	revision, err := client.Get(ctx, policyRevisionKeyPrefix+p.policySet).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		sub.Close()
+		return fmt.Errorf("failed to read policy revision: %w", err)
+	}
+	if err := p.enforcer.LoadPolicy(); err != nil {
+		sub.Close()
+		return err
+	}
+	p.watcher = PolicyWatcherRedis
+	p.revision = revision
+	p.loadedAt = time.Now()
+	if err := p.enforcer.SetWatcher(p); err != nil {
+		sub.Close()
+		return err
+	}
+
+	go func() {
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				p.receive(msg.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+// receive applies a change broadcast by another process. Changes arrive in
+// revision order; when one is missing the whole policy set is reloaded.
+func (p *watchedPolicy) receive(payload string) {
+	var change policyChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		logger.Error(code.RPWR3, "", p.policySet+": "+err.Error())
+		return
+	}
+	if change.Node == p.repo.Node {
+		return
+	}
+	// Requests are not enforced while the change is applied
+	p.enforcer.GetLock().Lock()
+	defer p.enforcer.GetLock().Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if change.Revision <= p.revision {
+		return
+	}
+	var err error
+	if change.Revision != p.revision+1 || change.Op == policyChangeReload {
+		if err = p.enforcer.Enforcer.LoadPolicy(); err == nil {
+			logger.Info(code.RPWR1, "", fmt.Sprintf("%s revision %d from %s", p.policySet, change.Revision, change.Node))
+		}
+	} else {
+		err = applyPolicyChange(p.enforcer, change)
+	}
+	if err != nil {
+		// Keep the revision so the next change reloads the whole policy set
+		logger.Error(code.RPWR3, "", fmt.Sprintf("%s revision %d: %s", p.policySet, change.Revision, err.Error()))
+		return
+	}
+	p.revision = change.Revision
+	p.loadedAt = time.Now()
+}
+
+// publish broadcasts a change made through this process. The change is
+// already stored, so a failed broadcast is only logged: the other processes
+// catch up with the next change they receive.
+func (p *watchedPolicy) publish(change policyChange) error {
+	ctx := context.Background()
+	client := p.repo.RedisClient
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	revision, err := client.Incr(ctx, policyRevisionKeyPrefix+p.policySet).Result()
+	if err != nil {
+		logger.Warn(code.RPWR2, "", p.policySet+": "+err.Error())
+		return nil
+	}
+	change.Node = p.repo.Node
+	change.Revision = revision
+	payload, err := json.Marshal(change)
+	if err == nil {
+		err = client.Publish(ctx, policyChannelPrefix+p.policySet, payload).Err()
+	}
+	if err != nil {
+		logger.Warn(code.RPWR2, "", p.policySet+": "+err.Error())
+	}
+	if revision != p.revision+1 {
+		// Changes of other processes are still on their way; they are stored already
+		if err := p.enforcer.Enforcer.LoadPolicy(); err != nil {
+			logger.Error(code.RPWR3, "", p.policySet+": "+err.Error())
+			return nil
+		}
+	}
+	p.revision = revision
+	p.loadedAt = time.Now()
+	return nil
+}
+
+// watchFile reloads policy.csv when its modification time or size changes
+func (p *watchedPolicy) watchFile(ctx context.Context) {
+	p.revision = 1
+	seconds := p.repo.BaseConfig.YamlConfig.Application.Server.Casbin.WatchIntervalSeconds
+	if seconds < 0 {
+		return
+	}
+	interval := defaultPolicyWatchInterval
+	if seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	path := policyFilePath(p.policySet)
+	last, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	p.watcher = PolicyWatcherFile
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil || (info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+					continue
+				}
+				p.enforcer.GetLock().Lock()
+				p.mu.Lock()
+				if err := p.enforcer.Enforcer.LoadPolicy(); err != nil {
+					// Retried on the next tick, e.g. when the file was caught half written
+					logger.Error(code.RPWR3, "", path+": "+err.Error())
+				} else {
+					last = info
+					p.revision++
+					p.loadedAt = time.Now()
+					logger.Info(code.RPWR1, "", fmt.Sprintf("%s revision %d from %s", p.policySet, p.revision, path))
+				}
+				p.mu.Unlock()
+				p.enforcer.GetLock().Unlock()
+			}
+		}
+	}()
+}
+
+// SetUpdateCallback is not used: changes are applied by the watcher itself
+func (p *watchedPolicy) SetUpdateCallback(func(string)) error { return nil }
+
+// Update broadcasts that the whole policy set has to be reloaded
+func (p *watchedPolicy) Update() error {
+	return p.publish(policyChange{Op: policyChangeReload})
+}
+
+// Close is a no-op: the subscription ends with the context passed to Watch
+func (p *watchedPolicy) Close() {}
+
+func (p *watchedPolicy) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return p.publish(policyChange{Op: policyChangeAdd, Sec: sec, PType: ptype, Rules: [][]string{params}})
+}
+
+func (p *watchedPolicy) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return p.publish(policyChange{Op: policyChangeRemove, Sec: sec, PType: ptype, Rules: [][]string{params}})
+}
+
+func (p *watchedPolicy) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return p.publish(policyChange{Op: policyChangeRemoveFiltered, Sec: sec, PType: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+func (p *watchedPolicy) UpdateForSavePolicy(model casbinmodel.Model) error {
+	return p.publish(policyChange{Op: policyChangeReload})
+}
+
+func (p *watchedPolicy) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return p.publish(policyChange{Op: policyChangeAdd, Sec: sec, PType: ptype, Rules: rules})
+}
+
+func (p *watchedPolicy) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return p.publish(policyChange{Op: policyChangeRemove, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// applyPolicyChange changes the enforcer's in-memory policy only; the change
+// is already stored by the process that made it. The caller holds the
+// enforcer's lock.
+func applyPolicyChange(e *casbin.SyncedEnforcer, change policyChange) error {
+	m := e.GetModel()
+	var affected [][]string
+	var err error
+	op := casbinmodel.PolicyAdd
+	switch change.Op {
+	case policyChangeAdd:
+		affected, err = m.AddPoliciesWithAffected(change.Sec, change.PType, change.Rules)
+	case policyChangeRemove:
+		op = casbinmodel.PolicyRemove
+		affected, err = m.RemovePoliciesWithAffected(change.Sec, change.PType, change.Rules)
+	case policyChangeRemoveFiltered:
+		op = casbinmodel.PolicyRemove
+		_, affected, err = m.RemoveFilteredPolicy(change.Sec, change.PType, change.FieldIndex, change.FieldValues...)
+	default:
+		return e.Enforcer.LoadPolicy()
+	}
+	if err != nil {
+		return err
+	}
+	if change.Sec == "g" && len(affected) > 0 {
+		return e.BuildIncrementalRoleLinks(op, change.PType, affected)
+	}
+	return nil
+}
+
+// policyDigest hashes the sorted policy lines of the enforcer; the caller
+// holds the enforcer's lock
+func policyDigest(e *casbin.SyncedEnforcer) (string, int) {
+	lines := []string{}
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range e.GetModel()[sec] {
+			for _, rule := range ast.Policy {
+				lines = append(lines, ptype+", "+strings.Join(rule, ", "))
+			}
+		}
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:]), len(lines)
+}
+
+func policyFilePath(policySet string) string {
+	return "etc/casbin/" + policySet + "/policy.csv"
+}
+
+// NewPolicyWatcherRepository creates the policy watcher of this server process
+func NewPolicyWatcherRepository(conf config.BaseConfig, redisClient *redis.Client) PolicyWatcherRepository {
+	node, err := os.Hostname()
+	if err != nil || node == "" {
+		node = "locky"
+	}
+	return &policyWatcherRepository{
+		BaseConfig:  conf,
+		RedisClient: redisClient,
+		Node:        node + "-" + uuid.New().String()[:8],
+	}
+}
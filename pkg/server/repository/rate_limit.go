@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+)
+
+// Rate limit stores
+const (
+	RateLimitStoreRedis  = "redis"
+	RateLimitStoreMemory = "memory"
+)
+
+// RateLimitResult is the outcome of counting one request against a limit
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the current window ends
+	RetryAfter time.Duration // until the next request is allowed, set when not allowed
+}
+
+// RateLimitRepository counts requests per key with a sliding window: the count of
+// the previous fixed window is weighted by how much of it still overlaps the sliding
+// window and added to the count of the current one. Rejected requests are not counted.
+type RateLimitRepository interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// redisRateLimitRepository shares the counters between all server instances
+type redisRateLimitRepository struct {
+	RedisClient *redis.Client
+}
+
+// memoryRateLimitRepository keeps the counters of a single process
+type memoryRateLimitRepository struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryRateLimitWindow
+	lastSweep time.Time
+}
+
+type memoryRateLimitWindow struct {
+	index    int64
+	current  int
+	previous int
+	expires  time.Time
+}
+
+func rateLimitKey(key string, index int64) string {
+	return "rate_limit:" + hashSecret(key) + ":" + strconv.FormatInt(index, 10)
+}
+
+// rateLimitWindow returns the index of the fixed window containing now and how far into it now is
+func rateLimitWindow(now time.Time, window time.Duration) (int64, time.Duration) {
+	index := now.UnixNano() / int64(window)
+	return index, time.Duration(now.UnixNano() - index*int64(window))
+}
+
+// evaluateRateLimit decides on a request given the counts of the previous and current
+// window (the latter including the request itself)
+func evaluateRateLimit(previous, current, limit int, window, elapsed time.Duration) RateLimitResult {
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := float64(previous)*weight + float64(current)
+	result := RateLimitResult{
+		Allowed:   estimate <= float64(limit),
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(float64(limit)-estimate))),
+		Reset:     window - elapsed,
+	}
+	if result.Allowed {
+		return result
+	}
+	// The rejected request is not counted; find when one more request fits again
+	current--
+	if free := limit - current - 1; free >= 0 && previous > 0 {
+		wait := time.Duration(float64(window)*(1-float64(free)/float64(previous))) - elapsed
+		result.RetryAfter = wait
+	} else {
+		// Only possible in the next window, where the current count becomes the previous one
+		next := time.Duration(0)
+		if current > 0 {
+			next = time.Duration(math.Max(0, float64(window)*(1-float64(limit-1)/float64(current))))
+		}
+		result.RetryAfter = window - elapsed + next
+	}
+	if result.RetryAfter < time.Second {
+		result.RetryAfter = time.Second
+	}
+	return result
+}
+
+func (rcvr redisRateLimitRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	index, elapsed := rateLimitWindow(time.Now(), window)
+	currentKey := rateLimitKey(key, index)
+	pipe := rcvr.RedisClient.TxPipeline()
+	incr := pipe.Incr(ctx, currentKey)
+	pipe.PExpire(ctx, currentKey, 2*window)
+	prev := pipe.Get(ctx, rateLimitKey(key, index-1))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return RateLimitResult{}, fmt.Errorf("failed to count request: %w", err)
+	}
+	previous, err := prev.Int()
+	if err != nil && err != redis.Nil {
+		return RateLimitResult{}, fmt.Errorf("failed to count request: %w", err)
+	}
+	result := evaluateRateLimit(previous, int(incr.Val()), limit, window, elapsed)
+	if !result.Allowed {
+		if err := rcvr.RedisClient.Decr(ctx, currentKey).Err(); err != nil {
+			return result, fmt.Errorf("failed to count request: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func (rcvr *memoryRateLimitRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+	index, elapsed := rateLimitWindow(now, window)
+
+	rcvr.mu.Lock()
+	defer rcvr.mu.Unlock()
+	rcvr.sweep(now)
+	w, ok := rcvr.windows[key]
+	if !ok {
+		w = &memoryRateLimitWindow{index: index}
+		rcvr.windows[key] = w
+	}
+	switch {
+	case w.index == index-1:
+		w.previous, w.current = w.current, 0
+	case w.index < index-1:
+		w.previous, w.current = 0, 0
+	}
+	w.index = index
+	w.expires = now.Add(2*window - elapsed)
+
+	w.current++
+	result := evaluateRateLimit(w.previous, w.current, limit, window, elapsed)
+	if !result.Allowed {
+		w.current--
+	}
+	return result, nil
+}
+
+// sweep drops counters that no longer affect any decision, at most once a minute
+func (rcvr *memoryRateLimitRepository) sweep(now time.Time) {
+	if now.Sub(rcvr.lastSweep) < time.Minute {
+		return
+	}
+	rcvr.lastSweep = now
+	for key, w := range rcvr.windows {
+		if now.After(w.expires) {
+			delete(rcvr.windows, key)
+		}
+	}
+}
+
+// NewRateLimitRepository uses Redis unless rate_limit.store is memory or no Redis client is given
+func NewRateLimitRepository(conf config.BaseConfig, redisClient *redis.Client) RateLimitRepository {
+	if redisClient == nil || conf.YamlConfig.Application.Server.RateLimit.Store == RateLimitStoreMemory {
+		return &memoryRateLimitRepository{windows: map[string]*memoryRateLimitWindow{}}
+	}
+	return redisRateLimitRepository{RedisClient: redisClient}
+}
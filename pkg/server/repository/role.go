@@ -17,40 +17,74 @@ type RolePermission struct {
 	Action   string `json:"action"`
 }
 
-// RoleRepository uses Casbin policy as storage (currently file policy.csv + memory)
+// EffectiveRolePermission is a permission a role holds directly or through
+// inheritance. Role names the role whose policy line grants it.
+type EffectiveRolePermission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Role     string `json:"role"`
+}
+
+// RoleHierarchy describes where a role sits in the inheritance graph.
+// "g, maintainer, member" makes member a parent of maintainer: maintainer holds
+// all permissions of member.
+type RoleHierarchy struct {
+	Role        string   `json:"role"`
+	Parents     []string `json:"parents"`
+	Ancestors   []string `json:"ancestors"`
+	Descendants []string `json:"descendants"`
+}
+
+// Errors of role inheritance changes
+var (
+	ErrRoleNotFound         = errors.New("role not found")
+	ErrRoleParentExists     = errors.New("role already inherits from parent")
+	ErrRoleParentNotFound   = errors.New("role does not inherit from parent")
+	ErrRoleInheritanceCycle = errors.New("role inheritance would create a cycle")
+)
+
+// RoleRepository uses Casbin policy as storage (policy.csv or the casbin_rules table)
 // for application-level Role CRUD abstraction.
 // Note: Does not use DB here, Casbin Enforcer is the single source of truth.
 // - ListRoles(): enumerate all subjects(roles) appearing in policy
 // - GetRolePermissions(): list of (resource,action) pairs for 1 role
 // - CreateRole(): check existing role duplication + add permissions
 // - UpdateRole(): delete all existing policy role lines → recreate with new permissions
-// - DeleteRole(): delete all role lines
-// All call SavePolicy() for persistence to file (when adapter is file).
+// - DeleteRole(): delete all role lines, including its inheritance (g) lines
+// - GetEffectiveRolePermissions(): permissions of the role and of the roles it inherits
+// - GetRoleHierarchy(): parents, ancestors and descendants of a role (g, <role>, <parent>)
+// - AddRoleParent() / RemoveRoleParent(): edit one inheritance line, rejecting cycles
+// Changes are stored line by line through the adapter's auto-save; only the file
+// adapter needs the whole policy.csv rewritten with SavePolicy().
 type RoleRepository interface {
 	ListRoles(c *gin.Context) ([]string, error)
 	GetRolePermissions(c *gin.Context, role string) ([]RolePermission, error)
 	CreateRole(c *gin.Context, role string, perms []RolePermission) error
 	UpdateRole(c *gin.Context, role string, perms []RolePermission) error
 	DeleteRole(c *gin.Context, role string) error
+	GetEffectiveRolePermissions(c *gin.Context, role string) ([]EffectiveRolePermission, error)
+	GetRoleHierarchy(c *gin.Context, role string) (RoleHierarchy, error)
+	AddRoleParent(c *gin.Context, role, parent string) error
+	RemoveRoleParent(c *gin.Context, role, parent string) error
 }
 
 type roleRepository struct {
 	// App-wide (global) permissions: etc/casbin/locky/*. Read-only in this repository.
-	appEnforcer *casbin.Enforcer
+	appEnforcer *casbin.SyncedEnforcer
 	// Group/resource roles (CRUD target): etc/casbin/resources/*. Focus role CRUD here.
-	resourceEnforcer *casbin.Enforcer
+	resourceEnforcer *casbin.SyncedEnforcer
 }
 
 // NewRoleRepository: receives 2 types of Enforcers and returns repository for resource role management.
 //
 //	appEnf      -> etc/casbin/locky/model.conf + policy.csv (app-wide RBAC)
 //	resourceEnf -> etc/casbin/resources/model.conf + policy.csv (group/internal resource RBAC / CRUD target)
-func NewRoleRepository(appEnf *casbin.Enforcer, resourceEnf *casbin.Enforcer) RoleRepository {
+func NewRoleRepository(appEnf *casbin.SyncedEnforcer, resourceEnf *casbin.SyncedEnforcer) RoleRepository {
 	return &roleRepository{appEnforcer: appEnf, resourceEnforcer: resourceEnf}
 }
 
 // internal helper: returns the Enforcer that is currently the CRUD target.
-func (r *roleRepository) target() *casbin.Enforcer { return r.resourceEnforcer }
+func (r *roleRepository) target() *casbin.SyncedEnforcer { return r.resourceEnforcer }
 
 // ListRoles: enumerate subjects in group policy
 func (r *roleRepository) ListRoles(c *gin.Context) ([]string, error) {
@@ -118,34 +152,40 @@ func (r *roleRepository) CreateRole(c *gin.Context, role string, perms []RolePer
 	if len(perms) == 0 {
 		perms = []RolePermission{{Resource: "group_info", Action: "read"}}
 	}
-	for _, pm := range perms {
-		if _, err := r.target().AddPolicy(role, pm.Resource, pm.Action); err != nil {
-			return err
-		}
+	if _, err := r.target().AddPolicies(rolePolicies(role, diffPermissions(perms, nil))); err != nil {
+		return err
 	}
-	return r.target().SavePolicy()
+	return savePolicy(r.target())
 }
 
-// UpdateRole: delete all permissions for existing role with RemoveFilteredPolicy, then re-insert new permissions.
+// UpdateRole: replace the permissions of a role. Only the lines that differ are
+// removed or added, so the other lines stay untouched in the storage.
 // When perms is empty, grant roles:read as minimum permission like Create.
-// Note: Full replacement, not differential update.
 func (r *roleRepository) UpdateRole(c *gin.Context, role string, perms []RolePermission) error {
 	role = strings.TrimSpace(role)
 	if role == "" {
 		return errors.New("role name required")
 	}
-	if _, err := r.target().RemoveFilteredPolicy(0, role); err != nil {
-		return err
-	}
 	if len(perms) == 0 {
 		perms = []RolePermission{{Resource: "group_info", Action: "read"}}
 	}
-	for _, pm := range perms {
-		if _, err := r.target().AddPolicy(role, pm.Resource, pm.Action); err != nil {
+	current, err := r.GetRolePermissions(c, role)
+	if err != nil {
+		return err
+	}
+	removed := rolePolicies(role, diffPermissions(current, perms))
+	if len(removed) > 0 {
+		if _, err := r.target().RemovePolicies(removed); err != nil {
+			return err
+		}
+	}
+	added := rolePolicies(role, diffPermissions(perms, current))
+	if len(added) > 0 {
+		if _, err := r.target().AddPolicies(added); err != nil {
 			return err
 		}
 	}
-	return r.target().SavePolicy()
+	return savePolicy(r.target())
 }
 
 // DeleteRole: delete all policy lines for specified role. If non-existent, leave to RemoveFilteredPolicy result.
@@ -157,5 +197,179 @@ func (r *roleRepository) DeleteRole(c *gin.Context, role string) error {
 	if _, err := r.target().RemoveFilteredPolicy(0, role); err != nil {
 		return err
 	}
-	return r.target().SavePolicy()
+	// Inheritance lines naming the role as child or parent
+	for _, idx := range []int{0, 1} {
+		if _, err := r.target().RemoveFilteredGroupingPolicy(idx, role); err != nil {
+			return err
+		}
+	}
+	return savePolicy(r.target())
+}
+
+// GetEffectiveRolePermissions: direct permissions of the role followed by those
+// inherited from its ancestors, nearest first. A permission granted on several
+// levels is listed once, with the nearest role.
+func (r *roleRepository) GetEffectiveRolePermissions(c *gin.Context, role string) ([]EffectiveRolePermission, error) {
+	if strings.TrimSpace(role) == "" {
+		return nil, errors.New("role required")
+	}
+	parents, _, err := r.inheritance()
+	if err != nil {
+		return nil, err
+	}
+	res := []EffectiveRolePermission{}
+	seen := map[RolePermission]bool{}
+	for _, from := range append([]string{role}, walkRoles(parents, role)...) {
+		perms, err := r.GetRolePermissions(c, from)
+		if err != nil {
+			return nil, err
+		}
+		for _, pm := range perms {
+			if !seen[pm] {
+				seen[pm] = true
+				res = append(res, EffectiveRolePermission{Resource: pm.Resource, Action: pm.Action, Role: from})
+			}
+		}
+	}
+	return res, nil
+}
+
+// GetRoleHierarchy: parents (direct), ancestors and descendants (transitive) of a role
+func (r *roleRepository) GetRoleHierarchy(c *gin.Context, role string) (RoleHierarchy, error) {
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return RoleHierarchy{}, errors.New("role required")
+	}
+	parents, children, err := r.inheritance()
+	if err != nil {
+		return RoleHierarchy{}, err
+	}
+	direct := append([]string{}, parents[role]...)
+	sort.Strings(direct)
+	ancestors := walkRoles(parents, role)
+	sort.Strings(ancestors)
+	descendants := walkRoles(children, role)
+	sort.Strings(descendants)
+	return RoleHierarchy{Role: role, Parents: direct, Ancestors: ancestors, Descendants: descendants}, nil
+}
+
+// AddRoleParent: role inherits the permissions of parent (g, <role>, <parent>).
+// Both roles must exist, and parent must not already inherit from role.
+func (r *roleRepository) AddRoleParent(c *gin.Context, role, parent string) error {
+	role, parent = strings.TrimSpace(role), strings.TrimSpace(parent)
+	if role == "" || parent == "" {
+		return errors.New("role and parent required")
+	}
+	if !r.roleExists(c, role) || !r.roleExists(c, parent) {
+		return ErrRoleNotFound
+	}
+	if err := r.addRoleParent(role, parent); err != nil {
+		return err
+	}
+	return savePolicy(r.target())
+}
+
+// addRoleParent checks for cycles and adds the line under the enforcer's
+// write lock, so two concurrent additions (A->B, B->A) cannot both pass
+func (r *roleRepository) addRoleParent(role, parent string) error {
+	e := r.target()
+	e.GetLock().Lock()
+	defer e.GetLock().Unlock()
+
+	rules, err := e.Enforcer.GetGroupingPolicy()
+	if err != nil {
+		return err
+	}
+	parents, _ := roleInheritance(rules)
+	if containsRole(parents[role], parent) {
+		return ErrRoleParentExists
+	}
+	if role == parent || containsRole(walkRoles(parents, parent), role) {
+		return ErrRoleInheritanceCycle
+	}
+	_, err = e.Enforcer.AddGroupingPolicy(role, parent)
+	return err
+}
+
+// RemoveRoleParent: role no longer inherits directly from parent
+func (r *roleRepository) RemoveRoleParent(c *gin.Context, role, parent string) error {
+	role, parent = strings.TrimSpace(role), strings.TrimSpace(parent)
+	if role == "" || parent == "" {
+		return errors.New("role and parent required")
+	}
+	ok, err := r.target().RemoveGroupingPolicy(role, parent)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRoleParentNotFound
+	}
+	return savePolicy(r.target())
+}
+
+// inheritance reads the g lines as parents and children of each role
+func (r *roleRepository) inheritance() (parents, children map[string][]string, err error) {
+	rules, err := r.target().GetGroupingPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+	parents, children = roleInheritance(rules)
+	return parents, children, nil
+}
+
+func roleInheritance(rules [][]string) (parents, children map[string][]string) {
+	parents, children = map[string][]string{}, map[string][]string{}
+	for _, g := range rules {
+		if len(g) < 2 {
+			continue
+		}
+		parents[g[0]] = append(parents[g[0]], g[1])
+		children[g[1]] = append(children[g[1]], g[0])
+	}
+	return parents, children
+}
+
+// walkRoles returns the roles reachable from role over edges, nearest first,
+// without role itself
+func walkRoles(edges map[string][]string, role string) []string {
+	seen := map[string]bool{role: true}
+	res := []string{}
+	queue := []string{role}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, to := range edges[next] {
+			if !seen[to] {
+				seen[to] = true
+				res = append(res, to)
+				queue = append(queue, to)
+			}
+		}
+	}
+	return res
+}
+
+// rolePolicies converts permissions to policy lines of the role
+func rolePolicies(role string, perms []RolePermission) [][]string {
+	rules := make([][]string, 0, len(perms))
+	for _, pm := range perms {
+		rules = append(rules, []string{role, pm.Resource, pm.Action})
+	}
+	return rules
+}
+
+// diffPermissions returns the permissions of a that are not in b, without duplicates
+func diffPermissions(a, b []RolePermission) []RolePermission {
+	seen := map[RolePermission]bool{}
+	for _, pm := range b {
+		seen[pm] = true
+	}
+	diff := []RolePermission{}
+	for _, pm := range a {
+		if !seen[pm] {
+			seen[pm] = true
+			diff = append(diff, pm)
+		}
+	}
+	return diff
 }
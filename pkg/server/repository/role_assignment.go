@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleAssignmentNotFound = errors.New("role is not assigned to the user")
+	ErrRoleAlreadyAssigned    = errors.New("role is already assigned to the user")
+)
+
+// RoleAssignmentRepository stores the global roles granted to users through the
+// API, in addition to the role derived from admin.emails or the directory.
+type RoleAssignmentRepository interface {
+	ListRoleAssignments(c *gin.Context, userUUID string) ([]model.RoleAssignments, error)
+	GrantRole(c *gin.Context, assignment model.RoleAssignments) (*model.RoleAssignments, error)
+	RevokeRole(c *gin.Context, userUUID, role string) error
+}
+
+type roleAssignmentRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// ListRoleAssignments returns the roles granted to a user, oldest first
+func (rcvr roleAssignmentRepository) ListRoleAssignments(c *gin.Context, userUUID string) ([]model.RoleAssignments, error) {
+	return listRoleAssignments(rcvr.BaseConfig.DBConnection, userUUID)
+}
+
+// GrantRole assigns a role to a user. A role can only be assigned once.
+func (rcvr roleAssignmentRepository) GrantRole(c *gin.Context, assignment model.RoleAssignments) (*model.RoleAssignments, error) {
+	now := time.Now()
+	assignment.ID = 0
+	assignment.UUID = uuid.New().String()
+	assignment.CreatedAt = &now
+	if err := rcvr.BaseConfig.DBConnection.Create(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrRoleAlreadyAssigned
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// RevokeRole removes a role from a user
+func (rcvr roleAssignmentRepository) RevokeRole(c *gin.Context, userUUID, role string) error {
+	result := rcvr.BaseConfig.DBConnection.Where("user_uuid = ? AND role = ?", userUUID, role).Delete(&model.RoleAssignments{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRoleAssignmentNotFound
+	}
+	return nil
+}
+
+func listRoleAssignments(db *gorm.DB, userUUID string) ([]model.RoleAssignments, error) {
+	assignments := []model.RoleAssignments{}
+	if db == nil {
+		return assignments, nil
+	}
+	if err := db.Where("user_uuid = ?", userUUID).Order("id").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// NewRoleAssignmentRepository creates the role assignment repository
+func NewRoleAssignmentRepository(conf config.BaseConfig) RoleAssignmentRepository {
+	return roleAssignmentRepository{BaseConfig: conf}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/ryo-arima/locky/pkg/config"
+)
+
+const (
+	// DefaultSCIMMemberRole is the group role of members added through SCIM
+	DefaultSCIMMemberRole = "member"
+	// DefaultSCIMMaxFilterScan bounds the rows examined for a filter the database cannot evaluate
+	DefaultSCIMMaxFilterScan = 10000
+)
+
+// ErrSCIMInvalidToken means the bearer token is not a configured SCIM token
+var ErrSCIMInvalidToken = errors.New("invalid scim token")
+
+// SCIMRepository authenticates provisioning clients of the SCIM API.
+type SCIMRepository interface {
+	GetConfig() config.SCIM
+	AuthenticateToken(token string) (*config.SCIMToken, error)
+}
+
+type scimRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// GetConfig returns the scim settings with defaults applied
+func (rcvr scimRepository) GetConfig() config.SCIM {
+	conf := rcvr.BaseConfig.YamlConfig.Application.Server.SCIM
+	if conf.MemberRole == "" {
+		conf.MemberRole = DefaultSCIMMemberRole
+	}
+	if conf.MaxFilterScan <= 0 {
+		conf.MaxFilterScan = DefaultSCIMMaxFilterScan
+	}
+	return conf
+}
+
+// AuthenticateToken returns the configured token matching the bearer token.
+// Tokens are compared by SHA-256 hash in constant time.
+func (rcvr scimRepository) AuthenticateToken(token string) (*config.SCIMToken, error) {
+	if token == "" {
+		return nil, ErrSCIMInvalidToken
+	}
+	sum := sha256.Sum256([]byte(token))
+	for _, configured := range rcvr.BaseConfig.YamlConfig.Application.Server.SCIM.Tokens {
+		expected, err := hex.DecodeString(strings.TrimSpace(configured.TokenSHA256))
+		if err != nil || len(expected) != sha256.Size {
+			continue
+		}
+		if subtle.ConstantTimeCompare(expected, sum[:]) == 1 {
+			matched := configured
+			return &matched, nil
+		}
+	}
+	return nil, ErrSCIMInvalidToken
+}
+
+// NewSCIMRepository creates the SCIM client repository
+func NewSCIMRepository(conf config.BaseConfig) SCIMRepository {
+	return &scimRepository{BaseConfig: conf}
+}
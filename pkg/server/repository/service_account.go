@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ServiceAccountClientIDPrefix marks client IDs issued to service accounts
+const ServiceAccountClientIDPrefix = "sa-"
+
+var (
+	ErrServiceAccountNotFound           = errors.New("service account not found")
+	ErrServiceAccountCredentialNotFound = errors.New("service account credential not found")
+	ErrServiceAccountInvalidClient      = errors.New("invalid client credentials")
+)
+
+// ServiceAccountRepository stores service accounts and their client credentials.
+// Secrets are only kept as bcrypt hashes; the plain value is returned once by RotateSecret.
+type ServiceAccountRepository interface {
+	ListServiceAccounts(c *gin.Context) ([]model.ServiceAccounts, error)
+	GetServiceAccount(c *gin.Context, idOrUUID string) (model.ServiceAccounts, error)
+	CreateServiceAccount(c *gin.Context, sa *model.ServiceAccounts) error
+	UpdateServiceAccount(c *gin.Context, sa *model.ServiceAccounts) error
+	DeleteServiceAccount(c *gin.Context, saUUID string) error
+	ListCredentials(c *gin.Context, saUUID string) ([]model.ServiceAccountCredentials, error)
+	RotateSecret(c *gin.Context, saUUID string, grace time.Duration) (*model.ServiceAccountCredentials, string, error)
+	DeleteCredential(c *gin.Context, saUUID, clientID string) error
+	Authenticate(c *gin.Context, clientID, clientSecret string) (*model.ServiceAccounts, error)
+}
+
+type serviceAccountRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// ListServiceAccounts returns all service accounts that are not deleted
+func (rcvr serviceAccountRepository) ListServiceAccounts(c *gin.Context) ([]model.ServiceAccounts, error) {
+	var list []model.ServiceAccounts
+	if err := rcvr.BaseConfig.DBConnection.Where("deleted_at IS NULL").Order("id").Find(&list).Error; err != nil {
+		return []model.ServiceAccounts{}, err
+	}
+	return list, nil
+}
+
+// GetServiceAccount looks up a service account by numeric ID or UUID
+func (rcvr serviceAccountRepository) GetServiceAccount(c *gin.Context, idOrUUID string) (model.ServiceAccounts, error) {
+	var sa model.ServiceAccounts
+	q := rcvr.BaseConfig.DBConnection.Where("deleted_at IS NULL")
+	if _, err := uuid.Parse(idOrUUID); err == nil {
+		q = q.Where("uuid = ?", idOrUUID)
+	} else {
+		q = q.Where("id = ?", idOrUUID)
+	}
+	if err := q.First(&sa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ServiceAccounts{}, ErrServiceAccountNotFound
+		}
+		return model.ServiceAccounts{}, err
+	}
+	return sa, nil
+}
+
+func (rcvr serviceAccountRepository) CreateServiceAccount(c *gin.Context, sa *model.ServiceAccounts) error {
+	if sa == nil {
+		return errors.New("service account is nil")
+	}
+	if sa.UUID == "" {
+		sa.UUID = uuid.New().String()
+	}
+	return rcvr.BaseConfig.DBConnection.Create(sa).Error
+}
+
+func (rcvr serviceAccountRepository) UpdateServiceAccount(c *gin.Context, sa *model.ServiceAccounts) error {
+	if sa == nil {
+		return errors.New("service account is nil")
+	}
+	return rcvr.BaseConfig.DBConnection.Model(&model.ServiceAccounts{}).Where("id = ?", sa.ID).Updates(sa).Error
+}
+
+// DeleteServiceAccount soft-deletes the service account and drops its credentials
+func (rcvr serviceAccountRepository) DeleteServiceAccount(c *gin.Context, saUUID string) error {
+	return rcvr.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("service_account_uuid = ?", saUUID).Delete(&model.ServiceAccountCredentials{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.ServiceAccounts{}).Where("uuid = ?", saUUID).Update("deleted_at", time.Now()).Error
+	})
+}
+
+// ListCredentials returns the credentials of a service account, newest first
+func (rcvr serviceAccountRepository) ListCredentials(c *gin.Context, saUUID string) ([]model.ServiceAccountCredentials, error) {
+	var list []model.ServiceAccountCredentials
+	if err := rcvr.BaseConfig.DBConnection.Where("service_account_uuid = ?", saUUID).Order("id DESC").Find(&list).Error; err != nil {
+		return []model.ServiceAccountCredentials{}, err
+	}
+	return list, nil
+}
+
+// RotateSecret issues a new client ID / secret pair. Credentials that were still
+// valid expire after grace (immediately when grace is zero), so callers can roll
+// the new secret out before the old one stops working.
+func (rcvr serviceAccountRepository) RotateSecret(c *gin.Context, saUUID string, grace time.Duration) (*model.ServiceAccountCredentials, string, error) {
+	idBuf := make([]byte, 8)
+	secretBuf := make([]byte, 32)
+	if _, err := rand.Read(idBuf); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secretBuf); err != nil {
+		return nil, "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(secretBuf)
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(grace)
+	cred := &model.ServiceAccountCredentials{
+		ServiceAccountUUID: saUUID,
+		ClientID:           ServiceAccountClientIDPrefix + hex.EncodeToString(idBuf),
+		SecretHash:         string(hash),
+		CreatedAt:          &now,
+	}
+	err = rcvr.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ServiceAccountCredentials{}).
+			Where("service_account_uuid = ? AND (expires_at IS NULL OR expires_at > ?)", saUUID, expiresAt).
+			Update("expires_at", expiresAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(cred).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return cred, secret, nil
+}
+
+// DeleteCredential revokes one client ID of a service account
+func (rcvr serviceAccountRepository) DeleteCredential(c *gin.Context, saUUID, clientID string) error {
+	res := rcvr.BaseConfig.DBConnection.Where("service_account_uuid = ? AND client_id = ?", saUUID, clientID).Delete(&model.ServiceAccountCredentials{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrServiceAccountCredentialNotFound
+	}
+	return nil
+}
+
+// Authenticate verifies a client ID / secret pair and returns its service account
+func (rcvr serviceAccountRepository) Authenticate(c *gin.Context, clientID, clientSecret string) (*model.ServiceAccounts, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrServiceAccountInvalidClient
+	}
+	var cred model.ServiceAccountCredentials
+	if err := rcvr.BaseConfig.DBConnection.Where("client_id = ?", clientID).First(&cred).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountInvalidClient
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if cred.ExpiresAt != nil && !cred.ExpiresAt.After(now) {
+		return nil, ErrServiceAccountInvalidClient
+	}
+	if bcrypt.CompareHashAndPassword([]byte(cred.SecretHash), []byte(clientSecret)) != nil {
+		return nil, ErrServiceAccountInvalidClient
+	}
+
+	var sa model.ServiceAccounts
+	if err := rcvr.BaseConfig.DBConnection.Where("uuid = ? AND deleted_at IS NULL", cred.ServiceAccountUUID).First(&sa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountInvalidClient
+		}
+		return nil, err
+	}
+	_ = rcvr.BaseConfig.DBConnection.Model(&model.ServiceAccountCredentials{}).Where("id = ?", cred.ID).Update("last_used_at", now).Error
+	return &sa, nil
+}
+
+// NewServiceAccountRepository creates a new service account repository
+func NewServiceAccountRepository(conf config.BaseConfig) ServiceAccountRepository {
+	return &serviceAccountRepository{BaseConfig: conf}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository lists and revokes login sessions.
+// A session is a refresh token family recorded by CommonRepository.
+type SessionRepository interface {
+	ListSessions(ctx context.Context, userUUID string) ([]model.Sessions, error)
+	RevokeSession(ctx context.Context, userUUID, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userUUID, exceptSessionID string) (int, error)
+}
+
+type sessionRepository struct {
+	CommonRepository CommonRepository
+	RedisClient      *redis.Client
+}
+
+// ListSessions returns the active sessions of a user, newest first.
+// Expired or revoked entries found in the index are cleaned up on the way.
+func (rcvr sessionRepository) ListSessions(ctx context.Context, userUUID string) ([]model.Sessions, error) {
+	sessions := []model.Sessions{}
+	if rcvr.RedisClient == nil {
+		return sessions, nil
+	}
+
+	ids, err := rcvr.RedisClient.ZRange(ctx, userSessionsKey(userUUID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var stale []interface{}
+	for _, id := range ids {
+		fields, err := rcvr.RedisClient.HGetAll(ctx, tokenFamilyKey(id)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session: %w", err)
+		}
+		if len(fields) == 0 || fields["status"] == TokenFamilyStatusRevoked || fields["user_uuid"] != userUUID {
+			stale = append(stale, id)
+			continue
+		}
+		sessions = append(sessions, sessionFromFields(id, fields))
+	}
+	if len(stale) > 0 {
+		_ = rcvr.RedisClient.ZRem(ctx, userSessionsKey(userUUID), stale...).Err()
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].IssuedAt > sessions[j].IssuedAt })
+	return sessions, nil
+}
+
+// RevokeSession revokes one session owned by the user
+func (rcvr sessionRepository) RevokeSession(ctx context.Context, userUUID, sessionID string) error {
+	if rcvr.RedisClient == nil {
+		return ErrSessionNotFound
+	}
+	if _, err := rcvr.RedisClient.ZScore(ctx, userSessionsKey(userUUID), sessionID).Result(); err != nil {
+		if err == redis.Nil {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+	if err := rcvr.CommonRepository.RevokeTokenFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	return rcvr.RedisClient.ZRem(ctx, userSessionsKey(userUUID), sessionID).Err()
+}
+
+// RevokeAllSessions revokes every session of the user ("log out everywhere").
+// exceptSessionID, when set, keeps that session alive.
+func (rcvr sessionRepository) RevokeAllSessions(ctx context.Context, userUUID, exceptSessionID string) (int, error) {
+	if rcvr.RedisClient == nil {
+		return 0, nil
+	}
+	ids, err := rcvr.RedisClient.ZRange(ctx, userSessionsKey(userUUID), 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	revoked := 0
+	for _, id := range ids {
+		if id == exceptSessionID {
+			continue
+		}
+		if err := rcvr.RevokeSession(ctx, userUUID, id); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+func sessionFromFields(id string, fields map[string]string) model.Sessions {
+	issuedAt, _ := strconv.ParseInt(fields["issued_at"], 10, 64)
+	lastSeenAt, _ := strconv.ParseInt(fields["last_seen_at"], 10, 64)
+	return model.Sessions{
+		ID:         id,
+		UserUUID:   fields["user_uuid"],
+		AccessJti:  fields["access_jti"],
+		RefreshJti: fields["refresh_jti"],
+		UserAgent:  fields["user_agent"],
+		IPAddress:  fields["ip_address"],
+		IssuedAt:   issuedAt,
+		LastSeenAt: lastSeenAt,
+		Status:     fields["status"],
+	}
+}
+
+func NewSessionRepository(commonRepository CommonRepository, redisClient *redis.Client) SessionRepository {
+	return &sessionRepository{CommonRepository: commonRepository, RedisClient: redisClient}
+}
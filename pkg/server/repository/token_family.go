@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+// Token lifetimes
+const (
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// Token family status
+const (
+	TokenFamilyStatusActive  = "active"
+	TokenFamilyStatusRevoked = "revoked"
+)
+
+var (
+	ErrInvalidTokenUse     = errors.New("invalid token use")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrTokenFamilyRevoked  = errors.New("token family has been revoked")
+	ErrTokenGeneration     = errors.New("failed to generate tokens")
+	ErrTokenClientMismatch = errors.New("refresh token was issued to another client")
+)
+
+// helper: token family key builders
+func tokenFamilyKey(familyID string) string {
+	return "auth:family:" + familyID
+}
+
+func tokenFamilyJTIsKey(familyID string) string {
+	return "auth:family:" + familyID + ":jtis"
+}
+
+func refreshTokenUsedKey(jti string) string {
+	return "auth:refresh:used:" + jti
+}
+
+func userSessionsKey(userUUID string) string {
+	return "auth:user:" + userUUID + ":sessions"
+}
+
+// GenerateTokenPair creates both access and refresh tokens in a new token family.
+// The first role is the primary role of the token (role claim); all of them
+// are carried in the roles claim.
+func (cr *commonRepository) GenerateTokenPair(userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error) {
+	base := model.JWTClaims{
+		UserID: userID,
+		UUID:   userUUID,
+		Email:  email,
+		Name:   name,
+	}
+	if len(roles) > 0 {
+		base.Role = roles[0]
+		base.Roles = roles
+	}
+	return cr.issueTokenPair(context.Background(), uuid.New().String(), base)
+}
+
+// GenerateAuthorizedTokenPair creates a user token pair issued to an OAuth client
+// (OIDC authorization code grant). The tokens carry the client as azp and the
+// family records it, so only that client can refresh or revoke them.
+func (cr *commonRepository) GenerateAuthorizedTokenPair(clientID string, userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error) {
+	base := model.JWTClaims{
+		UserID:          userID,
+		UUID:            userUUID,
+		Email:           email,
+		Name:            name,
+		AuthorizedParty: clientID,
+	}
+	if len(roles) > 0 {
+		base.Role = roles[0]
+		base.Roles = roles
+	}
+	return cr.issueTokenPair(context.Background(), uuid.New().String(), base)
+}
+
+// GenerateClientTokenPair creates a token pair for a non-human principal (service account).
+// The tokens carry the client_id claim and no user ID or email.
+func (cr *commonRepository) GenerateClientTokenPair(clientID, subjectUUID, name, role string) (*model.TokenPair, error) {
+	return cr.issueTokenPair(context.Background(), uuid.New().String(), model.JWTClaims{
+		UUID:     subjectUUID,
+		Name:     name,
+		Role:     role,
+		ClientID: clientID,
+	})
+}
+
+// RotateRefreshToken exchanges a refresh token for a new pair in the same family.
+// clientID is the authenticated OAuth client redeeming it, empty for the
+// first-party refresh endpoint; it must be the client the token was issued to.
+// Every refresh token can be used once; presenting an already rotated token
+// revokes the whole family (all access and refresh tokens issued in it).
+// User tokens are reissued from the current user record and roles; a family
+// whose user was deleted or deactivated is revoked.
+func (cr *commonRepository) RotateRefreshToken(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error) {
+	claims, err := cr.ValidateJWTToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != model.TokenUseRefresh {
+		return nil, ErrInvalidTokenUse
+	}
+	// Checked before the token is consumed, so a foreign client cannot burn it
+	if claims.AuthorizedParty != clientID {
+		return nil, ErrTokenClientMismatch
+	}
+
+	invalidated, err := cr.IsTokenInvalidated(ctx, claims.Jti)
+	if err != nil {
+		return nil, err
+	}
+	if invalidated {
+		return nil, errors.New("token has been invalidated")
+	}
+
+	familyID := claims.FamilyID
+	if familyID == "" {
+		familyID = uuid.New().String()
+	} else if cr.RedisClient != nil {
+		family, err := cr.RedisClient.HMGet(ctx, tokenFamilyKey(familyID), "status", "client_id").Result()
+		if err != nil {
+			return nil, fmt.Errorf("error checking token family in redis: %w", err)
+		}
+		status, _ := family[0].(string)
+		if status == "" || status == TokenFamilyStatusRevoked {
+			return nil, ErrTokenFamilyRevoked
+		}
+		if familyClient, _ := family[1].(string); familyClient != clientID {
+			return nil, ErrTokenClientMismatch
+		}
+	}
+
+	base := model.JWTClaims{
+		UserID:          claims.UserID,
+		UUID:            claims.UUID,
+		Email:           claims.Email,
+		Name:            claims.Name,
+		Role:            claims.Role,
+		Roles:           claims.Roles,
+		ClientID:        claims.ClientID,
+		AuthorizedParty: claims.AuthorizedParty,
+	}
+	if db := cr.BaseConfig.DBConnection; db != nil && claims.ClientID == "" {
+		var user model.Users
+		if err := db.WithContext(ctx).Where("uuid = ? AND deleted_at IS NULL", claims.UUID).First(&user).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err := cr.RevokeTokenFamily(ctx, familyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrTokenFamilyRevoked
+		}
+		base.UserID = user.ID
+		base.Email = user.Email
+		base.Name = user.Name
+		base.Roles = cr.LoadUserRoles(user)
+		base.Role = base.Roles[0]
+	}
+
+	if claims.FamilyID != "" && cr.RedisClient != nil {
+		// Mark this refresh token as consumed; a second attempt means it leaked
+		ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+		if ttl <= 0 {
+			ttl = time.Minute
+		}
+		first, err := cr.RedisClient.SetNX(ctx, refreshTokenUsedKey(claims.Jti), familyID, ttl).Result()
+		if err != nil {
+			return nil, fmt.Errorf("error marking refresh token as used: %w", err)
+		}
+		if !first {
+			if err := cr.RevokeTokenFamily(ctx, familyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+	}
+
+	return cr.issueTokenPair(ctx, familyID, base)
+}
+
+// RevokeTokenFamily denylists every token issued in the family and blocks further rotation
+func (cr *commonRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	if cr.RedisClient == nil || familyID == "" {
+		return nil
+	}
+
+	jtis, err := cr.RedisClient.SMembers(ctx, tokenFamilyJTIsKey(familyID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load token family: %w", err)
+	}
+
+	pipe := cr.RedisClient.TxPipeline()
+	pipe.HSet(ctx, tokenFamilyKey(familyID), "status", TokenFamilyStatusRevoked)
+	pipe.Expire(ctx, tokenFamilyKey(familyID), RefreshTokenTTL)
+	for _, jti := range jtis {
+		pipe.Set(ctx, jti, "invalidated", RefreshTokenTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+// issueTokenPair signs an access/refresh pair for the principal described by
+// base (user_id, uuid, email, name, role, client_id, azp) and records both JTIs in the family
+func (cr *commonRepository) issueTokenPair(ctx context.Context, familyID string, base model.JWTClaims) (*model.TokenPair, error) {
+	now := time.Now()
+	accessTokenExpiry := now.Add(AccessTokenTTL).Unix()
+	refreshTokenExpiry := now.Add(RefreshTokenTTL).Unix()
+
+	// Create access token claims
+	accessClaims := model.JWTClaims{
+		Jti:             uuid.New().String(),
+		Subject:         base.UUID,
+		UserID:          base.UserID,
+		UUID:            base.UUID,
+		Email:           base.Email,
+		Name:            base.Name,
+		Role:            base.Role,
+		Roles:           base.Roles,
+		ClientID:        base.ClientID,
+		AuthorizedParty: base.AuthorizedParty,
+		TokenUse:        model.TokenUseAccess,
+		FamilyID:        familyID,
+		IssuedAt:        now.Unix(),
+		ExpiresAt:       accessTokenExpiry,
+	}
+
+	// Generate access token
+	accessToken, err := cr.GenerateJWTToken(accessClaims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: access token: %v", ErrTokenGeneration, err)
+	}
+
+	// Create refresh token claims (longer expiry, different JTI)
+	refreshClaims := accessClaims
+	refreshClaims.Jti = uuid.New().String()
+	refreshClaims.TokenUse = model.TokenUseRefresh
+	refreshClaims.ExpiresAt = refreshTokenExpiry
+
+	// Generate refresh token
+	refreshToken, err := cr.GenerateJWTToken(refreshClaims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: refresh token: %v", ErrTokenGeneration, err)
+	}
+
+	if err := cr.recordTokenFamily(ctx, familyID, base.UUID, base.AuthorizedParty, accessClaims.Jti, refreshClaims.Jti); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenGeneration, err)
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    accessTokenExpiry - now.Unix(),
+	}, nil
+}
+
+// recordTokenFamily stores the current pair of the family (no-op without Redis).
+// clientID is the OAuth client the family was issued to, empty for first-party logins.
+func (cr *commonRepository) recordTokenFamily(ctx context.Context, familyID, userUUID, clientID, accessJti, refreshJti string) error {
+	if cr.RedisClient == nil {
+		return nil
+	}
+	now := time.Now().Unix()
+	pipe := cr.RedisClient.TxPipeline()
+	pipe.HSet(ctx, tokenFamilyKey(familyID), map[string]interface{}{
+		"user_uuid":   userUUID,
+		"access_jti":  accessJti,
+		"refresh_jti": refreshJti,
+	})
+	// Never flip a revoked family back to active
+	pipe.HSetNX(ctx, tokenFamilyKey(familyID), "status", TokenFamilyStatusActive)
+	pipe.HSetNX(ctx, tokenFamilyKey(familyID), "issued_at", now)
+	if clientID != "" {
+		pipe.HSetNX(ctx, tokenFamilyKey(familyID), "client_id", clientID)
+	}
+	pipe.Expire(ctx, tokenFamilyKey(familyID), RefreshTokenTTL)
+	pipe.SAdd(ctx, tokenFamilyJTIsKey(familyID), accessJti, refreshJti)
+	pipe.Expire(ctx, tokenFamilyJTIsKey(familyID), RefreshTokenTTL)
+	// Per-user session index (score = login time)
+	pipe.ZAddNX(ctx, userSessionsKey(userUUID), &redis.Z{Score: float64(now), Member: familyID})
+	pipe.Expire(ctx, userSessionsKey(userUUID), RefreshTokenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record token family: %w", err)
+	}
+	return nil
+}
+
+// TouchSession records the client and last-seen time of a session (token family).
+// Empty userAgent / ipAddress keep the stored values. Unknown sessions are ignored.
+func (cr *commonRepository) TouchSession(ctx context.Context, familyID, userAgent, ipAddress string) error {
+	if cr.RedisClient == nil || familyID == "" {
+		return nil
+	}
+	exists, err := cr.RedisClient.Exists(ctx, tokenFamilyKey(familyID)).Result()
+	if err != nil {
+		return fmt.Errorf("error checking session in redis: %w", err)
+	}
+	if exists == 0 {
+		return nil
+	}
+	fields := map[string]interface{}{"last_seen_at": time.Now().Unix()}
+	if userAgent != "" {
+		fields["user_agent"] = userAgent
+	}
+	if ipAddress != "" {
+		fields["ip_address"] = ipAddress
+	}
+	if err := cr.RedisClient.HSet(ctx, tokenFamilyKey(familyID), fields).Err(); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
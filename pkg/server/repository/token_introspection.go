@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+)
+
+var ErrTokenRevocationUnavailable = errors.New("token revocation requires redis")
+
+// TokenIntrospectionRepository reports whether a token is currently usable
+// (RFC 7662) and revokes tokens on request of their client (RFC 7009).
+// Access, refresh and personal access tokens are supported.
+type TokenIntrospectionRepository interface {
+	Introspect(c *gin.Context, token string) (*model.JWTClaims, error)
+	Revoke(c *gin.Context, claims model.JWTClaims) error
+}
+
+type tokenIntrospectionRepository struct {
+	CommonRepository              CommonRepository
+	PersonalAccessTokenRepository PersonalAccessTokenRepository
+	RedisClient                   *redis.Client
+}
+
+// Introspect returns the claims of an active token, or nil when the token is
+// invalid, expired, revoked, already rotated (refresh tokens) or not an access,
+// refresh or personal access token
+func (rcvr tokenIntrospectionRepository) Introspect(c *gin.Context, token string) (*model.JWTClaims, error) {
+	ctx := c.Request.Context()
+	if IsPersonalAccessToken(token) {
+		claims, err := rcvr.CommonRepository.ValidatePersonalAccessToken(ctx, token)
+		if errors.Is(err, ErrPersonalAccessTokenInvalid) {
+			return nil, nil
+		}
+		return claims, err
+	}
+
+	claims, err := rcvr.CommonRepository.ValidateJWTToken(token)
+	if err != nil {
+		return nil, nil
+	}
+	switch claims.TokenUse {
+	case "", model.TokenUseAccess, model.TokenUseRefresh:
+	default:
+		return nil, nil
+	}
+	invalidated, err := rcvr.CommonRepository.IsTokenInvalidated(ctx, claims.Jti)
+	if err != nil {
+		return nil, err
+	}
+	if invalidated {
+		return nil, nil
+	}
+	if claims.TokenUse == model.TokenUseRefresh {
+		active, err := rcvr.refreshTokenActive(ctx, claims)
+		if err != nil || !active {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// refreshTokenActive checks that the family of a refresh token has not been
+// revoked and that the token has not been rotated yet
+func (rcvr tokenIntrospectionRepository) refreshTokenActive(ctx context.Context, claims *model.JWTClaims) (bool, error) {
+	if rcvr.RedisClient == nil || claims.FamilyID == "" {
+		return true, nil
+	}
+	status, err := rcvr.RedisClient.HGet(ctx, tokenFamilyKey(claims.FamilyID), "status").Result()
+	if err == redis.Nil || status == TokenFamilyStatusRevoked {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking token family in redis: %w", err)
+	}
+	used, err := rcvr.RedisClient.Exists(ctx, refreshTokenUsedKey(claims.Jti)).Result()
+	if err != nil {
+		return false, fmt.Errorf("error checking refresh token in redis: %w", err)
+	}
+	return used == 0, nil
+}
+
+// Revoke invalidates an active token. Revoking a refresh token revokes its whole
+// family, including the access tokens issued with it; revoking an access token
+// only denylists that token. Personal access tokens are deleted.
+func (rcvr tokenIntrospectionRepository) Revoke(c *gin.Context, claims model.JWTClaims) error {
+	if claims.TokenUse == model.TokenUsePAT {
+		err := rcvr.PersonalAccessTokenRepository.RevokeToken(c, claims.UUID, strings.TrimPrefix(claims.Jti, "pat:"))
+		if errors.Is(err, ErrPersonalAccessTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	if rcvr.RedisClient == nil {
+		return ErrTokenRevocationUnavailable
+	}
+	ctx := c.Request.Context()
+	if claims.TokenUse == model.TokenUseRefresh && claims.FamilyID != "" {
+		return rcvr.CommonRepository.RevokeTokenFamily(ctx, claims.FamilyID)
+	}
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	if err := rcvr.RedisClient.Set(ctx, claims.Jti, "invalidated", ttl).Err(); err != nil {
+		return fmt.Errorf("failed to add token to denylist: %w", err)
+	}
+	return nil
+}
+
+func NewTokenIntrospectionRepository(commonRepository CommonRepository, personalAccessTokenRepository PersonalAccessTokenRepository, redisClient *redis.Client) TokenIntrospectionRepository {
+	return tokenIntrospectionRepository{
+		CommonRepository:              commonRepository,
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+		RedisClient:                   redisClient,
+	}
+}
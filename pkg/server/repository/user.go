@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -8,12 +9,19 @@ import (
 	"github.com/ryo-arima/locky/pkg/config"
 	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailAlreadyExists = errors.New("email already exists")
 )
 
 type UserRepository interface {
 	GetUsers(c *gin.Context) []model.Users
-	CreateUser(c *gin.Context, user model.Users) model.Users
-	UpdateUser(c *gin.Context, user model.Users) model.Users
+	GetUserByEmail(c *gin.Context, email string) (model.Users, error)
+	CreateUser(c *gin.Context, user model.Users) (model.Users, error)
+	UpdateUser(c *gin.Context, user model.Users) (model.Users, error)
 	DeleteUser(c *gin.Context, user model.Users) model.Users
 	ListUsers(c *gin.Context, filter UserQueryFilter) ([]model.Users, error)
 	CountUsers(c *gin.Context, filter UserQueryFilter) (int64, error)
@@ -35,32 +43,66 @@ func (rcvr userRepository) GetUsers(c *gin.Context) []model.Users {
 	return users
 }
 
-func (rcvr userRepository) CreateUser(c *gin.Context, user model.Users) model.Users {
+// NormalizeEmail returns the form of an email address used for lookups and
+// for the unique index: surrounding spaces removed and lower-cased
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// GetUserByEmail finds a user by email, ignoring case, through the unique
+// index on email_normalized. Deactivated users are returned as well.
+func (rcvr userRepository) GetUserByEmail(c *gin.Context, email string) (model.Users, error) {
+	db := rcvr.BaseConfig.DBConnection
+	if db == nil {
+		return model.Users{}, ErrUserNotFound
+	}
+	var user model.Users
+	if err := db.Where("email_normalized = ?", NormalizeEmail(email)).Take(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Users{}, ErrUserNotFound
+		}
+		logger.Error(code.RUGE1, c.GetString("requestID"), "Failed to get user by email: "+err.Error())
+		return model.Users{}, err
+	}
+	return user, nil
+}
+
+func (rcvr userRepository) CreateUser(c *gin.Context, user model.Users) (model.Users, error) {
 	requestID, _ := c.Get("requestID")
 	reqID := requestID.(string)
 	logger.Info(code.RUCR1, reqID, "Creating user in database: "+user.Email)
 
+	user.EmailNormalized = NormalizeEmail(user.Email)
 	if err := rcvr.BaseConfig.DBConnection.Create(&user).Error; err != nil {
 		logger.Error(code.RUCR1, reqID, "Failed to create user: "+err.Error())
-		return model.Users{}
+		return model.Users{}, userWriteError(err)
 	}
 
 	logger.Info(code.RUCR1, reqID, "User created in database: "+user.UUID)
-	return user
+	return user, nil
 }
 
-func (rcvr userRepository) UpdateUser(c *gin.Context, user model.Users) model.Users {
+func (rcvr userRepository) UpdateUser(c *gin.Context, user model.Users) (model.Users, error) {
 	requestID, _ := c.Get("requestID")
 	reqID := requestID.(string)
 	logger.Info(code.RUUP1, reqID, "Updating user in database: "+user.UUID)
 
+	user.EmailNormalized = NormalizeEmail(user.Email)
 	if err := rcvr.BaseConfig.DBConnection.Save(&user).Error; err != nil {
 		logger.Error(code.RUUP1, reqID, "Failed to update user: "+err.Error())
-		return model.Users{}
+		return model.Users{}, userWriteError(err)
 	}
 
 	logger.Info(code.RUUP1, reqID, "User updated in database: "+user.UUID)
-	return user
+	return user, nil
+}
+
+// userWriteError reports a violation of the unique email index as ErrEmailAlreadyExists
+func userWriteError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrEmailAlreadyExists
+	}
+	return err
 }
 
 func (rcvr userRepository) DeleteUser(c *gin.Context, user model.Users) model.Users {
@@ -84,7 +126,7 @@ type UserQueryFilter struct {
 	Name        *string // exact match
 	NamePrefix  *string
 	NameLike    *string
-	Email       *string // exact match
+	Email       *string // exact match, ignoring case
 	EmailPrefix *string
 	EmailLike   *string
 	Limit       int
@@ -129,7 +171,7 @@ func (rcvr userRepository) ListUsers(c *gin.Context, filter UserQueryFilter) ([]
 		q = q.Where("name LIKE ?", "%"+*filter.NameLike+"%")
 	}
 	if filter.Email != nil {
-		q = q.Where("email = ?", *filter.Email)
+		q = q.Where("email_normalized = ?", NormalizeEmail(*filter.Email))
 	}
 	if filter.EmailPrefix != nil {
 		q = q.Where("email LIKE ?", strings.TrimRight(*filter.EmailPrefix, "%")+"%")
@@ -175,7 +217,7 @@ func (rcvr userRepository) CountUsers(c *gin.Context, filter UserQueryFilter) (i
 		q = q.Where("name LIKE ?", "%"+*filter.NameLike+"%")
 	}
 	if filter.Email != nil {
-		q = q.Where("email = ?", *filter.Email)
+		q = q.Where("email_normalized = ?", NormalizeEmail(*filter.Email))
 	}
 	if filter.EmailPrefix != nil {
 		q = q.Where("email LIKE ?", strings.TrimRight(*filter.EmailPrefix, "%")+"%")
@@ -1,11 +1,12 @@
 package server
 
 import (
+	"context"
 	"log"
 
-	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/server/controller"
 	"github.com/ryo-arima/locky/pkg/server/middleware"
 	"github.com/ryo-arima/locky/pkg/server/repository"
@@ -22,8 +23,9 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		panic(err)
 	}
 
-	// Casbin initialization: app-wide (locky) + group/resource permissions (resources)
-	appEnforcer, err := casbin.NewEnforcer("etc/casbin/locky/model.conf", "etc/casbin/locky/policy.csv")
+	// Casbin initialization: app-wide (locky) + group/resource permissions (resources),
+	// stored in policy.csv files or the casbin_rules table (casbin.storage)
+	appEnforcer, err := repository.NewEnforcer(conf, model.PolicySetApp)
 	if err != nil {
 		panic(err)
 	}
@@ -31,7 +33,7 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		log.Fatalf("failed to load app casbin policy: %v", err)
 	}
 
-	resourceEnforcer, err := casbin.NewEnforcer("etc/casbin/resources/model.conf", "etc/casbin/resources/policy.csv")
+	resourceEnforcer, err := repository.NewEnforcer(conf, model.PolicySetResources)
 	if err != nil {
 		panic(err)
 	}
@@ -39,15 +41,35 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		log.Fatalf("failed to load resource casbin policy: %v", err)
 	}
 
+	// Keep the enforcers of all server processes in step with policy changes
+	policyWatcherRepository := repository.NewPolicyWatcherRepository(conf, redisClient)
+	if err := policyWatcherRepository.Watch(context.Background(), appEnforcer, model.PolicySetApp); err != nil {
+		log.Fatalf("failed to watch app casbin policy: %v", err)
+	}
+	if err := policyWatcherRepository.Watch(context.Background(), resourceEnforcer, model.PolicySetResources); err != nil {
+		log.Fatalf("failed to watch resource casbin policy: %v", err)
+	}
+	policyControllerForPrivate := controller.NewPolicyControllerForPrivate(policyWatcherRepository)
+
 	userRepository := repository.NewUserRepository(conf)
 	commonRepository := repository.NewCommonRepository(conf, redisClient)
+	commonRepository.StartKeyRotation(context.Background())
+	authenticatorChain, err := repository.NewConfiguredAuthenticatorChain(conf, userRepository, commonRepository)
+	if err != nil {
+		log.Fatalf("failed to configure authenticators: %v", err)
+	}
 
 	// Initialize usecase layer
 	userUsecase := usecase.NewUserUsecase(userRepository)
 
-	userControllerForPublic := controller.NewUserControllerForPublic(userUsecase, commonRepository, conf)
-	userControllerForInternal := controller.NewUserControllerForInternal(userUsecase, commonRepository)
-	userControllerForPrivate := controller.NewUserControllerForPrivate(userUsecase, commonRepository)
+	emailVerificationRepository := repository.NewEmailVerificationRepository(conf, commonRepository, redisClient)
+	emailVerificationControllerForPublic := controller.NewEmailVerificationControllerForPublic(userRepository, commonRepository, emailVerificationRepository)
+
+	passwordPolicyRepository := repository.NewPasswordPolicyRepository(conf, commonRepository)
+	sessionRepository := repository.NewSessionRepository(commonRepository, redisClient)
+	userControllerForPublic := controller.NewUserControllerForPublic(userUsecase, commonRepository, emailVerificationRepository, passwordPolicyRepository, conf)
+	userControllerForInternal := controller.NewUserControllerForInternal(userUsecase, commonRepository, passwordPolicyRepository, sessionRepository)
+	userControllerForPrivate := controller.NewUserControllerForPrivate(userUsecase, commonRepository, passwordPolicyRepository, sessionRepository)
 
 	groupRepository := repository.NewGroupRepository(conf)
 	groupControllerForInternal := controller.NewGroupControllerForInternal(groupRepository, commonRepository)
@@ -56,15 +78,69 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 	memberRepository := repository.NewMemberRepository(conf)
 	memberControllerForInternal := controller.NewMemberControllerForInternal(memberRepository, commonRepository)
 	memberControllerForPrivate := controller.NewMemberControllerForPrivate(memberRepository, commonRepository)
+	groupPermissionRepository := repository.NewGroupPermissionRepository(memberRepository, resourceEnforcer)
 
 	roleRepository := repository.NewRoleRepository(appEnforcer, resourceEnforcer)
 	roleControllerForInternal := controller.NewRoleControllerForInternal(roleRepository, appEnforcer)
 	roleControllerForPrivate := controller.NewRoleControllerForPrivate(roleRepository, appEnforcer)
 
+	sessionControllerForInternal := controller.NewSessionControllerForInternal(sessionRepository)
+	sessionControllerForPrivate := controller.NewSessionControllerForPrivate(sessionRepository, userRepository)
+
+	personalAccessTokenRepository := repository.NewPersonalAccessTokenRepository(conf)
+	personalAccessTokenControllerForInternal := controller.NewPersonalAccessTokenControllerForInternal(personalAccessTokenRepository, appEnforcer)
+
+	passwordResetRepository := repository.NewPasswordResetRepository(conf, redisClient)
+	passwordControllerForPublic := controller.NewPasswordControllerForPublic(userRepository, commonRepository, passwordResetRepository, sessionRepository, personalAccessTokenRepository, passwordPolicyRepository)
+
+	serviceAccountRepository := repository.NewServiceAccountRepository(conf)
+	serviceAccountControllerForPublic := controller.NewServiceAccountControllerForPublic(serviceAccountRepository, commonRepository)
+	serviceAccountControllerForPrivate := controller.NewServiceAccountControllerForPrivate(serviceAccountRepository, sessionRepository, appEnforcer)
+
+	mfaRepository := repository.NewMFARepository(conf, redisClient)
+	mfaControllerForInternal := controller.NewMFAControllerForInternal(mfaRepository)
+	mfaControllerForPrivate := controller.NewMFAControllerForPrivate(mfaRepository, userRepository)
+
+	loginLockoutRepository := repository.NewLoginLockoutRepository(conf, redisClient)
+	loginLockoutControllerForPrivate := controller.NewLoginLockoutControllerForPrivate(loginLockoutRepository)
+
+	rateLimitRepository := repository.NewRateLimitRepository(conf, redisClient)
+
+	if err := repository.ValidateOIDCConfig(conf.YamlConfig.Application.Server); err != nil {
+		log.Fatalf("failed to configure oidc provider: %v", err)
+	}
+	oidcRepository := repository.NewOIDCRepository(conf, commonRepository, redisClient)
+	oidcControllerForPublic := controller.NewOIDCControllerForPublic(oidcRepository, userRepository, commonRepository, mfaRepository, loginLockoutRepository, authenticatorChain)
+
+	tokenIntrospectionRepository := repository.NewTokenIntrospectionRepository(commonRepository, personalAccessTokenRepository, redisClient)
+	tokenIntrospectionControllerForPublic := controller.NewTokenIntrospectionControllerForPublic(tokenIntrospectionRepository, serviceAccountRepository, oidcRepository)
+	authorizationRepository := repository.NewAuthorizationRepository(appEnforcer, resourceEnforcer, groupPermissionRepository)
+	authzControllerForInternal := controller.NewAuthzControllerForInternal(authorizationRepository, tokenIntrospectionRepository, userRepository, commonRepository, appEnforcer)
+
+	impersonationRepository := repository.NewImpersonationRepository(conf, commonRepository)
+	impersonationControllerForPrivate := controller.NewImpersonationControllerForPrivate(impersonationRepository, userRepository)
+
+	roleAssignmentRepository := repository.NewRoleAssignmentRepository(conf)
+	userRoleControllerForPrivate := controller.NewUserRoleControllerForPrivate(roleAssignmentRepository, userRepository, sessionRepository, commonRepository, appEnforcer)
+
+	identityLinkRepository := repository.NewIdentityLinkRepository(conf)
+	federationRepository := repository.NewFederationRepository(conf, redisClient)
+	federationControllerForPublic := controller.NewFederationControllerForPublic(federationRepository, identityLinkRepository, userRepository, commonRepository, mfaRepository, loginLockoutRepository)
+	federationControllerForInternal := controller.NewFederationControllerForInternal(federationRepository, identityLinkRepository, userRepository)
+
+	scimRepository := repository.NewSCIMRepository(conf)
+	scimUsecase := usecase.NewSCIMUsecase(userRepository, groupRepository, memberRepository, commonRepository, passwordPolicyRepository, sessionRepository, scimRepository)
+	scimController := controller.NewSCIMController(scimUsecase)
+
 	// CommonController for authentication endpoints
-	commonControllerForPublic := controller.NewCommonControllerForPublic(userRepository, commonRepository)
+	commonControllerForPublic := controller.NewCommonControllerForPublic(userRepository, commonRepository, mfaRepository, loginLockoutRepository, authenticatorChain)
 
 	router := gin.Default()
+	// The client IP keys rate limits and lockouts, so forwarding headers are
+	// only believed from the configured proxies
+	if err := router.SetTrustedProxies(conf.YamlConfig.Application.Server.TrustedProxies); err != nil {
+		log.Fatalf("failed to configure trusted proxies: %v", err)
+	}
 
 	// Health check endpoint (no authentication required)
 	router.GET("/health", func(c *gin.Context) {
@@ -73,6 +149,10 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 
 	loggerMW := middleware.LoggerWithConfig(conf)
 	requestIDMW := middleware.RequestID()
+	// Credential changes are refused for impersonation tokens
+	denyImpersonationMW := middleware.DenyImpersonation()
+	// Per-IP limit on guessing passwords, second factors and client secrets
+	authRateLimitMW := middleware.RateLimit(conf, rateLimitRepository, middleware.RateLimitTierAuth)
 
 	// OpenStack Keystone-style API versioning and structure
 	// v1 API with proper versioning
@@ -83,10 +163,15 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 	auth := v1.Group("/share/common/auth")
 	auth.Use(loggerMW)
 	{
-		auth.POST("/tokens", commonControllerForPublic.Login)                 // Issue token (login)
-		auth.DELETE("/tokens", commonControllerForPublic.Logout)              // Revoke token (logout)
-		auth.GET("/tokens/validate", commonControllerForPublic.ValidateToken) // Validate token
-		auth.POST("/tokens/refresh", commonControllerForPublic.RefreshToken)  // Refresh token
+		auth.POST("/tokens", authRateLimitMW, commonControllerForPublic.Login)                            // Issue token (login)
+		auth.DELETE("/tokens", commonControllerForPublic.Logout)                                          // Revoke token (logout)
+		auth.GET("/tokens/validate", commonControllerForPublic.ValidateToken)                             // Validate token
+		auth.POST("/tokens/refresh", authRateLimitMW, commonControllerForPublic.RefreshToken)             // Refresh token
+		auth.POST("/tokens/mfa", authRateLimitMW, commonControllerForPublic.VerifyMFA)                    // Complete login with a second factor
+		auth.GET("/.well-known/jwks.json", commonControllerForPublic.GetJWKS)                             // Public signing keys
+		auth.POST("/oauth/token", authRateLimitMW, serviceAccountControllerForPublic.Token)               // Service account client_credentials grant
+		auth.POST("/oauth/introspect", authRateLimitMW, tokenIntrospectionControllerForPublic.Introspect) // RFC 7662 token introspection
+		auth.POST("/oauth/revoke", authRateLimitMW, tokenIntrospectionControllerForPublic.Revoke)         // RFC 7009 token revocation
 
 		// GetUserInfo requires authentication middleware
 		authWithMW := auth.Group("")
@@ -94,21 +179,77 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		authWithMW.GET("/tokens/user", commonControllerForPublic.GetUserInfo) // Get user info from token
 	}
 
+	// OpenID Connect provider endpoints (enabled by oidc.enabled)
+	if conf.YamlConfig.Application.Server.OIDC.Enabled {
+		router.GET(controller.OIDCDiscoveryPath, requestIDMW, loggerMW, oidcControllerForPublic.Discovery)
+		oidc := v1.Group("/share/common/oidc")
+		oidc.Use(loggerMW)
+		{
+			oidc.GET("/authorize", oidcControllerForPublic.Authorize)                         // Login page
+			oidc.POST("/authorize", authRateLimitMW, oidcControllerForPublic.AuthorizeSubmit) // Login / consent form
+			oidc.POST("/token", authRateLimitMW, oidcControllerForPublic.Token)               // Code / refresh token exchange
+			oidc.GET("/userinfo", oidcControllerForPublic.UserInfo)                           // Claims of the token owner
+			oidc.POST("/userinfo", oidcControllerForPublic.UserInfo)
+		}
+	}
+
+	// Federated login through upstream OpenID Connect providers (federation.providers)
+	if len(conf.YamlConfig.Application.Server.Federation.Providers) > 0 {
+		federation := v1.Group("/share/common/federation")
+		federation.Use(loggerMW)
+		{
+			federation.GET("/providers", federationControllerForPublic.ListProviders)     // Configured providers
+			federation.GET("/:provider/login", federationControllerForPublic.Login)       // Redirect to the provider
+			federation.GET("/:provider/callback", federationControllerForPublic.Callback) // Token pair for the linked user
+		}
+	}
+
+	// SCIM 2.0 provisioning API (enabled by scim.enabled, scim.tokens bearer tokens)
+	if conf.YamlConfig.Application.Server.SCIM.Enabled {
+		scim := router.Group(controller.SCIMBasePath)
+		scim.Use(requestIDMW, loggerMW, middleware.ForSCIM(scimRepository), middleware.RateLimit(conf, rateLimitRepository, middleware.RateLimitTierPrivate))
+		{
+			scim.GET("/ServiceProviderConfig", scimController.ServiceProviderConfig) // Supported features
+			scim.GET("/ResourceTypes", scimController.ListResourceTypes)             // Users and Groups endpoints
+			scim.GET("/ResourceTypes/:id", scimController.GetResourceType)
+			scim.GET("/Schemas", scimController.ListSchemas) // Attribute definitions
+			scim.GET("/Schemas/:id", scimController.GetSchema)
+			scim.GET("/Users", scimController.ListUsers)           // Filter / paginate users
+			scim.POST("/Users", scimController.CreateUser)         // Provision a user
+			scim.GET("/Users/:id", scimController.GetUser)         // Get user
+			scim.PUT("/Users/:id", scimController.ReplaceUser)     // Replace user
+			scim.PATCH("/Users/:id", scimController.PatchUser)     // Modify / deactivate user
+			scim.DELETE("/Users/:id", scimController.DeleteUser)   // Delete user
+			scim.GET("/Groups", scimController.ListGroups)         // Filter / paginate groups
+			scim.POST("/Groups", scimController.CreateGroup)       // Create group
+			scim.GET("/Groups/:id", scimController.GetGroup)       // Get group
+			scim.PUT("/Groups/:id", scimController.ReplaceGroup)   // Replace group and members
+			scim.PATCH("/Groups/:id", scimController.PatchGroup)   // Rename / add or remove members
+			scim.DELETE("/Groups/:id", scimController.DeleteGroup) // Delete group
+		}
+	}
+
 	// Public API - No authentication required (read-only discovery)
 	publicAPI := v1.Group("/public")
-	publicAPI.Use(loggerMW, middleware.ForPublic(conf))
+	publicAPI.Use(loggerMW, middleware.ForPublic(conf), middleware.RateLimit(conf, rateLimitRepository, middleware.RateLimitTierPublic))
 
 	// Internal API - Authentication required (standard operations)
 	internalAPI := v1.Group("/internal")
-	internalAPI.Use(loggerMW, middleware.ForInternal(commonRepository, appEnforcer))
+	internalAPI.Use(loggerMW, middleware.ForInternal(commonRepository, appEnforcer), middleware.RateLimit(conf, rateLimitRepository, middleware.RateLimitTierInternal))
 
 	// Private API - Administrative operations (Keystone admin endpoints style)
 	privateAPI := v1.Group("/private")
-	privateAPI.Use(loggerMW, middleware.ForPrivate(commonRepository, appEnforcer))
+	privateAPI.Use(loggerMW, middleware.ForPrivate(commonRepository, appEnforcer), middleware.RateLimit(conf, rateLimitRepository, middleware.RateLimitTierPrivate))
 
 	// ============ USER ENDPOINTS ============
 	// Public: User registration (POST uses singular)
 	publicAPI.POST("/user", userControllerForPublic.CreateUser)
+	// Public: Email verification of self-registered users (resend answers the same for unknown emails)
+	publicAPI.POST("/user/verify", emailVerificationControllerForPublic.VerifyEmail)
+	publicAPI.POST("/user/verify/resend", emailVerificationControllerForPublic.ResendVerification)
+	// Public: Self-service password reset (rate limited, same answer for unknown emails)
+	publicAPI.POST("/password/forgot", passwordControllerForPublic.ForgotPassword)
+	publicAPI.POST("/password/reset", passwordControllerForPublic.ResetPassword)
 	// Internal: Standard user operations (GET plural, mutating singular)
 	internalAPI.GET("/users", middleware.CasbinAuthorization(appEnforcer, "users", "read"), userControllerForInternal.GetUsers)
 	internalAPI.GET("/users/count", middleware.CasbinAuthorization(appEnforcer, "users", "read"), userControllerForInternal.CountUsers)
@@ -125,8 +266,8 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 	internalAPI.GET("/groups", middleware.CasbinAuthorization(appEnforcer, "groups", "read"), groupControllerForInternal.GetGroups)
 	internalAPI.GET("/groups/count", middleware.CasbinAuthorization(appEnforcer, "groups", "read"), groupControllerForInternal.CountGroups)
 	internalAPI.POST("/group", middleware.CasbinAuthorization(appEnforcer, "groups", "write"), groupControllerForInternal.CreateGroup)
-	internalAPI.PUT("/group/:id", middleware.CasbinAuthorization(appEnforcer, "groups", "write"), groupControllerForInternal.UpdateGroup)
-	internalAPI.DELETE("/group/:id", middleware.CasbinAuthorization(appEnforcer, "groups", "write"), groupControllerForInternal.DeleteGroup)
+	internalAPI.PUT("/group/:id", middleware.CasbinAuthorization(appEnforcer, "groups", "write"), middleware.GroupAuthorization(groupPermissionRepository, groupRepository, "group_info", "write"), groupControllerForInternal.UpdateGroup)
+	internalAPI.DELETE("/group/:id", middleware.CasbinAuthorization(appEnforcer, "groups", "write"), middleware.GroupAuthorization(groupPermissionRepository, groupRepository, "group_info", "write"), groupControllerForInternal.DeleteGroup)
 	privateAPI.GET("/groups", middleware.CasbinAuthorization(appEnforcer, "groups", "read"), groupControllerForPrivate.GetGroups)
 	privateAPI.GET("/groups/count", middleware.CasbinAuthorization(appEnforcer, "groups", "read"), groupControllerForPrivate.CountGroups)
 	privateAPI.POST("/group", middleware.CasbinAuthorization(appEnforcer, "groups", "write"), groupControllerForPrivate.CreateGroup)
@@ -136,21 +277,75 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 	// ============ MEMBER ENDPOINTS ============
 	internalAPI.GET("/members", middleware.CasbinAuthorization(appEnforcer, "members", "read"), memberControllerForInternal.GetMembers)
 	internalAPI.GET("/members/count", middleware.CasbinAuthorization(appEnforcer, "members", "read"), memberControllerForInternal.CountMembers)
-	internalAPI.POST("/member", middleware.CasbinAuthorization(appEnforcer, "members", "write"), memberControllerForInternal.CreateMember)
-	internalAPI.PUT("/member/:id", middleware.CasbinAuthorization(appEnforcer, "members", "write"), memberControllerForInternal.UpdateMember)
-	internalAPI.DELETE("/member/:id", middleware.CasbinAuthorization(appEnforcer, "members", "write"), memberControllerForInternal.DeleteMember)
+	internalAPI.POST("/member", middleware.CasbinAuthorization(appEnforcer, "members", "write"), middleware.MemberAuthorization(groupPermissionRepository, memberRepository, "member", "write"), memberControllerForInternal.CreateMember)
+	internalAPI.PUT("/member/:id", middleware.CasbinAuthorization(appEnforcer, "members", "write"), middleware.MemberAuthorization(groupPermissionRepository, memberRepository, "member", "write"), memberControllerForInternal.UpdateMember)
+	internalAPI.DELETE("/member/:id", middleware.CasbinAuthorization(appEnforcer, "members", "write"), middleware.MemberAuthorization(groupPermissionRepository, memberRepository, "member", "write"), memberControllerForInternal.DeleteMember)
 	privateAPI.GET("/members", middleware.CasbinAuthorization(appEnforcer, "members", "read"), memberControllerForPrivate.GetMembers)
 	privateAPI.GET("/members/count", middleware.CasbinAuthorization(appEnforcer, "members", "read"), memberControllerForPrivate.CountMembers)
 	privateAPI.POST("/member", middleware.CasbinAuthorization(appEnforcer, "members", "write"), memberControllerForPrivate.CreateMember)
 	privateAPI.PUT("/member/:id", middleware.CasbinAuthorization(appEnforcer, "members", "write"), memberControllerForPrivate.UpdateMember)
 	privateAPI.DELETE("/member/:id", middleware.CasbinAuthorization(appEnforcer, "members", "write"), memberControllerForPrivate.DeleteMember)
 
+	// ===== AUTHORIZATION CHECKS (for other services) =====
+	internalAPI.POST("/authz/check", authzControllerForInternal.Check)
+	internalAPI.POST("/authz/check/batch", authzControllerForInternal.CheckBatch)
+
 	// ===== ROLE (policy driven) =====
 	internalAPI.GET("/roles", middleware.CasbinAuthorization(appEnforcer, "roles", "read"), roleControllerForInternal.ListRoles)
+	internalAPI.GET("/role/:id/hierarchy", middleware.CasbinAuthorization(appEnforcer, "roles", "read"), roleControllerForInternal.GetRoleHierarchy)
 	privateAPI.GET("/roles", middleware.CasbinAuthorization(appEnforcer, "roles", "read"), roleControllerForPrivate.ListRoles)
 	privateAPI.POST("/role", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), roleControllerForPrivate.CreateRole)
 	privateAPI.PUT("/role/:id", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), roleControllerForPrivate.UpdateRole)
 	privateAPI.DELETE("/role/:id", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), roleControllerForPrivate.DeleteRole)
+	privateAPI.GET("/role/:id/hierarchy", middleware.CasbinAuthorization(appEnforcer, "roles", "read"), roleControllerForPrivate.GetRoleHierarchy)
+	privateAPI.POST("/role/:id/parents", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), roleControllerForPrivate.AddRoleParent)
+	privateAPI.DELETE("/role/:id/parents/:parent", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), roleControllerForPrivate.RemoveRoleParent)
+	privateAPI.GET("/policy/status", middleware.CasbinAuthorization(appEnforcer, "roles", "read"), policyControllerForPrivate.GetPolicyStatus)
+
+	// Global roles granted to users
+	privateAPI.GET("/users/:id/roles", middleware.CasbinAuthorization(appEnforcer, "roles", "read"), userRoleControllerForPrivate.ListUserRoles)
+	privateAPI.POST("/users/:id/roles", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), userRoleControllerForPrivate.GrantUserRole)
+	privateAPI.DELETE("/users/:id/roles/:role", middleware.CasbinAuthorization(appEnforcer, "roles", "write"), userRoleControllerForPrivate.RevokeUserRole)
+
+	// Sessions
+	internalAPI.GET("/me/sessions", middleware.CasbinAuthorization(appEnforcer, "sessions", "read"), sessionControllerForInternal.ListMySessions)
+	internalAPI.DELETE("/me/sessions", middleware.CasbinAuthorization(appEnforcer, "sessions", "write"), sessionControllerForInternal.RevokeMySessions)
+	internalAPI.DELETE("/me/sessions/:session_id", middleware.CasbinAuthorization(appEnforcer, "sessions", "write"), sessionControllerForInternal.RevokeMySession)
+	privateAPI.GET("/users/:id/sessions", middleware.CasbinAuthorization(appEnforcer, "users", "read"), sessionControllerForPrivate.ListUserSessions)
+	privateAPI.DELETE("/users/:id/sessions", middleware.CasbinAuthorization(appEnforcer, "users", "write"), sessionControllerForPrivate.RevokeUserSessions)
+	privateAPI.DELETE("/users/:id/sessions/:session_id", middleware.CasbinAuthorization(appEnforcer, "users", "write"), sessionControllerForPrivate.RevokeUserSession)
+
+	// Personal access tokens
+	internalAPI.GET("/me/tokens", middleware.CasbinAuthorization(appEnforcer, "tokens", "read"), personalAccessTokenControllerForInternal.ListMyTokens)
+	internalAPI.POST("/me/token", middleware.CasbinAuthorization(appEnforcer, "tokens", "write"), denyImpersonationMW, personalAccessTokenControllerForInternal.CreateMyToken)
+	internalAPI.DELETE("/me/token/:id", middleware.CasbinAuthorization(appEnforcer, "tokens", "write"), denyImpersonationMW, personalAccessTokenControllerForInternal.RevokeMyToken)
+
+	// Multi-factor authentication
+	internalAPI.GET("/me/mfa", middleware.CasbinAuthorization(appEnforcer, "mfa", "read"), mfaControllerForInternal.GetMyMFA)
+	internalAPI.POST("/me/mfa", middleware.CasbinAuthorization(appEnforcer, "mfa", "write"), denyImpersonationMW, mfaControllerForInternal.EnrollMyMFA)
+	internalAPI.POST("/me/mfa/confirm", middleware.CasbinAuthorization(appEnforcer, "mfa", "write"), denyImpersonationMW, mfaControllerForInternal.ConfirmMyMFA)
+	internalAPI.DELETE("/me/mfa", middleware.CasbinAuthorization(appEnforcer, "mfa", "write"), denyImpersonationMW, mfaControllerForInternal.DisableMyMFA)
+	privateAPI.DELETE("/users/:id/mfa", middleware.CasbinAuthorization(appEnforcer, "users", "write"), denyImpersonationMW, mfaControllerForPrivate.ResetUserMFA)
+
+	// Linked upstream identities (federated login)
+	internalAPI.GET("/me/identities", middleware.CasbinAuthorization(appEnforcer, "identities", "read"), federationControllerForInternal.ListMyIdentities)
+	internalAPI.POST("/me/identity/:provider", middleware.CasbinAuthorization(appEnforcer, "identities", "write"), denyImpersonationMW, federationControllerForInternal.LinkMyIdentity)
+	internalAPI.DELETE("/me/identity/:id", middleware.CasbinAuthorization(appEnforcer, "identities", "write"), denyImpersonationMW, federationControllerForInternal.UnlinkMyIdentity)
+
+	// Login lockouts (brute-force protection)
+	privateAPI.GET("/lockouts", middleware.CasbinAuthorization(appEnforcer, "lockouts", "read"), loginLockoutControllerForPrivate.ListLockouts)
+	privateAPI.DELETE("/lockout/:scope/:key", middleware.CasbinAuthorization(appEnforcer, "lockouts", "write"), loginLockoutControllerForPrivate.ClearLockout)
+
+	// ===== SERVICE ACCOUNTS =====
+	privateAPI.GET("/service-accounts", middleware.CasbinAuthorization(appEnforcer, "service_accounts", "read"), serviceAccountControllerForPrivate.GetServiceAccounts)
+	privateAPI.POST("/service-account", middleware.CasbinAuthorization(appEnforcer, "service_accounts", "write"), denyImpersonationMW, serviceAccountControllerForPrivate.CreateServiceAccount)
+	privateAPI.PUT("/service-account/:id", middleware.CasbinAuthorization(appEnforcer, "service_accounts", "write"), serviceAccountControllerForPrivate.UpdateServiceAccount)
+	privateAPI.DELETE("/service-account/:id", middleware.CasbinAuthorization(appEnforcer, "service_accounts", "write"), serviceAccountControllerForPrivate.DeleteServiceAccount)
+	privateAPI.POST("/service-account/:id/secret", middleware.CasbinAuthorization(appEnforcer, "service_accounts", "write"), denyImpersonationMW, serviceAccountControllerForPrivate.RotateSecret)
+	privateAPI.DELETE("/service-account/:id/secret/:client_id", middleware.CasbinAuthorization(appEnforcer, "service_accounts", "write"), denyImpersonationMW, serviceAccountControllerForPrivate.DeleteSecret)
+
+	// Impersonation (RFC 8693 token exchange, the issued token names the admin in its act claim)
+	privateAPI.POST("/token/exchange", middleware.CasbinAuthorization(appEnforcer, "impersonation", "write"), denyImpersonationMW, impersonationControllerForPrivate.ExchangeToken)
 
 	return router
 }
@@ -15,7 +15,13 @@ type CommonUsecase interface {
 	ParseTokenUnverified(tokenString string) (*model.JWTClaims, error)
 	IsTokenInvalidated(ctx context.Context, jti string) (bool, error)
 	InvalidateToken(ctx context.Context, tokenString string) error
-	GenerateTokenPair(userID uint, userUUID, email, name, role string) (*model.TokenPair, error)
+	GenerateTokenPair(userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error)
+	GenerateAuthorizedTokenPair(clientID string, userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error)
+	GenerateClientTokenPair(clientID, subjectUUID, name, role string) (*model.TokenPair, error)
+	RotateRefreshToken(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	TouchSession(ctx context.Context, familyID, userAgent, ipAddress string) error
+	ValidatePersonalAccessToken(ctx context.Context, token string) (*model.JWTClaims, error)
 	GenerateJWTSecret() (string, error)
 	ValidateJWTSecretStrength(secret string) error
 	HashPassword(password string) (string, error)
@@ -25,6 +31,7 @@ type CommonUsecase interface {
 	SendEmail(ctx context.Context, to, subject, body string, isHTML bool) error
 	SendWelcomeEmail(ctx context.Context, to, name string) error
 	SendPasswordResetEmail(ctx context.Context, to, name, resetURL string) error
+	GetJWKS() model.JWKSet
 }
 
 type commonUsecase struct {
@@ -61,8 +68,32 @@ func (uc *commonUsecase) InvalidateToken(ctx context.Context, tokenString string
 	return uc.commonRepo.InvalidateToken(ctx, tokenString)
 }
 
-func (uc *commonUsecase) GenerateTokenPair(userID uint, userUUID, email, name, role string) (*model.TokenPair, error) {
-	return uc.commonRepo.GenerateTokenPair(userID, userUUID, email, name, role)
+func (uc *commonUsecase) GenerateTokenPair(userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error) {
+	return uc.commonRepo.GenerateTokenPair(userID, userUUID, email, name, roles...)
+}
+
+func (uc *commonUsecase) GenerateAuthorizedTokenPair(clientID string, userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error) {
+	return uc.commonRepo.GenerateAuthorizedTokenPair(clientID, userID, userUUID, email, name, roles...)
+}
+
+func (uc *commonUsecase) GenerateClientTokenPair(clientID, subjectUUID, name, role string) (*model.TokenPair, error) {
+	return uc.commonRepo.GenerateClientTokenPair(clientID, subjectUUID, name, role)
+}
+
+func (uc *commonUsecase) RotateRefreshToken(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error) {
+	return uc.commonRepo.RotateRefreshToken(ctx, refreshToken, clientID)
+}
+
+func (uc *commonUsecase) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	return uc.commonRepo.RevokeTokenFamily(ctx, familyID)
+}
+
+func (uc *commonUsecase) TouchSession(ctx context.Context, familyID, userAgent, ipAddress string) error {
+	return uc.commonRepo.TouchSession(ctx, familyID, userAgent, ipAddress)
+}
+
+func (uc *commonUsecase) ValidatePersonalAccessToken(ctx context.Context, token string) (*model.JWTClaims, error) {
+	return uc.commonRepo.ValidatePersonalAccessToken(ctx, token)
 }
 
 func (uc *commonUsecase) GenerateJWTSecret() (string, error) {
@@ -100,3 +131,7 @@ func (uc *commonUsecase) SendWelcomeEmail(ctx context.Context, to, name string)
 func (uc *commonUsecase) SendPasswordResetEmail(ctx context.Context, to, name, resetURL string) error {
 	return uc.commonRepo.SendPasswordResetEmail(ctx, to, name, resetURL)
 }
+
+func (uc *commonUsecase) GetJWKS() model.JWKSet {
+	return uc.commonRepo.GetJWKS()
+}
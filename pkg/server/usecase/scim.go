@@ -0,0 +1,1122 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/code"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/logger"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+)
+
+// SCIM list paging
+const (
+	SCIMDefaultCount = 100
+	SCIMMaxCount     = 200
+	// scimScanBatch is the page size used while evaluating a filter row by row
+	scimScanBatch = 200
+)
+
+// SCIMError is a SCIM error response (RFC 7644 section 3.12)
+type SCIMError struct {
+	Status   int
+	ScimType string // invalidFilter, tooMany, uniqueness, mutability, invalidSyntax, invalidPath, noTarget, invalidValue
+	Detail   string
+}
+
+func (e *SCIMError) Error() string {
+	return e.Detail
+}
+
+func scimError(status int, scimType, format string, args ...interface{}) *SCIMError {
+	return &SCIMError{Status: status, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+// SCIMQuery is a list request: an optional filter and a 1-based page
+type SCIMQuery struct {
+	Filter     *SCIMFilter
+	StartIndex int
+	Count      int  // 0 only counts the matching resources
+	Expand     bool // load the groups of users or the members of groups
+}
+
+// SCIMUsecase provisions users and groups through SCIM. Users map onto Users
+// rows (userName is the email, active is the soft-delete state) and group
+// members onto Members rows.
+type SCIMUsecase interface {
+	ListUsers(c *gin.Context, query SCIMQuery) ([]model.SCIMUser, int64, error)
+	GetUser(c *gin.Context, id string, withGroups bool) (*model.SCIMUser, error)
+	CreateUser(c *gin.Context, user model.SCIMUser) (*model.SCIMUser, error)
+	ReplaceUser(c *gin.Context, id string, user model.SCIMUser) (*model.SCIMUser, error)
+	PatchUser(c *gin.Context, id string, operations []request.SCIMPatchOperation) (*model.SCIMUser, error)
+	DeleteUser(c *gin.Context, id string) error
+	ListGroups(c *gin.Context, query SCIMQuery) ([]model.SCIMGroup, int64, error)
+	GetGroup(c *gin.Context, id string, withMembers bool) (*model.SCIMGroup, error)
+	CreateGroup(c *gin.Context, group model.SCIMGroup) (*model.SCIMGroup, error)
+	ReplaceGroup(c *gin.Context, id string, group model.SCIMGroup) (*model.SCIMGroup, error)
+	PatchGroup(c *gin.Context, id string, operations []request.SCIMPatchOperation) (*model.SCIMGroup, error)
+	DeleteGroup(c *gin.Context, id string) error
+}
+
+type scimUsecase struct {
+	userRepo           repository.UserRepository
+	groupRepo          repository.GroupRepository
+	memberRepo         repository.MemberRepository
+	commonRepo         repository.CommonRepository
+	passwordPolicyRepo repository.PasswordPolicyRepository
+	sessionRepo        repository.SessionRepository
+	scimRepo           repository.SCIMRepository
+}
+
+func NewSCIMUsecase(
+	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	memberRepo repository.MemberRepository,
+	commonRepo repository.CommonRepository,
+	passwordPolicyRepo repository.PasswordPolicyRepository,
+	sessionRepo repository.SessionRepository,
+	scimRepo repository.SCIMRepository,
+) SCIMUsecase {
+	return &scimUsecase{
+		userRepo:           userRepo,
+		groupRepo:          groupRepo,
+		memberRepo:         memberRepo,
+		commonRepo:         commonRepo,
+		passwordPolicyRepo: passwordPolicyRepo,
+		sessionRepo:        sessionRepo,
+		scimRepo:           scimRepo,
+	}
+}
+
+// ---- Users ----
+
+// ListUsers returns a page of the users matching the filter and the match count.
+// Filters made of userName, emails, displayName and id conditions joined by and
+// run in the database; other filters are evaluated on up to max_filter_scan users
+// narrowed by those conditions.
+func (uc *scimUsecase) ListUsers(c *gin.Context, query SCIMQuery) ([]model.SCIMUser, int64, error) {
+	filter, exact := scimUserQueryFilter(query.Filter)
+	withGroups := query.Expand || (query.Filter != nil && query.Filter.References("groups"))
+	if exact {
+		total, err := uc.userRepo.CountUsers(c, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		users := []model.SCIMUser{}
+		if query.Count == 0 || int64(query.StartIndex) > total {
+			return users, total, nil
+		}
+		filter.Offset, filter.Limit = query.StartIndex-1, query.Count
+		rows, err := uc.userRepo.ListUsers(c, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, row := range rows {
+			user, err := uc.toSCIMUser(c, row, query.Expand)
+			if err != nil {
+				return nil, 0, err
+			}
+			users = append(users, user)
+		}
+		return users, total, nil
+	}
+	return scanSCIMResources(query, uc.scimRepo.GetConfig().MaxFilterScan, func(offset, limit int) ([]model.SCIMUser, int, error) {
+		filter.Offset, filter.Limit = offset, limit
+		rows, err := uc.userRepo.ListUsers(c, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		users := make([]model.SCIMUser, 0, len(rows))
+		for _, row := range rows {
+			user, err := uc.toSCIMUser(c, row, withGroups)
+			if err != nil {
+				return nil, 0, err
+			}
+			users = append(users, user)
+		}
+		return users, len(rows), nil
+	}, func(user model.SCIMUser) model.SCIMUser {
+		if !query.Expand {
+			user.Groups = nil
+		}
+		return user
+	})
+}
+
+// GetUser returns a user by id
+func (uc *scimUsecase) GetUser(c *gin.Context, id string, withGroups bool) (*model.SCIMUser, error) {
+	row, err := uc.findUser(c, id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.toSCIMUser(c, *row, withGroups)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateUser provisions a user. The address is trusted as verified; without
+// a password the user cannot sign in with a local password.
+func (uc *scimUsecase) CreateUser(c *gin.Context, user model.SCIMUser) (*model.SCIMUser, error) {
+	now := time.Now()
+	change := scimUserChange{
+		user: model.Users{
+			UUID:            uuid.New().String(),
+			Email:           strings.TrimSpace(user.UserName),
+			Name:            scimUserName(user),
+			EmailVerifiedAt: &now,
+			CreatedAt:       &now,
+			UpdatedAt:       &now,
+		},
+		active: user.Active,
+	}
+	if user.Password != "" {
+		change.password = &user.Password
+	}
+	saved, err := uc.saveUser(c, nil, change)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(code.USCU1, c.GetString("requestID"), saved.UUID+" "+saved.Email)
+	created, err := uc.toSCIMUser(c, *saved, false)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ReplaceUser replaces the attributes of a user. active is kept when omitted.
+func (uc *scimUsecase) ReplaceUser(c *gin.Context, id string, user model.SCIMUser) (*model.SCIMUser, error) {
+	current, err := uc.findUser(c, id)
+	if err != nil {
+		return nil, err
+	}
+	change := scimUserChange{user: *current, active: user.Active}
+	change.user.Email = strings.TrimSpace(user.UserName)
+	change.user.Name = scimUserName(user)
+	if user.Password != "" {
+		change.password = &user.Password
+	}
+	return uc.updateUser(c, current, change)
+}
+
+// PatchUser applies PATCH operations to a user. Attributes Locky does not keep
+// (emails, phone numbers, extension schemas, ...) are accepted and ignored.
+func (uc *scimUsecase) PatchUser(c *gin.Context, id string, operations []request.SCIMPatchOperation) (*model.SCIMUser, error) {
+	current, err := uc.findUser(c, id)
+	if err != nil {
+		return nil, err
+	}
+	patch := scimUserPatch{change: scimUserChange{user: *current}}
+	err = applySCIMPatch(operations, func(op string, path *SCIMPath, value json.RawMessage) error {
+		return patch.apply(op, path, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !patch.displaySet && (patch.givenName != "" || patch.familyName != "") {
+		patch.change.user.Name = strings.TrimSpace(patch.givenName + " " + patch.familyName)
+	}
+	return uc.updateUser(c, current, patch.change)
+}
+
+// DeleteUser deletes a user with its memberships and signs it out everywhere
+func (uc *scimUsecase) DeleteUser(c *gin.Context, id string) error {
+	reqID := c.GetString("requestID")
+	current, err := uc.findUser(c, id)
+	if err != nil {
+		return err
+	}
+	members, err := uc.listMembers(c, repository.MemberQueryFilter{UserUUID: &current.UUID, ExcludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	if deleted := uc.userRepo.DeleteUser(c, *current); deleted.UUID == "" {
+		return errors.New("failed to delete user")
+	}
+	for _, member := range members {
+		if err := uc.memberRepo.DeleteMember(c, member.UUID).Error; err != nil {
+			logger.Warn(code.USWN1, reqID, "failed to delete membership "+member.UUID+": "+err.Error())
+		}
+	}
+	uc.revokeSessions(c, current.UUID)
+	logger.Info(code.USDU1, reqID, current.UUID)
+	return nil
+}
+
+// scimUserChange is a user with the attributes of a create, replace or patch
+// request applied, and the changes that need more than a column update
+type scimUserChange struct {
+	user     model.Users
+	password *string
+	active   *bool
+}
+
+func (uc *scimUsecase) updateUser(c *gin.Context, current *model.Users, change scimUserChange) (*model.SCIMUser, error) {
+	saved, err := uc.saveUser(c, current, change)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(code.USUU1, c.GetString("requestID"), saved.UUID)
+	updated, err := uc.toSCIMUser(c, *saved, false)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// saveUser validates and stores a user change; current is nil on create.
+// Deactivating a user revokes its sessions.
+func (uc *scimUsecase) saveUser(c *gin.Context, current *model.Users, change scimUserChange) (*model.Users, error) {
+	user := change.user
+	if user.Email == "" || !strings.Contains(user.Email, "@") {
+		return nil, scimError(http.StatusBadRequest, "invalidValue", "userName must be an email address")
+	}
+	if user.Name == "" {
+		user.Name = user.Email
+	}
+	if current == nil || !strings.EqualFold(current.Email, user.Email) {
+		other, err := uc.userRepo.GetUserByEmail(c, user.Email)
+		if err == nil && other.UUID != user.UUID {
+			return nil, scimError(http.StatusConflict, "uniqueness", "userName %s is already in use", user.Email)
+		}
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	password := ""
+	if change.password != nil {
+		violations, err := uc.passwordPolicyRepo.Validate(c, *change.password, user.UUID)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 {
+			return nil, scimError(http.StatusBadRequest, "invalidValue", "password does not meet the password policy: %s", violations[0].Message)
+		}
+		password = *change.password
+	} else if current == nil {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		password = base64.RawURLEncoding.EncodeToString(secret)
+	}
+	if password != "" {
+		hash, err := uc.commonRepo.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = hash
+		user.PasswordChangedAt = &now
+	}
+
+	deactivated := false
+	if change.active != nil {
+		switch {
+		case *change.active:
+			user.DeletedAt = nil
+		case user.DeletedAt == nil:
+			user.DeletedAt = &now
+			deactivated = current != nil
+		}
+	}
+
+	var saved model.Users
+	var err error
+	if current == nil {
+		saved, err = uc.userRepo.CreateUser(c, user)
+	} else {
+		user.UpdatedAt = &now
+		saved, err = uc.userRepo.UpdateUser(c, user)
+	}
+	if errors.Is(err, repository.ErrEmailAlreadyExists) {
+		return nil, scimError(http.StatusConflict, "uniqueness", "userName %s is already in use", user.Email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if change.password != nil {
+		if err := uc.passwordPolicyRepo.RecordPassword(c, saved.UUID, saved.Password); err != nil {
+			logger.Warn(code.USWN1, c.GetString("requestID"), "failed to record password of "+saved.UUID+": "+err.Error())
+		}
+	}
+	if deactivated {
+		uc.revokeSessions(c, saved.UUID)
+	}
+	return &saved, nil
+}
+
+func (uc *scimUsecase) revokeSessions(c *gin.Context, userUUID string) {
+	if _, err := uc.sessionRepo.RevokeAllSessions(c.Request.Context(), userUUID, ""); err != nil {
+		logger.Warn(code.USWN1, c.GetString("requestID"), "failed to revoke sessions of "+userUUID+": "+err.Error())
+	}
+}
+
+func (uc *scimUsecase) findUser(c *gin.Context, id string) (*model.Users, error) {
+	users, err := uc.userRepo.ListUsers(c, repository.UserQueryFilter{UUID: &id, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if users[i].UUID == id {
+			return &users[i], nil
+		}
+	}
+	return nil, scimError(http.StatusNotFound, "", "User %s not found", id)
+}
+
+func (uc *scimUsecase) toSCIMUser(c *gin.Context, row model.Users, withGroups bool) (model.SCIMUser, error) {
+	active := row.DeletedAt == nil
+	user := model.SCIMUser{
+		Schemas:     []string{model.SCIMSchemaUser},
+		ID:          row.UUID,
+		UserName:    row.Email,
+		DisplayName: row.Name,
+		Active:      &active,
+		Meta:        &model.SCIMMeta{ResourceType: "User", Created: row.CreatedAt, LastModified: row.UpdatedAt},
+	}
+	if row.Name != "" {
+		user.Name = &model.SCIMName{Formatted: row.Name}
+	}
+	if row.Email != "" {
+		user.Emails = []model.SCIMMultiValue{{Value: row.Email, Type: "work", Primary: true}}
+	}
+	if !withGroups {
+		return user, nil
+	}
+	members, err := uc.listMembers(c, repository.MemberQueryFilter{UserUUID: &row.UUID, ExcludeDeleted: true})
+	if err != nil {
+		return user, err
+	}
+	seen := map[string]bool{}
+	for _, member := range members {
+		if seen[member.GroupUUID] {
+			continue
+		}
+		seen[member.GroupUUID] = true
+		group, err := uc.findGroup(c, member.GroupUUID)
+		if err != nil {
+			continue
+		}
+		user.Groups = append(user.Groups, model.SCIMMultiValue{Value: group.UUID, Display: group.Name, Type: "direct"})
+	}
+	return user, nil
+}
+
+// scimUserName is the name stored for a user: displayName, else name.formatted,
+// else givenName and familyName
+func scimUserName(user model.SCIMUser) string {
+	if name := strings.TrimSpace(user.DisplayName); name != "" {
+		return name
+	}
+	if user.Name == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(user.Name.Formatted); name != "" {
+		return name
+	}
+	return strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName)
+}
+
+// scimUserPatch accumulates the PATCH operations of a user
+type scimUserPatch struct {
+	change     scimUserChange
+	displaySet bool
+	givenName  string
+	familyName string
+}
+
+func (p *scimUserPatch) apply(op string, path *SCIMPath, value json.RawMessage) error {
+	attr, sub, _ := strings.Cut(path.Attr, ".")
+	switch attr {
+	case "id", "meta", "groups", "schemas":
+		return scimError(http.StatusBadRequest, "mutability", "%s is read only", attr)
+	case "username", "displayname", "name", "active", "password":
+		if path.Filter != nil {
+			return scimError(http.StatusBadRequest, "invalidPath", "%s is not multi-valued", attr)
+		}
+	default:
+		// not kept by Locky
+		return nil
+	}
+	if op == "remove" && attr != "displayname" && attr != "name" {
+		return scimError(http.StatusBadRequest, "mutability", "%s cannot be removed", attr)
+	}
+
+	switch attr {
+	case "username":
+		email, err := decodeSCIMString(attr, value)
+		if err != nil {
+			return err
+		}
+		p.change.user.Email = strings.TrimSpace(email)
+	case "displayname":
+		name := ""
+		if op != "remove" {
+			var err error
+			if name, err = decodeSCIMString(attr, value); err != nil {
+				return err
+			}
+		}
+		p.change.user.Name = strings.TrimSpace(name)
+		p.displaySet = true
+	case "name":
+		if op == "remove" {
+			if !p.displaySet {
+				p.change.user.Name = ""
+			}
+			return nil
+		}
+		var name model.SCIMName
+		switch sub {
+		case "":
+			if err := json.Unmarshal(value, &name); err != nil {
+				return scimError(http.StatusBadRequest, "invalidValue", "name must be an object")
+			}
+		case "formatted", "givenname", "familyname":
+			s, err := decodeSCIMString(path.Attr, value)
+			if err != nil {
+				return err
+			}
+			switch sub {
+			case "formatted":
+				name.Formatted = s
+			case "givenname":
+				name.GivenName = s
+			default:
+				name.FamilyName = s
+			}
+		default:
+			return nil
+		}
+		if name.GivenName != "" {
+			p.givenName = name.GivenName
+		}
+		if name.FamilyName != "" {
+			p.familyName = name.FamilyName
+		}
+		if formatted := strings.TrimSpace(name.Formatted); formatted != "" && !p.displaySet {
+			p.change.user.Name = formatted
+			p.displaySet = true
+		}
+	case "active":
+		active, err := decodeSCIMBool(attr, value)
+		if err != nil {
+			return err
+		}
+		p.change.active = &active
+	case "password":
+		password, err := decodeSCIMString(attr, value)
+		if err != nil {
+			return err
+		}
+		p.change.password = &password
+	}
+	return nil
+}
+
+// ---- Groups ----
+
+// ListGroups returns a page of the groups matching the filter and the match
+// count. displayName and id conditions joined by and run in the database.
+func (uc *scimUsecase) ListGroups(c *gin.Context, query SCIMQuery) ([]model.SCIMGroup, int64, error) {
+	filter, exact := scimGroupQueryFilter(query.Filter)
+	filter.ExcludeDeleted = true
+	withMembers := query.Expand || (query.Filter != nil && query.Filter.References("members"))
+	if exact {
+		total, err := uc.groupRepo.CountGroups(c, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups := []model.SCIMGroup{}
+		if query.Count == 0 || int64(query.StartIndex) > total {
+			return groups, total, nil
+		}
+		filter.Offset, filter.Limit = query.StartIndex-1, query.Count
+		rows, err := uc.groupRepo.ListGroups(c, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, row := range rows {
+			group, err := uc.toSCIMGroup(c, row, query.Expand)
+			if err != nil {
+				return nil, 0, err
+			}
+			groups = append(groups, group)
+		}
+		return groups, total, nil
+	}
+	return scanSCIMResources(query, uc.scimRepo.GetConfig().MaxFilterScan, func(offset, limit int) ([]model.SCIMGroup, int, error) {
+		filter.Offset, filter.Limit = offset, limit
+		rows, err := uc.groupRepo.ListGroups(c, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups := make([]model.SCIMGroup, 0, len(rows))
+		for _, row := range rows {
+			group, err := uc.toSCIMGroup(c, row, withMembers)
+			if err != nil {
+				return nil, 0, err
+			}
+			groups = append(groups, group)
+		}
+		return groups, len(rows), nil
+	}, func(group model.SCIMGroup) model.SCIMGroup {
+		if !query.Expand {
+			group.Members = nil
+		}
+		return group
+	})
+}
+
+// GetGroup returns a group by id
+func (uc *scimUsecase) GetGroup(c *gin.Context, id string, withMembers bool) (*model.SCIMGroup, error) {
+	row, err := uc.findGroup(c, id)
+	if err != nil {
+		return nil, err
+	}
+	group, err := uc.toSCIMGroup(c, *row, withMembers)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// CreateGroup creates a group with its members
+func (uc *scimUsecase) CreateGroup(c *gin.Context, group model.SCIMGroup) (*model.SCIMGroup, error) {
+	members, err := scimMemberIDs(group.Members)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	row := model.Groups{UUID: uuid.New().String(), CreatedAt: &now, UpdatedAt: &now}
+	created, err := uc.saveGroup(c, nil, row, scimGroupState{name: group.DisplayName, members: members})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(code.USCG1, c.GetString("requestID"), created.ID+" "+created.DisplayName)
+	return created, nil
+}
+
+// ReplaceGroup replaces the name and the members of a group
+func (uc *scimUsecase) ReplaceGroup(c *gin.Context, id string, group model.SCIMGroup) (*model.SCIMGroup, error) {
+	current, err := uc.findGroup(c, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := scimMemberIDs(group.Members)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := uc.saveGroup(c, current, *current, scimGroupState{name: group.DisplayName, members: members})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(code.USUG1, c.GetString("requestID"), updated.ID)
+	return updated, nil
+}
+
+// PatchGroup applies PATCH operations to the name and the members of a group
+func (uc *scimUsecase) PatchGroup(c *gin.Context, id string, operations []request.SCIMPatchOperation) (*model.SCIMGroup, error) {
+	current, err := uc.findGroup(c, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := uc.groupMemberIDs(c, current.UUID)
+	if err != nil {
+		return nil, err
+	}
+	state := &scimGroupState{name: current.Name, members: members}
+	if err := applySCIMPatch(operations, state.apply); err != nil {
+		return nil, err
+	}
+	updated, err := uc.saveGroup(c, current, *current, *state)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(code.USUG1, c.GetString("requestID"), updated.ID)
+	return updated, nil
+}
+
+// DeleteGroup soft-deletes a group and its memberships
+func (uc *scimUsecase) DeleteGroup(c *gin.Context, id string) error {
+	reqID := c.GetString("requestID")
+	current, err := uc.findGroup(c, id)
+	if err != nil {
+		return err
+	}
+	members, err := uc.listMembers(c, repository.MemberQueryFilter{GroupUUID: &current.UUID, ExcludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	if err := uc.groupRepo.DeleteGroup(c, current.UUID).Error; err != nil {
+		return err
+	}
+	for _, member := range members {
+		if err := uc.memberRepo.DeleteMember(c, member.UUID).Error; err != nil {
+			logger.Warn(code.USWN1, reqID, "failed to delete membership "+member.UUID+": "+err.Error())
+		}
+	}
+	logger.Info(code.USDG1, reqID, current.UUID)
+	return nil
+}
+
+// scimGroupState is the name and the member user ids of a group
+type scimGroupState struct {
+	name    string
+	members []string
+}
+
+func (s *scimGroupState) apply(op string, path *SCIMPath, value json.RawMessage) error {
+	attr, sub, _ := strings.Cut(path.Attr, ".")
+	switch attr {
+	case "id", "meta", "schemas":
+		return scimError(http.StatusBadRequest, "mutability", "%s is read only", attr)
+	case "displayname":
+		if path.Filter != nil {
+			return scimError(http.StatusBadRequest, "invalidPath", "displayName is not multi-valued")
+		}
+		if op == "remove" {
+			return scimError(http.StatusBadRequest, "mutability", "displayName cannot be removed")
+		}
+		name, err := decodeSCIMString(attr, value)
+		if err != nil {
+			return err
+		}
+		s.name = name
+		return nil
+	case "members":
+	default:
+		// not kept by Locky
+		return nil
+	}
+	if sub != "" || path.SubAttr != "" {
+		return scimError(http.StatusBadRequest, "invalidPath", "sub-attributes of members cannot be modified")
+	}
+
+	if path.Filter != nil {
+		if op != "remove" {
+			return scimError(http.StatusBadRequest, "invalidPath", "a members filter is only supported by remove")
+		}
+		kept := []string{}
+		for _, id := range s.members {
+			if !path.Filter.Matches(map[string]interface{}{"value": id, "type": "User"}) {
+				kept = append(kept, id)
+			}
+		}
+		s.members = kept
+		return nil
+	}
+
+	var ids []string
+	if len(value) > 0 && string(value) != "null" {
+		var entries []model.SCIMMultiValue
+		if err := json.Unmarshal(value, &entries); err != nil {
+			var entry model.SCIMMultiValue
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return scimError(http.StatusBadRequest, "invalidValue", "members must be a list of members")
+			}
+			entries = []model.SCIMMultiValue{entry}
+		}
+		var err error
+		if ids, err = scimMemberIDs(entries); err != nil {
+			return err
+		}
+	}
+	switch op {
+	case "add":
+		s.members = append(s.members, ids...)
+	case "replace":
+		s.members = ids
+	case "remove":
+		if ids == nil {
+			s.members = nil
+			return nil
+		}
+		removed := map[string]bool{}
+		for _, id := range ids {
+			removed[id] = true
+		}
+		kept := []string{}
+		for _, id := range s.members {
+			if !removed[id] {
+				kept = append(kept, id)
+			}
+		}
+		s.members = kept
+	}
+	return nil
+}
+
+// saveGroup validates and stores a group and reconciles its memberships;
+// current is nil on create. Members added through SCIM get scim.member_role.
+func (uc *scimUsecase) saveGroup(c *gin.Context, current *model.Groups, row model.Groups, state scimGroupState) (*model.SCIMGroup, error) {
+	row.Name = strings.TrimSpace(state.name)
+	if row.Name == "" {
+		return nil, scimError(http.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+	if current == nil || current.Name != row.Name {
+		existing, err := uc.groupRepo.ListGroups(c, repository.GroupQueryFilter{Name: &row.Name, Limit: 1, ExcludeDeleted: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range existing {
+			if other.UUID != row.UUID {
+				return nil, scimError(http.StatusConflict, "uniqueness", "displayName %s is already in use", row.Name)
+			}
+		}
+	}
+
+	desired := map[string]bool{}
+	for _, id := range state.members {
+		desired[id] = true
+	}
+	currentMembers := []model.Members{}
+	if current != nil {
+		var err error
+		if currentMembers, err = uc.listMembers(c, repository.MemberQueryFilter{GroupUUID: &current.UUID, ExcludeDeleted: true}); err != nil {
+			return nil, err
+		}
+	}
+	existingUsers := map[string]bool{}
+	for _, member := range currentMembers {
+		existingUsers[member.UserUUID] = true
+	}
+	added := []string{}
+	for id := range desired {
+		if existingUsers[id] {
+			continue
+		}
+		if _, err := uc.findUser(c, id); err != nil {
+			var scimErr *SCIMError
+			if errors.As(err, &scimErr) {
+				return nil, scimError(http.StatusBadRequest, "invalidValue", "member %s is not a user", id)
+			}
+			return nil, err
+		}
+		added = append(added, id)
+	}
+	sort.Strings(added)
+
+	now := time.Now()
+	if current == nil {
+		if err := uc.groupRepo.CreateGroup(c, &row).Error; err != nil {
+			return nil, err
+		}
+	} else if current.Name != row.Name {
+		row.UpdatedAt = &now
+		if err := uc.groupRepo.UpdateGroup(c, &row).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	role := uc.scimRepo.GetConfig().MemberRole
+	for _, member := range currentMembers {
+		if desired[member.UserUUID] {
+			continue
+		}
+		if err := uc.memberRepo.DeleteMember(c, member.UUID).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range added {
+		member := model.Members{UUID: uuid.New().String(), GroupUUID: row.UUID, UserUUID: id, Role: role, CreatedAt: &now, UpdatedAt: &now}
+		if err := uc.memberRepo.CreateMember(c, &member).Error; err != nil {
+			return nil, err
+		}
+	}
+	group, err := uc.toSCIMGroup(c, row, true)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (uc *scimUsecase) findGroup(c *gin.Context, id string) (*model.Groups, error) {
+	groups, err := uc.groupRepo.ListGroups(c, repository.GroupQueryFilter{UUID: &id, Limit: 1, ExcludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].UUID == id && groups[i].DeletedAt == nil {
+			return &groups[i], nil
+		}
+	}
+	return nil, scimError(http.StatusNotFound, "", "Group %s not found", id)
+}
+
+// groupMemberIDs returns the users with a membership in a group, in the order they joined
+func (uc *scimUsecase) groupMemberIDs(c *gin.Context, groupUUID string) ([]string, error) {
+	members, err := uc.listMembers(c, repository.MemberQueryFilter{GroupUUID: &groupUUID, ExcludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, member := range members {
+		if !seen[member.UserUUID] {
+			seen[member.UserUUID] = true
+			ids = append(ids, member.UserUUID)
+		}
+	}
+	return ids, nil
+}
+
+func (uc *scimUsecase) toSCIMGroup(c *gin.Context, row model.Groups, withMembers bool) (model.SCIMGroup, error) {
+	group := model.SCIMGroup{
+		Schemas:     []string{model.SCIMSchemaGroup},
+		ID:          row.UUID,
+		DisplayName: row.Name,
+		Meta:        &model.SCIMMeta{ResourceType: "Group", Created: row.CreatedAt, LastModified: row.UpdatedAt},
+	}
+	if !withMembers {
+		return group, nil
+	}
+	ids, err := uc.groupMemberIDs(c, row.UUID)
+	if err != nil {
+		return group, err
+	}
+	for _, id := range ids {
+		group.Members = append(group.Members, model.SCIMMultiValue{Value: id, Type: "User"})
+	}
+	return group, nil
+}
+
+// listMembers returns every membership of the filter, page by page
+func (uc *scimUsecase) listMembers(c *gin.Context, filter repository.MemberQueryFilter) ([]model.Members, error) {
+	all := []model.Members{}
+	filter.Limit = scimScanBatch
+	for filter.Offset = 0; ; filter.Offset += scimScanBatch {
+		members, err := uc.memberRepo.ListMembers(c, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, members...)
+		if len(members) < scimScanBatch {
+			return all, nil
+		}
+	}
+}
+
+// scimMemberIDs returns the user ids of member entries. Nested groups are not supported.
+func scimMemberIDs(entries []model.SCIMMultiValue) ([]string, error) {
+	ids := []string{}
+	for _, entry := range entries {
+		if entry.Type != "" && !strings.EqualFold(entry.Type, "User") {
+			return nil, scimError(http.StatusBadRequest, "invalidValue", "only users can be group members")
+		}
+		if entry.Value == "" {
+			return nil, scimError(http.StatusBadRequest, "invalidValue", "member value is required")
+		}
+		ids = append(ids, entry.Value)
+	}
+	return ids, nil
+}
+
+// ---- Filters, patches and values ----
+
+// scimUserQueryFilter maps the and-joined conditions of a filter the database
+// can evaluate onto a UserQueryFilter. exact is false when the filter has
+// other conditions and still has to be evaluated on the rows.
+func scimUserQueryFilter(filter *SCIMFilter) (repository.UserQueryFilter, bool) {
+	q := repository.UserQueryFilter{}
+	exact := true
+	for _, cond := range scimConjuncts(filter) {
+		value, ok := scimPushdownValue(cond)
+		if !ok {
+			exact = false
+			continue
+		}
+		var target **string
+		switch {
+		case cond.Attr == "id" && cond.Op == "eq":
+			target = &q.UUID
+		case cond.Attr == "username" || cond.Attr == "emails" || cond.Attr == "emails.value":
+			target = map[string]**string{"eq": &q.Email, "sw": &q.EmailPrefix, "co": &q.EmailLike}[cond.Op]
+		case cond.Attr == "displayname" || cond.Attr == "name.formatted":
+			target = map[string]**string{"eq": &q.Name, "sw": &q.NamePrefix, "co": &q.NameLike}[cond.Op]
+		}
+		if target == nil || *target != nil {
+			exact = false
+			continue
+		}
+		*target = &value
+	}
+	return q, exact
+}
+
+// scimGroupQueryFilter maps the and-joined conditions of a filter the database
+// can evaluate onto a GroupQueryFilter, like scimUserQueryFilter
+func scimGroupQueryFilter(filter *SCIMFilter) (repository.GroupQueryFilter, bool) {
+	q := repository.GroupQueryFilter{}
+	exact := true
+	for _, cond := range scimConjuncts(filter) {
+		value, ok := scimPushdownValue(cond)
+		if !ok {
+			exact = false
+			continue
+		}
+		var target **string
+		switch {
+		case cond.Attr == "id" && cond.Op == "eq":
+			target = &q.UUID
+		case cond.Attr == "displayname":
+			target = map[string]**string{"eq": &q.Name, "sw": &q.NamePrefix, "co": &q.NameLike}[cond.Op]
+		}
+		if target == nil || *target != nil {
+			exact = false
+			continue
+		}
+		*target = &value
+	}
+	return q, exact
+}
+
+// scimConjuncts splits a filter into its and-joined conditions
+func scimConjuncts(filter *SCIMFilter) []*SCIMFilter {
+	if filter == nil {
+		return nil
+	}
+	if filter.Op == SCIMFilterAnd {
+		return append(scimConjuncts(filter.Children[0]), scimConjuncts(filter.Children[1])...)
+	}
+	return []*SCIMFilter{filter}
+}
+
+// scimPushdownValue returns the string of an eq, sw or co condition the
+// database can evaluate: LIKE wildcards in sw and co values cannot be escaped
+func scimPushdownValue(cond *SCIMFilter) (string, bool) {
+	value, ok := cond.Value.(string)
+	if !ok {
+		return "", false
+	}
+	switch cond.Op {
+	case "eq":
+		return value, true
+	case "sw", "co":
+		return value, value != "" && !strings.ContainsAny(value, `%_\`)
+	}
+	return "", false
+}
+
+// scanSCIMResources evaluates a filter on the rows returned by fetch, at most
+// maxScan of them, and returns the requested page of the matches with their count
+func scanSCIMResources[R any](query SCIMQuery, maxScan int, fetch func(offset, limit int) ([]R, int, error), project func(R) R) ([]R, int64, error) {
+	page := []R{}
+	var total int64
+	for offset := 0; ; offset += scimScanBatch {
+		if offset >= maxScan {
+			return nil, 0, scimError(http.StatusBadRequest, "tooMany", "the filter has to be evaluated on more than %d resources; add a userName or displayName condition", maxScan)
+		}
+		resources, rows, err := fetch(offset, scimScanBatch)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, resource := range resources {
+			if query.Filter != nil && !query.Filter.Matches(scimResourceMap(resource)) {
+				continue
+			}
+			total++
+			if total >= int64(query.StartIndex) && len(page) < query.Count {
+				page = append(page, project(resource))
+			}
+		}
+		if rows < scimScanBatch {
+			return page, total, nil
+		}
+	}
+}
+
+// scimResourceMap is the JSON form of a resource filters are evaluated on
+func scimResourceMap(resource interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if data, err := json.Marshal(resource); err == nil {
+		_ = json.Unmarshal(data, &m)
+	}
+	return m
+}
+
+// applySCIMPatch validates PATCH operations and applies them in order.
+// Operations without a path take an object of attribute values.
+func applySCIMPatch(operations []request.SCIMPatchOperation, apply func(op string, path *SCIMPath, value json.RawMessage) error) error {
+	if len(operations) == 0 {
+		return scimError(http.StatusBadRequest, "invalidSyntax", "Operations is required")
+	}
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return scimError(http.StatusBadRequest, "invalidSyntax", "unsupported op %q", operation.Op)
+		}
+		if operation.Path == "" {
+			if op == "remove" {
+				return scimError(http.StatusBadRequest, "noTarget", "remove requires a path")
+			}
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &values); err != nil {
+				return scimError(http.StatusBadRequest, "invalidValue", "value must be an object when path is omitted")
+			}
+			attrs := make([]string, 0, len(values))
+			for attr := range values {
+				attrs = append(attrs, attr)
+			}
+			sort.Strings(attrs)
+			for _, attr := range attrs {
+				if err := apply(op, &SCIMPath{Attr: normalizeSCIMAttr(attr)}, values[attr]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		path, err := ParseSCIMPath(operation.Path)
+		if err != nil {
+			return err
+		}
+		if op != "remove" && len(operation.Value) == 0 {
+			return scimError(http.StatusBadRequest, "invalidValue", "%s requires a value", op)
+		}
+		if err := apply(op, path, operation.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeSCIMString(attr string, value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", scimError(http.StatusBadRequest, "invalidValue", "%s must be a string", attr)
+	}
+	return s, nil
+}
+
+// decodeSCIMBool accepts a boolean or its string form, as sent by some identity providers
+func decodeSCIMBool(attr string, value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, scimError(http.StatusBadRequest, "invalidValue", "%s must be a boolean", attr)
+}
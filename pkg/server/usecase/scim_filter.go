@@ -0,0 +1,423 @@
+package usecase
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SCIM filter operators
+const (
+	SCIMFilterAnd       = "and"
+	SCIMFilterOr        = "or"
+	SCIMFilterNot       = "not"
+	SCIMFilterValuePath = "[]" // attr[filter]: an entry of a multi-valued attribute matches
+	SCIMFilterPresent   = "pr"
+)
+
+var scimCompareOps = map[string]bool{"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "lt": true, "ge": true, "le": true}
+
+// SCIMFilter is a parsed SCIM filter expression (RFC 7644 section 3.4.2.2).
+// Attribute paths are lower case, without the schema URN.
+type SCIMFilter struct {
+	Op       string        // and / or / not / [] / pr / eq / ne / co / sw / ew / gt / lt / ge / le
+	Attr     string        // attribute path of pr, comparisons and value paths
+	Value    interface{}   // string, float64, bool or nil
+	Children []*SCIMFilter // operands of and / or, the expression of not and value paths
+}
+
+// SCIMPath is the target of a PATCH operation: attr, attr[filter] or attr[filter].subAttr
+type SCIMPath struct {
+	Attr    string
+	Filter  *SCIMFilter
+	SubAttr string
+}
+
+// ParseSCIMFilter parses the filter query parameter
+func ParseSCIMFilter(filter string) (*SCIMFilter, error) {
+	p, err := newSCIMFilterParser(filter)
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return f, nil
+}
+
+// ParseSCIMPath parses the path of a PATCH operation
+func ParseSCIMPath(path string) (*SCIMPath, error) {
+	p, err := newSCIMFilterParser(path)
+	if err != nil {
+		return nil, invalidSCIMPath(err.Error())
+	}
+	if p.peek() == "" || p.peek() == "(" || p.peek() == "[" {
+		return nil, invalidSCIMPath("attribute expected")
+	}
+	target := &SCIMPath{Attr: normalizeSCIMAttr(p.next())}
+	if p.peek() == "[" {
+		p.next()
+		if target.Filter, err = p.parseOr(); err != nil {
+			return nil, invalidSCIMPath(err.Error())
+		}
+		if p.next() != "]" {
+			return nil, invalidSCIMPath("] expected")
+		}
+		if strings.HasPrefix(p.peek(), ".") {
+			target.SubAttr = strings.ToLower(strings.TrimPrefix(p.next(), "."))
+		}
+	}
+	if !p.done() {
+		return nil, invalidSCIMPath(fmt.Sprintf("unexpected %q", p.peek()))
+	}
+	return target, nil
+}
+
+func invalidSCIMPath(detail string) error {
+	return &SCIMError{Status: http.StatusBadRequest, ScimType: "invalidPath", Detail: "invalid path: " + detail}
+}
+
+// normalizeSCIMAttr lower-cases an attribute path and removes its schema URN
+func normalizeSCIMAttr(attr string) string {
+	attr = strings.ToLower(attr)
+	if strings.HasPrefix(attr, "urn:") {
+		attr = attr[strings.LastIndex(attr, ":")+1:]
+	}
+	return attr
+}
+
+// Matches evaluates the filter against a resource in its JSON form
+func (f *SCIMFilter) Matches(resource map[string]interface{}) bool {
+	switch f.Op {
+	case SCIMFilterAnd:
+		return f.Children[0].Matches(resource) && f.Children[1].Matches(resource)
+	case SCIMFilterOr:
+		return f.Children[0].Matches(resource) || f.Children[1].Matches(resource)
+	case SCIMFilterNot:
+		return !f.Children[0].Matches(resource)
+	case SCIMFilterValuePath:
+		for _, entry := range resolveSCIMAttr(resource, f.Attr, false) {
+			if m, ok := entry.(map[string]interface{}); ok && f.Children[0].Matches(m) {
+				return true
+			}
+		}
+		return false
+	case SCIMFilterPresent:
+		for _, v := range resolveSCIMAttr(resource, f.Attr, true) {
+			if s, ok := v.(string); !ok || s != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	values := resolveSCIMAttr(resource, f.Attr, true)
+	if f.Value == nil {
+		// attr eq null is true when the attribute is absent
+		return (f.Op == "eq") == (len(values) == 0)
+	}
+	if f.Op == "ne" {
+		for _, v := range values {
+			if compareSCIMValue("eq", v, f.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if compareSCIMValue(f.Op, v, f.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// References reports whether the filter uses an attribute or one of its sub-attributes
+func (f *SCIMFilter) References(attr string) bool {
+	if f.Attr == attr || strings.HasPrefix(f.Attr, attr+".") {
+		return true
+	}
+	for _, child := range f.Children {
+		if f.Op != SCIMFilterValuePath && child.References(attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSCIMAttr returns the values of an attribute path. Multi-valued
+// attributes give one value per entry; with primitives set, entries of complex
+// attributes stand for their value sub-attribute.
+func resolveSCIMAttr(resource map[string]interface{}, path string, primitives bool) []interface{} {
+	name, sub, _ := strings.Cut(path, ".")
+	var value interface{}
+	for k, v := range resource {
+		if strings.EqualFold(k, name) {
+			value = v
+			break
+		}
+	}
+	if value == nil {
+		return nil
+	}
+	entries, ok := value.([]interface{})
+	if !ok {
+		entries = []interface{}{value}
+	}
+	values := []interface{}{}
+	for _, entry := range entries {
+		m, complex := entry.(map[string]interface{})
+		switch {
+		case sub != "" && complex:
+			values = append(values, resolveSCIMAttr(m, sub, primitives)...)
+		case sub != "":
+		case complex && primitives:
+			values = append(values, resolveSCIMAttr(m, "value", true)...)
+		default:
+			values = append(values, entry)
+		}
+	}
+	return values
+}
+
+// compareSCIMValue applies a comparison operator. Strings compare case
+// insensitively; dateTime strings compare as times.
+func compareSCIMValue(op string, actual, expected interface{}) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		if at, err := time.Parse(time.RFC3339Nano, a); err == nil {
+			if et, err := time.Parse(time.RFC3339Nano, e); err == nil {
+				return compareOrdered(op, at.Compare(et))
+			}
+		}
+		a, e = strings.ToLower(a), strings.ToLower(e)
+		switch op {
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		}
+		return compareOrdered(op, strings.Compare(a, e))
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case a < e:
+			return compareOrdered(op, -1)
+		case a > e:
+			return compareOrdered(op, 1)
+		}
+		return compareOrdered(op, 0)
+	case bool:
+		e, ok := expected.(bool)
+		return ok && op == "eq" && a == e
+	}
+	return false
+}
+
+func compareOrdered(op string, cmp int) bool {
+	switch op {
+	case "eq":
+		return cmp == 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+	return false
+}
+
+// scimFilterParser is a recursive descent parser over the filter tokens
+type scimFilterParser struct {
+	input  string
+	tokens []scimToken
+	pos    int
+}
+
+type scimToken struct {
+	text   string
+	quoted bool // string literal
+}
+
+func newSCIMFilterParser(input string) (*scimFilterParser, error) {
+	p := &scimFilterParser{input: input}
+	for i := 0; i < len(input); {
+		switch ch := input[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(' || ch == ')' || ch == '[' || ch == ']':
+			p.tokens = append(p.tokens, scimToken{text: string(ch)})
+			i++
+		case ch == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, p.errorf("unterminated string")
+			}
+			value, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, p.errorf("invalid string %s", input[i:end+1])
+			}
+			p.tokens = append(p.tokens, scimToken{text: value, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(input) && !strings.ContainsRune(" \t\n\r()[]\"", rune(input[end])) {
+				end++
+			}
+			p.tokens = append(p.tokens, scimToken{text: input[i:end]})
+			i = end
+		}
+	}
+	return p, nil
+}
+
+func (p *scimFilterParser) errorf(format string, args ...interface{}) error {
+	return &SCIMError{Status: http.StatusBadRequest, ScimType: "invalidFilter", Detail: "invalid filter: " + fmt.Sprintf(format, args...)}
+}
+
+func (p *scimFilterParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *scimFilterParser) peek() string {
+	if p.done() || p.tokens[p.pos].quoted {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *scimFilterParser) next() string {
+	if p.done() {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1].text
+}
+
+func (p *scimFilterParser) keyword(word string) bool {
+	if strings.EqualFold(p.peek(), word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// FILTER = FILTER "or" FILTER, and binds tighter than or
+func (p *scimFilterParser) parseOr() (*SCIMFilter, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.keyword(SCIMFilterOr) {
+		var right *SCIMFilter
+		if right, err = p.parseAnd(); err == nil {
+			left = &SCIMFilter{Op: SCIMFilterOr, Children: []*SCIMFilter{left, right}}
+		}
+	}
+	return left, err
+}
+
+func (p *scimFilterParser) parseAnd() (*SCIMFilter, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.keyword(SCIMFilterAnd) {
+		var right *SCIMFilter
+		if right, err = p.parseUnary(); err == nil {
+			left = &SCIMFilter{Op: SCIMFilterAnd, Children: []*SCIMFilter{left, right}}
+		}
+	}
+	return left, err
+}
+
+// parseUnary parses not (FILTER), (FILTER), attr[FILTER], attr pr and attr op value
+func (p *scimFilterParser) parseUnary() (*SCIMFilter, error) {
+	if strings.EqualFold(p.peek(), SCIMFilterNot) && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "(" {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &SCIMFilter{Op: SCIMFilterNot, Children: []*SCIMFilter{inner}}, nil
+	}
+	if p.peek() == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, p.errorf(") expected")
+		}
+		return inner, nil
+	}
+
+	attr := p.peek()
+	if attr == "" || attr == ")" || attr == "]" || attr == "[" {
+		return nil, p.errorf("attribute expected")
+	}
+	p.next()
+	attr = normalizeSCIMAttr(attr)
+	if p.peek() == "[" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != "]" {
+			return nil, p.errorf("] expected")
+		}
+		return &SCIMFilter{Op: SCIMFilterValuePath, Attr: attr, Children: []*SCIMFilter{inner}}, nil
+	}
+
+	op := strings.ToLower(p.peek())
+	if op == SCIMFilterPresent {
+		p.next()
+		return &SCIMFilter{Op: op, Attr: attr}, nil
+	}
+	if !scimCompareOps[op] {
+		return nil, p.errorf("operator expected after %s", attr)
+	}
+	p.next()
+	if p.done() {
+		return nil, p.errorf("value expected after %s %s", attr, op)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	if token.quoted {
+		return &SCIMFilter{Op: op, Attr: attr, Value: token.text}, nil
+	}
+	switch strings.ToLower(token.text) {
+	case "true":
+		return &SCIMFilter{Op: op, Attr: attr, Value: true}, nil
+	case "false":
+		return &SCIMFilter{Op: op, Attr: attr, Value: false}, nil
+	case "null":
+		if op != "eq" && op != "ne" {
+			return nil, p.errorf("null can only be compared with eq and ne")
+		}
+		return &SCIMFilter{Op: op, Attr: attr}, nil
+	}
+	number, err := strconv.ParseFloat(token.text, 64)
+	if err != nil {
+		return nil, p.errorf("invalid value %q", token.text)
+	}
+	return &SCIMFilter{Op: op, Attr: attr, Value: number}, nil
+}
@@ -14,6 +14,7 @@ import (
 
 type UserUsecase interface {
 	GetUsers(c *gin.Context) ([]response.User, error)
+	GetUserByEmail(c *gin.Context, email string) (*response.User, error)
 	CreateUser(c *gin.Context, req request.UserRequest) (*response.User, error)
 	UpdateUser(c *gin.Context, req request.UserRequest) (*response.User, error)
 	DeleteUser(c *gin.Context, req request.UserRequest) error
@@ -41,10 +42,11 @@ func (uc *userUsecase) GetUsers(c *gin.Context) ([]response.User, error) {
 	responseUsers := make([]response.User, 0, len(users))
 	for _, user := range users {
 		responseUsers = append(responseUsers, response.User{
-			ID:    user.ID,
-			UUID:  user.UUID,
-			Email: user.Email,
-			Name:  user.Name,
+			ID:              user.ID,
+			UUID:            user.UUID,
+			Email:           user.Email,
+			Name:            user.Name,
+			EmailVerifiedAt: user.EmailVerifiedAt,
 		})
 	}
 
@@ -52,6 +54,21 @@ func (uc *userUsecase) GetUsers(c *gin.Context) ([]response.User, error) {
 	return responseUsers, nil
 }
 
+// GetUserByEmail returns the user with the email, ignoring case, or repository.ErrUserNotFound
+func (uc *userUsecase) GetUserByEmail(c *gin.Context, email string) (*response.User, error) {
+	user, err := uc.userRepo.GetUserByEmail(c, email)
+	if err != nil {
+		return nil, err
+	}
+	return &response.User{
+		ID:              user.ID,
+		UUID:            user.UUID,
+		Email:           user.Email,
+		Name:            user.Name,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}, nil
+}
+
 func (uc *userUsecase) CreateUser(c *gin.Context, req request.UserRequest) (*response.User, error) {
 	requestID, _ := c.Get("requestID")
 	reqID := requestID.(string)
@@ -68,18 +85,28 @@ func (uc *userUsecase) CreateUser(c *gin.Context, req request.UserRequest) (*res
 		UpdatedAt: &now,
 		DeletedAt: nil,
 	}
+	if req.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+	if req.Password != "" {
+		user.PasswordChangedAt = &now
+	}
 
 	// Call repository
-	createdUser := uc.userRepo.CreateUser(c, user)
+	createdUser, err := uc.userRepo.CreateUser(c, user)
+	if err != nil {
+		return nil, err
+	}
 
 	logger.Info(code.UUCR2, reqID, "User created successfully: "+createdUser.UUID)
 
 	// Convert model to response
 	return &response.User{
-		ID:    createdUser.ID,
-		UUID:  createdUser.UUID,
-		Email: createdUser.Email,
-		Name:  createdUser.Name,
+		ID:              createdUser.ID,
+		UUID:            createdUser.UUID,
+		Email:           createdUser.Email,
+		Name:            createdUser.Name,
+		EmailVerifiedAt: createdUser.EmailVerifiedAt,
 	}, nil
 }
 
@@ -97,9 +124,15 @@ func (uc *userUsecase) UpdateUser(c *gin.Context, req request.UserRequest) (*res
 		Name:      req.Name,
 		UpdatedAt: &now,
 	}
+	if req.Password != "" {
+		user.PasswordChangedAt = &now
+	}
 
 	// Call repository
-	updatedUser := uc.userRepo.UpdateUser(c, user)
+	updatedUser, err := uc.userRepo.UpdateUser(c, user)
+	if err != nil {
+		return nil, err
+	}
 
 	logger.Info(code.UUUP2, reqID, "User updated successfully: "+updatedUser.UUID)
 
@@ -143,10 +176,11 @@ func (uc *userUsecase) ListUsers(c *gin.Context, filter repository.UserQueryFilt
 	responseUsers := make([]response.User, 0, len(users))
 	for _, user := range users {
 		responseUsers = append(responseUsers, response.User{
-			ID:    user.ID,
-			UUID:  user.UUID,
-			Email: user.Email,
-			Name:  user.Name,
+			ID:              user.ID,
+			UUID:            user.UUID,
+			Email:           user.Email,
+			Name:            user.Name,
+			EmailVerifiedAt: user.EmailVerifiedAt,
 		})
 	}
 
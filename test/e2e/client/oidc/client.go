@@ -0,0 +1,263 @@
+// Package oidc is a minimal OpenID Connect relying party used to drive the
+// authorization code + PKCE flow against the test server.
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Client is a relying party registered with the provider
+type Client struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Metadata     map[string]interface{}
+	httpClient   *http.Client
+}
+
+// Tokens is the token endpoint response
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	TokenType    string `json:"token_type"`
+	Error        string `json:"error"`
+}
+
+// NewClient creates a relying party that does not follow redirects
+func NewClient(issuer, clientID, clientSecret, redirectURI string) *Client {
+	return &Client{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURI:  redirectURI,
+		httpClient: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+}
+
+// NewPKCE returns a random code verifier and its S256 challenge
+func NewPKCE() (string, string) {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	verifier := base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Discover loads the provider metadata
+func (rcvr *Client) Discover() error {
+	resp, err := rcvr.httpClient.Get(rcvr.Issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("discovery failed: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(&rcvr.Metadata)
+}
+
+func (rcvr *Client) endpoint(name string) string {
+	v, _ := rcvr.Metadata[name].(string)
+	return v
+}
+
+// Authorize signs in through the login (and consent) pages and returns the authorization code
+func (rcvr *Client) Authorize(email, password, scope, state, nonce, challenge string) (string, error) {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rcvr.ClientID},
+		"redirect_uri":          {rcvr.RedirectURI},
+		"scope":                 {scope},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	authorizeURL := rcvr.endpoint("authorization_endpoint")
+
+	resp, err := rcvr.httpClient.Get(authorizeURL + "?" + params.Encode())
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("authorize page: %s", resp.Status)
+	}
+
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+	form.Set("email", email)
+	form.Set("password", password)
+	resp, err = rcvr.httpClient.PostForm(authorizeURL, form)
+	if err != nil {
+		return "", err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// Consent page
+	if resp.StatusCode == http.StatusOK {
+		m := regexp.MustCompile(`name="ticket" value="([^"]+)"`).FindSubmatch(body)
+		if m == nil {
+			return "", errors.New("consent page without ticket")
+		}
+		resp, err = rcvr.httpClient.PostForm(authorizeURL, url.Values{"ticket": {string(m[1])}, "consent": {"allow"}})
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("expected redirect, got %s", resp.Status)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+	if e := location.Query().Get("error"); e != "" {
+		return "", fmt.Errorf("authorization error: %s", e)
+	}
+	if location.Query().Get("state") != state {
+		return "", errors.New("state mismatch")
+	}
+	return location.Query().Get("code"), nil
+}
+
+// Exchange redeems the authorization code
+func (rcvr *Client) Exchange(code, verifier string) (*Tokens, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rcvr.RedirectURI},
+		"code_verifier": {verifier},
+		"client_id":     {rcvr.ClientID},
+	}
+	if rcvr.ClientSecret != "" {
+		form.Set("client_secret", rcvr.ClientSecret)
+	}
+	resp, err := rcvr.httpClient.PostForm(rcvr.endpoint("token_endpoint"), form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tokens Tokens
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &tokens, fmt.Errorf("token endpoint: %s (%s)", resp.Status, tokens.Error)
+	}
+	return &tokens, nil
+}
+
+// UserInfo calls the userinfo endpoint with the access token
+func (rcvr *Client) UserInfo(accessToken string) (map[string]interface{}, error) {
+	req, _ := http.NewRequest(http.MethodGet, rcvr.endpoint("userinfo_endpoint"), nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := rcvr.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo: %s", resp.Status)
+	}
+	info := map[string]interface{}{}
+	return info, json.NewDecoder(resp.Body).Decode(&info)
+}
+
+// VerifyIDToken checks the ES256 signature against the provider JWKS and the iss / aud / nonce claims
+func (rcvr *Client) VerifyIDToken(idToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "ES256" {
+		return nil, fmt.Errorf("unexpected alg %s", header.Alg)
+	}
+
+	resp, err := rcvr.httpClient.Get(rcvr.endpoint("jwks_uri"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return nil, errors.New("malformed signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	verified := false
+	for _, k := range jwks.Keys {
+		if k.Kid != header.Kid {
+			continue
+		}
+		x, _ := base64.RawURLEncoding.DecodeString(k.X)
+		y, _ := base64.RawURLEncoding.DecodeString(k.Y)
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		verified = ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+	}
+	if !verified {
+		return nil, errors.New("id token signature not verified")
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if claims["iss"] != rcvr.Metadata["issuer"] {
+		return nil, errors.New("issuer mismatch")
+	}
+	if claims["aud"] != rcvr.ClientID {
+		return nil, errors.New("audience mismatch")
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	return claims, nil
+}
+
+func decodeSegment(seg string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
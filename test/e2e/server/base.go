@@ -116,8 +116,16 @@ func InitializeDatabase() error {
 	// Auto-migrate all tables
 	if err := conf.DBConnection.AutoMigrate(
 		&model.Users{},
+		&model.PasswordHistories{},
+		&model.IdentityLinks{},
+		&model.RoleAssignments{},
 		&model.Groups{},
 		&model.Members{},
+		&model.ServiceAccounts{},
+		&model.ServiceAccountCredentials{},
+		&model.PersonalAccessTokens{},
+		&model.UserMFAs{},
+		&model.MFARecoveryCodes{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
@@ -0,0 +1,41 @@
+package testcase
+
+import (
+	"testing"
+
+	"github.com/ryo-arima/locky/test/e2e/client/anonymous"
+	"github.com/ryo-arima/locky/test/e2e/client/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	email := "oidc-user@locky.local"
+	password := "OidcPassword123!"
+	_, _ = anonymous.CreateUser("oidc-user", email, password)
+
+	client := oidc.NewClient("http://localhost:8000", "locky-test-client", "", "http://localhost:8000/callback")
+	require.NoError(t, client.Discover())
+
+	verifier, challenge := oidc.NewPKCE()
+	code, err := client.Authorize(email, password, "openid profile email", "state-1", "nonce-1", challenge)
+	require.NoError(t, err)
+	require.NotEmpty(t, code)
+
+	tokens, err := client.Exchange(code, verifier)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	claims, err := client.VerifyIDToken(tokens.IDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, email, claims["email"])
+
+	info, err := client.UserInfo(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims["sub"], info["sub"])
+
+	// Codes are single-use
+	_, err = client.Exchange(code, verifier)
+	assert.Error(t, err)
+}
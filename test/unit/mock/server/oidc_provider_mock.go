@@ -0,0 +1,183 @@
+package mock
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockOIDCProvider is an upstream OpenID Connect provider for federated login
+// tests. It serves the discovery document, the JWKS and the token endpoint
+// (client_secret_basic + PKCE). Authorize stands in for the provider's login
+// page and returns the code the browser would bring back to the callback.
+type MockOIDCProvider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	kid    string
+	codes  map[string]mockOIDCAuthorization
+	server *httptest.Server
+}
+
+type mockOIDCAuthorization struct {
+	RedirectURI   string
+	CodeChallenge string
+	Claims        map[string]interface{}
+}
+
+// NewMockOIDCProvider starts the provider. It is stopped when the test finishes.
+func NewMockOIDCProvider(t *testing.T, clientID, clientSecret string) *MockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate provider key: %v", err)
+	}
+	p := &MockOIDCProvider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		kid:          "upstream-1",
+		codes:        map[string]mockOIDCAuthorization{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	p.Issuer = p.server.URL
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// Authorize accepts the authorization request URL built by Locky and returns
+// a code for an ID token with the claims. Standard claims (iss, aud, exp, iat,
+// nonce) are filled in; a nil value in claims removes a claim.
+func (p *MockOIDCProvider) Authorize(t *testing.T, authorizationURL string, claims map[string]interface{}) string {
+	t.Helper()
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("invalid authorization url: %v", err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request: %s", authorizationURL)
+	}
+	now := time.Now()
+	idClaims := map[string]interface{}{
+		"iss":   p.Issuer,
+		"aud":   p.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(idClaims, name)
+			continue
+		}
+		idClaims[name] = value
+	}
+	code := randomMockToken()
+	p.mu.Lock()
+	p.codes[code] = mockOIDCAuthorization{RedirectURI: query.Get("redirect_uri"), CodeChallenge: query.Get("code_challenge"), Claims: idClaims}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *MockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 p.Issuer,
+		"authorization_endpoint": p.Issuer + "/authorize",
+		"token_endpoint":         p.Issuer + "/token",
+		"jwks_uri":               p.Issuer + "/jwks",
+	})
+}
+
+func (p *MockOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	enc := base64.RawURLEncoding
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": p.kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   enc.EncodeToString(p.key.N.Bytes()),
+		"e":   enc.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}})
+}
+
+func (p *MockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != url.QueryEscape(p.ClientID) || clientSecret != url.QueryEscape(p.ClientSecret) {
+		writeMockJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	p.mu.Lock()
+	auth, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || auth.RedirectURI != r.PostForm.Get("redirect_uri") || auth.CodeChallenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	idToken, err := p.sign(auth.Claims)
+	if err != nil {
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomMockToken(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *MockOIDCProvider) sign(claims map[string]interface{}) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": p.kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + enc.EncodeToString(signature), nil
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomMockToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -3,6 +3,7 @@ package mock
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,13 +15,14 @@ import (
 
 // MockUserRepository implements repository.UserRepository for testing
 type MockUserRepository struct {
-	Users          []model.Users
-	GetUsersFunc   func(c *gin.Context) []model.Users
-	CreateUserFunc func(c *gin.Context, user model.Users) model.Users
-	UpdateUserFunc func(c *gin.Context, user model.Users) model.Users
-	DeleteUserFunc func(c *gin.Context, user model.Users) model.Users
-	ListUsersFunc  func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error)
-	CountUsersFunc func(c *gin.Context, filter repository.UserQueryFilter) (int64, error)
+	Users              []model.Users
+	GetUsersFunc       func(c *gin.Context) []model.Users
+	GetUserByEmailFunc func(c *gin.Context, email string) (model.Users, error)
+	CreateUserFunc     func(c *gin.Context, user model.Users) (model.Users, error)
+	UpdateUserFunc     func(c *gin.Context, user model.Users) (model.Users, error)
+	DeleteUserFunc     func(c *gin.Context, user model.Users) model.Users
+	ListUsersFunc      func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error)
+	CountUsersFunc     func(c *gin.Context, filter repository.UserQueryFilter) (int64, error)
 }
 
 func (m *MockUserRepository) GetUsers(c *gin.Context) []model.Users {
@@ -30,26 +32,46 @@ func (m *MockUserRepository) GetUsers(c *gin.Context) []model.Users {
 	return m.Users
 }
 
-func (m *MockUserRepository) CreateUser(c *gin.Context, user model.Users) model.Users {
+// GetUserByEmail matches the normalized email like the unique index of the users table
+func (m *MockUserRepository) GetUserByEmail(c *gin.Context, email string) (model.Users, error) {
+	if m.GetUserByEmailFunc != nil {
+		return m.GetUserByEmailFunc(c, email)
+	}
+	for _, u := range m.Users {
+		if repository.NormalizeEmail(u.Email) == repository.NormalizeEmail(email) {
+			return u, nil
+		}
+	}
+	return model.Users{}, repository.ErrUserNotFound
+}
+
+func (m *MockUserRepository) CreateUser(c *gin.Context, user model.Users) (model.Users, error) {
 	if m.CreateUserFunc != nil {
 		return m.CreateUserFunc(c, user)
 	}
+	user.EmailNormalized = repository.NormalizeEmail(user.Email)
+	for _, u := range m.Users {
+		if repository.NormalizeEmail(u.Email) == user.EmailNormalized {
+			return model.Users{}, repository.ErrEmailAlreadyExists
+		}
+	}
 	user.ID = uint(len(m.Users) + 1)
 	m.Users = append(m.Users, user)
-	return user
+	return user, nil
 }
 
-func (m *MockUserRepository) UpdateUser(c *gin.Context, user model.Users) model.Users {
+func (m *MockUserRepository) UpdateUser(c *gin.Context, user model.Users) (model.Users, error) {
 	if m.UpdateUserFunc != nil {
 		return m.UpdateUserFunc(c, user)
 	}
+	user.EmailNormalized = repository.NormalizeEmail(user.Email)
 	for i, u := range m.Users {
 		if u.ID == user.ID {
 			m.Users[i] = user
-			return user
+			return user, nil
 		}
 	}
-	return model.Users{}
+	return model.Users{}, fmt.Errorf("user not found")
 }
 
 func (m *MockUserRepository) DeleteUser(c *gin.Context, user model.Users) model.Users {
@@ -206,39 +228,39 @@ func (m *MockMemberRepository) GetMemberByUUID(c *gin.Context, uuid string) (mod
 	return model.Members{}, fmt.Errorf("member not found")
 }
 
-func (m *MockMemberRepository) CreateMember(c *gin.Context, member *model.Members) interface{} {
+func (m *MockMemberRepository) CreateMember(c *gin.Context, member *model.Members) *gorm.DB {
 	if m.CreateMemberFunc != nil {
-		return m.CreateMemberFunc(c, member)
+		return &gorm.DB{Error: m.CreateMemberFunc(c, member)}
 	}
 	member.ID = uint(len(m.Members) + 1)
 	m.Members = append(m.Members, *member)
-	return nil
+	return &gorm.DB{}
 }
 
-func (m *MockMemberRepository) UpdateMember(c *gin.Context, member *model.Members) interface{} {
+func (m *MockMemberRepository) UpdateMember(c *gin.Context, member *model.Members) *gorm.DB {
 	if m.UpdateMemberFunc != nil {
-		return m.UpdateMemberFunc(c, member)
+		return &gorm.DB{Error: m.UpdateMemberFunc(c, member)}
 	}
 	for i, mem := range m.Members {
 		if mem.ID == member.ID {
 			m.Members[i] = *member
-			return nil
+			break
 		}
 	}
-	return nil
+	return &gorm.DB{}
 }
 
-func (m *MockMemberRepository) DeleteMember(c *gin.Context, uuid string) interface{} {
+func (m *MockMemberRepository) DeleteMember(c *gin.Context, uuid string) *gorm.DB {
 	if m.DeleteMemberFunc != nil {
-		return m.DeleteMemberFunc(c, uuid)
+		return &gorm.DB{Error: m.DeleteMemberFunc(c, uuid)}
 	}
 	for i, mem := range m.Members {
 		if mem.UUID == uuid {
 			m.Members = append(m.Members[:i], m.Members[i+1:]...)
-			return nil
+			break
 		}
 	}
-	return nil
+	return &gorm.DB{}
 }
 
 func (m *MockMemberRepository) ListMembers(c *gin.Context, filter repository.MemberQueryFilter) ([]model.Members, error) {
@@ -261,9 +283,16 @@ type MockCommonRepository struct {
 	InvalidatedTokens  map[string]bool
 	GenerateTokenFunc  func(email, role, uuid string) (string, string, error)
 	ValidateTokenFunc  func(tokenString string) (*model.JWTClaims, error)
+	ValidateForAudFunc func(tokenString, audience string) (*model.JWTClaims, error)
 	InvalidateFunc     func(ctx context.Context, jti string, expiration time.Duration) error
 	IsInvalidatedFunc  func(ctx context.Context, jti string) (bool, error)
 	VerifyPasswordFunc func(hashedPassword, password string) error
+	NeedsRehashFunc    func(hashedPassword string) bool
+	RotateRefreshFunc  func(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error)
+	ValidatePATFunc    func(ctx context.Context, token string) (*model.JWTClaims, error)
+	LoadUserRolesFunc  func(user model.Users) []string
+	RevokedFamilies    map[string]bool
+	BaseConfig         config.BaseConfig
 }
 
 func (m *MockCommonRepository) GenerateJWTToken(claims model.JWTClaims) (string, error) {
@@ -282,6 +311,13 @@ func (m *MockCommonRepository) ValidateJWTToken(tokenString string) (*model.JWTC
 	}, nil
 }
 
+func (m *MockCommonRepository) ValidateJWTTokenForAudience(tokenString, audience string) (*model.JWTClaims, error) {
+	if m.ValidateForAudFunc != nil {
+		return m.ValidateForAudFunc(tokenString, audience)
+	}
+	return m.ValidateJWTToken(tokenString)
+}
+
 func (m *MockCommonRepository) ParseTokenUnverified(tokenString string) (*model.JWTClaims, error) {
 	return &model.JWTClaims{
 		Email: "test@example.com",
@@ -316,6 +352,10 @@ func (m *MockCommonRepository) VerifyPassword(hashedPassword, password string) e
 	if m.VerifyPasswordFunc != nil {
 		return m.VerifyPasswordFunc(hashedPassword, password)
 	}
+	// Real hashes ($argon2id$, $2a$...) are checked with the password hasher
+	if strings.HasPrefix(hashedPassword, "$") {
+		return repository.NewPasswordHasher(m.BaseConfig.YamlConfig.Application.Server.PasswordHashing).Verify(hashedPassword, password)
+	}
 	// Simple mock: just compare strings
 	if hashedPassword == password {
 		return nil
@@ -323,15 +363,62 @@ func (m *MockCommonRepository) VerifyPassword(hashedPassword, password string) e
 	return fmt.Errorf("password mismatch")
 }
 
-func (m *MockCommonRepository) GenerateTokenPair(userID uint, userUUID, email, name, role string) (*model.TokenPair, error) {
+func (m *MockCommonRepository) GenerateTokenPair(userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error) {
+	return &model.TokenPair{
+		AccessToken:  "mock-access-token",
+		RefreshToken: "mock-refresh-token",
+	}, nil
+}
+
+func (m *MockCommonRepository) GenerateAuthorizedTokenPair(clientID string, userID uint, userUUID, email, name string, roles ...string) (*model.TokenPair, error) {
+	return m.GenerateTokenPair(userID, userUUID, email, name, roles...)
+}
+
+func (m *MockCommonRepository) GenerateClientTokenPair(clientID, subjectUUID, name, role string) (*model.TokenPair, error) {
 	return &model.TokenPair{
 		AccessToken:  "mock-access-token",
 		RefreshToken: "mock-refresh-token",
 	}, nil
 }
 
+func (m *MockCommonRepository) RotateRefreshToken(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error) {
+	if m.RotateRefreshFunc != nil {
+		return m.RotateRefreshFunc(ctx, refreshToken, clientID)
+	}
+	return &model.TokenPair{
+		AccessToken:  "mock-access-token",
+		RefreshToken: "mock-refresh-token",
+	}, nil
+}
+
+func (m *MockCommonRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	if m.RevokedFamilies == nil {
+		m.RevokedFamilies = make(map[string]bool)
+	}
+	m.RevokedFamilies[familyID] = true
+	return nil
+}
+
+func (m *MockCommonRepository) TouchSession(ctx context.Context, familyID, userAgent, ipAddress string) error {
+	return nil
+}
+
+func (m *MockCommonRepository) ValidatePersonalAccessToken(ctx context.Context, token string) (*model.JWTClaims, error) {
+	if m.ValidatePATFunc != nil {
+		return m.ValidatePATFunc(ctx, token)
+	}
+	return nil, repository.ErrPersonalAccessTokenInvalid
+}
+
 func (m *MockCommonRepository) GetBaseConfig() config.BaseConfig {
-	return config.BaseConfig{}
+	return m.BaseConfig
+}
+
+func (m *MockCommonRepository) LoadUserRoles(user model.Users) []string {
+	if m.LoadUserRolesFunc != nil {
+		return m.LoadUserRolesFunc(user)
+	}
+	return repository.UserRoles(m.BaseConfig, user, nil)
 }
 
 func (m *MockCommonRepository) GenerateJWTSecret() (string, error) {
@@ -342,6 +429,13 @@ func (m *MockCommonRepository) ValidateJWTSecretStrength(secret string) error {
 	return nil
 }
 
+func (m *MockCommonRepository) NeedsPasswordRehash(hashedPassword string) bool {
+	if m.NeedsRehashFunc != nil {
+		return m.NeedsRehashFunc(hashedPassword)
+	}
+	return false
+}
+
 func (m *MockCommonRepository) HashPassword(password string) (string, error) {
 	return "hashed-" + password, nil
 }
@@ -365,3 +459,373 @@ func (m *MockCommonRepository) SendWelcomeEmail(ctx context.Context, to, name st
 func (m *MockCommonRepository) SendPasswordResetEmail(ctx context.Context, to, name, resetURL string) error {
 	return nil
 }
+
+func (m *MockCommonRepository) SendVerificationEmail(ctx context.Context, to, name, verifyURL string) error {
+	return nil
+}
+
+func (m *MockCommonRepository) GetJWKS() model.JWKSet {
+	return model.JWKSet{Keys: []model.JWK{}}
+}
+
+func (m *MockCommonRepository) StartKeyRotation(ctx context.Context) {}
+
+// MockServiceAccountRepository implements repository.ServiceAccountRepository for testing
+type MockServiceAccountRepository struct {
+	ListServiceAccountsFunc  func(c *gin.Context) ([]model.ServiceAccounts, error)
+	GetServiceAccountFunc    func(c *gin.Context, idOrUUID string) (model.ServiceAccounts, error)
+	CreateServiceAccountFunc func(c *gin.Context, sa *model.ServiceAccounts) error
+	UpdateServiceAccountFunc func(c *gin.Context, sa *model.ServiceAccounts) error
+	DeleteServiceAccountFunc func(c *gin.Context, saUUID string) error
+	ListCredentialsFunc      func(c *gin.Context, saUUID string) ([]model.ServiceAccountCredentials, error)
+	RotateSecretFunc         func(c *gin.Context, saUUID string, grace time.Duration) (*model.ServiceAccountCredentials, string, error)
+	DeleteCredentialFunc     func(c *gin.Context, saUUID, clientID string) error
+	AuthenticateFunc         func(c *gin.Context, clientID, clientSecret string) (*model.ServiceAccounts, error)
+}
+
+func (m *MockServiceAccountRepository) ListServiceAccounts(c *gin.Context) ([]model.ServiceAccounts, error) {
+	if m.ListServiceAccountsFunc != nil {
+		return m.ListServiceAccountsFunc(c)
+	}
+	return []model.ServiceAccounts{}, nil
+}
+
+func (m *MockServiceAccountRepository) GetServiceAccount(c *gin.Context, idOrUUID string) (model.ServiceAccounts, error) {
+	if m.GetServiceAccountFunc != nil {
+		return m.GetServiceAccountFunc(c, idOrUUID)
+	}
+	return model.ServiceAccounts{}, repository.ErrServiceAccountNotFound
+}
+
+func (m *MockServiceAccountRepository) CreateServiceAccount(c *gin.Context, sa *model.ServiceAccounts) error {
+	if m.CreateServiceAccountFunc != nil {
+		return m.CreateServiceAccountFunc(c, sa)
+	}
+	return nil
+}
+
+func (m *MockServiceAccountRepository) UpdateServiceAccount(c *gin.Context, sa *model.ServiceAccounts) error {
+	if m.UpdateServiceAccountFunc != nil {
+		return m.UpdateServiceAccountFunc(c, sa)
+	}
+	return nil
+}
+
+func (m *MockServiceAccountRepository) DeleteServiceAccount(c *gin.Context, saUUID string) error {
+	if m.DeleteServiceAccountFunc != nil {
+		return m.DeleteServiceAccountFunc(c, saUUID)
+	}
+	return nil
+}
+
+func (m *MockServiceAccountRepository) ListCredentials(c *gin.Context, saUUID string) ([]model.ServiceAccountCredentials, error) {
+	if m.ListCredentialsFunc != nil {
+		return m.ListCredentialsFunc(c, saUUID)
+	}
+	return []model.ServiceAccountCredentials{}, nil
+}
+
+func (m *MockServiceAccountRepository) RotateSecret(c *gin.Context, saUUID string, grace time.Duration) (*model.ServiceAccountCredentials, string, error) {
+	if m.RotateSecretFunc != nil {
+		return m.RotateSecretFunc(c, saUUID, grace)
+	}
+	return &model.ServiceAccountCredentials{ServiceAccountUUID: saUUID, ClientID: "sa-mock"}, "mock-secret", nil
+}
+
+func (m *MockServiceAccountRepository) DeleteCredential(c *gin.Context, saUUID, clientID string) error {
+	if m.DeleteCredentialFunc != nil {
+		return m.DeleteCredentialFunc(c, saUUID, clientID)
+	}
+	return nil
+}
+
+func (m *MockServiceAccountRepository) Authenticate(c *gin.Context, clientID, clientSecret string) (*model.ServiceAccounts, error) {
+	if m.AuthenticateFunc != nil {
+		return m.AuthenticateFunc(c, clientID, clientSecret)
+	}
+	return nil, repository.ErrServiceAccountInvalidClient
+}
+
+// MockMFARepository implements repository.MFARepository for testing
+type MockMFARepository struct {
+	GetMFAFunc            func(c *gin.Context, userUUID string) (*model.UserMFAs, error)
+	IsEnabledFunc         func(c *gin.Context, userUUID string) (bool, error)
+	BeginEnrollmentFunc   func(c *gin.Context, userUUID string) (string, error)
+	ConfirmEnrollmentFunc func(c *gin.Context, userUUID, code string) ([]string, error)
+	VerifyFunc            func(c *gin.Context, userUUID, code string) error
+	ResetFunc             func(c *gin.Context, userUUID string) error
+	CreateChallengeFunc   func(ctx context.Context, userUUID, email string) (string, error)
+	ChallengeEmailFunc    func(ctx context.Context, challenge string) (string, error)
+	CompleteChallengeFunc func(c *gin.Context, challenge, code string) (string, error)
+}
+
+func (m *MockMFARepository) GetMFA(c *gin.Context, userUUID string) (*model.UserMFAs, error) {
+	if m.GetMFAFunc != nil {
+		return m.GetMFAFunc(c, userUUID)
+	}
+	return nil, repository.ErrMFANotEnrolled
+}
+
+func (m *MockMFARepository) IsEnabled(c *gin.Context, userUUID string) (bool, error) {
+	if m.IsEnabledFunc != nil {
+		return m.IsEnabledFunc(c, userUUID)
+	}
+	return false, nil
+}
+
+func (m *MockMFARepository) BeginEnrollment(c *gin.Context, userUUID string) (string, error) {
+	if m.BeginEnrollmentFunc != nil {
+		return m.BeginEnrollmentFunc(c, userUUID)
+	}
+	return "", nil
+}
+
+func (m *MockMFARepository) ConfirmEnrollment(c *gin.Context, userUUID, code string) ([]string, error) {
+	if m.ConfirmEnrollmentFunc != nil {
+		return m.ConfirmEnrollmentFunc(c, userUUID, code)
+	}
+	return []string{}, nil
+}
+
+func (m *MockMFARepository) Verify(c *gin.Context, userUUID, code string) error {
+	if m.VerifyFunc != nil {
+		return m.VerifyFunc(c, userUUID, code)
+	}
+	return repository.ErrMFANotEnrolled
+}
+
+func (m *MockMFARepository) Reset(c *gin.Context, userUUID string) error {
+	if m.ResetFunc != nil {
+		return m.ResetFunc(c, userUUID)
+	}
+	return nil
+}
+
+func (m *MockMFARepository) CreateChallenge(ctx context.Context, userUUID, email string) (string, error) {
+	if m.CreateChallengeFunc != nil {
+		return m.CreateChallengeFunc(ctx, userUUID, email)
+	}
+	return "", repository.ErrMFAStorageUnavailable
+}
+
+func (m *MockMFARepository) ChallengeEmail(ctx context.Context, challenge string) (string, error) {
+	if m.ChallengeEmailFunc != nil {
+		return m.ChallengeEmailFunc(ctx, challenge)
+	}
+	return "", repository.ErrMFAChallengeInvalid
+}
+
+func (m *MockMFARepository) CompleteChallenge(c *gin.Context, challenge, code string) (string, error) {
+	if m.CompleteChallengeFunc != nil {
+		return m.CompleteChallengeFunc(c, challenge, code)
+	}
+	return "", repository.ErrMFAChallengeInvalid
+}
+
+func (m *MockMFARepository) Issuer() string {
+	return repository.DefaultMFAIssuer
+}
+
+// MockPersonalAccessTokenRepository implements repository.PersonalAccessTokenRepository for testing
+type MockPersonalAccessTokenRepository struct {
+	ListTokensFunc      func(c *gin.Context, userUUID string) ([]model.PersonalAccessTokens, error)
+	CreateTokenFunc     func(c *gin.Context, pat *model.PersonalAccessTokens) (string, error)
+	RevokeTokenFunc     func(c *gin.Context, userUUID, idOrUUID string) error
+	RevokeAllTokensFunc func(c *gin.Context, userUUID string) (int64, error)
+}
+
+func (m *MockPersonalAccessTokenRepository) ListTokens(c *gin.Context, userUUID string) ([]model.PersonalAccessTokens, error) {
+	if m.ListTokensFunc != nil {
+		return m.ListTokensFunc(c, userUUID)
+	}
+	return []model.PersonalAccessTokens{}, nil
+}
+
+func (m *MockPersonalAccessTokenRepository) CreateToken(c *gin.Context, pat *model.PersonalAccessTokens) (string, error) {
+	if m.CreateTokenFunc != nil {
+		return m.CreateTokenFunc(c, pat)
+	}
+	return repository.PersonalAccessTokenPrefix + "mock", nil
+}
+
+func (m *MockPersonalAccessTokenRepository) RevokeToken(c *gin.Context, userUUID, idOrUUID string) error {
+	if m.RevokeTokenFunc != nil {
+		return m.RevokeTokenFunc(c, userUUID, idOrUUID)
+	}
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) RevokeAllTokens(c *gin.Context, userUUID string) (int64, error) {
+	if m.RevokeAllTokensFunc != nil {
+		return m.RevokeAllTokensFunc(c, userUUID)
+	}
+	return 0, nil
+}
+
+// MockLoginLockoutRepository implements repository.LoginLockoutRepository for testing.
+// Without funcs nothing is ever locked out.
+type MockLoginLockoutRepository struct {
+	CheckFunc           func(ctx context.Context, email, ip string) (*model.LoginLockouts, error)
+	RegisterFailureFunc func(ctx context.Context, email, ip string) (*model.LoginLockouts, error)
+	RegisterSuccessFunc func(ctx context.Context, email string) error
+	ListLockoutsFunc    func(ctx context.Context, scope string) ([]model.LoginLockouts, error)
+	ClearLockoutFunc    func(ctx context.Context, scope, key string) (bool, error)
+}
+
+func (m *MockLoginLockoutRepository) Check(ctx context.Context, email, ip string) (*model.LoginLockouts, error) {
+	if m.CheckFunc != nil {
+		return m.CheckFunc(ctx, email, ip)
+	}
+	return nil, nil
+}
+
+func (m *MockLoginLockoutRepository) RegisterFailure(ctx context.Context, email, ip string) (*model.LoginLockouts, error) {
+	if m.RegisterFailureFunc != nil {
+		return m.RegisterFailureFunc(ctx, email, ip)
+	}
+	return nil, nil
+}
+
+func (m *MockLoginLockoutRepository) RegisterSuccess(ctx context.Context, email string) error {
+	if m.RegisterSuccessFunc != nil {
+		return m.RegisterSuccessFunc(ctx, email)
+	}
+	return nil
+}
+
+func (m *MockLoginLockoutRepository) ListLockouts(ctx context.Context, scope string) ([]model.LoginLockouts, error) {
+	if m.ListLockoutsFunc != nil {
+		return m.ListLockoutsFunc(ctx, scope)
+	}
+	return []model.LoginLockouts{}, nil
+}
+
+func (m *MockLoginLockoutRepository) ClearLockout(ctx context.Context, scope, key string) (bool, error) {
+	if m.ClearLockoutFunc != nil {
+		return m.ClearLockoutFunc(ctx, scope, key)
+	}
+	return false, nil
+}
+
+// MockPasswordPolicyRepository implements repository.PasswordPolicyRepository for testing.
+// Without funcs every password is accepted and nothing is recorded.
+type MockPasswordPolicyRepository struct {
+	ValidateFunc       func(c *gin.Context, password, userUUID string) ([]repository.PasswordPolicyViolation, error)
+	RecordPasswordFunc func(c *gin.Context, userUUID, passwordHash string) error
+}
+
+func (m *MockPasswordPolicyRepository) Validate(c *gin.Context, password, userUUID string) ([]repository.PasswordPolicyViolation, error) {
+	if m.ValidateFunc != nil {
+		return m.ValidateFunc(c, password, userUUID)
+	}
+	return nil, nil
+}
+
+func (m *MockPasswordPolicyRepository) RecordPassword(c *gin.Context, userUUID, passwordHash string) error {
+	if m.RecordPasswordFunc != nil {
+		return m.RecordPasswordFunc(c, userUUID, passwordHash)
+	}
+	return nil
+}
+
+// MockIdentityLinkRepository implements repository.IdentityLinkRepository in memory
+type MockIdentityLinkRepository struct {
+	Links []model.IdentityLinks
+}
+
+func (m *MockIdentityLinkRepository) GetLink(c *gin.Context, provider, subject string) (*model.IdentityLinks, error) {
+	for i := range m.Links {
+		if m.Links[i].Provider == provider && m.Links[i].Subject == subject {
+			link := m.Links[i]
+			return &link, nil
+		}
+	}
+	return nil, repository.ErrIdentityLinkNotFound
+}
+
+func (m *MockIdentityLinkRepository) ListLinks(c *gin.Context, userUUID string) ([]model.IdentityLinks, error) {
+	links := []model.IdentityLinks{}
+	for _, link := range m.Links {
+		if link.UserUUID == userUUID {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (m *MockIdentityLinkRepository) CreateLink(c *gin.Context, link model.IdentityLinks) (*model.IdentityLinks, error) {
+	if _, err := m.GetLink(c, link.Provider, link.Subject); err == nil {
+		return nil, repository.ErrIdentityLinkExists
+	}
+	now := time.Now()
+	link.ID = uint(len(m.Links) + 1)
+	link.UUID = fmt.Sprintf("link-%d", link.ID)
+	link.CreatedAt = &now
+	m.Links = append(m.Links, link)
+	return &link, nil
+}
+
+func (m *MockIdentityLinkRepository) TouchLink(c *gin.Context, link model.IdentityLinks) error {
+	now := time.Now()
+	for i := range m.Links {
+		if m.Links[i].ID == link.ID {
+			m.Links[i].LastLoginAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MockIdentityLinkRepository) DeleteLink(c *gin.Context, userUUID, linkUUID string) error {
+	for i, link := range m.Links {
+		if link.UserUUID == userUUID && link.UUID == linkUUID {
+			m.Links = append(m.Links[:i], m.Links[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrIdentityLinkNotFound
+}
+
+// MockRoleAssignmentRepository implements repository.RoleAssignmentRepository in memory
+type MockRoleAssignmentRepository struct {
+	Assignments []model.RoleAssignments
+}
+
+func (m *MockRoleAssignmentRepository) ListRoleAssignments(c *gin.Context, userUUID string) ([]model.RoleAssignments, error) {
+	assignments := []model.RoleAssignments{}
+	for _, assignment := range m.Assignments {
+		if assignment.UserUUID == userUUID {
+			assignments = append(assignments, assignment)
+		}
+	}
+	return assignments, nil
+}
+
+func (m *MockRoleAssignmentRepository) GrantRole(c *gin.Context, assignment model.RoleAssignments) (*model.RoleAssignments, error) {
+	for _, existing := range m.Assignments {
+		if existing.UserUUID == assignment.UserUUID && existing.Role == assignment.Role {
+			return nil, repository.ErrRoleAlreadyAssigned
+		}
+	}
+	now := time.Now()
+	assignment.ID = uint(len(m.Assignments) + 1)
+	assignment.UUID = fmt.Sprintf("assignment-%d", assignment.ID)
+	assignment.CreatedAt = &now
+	m.Assignments = append(m.Assignments, assignment)
+	return &assignment, nil
+}
+
+func (m *MockRoleAssignmentRepository) RevokeRole(c *gin.Context, userUUID, role string) error {
+	for i, assignment := range m.Assignments {
+		if assignment.UserUUID == userUUID && assignment.Role == role {
+			m.Assignments = append(m.Assignments[:i], m.Assignments[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrRoleAssignmentNotFound
+}
+
+// RolesOf returns the roles granted to a user, for LoadUserRolesFunc
+func (m *MockRoleAssignmentRepository) RolesOf(conf config.BaseConfig, user model.Users) []string {
+	assignments, _ := m.ListRoleAssignments(nil, user.UUID)
+	return repository.UserRoles(conf, user, assignments)
+}
@@ -0,0 +1,100 @@
+package mock
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// MailMessage is an email accepted by MockSMTPServer
+type MailMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// MockSMTPServer is a minimal local SMTP stand-in (like the mailserver in
+// docker-compose.yaml) that accepts PLAIN auth and records every message.
+type MockSMTPServer struct {
+	Host     string
+	Port     int
+	Messages chan MailMessage
+	listener net.Listener
+}
+
+// NewMockSMTPServer listens on 127.0.0.1 so net/smtp allows PLAIN auth without TLS.
+// The server is stopped when the test finishes.
+func NewMockSMTPServer(t *testing.T) *MockSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start mock smtp server: %v", err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	server := &MockSMTPServer{Host: "127.0.0.1", Port: addr.Port, Messages: make(chan MailMessage, 16), listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *MockSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *MockSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost mock smtp")
+	var msg MailMessage
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = MailMessage{From: strings.Trim(line[len("MAIL FROM:"):], "<> ")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if strings.TrimRight(dataLine, "\r\n") == "." {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.Data = data.String()
+			s.Messages <- msg
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
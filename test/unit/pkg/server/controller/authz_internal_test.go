@@ -0,0 +1,165 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const authzGroupUUID = "group-uuid"
+
+// fakeIntrospection answers for the tokens it knows; others are inactive
+type fakeIntrospection map[string]model.JWTClaims
+
+func (f fakeIntrospection) Introspect(c *gin.Context, token string) (*model.JWTClaims, error) {
+	if claims, ok := f[token]; ok {
+		return &claims, nil
+	}
+	return nil, nil
+}
+
+func (f fakeIntrospection) Revoke(c *gin.Context, claims model.JWTClaims) error { return nil }
+
+func newAuthzTestRouter(t *testing.T, caller model.JWTClaims) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	appEnforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+	resourceEnforcer, err := casbin.NewSyncedEnforcer("../../../../.etc/casbin/resources/model.conf", "../../../../.etc/casbin/resources/policy.csv")
+	require.NoError(t, err)
+
+	members := &mock.MockMemberRepository{Members: []model.Members{}}
+	members.ListMembersFunc = func(c *gin.Context, filter repository.MemberQueryFilter) ([]model.Members, error) {
+		if *filter.GroupUUID == authzGroupUUID && *filter.UserUUID == "alice-uuid" {
+			return []model.Members{{GroupUUID: authzGroupUUID, UserUUID: "alice-uuid", Role: "member"}}, nil
+		}
+		return []model.Members{}, nil
+	}
+	users := &mock.MockUserRepository{
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			if filter.UUID != nil && *filter.UUID == "alice-uuid" {
+				return []model.Users{{ID: 7, UUID: "alice-uuid", Email: "alice@example.com"}}, nil
+			}
+			return []model.Users{}, nil
+		},
+	}
+	common := &mock.MockCommonRepository{LoadUserRolesFunc: func(user model.Users) []string { return []string{"user"} }}
+	tokens := fakeIntrospection{
+		"pat-token":        {UUID: "bob-uuid", Role: "user", TokenUse: model.TokenUsePAT, Scope: "groups:read"},
+		"alice-pat":        {UUID: "alice-uuid", Role: "user", TokenUse: model.TokenUsePAT, Scope: "groups:read"},
+		"alice-member-pat": {UUID: "alice-uuid", Role: "user", TokenUse: model.TokenUsePAT, Scope: "member:read"},
+	}
+	authz := repository.NewAuthorizationRepository(appEnforcer, resourceEnforcer, repository.NewGroupPermissionRepository(members, resourceEnforcer))
+	ctrl := controller.NewAuthzControllerForInternal(authz, tokens, users, common, appEnforcer)
+
+	router := gin.New()
+	internal := router.Group("/v1/internal", func(c *gin.Context) { c.Set("user_claims", &caller) })
+	internal.POST("/authz/check", ctrl.Check)
+	internal.POST("/authz/check/batch", ctrl.CheckBatch)
+	return router
+}
+
+func serveAuthz(t *testing.T, router *gin.Engine, path string, body interface{}) (int, response.AuthzResponse) {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res response.AuthzResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return w.Code, res
+}
+
+func TestAuthzCheck_Caller(t *testing.T) {
+	router := newAuthzTestRouter(t, model.JWTClaims{UUID: "alice-uuid", Roles: []string{"user"}})
+
+	code, res := serveAuthz(t, router, "/v1/internal/authz/check", request.AuthzCheckRequest{Object: "groups", Action: "read"})
+	require.Equal(t, http.StatusOK, code, res.Message)
+	require.NotNil(t, res.Result)
+	assert.True(t, res.Result.Allowed)
+	assert.Equal(t, "alice-uuid", res.Result.Subject)
+	assert.Equal(t, model.PolicySetApp, res.Result.PolicySet)
+	assert.Equal(t, []string{"user", "groups", "read"}, res.Result.Policy)
+
+	// Checking other subjects needs authz read
+	code, res = serveAuthz(t, router, "/v1/internal/authz/check", request.AuthzCheckRequest{UserUUID: "bob-uuid", Object: "groups", Action: "read"})
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "AUTHZ_CHECK_003", res.Code)
+
+	code, res = serveAuthz(t, router, "/v1/internal/authz/check", request.AuthzCheckRequest{Object: "groups"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "AUTHZ_CHECK_002", res.Code)
+}
+
+func TestAuthzCheck_Batch(t *testing.T) {
+	router := newAuthzTestRouter(t, model.JWTClaims{UUID: "admin-uuid", Roles: []string{"admin"}})
+
+	code, res := serveAuthz(t, router, "/v1/internal/authz/check/batch", request.AuthzBatchCheckRequest{Checks: []request.AuthzCheckRequest{
+		{UserUUID: "alice-uuid", Object: "member", Action: "read", GroupUUID: authzGroupUUID},
+		{UserUUID: "alice-uuid", Object: "group_info", Action: "read", GroupUUID: authzGroupUUID},
+		{UserUUID: "alice-uuid", Object: "secret", Action: "read", GroupUUID: authzGroupUUID},
+		{UserUUID: "alice-uuid", Object: "member", Action: "read", GroupUUID: "other-group"},
+		{UserUUID: "ghost-uuid", Object: "groups", Action: "read"},
+		{Token: "pat-token", Object: "groups", Action: "write"},
+		{Token: "expired-token", Object: "groups", Action: "read"},
+	}})
+	require.Equal(t, http.StatusOK, code, res.Message)
+	require.Len(t, res.Results, 7)
+
+	assert.True(t, res.Results[0].Allowed)
+	assert.Equal(t, model.PolicySetResources, res.Results[0].PolicySet)
+	assert.Equal(t, "member", res.Results[0].Role)
+	assert.Equal(t, []string{"member", "member", "read"}, res.Results[0].Policy)
+	assert.True(t, res.Results[1].Allowed)
+	assert.Equal(t, []string{"member", "group_info", "read"}, res.Results[1].Policy)
+
+	assert.False(t, res.Results[2].Allowed)
+	assert.Equal(t, repository.AuthzReasonNoPolicy, res.Results[2].Reason)
+	assert.Equal(t, repository.AuthzReasonNotMember, res.Results[3].Reason)
+	assert.Equal(t, repository.AuthzReasonUserNotFound, res.Results[4].Reason)
+	assert.Equal(t, "bob-uuid", res.Results[5].Subject)
+	assert.Equal(t, repository.AuthzReasonTokenScope, res.Results[5].Reason)
+	assert.Equal(t, repository.AuthzReasonTokenInactive, res.Results[6].Reason)
+
+	code, res = serveAuthz(t, router, "/v1/internal/authz/check/batch", request.AuthzBatchCheckRequest{})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "AUTHZ_CHECK_005", res.Code)
+}
+
+func TestAuthzCheck_PATScopeInGroup(t *testing.T) {
+	router := newAuthzTestRouter(t, model.JWTClaims{UUID: "admin-uuid", Roles: []string{"admin"}})
+
+	code, res := serveAuthz(t, router, "/v1/internal/authz/check/batch", request.AuthzBatchCheckRequest{Checks: []request.AuthzCheckRequest{
+		{Token: "alice-pat", Object: "member", Action: "read", GroupUUID: authzGroupUUID},
+		{Token: "alice-pat", Object: "group_info", Action: "read", GroupUUID: authzGroupUUID},
+		{Token: "alice-member-pat", Object: "member", Action: "read", GroupUUID: authzGroupUUID},
+		{UserUUID: "alice-uuid", Object: "member", Action: "read", GroupUUID: authzGroupUUID},
+	}})
+	require.Equal(t, http.StatusOK, code, res.Message)
+	require.Len(t, res.Results, 4)
+
+	// Alice's member role allows these, but her token is not scoped for them
+	for _, result := range res.Results[:2] {
+		assert.False(t, result.Allowed)
+		assert.Equal(t, "alice-uuid", result.Subject)
+		assert.Equal(t, repository.AuthzReasonTokenScope, result.Reason)
+	}
+	assert.True(t, res.Results[2].Allowed)
+	assert.Equal(t, model.PolicySetResources, res.Results[2].PolicySet)
+	assert.True(t, res.Results[3].Allowed)
+}
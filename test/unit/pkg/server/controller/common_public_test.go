@@ -1,9 +1,15 @@
 package controller_test
 
 import (
+	"fmt"
+	"net/http"
 	"testing"
 
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
 	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
 	mock "github.com/ryo-arima/locky/test/unit/mock/server"
 	"github.com/stretchr/testify/assert"
 )
@@ -12,7 +18,7 @@ func TestNewCommonControllerForPublic(t *testing.T) {
 	userRepo := &mock.MockUserRepository{}
 	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
 
-	ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo)
+	ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
 
 	assert.NotNil(t, ctrl)
 }
@@ -32,3 +38,46 @@ func TestNewCommonControllerForPrivate(t *testing.T) {
 
 	assert.NotNil(t, ctrl)
 }
+
+// BenchmarkLogin measures the login handler. The user repository is a map,
+// so this says nothing about the database; lookups against MySQL at up to
+// 100k users are measured by BenchmarkUserRepository_GetUserByEmail (-tags mysql).
+// Loading or listing users fails the benchmark.
+func BenchmarkLogin(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	const count = 1000
+	byEmail := make(map[string]model.Users, count)
+	for i := 0; i < count; i++ {
+		email := fmt.Sprintf("User%d@Example.com", i)
+		byEmail[repository.NormalizeEmail(email)] = model.Users{ID: uint(i + 1), UUID: fmt.Sprintf("user-%d", i), Email: email, Password: "Password123!"}
+	}
+	userRepo := &mock.MockUserRepository{
+		GetUserByEmailFunc: func(c *gin.Context, email string) (model.Users, error) {
+			if user, ok := byEmail[repository.NormalizeEmail(email)]; ok {
+				return user, nil
+			}
+			return model.Users{}, repository.ErrUserNotFound
+		},
+		GetUsersFunc: func(c *gin.Context) []model.Users {
+			b.Fatal("login must not load every user")
+			return nil
+		},
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			b.Fatal("login must not list users")
+			return nil, nil
+		},
+	}
+	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
+	ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/v1/share/common/auth/tokens", ctrl.Login)
+	body := map[string]string{"email": fmt.Sprintf("user%d@example.com", count/2), "password": "Password123!"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if w, _ := postLoginJSON(router, "/v1/share/common/auth/tokens", body); w.Code != http.StatusOK {
+			b.Fatalf("login failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+}
@@ -18,7 +18,7 @@ func TestNewCommonControllerForPublic(t *testing.T) {
 	commonRepo := repository.NewCommonRepository(testHelper.BaseConfig, nil)
 
 	// Test
-	commonController := controller.NewCommonControllerForPublic(userRepo, commonRepo)
+	commonController := controller.NewCommonControllerForPublic(userRepo, commonRepo, repository.NewMFARepository(testHelper.BaseConfig, nil), repository.NewLoginLockoutRepository(testHelper.BaseConfig, nil), repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
 
 	// Assert using go-cmp
 	isNil := commonController == nil
@@ -111,7 +111,7 @@ func TestCommonControllers_TableDriven(t *testing.T) {
 			var commonController interface{}
 			switch tt.controllerType {
 			case "public":
-				commonController = controller.NewCommonControllerForPublic(userRepo, commonRepo)
+				commonController = controller.NewCommonControllerForPublic(userRepo, commonRepo, repository.NewMFARepository(testHelper.BaseConfig, nil), repository.NewLoginLockoutRepository(testHelper.BaseConfig, nil), repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
 			case "private":
 				commonController = controller.NewCommonControllerForPrivate(commonRepo)
 			case "internal":
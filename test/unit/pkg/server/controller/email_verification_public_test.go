@@ -0,0 +1,237 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var verifyLinkPattern = regexp.MustCompile(`https://app\.test/verify-email\?token=\S+`)
+
+type verificationTestServer struct {
+	router *gin.Engine
+	common repository.CommonRepository
+	smtp   *mock.MockSMTPServer
+	db     sqlmock.Sqlmock
+	user   *model.Users
+}
+
+func newVerificationTestServer(t *testing.T) *verificationTestServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	sqlDB, db, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"VERSION()"}).AddRow("8.0.0"))
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	smtpServer := mock.NewMockSMTPServer(t)
+	conf := config.BaseConfig{DBConnection: gormDB}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.EmailVerification = config.EmailVerification{VerifyURL: "https://app.test/verify-email"}
+	conf.YamlConfig.Application.Mail = config.Mail{Host: smtpServer.Host, Port: smtpServer.Port, Username: "locky", Password: "secret", From: "noreply@locky.local"}
+	common := repository.NewCommonRepository(conf, client)
+
+	ts := &verificationTestServer{
+		common: common,
+		smtp:   smtpServer,
+		db:     db,
+		user:   &model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice"},
+	}
+	userRepo := &mock.MockUserRepository{
+		GetUserByEmailFunc: func(c *gin.Context, email string) (model.Users, error) {
+			if repository.NormalizeEmail(email) == ts.user.Email {
+				return *ts.user, nil
+			}
+			return model.Users{}, repository.ErrUserNotFound
+		},
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			if filter.UUID != nil && *filter.UUID == ts.user.UUID {
+				return []model.Users{*ts.user}, nil
+			}
+			return []model.Users{}, nil
+		},
+	}
+
+	ctrl := controller.NewEmailVerificationControllerForPublic(userRepo, common, repository.NewEmailVerificationRepository(conf, common, client))
+	ts.router = gin.New()
+	ts.router.Use(middleware.RequestID())
+	ts.router.POST("/v1/public/user/verify", ctrl.VerifyEmail)
+	ts.router.POST("/v1/public/user/verify/resend", ctrl.ResendVerification)
+	return ts
+}
+
+func (ts *verificationTestServer) post(path string, body interface{}) (*httptest.ResponseRecorder, response.CommonResponse) {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	var resp response.CommonResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func (ts *verificationTestServer) nextMail(t *testing.T) mock.MailMessage {
+	t.Helper()
+	select {
+	case msg := <-ts.smtp.Messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no email sent")
+		return mock.MailMessage{}
+	}
+}
+
+func TestEmailVerification_ResendAndVerify(t *testing.T) {
+	ts := newVerificationTestServer(t)
+
+	w, resp := ts.post("/v1/public/user/verify/resend", map[string]string{"email": "Alice@Example.com"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", resp.Code)
+
+	msg := ts.nextMail(t)
+	assert.Equal(t, []string{"alice@example.com"}, msg.To)
+	assert.Contains(t, msg.Data, "Subject: Verify your email address")
+	link := verifyLinkPattern.FindString(msg.Data)
+	require.NotEmpty(t, link, msg.Data)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+
+	ts.db.ExpectBegin()
+	ts.db.ExpectExec("UPDATE `users` SET `email_verified_at`=\\?").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "alice-uuid", "alice@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	ts.db.ExpectCommit()
+
+	w, resp = ts.post("/v1/public/user/verify", map[string]string{"token": token})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Email address verified", resp.Message)
+	assert.NoError(t, ts.db.ExpectationsWereMet())
+
+	welcome := ts.nextMail(t)
+	assert.Contains(t, welcome.Data, "Subject: Welcome to Locky!")
+	assert.Contains(t, welcome.Data, "Hello Alice")
+}
+
+func TestEmailVerification_ResendIsThrottledAndGeneric(t *testing.T) {
+	ts := newVerificationTestServer(t)
+
+	_, first := ts.post("/v1/public/user/verify/resend", map[string]string{"email": "alice@example.com"})
+	ts.nextMail(t)
+	_, second := ts.post("/v1/public/user/verify/resend", map[string]string{"email": "alice@example.com"})
+	_, unknown := ts.post("/v1/public/user/verify/resend", map[string]string{"email": "nobody@example.com"})
+	assert.Equal(t, first, second)
+	assert.Equal(t, first, unknown)
+
+	select {
+	case msg := <-ts.smtp.Messages:
+		t.Fatalf("unexpected email to %v", msg.To)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	now := time.Now()
+	ts.user.EmailVerifiedAt = &now
+	w, resp := ts.post("/v1/public/user/verify/resend", map[string]string{"email": "alice@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, first, resp)
+}
+
+func TestEmailVerification_RejectsOtherTokens(t *testing.T) {
+	ts := newVerificationTestServer(t)
+	pair, err := ts.common.GenerateTokenPair(ts.user.ID, ts.user.UUID, ts.user.Email, ts.user.Name, "user")
+	require.NoError(t, err)
+
+	for _, token := range []string{pair.AccessToken, pair.RefreshToken, "not-a-token"} {
+		w, resp := ts.post("/v1/public/user/verify", map[string]string{"token": token})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "USER_VERIFY_002", resp.Code)
+	}
+
+	w, resp := ts.post("/v1/public/user/verify", map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "USER_VERIFY_001", resp.Code)
+}
+
+func TestEmailVerification_AlreadyVerifiedAndChangedEmail(t *testing.T) {
+	ts := newVerificationTestServer(t)
+	evRepo := repository.NewEmailVerificationRepository(ts.common.GetBaseConfig(), ts.common, nil)
+	token, err := evRepo.CreateVerificationToken(*ts.user)
+	require.NoError(t, err)
+
+	now := time.Now()
+	ts.user.EmailVerifiedAt = &now
+	ts.db.ExpectBegin()
+	ts.db.ExpectExec("UPDATE `users` SET `email_verified_at`=\\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	ts.db.ExpectCommit()
+	w, resp := ts.post("/v1/public/user/verify", map[string]string{"token": token})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Email address already verified", resp.Message)
+
+	ts.user.Email = "alice@new.example.com"
+	ts.db.ExpectBegin()
+	ts.db.ExpectExec("UPDATE `users` SET `email_verified_at`=\\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	ts.db.ExpectCommit()
+	w, resp = ts.post("/v1/public/user/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "USER_VERIFY_002", resp.Code)
+}
+
+func TestLogin_RequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	now := time.Now()
+	unverified := model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: string(hash)}
+	verified := model.Users{ID: 8, UUID: "bob-uuid", Email: "bob@example.com", Name: "Bob", Password: string(hash), EmailVerifiedAt: &now}
+
+	for _, requireVerified := range []bool{true, false} {
+		commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
+		commonRepo.BaseConfig.YamlConfig.Application.Server.EmailVerification.RequireForLogin = requireVerified
+		userRepo := &mock.MockUserRepository{Users: []model.Users{unverified, verified}}
+		ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
+		router := gin.New()
+		router.POST("/v1/share/common/auth/tokens", ctrl.Login)
+
+		w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": "Password123!"})
+		if requireVerified {
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, "AUTH_LOGIN_008", resp.Code)
+		} else {
+			assert.NotEqual(t, http.StatusForbidden, w.Code, w.Body.String())
+		}
+
+		w, _ = postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "bob@example.com", "password": "Password123!"})
+		assert.NotEqual(t, http.StatusForbidden, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,356 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type federationFixture struct {
+	router   *gin.Engine
+	common   repository.CommonRepository
+	provider *mock.MockOIDCProvider
+	users    *mock.MockUserRepository
+	links    *mock.MockIdentityLinkRepository
+	// cookies is the browser's cookie jar, kept across requests
+	cookies map[string]*http.Cookie
+}
+
+func newFederationFixture(t *testing.T, linkByEmail, autoProvision bool) *federationFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+
+	provider := mock.NewMockOIDCProvider(t, "locky", "upstream-secret")
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.Federation.Providers = []config.UpstreamProvider{{
+		Name:          "upstream",
+		DisplayName:   "Upstream",
+		Issuer:        provider.Issuer,
+		ClientID:      provider.ClientID,
+		ClientSecret:  provider.ClientSecret,
+		RedirectURL:   "https://locky.example.com" + controller.FederationBasePath + "/upstream/callback",
+		LinkByEmail:   linkByEmail,
+		AutoProvision: autoProvision,
+	}}
+	common := repository.NewCommonRepository(conf, client)
+
+	f := &federationFixture{common: common, provider: provider, links: &mock.MockIdentityLinkRepository{}, cookies: map[string]*http.Cookie{}}
+	f.users = &mock.MockUserRepository{
+		Users: []model.Users{
+			{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice"},
+			{ID: 8, UUID: "bob-uuid", Email: "bob@example.com", Name: "Bob"},
+		},
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			for _, u := range f.users.Users {
+				if filter.UUID != nil && u.UUID == *filter.UUID {
+					return []model.Users{u}, nil
+				}
+			}
+			return []model.Users{}, nil
+		},
+	}
+	fedRepo := repository.NewFederationRepository(conf, client)
+	public := controller.NewFederationControllerForPublic(fedRepo, f.links, f.users, common, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{})
+	internal := controller.NewFederationControllerForInternal(fedRepo, f.links, f.users)
+
+	f.router = gin.New()
+	f.router.Use(middleware.RequestID())
+	federation := f.router.Group(controller.FederationBasePath)
+	federation.GET("/providers", public.ListProviders)
+	federation.GET("/:provider/login", public.Login)
+	federation.GET("/:provider/callback", public.Callback)
+	me := f.router.Group("/v1/internal", middleware.ForInternal(common, enforcer))
+	me.GET("/me/identities", middleware.CasbinAuthorization(enforcer, "identities", "read"), internal.ListMyIdentities)
+	me.POST("/me/identity/:provider", middleware.CasbinAuthorization(enforcer, "identities", "write"), middleware.DenyImpersonation(), internal.LinkMyIdentity)
+	me.DELETE("/me/identity/:id", middleware.CasbinAuthorization(enforcer, "identities", "write"), middleware.DenyImpersonation(), internal.UnlinkMyIdentity)
+	return f
+}
+
+func (f *federationFixture) do(method, path, bearer string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	for _, cookie := range f.cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(f.cookies, cookie.Name)
+			continue
+		}
+		f.cookies[cookie.Name] = cookie
+	}
+	return w
+}
+
+// login starts a federated login and returns the authorization URL
+func (f *federationFixture) login(t *testing.T) string {
+	t.Helper()
+	w := f.do(http.MethodGet, controller.FederationBasePath+"/upstream/login", "")
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+	return w.Header().Get("Location")
+}
+
+// callback sends the browser back from the provider with a code for the claims
+func (f *federationFixture) callback(t *testing.T, authorizationURL string, claims map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	code := f.provider.Authorize(t, authorizationURL, claims)
+	u, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	query := url.Values{"code": {code}, "state": {u.Query().Get("state")}}
+	return f.do(http.MethodGet, controller.FederationBasePath+"/upstream/callback?"+query.Encode(), "")
+}
+
+func decodeLogin(t *testing.T, w *httptest.ResponseRecorder) response.LoginResponse {
+	t.Helper()
+	var resp response.LoginResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestFederation_ListProviders(t *testing.T) {
+	f := newFederationFixture(t, false, false)
+	w := f.do(http.MethodGet, controller.FederationBasePath+"/providers", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp response.FederationProvidersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []response.FederationProvider{{
+		Name:        "upstream",
+		DisplayName: "Upstream",
+		LoginURL:    controller.FederationBasePath + "/upstream/login",
+	}}, resp.Providers)
+
+	w = f.do(http.MethodGet, controller.FederationBasePath+"/unknown/login", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "AUTH_FEDERATION_001")
+}
+
+func TestFederation_LoginLinksByVerifiedEmail(t *testing.T) {
+	f := newFederationFixture(t, true, false)
+
+	authorizationURL := f.login(t)
+	assert.True(t, strings.HasPrefix(authorizationURL, f.provider.Issuer+"/authorize?"), authorizationURL)
+	u, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.NotEmpty(t, u.Query().Get("code_challenge"))
+
+	w := f.callback(t, authorizationURL, map[string]interface{}{"sub": "ext-alice", "email": "Alice@Example.com", "email_verified": true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp := decodeLogin(t, w)
+	assert.Equal(t, "SUCCESS", resp.Code)
+	require.NotNil(t, resp.TokenPair)
+	claims, err := f.common.ValidateJWTToken(resp.TokenPair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", claims.UUID)
+	require.Len(t, f.links.Links, 1)
+	link := f.links.Links[0]
+	assert.Equal(t, []string{"alice-uuid", "upstream", "ext-alice"}, []string{link.UserUUID, link.Provider, link.Subject})
+
+	// Later logins follow the link, whatever email the provider reports
+	w = f.callback(t, f.login(t), map[string]interface{}{"sub": "ext-alice", "email": "alice@elsewhere.example"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	claims, err = f.common.ValidateJWTToken(decodeLogin(t, w).TokenPair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", claims.UUID)
+	assert.Len(t, f.links.Links, 1)
+	assert.NotNil(t, f.links.Links[0].LastLoginAt)
+}
+
+func TestFederation_UnlinkedIdentityIsRejected(t *testing.T) {
+	cases := []struct {
+		name        string
+		linkByEmail bool
+		claims      map[string]interface{}
+	}{
+		{"linking disabled", false, map[string]interface{}{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}},
+		{"unverified email", true, map[string]interface{}{"sub": "ext-alice", "email": "alice@example.com", "email_verified": false}},
+		{"unknown email", true, map[string]interface{}{"sub": "ext-carol", "email": "carol@example.com", "email_verified": "true"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFederationFixture(t, tc.linkByEmail, false)
+			w := f.callback(t, f.login(t), tc.claims)
+			assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+			assert.Equal(t, "AUTH_FEDERATION_007", decodeLogin(t, w).Code)
+			assert.Empty(t, f.links.Links)
+		})
+	}
+}
+
+func TestFederation_AutoProvision(t *testing.T) {
+	f := newFederationFixture(t, false, true)
+
+	w := f.callback(t, f.login(t), map[string]interface{}{"sub": "ext-carol", "email": "carol@example.com", "email_verified": true, "name": "Carol"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, f.users.Users, 3)
+	carol := f.users.Users[2]
+	assert.Equal(t, "carol@example.com", carol.Email)
+	assert.Equal(t, "Carol", carol.Name)
+	assert.Equal(t, repository.FederatedAuthSource("upstream"), carol.AuthSource)
+	assert.NotNil(t, carol.EmailVerifiedAt)
+	require.Len(t, f.links.Links, 1)
+	assert.Equal(t, carol.UUID, f.links.Links[0].UserUUID)
+
+	// Without link_by_email an existing account is never taken over
+	w = f.callback(t, f.login(t), map[string]interface{}{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Len(t, f.users.Users, 3)
+}
+
+func TestFederation_CallbackRejectsInvalidState(t *testing.T) {
+	f := newFederationFixture(t, true, false)
+	authorizationURL := f.login(t)
+	claims := map[string]interface{}{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+	require.Equal(t, http.StatusOK, f.callback(t, authorizationURL, claims).Code)
+
+	// The state is consumed by the first callback
+	w := f.callback(t, authorizationURL, claims)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "AUTH_FEDERATION_002", decodeLogin(t, w).Code)
+
+	w = f.do(http.MethodGet, controller.FederationBasePath+"/upstream/callback?code=abc&state=forged", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = f.do(http.MethodGet, controller.FederationBasePath+"/upstream/callback?error=access_denied", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "AUTH_FEDERATION_006", decodeLogin(t, w).Code)
+}
+
+func TestFederation_CallbackRequiresStateCookie(t *testing.T) {
+	f := newFederationFixture(t, true, false)
+	authorizationURL := f.login(t)
+	cookie := f.cookies[controller.FederationStateCookie]
+	require.NotNil(t, cookie)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, controller.FederationBasePath, cookie.Path)
+	claims := map[string]interface{}{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+
+	// A callback URL opened in another browser is refused
+	f.cookies = map[string]*http.Cookie{}
+	w := f.callback(t, authorizationURL, claims)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "AUTH_FEDERATION_002", decodeLogin(t, w).Code)
+	f.cookies[controller.FederationStateCookie] = &http.Cookie{Name: controller.FederationStateCookie, Value: "forged"}
+	w = f.callback(t, authorizationURL, claims)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The browser that started the login can still complete it
+	f.cookies = map[string]*http.Cookie{controller.FederationStateCookie: cookie}
+	w = f.callback(t, authorizationURL, claims)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, f.cookies, controller.FederationStateCookie)
+}
+
+func TestFederation_CallbackRejectsInvalidIDToken(t *testing.T) {
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"wrong audience", map[string]interface{}{"aud": "someone-else"}},
+		{"wrong issuer", map[string]interface{}{"iss": "https://evil.example.com"}},
+		{"wrong nonce", map[string]interface{}{"nonce": "replayed"}},
+		{"missing nonce", map[string]interface{}{"nonce": nil}},
+		{"expired", map[string]interface{}{"exp": 1000}},
+		{"missing subject", map[string]interface{}{"sub": nil}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFederationFixture(t, true, true)
+			claims := map[string]interface{}{"sub": "ext-alice", "email": "alice@example.com", "email_verified": true}
+			for name, value := range tc.claims {
+				claims[name] = value
+			}
+			w := f.callback(t, f.login(t), claims)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+			assert.Equal(t, "AUTH_FEDERATION_003", decodeLogin(t, w).Code)
+			assert.Empty(t, f.links.Links)
+		})
+	}
+}
+
+func TestFederation_LinkAndUnlinkIdentity(t *testing.T) {
+	f := newFederationFixture(t, false, false)
+	alice, err := f.common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+
+	w := f.do(http.MethodPost, "/v1/internal/me/identity/upstream", alice.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp response.IdentityLinkResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.AuthorizationURL)
+	require.Contains(t, f.cookies, controller.FederationStateCookie, "the link is bound to the browser that started it")
+
+	// The provider account does not need a matching email to be linked explicitly
+	w = f.callback(t, resp.AuthorizationURL, map[string]interface{}{"sub": "ext-alice", "email": "alice@elsewhere.example"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "Identity linked successfully")
+
+	w = f.callback(t, f.login(t), map[string]interface{}{"sub": "ext-alice"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	claims, err := f.common.ValidateJWTToken(decodeLogin(t, w).TokenPair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", claims.UUID)
+
+	// Bob cannot link the identity Alice already owns
+	bob, err := f.common.GenerateTokenPair(8, "bob-uuid", "bob@example.com", "Bob", "user")
+	require.NoError(t, err)
+	w = f.do(http.MethodPost, "/v1/internal/me/identity/upstream", bob.AccessToken)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	w = f.callback(t, resp.AuthorizationURL, map[string]interface{}{"sub": "ext-alice"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "AUTH_FEDERATION_008")
+
+	w = f.do(http.MethodGet, "/v1/internal/me/identities", alice.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = response.IdentityLinkResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Identities, 1)
+	assert.Equal(t, "ext-alice", resp.Identities[0].Subject)
+
+	w = f.do(http.MethodDelete, "/v1/internal/me/identity/"+resp.Identities[0].UUID, bob.AccessToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = f.do(http.MethodDelete, "/v1/internal/me/identity/"+resp.Identities[0].UUID, alice.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, f.links.Links)
+}
+
+func TestFederation_KeepsLastLinkOfFederatedUser(t *testing.T) {
+	f := newFederationFixture(t, false, true)
+	w := f.callback(t, f.login(t), map[string]interface{}{"sub": "ext-carol", "email": "carol@example.com", "email_verified": true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = f.do(http.MethodDelete, "/v1/internal/me/identity/"+f.links.Links[0].UUID, decodeLogin(t, w).TokenPair.AccessToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "IDENTITY_UNLINK_003")
+	assert.Len(t, f.links.Links, 1)
+}
@@ -0,0 +1,165 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tokenExchangePath = "/v1/private/token/exchange"
+
+func newImpersonationRouter(t *testing.T, impersonation config.Impersonation) (*gin.Engine, repository.CommonRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.Admin.Emails = []string{"admin@example.com", "root@example.com"}
+	conf.YamlConfig.Application.Server.Impersonation = impersonation
+	common := repository.NewCommonRepository(conf, client)
+
+	users := []model.Users{
+		{ID: 1, UUID: "admin-uuid", Email: "admin@example.com", Name: "Admin"},
+		{ID: 2, UUID: "root-uuid", Email: "root@example.com", Name: "Root"},
+		{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice"},
+	}
+	userRepo := &mock.MockUserRepository{
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			for _, u := range users {
+				if (filter.ID != nil && u.ID == *filter.ID) || (filter.UUID != nil && u.UUID == *filter.UUID) || (filter.Email != nil && u.Email == *filter.Email) {
+					return []model.Users{u}, nil
+				}
+			}
+			return []model.Users{}, nil
+		},
+	}
+	ctrl := controller.NewImpersonationControllerForPrivate(repository.NewImpersonationRepository(conf, common), userRepo)
+
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	whoami := func(c *gin.Context) {
+		email, _ := middleware.GetUserEmail(c)
+		c.String(http.StatusOK, email)
+	}
+	internal := router.Group("/v1/internal", middleware.ForInternal(common, enforcer))
+	internal.GET("/users", middleware.CasbinAuthorization(enforcer, "users", "read"), whoami)
+	internal.POST("/me/token", middleware.CasbinAuthorization(enforcer, "tokens", "write"), middleware.DenyImpersonation(), whoami)
+	private := router.Group("/v1/private", middleware.ForPrivate(common, enforcer))
+	private.POST("/token/exchange", middleware.CasbinAuthorization(enforcer, "impersonation", "write"), middleware.DenyImpersonation(), ctrl.ExchangeToken)
+	return router, common
+}
+
+func exchangeToken(router *gin.Engine, bearer string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, tokenExchangePath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func impersonationForm(subject string) url.Values {
+	return url.Values{"grant_type": {controller.GrantTypeTokenExchange}, "requested_subject": {subject}}
+}
+
+func TestExchangeToken_IssuesImpersonationToken(t *testing.T) {
+	router, common := newImpersonationRouter(t, config.Impersonation{Enabled: true, TokenTTLSeconds: 300})
+	admin, err := common.GenerateTokenPair(1, "admin-uuid", "admin@example.com", "Admin", "admin")
+	require.NoError(t, err)
+
+	w := exchangeToken(router, admin.AccessToken, impersonationForm("alice@example.com"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp response.TokenExchangeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, controller.TokenTypeAccessToken, resp.IssuedTokenType)
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, int64(300), resp.ExpiresIn)
+
+	claims, err := common.ValidateJWTToken(resp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", claims.UUID)
+	assert.Equal(t, "user", claims.Role)
+	assert.Empty(t, claims.FamilyID, "impersonation tokens cannot be refreshed")
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, model.Actor{Subject: "admin-uuid", Email: "admin@example.com"}, *claims.Actor)
+
+	// The token acts as Alice but cannot change her credentials
+	req := httptest.NewRequest(http.MethodGet, "/v1/internal/users", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice@example.com", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/internal/me/token", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "MIDDLEWARE_IMPERSONATION_001")
+}
+
+func TestExchangeToken_DefaultRules(t *testing.T) {
+	router, common := newImpersonationRouter(t, config.Impersonation{Enabled: true})
+	admin, err := common.GenerateTokenPair(1, "admin-uuid", "admin@example.com", "Admin", "admin")
+	require.NoError(t, err)
+
+	// Admins cannot impersonate other admins or themselves
+	for _, subject := range []string{"root@example.com", "admin-uuid"} {
+		w := exchangeToken(router, admin.AccessToken, impersonationForm(subject))
+		assert.Equal(t, http.StatusForbidden, w.Code, subject)
+		assert.Contains(t, w.Body.String(), "access_denied")
+	}
+
+	w := exchangeToken(router, admin.AccessToken, impersonationForm("7"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = exchangeToken(router, admin.AccessToken, impersonationForm("nobody@example.com"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_target")
+
+	w = exchangeToken(router, admin.AccessToken, url.Values{"grant_type": {"password"}, "requested_subject": {"7"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unsupported_grant_type")
+
+	// Non-admins are stopped by the authorization policy
+	alice, err := common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	w = exchangeToken(router, alice.AccessToken, impersonationForm("admin@example.com"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "MIDDLEWARE_AUTH_005")
+}
+
+func TestExchangeToken_Disabled(t *testing.T) {
+	router, common := newImpersonationRouter(t, config.Impersonation{})
+	admin, err := common.GenerateTokenPair(1, "admin-uuid", "admin@example.com", "Admin", "admin")
+	require.NoError(t, err)
+
+	w := exchangeToken(router, admin.AccessToken, impersonationForm("alice@example.com"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "impersonation is disabled")
+}
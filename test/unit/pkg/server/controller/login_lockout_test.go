@@ -0,0 +1,117 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newLockoutRouter(t *testing.T, lockout config.LoginLockout) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.LoginLockout = lockout
+	lockoutRepo := repository.NewLoginLockoutRepository(conf, client)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: string(hash)}
+
+	userRepo := &mock.MockUserRepository{Users: []model.Users{alice}}
+	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
+	ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo, &mock.MockMFARepository{}, lockoutRepo, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
+	admin := controller.NewLoginLockoutControllerForPrivate(lockoutRepo)
+	router := gin.New()
+	router.POST("/v1/share/common/auth/tokens", ctrl.Login)
+	router.GET("/v1/private/lockouts", admin.ListLockouts)
+	router.DELETE("/v1/private/lockout/:scope/:key", admin.ClearLockout)
+	return router
+}
+
+func lockoutRequest(router *gin.Engine, method, path string) (*httptest.ResponseRecorder, response.LoginLockoutResponse) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	var resp response.LoginLockoutResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestLogin_LockoutAfterRepeatedFailures(t *testing.T) {
+	router := newLockoutRouter(t, config.LoginLockout{MaxAttempts: 3, BackoffSeconds: -1, LockoutSeconds: 600})
+	wrong := map[string]string{"email": "alice@example.com", "password": "wrong"}
+	right := map[string]string{"email": "alice@example.com", "password": "Password123!"}
+
+	for i := 0; i < 2; i++ {
+		w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", wrong)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "AUTH_LOGIN_004", resp.Code)
+	}
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", wrong)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "AUTH_LOGIN_009", resp.Code)
+
+	// The correct password does not help while locked out
+	w, resp = postLoginJSON(router, "/v1/share/common/auth/tokens", right)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "AUTH_LOGIN_009", resp.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 600, retryAfter, 2)
+
+	w, list := lockoutRequest(router, http.MethodGet, "/v1/private/lockouts?scope=account")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, list.Lockouts, 1)
+	assert.Equal(t, "alice@example.com", list.Lockouts[0].Key)
+	assert.Equal(t, "lockout", list.Lockouts[0].Reason)
+
+	w, _ = lockoutRequest(router, http.MethodDelete, "/v1/private/lockout/account/alice@example.com")
+	require.Equal(t, http.StatusOK, w.Code)
+	w, resp = postLoginJSON(router, "/v1/share/common/auth/tokens", right)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", resp.Code)
+
+	w, _ = lockoutRequest(router, http.MethodDelete, "/v1/private/lockout/account/alice@example.com")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = lockoutRequest(router, http.MethodDelete, "/v1/private/lockout/user/alice@example.com")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLogin_BackoffAndUnknownAccounts(t *testing.T) {
+	router := newLockoutRouter(t, config.LoginLockout{BackoffSeconds: 30})
+	unknown := map[string]string{"email": "nobody@example.com", "password": "wrong"}
+
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", unknown)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "AUTH_LOGIN_003", resp.Code)
+	w, _ = postLoginJSON(router, "/v1/share/common/auth/tokens", unknown)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Unknown emails are throttled like real ones so the answer does not reveal accounts
+	w, resp = postLoginJSON(router, "/v1/share/common/auth/tokens", unknown)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "AUTH_LOGIN_009", resp.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Other accounts are not affected
+	w, _ = postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": "Password123!"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -0,0 +1,166 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newMFALoginRouter(t *testing.T, mfaEnabled bool) *gin.Engine {
+	return newMFALoginRouterWithLockout(t, mfaEnabled, &mock.MockLoginLockoutRepository{})
+}
+
+func newMFALoginRouterWithLockout(t *testing.T, mfaEnabled bool, lockoutRepo repository.LoginLockoutRepository) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: string(hash)}
+	userRepo := &mock.MockUserRepository{Users: []model.Users{alice}}
+
+	mfaRepo := &mock.MockMFARepository{
+		IsEnabledFunc: func(c *gin.Context, userUUID string) (bool, error) {
+			return mfaEnabled, nil
+		},
+		CreateChallengeFunc: func(ctx context.Context, userUUID, email string) (string, error) {
+			return "challenge-for-" + userUUID, nil
+		},
+		ChallengeEmailFunc: func(ctx context.Context, challenge string) (string, error) {
+			if challenge != "challenge-for-alice-uuid" {
+				return "", repository.ErrMFAChallengeInvalid
+			}
+			return "alice@example.com", nil
+		},
+		CompleteChallengeFunc: func(c *gin.Context, challenge, code string) (string, error) {
+			if challenge != "challenge-for-alice-uuid" {
+				return "", repository.ErrMFAChallengeInvalid
+			}
+			if code != "123456" {
+				return "", repository.ErrMFAInvalidCode
+			}
+			return "alice-uuid", nil
+		},
+	}
+
+	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
+	ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo, mfaRepo, lockoutRepo, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
+	router := gin.New()
+	router.POST("/v1/share/common/auth/tokens", ctrl.Login)
+	router.POST("/v1/share/common/auth/tokens/mfa", ctrl.VerifyMFA)
+	return router
+}
+
+func postLoginJSON(router *gin.Engine, path string, body interface{}) (*httptest.ResponseRecorder, response.LoginResponse) {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp response.LoginResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestLogin_WithoutMFAIssuesTokens(t *testing.T) {
+	router := newMFALoginRouter(t, false)
+
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": "Password123!"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", resp.Code)
+	require.NotNil(t, resp.TokenPair)
+	assert.Empty(t, resp.MFAToken)
+}
+
+func TestLogin_WithMFARequiresSecondStep(t *testing.T) {
+	router := newMFALoginRouter(t, true)
+
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": "Password123!"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "MFA_REQUIRED", resp.Code)
+	assert.Nil(t, resp.TokenPair, "no tokens before the second factor")
+	require.NotEmpty(t, resp.MFAToken)
+
+	w, bad := postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"mfa_token": resp.MFAToken, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "AUTH_MFA_003", bad.Code)
+
+	w, ok := postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"mfa_token": resp.MFAToken, "code": "123456"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", ok.Code)
+	require.NotNil(t, ok.TokenPair)
+	require.NotNil(t, ok.User)
+	assert.Equal(t, "alice-uuid", ok.User.UUID)
+}
+
+func TestVerifyMFA_MissingFields(t *testing.T) {
+	router := newMFALoginRouter(t, true)
+
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"code": "123456"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "AUTH_MFA_002", resp.Code)
+}
+
+func TestVerifyMFA_CountsTowardLoginLockout(t *testing.T) {
+	failures, successes := 0, 0
+	var locked *model.LoginLockouts
+	lockoutRepo := &mock.MockLoginLockoutRepository{
+		CheckFunc: func(ctx context.Context, email, ip string) (*model.LoginLockouts, error) {
+			return locked, nil
+		},
+		RegisterFailureFunc: func(ctx context.Context, email, ip string) (*model.LoginLockouts, error) {
+			assert.Equal(t, "alice@example.com", email)
+			failures++
+			if failures == 2 {
+				locked = &model.LoginLockouts{Scope: model.LoginLockoutScopeAccount, Key: email, Reason: model.LoginLockoutReasonLockout, LockedUntil: time.Now().Add(time.Minute).Unix()}
+				return locked, nil
+			}
+			return nil, nil
+		},
+		RegisterSuccessFunc: func(ctx context.Context, email string) error {
+			successes++
+			return nil
+		},
+	}
+	router := newMFALoginRouterWithLockout(t, true, lockoutRepo)
+
+	// The password alone does not reset the failed login counters
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": "Password123!"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "MFA_REQUIRED", resp.Code)
+	assert.Equal(t, 0, successes)
+
+	w, bad := postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"mfa_token": resp.MFAToken, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "AUTH_MFA_003", bad.Code)
+	w, bad = postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"mfa_token": resp.MFAToken, "code": "000001"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "AUTH_LOGIN_009", bad.Code)
+	assert.Equal(t, 2, failures)
+
+	// A locked account cannot finish the login even with the right code
+	w, bad = postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"mfa_token": resp.MFAToken, "code": "123456"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Nil(t, bad.TokenPair)
+
+	locked = nil
+	w, ok := postLoginJSON(router, "/v1/share/common/auth/tokens/mfa", map[string]string{"mfa_token": resp.MFAToken, "code": "123456"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", ok.Code)
+	assert.Equal(t, 1, successes)
+}
@@ -0,0 +1,323 @@
+package controller_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	oidcTestIssuer   = "http://locky.test"
+	oidcTestRedirect = "http://app.test/callback"
+	oidcTestVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk-verifier"
+)
+
+type oidcTestServer struct {
+	router *gin.Engine
+	common repository.CommonRepository
+}
+
+func newOIDCTestServer(t *testing.T) *oidcTestServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.JWT = config.JWT{Algorithm: "ES256"}
+	conf.YamlConfig.Application.Server.OIDC = config.OIDC{
+		Enabled: true,
+		Issuer:  oidcTestIssuer,
+		Clients: []config.OIDCClient{
+			{ClientID: "spa", Name: "Test SPA", Public: true, RedirectURIs: []string{oidcTestRedirect}},
+			{ClientID: "web", ClientSecret: "web-secret", Public: false, SkipConsent: true, RedirectURIs: []string{oidcTestRedirect}},
+		},
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	userRepo := &mock.MockUserRepository{
+		GetUserByEmailFunc: func(c *gin.Context, email string) (model.Users, error) {
+			if email == "alice@example.com" {
+				return model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: string(hash)}, nil
+			}
+			return model.Users{}, repository.ErrUserNotFound
+		},
+	}
+
+	common := repository.NewCommonRepository(conf, client)
+	ctrl := controller.NewOIDCControllerForPublic(repository.NewOIDCRepository(conf, common, client), userRepo, common, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, common)))
+
+	router := gin.New()
+	router.GET(controller.OIDCDiscoveryPath, ctrl.Discovery)
+	router.GET(controller.OIDCAuthorizePath, ctrl.Authorize)
+	router.POST(controller.OIDCAuthorizePath, ctrl.AuthorizeSubmit)
+	router.POST(controller.OIDCTokenPath, ctrl.Token)
+	router.GET(controller.OIDCUserInfoPath, ctrl.UserInfo)
+	return &oidcTestServer{router: router, common: common}
+}
+
+func (s *oidcTestServer) do(method, target string, form url.Values, header map[string]string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeParams(clientID string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {oidcTestRedirect},
+		"scope":                 {"openid profile email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6"},
+		"code_challenge":        {pkceChallenge(oidcTestVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// login posts the login form and returns the authorization code after consent
+func (s *oidcTestServer) login(t *testing.T, clientID string) string {
+	t.Helper()
+	form := authorizeParams(clientID)
+	form.Set("email", "alice@example.com")
+	form.Set("password", "Password123!")
+	w := s.do(http.MethodPost, controller.OIDCAuthorizePath, form, nil)
+
+	if w.Code == http.StatusOK {
+		ticket := regexp.MustCompile(`name="ticket" value="([^"]+)"`).FindStringSubmatch(w.Body.String())
+		require.Len(t, ticket, 2, w.Body.String())
+		w = s.do(http.MethodPost, controller.OIDCAuthorizePath, url.Values{"ticket": {ticket[1]}, "consent": {"allow"}}, nil)
+	}
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	require.NotEmpty(t, location.Query().Get("code"))
+	return location.Query().Get("code")
+}
+
+func TestOIDC_Discovery(t *testing.T) {
+	s := newOIDCTestServer(t)
+	w := s.do(http.MethodGet, controller.OIDCDiscoveryPath, nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc response.OIDCDiscoveryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, oidcTestIssuer, doc.Issuer)
+	assert.Equal(t, oidcTestIssuer+controller.OIDCTokenPath, doc.TokenEndpoint)
+	assert.Equal(t, []string{"ES256"}, doc.IDTokenSigningAlgValuesSupported)
+	assert.Equal(t, []string{"S256"}, doc.CodeChallengeMethodsSupported)
+}
+
+func TestOIDC_AuthorizationCodeFlowWithPKCE(t *testing.T) {
+	s := newOIDCTestServer(t)
+
+	w := s.do(http.MethodGet, controller.OIDCAuthorizePath+"?"+authorizeParams("spa").Encode(), nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Test SPA")
+
+	code := s.login(t, "spa")
+	tokenForm := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidcTestRedirect},
+		"client_id":     {"spa"},
+		"code_verifier": {oidcTestVerifier},
+	}
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, tokenForm, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var tokens response.OAuthTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	idClaims, err := s.common.ValidateJWTToken(tokens.IDToken)
+	require.NoError(t, err)
+	assert.Equal(t, model.TokenUseID, idClaims.TokenUse)
+	assert.Equal(t, oidcTestIssuer, idClaims.Issuer)
+	assert.Equal(t, model.Audience{"spa"}, idClaims.Audience)
+	assert.Equal(t, "alice-uuid", idClaims.Subject)
+	assert.Equal(t, "n-0S6", idClaims.Nonce)
+	assert.Equal(t, "alice@example.com", idClaims.Email)
+	assert.NotZero(t, idClaims.AuthTime)
+
+	// The code is single-use
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, tokenForm, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_grant")
+
+	// UserInfo accepts the access token but not the ID token
+	w = s.do(http.MethodGet, controller.OIDCUserInfoPath, nil, map[string]string{"Authorization": "Bearer " + tokens.AccessToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var info response.OIDCUserInfoResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "alice-uuid", info.Subject)
+	assert.Equal(t, "Alice", info.Name)
+
+	w = s.do(http.MethodGet, controller.OIDCUserInfoPath, nil, map[string]string{"Authorization": "Bearer " + tokens.IDToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Refresh token grant
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+		"client_id":     {"spa"},
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestOIDC_ConfidentialClientBasicAuth(t *testing.T) {
+	s := newOIDCTestServer(t)
+	code := s.login(t, "web") // skip_consent: redirected right after login
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidcTestRedirect},
+		"code_verifier": {oidcTestVerifier},
+	}
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("web:wrong"))
+	w := s.do(http.MethodPost, controller.OIDCTokenPath, form, map[string]string{"Authorization": basic})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_client")
+
+	basic = "Basic " + base64.StdEncoding.EncodeToString([]byte("web:web-secret"))
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, form, map[string]string{"Authorization": basic})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestOIDC_RefreshTokenBoundToClient(t *testing.T) {
+	s := newOIDCTestServer(t)
+	code := s.login(t, "web")
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("web:web-secret"))
+	w := s.do(http.MethodPost, controller.OIDCTokenPath, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidcTestRedirect},
+		"code_verifier": {oidcTestVerifier},
+	}, map[string]string{"Authorization": basic})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tokens response.OAuthTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+
+	// A public client cannot redeem the confidential client's refresh token
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+		"client_id":     {"spa"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_grant")
+
+	// Nor one issued by password login
+	login, err := s.common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {login.RefreshToken},
+		"client_id":     {"spa"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = s.do(http.MethodPost, controller.OIDCTokenPath, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+	}, map[string]string{"Authorization": basic})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestOIDC_RejectsWrongVerifier(t *testing.T) {
+	s := newOIDCTestServer(t)
+	code := s.login(t, "spa")
+
+	w := s.do(http.MethodPost, controller.OIDCTokenPath, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidcTestRedirect},
+		"client_id":     {"spa"},
+		"code_verifier": {strings.Repeat("a", 43)},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_grant")
+}
+
+func TestOIDC_AuthorizeValidation(t *testing.T) {
+	s := newOIDCTestServer(t)
+
+	t.Run("unregistered redirect_uri is not redirected", func(t *testing.T) {
+		params := authorizeParams("spa")
+		params.Set("redirect_uri", "http://evil.test/callback")
+		w := s.do(http.MethodGet, controller.OIDCAuthorizePath+"?"+params.Encode(), nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Location"))
+	})
+
+	t.Run("public client requires PKCE", func(t *testing.T) {
+		params := authorizeParams("spa")
+		params.Del("code_challenge")
+		w := s.do(http.MethodGet, controller.OIDCAuthorizePath+"?"+params.Encode(), nil, nil)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), "error=invalid_request")
+	})
+
+	t.Run("wrong password re-renders login", func(t *testing.T) {
+		form := authorizeParams("spa")
+		form.Set("email", "alice@example.com")
+		form.Set("password", "nope")
+		w := s.do(http.MethodPost, controller.OIDCAuthorizePath, form, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid email or password")
+	})
+
+	t.Run("consent denied", func(t *testing.T) {
+		form := authorizeParams("spa")
+		form.Set("email", "alice@example.com")
+		form.Set("password", "Password123!")
+		w := s.do(http.MethodPost, controller.OIDCAuthorizePath, form, nil)
+		ticket := regexp.MustCompile(`name="ticket" value="([^"]+)"`).FindStringSubmatch(w.Body.String())
+		require.Len(t, ticket, 2)
+		w = s.do(http.MethodPost, controller.OIDCAuthorizePath, url.Values{"ticket": {ticket[1]}, "consent": {"deny"}}, nil)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), "error=access_denied")
+	})
+}
@@ -0,0 +1,106 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/ryo-arima/locky/pkg/server/usecase"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCreateUser_RejectsPasswordWithFieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.PasswordPolicy = config.PasswordPolicy{MinLength: 10}
+	commonRepo := &mock.MockCommonRepository{JWTSecret: "test", BaseConfig: conf}
+	userRepo := &mock.MockUserRepository{}
+	recorded := []string{}
+	policyRepo := &mock.MockPasswordPolicyRepository{
+		ValidateFunc: func(c *gin.Context, password, userUUID string) ([]repository.PasswordPolicyViolation, error) {
+			return repository.CheckPasswordRules(conf.YamlConfig.Application.Server.PasswordPolicy, password), nil
+		},
+		RecordPasswordFunc: func(c *gin.Context, userUUID, passwordHash string) error {
+			recorded = append(recorded, userUUID)
+			return nil
+		},
+	}
+	ctrl := controller.NewUserControllerForPrivate(usecase.NewUserUsecase(userRepo), commonRepo, policyRepo, nil)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/v1/private/users", ctrl.CreateUser)
+	create := func(password string) (*httptest.ResponseRecorder, response.UserResponse) {
+		payload, _ := json.Marshal(map[string]string{"email": "alice@example.com", "name": "Alice", "password": password})
+		req := httptest.NewRequest(http.MethodPost, "/v1/private/users", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp response.UserResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, resp := create("password")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "SERVER_CONTROLLER_CREATE__FOR__003", resp.Code)
+	codes := []string{}
+	for _, fieldError := range resp.Errors {
+		assert.Equal(t, "password", fieldError.Field)
+		assert.NotEmpty(t, fieldError.Message)
+		codes = append(codes, fieldError.Code)
+	}
+	assert.Equal(t, []string{
+		repository.PasswordTooShort,
+		repository.PasswordMissingUppercase,
+		repository.PasswordMissingNumber,
+		repository.PasswordMissingSpecial,
+	}, codes)
+	assert.Empty(t, userRepo.Users)
+
+	w, resp = create("Correct-H0rse")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, resp.Errors)
+	require.Len(t, userRepo.Users, 1)
+	assert.NotEqual(t, "Correct-H0rse", userRepo.Users[0].Password, "passwords are stored hashed")
+	assert.NotNil(t, userRepo.Users[0].PasswordChangedAt)
+	assert.Len(t, recorded, 1)
+}
+
+func TestLogin_RejectsExpiredPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	recent := time.Now().Add(-24 * time.Hour)
+	users := []model.Users{
+		{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: string(hash), CreatedAt: &old},
+		{ID: 8, UUID: "bob-uuid", Email: "bob@example.com", Name: "Bob", Password: string(hash), CreatedAt: &old, PasswordChangedAt: &recent},
+	}
+
+	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
+	commonRepo.BaseConfig.YamlConfig.Application.Server.PasswordPolicy.MaxAgeDays = 90
+	userRepo := &mock.MockUserRepository{Users: users}
+	ctrl := controller.NewCommonControllerForPublic(userRepo, commonRepo, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
+	router := gin.New()
+	router.POST("/v1/share/common/auth/tokens", ctrl.Login)
+
+	w, resp := postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": "Password123!"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "AUTH_LOGIN_010", resp.Code)
+
+	w, _ = postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "bob@example.com", "password": "Password123!"})
+	assert.NotEqual(t, http.StatusForbidden, w.Code, w.Body.String())
+}
@@ -0,0 +1,246 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturingMailer records reset links instead of sending email
+type capturingMailer struct {
+	repository.CommonRepository
+	links chan string
+}
+
+func (m capturingMailer) SendPasswordResetEmail(ctx context.Context, to, name, resetURL string) error {
+	m.links <- resetURL
+	return nil
+}
+
+type passwordTestServer struct {
+	router       *gin.Engine
+	common       repository.CommonRepository
+	links        chan string
+	user         *model.Users
+	revokedPATs  []string
+	updatedCount int
+	policy       *mock.MockPasswordPolicyRepository
+	recorded     []string
+}
+
+func newPasswordTestServer(t *testing.T, maxRequests int) *passwordTestServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.PasswordReset = config.PasswordReset{
+		ResetURL:    "https://app.test/reset-password",
+		MaxRequests: maxRequests,
+	}
+	common := repository.NewCommonRepository(conf, client)
+
+	ts := &passwordTestServer{
+		common: common,
+		links:  make(chan string, 10),
+		user:   &model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: "old-hash"},
+	}
+	userRepo := &mock.MockUserRepository{
+		GetUserByEmailFunc: func(c *gin.Context, email string) (model.Users, error) {
+			if repository.NormalizeEmail(email) == ts.user.Email {
+				return *ts.user, nil
+			}
+			return model.Users{}, repository.ErrUserNotFound
+		},
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			if filter.UUID != nil && *filter.UUID == ts.user.UUID {
+				return []model.Users{*ts.user}, nil
+			}
+			return []model.Users{}, nil
+		},
+		UpdateUserFunc: func(c *gin.Context, user model.Users) (model.Users, error) {
+			*ts.user = user
+			ts.updatedCount++
+			return user, nil
+		},
+	}
+	patRepo := &mock.MockPersonalAccessTokenRepository{
+		RevokeAllTokensFunc: func(c *gin.Context, userUUID string) (int64, error) {
+			ts.revokedPATs = append(ts.revokedPATs, userUUID)
+			return 1, nil
+		},
+	}
+
+	ts.policy = &mock.MockPasswordPolicyRepository{
+		ValidateFunc: func(c *gin.Context, password, userUUID string) ([]repository.PasswordPolicyViolation, error) {
+			return repository.CheckPasswordRules(conf.YamlConfig.Application.Server.PasswordPolicy, password), nil
+		},
+		RecordPasswordFunc: func(c *gin.Context, userUUID, passwordHash string) error {
+			ts.recorded = append(ts.recorded, userUUID)
+			return nil
+		},
+	}
+
+	ctrl := controller.NewPasswordControllerForPublic(
+		userRepo,
+		capturingMailer{CommonRepository: common, links: ts.links},
+		repository.NewPasswordResetRepository(conf, client),
+		repository.NewSessionRepository(common, client),
+		patRepo,
+		ts.policy,
+	)
+	ts.router = gin.New()
+	ts.router.Use(middleware.RequestID())
+	ts.router.POST("/v1/public/password/forgot", ctrl.ForgotPassword)
+	ts.router.POST("/v1/public/password/reset", ctrl.ResetPassword)
+	return ts
+}
+
+func (ts *passwordTestServer) post(path string, body interface{}) (*httptest.ResponseRecorder, response.CommonResponse) {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	var resp response.CommonResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func (ts *passwordTestServer) resetToken(t *testing.T) string {
+	t.Helper()
+	select {
+	case link := <-ts.links:
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		assert.Equal(t, "app.test", u.Host)
+		return u.Query().Get("token")
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reset email sent")
+		return ""
+	}
+}
+
+func TestPasswordReset_FullFlow(t *testing.T) {
+	ts := newPasswordTestServer(t, 5)
+	pair, err := ts.common.GenerateTokenPair(ts.user.ID, ts.user.UUID, ts.user.Email, ts.user.Name, "user")
+	require.NoError(t, err)
+
+	w, resp := ts.post("/v1/public/password/forgot", map[string]string{"email": "Alice@Example.com"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", resp.Code)
+	token := ts.resetToken(t)
+	require.NotEmpty(t, token)
+
+	w, resp = ts.post("/v1/public/password/reset", map[string]string{"token": token, "password": "N3w-Passw0rd!"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "SUCCESS", resp.Code)
+	assert.Equal(t, 1, ts.updatedCount)
+	require.NoError(t, ts.common.VerifyPassword(ts.user.Password, "N3w-Passw0rd!"))
+	assert.Equal(t, []string{"alice-uuid"}, ts.revokedPATs)
+	assert.Equal(t, []string{"alice-uuid"}, ts.recorded)
+	assert.NotNil(t, ts.user.PasswordChangedAt)
+
+	// Tokens issued before the reset no longer work
+	_, err = ts.common.RotateRefreshToken(context.Background(), pair.RefreshToken, "")
+	assert.Error(t, err)
+
+	// The reset token is single use
+	w, resp = ts.post("/v1/public/password/reset", map[string]string{"token": token, "password": "An0ther-Passw0rd!"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "PASSWORD_RESET_004", resp.Code)
+}
+
+func TestPasswordReset_UnknownEmailLooksTheSame(t *testing.T) {
+	ts := newPasswordTestServer(t, 5)
+
+	wKnown, known := ts.post("/v1/public/password/forgot", map[string]string{"email": "alice@example.com"})
+	ts.resetToken(t)
+	wUnknown, unknown := ts.post("/v1/public/password/forgot", map[string]string{"email": "nobody@example.com"})
+
+	assert.Equal(t, wKnown.Code, wUnknown.Code)
+	assert.Equal(t, known, unknown)
+	select {
+	case <-ts.links:
+		t.Fatal("no email must be sent for an unknown address")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPasswordReset_WeakPasswordKeepsToken(t *testing.T) {
+	ts := newPasswordTestServer(t, 5)
+
+	ts.post("/v1/public/password/forgot", map[string]string{"email": "alice@example.com"})
+	token := ts.resetToken(t)
+
+	w, resp := ts.post("/v1/public/password/reset", map[string]string{"token": token, "password": "weak"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "PASSWORD_RESET_003", resp.Code)
+	require.NotEmpty(t, resp.Errors)
+	assert.Equal(t, "password", resp.Errors[0].Field)
+	assert.Equal(t, repository.PasswordTooShort, resp.Errors[0].Code)
+
+	w, _ = ts.post("/v1/public/password/reset", map[string]string{"token": token, "password": "N3w-Passw0rd!"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestPasswordReset_ReusedPasswordKeepsToken(t *testing.T) {
+	ts := newPasswordTestServer(t, 5)
+	var checkedUUID string
+	ts.policy.ValidateFunc = func(c *gin.Context, password, userUUID string) ([]repository.PasswordPolicyViolation, error) {
+		checkedUUID = userUUID
+		if password == "0ld-Passw0rd!" {
+			return []repository.PasswordPolicyViolation{{Field: "password", Code: repository.PasswordReused, Message: "password was used recently"}}, nil
+		}
+		return nil, nil
+	}
+
+	ts.post("/v1/public/password/forgot", map[string]string{"email": "alice@example.com"})
+	token := ts.resetToken(t)
+
+	w, resp := ts.post("/v1/public/password/reset", map[string]string{"token": token, "password": "0ld-Passw0rd!"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "PASSWORD_RESET_003", resp.Code)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, repository.PasswordReused, resp.Errors[0].Code)
+	assert.Equal(t, "alice-uuid", checkedUUID)
+	assert.Equal(t, 0, ts.updatedCount)
+
+	w, _ = ts.post("/v1/public/password/reset", map[string]string{"token": token, "password": "N3w-Passw0rd!"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestPasswordReset_RateLimited(t *testing.T) {
+	ts := newPasswordTestServer(t, 2)
+
+	for i := 0; i < 2; i++ {
+		w, _ := ts.post("/v1/public/password/forgot", map[string]string{"email": "nobody@example.com"})
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	w, resp := ts.post("/v1/public/password/forgot", map[string]string{"email": "nobody@example.com"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "PASSWORD_FORGOT_002", resp.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,65 @@
+package controller_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLogin_RehashesOutdatedPasswordHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.PasswordHashing = config.PasswordHashing{Argon2id: config.Argon2id{MemoryKiB: 1024, Iterations: 1, Parallelism: 1}}
+	common := repository.NewCommonRepository(conf, client)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	userRepo := &mock.MockUserRepository{Users: []model.Users{{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice", Password: string(legacy)}}}
+	updates := 0
+	userRepo.UpdateUserFunc = func(c *gin.Context, user model.Users) (model.Users, error) {
+		updates++
+		userRepo.Users[0] = user
+		return user, nil
+	}
+
+	ctrl := controller.NewCommonControllerForPublic(userRepo, common, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, common)))
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/v1/share/common/auth/tokens", ctrl.Login)
+	login := func(password string) int {
+		w, _ := postLoginJSON(router, "/v1/share/common/auth/tokens", map[string]string{"email": "alice@example.com", "password": password})
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+	assert.Equal(t, 0, updates, "a failed login must not touch the hash")
+
+	assert.Equal(t, http.StatusOK, login("Password123!"))
+	require.Equal(t, 1, updates)
+	rehashed := userRepo.Users[0].Password
+	assert.True(t, strings.HasPrefix(rehashed, "$argon2id$"), rehashed)
+	assert.NoError(t, common.VerifyPassword(rehashed, "Password123!"))
+
+	// The upgraded hash is current, so the next login leaves it alone
+	assert.Equal(t, http.StatusOK, login("Password123!"))
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, rehashed, userRepo.Users[0].Password)
+}
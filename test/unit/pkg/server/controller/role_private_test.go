@@ -0,0 +1,83 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRoleTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	for _, name := range []string{"model.conf", "policy.csv"} {
+		b, err := os.ReadFile(filepath.Join("../../../../.etc/casbin/resources", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), b, 0o644))
+	}
+	enforcer, err := casbin.NewSyncedEnforcer(filepath.Join(dir, "model.conf"), filepath.Join(dir, "policy.csv"))
+	require.NoError(t, err)
+	ctrl := controller.NewRoleControllerForPrivate(repository.NewRoleRepository(nil, enforcer), nil)
+
+	router := gin.New()
+	router.GET("/v1/private/roles", ctrl.ListRoles)
+	router.GET("/v1/private/role/:id/hierarchy", ctrl.GetRoleHierarchy)
+	router.POST("/v1/private/role/:id/parents", ctrl.AddRoleParent)
+	router.DELETE("/v1/private/role/:id/parents/:parent", ctrl.RemoveRoleParent)
+	return router
+}
+
+func serveRole(t *testing.T, router *gin.Engine, method, path string, body interface{}) (int, response.RoleResponse) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res response.RoleResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return w.Code, res
+}
+
+func TestRoleControllerForPrivate_Parents(t *testing.T) {
+	router := newRoleTestRouter(t)
+
+	code, res := serveRole(t, router, http.MethodPost, "/v1/private/role/viewer/parents", map[string]string{"parent": "owner"})
+	require.Equal(t, http.StatusOK, code, res.Message)
+	require.NotNil(t, res.Hierarchy)
+	assert.Equal(t, []string{"owner"}, res.Hierarchy.Parents)
+	assert.Equal(t, []string{"maintainer", "member"}, res.Hierarchy.Descendants)
+
+	code, res = serveRole(t, router, http.MethodPost, "/v1/private/role/owner/parents", map[string]string{"parent": "maintainer"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "ROLE_PARENT_ADD_CYCLE", res.Code)
+
+	code, res = serveRole(t, router, http.MethodPost, "/v1/private/role/viewer/parents", map[string]string{"parent": "ghost"})
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "ROLE_PARENT_ADD_NOT_FOUND", res.Code)
+
+	code, res = serveRole(t, router, http.MethodGet, "/v1/private/roles?id=viewer&effective=true", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, res.Effective, response.RoleEffectivePermission{Resource: "secret", Action: "write", Role: "owner"})
+
+	code, _ = serveRole(t, router, http.MethodDelete, "/v1/private/role/viewer/parents/owner", nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, res = serveRole(t, router, http.MethodDelete, "/v1/private/role/viewer/parents/owner", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "ROLE_PARENT_REMOVE_NOT_FOUND", res.Code)
+}
@@ -0,0 +1,421 @@
+package controller_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/ryo-arima/locky/pkg/server/usecase"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scimTestToken = "scim-test-token"
+
+type scimFixture struct {
+	router  *gin.Engine
+	users   *mock.MockUserRepository
+	groups  *mock.MockGroupRepository
+	members *mock.MockMemberRepository
+}
+
+// page applies the offset and limit of a query filter like the repositories
+func page[T any](rows []T, offset, limit int) []T {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset >= len(rows) {
+		return []T{}
+	}
+	return rows[offset:min(offset+limit, len(rows))]
+}
+
+func newSCIMFixture(t *testing.T, maxFilterScan int) *scimFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	sum := sha256.Sum256([]byte(scimTestToken))
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.SCIM = config.SCIM{
+		Enabled:       true,
+		Tokens:        []config.SCIMToken{{Name: "idp", TokenSHA256: hex.EncodeToString(sum[:])}},
+		MaxFilterScan: maxFilterScan,
+	}
+
+	f := &scimFixture{}
+	matchUser := func(u model.Users, filter repository.UserQueryFilter) bool {
+		lower := strings.ToLower
+		return (filter.UUID == nil || u.UUID == *filter.UUID) &&
+			(filter.Email == nil || strings.EqualFold(u.Email, *filter.Email)) &&
+			(filter.EmailPrefix == nil || strings.HasPrefix(lower(u.Email), lower(*filter.EmailPrefix))) &&
+			(filter.EmailLike == nil || strings.Contains(lower(u.Email), lower(*filter.EmailLike))) &&
+			(filter.Name == nil || u.Name == *filter.Name)
+	}
+	f.users = &mock.MockUserRepository{
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			rows := []model.Users{}
+			for _, u := range f.users.Users {
+				if matchUser(u, filter) {
+					rows = append(rows, u)
+				}
+			}
+			return page(rows, filter.Offset, filter.Limit), nil
+		},
+		CountUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) (int64, error) {
+			var n int64
+			for _, u := range f.users.Users {
+				if matchUser(u, filter) {
+					n++
+				}
+			}
+			return n, nil
+		},
+	}
+	matchGroup := func(g model.Groups, filter repository.GroupQueryFilter) bool {
+		return (filter.UUID == nil || g.UUID == *filter.UUID) &&
+			(filter.Name == nil || g.Name == *filter.Name) &&
+			(!filter.ExcludeDeleted || g.DeletedAt == nil)
+	}
+	f.groups = &mock.MockGroupRepository{
+		ListGroupsFunc: func(c *gin.Context, filter repository.GroupQueryFilter) ([]model.Groups, error) {
+			rows := []model.Groups{}
+			for _, g := range f.groups.Groups {
+				if matchGroup(g, filter) {
+					rows = append(rows, g)
+				}
+			}
+			return page(rows, filter.Offset, filter.Limit), nil
+		},
+		CountGroupsFunc: func(c *gin.Context, filter repository.GroupQueryFilter) (int64, error) {
+			var n int64
+			for _, g := range f.groups.Groups {
+				if matchGroup(g, filter) {
+					n++
+				}
+			}
+			return n, nil
+		},
+	}
+	f.members = &mock.MockMemberRepository{
+		ListMembersFunc: func(c *gin.Context, filter repository.MemberQueryFilter) ([]model.Members, error) {
+			rows := []model.Members{}
+			for _, m := range f.members.Members {
+				if (filter.GroupUUID == nil || m.GroupUUID == *filter.GroupUUID) && (filter.UserUUID == nil || m.UserUUID == *filter.UserUUID) {
+					rows = append(rows, m)
+				}
+			}
+			return page(rows, filter.Offset, filter.Limit), nil
+		},
+	}
+
+	scimRepo := repository.NewSCIMRepository(conf)
+	uc := usecase.NewSCIMUsecase(f.users, f.groups, f.members, &mock.MockCommonRepository{}, &mock.MockPasswordPolicyRepository{
+		ValidateFunc: func(c *gin.Context, password, userUUID string) ([]repository.PasswordPolicyViolation, error) {
+			if len(password) < 8 {
+				return []repository.PasswordPolicyViolation{{Field: "password", Code: repository.PasswordTooShort, Message: "password is too short"}}, nil
+			}
+			return nil, nil
+		},
+	}, repository.NewSessionRepository(nil, nil), scimRepo)
+	scim := controller.NewSCIMController(uc)
+
+	f.router = gin.New()
+	group := f.router.Group(controller.SCIMBasePath, middleware.RequestID(), middleware.ForSCIM(scimRepo))
+	group.GET("/ServiceProviderConfig", scim.ServiceProviderConfig)
+	group.GET("/Schemas/:id", scim.GetSchema)
+	group.GET("/Users", scim.ListUsers)
+	group.POST("/Users", scim.CreateUser)
+	group.GET("/Users/:id", scim.GetUser)
+	group.PUT("/Users/:id", scim.ReplaceUser)
+	group.PATCH("/Users/:id", scim.PatchUser)
+	group.DELETE("/Users/:id", scim.DeleteUser)
+	group.GET("/Groups", scim.ListGroups)
+	group.POST("/Groups", scim.CreateGroup)
+	group.GET("/Groups/:id", scim.GetGroup)
+	group.PATCH("/Groups/:id", scim.PatchGroup)
+	group.DELETE("/Groups/:id", scim.DeleteGroup)
+	return f
+}
+
+func (f *scimFixture) do(t *testing.T, method, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	var payload *strings.Reader
+	switch b := body.(type) {
+	case nil:
+		payload = strings.NewReader("")
+	case string:
+		payload = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		require.NoError(t, err)
+		payload = strings.NewReader(string(data))
+	}
+	req := httptest.NewRequest(method, controller.SCIMBasePath+path, payload)
+	req.Header.Set("Authorization", "Bearer "+scimTestToken)
+	req.Header.Set("Content-Type", "application/scim+json")
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	decoded := map[string]interface{}{}
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded), w.Body.String())
+	}
+	return w, decoded
+}
+
+func (f *scimFixture) createUser(t *testing.T, userName, displayName string) string {
+	t.Helper()
+	w, body := f.do(t, http.MethodPost, "/Users", map[string]interface{}{
+		"schemas":     []string{model.SCIMSchemaUser},
+		"userName":    userName,
+		"displayName": displayName,
+		"active":      true,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	return body["id"].(string)
+}
+
+func scimPatch(operations ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"schemas": []string{model.SCIMSchemaPatchOp}, "Operations": operations}
+}
+
+func TestSCIM_RequiresToken(t *testing.T) {
+	f := newSCIMFixture(t, 0)
+	for _, header := range []string{"", "Bearer wrong", "Basic " + scimTestToken} {
+		req := httptest.NewRequest(http.MethodGet, controller.SCIMBasePath+"/Users", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		f.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+		assert.Contains(t, w.Body.String(), model.SCIMSchemaError)
+	}
+
+	w, body := f.do(t, http.MethodGet, "/ServiceProviderConfig", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/scim+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, true, body["patch"].(map[string]interface{})["supported"])
+	assert.Equal(t, false, body["bulk"].(map[string]interface{})["supported"])
+
+	w, body = f.do(t, http.MethodGet, "/Schemas/"+model.SCIMSchemaUser, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "User", body["name"])
+}
+
+func TestSCIM_UserLifecycle(t *testing.T) {
+	f := newSCIMFixture(t, 0)
+	w, body := f.do(t, http.MethodPost, "/Users", map[string]interface{}{
+		"schemas":  []string{model.SCIMSchemaUser},
+		"userName": "alice@example.com",
+		"name":     map[string]string{"givenName": "Alice", "familyName": "Liddell"},
+		"password": "correct-horse",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	id := body["id"].(string)
+	assert.Equal(t, "http://example.com/scim/v2/Users/"+id, w.Header().Get("Location"))
+	assert.Equal(t, "Alice Liddell", body["displayName"])
+	assert.Equal(t, true, body["active"])
+	assert.NotContains(t, body, "password")
+	require.Len(t, f.users.Users, 1)
+	assert.NotNil(t, f.users.Users[0].EmailVerifiedAt)
+	assert.NotEqual(t, "correct-horse", f.users.Users[0].Password)
+
+	// userName is unique and must be an email address
+	w, body = f.do(t, http.MethodPost, "/Users", map[string]interface{}{"userName": "ALICE@example.com"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "uniqueness", body["scimType"])
+	w, body = f.do(t, http.MethodPost, "/Users", map[string]interface{}{"userName": "alice"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidValue", body["scimType"])
+	w, _ = f.do(t, http.MethodPost, "/Users", map[string]interface{}{"userName": "bob@example.com", "password": "short"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Deactivate as Azure AD does: string boolean, capitalised op
+	w, body = f.do(t, http.MethodPatch, "/Users/"+id, scimPatch(
+		map[string]interface{}{"op": "Replace", "path": "active", "value": "False"},
+		map[string]interface{}{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "ignored@example.com"},
+	))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, false, body["active"])
+	assert.Equal(t, "alice@example.com", body["userName"])
+	assert.NotNil(t, f.users.Users[0].DeletedAt)
+
+	// Reactivate and rename with a path-less operation
+	w, body = f.do(t, http.MethodPatch, "/Users/"+id, scimPatch(
+		map[string]interface{}{"op": "replace", "value": map[string]interface{}{"active": true, "displayName": "Alice L."}},
+	))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, "Alice L.", body["displayName"])
+	assert.Nil(t, f.users.Users[0].DeletedAt)
+
+	w, body = f.do(t, http.MethodPatch, "/Users/"+id, scimPatch(map[string]interface{}{"op": "replace", "path": "id", "value": "other"}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "mutability", body["scimType"])
+	w, _ = f.do(t, http.MethodPatch, "/Users/"+id, map[string]interface{}{"Operations": []interface{}{}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, body = f.do(t, http.MethodPut, "/Users/"+id, map[string]interface{}{
+		"schemas":  []string{model.SCIMSchemaUser},
+		"userName": "alice.liddell@example.com",
+		"name":     map[string]string{"formatted": "Alice Liddell"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "alice.liddell@example.com", body["userName"])
+	assert.Equal(t, true, body["active"])
+
+	w, _ = f.do(t, http.MethodDelete, "/Users/"+id, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, body = f.do(t, http.MethodGet, "/Users/"+id, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404", body["status"])
+}
+
+func TestSCIM_ListUsersFilterAndPagination(t *testing.T) {
+	f := newSCIMFixture(t, 0)
+	for i := 1; i <= 5; i++ {
+		f.createUser(t, fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("User %d", i))
+	}
+	f.createUser(t, "carol@corp.example", "Carol")
+	f.users.Users[1].DeletedAt = f.users.Users[1].CreatedAt
+
+	list := func(query url.Values) map[string]interface{} {
+		t.Helper()
+		w, body := f.do(t, http.MethodGet, "/Users?"+query.Encode(), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return body
+	}
+	names := func(body map[string]interface{}) []string {
+		out := []string{}
+		for _, r := range body["Resources"].([]interface{}) {
+			out = append(out, r.(map[string]interface{})["userName"].(string))
+		}
+		return out
+	}
+
+	body := list(url.Values{"startIndex": {"2"}, "count": {"2"}})
+	assert.Equal(t, float64(6), body["totalResults"])
+	assert.Equal(t, float64(2), body["startIndex"])
+	assert.Equal(t, []string{"user2@example.com", "user3@example.com"}, names(body))
+
+	body = list(url.Values{"filter": {`userName eq "USER4@example.com"`}})
+	assert.Equal(t, []string{"user4@example.com"}, names(body))
+
+	body = list(url.Values{"filter": {`userName sw "user" and active eq false`}})
+	assert.Equal(t, float64(1), body["totalResults"])
+	assert.Equal(t, []string{"user2@example.com"}, names(body))
+
+	body = list(url.Values{"filter": {`userName ew "corp.example" or displayName eq "User 5"`}, "count": {"0"}})
+	assert.Equal(t, float64(2), body["totalResults"])
+	assert.Empty(t, body["Resources"])
+
+	body = list(url.Values{"filter": {`userName eq "carol@corp.example"`}, "attributes": {"userName"}})
+	resource := body["Resources"].([]interface{})[0].(map[string]interface{})
+	assert.ElementsMatch(t, []string{"id", "schemas", "userName"}, keys(resource))
+
+	w, errBody := f.do(t, http.MethodGet, "/Users?filter="+url.QueryEscape(`userName xx "a"`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidFilter", errBody["scimType"])
+}
+
+func TestSCIM_FilterScanLimit(t *testing.T) {
+	f := newSCIMFixture(t, 200)
+	for i := 0; i < 201; i++ {
+		f.users.Users = append(f.users.Users, model.Users{ID: uint(i + 1), UUID: fmt.Sprintf("u-%d", i), Email: fmt.Sprintf("u%d@example.com", i)})
+	}
+	w, body := f.do(t, http.MethodGet, "/Users?filter="+url.QueryEscape(`active eq true`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "tooMany", body["scimType"])
+
+	// Conditions the database evaluates are not limited
+	w, body = f.do(t, http.MethodGet, "/Users?filter="+url.QueryEscape(`userName sw "u1"`), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(111), body["totalResults"]) // u1, u10-u19, u100-u199
+}
+
+func TestSCIM_GroupMembership(t *testing.T) {
+	f := newSCIMFixture(t, 0)
+	alice := f.createUser(t, "alice@example.com", "Alice")
+	bob := f.createUser(t, "bob@example.com", "Bob")
+	carol := f.createUser(t, "carol@example.com", "Carol")
+
+	w, body := f.do(t, http.MethodPost, "/Groups", map[string]interface{}{
+		"schemas":     []string{model.SCIMSchemaGroup},
+		"displayName": "engineering",
+		"members":     []map[string]string{{"value": alice}, {"value": bob}},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	groupID := body["id"].(string)
+	assert.Len(t, body["members"], 2)
+	for _, m := range f.members.Members {
+		assert.Equal(t, repository.DefaultSCIMMemberRole, m.Role)
+	}
+
+	w, body = f.do(t, http.MethodPost, "/Groups", map[string]interface{}{"displayName": "engineering"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, body = f.do(t, http.MethodPost, "/Groups", map[string]interface{}{"displayName": "ops", "members": []map[string]string{{"value": "missing-uuid"}}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidValue", body["scimType"])
+
+	// Okta style: add, then remove with a value filter
+	w, _ = f.do(t, http.MethodPatch, "/Groups/"+groupID, scimPatch(
+		map[string]interface{}{"op": "add", "path": "members", "value": []map[string]string{{"value": carol}, {"value": alice}}},
+		map[string]interface{}{"op": "remove", "path": `members[value eq "` + bob + `"]`},
+		map[string]interface{}{"op": "replace", "path": "displayName", "value": "platform"},
+	))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w, body = f.do(t, http.MethodGet, "/Groups/"+groupID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "platform", body["displayName"])
+	assert.ElementsMatch(t, []string{alice, carol}, memberValues(body))
+	assert.Equal(t, "http://example.com/scim/v2/Users/"+alice, body["members"].([]interface{})[0].(map[string]interface{})["$ref"])
+
+	w, body = f.do(t, http.MethodGet, "/Users/"+carol, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "platform", body["groups"].([]interface{})[0].(map[string]interface{})["display"])
+
+	w, body = f.do(t, http.MethodGet, "/Groups?"+url.Values{"filter": {`members.value eq "` + carol + `"`}, "excludedAttributes": {"members"}}.Encode(), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, float64(1), body["totalResults"])
+	assert.NotContains(t, body["Resources"].([]interface{})[0], "members")
+
+	w, _ = f.do(t, http.MethodPatch, "/Groups/"+groupID, scimPatch(map[string]interface{}{"op": "remove", "path": "members"}))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, f.members.Members)
+
+	w, _ = f.do(t, http.MethodDelete, "/Groups/"+groupID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w, _ = f.do(t, http.MethodGet, "/Groups/"+groupID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func keys(m map[string]interface{}) []string {
+	out := []string{}
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func memberValues(group map[string]interface{}) []string {
+	out := []string{}
+	members, _ := group["members"].([]interface{})
+	for _, m := range members {
+		out = append(out, m.(map[string]interface{})["value"].(string))
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const serviceAccountTokenPath = "/v1/share/common/auth/oauth/token"
+
+func newServiceAccountTokenRouter(t *testing.T) (*gin.Engine, repository.CommonRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	common := repository.NewCommonRepository(conf, client)
+
+	saRepo := &mock.MockServiceAccountRepository{
+		AuthenticateFunc: func(c *gin.Context, clientID, clientSecret string) (*model.ServiceAccounts, error) {
+			if clientID == "sa-0123456789abcdef" && clientSecret == "s3cret" {
+				return &model.ServiceAccounts{ID: 3, UUID: "sa-uuid", Name: "nightly-report", Role: "user"}, nil
+			}
+			return nil, repository.ErrServiceAccountInvalidClient
+		},
+	}
+
+	router := gin.New()
+	router.POST(serviceAccountTokenPath, controller.NewServiceAccountControllerForPublic(saRepo, common).Token)
+	return router, common
+}
+
+func postClientCredentials(router *gin.Engine, form url.Values, basicUser, basicPass string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, serviceAccountTokenPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestServiceAccountToken_BasicAuth(t *testing.T) {
+	router, common := newServiceAccountTokenRouter(t)
+
+	w := postClientCredentials(router, url.Values{"grant_type": {"client_credentials"}}, "sa-0123456789abcdef", "s3cret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var resp response.OAuthTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.AccessToken)
+	assert.Empty(t, resp.RefreshToken)
+
+	claims, err := common.ValidateJWTToken(resp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, model.TokenUseAccess, claims.TokenUse)
+	assert.Equal(t, "sa-uuid", claims.Subject)
+	assert.Equal(t, "user", claims.Role)
+	assert.Equal(t, "sa-0123456789abcdef", claims.ClientID)
+	assert.Empty(t, claims.Email)
+}
+
+func TestServiceAccountToken_FormCredentials(t *testing.T) {
+	router, _ := newServiceAccountTokenRouter(t)
+
+	w := postClientCredentials(router, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"sa-0123456789abcdef"},
+		"client_secret": {"s3cret"},
+	}, "", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestServiceAccountToken_InvalidClient(t *testing.T) {
+	router, _ := newServiceAccountTokenRouter(t)
+
+	w := postClientCredentials(router, url.Values{"grant_type": {"client_credentials"}}, "sa-0123456789abcdef", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	var resp response.OAuthErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid_client", resp.Error)
+}
+
+func TestServiceAccountToken_UnsupportedGrantType(t *testing.T) {
+	router, _ := newServiceAccountTokenRouter(t)
+
+	w := postClientCredentials(router, url.Values{"grant_type": {"password"}}, "sa-0123456789abcdef", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp response.OAuthErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "unsupported_grant_type", resp.Error)
+}
@@ -32,6 +32,7 @@ func NewTestHelper() *TestHelper {
 			Application: config.Application{
 				Common: config.Common{},
 				Server: config.Server{
+					JWTSecret: "test-secret-key-for-jwt-that-is-long-enough",
 					Admin: config.Admin{
 						Emails: []string{"admin@test.com"},
 					},
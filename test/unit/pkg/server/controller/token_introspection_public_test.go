@@ -0,0 +1,215 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenIntrospectionRouter(t *testing.T) (*gin.Engine, repository.CommonRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.OIDC = config.OIDC{
+		Enabled: true,
+		Clients: []config.OIDCClient{
+			{ClientID: "gateway", ClientSecret: "gateway-secret"},
+			{ClientID: "spa", Public: true},
+		},
+	}
+	common := repository.NewCommonRepository(conf, client)
+
+	saRepo := &mock.MockServiceAccountRepository{
+		AuthenticateFunc: func(c *gin.Context, clientID, clientSecret string) (*model.ServiceAccounts, error) {
+			switch {
+			case clientID == "sa-reporter" && clientSecret == "s3cret":
+				return &model.ServiceAccounts{ID: 3, UUID: "reporter-uuid", Name: "reporter", Role: "user"}, nil
+			case clientID == "sa-other" && clientSecret == "0ther":
+				return &model.ServiceAccounts{ID: 4, UUID: "other-uuid", Name: "other", Role: "user"}, nil
+			}
+			return nil, repository.ErrServiceAccountInvalidClient
+		},
+	}
+	ctrl := controller.NewTokenIntrospectionControllerForPublic(
+		repository.NewTokenIntrospectionRepository(common, &mock.MockPersonalAccessTokenRepository{}, client),
+		saRepo,
+		repository.NewOIDCRepository(conf, common, client),
+	)
+
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST(controller.TokenIntrospectionPath, ctrl.Introspect)
+	router.POST(controller.TokenRevocationPath, ctrl.Revoke)
+	return router, common
+}
+
+func postTokenForm(router *gin.Engine, path string, form url.Values, basicUser, basicPass string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func introspect(t *testing.T, router *gin.Engine, token string) response.TokenIntrospectionResponse {
+	t.Helper()
+	w := postTokenForm(router, controller.TokenIntrospectionPath, url.Values{"token": {token}}, "sa-reporter", "s3cret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var resp response.TokenIntrospectionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestIntrospect_AccessAndRefreshTokens(t *testing.T) {
+	router, common := newTokenIntrospectionRouter(t)
+	pair, err := common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+
+	access := introspect(t, router, pair.AccessToken)
+	assert.True(t, access.Active)
+	assert.Equal(t, "alice-uuid", access.Sub)
+	assert.Equal(t, "alice@example.com", access.Username)
+	assert.Equal(t, "Bearer", access.TokenType)
+	assert.Equal(t, model.TokenUseAccess, access.TokenUse)
+	assert.NotZero(t, access.Exp)
+
+	refresh := introspect(t, router, pair.RefreshToken)
+	assert.True(t, refresh.Active)
+	assert.Equal(t, model.TokenUseRefresh, refresh.TokenUse)
+	assert.Empty(t, refresh.TokenType)
+
+	// A rotated refresh token is no longer active
+	_, err = common.RotateRefreshToken(t.Context(), pair.RefreshToken, "")
+	require.NoError(t, err)
+	assert.Equal(t, response.TokenIntrospectionResponse{Active: false}, introspect(t, router, pair.RefreshToken))
+	assert.Equal(t, response.TokenIntrospectionResponse{Active: false}, introspect(t, router, "not-a-token"))
+
+	// Service account tokens carry their client_id
+	saPair, err := common.GenerateClientTokenPair("sa-reporter", "reporter-uuid", "reporter", "user")
+	require.NoError(t, err)
+	sa := introspect(t, router, saPair.AccessToken)
+	assert.True(t, sa.Active)
+	assert.Equal(t, "sa-reporter", sa.ClientID)
+}
+
+func TestIntrospect_ClientAuthentication(t *testing.T) {
+	router, common := newTokenIntrospectionRouter(t)
+	pair, err := common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	form := url.Values{"token": {pair.AccessToken}}
+
+	w := postTokenForm(router, controller.TokenIntrospectionPath, form, "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_client")
+
+	w = postTokenForm(router, controller.TokenIntrospectionPath, form, "sa-reporter", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="locky"`, w.Header().Get("WWW-Authenticate"))
+
+	// Public OIDC clients have no secret to authenticate with
+	w = postTokenForm(router, controller.TokenIntrospectionPath, url.Values{"token": {pair.AccessToken}, "client_id": {"spa"}}, "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Confidential OIDC clients may use client_secret_post
+	w = postTokenForm(router, controller.TokenIntrospectionPath, url.Values{"token": {pair.AccessToken}, "client_id": {"gateway"}, "client_secret": {"gateway-secret"}}, "", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"active":true`)
+
+	w = postTokenForm(router, controller.TokenIntrospectionPath, url.Values{}, "sa-reporter", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_request")
+}
+
+func TestRevoke_RefreshTokenRevokesFamily(t *testing.T) {
+	router, common := newTokenIntrospectionRouter(t)
+	pair, err := common.GenerateAuthorizedTokenPair("gateway", 7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+
+	w := postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {pair.RefreshToken}, "token_type_hint": {"refresh_token"}}, "gateway", "gateway-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, introspect(t, router, pair.RefreshToken).Active)
+	assert.False(t, introspect(t, router, pair.AccessToken).Active)
+
+	// Unknown and already revoked tokens are not an error
+	w = postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {pair.RefreshToken}}, "gateway", "gateway-secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {"not-a-token"}}, "gateway", "gateway-secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRevoke_AccessTokenOnly(t *testing.T) {
+	router, common := newTokenIntrospectionRouter(t)
+	pair, err := common.GenerateAuthorizedTokenPair("gateway", 7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	assert.Equal(t, "gateway", introspect(t, router, pair.AccessToken).ClientID)
+
+	w := postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {pair.AccessToken}}, "gateway", "gateway-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, introspect(t, router, pair.AccessToken).Active)
+	assert.True(t, introspect(t, router, pair.RefreshToken).Active)
+}
+
+func TestRevoke_ServiceAccountTokenOfAnotherClient(t *testing.T) {
+	router, common := newTokenIntrospectionRouter(t)
+	saPair, err := common.GenerateClientTokenPair("sa-reporter", "reporter-uuid", "reporter", "user")
+	require.NoError(t, err)
+
+	w := postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {saPair.AccessToken}}, "sa-other", "0ther")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unauthorized_client")
+	assert.True(t, introspect(t, router, saPair.AccessToken).Active)
+
+	w = postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {saPair.AccessToken}}, "sa-reporter", "s3cret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, introspect(t, router, saPair.AccessToken).Active)
+}
+
+func TestRevoke_UserTokenNotIssuedToCaller(t *testing.T) {
+	router, common := newTokenIntrospectionRouter(t)
+	login, err := common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	issued, err := common.GenerateAuthorizedTokenPair("other-app", 7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+
+	// Password login tokens belong to no client, and azp names another one
+	for _, token := range []string{login.AccessToken, login.RefreshToken, issued.AccessToken, issued.RefreshToken} {
+		for _, caller := range [][2]string{{"sa-reporter", "s3cret"}, {"gateway", "gateway-secret"}} {
+			w := postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {token}}, caller[0], caller[1])
+			assert.Equal(t, http.StatusBadRequest, w.Code, caller[0])
+			assert.Contains(t, w.Body.String(), "unauthorized_client")
+		}
+		assert.True(t, introspect(t, router, token).Active)
+	}
+
+	// A service account cannot revoke user tokens even when its client_id matches azp
+	named, err := common.GenerateAuthorizedTokenPair("sa-reporter", 7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	w := postTokenForm(router, controller.TokenRevocationPath, url.Values{"token": {named.AccessToken}}, "sa-reporter", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,69 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/ryo-arima/locky/pkg/server/usecase"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUser_RevokesSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	common := repository.NewCommonRepository(conf, client)
+	sessions := repository.NewSessionRepository(common, client)
+	var deleted []string
+	userRepo := &mock.MockUserRepository{
+		DeleteUserFunc: func(c *gin.Context, user model.Users) model.Users {
+			deleted = append(deleted, user.UUID)
+			return user
+		},
+	}
+	ctrl := controller.NewUserControllerForPrivate(usecase.NewUserUsecase(userRepo), common, &mock.MockPasswordPolicyRepository{}, sessions)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.DELETE("/v1/private/users/:id", ctrl.DeleteUser)
+
+	ctx := context.Background()
+	alice, err := common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	bob, err := common.GenerateTokenPair(8, "bob-uuid", "bob@example.com", "Bob", "user")
+	require.NoError(t, err)
+
+	payload, _ := json.Marshal(map[string]string{"uuid": "alice-uuid"})
+	req := httptest.NewRequest(http.MethodDelete, "/v1/private/users/7", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"alice-uuid"}, deleted)
+
+	// The deleted user's sessions and refresh tokens end, others keep theirs
+	list, err := sessions.ListSessions(ctx, "alice-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = common.RotateRefreshToken(ctx, alice.RefreshToken, "")
+	assert.Error(t, err)
+	_, err = common.RotateRefreshToken(ctx, bob.RefreshToken, "")
+	assert.NoError(t, err)
+}
@@ -1,13 +1,23 @@
 package controller_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
 	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
 	"github.com/ryo-arima/locky/pkg/server/usecase"
 	mock "github.com/ryo-arima/locky/test/unit/mock/server"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewUserControllerForPublic(t *testing.T) {
@@ -16,7 +26,9 @@ func TestNewUserControllerForPublic(t *testing.T) {
 	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
 	conf := config.BaseConfig{}
 
-	ctrl := controller.NewUserControllerForPublic(userUsecase, commonRepo, conf)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(conf, commonRepo, nil)
+
+	ctrl := controller.NewUserControllerForPublic(userUsecase, commonRepo, emailVerificationRepo, &mock.MockPasswordPolicyRepository{}, conf)
 
 	assert.NotNil(t, ctrl)
 }
@@ -26,7 +38,7 @@ func TestNewUserControllerForInternal(t *testing.T) {
 	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
 	userUsecase := usecase.NewUserUsecase(userRepo)
 
-	ctrl := controller.NewUserControllerForInternal(userUsecase, commonRepo)
+	ctrl := controller.NewUserControllerForInternal(userUsecase, commonRepo, &mock.MockPasswordPolicyRepository{}, nil)
 
 	assert.NotNil(t, ctrl)
 }
@@ -36,7 +48,7 @@ func TestNewUserControllerForPrivate(t *testing.T) {
 	userUsecase := usecase.NewUserUsecase(userRepo)
 	commonRepo := &mock.MockCommonRepository{JWTSecret: "test"}
 
-	ctrl := controller.NewUserControllerForPrivate(userUsecase, commonRepo)
+	ctrl := controller.NewUserControllerForPrivate(userUsecase, commonRepo, &mock.MockPasswordPolicyRepository{}, nil)
 
 	assert.NotNil(t, ctrl)
 }
@@ -49,3 +61,44 @@ func TestUserUsecaseInitialization(t *testing.T) {
 
 	assert.NotNil(t, uc)
 }
+
+func TestUserControllerForPublic_CreateUserRejectsDuplicateEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.BaseConfig{}
+	userRepo := &mock.MockUserRepository{Users: []model.Users{{ID: 1, UUID: "alice-uuid", Email: "alice@example.com"}}}
+	commonRepo := &mock.MockCommonRepository{JWTSecret: "test", BaseConfig: conf}
+	ctrl := controller.NewUserControllerForPublic(usecase.NewUserUsecase(userRepo), commonRepo, repository.NewEmailVerificationRepository(conf, commonRepo, nil), &mock.MockPasswordPolicyRepository{}, conf)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/v1/public/users", ctrl.CreateUser)
+	register := func(email string) (int, response.UserResponse) {
+		payload, _ := json.Marshal(map[string]string{"email": email, "name": "Alice", "password": "Correct-H0rse"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/public/users", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp response.UserResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// The lookup ignores case
+	status, resp := register("Alice@Example.COM")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "SERVER_CONTROLLER_CREATE__FOR__003", resp.Code)
+	assert.Len(t, userRepo.Users, 1)
+
+	// A concurrent registration that passes the lookup is stopped by the unique index
+	userRepo.GetUserByEmailFunc = func(c *gin.Context, email string) (model.Users, error) {
+		return model.Users{}, repository.ErrUserNotFound
+	}
+	status, resp = register("alice@example.com")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "SERVER_CONTROLLER_CREATE__FOR__003", resp.Code)
+	assert.Len(t, userRepo.Users, 1)
+
+	status, resp = register("bob@example.com")
+	require.Equal(t, http.StatusOK, status, resp.Message)
+	assert.Len(t, userRepo.Users, 2)
+	assert.Equal(t, "bob@example.com", userRepo.Users[1].EmailNormalized)
+}
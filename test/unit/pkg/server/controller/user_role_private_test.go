@@ -0,0 +1,152 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/response"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userRoleTestServer struct {
+	router      *gin.Engine
+	common      repository.CommonRepository
+	sessions    repository.SessionRepository
+	assignments *mock.MockRoleAssignmentRepository
+}
+
+func newUserRoleTestServer(t *testing.T) userRoleTestServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.Admin.Emails = []string{"admin@example.com"}
+	common := repository.NewCommonRepository(conf, client)
+	sessions := repository.NewSessionRepository(common, client)
+	assignments := &mock.MockRoleAssignmentRepository{}
+
+	users := []model.Users{
+		{ID: 1, UUID: "admin-uuid", Email: "admin@example.com", Name: "Admin"},
+		{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice"},
+	}
+	userRepo := &mock.MockUserRepository{
+		ListUsersFunc: func(c *gin.Context, filter repository.UserQueryFilter) ([]model.Users, error) {
+			for _, u := range users {
+				if (filter.ID != nil && u.ID == *filter.ID) || (filter.UUID != nil && u.UUID == *filter.UUID) {
+					return []model.Users{u}, nil
+				}
+			}
+			return []model.Users{}, nil
+		},
+	}
+	ctrl := controller.NewUserRoleControllerForPrivate(assignments, userRepo, sessions, common, enforcer)
+
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	private := router.Group("/v1/private", middleware.ForPrivate(common, enforcer))
+	private.GET("/users/:id/roles", middleware.CasbinAuthorization(enforcer, "roles", "read"), ctrl.ListUserRoles)
+	private.POST("/users/:id/roles", middleware.CasbinAuthorization(enforcer, "roles", "write"), ctrl.GrantUserRole)
+	private.DELETE("/users/:id/roles/:role", middleware.CasbinAuthorization(enforcer, "roles", "write"), ctrl.RevokeUserRole)
+	return userRoleTestServer{router: router, common: common, sessions: sessions, assignments: assignments}
+}
+
+func (s userRoleTestServer) do(t *testing.T, method, path, bearer string, body interface{}) (int, response.UserRoleResponse) {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	var res response.UserRoleResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func TestUserRoles_GrantListRevoke(t *testing.T) {
+	s := newUserRoleTestServer(t)
+	admin, err := s.common.GenerateTokenPair(1, "admin-uuid", "admin@example.com", "Admin", "admin")
+	require.NoError(t, err)
+	alice, err := s.common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+
+	status, res := s.do(t, http.MethodPost, "/v1/private/users/7/roles", admin.AccessToken, map[string]string{"role": "admin"})
+	require.Equal(t, http.StatusOK, status, res.Message)
+	require.Len(t, res.Roles, 1)
+	assert.Equal(t, "admin-uuid", res.Roles[0].GrantedBy)
+
+	status, res = s.do(t, http.MethodGet, "/v1/private/users/alice-uuid/roles", admin.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, res.Roles, 2)
+	assert.Equal(t, response.UserRole{Role: "user", Source: controller.UserRoleSourceConfig}, res.Roles[0])
+	assert.Equal(t, "admin", res.Roles[1].Role)
+	assert.Equal(t, controller.UserRoleSourceAssigned, res.Roles[1].Source)
+	assert.Equal(t, []string{"admin", "user"}, s.assignments.RolesOf(s.common.GetBaseConfig(), model.Users{UUID: "alice-uuid", Email: "alice@example.com"}))
+
+	status, res = s.do(t, http.MethodPost, "/v1/private/users/7/roles", admin.AccessToken, map[string]string{"role": "admin"})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "USER_ROLE_GRANT_003", res.Code)
+
+	// Revoking ends the sessions issued while the role was held
+	status, _ = s.do(t, http.MethodDelete, "/v1/private/users/7/roles/admin", admin.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, s.assignments.Assignments)
+	list, err := s.sessions.ListSessions(context.Background(), "alice-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = s.common.RotateRefreshToken(context.Background(), alice.RefreshToken, "")
+	assert.Error(t, err)
+
+	status, res = s.do(t, http.MethodDelete, "/v1/private/users/7/roles/admin", admin.AccessToken, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "USER_ROLE_REVOKE_001", res.Code)
+}
+
+func TestUserRoles_GrantValidation(t *testing.T) {
+	s := newUserRoleTestServer(t)
+	admin, err := s.common.GenerateTokenPair(1, "admin-uuid", "admin@example.com", "Admin", "admin")
+	require.NoError(t, err)
+	alice, err := s.common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+
+	status, res := s.do(t, http.MethodPost, "/v1/private/users/7/roles", admin.AccessToken, map[string]string{"role": "superuser"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "USER_ROLE_GRANT_002", res.Code)
+
+	status, res = s.do(t, http.MethodPost, "/v1/private/users/99/roles", admin.AccessToken, map[string]string{"role": "admin"})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "USER_ROLE_USER_002", res.Code)
+
+	// Users may read roles but not grant them
+	status, _ = s.do(t, http.MethodPost, "/v1/private/users/7/roles", alice.AccessToken, map[string]string{"role": "admin"})
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Empty(t, s.assignments.Assignments)
+}
@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRolesRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+	_, err = enforcer.AddPolicy("auditor", "lockouts", "read")
+	require.NoError(t, err)
+
+	tokens := map[string]*model.JWTClaims{
+		"user":    {UUID: "alice-uuid", Role: "user", Roles: []string{"user"}},
+		"auditor": {UUID: "alice-uuid", Role: "user", Roles: []string{"user", "auditor"}},
+		"legacy":  {UUID: "alice-uuid", Role: "admin"},
+	}
+	commonRepo := &mock.MockCommonRepository{
+		ValidateTokenFunc: func(tokenString string) (*model.JWTClaims, error) {
+			return tokens[tokenString], nil
+		},
+	}
+
+	router := gin.New()
+	api := router.Group("/v1/private", middleware.ForPrivate(commonRepo, enforcer))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/lockouts", middleware.CasbinAuthorization(enforcer, "lockouts", "read"), ok)
+	api.POST("/group", middleware.CasbinAuthorization(enforcer, "groups", "write"), ok)
+	return router
+}
+
+func TestCasbinAuthorization_AnyRoleAllows(t *testing.T) {
+	router := newRolesRouter(t)
+
+	assert.Equal(t, http.StatusForbidden, doWithBearer(router, http.MethodGet, "/v1/private/lockouts", "user"))
+	// The granted auditor role allows what the primary user role does not
+	assert.Equal(t, http.StatusOK, doWithBearer(router, http.MethodGet, "/v1/private/lockouts", "auditor"))
+	assert.Equal(t, http.StatusOK, doWithBearer(router, http.MethodPost, "/v1/private/group", "auditor"))
+	// Tokens without the roles claim are evaluated with role
+	assert.Equal(t, http.StatusOK, doWithBearer(router, http.MethodGet, "/v1/private/lockouts", "legacy"))
+}
@@ -0,0 +1,265 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/entity/request"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Every caller is a member of group-a with the role of the same name
+var groupTestMembers = []model.Members{
+	{ID: 1, UUID: "m-owner", GroupUUID: "group-a", UserUUID: "owner", Role: "owner"},
+	{ID: 2, UUID: "m-maintainer", GroupUUID: "group-a", UserUUID: "maintainer", Role: "maintainer"},
+	{ID: 3, UUID: "m-member", GroupUUID: "group-a", UserUUID: "member", Role: "member"},
+	{ID: 4, UUID: "m-viewer", GroupUUID: "group-a", UserUUID: "viewer", Role: "viewer"},
+	{ID: 5, UUID: "m-owner-b", GroupUUID: "group-b", UserUUID: "owner-b", Role: "owner"},
+}
+
+func newGroupRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	appEnforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+	resourceEnforcer, err := casbin.NewSyncedEnforcer("../../../../.etc/casbin/resources/model.conf", "../../../../.etc/casbin/resources/policy.csv")
+	require.NoError(t, err)
+
+	commonRepo := &mock.MockCommonRepository{
+		ValidateTokenFunc: func(tokenString string) (*model.JWTClaims, error) {
+			return &model.JWTClaims{UUID: tokenString, Role: "user"}, nil
+		},
+	}
+	memberRepo := &mock.MockMemberRepository{
+		ListMembersFunc: func(c *gin.Context, filter repository.MemberQueryFilter) ([]model.Members, error) {
+			list := []model.Members{}
+			for _, m := range groupTestMembers {
+				if (filter.ID != nil && m.ID != *filter.ID) || (filter.UUID != nil && m.UUID != *filter.UUID) ||
+					(filter.GroupUUID != nil && m.GroupUUID != *filter.GroupUUID) || (filter.UserUUID != nil && m.UserUUID != *filter.UserUUID) {
+					continue
+				}
+				list = append(list, m)
+			}
+			return list, nil
+		},
+	}
+	groups := []model.Groups{{ID: 1, UUID: "group-a", Name: "A"}, {ID: 2, UUID: "group-b", Name: "B"}}
+	groupRepo := &mock.MockGroupRepository{
+		GetGroupByIDFunc: func(c *gin.Context, id uint) (model.Groups, error) {
+			for _, g := range groups {
+				if g.ID == id {
+					return g, nil
+				}
+			}
+			return model.Groups{}, gorm.ErrRecordNotFound
+		},
+		GetGroupByUUIDFunc: func(c *gin.Context, uuid string) (model.Groups, error) {
+			for _, g := range groups {
+				if g.UUID == uuid {
+					return g, nil
+				}
+			}
+			return model.Groups{}, gorm.ErrRecordNotFound
+		},
+	}
+	groupPermissionRepo := repository.NewGroupPermissionRepository(memberRepo, resourceEnforcer)
+
+	router := gin.New()
+	api := router.Group("/v1/internal", middleware.ForInternal(commonRepo, appEnforcer))
+	// Echo the body to show the controller still receives it
+	echo := func(c *gin.Context) {
+		var body map[string]interface{}
+		_ = c.ShouldBindBodyWith(&body, binding.JSON)
+		c.JSON(http.StatusOK, body)
+	}
+	api.PUT("/group/:id", middleware.GroupAuthorization(groupPermissionRepo, groupRepo, "group_info", "write"), echo)
+	api.POST("/member", middleware.MemberAuthorization(groupPermissionRepo, memberRepo, "member", "write"), echo)
+	api.PUT("/member/:id", middleware.MemberAuthorization(groupPermissionRepo, memberRepo, "member", "write"), echo)
+	api.DELETE("/member/:id", middleware.MemberAuthorization(groupPermissionRepo, memberRepo, "member", "write"), echo)
+	return router
+}
+
+func doGroupRequest(t *testing.T, router *gin.Engine, method, path, caller string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+caller)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func TestGroupAuthorization_GroupInfo(t *testing.T) {
+	router := newGroupRouter(t)
+	rename := request.GroupRequest{ID: 1, Name: "renamed"}
+
+	status, res := doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/1", "maintainer", rename)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "renamed", res["name"])
+
+	status, res = doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/1", "member", rename)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "MIDDLEWARE_GROUP_004", res["code"])
+	// Owners of another group hold no role here
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/1", "owner-b", rename)
+	assert.Equal(t, http.StatusForbidden, status)
+	// Both the path and the body name groups the caller must be allowed in
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/2", "owner", rename)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/2", "owner", request.GroupRequest{Name: "renamed"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/1", "owner", request.GroupRequest{ID: 2, Name: "renamed"})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, res = doGroupRequest(t, router, http.MethodPut, "/v1/internal/group/9", "owner", request.GroupRequest{ID: 9})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "MIDDLEWARE_GROUP_002", res["code"])
+}
+
+func TestMemberAuthorization_ManageMembers(t *testing.T) {
+	router := newGroupRouter(t)
+
+	for _, caller := range []string{"owner", "maintainer"} {
+		status, res := doGroupRequest(t, router, http.MethodPost, "/v1/internal/member", caller, request.MemberRequest{GroupUUID: "group-a", UserUUID: "new", Role: "member"})
+		assert.Equal(t, http.StatusOK, status, caller)
+		assert.Equal(t, "new", res["user_uuid"])
+	}
+	for _, caller := range []string{"member", "viewer", "owner-b"} {
+		status, res := doGroupRequest(t, router, http.MethodPost, "/v1/internal/member", caller, request.MemberRequest{GroupUUID: "group-a", UserUUID: "new", Role: "viewer"})
+		assert.Equal(t, http.StatusForbidden, status, caller)
+		assert.Equal(t, "MIDDLEWARE_GROUP_004", res["code"], caller)
+	}
+
+	status, res := doGroupRequest(t, router, http.MethodPost, "/v1/internal/member", "owner", request.MemberRequest{GroupUUID: "group-a", UserUUID: "new", Role: "admin"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "MIDDLEWARE_GROUP_005", res["code"])
+
+	// Deleting goes by the group of the membership
+	status, _ = doGroupRequest(t, router, http.MethodDelete, "/v1/internal/member/0", "maintainer", request.MemberRequest{UUID: "m-member"})
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = doGroupRequest(t, router, http.MethodDelete, "/v1/internal/member/0", "owner", request.MemberRequest{UUID: "m-owner-b"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, res = doGroupRequest(t, router, http.MethodDelete, "/v1/internal/member/0", "owner", request.MemberRequest{UUID: "missing"})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "MIDDLEWARE_GROUP_002", res["code"])
+
+	// Moving a membership needs member write in the target group too
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/member/3", "owner", request.MemberRequest{ID: 3, GroupUUID: "group-b"})
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestMemberAuthorization_RoleCeiling(t *testing.T) {
+	router := newGroupRouter(t)
+
+	// Maintainers cannot hand out or take away more than they hold
+	status, res := doGroupRequest(t, router, http.MethodPost, "/v1/internal/member", "maintainer", request.MemberRequest{GroupUUID: "group-a", UserUUID: "new", Role: "owner"})
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "MIDDLEWARE_GROUP_006", res["code"])
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/member/2", "maintainer", request.MemberRequest{ID: 2, Role: "owner"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doGroupRequest(t, router, http.MethodDelete, "/v1/internal/member/0", "maintainer", request.MemberRequest{UUID: "m-owner"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/member/3", "maintainer", request.MemberRequest{ID: 3, Role: "maintainer"})
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = doGroupRequest(t, router, http.MethodPut, "/v1/internal/member/2", "owner", request.MemberRequest{ID: 2, Role: "owner"})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+// The controllers must act on the same JSON the middleware authorized, even
+// when the request is not sent as JSON and the query string names another target
+func TestGroupAuthorization_IgnoresQueryAndForm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appEnforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+	resourceEnforcer, err := casbin.NewSyncedEnforcer("../../../../.etc/casbin/resources/model.conf", "../../../../.etc/casbin/resources/policy.csv")
+	require.NoError(t, err)
+	commonRepo := &mock.MockCommonRepository{
+		ValidateTokenFunc: func(tokenString string) (*model.JWTClaims, error) {
+			return &model.JWTClaims{UUID: tokenString, Role: "user"}, nil
+		},
+	}
+	memberRepo := &mock.MockMemberRepository{
+		ListMembersFunc: func(c *gin.Context, filter repository.MemberQueryFilter) ([]model.Members, error) {
+			list := []model.Members{}
+			for _, m := range groupTestMembers {
+				if (filter.ID != nil && m.ID != *filter.ID) || (filter.UUID != nil && m.UUID != *filter.UUID) ||
+					(filter.GroupUUID != nil && m.GroupUUID != *filter.GroupUUID) || (filter.UserUUID != nil && m.UserUUID != *filter.UserUUID) {
+					continue
+				}
+				list = append(list, m)
+			}
+			return list, nil
+		},
+	}
+	deletedGroups, deletedMembers := []string{}, []string{}
+	groupRepo := &mock.MockGroupRepository{
+		GetGroupByIDFunc: func(c *gin.Context, id uint) (model.Groups, error) {
+			if id == 1 {
+				return model.Groups{ID: 1, UUID: "group-a"}, nil
+			}
+			return model.Groups{ID: 2, UUID: "group-b"}, nil
+		},
+		GetGroupByUUIDFunc: func(c *gin.Context, uuid string) (model.Groups, error) {
+			return model.Groups{UUID: uuid}, nil
+		},
+		DeleteGroupFunc: func(c *gin.Context, uuid string) error {
+			deletedGroups = append(deletedGroups, uuid)
+			return nil
+		},
+	}
+	memberRepo.DeleteMemberFunc = func(c *gin.Context, uuid string) error {
+		deletedMembers = append(deletedMembers, uuid)
+		return nil
+	}
+	groupPermissionRepo := repository.NewGroupPermissionRepository(memberRepo, resourceEnforcer)
+
+	router := gin.New()
+	api := router.Group("/v1/internal", middleware.ForInternal(commonRepo, appEnforcer))
+	api.DELETE("/group/:id", middleware.GroupAuthorization(groupPermissionRepo, groupRepo, "group_info", "write"), controller.NewGroupControllerForInternal(groupRepo, commonRepo).DeleteGroup)
+	api.DELETE("/member/:id", middleware.MemberAuthorization(groupPermissionRepo, memberRepo, "member", "write"), controller.NewMemberControllerForInternal(memberRepo, commonRepo).DeleteMember)
+
+	send := func(path, contentType, body string) int {
+		req := httptest.NewRequest(http.MethodDelete, path, bytes.NewBufferString(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Authorization", "Bearer owner")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		status := send("/v1/internal/group/0?UUID=group-b&uuid=group-b", contentType, `{"uuid":"group-a"}`)
+		assert.Equal(t, http.StatusOK, status, contentType)
+		status = send("/v1/internal/member/0?UUID=m-owner-b&uuid=m-owner-b", contentType, `{"uuid":"m-member"}`)
+		assert.Equal(t, http.StatusOK, status, contentType)
+	}
+	assert.Equal(t, []string{"group-a", "group-a", "group-a"}, deletedGroups)
+	assert.Equal(t, []string{"m-member", "m-member", "m-member"}, deletedMembers)
+
+	// A group named only by the path is still authorized
+	status := send("/v1/internal/group/2", "application/json", `{"uuid":"group-a"}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	status = send("/v1/internal/member/5", "application/json", `{"uuid":"m-member"}`)
+	assert.Equal(t, http.StatusForbidden, status)
+}
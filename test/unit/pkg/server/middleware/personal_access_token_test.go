@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPAT = repository.PersonalAccessTokenPrefix + "test-token"
+
+func newPATRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+
+	commonRepo := &mock.MockCommonRepository{
+		ValidatePATFunc: func(ctx context.Context, token string) (*model.JWTClaims, error) {
+			if token != testPAT {
+				return nil, repository.ErrPersonalAccessTokenInvalid
+			}
+			return &model.JWTClaims{UUID: "user-uuid", Role: "user", TokenUse: model.TokenUsePAT, Scope: "users:read groups:read"}, nil
+		},
+		ValidateTokenFunc: func(tokenString string) (*model.JWTClaims, error) {
+			t.Fatalf("personal access token must not be validated as a JWT")
+			return nil, nil
+		},
+	}
+
+	router := gin.New()
+	api := router.Group("/v1/internal", middleware.ForInternal(commonRepo, enforcer))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/users", middleware.CasbinAuthorization(enforcer, "users", "read"), ok)
+	api.POST("/group", middleware.CasbinAuthorization(enforcer, "groups", "write"), ok)
+	return router
+}
+
+func doWithBearer(router *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPersonalAccessToken_AllowedScope(t *testing.T) {
+	router := newPATRouter(t)
+	assert.Equal(t, http.StatusOK, doWithBearer(router, http.MethodGet, "/v1/internal/users", testPAT))
+}
+
+func TestPersonalAccessToken_ScopeNarrowsRole(t *testing.T) {
+	router := newPATRouter(t)
+	// the user role may write groups, but the token only carries groups:read
+	assert.Equal(t, http.StatusForbidden, doWithBearer(router, http.MethodPost, "/v1/internal/group", testPAT))
+}
+
+func TestPersonalAccessToken_Invalid(t *testing.T) {
+	router := newPATRouter(t)
+	assert.Equal(t, http.StatusUnauthorized, doWithBearer(router, http.MethodGet, "/v1/internal/users", repository.PersonalAccessTokenPrefix+"revoked"))
+}
@@ -0,0 +1,191 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingRateLimitRepository struct{}
+
+func (failingRateLimitRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (repository.RateLimitResult, error) {
+	return repository.RateLimitResult{}, errors.New("redis unavailable")
+}
+
+func newRateLimitRouter(rateLimit config.RateLimit, repo repository.RateLimitRepository) *gin.Engine {
+	return newRateLimitRouterBehind(rateLimit, repo, nil)
+}
+
+// newRateLimitRouterBehind configures the trusted proxies like InitRouter
+func newRateLimitRouterBehind(rateLimit config.RateLimit, repo repository.RateLimitRepository, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.RateLimit = rateLimit
+	conf.YamlConfig.Application.Server.TrustedProxies = trustedProxies
+	if repo == nil {
+		repo = repository.NewRateLimitRepository(conf, nil)
+	}
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	// Stand-in for ForInternal: the user is taken from a header
+	fakeAuth := func(c *gin.Context) {
+		if uuid := c.GetHeader("X-Test-User"); uuid != "" {
+			c.Set("user_uuid", uuid)
+			c.Set("user_role", c.GetHeader("X-Test-Role"))
+		}
+		c.Next()
+	}
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(conf.YamlConfig.Application.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
+	public := router.Group("/v1/public", middleware.RateLimit(conf, repo, middleware.RateLimitTierPublic))
+	public.POST("/user", ok)
+	public.GET("/info", ok)
+	internal := router.Group("/v1/internal", fakeAuth, middleware.RateLimit(conf, repo, middleware.RateLimitTierInternal))
+	internal.GET("/users", ok)
+	private := router.Group("/v1/private", fakeAuth, middleware.RateLimit(conf, repo, middleware.RateLimitTierPrivate))
+	private.GET("/users", ok)
+	auth := middleware.RateLimit(conf, repo, middleware.RateLimitTierAuth)
+	router.POST("/v1/share/common/auth/tokens", auth, ok)
+	router.POST("/v1/share/common/auth/tokens/mfa", auth, ok)
+	router.POST("/v1/share/common/auth/tokens/refresh", auth, ok)
+	return router
+}
+
+func doRateLimited(router *gin.Engine, method, path, ip, user, role string) *httptest.ResponseRecorder {
+	return doRateLimitedFor(router, method, path, ip, "", user, role)
+}
+
+func doRateLimitedFor(router *gin.Engine, method, path, ip, forwardedFor, user, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":12345"
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Role", role)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_PublicPerIP(t *testing.T) {
+	router := newRateLimitRouter(config.RateLimit{Public: config.RateLimitRule{Requests: 2, WindowSeconds: 60}}, nil)
+
+	w := doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+	reset, err := strconv.Atoi(w.Header().Get("RateLimit-Reset"))
+	require.NoError(t, err)
+	assert.True(t, reset >= 1 && reset <= 60, reset)
+
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "", "").Code)
+	w = doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "MIDDLEWARE_RATE_001")
+
+	// The limit covers the whole tier but not other clients
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimited(router, http.MethodPost, "/v1/public/user", "192.0.2.1", "", "").Code)
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.2", "", "").Code)
+}
+
+func TestRateLimit_RouteOverride(t *testing.T) {
+	router := newRateLimitRouter(config.RateLimit{
+		Public: config.RateLimitRule{Requests: 100},
+		Routes: []config.RateLimitRoute{{Method: "POST", Path: "/v1/public/user", RateLimitRule: config.RateLimitRule{Requests: 1, WindowSeconds: 3600}}},
+	}, nil)
+
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodPost, "/v1/public/user", "192.0.2.1", "", "").Code)
+	w := doRateLimited(router, http.MethodPost, "/v1/public/user", "192.0.2.1", "", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1;w=3600", w.Header().Get("RateLimit-Policy"))
+
+	// Other routes of the tier keep the tier limit and counter
+	w = doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "99", w.Header().Get("RateLimit-Remaining"))
+}
+
+func TestRateLimit_KeyByUserAndRole(t *testing.T) {
+	router := newRateLimitRouter(config.RateLimit{
+		Internal: config.RateLimitRule{Requests: 1, KeyBy: middleware.RateLimitKeyByUser},
+		Private:  config.RateLimitRule{Requests: 1, KeyBy: middleware.RateLimitKeyByRole},
+	}, nil)
+
+	// Per user, regardless of the client IP
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/internal/users", "192.0.2.1", "alice", "user").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimited(router, http.MethodGet, "/v1/internal/users", "192.0.2.2", "alice", "user").Code)
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/internal/users", "192.0.2.1", "bob", "user").Code)
+
+	// Per role: all admins share the limit
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/private/users", "192.0.2.1", "alice", "admin").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimited(router, http.MethodGet, "/v1/private/users", "192.0.2.2", "carol", "admin").Code)
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/private/users", "192.0.2.1", "bob", "user").Code)
+}
+
+func TestRateLimit_UnlimitedAndFailOpen(t *testing.T) {
+	router := newRateLimitRouter(config.RateLimit{}, nil)
+	for i := 0; i < 5; i++ {
+		w := doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+
+	router = newRateLimitRouter(config.RateLimit{Public: config.RateLimitRule{Requests: 1}}, failingRateLimitRepository{})
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "", "").Code)
+	}
+}
+
+func TestRateLimit_AuthTierDefault(t *testing.T) {
+	router := newRateLimitRouter(config.RateLimit{}, nil)
+	for i := 0; i < middleware.DefaultAuthRateLimit.Requests; i++ {
+		require.Equal(t, http.StatusOK, doRateLimited(router, http.MethodPost, "/v1/share/common/auth/tokens/mfa", "192.0.2.1", "", "").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimited(router, http.MethodPost, "/v1/share/common/auth/tokens/mfa", "192.0.2.1", "", "").Code)
+	assert.Equal(t, http.StatusOK, doRateLimited(router, http.MethodPost, "/v1/share/common/auth/tokens/mfa", "192.0.2.2", "", "").Code)
+
+	// Login and refresh share the counter of the credential endpoints
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimited(router, http.MethodPost, "/v1/share/common/auth/tokens", "192.0.2.1", "", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimited(router, http.MethodPost, "/v1/share/common/auth/tokens/refresh", "192.0.2.1", "", "").Code)
+
+	// A negative limit turns it off
+	router = newRateLimitRouter(config.RateLimit{Auth: config.RateLimitRule{Requests: -1}}, nil)
+	for i := 0; i <= middleware.DefaultAuthRateLimit.Requests; i++ {
+		require.Equal(t, http.StatusOK, doRateLimited(router, http.MethodPost, "/v1/share/common/auth/tokens/mfa", "192.0.2.1", "", "").Code)
+	}
+}
+
+func TestRateLimit_ForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	rule := config.RateLimit{Public: config.RateLimitRule{Requests: 1}}
+
+	// Without trusted proxies a client cannot pick its IP with X-Forwarded-For
+	router := newRateLimitRouterBehind(rule, nil, nil)
+	assert.Equal(t, http.StatusOK, doRateLimitedFor(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "198.51.100.1", "", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimitedFor(router, http.MethodGet, "/v1/public/info", "192.0.2.1", "198.51.100.2", "", "").Code)
+
+	// Behind a trusted proxy the forwarded client is counted
+	router = newRateLimitRouterBehind(rule, nil, []string{"10.0.0.0/8"})
+	assert.Equal(t, http.StatusOK, doRateLimitedFor(router, http.MethodGet, "/v1/public/info", "10.0.0.5", "198.51.100.1", "", "").Code)
+	assert.Equal(t, http.StatusOK, doRateLimitedFor(router, http.MethodGet, "/v1/public/info", "10.0.0.5", "198.51.100.2", "", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimitedFor(router, http.MethodGet, "/v1/public/info", "10.0.0.6", "198.51.100.1", "", "").Code)
+}
@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/controller"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenUseRouter(t *testing.T) (*gin.Engine, repository.CommonRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	commonRepo := repository.NewCommonRepository(conf, client)
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+
+	userRepo := &mock.MockUserRepository{}
+	common := controller.NewCommonControllerForPublic(userRepo, commonRepo, &mock.MockMFARepository{}, &mock.MockLoginLockoutRepository{}, repository.NewAuthenticatorChain(repository.NewLocalAuthenticator(userRepo, commonRepo)))
+
+	router := gin.New()
+	router.GET("/v1/share/common/auth/tokens/validate", common.ValidateToken)
+	api := router.Group("/v1/internal", middleware.ForInternal(commonRepo, enforcer))
+	api.GET("/users", middleware.CasbinAuthorization(enforcer, "users", "read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router, commonRepo
+}
+
+// tokenWithUse signs a user token with the given token_use claim
+func tokenWithUse(t *testing.T, commonRepo repository.CommonRepository, tokenUse string) string {
+	t.Helper()
+	now := time.Now().Unix()
+	token, err := commonRepo.GenerateJWTToken(model.JWTClaims{
+		Jti:       uuid.New().String(),
+		UserID:    7,
+		UUID:      "alice-uuid",
+		Email:     "alice@example.com",
+		Role:      "user",
+		TokenUse:  tokenUse,
+		IssuedAt:  now,
+		ExpiresAt: now + 300,
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestTokenUse_OnlyAccessTokensReachTheAPI(t *testing.T) {
+	router, commonRepo := newTokenUseRouter(t)
+
+	for _, tokenUse := range []string{model.TokenUseAccess, ""} {
+		token := tokenWithUse(t, commonRepo, tokenUse)
+		assert.Equal(t, http.StatusOK, doWithBearer(router, http.MethodGet, "/v1/internal/users", token), tokenUse)
+		assert.Equal(t, http.StatusOK, doWithBearer(router, http.MethodGet, "/v1/share/common/auth/tokens/validate", token), tokenUse)
+	}
+
+	// Known non-access uses and uses added later are refused alike
+	for _, tokenUse := range []string{model.TokenUseRefresh, model.TokenUseID, model.TokenUseEmailVerify, "mfa_challenge"} {
+		token := tokenWithUse(t, commonRepo, tokenUse)
+		assert.Equal(t, http.StatusUnauthorized, doWithBearer(router, http.MethodGet, "/v1/internal/users", token), tokenUse)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/share/common/auth/tokens/validate", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, tokenUse)
+		assert.Contains(t, w.Body.String(), "COMMON_VALIDATE_002")
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/casbin/casbin/v2"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectCasbinRules = "SELECT * FROM `casbin_rules` WHERE policy_set = ? ORDER BY id"
+
+var casbinRuleColumns = []string{"id", "policy_set", "ptype", "v0", "v1", "v2"}
+
+func TestCasbinRule_Values(t *testing.T) {
+	rule := model.NewCasbinRule(model.PolicySetApp, "p", []string{"admin", "users", "write"})
+	assert.Equal(t, "admin", rule.V0)
+	assert.Equal(t, "write", rule.V2)
+	assert.Equal(t, []string{"admin", "users", "write"}, rule.Rule())
+	assert.Empty(t, model.CasbinRules{}.Rule())
+}
+
+func TestCasbinAdapter_LoadAndAutoSave(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectCasbinRules)).
+		WithArgs(model.PolicySetApp).
+		WillReturnRows(sqlmock.NewRows(casbinRuleColumns).
+			AddRow(1, "locky", "p", "user", "groups", "read").
+			AddRow(2, "locky", "g", "operator", "user", ""))
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", repository.NewCasbinAdapter(th.BaseConfig, model.PolicySetApp))
+	require.NoError(t, err)
+	ok, err := enforcer.Enforce("user", "groups", "read")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, [][]string{{"operator", "user"}}, mustGroupingPolicy(t, enforcer))
+
+	// Changes are written line by line, not by rewriting the policy set
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("INSERT INTO `casbin_rules`")).
+		WithArgs("locky", "p", "auditor", "lockouts", "read", "", "", "").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	th.MockDB.ExpectCommit()
+	_, err = enforcer.AddPolicy("auditor", "lockouts", "read")
+	require.NoError(t, err)
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("DELETE FROM `casbin_rules` WHERE policy_set = ? AND ptype = ? AND v0 = ?")).
+		WithArgs("locky", "p", "auditor").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+	_, err = enforcer.RemoveFilteredPolicy(0, "auditor")
+	require.NoError(t, err)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestRoleRepository_UpdateRoleIsIncremental(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	c := newUserTestContext()
+
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectCasbinRules)).
+		WithArgs(model.PolicySetResources).
+		WillReturnRows(sqlmock.NewRows(casbinRuleColumns).
+			AddRow(1, "resources", "p", "maintainer", "group_info", "read").
+			AddRow(2, "resources", "p", "maintainer", "group_info", "write"))
+	resourceEnforcer, err := casbin.NewSyncedEnforcer("../../../../.etc/casbin/resources/model.conf", repository.NewCasbinAdapter(th.BaseConfig, model.PolicySetResources))
+	require.NoError(t, err)
+	roles := repository.NewRoleRepository(nil, resourceEnforcer)
+
+	// Only the dropped and the new permission touch the table
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("DELETE FROM `casbin_rules` WHERE policy_set = ? AND ptype = ? AND v0 = ? AND v1 = ? AND v2 = ?")).
+		WithArgs("resources", "p", "maintainer", "group_info", "write", "", "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("INSERT INTO `casbin_rules`")).
+		WithArgs("resources", "p", "maintainer", "member", "write", "", "", "").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	th.MockDB.ExpectCommit()
+	err = roles.UpdateRole(c, "maintainer", []repository.RolePermission{
+		{Resource: "group_info", Action: "read"},
+		{Resource: "member", Action: "write"},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+
+	perms, err := roles.GetRolePermissions(c, "maintainer")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []repository.RolePermission{{Resource: "group_info", Action: "read"}, {Resource: "member", Action: "write"}}, perms)
+}
+
+func mustGroupingPolicy(t *testing.T, e *casbin.SyncedEnforcer) [][]string {
+	t.Helper()
+	rules, err := e.GetGroupingPolicy()
+	require.NoError(t, err)
+	return rules
+}
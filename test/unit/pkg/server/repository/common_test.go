@@ -6,6 +6,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/ryo-arima/locky/pkg/config"
 	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCommonRepository_NewCommonRepository(t *testing.T) {
@@ -32,6 +33,7 @@ func createTestConfig() config.BaseConfig {
 			Application: config.Application{
 				Common: config.Common{},
 				Server: config.Server{
+					JWTSecret: "test-secret-key-for-jwt-that-is-long-enough",
 					Admin: config.Admin{
 						Emails: []string{"admin@test.com"},
 					},
@@ -253,14 +255,19 @@ func TestCommonRepository_TableDriven(t *testing.T) {
 // TestCommonRepository_EdgeCases tests edge cases and error scenarios
 func TestCommonRepository_EdgeCases(t *testing.T) {
 	tests := []struct {
-		name       string
-		baseConfig config.BaseConfig
-		expectNil  bool
+		name        string
+		baseConfig  config.BaseConfig
+		expectNil   bool
+		expectPanic bool
 	}{
 		{
 			name: "minimal config",
 			baseConfig: config.BaseConfig{
-				YamlConfig: config.YamlConfig{},
+				YamlConfig: config.YamlConfig{
+					Application: config.Application{
+						Server: config.Server{JWTSecret: "test-secret-key-for-jwt-that-is-long-enough"},
+					},
+				},
 			},
 			expectNil: false,
 		},
@@ -270,6 +277,7 @@ func TestCommonRepository_EdgeCases(t *testing.T) {
 				YamlConfig: config.YamlConfig{
 					Application: config.Application{
 						Server: config.Server{
+							JWTSecret: "test-secret-key-for-jwt-that-is-long-enough",
 							Admin: config.Admin{
 								Emails: []string{},
 							},
@@ -280,16 +288,21 @@ func TestCommonRepository_EdgeCases(t *testing.T) {
 			expectNil: false,
 		},
 		{
+			// Without jwt_secret there is nothing to sign HS256 tokens with
 			name: "nil yaml config",
 			baseConfig: config.BaseConfig{
 				DBConnection: nil,
 			},
-			expectNil: false,
+			expectPanic: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectPanic {
+				assert.Panics(t, func() { repository.NewCommonRepository(tt.baseConfig, nil) })
+				return
+			}
 			// Test
 			commonRepo := repository.NewCommonRepository(tt.baseConfig, nil)
 
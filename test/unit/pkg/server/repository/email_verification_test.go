@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEmailVerificationRepository(t *testing.T, client *redis.Client) repository.EmailVerificationRepository {
+	t.Helper()
+	conf := CreateTestConfig()
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.EmailVerification.VerifyURL = "https://app.test/verify-email?lang=en"
+	conf.YamlConfig.Application.Server.EmailVerification.ResendIntervalSeconds = 30
+	return repository.NewEmailVerificationRepository(conf, repository.NewCommonRepository(conf, client), client)
+}
+
+func TestEmailVerificationToken_RoundTrip(t *testing.T) {
+	repo := newEmailVerificationRepository(t, nil)
+
+	token, err := repo.CreateVerificationToken(model.Users{UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice"})
+	require.NoError(t, err)
+	claims, err := repo.ParseVerificationToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", claims.UUID)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.Equal(t, model.TokenUseEmailVerify, claims.TokenUse)
+	assert.InDelta(t, time.Now().Add(repository.DefaultEmailVerificationTokenTTL).Unix(), claims.ExpiresAt, 5)
+
+	_, err = repo.ParseVerificationToken(token + "x")
+	assert.ErrorIs(t, err, repository.ErrEmailVerificationTokenInvalid)
+}
+
+func TestEmailVerificationURL_KeepsQuery(t *testing.T) {
+	repo := newEmailVerificationRepository(t, nil)
+
+	u, err := url.Parse(repo.VerifyURL("abc+/="))
+	require.NoError(t, err)
+	assert.Equal(t, "app.test", u.Host)
+	assert.Equal(t, "en", u.Query().Get("lang"))
+	assert.Equal(t, "abc+/=", u.Query().Get("token"))
+}
+
+func TestEmailVerificationAllowResend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	repo := newEmailVerificationRepository(t, client)
+	ctx := context.Background()
+
+	ok, err := repo.AllowResend(ctx, "alice-uuid")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = repo.AllowResend(ctx, "alice-uuid")
+	assert.False(t, ok)
+	ok, _ = repo.AllowResend(ctx, "bob-uuid")
+	assert.True(t, ok)
+
+	mr.FastForward(31 * time.Second)
+	ok, _ = repo.AllowResend(ctx, "alice-uuid")
+	assert.True(t, ok)
+
+	// Without Redis resends are not throttled
+	ok, err = newEmailVerificationRepository(t, nil).AllowResend(ctx, "alice-uuid")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestEmailVerificationToken_OwnAudience(t *testing.T) {
+	conf := CreateTestConfig()
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.JWT.Audience = []string{"locky"}
+	common := repository.NewCommonRepository(conf, nil)
+	repo := repository.NewEmailVerificationRepository(conf, common, nil)
+
+	token, err := repo.CreateVerificationToken(model.Users{UUID: "alice-uuid", Email: "alice@example.com"})
+	require.NoError(t, err)
+	claims, err := repo.ParseVerificationToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, model.Audience{repository.EmailVerificationAudience}, claims.Audience)
+
+	// Services expecting the API audience refuse the link token
+	_, err = common.ValidateJWTToken(token)
+	assert.Error(t, err)
+
+	// and an access token cannot stand in for a link token
+	pair, err := common.GenerateTokenPair(7, "alice-uuid", "alice@example.com", "Alice", "user")
+	require.NoError(t, err)
+	_, err = repo.ParseVerificationToken(pair.AccessToken)
+	assert.ErrorIs(t, err, repository.ErrEmailVerificationTokenInvalid)
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFederationRepository(t *testing.T, issuer string, withRedis bool) (repository.FederationRepository, *miniredis.Miniredis) {
+	t.Helper()
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.Federation = config.Federation{
+		StateTTLSeconds: 60,
+		Providers: []config.UpstreamProvider{{
+			Name:         "upstream",
+			Issuer:       issuer,
+			ClientID:     "locky",
+			ClientSecret: "upstream-secret",
+			RedirectURL:  "https://locky.example.com/v1/share/common/federation/upstream/callback",
+		}},
+	}
+	if !withRedis {
+		return repository.NewFederationRepository(conf, nil), nil
+	}
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return repository.NewFederationRepository(conf, client), mr
+}
+
+func TestFederation_ProviderDefaults(t *testing.T) {
+	repo, _ := newFederationRepository(t, "https://idp.example.com", false)
+	provider, err := repo.GetProvider("upstream")
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", provider.DisplayName)
+	assert.Equal(t, repository.DefaultFederationScopes, provider.Scopes)
+
+	_, err = repo.GetProvider("")
+	assert.ErrorIs(t, err, repository.ErrFederationProviderNotFound)
+
+	// The flow state needs Redis
+	_, _, err = repo.AuthorizationURL(context.Background(), "upstream", "")
+	assert.ErrorIs(t, err, repository.ErrFederationStorageUnavailable)
+}
+
+func TestFederation_RejectsMismatchedIssuer(t *testing.T) {
+	provider := mock.NewMockOIDCProvider(t, "locky", "upstream-secret")
+	repo, _ := newFederationRepository(t, provider.Issuer+"/tenant", true)
+	_, _, err := repo.AuthorizationURL(context.Background(), "upstream", "")
+	assert.ErrorIs(t, err, repository.ErrFederationUpstream)
+}
+
+func TestFederation_StateExpires(t *testing.T) {
+	provider := mock.NewMockOIDCProvider(t, "locky", "upstream-secret")
+	repo, mr := newFederationRepository(t, provider.Issuer+"/", true)
+	ctx := context.Background()
+
+	authorizationURL, binding, err := repo.AuthorizationURL(ctx, "upstream", "alice-uuid")
+	require.NoError(t, err)
+	u, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	state := u.Query().Get("state")
+	assert.Equal(t, "https://locky.example.com/v1/share/common/federation/upstream/callback", u.Query().Get("redirect_uri"))
+
+	mr.FastForward(61 * time.Second)
+	_, _, err = repo.CompleteLogin(ctx, "upstream", state, provider.Authorize(t, authorizationURL, map[string]interface{}{"sub": "ext-alice"}), binding)
+	assert.ErrorIs(t, err, repository.ErrFederationInvalidState)
+
+	authorizationURL, binding, err = repo.AuthorizationURL(ctx, "upstream", "alice-uuid")
+	require.NoError(t, err)
+	u, _ = url.Parse(authorizationURL)
+	identity, login, err := repo.CompleteLogin(ctx, "upstream", u.Query().Get("state"), provider.Authorize(t, authorizationURL, map[string]interface{}{"sub": "ext-alice", "email_verified": "true", "email": "alice@example.com"}), binding)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", login.LinkUserUUID)
+	assert.Equal(t, "ext-alice", identity.Subject)
+	assert.True(t, identity.EmailVerified)
+}
+
+func TestFederation_StateBoundToBrowser(t *testing.T) {
+	provider := mock.NewMockOIDCProvider(t, "locky", "upstream-secret")
+	repo, _ := newFederationRepository(t, provider.Issuer+"/", true)
+	ctx := context.Background()
+
+	authorizationURL, binding, err := repo.AuthorizationURL(ctx, "upstream", "alice-uuid")
+	require.NoError(t, err)
+	require.NotEmpty(t, binding)
+	u, _ := url.Parse(authorizationURL)
+	state := u.Query().Get("state")
+	code := provider.Authorize(t, authorizationURL, map[string]interface{}{"sub": "ext-alice"})
+
+	// Another browser cannot redeem the state, and does not burn it either
+	_, _, err = repo.CompleteLogin(ctx, "upstream", state, code, "")
+	assert.ErrorIs(t, err, repository.ErrFederationInvalidState)
+	_, _, err = repo.CompleteLogin(ctx, "upstream", state, code, "other-browser")
+	assert.ErrorIs(t, err, repository.ErrFederationInvalidState)
+
+	_, login, err := repo.CompleteLogin(ctx, "upstream", state, code, binding)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", login.LinkUserUUID)
+	_, _, err = repo.CompleteLogin(ctx, "upstream", state, code, binding)
+	assert.ErrorIs(t, err, repository.ErrFederationInvalidState)
+}
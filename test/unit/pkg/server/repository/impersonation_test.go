@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newImpersonationRepository(impersonation config.Impersonation) (repository.ImpersonationRepository, repository.CommonRepository) {
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.Admin.Emails = []string{"admin@example.com"}
+	conf.YamlConfig.Application.Server.Impersonation = impersonation
+	common := repository.NewCommonRepository(conf, nil)
+	return repository.NewImpersonationRepository(conf, common), common
+}
+
+func TestImpersonation_ConfiguredRules(t *testing.T) {
+	repo, _ := newImpersonationRepository(config.Impersonation{
+		Enabled: true,
+		Rules: []config.ImpersonationRule{
+			{Actors: []string{"Support@Example.com"}, Targets: []string{"role:user"}},
+			{Actors: []string{"role:admin"}, Targets: []string{"*"}},
+		},
+	})
+	support := model.JWTClaims{UUID: "support-uuid", Email: "support@example.com", Role: "user"}
+	admin := model.JWTClaims{UUID: "admin-uuid", Email: "admin@example.com", Role: "admin"}
+	alice := model.Users{UUID: "alice-uuid", Email: "alice@example.com"}
+	adminUser := model.Users{UUID: "admin2-uuid", Email: "admin@example.com"}
+
+	assert.NoError(t, repo.CanImpersonate(support, alice))
+	assert.ErrorIs(t, repo.CanImpersonate(support, adminUser), repository.ErrImpersonationForbidden)
+	assert.NoError(t, repo.CanImpersonate(admin, adminUser))
+
+	// Service accounts, personal access tokens and impersonation tokens cannot impersonate
+	sa := admin
+	sa.ClientID = "sa-reporter"
+	pat := admin
+	pat.TokenUse = model.TokenUsePAT
+	nested := admin
+	nested.Actor = &model.Actor{Subject: "root-uuid"}
+	for _, actor := range []model.JWTClaims{sa, pat, nested} {
+		assert.ErrorIs(t, repo.CanImpersonate(actor, alice), repository.ErrImpersonationForbidden)
+	}
+}
+
+func TestImpersonation_IssueToken(t *testing.T) {
+	repo, common := newImpersonationRepository(config.Impersonation{Enabled: true})
+	admin := model.JWTClaims{UUID: "admin-uuid", Email: "admin@example.com", Role: "admin"}
+
+	token, expiresIn, err := repo.IssueToken(admin, model.Users{ID: 7, UUID: "alice-uuid", Email: "alice@example.com", Name: "Alice"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(repository.DefaultImpersonationTokenTTL.Seconds()), expiresIn)
+
+	claims, err := common.ValidateJWTToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, model.TokenUseAccess, claims.TokenUse)
+	assert.Equal(t, expiresIn, claims.ExpiresAt-claims.IssuedAt)
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, "admin-uuid", claims.Actor.Subject)
+
+	_, _, err = repo.IssueToken(admin, model.Users{UUID: "admin-uuid", Email: "admin@example.com"})
+	assert.ErrorIs(t, err, repository.ErrImpersonationForbidden)
+
+	disabled, _ := newImpersonationRepository(config.Impersonation{})
+	_, _, err = disabled.IssueToken(admin, model.Users{UUID: "alice-uuid", Email: "alice@example.com"})
+	assert.ErrorIs(t, err, repository.ErrImpersonationDisabled)
+}
@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJWTTestConfig(jwtConf config.JWT) config.BaseConfig {
+	cfg := CreateTestConfig()
+	cfg.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	cfg.YamlConfig.Application.Server.JWT = jwtConf
+	return cfg
+}
+
+func newTestClaims() model.JWTClaims {
+	now := time.Now().Unix()
+	return model.JWTClaims{
+		Jti:       "jti-1",
+		UserID:    1,
+		UUID:      "user-uuid",
+		Email:     "test@example.com",
+		Name:      "Test",
+		Role:      "user",
+		IssuedAt:  now,
+		ExpiresAt: now + 60,
+	}
+}
+
+func decodeTokenHeader(t *testing.T, token string) map[string]string {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	require.NoError(t, err)
+	header := map[string]string{}
+	require.NoError(t, json.Unmarshal(raw, &header))
+	return header
+}
+
+func TestJWTKeyring_SignVerifyPerAlgorithm(t *testing.T) {
+	tests := []struct {
+		alg     string
+		kty     string
+		jwksLen int
+	}{
+		{alg: "HS256", jwksLen: 0},
+		{alg: "RS256", kty: "RSA", jwksLen: 1},
+		{alg: "ES256", kty: "EC", jwksLen: 1},
+		{alg: "EdDSA", kty: "OKP", jwksLen: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			repo := repository.NewCommonRepository(newJWTTestConfig(config.JWT{Algorithm: tt.alg}), nil)
+
+			token, err := repo.GenerateJWTToken(newTestClaims())
+			require.NoError(t, err)
+
+			header := decodeTokenHeader(t, token)
+			assert.Equal(t, tt.alg, header["alg"])
+			assert.NotEmpty(t, header["kid"])
+
+			claims, err := repo.ValidateJWTToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-uuid", claims.UUID)
+
+			jwks := repo.GetJWKS()
+			require.Len(t, jwks.Keys, tt.jwksLen)
+			if tt.jwksLen > 0 {
+				assert.Equal(t, header["kid"], jwks.Keys[0].Kid)
+				assert.Equal(t, tt.kty, jwks.Keys[0].Kty)
+				assert.Equal(t, "sig", jwks.Keys[0].Use)
+			}
+
+			// Tampered payload must fail
+			parts := strings.Split(token, ".")
+			tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"uuid":"other","exp":9999999999}`)) + "." + parts[2]
+			_, err = repo.ValidateJWTToken(tampered)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJWTKeyring_LegacyTokenWithoutKid(t *testing.T) {
+	cfg := newJWTTestConfig(config.JWT{})
+	repo := repository.NewCommonRepository(cfg, nil)
+
+	claims, err := repo.ValidateJWTToken(legacyHS256Token(cfg.YamlConfig.Application.Server.JWTSecret))
+	require.NoError(t, err)
+	assert.Equal(t, "jti-1", claims.Jti)
+}
+
+// legacyHS256Token builds a kid-less token like the ones issued before kid was introduced
+func legacyHS256Token(secret string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payloadJSON, _ := json.Marshal(newTestClaims())
+	payload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func TestJWTKeyring_LegacyKeyAfterLeavingHS256(t *testing.T) {
+	secret := "unit-test-secret-with-at-least-32-chars"
+	token := legacyHS256Token(secret)
+
+	// The shared secret no longer verifies anything by default
+	repo := repository.NewCommonRepository(newJWTTestConfig(config.JWT{Algorithm: "RS256"}), nil)
+	_, err := repo.ValidateJWTToken(token)
+	assert.Error(t, err)
+
+	// The opt-in keeps it verifying until the deadline, but never signing
+	repo = repository.NewCommonRepository(newJWTTestConfig(config.JWT{
+		Algorithm:         "RS256",
+		AcceptLegacyHS256: true,
+		LegacyHS256Until:  time.Now().Add(time.Hour).Format(time.RFC3339),
+	}), nil)
+	_, err = repo.ValidateJWTToken(token)
+	assert.NoError(t, err)
+	issued, err := repo.GenerateJWTToken(newTestClaims())
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", decodeTokenHeader(t, issued)["alg"])
+
+	repo = repository.NewCommonRepository(newJWTTestConfig(config.JWT{
+		Algorithm:         "RS256",
+		AcceptLegacyHS256: true,
+		LegacyHS256Until:  time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}), nil)
+	_, err = repo.ValidateJWTToken(token)
+	assert.Error(t, err)
+
+	_, err = repository.NewJWTKeyring(config.JWT{Algorithm: "RS256", AcceptLegacyHS256: true}, secret, nil)
+	assert.Error(t, err)
+}
+
+func TestJWTKeyring_RefusesDefaultSecret(t *testing.T) {
+	for _, secret := range []string{"", repository.DefaultJWTSecret} {
+		_, err := repository.NewJWTKeyring(config.JWT{}, secret, nil)
+		assert.Error(t, err, secret)
+		_, err = repository.NewJWTKeyring(config.JWT{
+			Algorithm:         "ES256",
+			AcceptLegacyHS256: true,
+			LegacyHS256Until:  time.Now().Add(time.Hour).Format(time.RFC3339),
+		}, secret, nil)
+		assert.Error(t, err, secret)
+	}
+	// Asymmetric signing does not need the secret at all
+	_, err := repository.NewJWTKeyring(config.JWT{Algorithm: "ES256"}, "", nil)
+	assert.NoError(t, err)
+}
+
+func TestJWTKeyring_RejectsAlgorithmConfusion(t *testing.T) {
+	repo := repository.NewCommonRepository(newJWTTestConfig(config.JWT{Algorithm: "RS256"}), nil)
+	token, err := repo.GenerateJWTToken(newTestClaims())
+	require.NoError(t, err)
+
+	kid := decodeTokenHeader(t, token)["kid"]
+	parts := strings.Split(token, ".")
+	for _, alg := range []string{"none", "HS256"} {
+		forged := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `","typ":"JWT","kid":"` + kid + `"}`))
+		_, err := repo.ValidateJWTToken(forged + "." + parts[1] + "." + parts[2])
+		assert.Error(t, err, alg)
+	}
+}
+
+func TestJWTKeyring_RotateKeepsOldTokensValid(t *testing.T) {
+	keyring, err := repository.NewJWTKeyring(config.JWT{Algorithm: "ES256"}, "unit-test-secret-with-at-least-32-chars", nil)
+	require.NoError(t, err)
+
+	oldKid, alg, err := keyring.SigningKey()
+	require.NoError(t, err)
+	oldSig, err := keyring.Sign(oldKid, "header.payload")
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.Rotate(context.Background()))
+
+	newKid, _, err := keyring.SigningKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKid, newKid)
+
+	// Retired key still verifies and is still published
+	assert.NoError(t, keyring.Verify(oldKid, alg, "header.payload", oldSig))
+	assert.Len(t, keyring.JWKS().Keys, 2)
+}
+
+func TestJWTKeyring_MultipleRotations(t *testing.T) {
+	keyring, err := repository.NewJWTKeyring(config.JWT{Algorithm: "EdDSA", RetiredKeyTTLHours: 1}, "unit-test-secret-with-at-least-32-chars", nil)
+	require.NoError(t, err)
+
+	oldKid, _, _ := keyring.SigningKey()
+	require.NoError(t, keyring.Rotate(context.Background()))
+	require.NoError(t, keyring.Rotate(context.Background()))
+
+	// Both previous keys are retired but still within TTL
+	assert.Len(t, keyring.JWKS().Keys, 3)
+	sig, err := keyring.Sign(oldKid, "x")
+	require.NoError(t, err)
+	assert.NoError(t, keyring.Verify(oldKid, "EdDSA", "x", sig))
+}
+
+func TestJWTKeyring_StaticKeyFromFile(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "es256.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+
+	keyring, err := repository.NewJWTKeyring(config.JWT{
+		Algorithm: "ES256",
+		Keys: []config.JWTKey{
+			{Kid: "static-1", PrivateKeyFile: path},
+		},
+	}, "unit-test-secret-with-at-least-32-chars", nil)
+	require.NoError(t, err)
+
+	kid, alg, err := keyring.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "static-1", kid)
+	assert.Equal(t, "ES256", alg)
+
+	// Algorithm mismatch is rejected at load time
+	_, err = repository.NewJWTKeyring(config.JWT{
+		Algorithm: "RS256",
+		Keys:      []config.JWTKey{{Kid: "static-1", PrivateKeyFile: path}},
+	}, "unit-test-secret-with-at-least-32-chars", nil)
+	assert.Error(t, err)
+}
+
+func TestJWTKeyring_RotationLeavesConfiguredKeys(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "es256.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	// A static key and jwt_secret sign until the operator replaces them
+	for _, jwtConf := range []config.JWT{
+		{Algorithm: "ES256", RotationIntervalHours: 1, Keys: []config.JWTKey{{Kid: "static-1", PrivateKeyFile: path}}},
+		{RotationIntervalHours: 1},
+	} {
+		keyring, err := repository.NewJWTKeyring(jwtConf, "unit-test-secret-with-at-least-32-chars", client)
+		require.NoError(t, err)
+		kid, _, err := keyring.SigningKey()
+		require.NoError(t, err)
+
+		rotated, err := keyring.RotateIfDue(ctx)
+		require.NoError(t, err)
+		assert.False(t, rotated)
+		current, _, err := keyring.SigningKey()
+		require.NoError(t, err)
+		assert.Equal(t, kid, current)
+	}
+	assert.False(t, mr.Exists("auth:jwt:keyring"))
+
+	// Generated keys are rotated once they are older than the interval
+	keyring, err := repository.NewJWTKeyring(config.JWT{Algorithm: "ES256", RotationIntervalHours: 1}, "unit-test-secret-with-at-least-32-chars", client)
+	require.NoError(t, err)
+	kid, _, err := keyring.SigningKey()
+	require.NoError(t, err)
+	rotated, err := keyring.RotateIfDue(ctx)
+	require.NoError(t, err)
+	assert.False(t, rotated)
+
+	raw := mr.HGet("auth:jwt:keyring", kid)
+	stored := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &stored))
+	stored["created_at"] = time.Now().Add(-2 * time.Hour).Unix()
+	aged, err := json.Marshal(stored)
+	require.NoError(t, err)
+	mr.HSet("auth:jwt:keyring", kid, string(aged))
+
+	rotated, err = keyring.RotateIfDue(ctx)
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	current, _, err := keyring.SigningKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, kid, current)
+}
+
+func TestJWTKeyring_EncryptsStoredKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	kek := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	// Keys stored before the key encryption key was set are encrypted on startup
+	plain, err := repository.NewJWTKeyring(config.JWT{Algorithm: "ES256"}, "unit-test-secret-with-at-least-32-chars", client)
+	require.NoError(t, err)
+	kid, alg, err := plain.SigningKey()
+	require.NoError(t, err)
+	sig, err := plain.Sign(kid, "header.payload")
+	require.NoError(t, err)
+	assert.Contains(t, mr.HGet("auth:jwt:keyring", kid), "PRIVATE KEY")
+
+	sealed, err := repository.NewJWTKeyring(config.JWT{Algorithm: "ES256", KeyEncryptionKey: kek}, "unit-test-secret-with-at-least-32-chars", client)
+	require.NoError(t, err)
+	require.NoError(t, sealed.Rotate(context.Background()))
+	kids, err := mr.HKeys("auth:jwt:keyring")
+	require.NoError(t, err)
+	assert.Len(t, kids, 2)
+	for _, storedKid := range kids {
+		stored := mr.HGet("auth:jwt:keyring", storedKid)
+		assert.Contains(t, stored, `"sealed"`)
+		assert.NotContains(t, stored, "PRIVATE KEY")
+	}
+	assert.NoError(t, sealed.Verify(kid, alg, "header.payload", sig))
+
+	// Another instance with the same key encryption key reads them
+	other, err := repository.NewJWTKeyring(config.JWT{Algorithm: "ES256", KeyEncryptionKey: kek}, "unit-test-secret-with-at-least-32-chars", client)
+	require.NoError(t, err)
+	assert.NoError(t, other.Verify(kid, alg, "header.payload", sig))
+
+	_, err = repository.NewJWTKeyring(config.JWT{Algorithm: "ES256", KeyEncryptionKey: "c2hvcnQ="}, "unit-test-secret-with-at-least-32-chars", client)
+	assert.Error(t, err)
+}
+
+func TestValidateJWTToken_RegisteredClaims(t *testing.T) {
+	jwtConf := config.JWT{
+		Algorithm:        "RS256",
+		Issuer:           "https://locky.example.com",
+		Audience:         []string{"locky-api"},
+		ClockSkewSeconds: 30,
+	}
+	repo := repository.NewCommonRepository(newJWTTestConfig(jwtConf), nil)
+
+	t.Run("defaults iss and aud from config", func(t *testing.T) {
+		token, err := repo.GenerateJWTToken(newTestClaims())
+		require.NoError(t, err)
+		claims, err := repo.ValidateJWTToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "https://locky.example.com", claims.Issuer)
+		assert.Equal(t, model.Audience{"locky-api"}, claims.Audience)
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		claims := newTestClaims()
+		claims.Issuer = "https://evil.example.com"
+		token, _ := repo.GenerateJWTToken(claims)
+		_, err := repo.ValidateJWTToken(token)
+		assert.EqualError(t, err, "invalid token issuer")
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		claims := newTestClaims()
+		claims.Audience = model.Audience{"other-api"}
+		token, _ := repo.GenerateJWTToken(claims)
+		_, err := repo.ValidateJWTToken(token)
+		assert.EqualError(t, err, "invalid token audience")
+	})
+
+	t.Run("nbf within clock skew", func(t *testing.T) {
+		claims := newTestClaims()
+		claims.NotBefore = time.Now().Unix() + 10
+		token, _ := repo.GenerateJWTToken(claims)
+		_, err := repo.ValidateJWTToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("nbf beyond clock skew", func(t *testing.T) {
+		claims := newTestClaims()
+		claims.NotBefore = time.Now().Unix() + 120
+		token, _ := repo.GenerateJWTToken(claims)
+		_, err := repo.ValidateJWTToken(token)
+		assert.EqualError(t, err, "token not yet valid")
+	})
+
+	t.Run("expired within clock skew", func(t *testing.T) {
+		claims := newTestClaims()
+		claims.ExpiresAt = time.Now().Unix() - 10
+		token, _ := repo.GenerateJWTToken(claims)
+		_, err := repo.ValidateJWTToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("expired beyond clock skew", func(t *testing.T) {
+		claims := newTestClaims()
+		claims.ExpiresAt = time.Now().Unix() - 120
+		token, _ := repo.GenerateJWTToken(claims)
+		_, err := repo.ValidateJWTToken(token)
+		assert.EqualError(t, err, "token expired")
+	})
+}
+
+func TestAudience_JSON(t *testing.T) {
+	var single model.Audience
+	require.NoError(t, json.Unmarshal([]byte(`"a"`), &single))
+	assert.Equal(t, model.Audience{"a"}, single)
+
+	var multi model.Audience
+	require.NoError(t, json.Unmarshal([]byte(`["a","b"]`), &multi))
+	assert.Equal(t, model.Audience{"a", "b"}, multi)
+
+	out, _ := json.Marshal(model.Audience{"a"})
+	assert.Equal(t, `"a"`, string(out))
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jimlambrt/gldap"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	mock "github.com/ryo-arima/locky/test/unit/mock/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ldapServiceDN       = "cn=locky,ou=services,dc=example,dc=org"
+	ldapServicePassword = "service-secret"
+	ldapAdminsGroup     = "cn=locky-admins,ou=groups,dc=example,dc=org"
+)
+
+// ldapTestUser is a person entry of the in-process directory
+type ldapTestUser struct {
+	DN       string
+	Mail     string
+	Name     string
+	Password string
+	MemberOf []string
+}
+
+// startLDAPServer runs an in-process directory that supports simple binds and
+// (mail=...) searches, and returns its ldap:// URL
+func startLDAPServer(t *testing.T, users ...ldapTestUser) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	server, err := gldap.NewServer()
+	require.NoError(t, err)
+	mux, err := gldap.NewMux()
+	require.NoError(t, err)
+
+	require.NoError(t, mux.Bind(func(w *gldap.ResponseWriter, r *gldap.Request) {
+		resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+		defer func() { _ = w.Write(resp) }()
+		m, err := r.GetSimpleBindMessage()
+		if err != nil {
+			return
+		}
+		if m.UserName == ldapServiceDN && string(m.Password) == ldapServicePassword {
+			resp.SetResultCode(gldap.ResultSuccess)
+			return
+		}
+		for _, user := range users {
+			if m.UserName == user.DN && string(m.Password) == user.Password {
+				resp.SetResultCode(gldap.ResultSuccess)
+				return
+			}
+		}
+	}))
+	require.NoError(t, mux.Search(func(w *gldap.ResponseWriter, r *gldap.Request) {
+		done := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+		defer func() { _ = w.Write(done) }()
+		m, err := r.GetSearchMessage()
+		if err != nil {
+			done.SetResultCode(gldap.ResultOperationsError)
+			return
+		}
+		for _, user := range users {
+			if !strings.Contains(m.Filter, "(mail="+ldap.EscapeFilter(user.Mail)+")") {
+				continue
+			}
+			entry := r.NewSearchResponseEntry(user.DN)
+			entry.AddAttribute("mail", []string{user.Mail})
+			entry.AddAttribute("cn", []string{user.Name})
+			entry.AddAttribute("memberOf", user.MemberOf)
+			_ = w.Write(entry)
+		}
+	}))
+	require.NoError(t, server.Router(mux))
+
+	go func() { _ = server.Run(addr) }()
+	t.Cleanup(func() { _ = server.Stop() })
+	require.Eventually(t, server.Ready, 5*time.Second, 10*time.Millisecond)
+	return fmt.Sprintf("ldap://%s", addr)
+}
+
+func newLDAPConfig(url string) config.BaseConfig {
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.Authenticators = []string{repository.AuthenticatorLDAP, repository.AuthenticatorLocal}
+	conf.YamlConfig.Application.Server.LDAP = config.LDAP{
+		URL:            url,
+		BindDN:         ldapServiceDN,
+		BindPassword:   ldapServicePassword,
+		BaseDN:         "ou=people,dc=example,dc=org",
+		GroupRoles:     []config.LDAPGroupRole{{Group: ldapAdminsGroup, Role: "admin"}},
+		TimeoutSeconds: 2,
+	}
+	return conf
+}
+
+func newLDAPTestContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+func TestLDAPAuthenticator_ProvisionsUserOnFirstLogin(t *testing.T) {
+	url := startLDAPServer(t,
+		ldapTestUser{DN: "uid=alice,ou=people,dc=example,dc=org", Mail: "alice@example.com", Name: "Alice", Password: "alice-pass", MemberOf: []string{"CN=Locky-Admins,OU=Groups,DC=example,DC=org"}},
+		ldapTestUser{DN: "uid=bob,ou=people,dc=example,dc=org", Mail: "bob@example.com", Name: "Bob", Password: "bob-pass"},
+	)
+	conf := newLDAPConfig(url)
+	userRepo := &mock.MockUserRepository{}
+	authenticator := repository.NewLDAPAuthenticator(conf, userRepo, repository.NewCommonRepository(conf, nil))
+	c := newLDAPTestContext()
+
+	user, err := authenticator.Authenticate(c, "alice@example.com", "alice-pass")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.Name)
+	assert.Equal(t, repository.AuthenticatorLDAP, user.AuthSource)
+	assert.Equal(t, "admin", user.DirectoryRole, "group match is case-insensitive")
+	assert.NotEmpty(t, user.UUID)
+	assert.NotNil(t, user.EmailVerifiedAt)
+	require.Len(t, userRepo.Users, 1)
+
+	// The second login reuses the provisioned row
+	again, err := authenticator.Authenticate(c, "alice@example.com", "alice-pass")
+	require.NoError(t, err)
+	assert.Equal(t, user.UUID, again.UUID)
+	assert.Len(t, userRepo.Users, 1)
+
+	bob, err := authenticator.Authenticate(c, "bob@example.com", "bob-pass")
+	require.NoError(t, err)
+	assert.Empty(t, bob.DirectoryRole)
+	assert.Equal(t, "user", repository.UserRole(conf, *bob))
+
+	_, err = authenticator.Authenticate(c, "alice@example.com", "wrong")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+	_, err = authenticator.Authenticate(c, "alice@example.com", "")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+	_, err = authenticator.Authenticate(c, "nobody@example.com", "whatever")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorSkipped)
+}
+
+func TestAuthenticatorChain_LDAPThenLocal(t *testing.T) {
+	url := startLDAPServer(t,
+		ldapTestUser{DN: "uid=alice,ou=people,dc=example,dc=org", Mail: "alice@example.com", Name: "Alice", Password: "alice-pass"},
+	)
+	conf := newLDAPConfig(url)
+	common := repository.NewCommonRepository(conf, nil)
+	hash, err := common.HashPassword("carol-pass")
+	require.NoError(t, err)
+	userRepo := &mock.MockUserRepository{Users: []model.Users{{ID: 1, UUID: "carol-uuid", Email: "carol@example.com", Password: hash}}}
+	chain, err := repository.NewConfiguredAuthenticatorChain(conf, userRepo, common)
+	require.NoError(t, err)
+	c := newLDAPTestContext()
+
+	user, err := chain.Authenticate(c, "alice@example.com", "alice-pass")
+	require.NoError(t, err)
+	assert.Equal(t, repository.AuthenticatorLDAP, user.AuthSource)
+
+	// Users unknown to the directory fall through to the local password check
+	user, err = chain.Authenticate(c, "carol@example.com", "carol-pass")
+	require.NoError(t, err)
+	assert.Equal(t, "carol-uuid", user.UUID)
+	_, err = chain.Authenticate(c, "carol@example.com", "wrong")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+	_, err = chain.Authenticate(c, "nobody@example.com", "whatever")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorSkipped)
+
+	// Directory users cannot log in with the local password of their row
+	_, err = repository.NewLocalAuthenticator(userRepo, common).Authenticate(c, "alice@example.com", "alice-pass")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorSkipped)
+
+	// Deactivated users are refused once the password is verified
+	now := time.Now()
+	userRepo.Users[0].DeletedAt = &now
+	_, err = chain.Authenticate(c, "carol@example.com", "carol-pass")
+	assert.ErrorIs(t, err, repository.ErrAccountDisabled)
+	_, err = chain.Authenticate(c, "carol@example.com", "wrong")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+}
+
+func TestAuthenticatorChain_DirectoryUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	conf := newLDAPConfig("ldap://" + listener.Addr().String())
+	require.NoError(t, listener.Close())
+
+	common := repository.NewCommonRepository(conf, nil)
+	hash, err := common.HashPassword("carol-pass")
+	require.NoError(t, err)
+	userRepo := &mock.MockUserRepository{Users: []model.Users{{ID: 1, UUID: "carol-uuid", Email: "carol@example.com", Password: hash}}}
+	chain, err := repository.NewConfiguredAuthenticatorChain(conf, userRepo, common)
+	require.NoError(t, err)
+	c := newLDAPTestContext()
+
+	// Local users can still log in while the directory is down
+	user, err := chain.Authenticate(c, "carol@example.com", "carol-pass")
+	require.NoError(t, err)
+	assert.Equal(t, "carol-uuid", user.UUID)
+
+	_, err = chain.Authenticate(c, "alice@example.com", "alice-pass")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorUnavailable)
+
+	conf.YamlConfig.Application.Server.Authenticators = []string{"kerberos"}
+	_, err = repository.NewConfiguredAuthenticatorChain(conf, userRepo, common)
+	assert.Error(t, err)
+}
+
+func TestLDAPAuthenticator_DoesNotAdoptOtherUsers(t *testing.T) {
+	url := startLDAPServer(t,
+		ldapTestUser{DN: "uid=carol,ou=people,dc=example,dc=org", Mail: "carol@example.com", Name: "Carol", Password: "directory-pass"},
+		ldapTestUser{DN: "uid=dave,ou=people,dc=example,dc=org", Mail: "dave@example.com", Name: "Dave", Password: "directory-pass"},
+	)
+	conf := newLDAPConfig(url)
+	common := repository.NewCommonRepository(conf, nil)
+	hash, err := common.HashPassword("carol-pass")
+	require.NoError(t, err)
+	userRepo := &mock.MockUserRepository{Users: []model.Users{
+		{ID: 1, UUID: "carol-uuid", Email: "carol@example.com", Name: "Carol", Password: hash, AuthSource: repository.AuthenticatorLocal},
+		{ID: 2, UUID: "dave-uuid", Email: "dave@example.com", Name: "Dave", AuthSource: repository.FederatedAuthSource("github")},
+	}}
+	chain, err := repository.NewConfiguredAuthenticatorChain(conf, userRepo, common)
+	require.NoError(t, err)
+	c := newLDAPTestContext()
+
+	// A directory entry with the same email does not take over existing rows
+	_, err = repository.NewLDAPAuthenticator(conf, userRepo, common).Authenticate(c, "carol@example.com", "directory-pass")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorSkipped)
+	_, err = chain.Authenticate(c, "carol@example.com", "directory-pass")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+	user, err := chain.Authenticate(c, "carol@example.com", "carol-pass")
+	require.NoError(t, err)
+	assert.Equal(t, repository.AuthenticatorLocal, user.AuthSource)
+	_, err = chain.Authenticate(c, "dave@example.com", "directory-pass")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorSkipped)
+	assert.Equal(t, repository.AuthenticatorLocal, userRepo.Users[0].AuthSource)
+
+	// Migrations adopt local users, but never federated ones
+	conf.YamlConfig.Application.Server.LDAP.AdoptLocalUsers = true
+	chain, err = repository.NewConfiguredAuthenticatorChain(conf, userRepo, common)
+	require.NoError(t, err)
+	user, err = chain.Authenticate(c, "carol@example.com", "directory-pass")
+	require.NoError(t, err)
+	assert.Equal(t, "carol-uuid", user.UUID)
+	assert.Equal(t, repository.AuthenticatorLDAP, user.AuthSource)
+	assert.Equal(t, repository.AuthenticatorLDAP, userRepo.Users[0].AuthSource)
+	_, err = chain.Authenticate(c, "carol@example.com", "carol-pass")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+	_, err = chain.Authenticate(c, "dave@example.com", "directory-pass")
+	assert.ErrorIs(t, err, repository.ErrAuthenticatorSkipped)
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoginLockoutRepository(t *testing.T, lockout config.LoginLockout) (repository.LoginLockoutRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	conf := CreateTestConfig()
+	conf.YamlConfig.Application.Server.LoginLockout = lockout
+	return repository.NewLoginLockoutRepository(conf, client), mr
+}
+
+func TestLoginLockout_BackoffDoublesPerFailure(t *testing.T) {
+	repo, _ := newLoginLockoutRepository(t, config.LoginLockout{BackoffSeconds: 2, MaxAttempts: 10})
+	ctx := context.Background()
+
+	lockout, err := repo.RegisterFailure(ctx, "alice@example.com", "192.0.2.1")
+	require.NoError(t, err)
+	assert.Nil(t, lockout, "the first failure is free")
+
+	for i, want := range []int64{2, 4, 8} {
+		lockout, err = repo.RegisterFailure(ctx, "alice@example.com", "192.0.2.1")
+		require.NoError(t, err)
+		require.NotNil(t, lockout)
+		assert.Equal(t, model.LoginLockoutReasonBackoff, lockout.Reason)
+		assert.Equal(t, i+2, lockout.Failures)
+		assert.InDelta(t, time.Now().Unix()+want, lockout.LockedUntil, 1)
+	}
+
+	active, err := repo.Check(ctx, "Alice@Example.com", "198.51.100.7")
+	require.NoError(t, err)
+	require.NotNil(t, active, "accounts are matched case-insensitively")
+	assert.Equal(t, model.LoginLockoutScopeAccount, active.Scope)
+	assert.Equal(t, "alice@example.com", active.Key)
+}
+
+func TestLoginLockout_ProgressiveLockout(t *testing.T) {
+	repo, mr := newLoginLockoutRepository(t, config.LoginLockout{BackoffSeconds: -1, MaxAttempts: 3, LockoutSeconds: 60, MaxLockoutSeconds: 200})
+	ctx := context.Background()
+
+	for round, want := range []int64{60, 120, 200} {
+		var lockout *model.LoginLockouts
+		for i := 0; i < 3; i++ {
+			var err error
+			lockout, err = repo.RegisterFailure(ctx, "alice@example.com", "")
+			require.NoError(t, err)
+			if i < 2 {
+				assert.Nil(t, lockout, "backoff is disabled")
+			}
+		}
+		require.NotNil(t, lockout)
+		assert.Equal(t, model.LoginLockoutReasonLockout, lockout.Reason)
+		assert.Equal(t, round+1, lockout.Level)
+		assert.InDelta(t, time.Now().Unix()+want, lockout.LockedUntil, 1)
+
+		active, err := repo.Check(ctx, "alice@example.com", "")
+		require.NoError(t, err)
+		assert.NotNil(t, active)
+		mr.FastForward(time.Duration(want+1) * time.Second)
+		active, err = repo.Check(ctx, "alice@example.com", "")
+		require.NoError(t, err)
+		assert.Nil(t, active, "lockouts expire")
+	}
+
+	// A successful login resets the level
+	require.NoError(t, repo.RegisterSuccess(ctx, "alice@example.com"))
+	for i := 0; i < 3; i++ {
+		_, _ = repo.RegisterFailure(ctx, "alice@example.com", "")
+	}
+	active, err := repo.Check(ctx, "alice@example.com", "")
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, 1, active.Level)
+}
+
+func TestLoginLockout_IPThreshold(t *testing.T) {
+	repo, _ := newLoginLockoutRepository(t, config.LoginLockout{IPMaxAttempts: 4, MaxAttempts: 100, BackoffSeconds: -1})
+	ctx := context.Background()
+
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"}
+	var lockout *model.LoginLockouts
+	for _, email := range emails {
+		var err error
+		lockout, err = repo.RegisterFailure(ctx, email, "192.0.2.1")
+		require.NoError(t, err)
+	}
+	require.NotNil(t, lockout)
+	assert.Equal(t, model.LoginLockoutScopeIP, lockout.Scope)
+	assert.Equal(t, "192.0.2.1", lockout.Key)
+
+	active, err := repo.Check(ctx, "someone-else@example.com", "192.0.2.1")
+	require.NoError(t, err)
+	assert.NotNil(t, active)
+	active, err = repo.Check(ctx, "someone-else@example.com", "192.0.2.2")
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	// Success on one account does not reset the IP
+	require.NoError(t, repo.RegisterSuccess(ctx, "a@example.com"))
+	active, _ = repo.Check(ctx, "", "192.0.2.1")
+	assert.NotNil(t, active)
+}
+
+func TestLoginLockout_ListAndClear(t *testing.T) {
+	repo, _ := newLoginLockoutRepository(t, config.LoginLockout{MaxAttempts: 1, IPMaxAttempts: 1})
+	ctx := context.Background()
+
+	_, err := repo.RegisterFailure(ctx, "alice@example.com", "192.0.2.1")
+	require.NoError(t, err)
+
+	all, err := repo.ListLockouts(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	accounts, err := repo.ListLockouts(ctx, model.LoginLockoutScopeAccount)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "alice@example.com", accounts[0].Key)
+
+	cleared, err := repo.ClearLockout(ctx, model.LoginLockoutScopeAccount, "ALICE@example.com")
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	cleared, err = repo.ClearLockout(ctx, model.LoginLockoutScopeAccount, "alice@example.com")
+	require.NoError(t, err)
+	assert.False(t, cleared)
+
+	remaining, err := repo.ListLockouts(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, model.LoginLockoutScopeIP, remaining[0].Scope)
+}
+
+func TestLoginLockout_DisabledOrWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	disabled, _ := newLoginLockoutRepository(t, config.LoginLockout{Disabled: true, MaxAttempts: 1})
+	withoutRedis := repository.NewLoginLockoutRepository(CreateTestConfig(), nil)
+
+	for _, repo := range []repository.LoginLockoutRepository{disabled, withoutRedis} {
+		for i := 0; i < 5; i++ {
+			lockout, err := repo.RegisterFailure(ctx, "alice@example.com", "192.0.2.1")
+			require.NoError(t, err)
+			assert.Nil(t, lockout)
+		}
+		active, err := repo.Check(ctx, "alice@example.com", "192.0.2.1")
+		require.NoError(t, err)
+		assert.Nil(t, active)
+	}
+}
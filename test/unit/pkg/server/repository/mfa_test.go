@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 appendix B secret ("12345678901234567890") in base32
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; the 6-digit code is the last 6 digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		code, err := repository.TOTPCode(testTOTPSecret, repository.TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "time %d", unix)
+	}
+}
+
+func TestMatchTOTP_Skew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := repository.TOTPStep(now)
+
+	previous, _ := repository.TOTPCode(testTOTPSecret, step-1)
+	got, ok := repository.MatchTOTP(testTOTPSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, got)
+
+	tooOld, _ := repository.TOTPCode(testTOTPSecret, step-2)
+	_, ok = repository.MatchTOTP(testTOTPSecret, tooOld, now)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := repository.TOTPProvisioningURI("Locky", "alice@example.com", testTOTPSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Locky:alice@example.com?"))
+	assert.Contains(t, uri, "secret="+testTOTPSecret)
+	assert.Contains(t, uri, "issuer=Locky")
+}
+
+func newMFATestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	return c
+}
+
+func expectEnabledMFA(th *TestHelper, lastUsedStep int64) {
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user_mfas` WHERE user_uuid = ?")).
+		WithArgs("user-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "secret", "enabled", "last_used_step"}).
+			AddRow(1, "user-uuid", testTOTPSecret, true, lastUsedStep))
+}
+
+func TestMFARepository_VerifyRejectsReplayedStep(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewMFARepository(th.BaseConfig, nil)
+	code, _ := repository.TOTPCode(testTOTPSecret, repository.TOTPStep(time.Now()))
+
+	// The conditional update matches no row when the step was already used
+	expectEnabledMFA(th, repository.TOTPStep(time.Now())+1)
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("UPDATE `user_mfas` SET `last_used_step`=.*WHERE id = .* AND last_used_step < ").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	th.MockDB.ExpectCommit()
+
+	err := repo.Verify(newMFATestContext(), "user-uuid", code)
+	assert.ErrorIs(t, err, repository.ErrMFAInvalidCode)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestMFARepository_VerifyRecoveryCode(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewMFARepository(th.BaseConfig, nil)
+
+	expectEnabledMFA(th, 0)
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("UPDATE `mfa_recovery_codes` SET `used_at`=.*WHERE user_uuid = .* AND code_hash = .* AND used_at IS NULL").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+
+	err := repo.Verify(newMFATestContext(), "user-uuid", "abcd-efgh-ijkl-mnop")
+	assert.NoError(t, err)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestMFARepository_ChallengeIsSingleUse(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	repo := repository.NewMFARepository(th.BaseConfig, client)
+	c := newMFATestContext()
+
+	challenge, err := repo.CreateChallenge(c.Request.Context(), "user-uuid", "user@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(mr.Keys()))
+	assert.False(t, strings.Contains(mr.Keys()[0], challenge), "challenge must not be stored in clear")
+
+	code, _ := repository.TOTPCode(testTOTPSecret, repository.TOTPStep(time.Now()))
+	expectEnabledMFA(th, 0)
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("UPDATE `user_mfas` SET `last_used_step`=").WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+
+	userUUID, err := repo.CompleteChallenge(c, challenge, code)
+	require.NoError(t, err)
+	assert.Equal(t, "user-uuid", userUUID)
+
+	_, err = repo.CompleteChallenge(c, challenge, code)
+	assert.ErrorIs(t, err, repository.ErrMFAChallengeInvalid)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestMFARepository_ChallengeDroppedAfterTooManyAttempts(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	repo := repository.NewMFARepository(th.BaseConfig, client)
+	c := newMFATestContext()
+
+	challenge, err := repo.CreateChallenge(c.Request.Context(), "user-uuid", "user@example.com")
+	require.NoError(t, err)
+
+	for i := 0; i < repository.MFAChallengeMaxAttempts; i++ {
+		expectEnabledMFA(th, 0)
+		th.MockDB.ExpectBegin()
+		th.MockDB.ExpectExec("UPDATE `mfa_recovery_codes` SET `used_at`=").WillReturnResult(sqlmock.NewResult(0, 0))
+		th.MockDB.ExpectCommit()
+		_, err = repo.CompleteChallenge(c, challenge, "wrong")
+		assert.ErrorIs(t, err, repository.ErrMFAInvalidCode)
+	}
+	assert.Empty(t, mr.Keys())
+
+	_, err = repo.CompleteChallenge(c, challenge, "wrong")
+	assert.ErrorIs(t, err, repository.ErrMFAChallengeInvalid)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestMFARepository_ChallengeCountsAttemptBeforeVerifying(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	repo := repository.NewMFARepository(th.BaseConfig, client)
+	c := newMFATestContext()
+
+	challenge, err := repo.CreateChallenge(c.Request.Context(), "user-uuid", "user@example.com")
+	require.NoError(t, err)
+	email, err := repo.ChallengeEmail(c.Request.Context(), challenge)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", email)
+
+	// Attempts still in flight count as well: with the budget used up, even
+	// the right code is refused without reaching the database
+	key := mr.Keys()[0]
+	mr.HSet(key, "attempts", strconv.Itoa(repository.MFAChallengeMaxAttempts))
+	code, _ := repository.TOTPCode(testTOTPSecret, repository.TOTPStep(time.Now()))
+	_, err = repo.CompleteChallenge(c, challenge, code)
+	assert.ErrorIs(t, err, repository.ErrMFAChallengeInvalid)
+	assert.Empty(t, mr.Keys())
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+
+	// Unknown challenges leave nothing behind
+	_, err = repo.CompleteChallenge(c, "unknown", code)
+	assert.ErrorIs(t, err, repository.ErrMFAChallengeInvalid)
+	assert.Empty(t, mr.Keys())
+	_, err = repo.ChallengeEmail(c.Request.Context(), "unknown")
+	assert.ErrorIs(t, err, repository.ErrMFAChallengeInvalid)
+}
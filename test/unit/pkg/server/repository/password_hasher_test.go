@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Small argon2id parameters keep the tests fast
+var testArgon2id = config.Argon2id{MemoryKiB: 1024, Iterations: 1, Parallelism: 1}
+
+func TestPasswordHasher_Argon2idByDefault(t *testing.T) {
+	hasher := repository.NewPasswordHasher(config.PasswordHashing{Argon2id: testArgon2id})
+
+	hash, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+	assert.Len(t, strings.Split(hash, "$"), 6)
+
+	other, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "every hash gets its own salt")
+
+	assert.NoError(t, hasher.Verify(hash, "Password123!"))
+	assert.ErrorIs(t, hasher.Verify(hash, "password123!"), repository.ErrPasswordMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+}
+
+func TestPasswordHasher_Bcrypt(t *testing.T) {
+	hasher := repository.NewPasswordHasher(config.PasswordHashing{Algorithm: "bcrypt", BcryptCost: bcrypt.MinCost + 1})
+
+	hash, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$05$"), hash)
+	assert.NoError(t, hasher.Verify(hash, "Password123!"))
+	assert.ErrorIs(t, hasher.Verify(hash, "wrong"), repository.ErrPasswordMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	weaker, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	assert.True(t, hasher.NeedsRehash(string(weaker)), "lower cost")
+
+	argonHash, err := repository.NewPasswordHasher(config.PasswordHashing{Argon2id: testArgon2id}).Hash("Password123!")
+	require.NoError(t, err)
+	assert.NoError(t, hasher.Verify(argonHash, "Password123!"), "other algorithms keep verifying")
+	assert.True(t, hasher.NeedsRehash(argonHash))
+}
+
+func TestPasswordHasher_RehashOutdatedHashes(t *testing.T) {
+	hasher := repository.NewPasswordHasher(config.PasswordHashing{Argon2id: config.Argon2id{MemoryKiB: 2048, Iterations: 2, Parallelism: 1}})
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	assert.NoError(t, hasher.Verify(string(legacy), "Password123!"))
+	assert.True(t, hasher.NeedsRehash(string(legacy)), "bcrypt to argon2id")
+
+	old, err := repository.NewPasswordHasher(config.PasswordHashing{Argon2id: testArgon2id}).Hash("Password123!")
+	require.NoError(t, err)
+	assert.NoError(t, hasher.Verify(old, "Password123!"), "stored parameters are used for verification")
+	assert.True(t, hasher.NeedsRehash(old), "weaker argon2id parameters")
+
+	current, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.False(t, hasher.NeedsRehash(current))
+}
+
+func TestPasswordHasher_RejectsUnknownHashes(t *testing.T) {
+	hasher := repository.NewPasswordHasher(config.PasswordHashing{Argon2id: testArgon2id})
+
+	for _, hash := range []string{
+		"",
+		"Password123!",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$2a$10$tooshort",
+	} {
+		assert.ErrorIs(t, hasher.Verify(hash, "Password123!"), repository.ErrPasswordHashUnsupported, hash)
+		assert.True(t, hasher.NeedsRehash(hash), hash)
+	}
+}
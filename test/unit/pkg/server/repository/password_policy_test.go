@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func violationCodes(violations []repository.PasswordPolicyViolation) []string {
+	codes := []string{}
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestCheckPasswordRules_Defaults(t *testing.T) {
+	policy := config.PasswordPolicy{}
+
+	assert.Empty(t, repository.CheckPasswordRules(policy, "Passw0rd!"))
+	assert.Equal(t, []string{
+		repository.PasswordTooShort,
+		repository.PasswordMissingUppercase,
+		repository.PasswordMissingNumber,
+		repository.PasswordMissingSpecial,
+	}, violationCodes(repository.CheckPasswordRules(policy, "abc")))
+	assert.Equal(t, []string{repository.PasswordTooLong}, violationCodes(repository.CheckPasswordRules(policy, "Aa1!"+strings.Repeat("x", 125))))
+}
+
+func TestCheckPasswordRules_Configured(t *testing.T) {
+	policy := config.PasswordPolicy{MinLength: 12, MaxLength: 16, CharacterClasses: []string{"number"}}
+	assert.Empty(t, repository.CheckPasswordRules(policy, "correcthorse1"))
+	assert.Equal(t, []string{repository.PasswordTooShort}, violationCodes(repository.CheckPasswordRules(policy, "short1")))
+	assert.Equal(t, []string{repository.PasswordMissingNumber}, violationCodes(repository.CheckPasswordRules(policy, "correcthorsebat")))
+
+	// An explicitly empty list disables the character class rules
+	policy.CharacterClasses = []string{}
+	assert.Empty(t, repository.CheckPasswordRules(policy, "correcthorsebat"))
+}
+
+func TestCheckPasswordRules_BreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("Passw0rd!"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	content := "0000000000000000000000000000000000A:3\r\n" + hash[5:] + ":42\r\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0o600))
+
+	policy := config.PasswordPolicy{BreachedPasswordsDir: dir}
+	assert.Equal(t, []string{repository.PasswordBreached}, violationCodes(repository.CheckPasswordRules(policy, "Passw0rd!")))
+	assert.Empty(t, repository.CheckPasswordRules(policy, "N3w-Passw0rd!"))
+
+	// Passwords seen fewer times than breached_min_count are allowed
+	policy.BreachedMinCount = 100
+	assert.Empty(t, repository.CheckPasswordRules(policy, "Passw0rd!"))
+}
+
+func TestPasswordExpired(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-100 * 24 * time.Hour)
+	changed := now.Add(-10 * 24 * time.Hour)
+	user := model.Users{CreatedAt: &created}
+
+	assert.False(t, repository.PasswordExpired(config.PasswordPolicy{}, user, now))
+	assert.True(t, repository.PasswordExpired(config.PasswordPolicy{MaxAgeDays: 90}, user, now))
+
+	user.PasswordChangedAt = &changed
+	assert.False(t, repository.PasswordExpired(config.PasswordPolicy{MaxAgeDays: 90}, user, now))
+}
+
+func TestPasswordPolicyRepository_RejectsRecentPasswords(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	th.BaseConfig.YamlConfig.Application.Server.PasswordPolicy.HistorySize = 3
+	common := repository.NewCommonRepository(th.BaseConfig, nil)
+	repo := repository.NewPasswordPolicyRepository(th.BaseConfig, common)
+
+	current, err := common.HashPassword("Curr3nt-Passw0rd!")
+	require.NoError(t, err)
+	previous, err := common.HashPassword("0ld-Passw0rd!")
+	require.NoError(t, err)
+	expectHistory := func() {
+		th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE uuid = ? AND deleted_at IS NULL")).
+			WithArgs("user-uuid", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "password"}).AddRow(1, "user-uuid", current))
+		th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `password_histories` WHERE user_uuid = ? ORDER BY id DESC")).
+			WithArgs("user-uuid", 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "password_hash"}).
+				AddRow(2, "user-uuid", current).
+				AddRow(1, "user-uuid", previous))
+	}
+
+	for _, password := range []string{"Curr3nt-Passw0rd!", "0ld-Passw0rd!"} {
+		expectHistory()
+		violations, err := repo.Validate(newMFATestContext(), password, "user-uuid")
+		require.NoError(t, err)
+		assert.Equal(t, []string{repository.PasswordReused}, violationCodes(violations), password)
+	}
+
+	expectHistory()
+	violations, err := repo.Validate(newMFATestContext(), "N3w-Passw0rd!", "user-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	// New users have no history to check
+	violations, err = repo.Validate(newMFATestContext(), "N3w-Passw0rd!", "")
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestPasswordPolicyRepository_RecordPasswordTrimsHistory(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	th.BaseConfig.YamlConfig.Application.Server.PasswordPolicy.HistorySize = 2
+	repo := repository.NewPasswordPolicyRepository(th.BaseConfig, repository.NewCommonRepository(th.BaseConfig, nil))
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("INSERT INTO `password_histories`").WillReturnResult(sqlmock.NewResult(5, 1))
+	th.MockDB.ExpectCommit()
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `password_histories` WHERE user_uuid = ? ORDER BY id DESC")).
+		WithArgs("user-uuid", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(4))
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("DELETE FROM `password_histories` WHERE user_uuid = ? AND id NOT IN (?,?)")).
+		WithArgs("user-uuid", 5, 4).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	th.MockDB.ExpectCommit()
+
+	require.NoError(t, repo.RecordPassword(newMFATestContext(), "user-uuid", "hash"))
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+
+	// Without a history_size nothing is stored
+	th.BaseConfig.YamlConfig.Application.Server.PasswordPolicy.HistorySize = 0
+	repo = repository.NewPasswordPolicyRepository(th.BaseConfig, repository.NewCommonRepository(th.BaseConfig, nil))
+	require.NoError(t, repo.RecordPassword(newMFATestContext(), "user-uuid", "hash"))
+}
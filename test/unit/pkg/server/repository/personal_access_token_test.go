@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPersonalAccessToken = repository.PersonalAccessTokenPrefix + "0123456789"
+
+func expectPersonalAccessToken(th *TestHelper, expiresAt time.Time) {
+	sum := sha256.Sum256([]byte(testPersonalAccessToken))
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `personal_access_tokens` WHERE token_hash = ? AND deleted_at IS NULL")).
+		WithArgs(hex.EncodeToString(sum[:]), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "user_uuid", "scopes", "expires_at"}).
+			AddRow(1, "pat-uuid", "user-uuid", "users:read", expiresAt))
+}
+
+func TestPersonalAccessToken_Validate(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	common := repository.NewCommonRepository(th.BaseConfig, nil)
+
+	expectPersonalAccessToken(th, time.Now().Add(time.Hour))
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE uuid = ? AND deleted_at IS NULL")).
+		WithArgs("user-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email", "name"}).AddRow(5, "user-uuid", "admin@test.com", "Admin"))
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("UPDATE `personal_access_tokens` SET `last_used_at`=.*WHERE id = ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+
+	claims, err := common.ValidatePersonalAccessToken(context.Background(), testPersonalAccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, model.TokenUsePAT, claims.TokenUse)
+	assert.Equal(t, "user-uuid", claims.UUID)
+	assert.Equal(t, uint(5), claims.UserID)
+	assert.Equal(t, "admin", claims.Role) // resolved from admin.emails at use time
+	assert.Equal(t, "users:read", claims.Scope)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestPersonalAccessToken_Expired(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	common := repository.NewCommonRepository(th.BaseConfig, nil)
+
+	expectPersonalAccessToken(th, time.Now().Add(-time.Minute))
+
+	_, err := common.ValidatePersonalAccessToken(context.Background(), testPersonalAccessToken)
+	assert.ErrorIs(t, err, repository.ErrPersonalAccessTokenInvalid)
+}
+
+func TestIsPersonalAccessToken(t *testing.T) {
+	assert.True(t, repository.IsPersonalAccessToken(testPersonalAccessToken))
+	assert.False(t, repository.IsPersonalAccessToken("eyJhbGciOiJFUzI1NiJ9.e30.sig"))
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWatchedNode starts the policy watcher of one server process sharing mr
+func newWatchedNode(t *testing.T, ctx context.Context, mr *miniredis.Miniredis) (repository.PolicyWatcherRepository, *casbin.SyncedEnforcer) {
+	t.Helper()
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.Casbin = config.Casbin{Storage: repository.CasbinStorageDatabase}
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	enforcer, err := casbin.NewSyncedEnforcer("../../../testdata/casbin/model.conf", "../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+	watcher := repository.NewPolicyWatcherRepository(conf, client)
+	require.NoError(t, watcher.Watch(ctx, enforcer, model.PolicySetApp))
+	return watcher, enforcer
+}
+
+func policyRevision(w repository.PolicyWatcherRepository) int64 {
+	return w.GetPolicyStatus()[0].Revision
+}
+
+func TestPolicyWatcher_PropagatesChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	w1, e1 := newWatchedNode(t, ctx, mr)
+	w2, e2 := newWatchedNode(t, ctx, mr)
+	assert.NotEqual(t, w1.NodeID(), w2.NodeID())
+
+	_, err := e1.AddPolicy("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return policyRevision(w2) == 1 }, 2*time.Second, 10*time.Millisecond)
+	ok, err := e2.Enforce("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = e1.RemoveFilteredPolicy(0, "auditor")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return policyRevision(w2) == 2 }, 2*time.Second, 10*time.Millisecond)
+	ok, err = e2.Enforce("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	s1, s2 := w1.GetPolicyStatus()[0], w2.GetPolicyStatus()[0]
+	assert.Equal(t, repository.PolicyWatcherRedis, s2.Watcher)
+	assert.Equal(t, s1.Revision, s2.Revision)
+	assert.Equal(t, s1.Digest, s2.Digest)
+}
+
+func TestPolicyWatcher_ReloadsAfterMissedChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	_, e1 := newWatchedNode(t, ctx, mr)
+	w2, e2 := newWatchedNode(t, ctx, mr)
+
+	// A line only in memory disappears once node 2 notices it missed revision 1
+	_, err := e2.GetModel().AddPoliciesWithAffected("p", "p", [][]string{{"stale", "users", "read"}})
+	require.NoError(t, err)
+	_, err = mr.Incr("locky:casbin:revision:"+model.PolicySetApp, 1)
+	require.NoError(t, err)
+
+	_, err = e1.AddPolicy("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return policyRevision(w2) == 2 }, 2*time.Second, 10*time.Millisecond)
+	ok, err := e2.Enforce("stale", "users", "read")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// Run with -race: both nodes keep enforcing while changes are made locally,
+// applied from the other node and reloaded after a missed revision.
+func TestPolicyWatcher_EnforcesWhileChangesApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	w1, e1 := newWatchedNode(t, ctx, mr)
+	w2, e2 := newWatchedNode(t, ctx, mr)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, e := range []*casbin.SyncedEnforcer{e1, e2} {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(e *casbin.SyncedEnforcer) {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					case <-time.After(100 * time.Microsecond):
+					}
+					_, err := e.Enforce("auditor", "lockouts", "read")
+					assert.NoError(t, err)
+					_, err = e.GetImplicitPermissionsForUser("auditor")
+					assert.NoError(t, err)
+				}
+			}(e)
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				w1.GetPolicyStatus()
+				w2.GetPolicyStatus()
+			}
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		role := fmt.Sprintf("role-%d", i)
+		_, err := e1.AddPolicy(role, "lockouts", "read")
+		require.NoError(t, err)
+		_, err = e1.AddGroupingPolicy("auditor", role)
+		require.NoError(t, err)
+		if i%4 == 0 {
+			// Another process changed the policy without node 1 noticing: node 1 reloads
+			_, err = mr.Incr("locky:casbin:revision:"+model.PolicySetApp, 1)
+			require.NoError(t, err)
+		}
+		_, err = e1.RemoveGroupingPolicy("auditor", role)
+		require.NoError(t, err)
+		_, err = e1.RemovePolicies([][]string{{role, "lockouts", "read"}})
+		require.NoError(t, err)
+	}
+	_, err := e1.AddPolicy("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	revision := policyRevision(w1)
+	assert.Eventually(t, func() bool { return policyRevision(w2) == revision }, 5*time.Second, 10*time.Millisecond)
+	close(done)
+	wg.Wait()
+
+	ok, err := e2.Enforce("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, w1.GetPolicyStatus()[0].Digest, w2.GetPolicyStatus()[0].Digest)
+}
+
+func TestPolicyWatcher_ReloadsEditedPolicyFile(t *testing.T) {
+	policy, err := os.ReadFile("../../../testdata/casbin/policy.csv")
+	require.NoError(t, err)
+	modelConf, err := filepath.Abs("../../../testdata/casbin/model.conf")
+	require.NoError(t, err)
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("etc/casbin/locky", 0o755))
+	require.NoError(t, os.WriteFile("etc/casbin/locky/policy.csv", policy, 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.Casbin = config.Casbin{WatchIntervalSeconds: 1}
+	enforcer, err := casbin.NewSyncedEnforcer(modelConf, "etc/casbin/locky/policy.csv")
+	require.NoError(t, err)
+	watcher := repository.NewPolicyWatcherRepository(conf, nil)
+	require.NoError(t, watcher.Watch(ctx, enforcer, model.PolicySetApp))
+	status := watcher.GetPolicyStatus()[0]
+	assert.Equal(t, repository.PolicyWatcherFile, status.Watcher)
+	assert.Equal(t, repository.CasbinStorageFile, status.Storage)
+	assert.EqualValues(t, 1, status.Revision)
+
+	edited := append(policy, []byte("p, auditor, lockouts, read\n")...)
+	require.NoError(t, os.WriteFile("etc/casbin/locky/policy.csv", edited, 0o644))
+	assert.Eventually(t, func() bool { return policyRevision(watcher) == 2 }, 5*time.Second, 50*time.Millisecond)
+	ok, err := enforcer.Enforce("auditor", "lockouts", "read")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, status.Rules+1, watcher.GetPolicyStatus()[0].Rules)
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisRateLimitRepositories(t *testing.T) (repository.RateLimitRepository, *miniredis.Miniredis, repository.RateLimitRepository) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	newClient := func() *redis.Client {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	conf := CreateTestConfig()
+	return repository.NewRateLimitRepository(conf, newClient()), mr, repository.NewRateLimitRepository(conf, newClient())
+}
+
+func assertRateLimit(t *testing.T, repo repository.RateLimitRepository, key string, limit int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 1; i <= limit; i++ {
+		result, err := repo.Allow(ctx, key, limit, time.Hour)
+		require.NoError(t, err)
+		require.True(t, result.Allowed, "request %d", i)
+		assert.Equal(t, limit, result.Limit)
+		assert.Equal(t, limit-i, result.Remaining)
+		assert.True(t, result.Reset > 0 && result.Reset <= time.Hour)
+	}
+	for i := 0; i < 3; i++ {
+		result, err := repo.Allow(ctx, key, limit, time.Hour)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.True(t, result.RetryAfter >= time.Second && result.RetryAfter <= 2*time.Hour, result.RetryAfter)
+	}
+}
+
+func TestRateLimit_Redis(t *testing.T) {
+	repo, _, _ := newRedisRateLimitRepositories(t)
+	assertRateLimit(t, repo, "public:ip:192.0.2.1", 3)
+
+	// Other keys have their own counters
+	result, err := repo.Allow(context.Background(), "public:ip:192.0.2.2", 3, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimit_RedisIsSharedBetweenInstances(t *testing.T) {
+	first, _, second := newRedisRateLimitRepositories(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := first.Allow(ctx, "internal:user:alice", 4, time.Hour)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	result, err := second.Allow(ctx, "internal:user:alice", 4, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestRateLimit_RejectedRequestsAreNotCounted(t *testing.T) {
+	repo, mr, _ := newRedisRateLimitRepositories(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := repo.Allow(ctx, "public:ip:192.0.2.1", 2, time.Hour)
+		require.NoError(t, err)
+	}
+	counters := mr.Keys()
+	require.Len(t, counters, 1)
+	assert.True(t, strings.HasPrefix(counters[0], "rate_limit:"))
+	value, err := mr.Get(counters[0])
+	require.NoError(t, err)
+	assert.Equal(t, "2", value)
+	assert.True(t, mr.TTL(counters[0]) > time.Hour)
+}
+
+func TestRateLimit_MemoryStore(t *testing.T) {
+	conf := CreateTestConfig()
+	conf.YamlConfig.Application.Server.RateLimit.Store = repository.RateLimitStoreMemory
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	repo := repository.NewRateLimitRepository(conf, client)
+	assertRateLimit(t, repo, "public:ip:192.0.2.1", 3)
+	assert.Empty(t, mr.Keys(), "the memory store must not touch Redis")
+
+	// Without a Redis client the memory store is used as well
+	assertRateLimit(t, repository.NewRateLimitRepository(CreateTestConfig(), nil), "public:ip:192.0.2.1", 2)
+}
+
+func TestRateLimit_SlidingWindow(t *testing.T) {
+	repo := repository.NewRateLimitRepository(CreateTestConfig(), nil)
+	ctx := context.Background()
+	window := 500 * time.Millisecond
+
+	// Align to the start of a window so all requests land in it
+	time.Sleep(window - time.Duration(time.Now().UnixNano()%int64(window)))
+	for i := 0; i < 4; i++ {
+		result, err := repo.Allow(ctx, "k", 4, window)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	result, err := repo.Allow(ctx, "k", 4, window)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Early in the next window the previous requests still count almost fully
+	time.Sleep(window + window/10 - time.Duration(time.Now().UnixNano()%int64(window)))
+	result, err = repo.Allow(ctx, "k", 4, window)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// At 60% of it they weigh 40%: 4*0.4 + 2 <= 4 < 4*0.4 + 3
+	time.Sleep(window / 2)
+	for i := 0; i < 2; i++ {
+		result, err = repo.Allow(ctx, "k", 4, window)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "request %d", i)
+	}
+	result, err = repo.Allow(ctx, "k", 4, window)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
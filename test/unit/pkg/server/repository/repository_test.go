@@ -14,6 +14,9 @@ import (
 func TestNewCommonRepository(t *testing.T) {
 	cfg := config.BaseConfig{
 		YamlConfig: config.YamlConfig{
+			Application: config.Application{
+				Server: config.Server{JWTSecret: "test-secret-key-for-jwt-that-is-long-enough"},
+			},
 			MySQL: config.MySQL{
 				Host: "localhost",
 				User: "test",
@@ -62,6 +65,7 @@ func TestNewUserRepository(t *testing.T) {
 
 func TestHashPassword(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 
 	password := "SecurePassword123!"
@@ -74,6 +78,7 @@ func TestHashPassword(t *testing.T) {
 
 func TestVerifyPassword(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 
 	password := "SecurePassword123!"
@@ -91,6 +96,7 @@ func TestVerifyPassword(t *testing.T) {
 
 func TestValidatePasswordStrength(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 
 	tests := []struct {
@@ -144,6 +150,7 @@ func TestValidatePasswordStrength(t *testing.T) {
 
 func TestGenerateJWTSecret(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 
 	secret, err := repo.GenerateJWTSecret()
@@ -154,6 +161,7 @@ func TestGenerateJWTSecret(t *testing.T) {
 
 func TestValidateJWTSecretStrength(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 
 	tests := []struct {
@@ -258,6 +266,7 @@ func TestParseTokenUnverified(t *testing.T) {
 
 func TestIsTokenInvalidated(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 
 	// Without Redis, this should return false (token is valid)
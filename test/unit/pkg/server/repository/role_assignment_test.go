@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectRoleAssignments = "SELECT * FROM `role_assignments` WHERE user_uuid = ? ORDER BY id"
+
+func TestUserRoles(t *testing.T) {
+	conf := CreateTestConfig()
+	alice := model.Users{UUID: "alice-uuid", Email: "alice@test.com"}
+	assigned := func(roles ...string) []model.RoleAssignments {
+		assignments := []model.RoleAssignments{}
+		for _, role := range roles {
+			assignments = append(assignments, model.RoleAssignments{UserUUID: alice.UUID, Role: role})
+		}
+		return assignments
+	}
+
+	assert.Equal(t, []string{"user"}, repository.UserRoles(conf, alice, nil))
+	assert.Equal(t, []string{"user", "auditor"}, repository.UserRoles(conf, alice, assigned("auditor", "user")))
+	// A granted admin role becomes the primary role
+	assert.Equal(t, []string{"admin", "user", "auditor"}, repository.UserRoles(conf, alice, assigned("auditor", "admin")))
+	assert.Equal(t, []string{"admin"}, repository.UserRoles(conf, model.Users{Email: "admin@test.com"}, assigned("admin")))
+}
+
+func TestCommonRepository_LoadUserRoles(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	common := repository.NewCommonRepository(th.BaseConfig, nil)
+	alice := model.Users{UUID: "alice-uuid", Email: "alice@test.com"}
+
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectRoleAssignments)).
+		WithArgs("alice-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "role"}).AddRow(1, "alice-uuid", "auditor").AddRow(2, "alice-uuid", "admin"))
+	assert.Equal(t, []string{"admin", "user", "auditor"}, common.LoadUserRoles(alice))
+
+	// Without the assignments the user keeps the configured role only
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectRoleAssignments)).
+		WithArgs("alice-uuid").
+		WillReturnError(errors.New("connection refused"))
+	assert.Equal(t, []string{"user"}, common.LoadUserRoles(alice))
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+
+	withoutDB := repository.NewCommonRepository(CreateTestConfig(), nil)
+	assert.Equal(t, []string{"user"}, withoutDB.LoadUserRoles(alice))
+}
+
+func TestRoleAssignmentRepository_GrantAndRevoke(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewRoleAssignmentRepository(th.BaseConfig)
+	c := newUserTestContext()
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("INSERT INTO `role_assignments`").WillReturnResult(sqlmock.NewResult(1, 1))
+	th.MockDB.ExpectCommit()
+	assignment, err := repo.GrantRole(c, model.RoleAssignments{UserUUID: "alice-uuid", Role: "auditor", GrantedBy: "admin-uuid"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, assignment.UUID)
+	assert.NotNil(t, assignment.CreatedAt)
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("INSERT INTO `role_assignments`").
+		WillReturnError(&mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry 'alice-uuid-auditor' for key 'role_assignments.idx_role_assignments_user_role'"})
+	th.MockDB.ExpectRollback()
+	_, err = repo.GrantRole(c, model.RoleAssignments{UserUUID: "alice-uuid", Role: "auditor"})
+	assert.ErrorIs(t, err, repository.ErrRoleAlreadyAssigned)
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("DELETE FROM `role_assignments` WHERE user_uuid = ? AND role = ?")).
+		WithArgs("alice-uuid", "auditor").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+	assert.NoError(t, repo.RevokeRole(c, "alice-uuid", "auditor"))
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("DELETE FROM `role_assignments` WHERE user_uuid = ? AND role = ?")).
+		WithArgs("alice-uuid", "auditor").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	th.MockDB.ExpectCommit()
+	assert.ErrorIs(t, repo.RevokeRole(c, "alice-uuid", "auditor"), repository.ErrRoleAssignmentNotFound)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestGenerateTokenPair_CarriesRoles(t *testing.T) {
+	repo, _ := newRedisCommonRepository(t)
+
+	pair, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "admin", "auditor")
+	require.NoError(t, err)
+	access, err := repo.ValidateJWTToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", access.Role)
+	assert.Equal(t, []string{"admin", "auditor"}, access.Roles)
+
+	// Without a database, rotation keeps the roles of the family
+	rotated, err := repo.RotateRefreshToken(context.Background(), pair.RefreshToken, "")
+	require.NoError(t, err)
+	access, err = repo.ValidateJWTToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "auditor"}, access.AllRoles())
+
+	// Service account tokens and older tokens only carry role
+	assert.Equal(t, []string{"user"}, model.JWTClaims{Role: "user"}.AllRoles())
+}
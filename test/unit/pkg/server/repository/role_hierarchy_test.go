@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newResourceRoleRepository works on a copy of the test resource policy, as
+// role changes rewrite policy.csv
+func newResourceRoleRepository(t *testing.T) (repository.RoleRepository, *casbin.SyncedEnforcer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"model.conf", "policy.csv"} {
+		b, err := os.ReadFile(filepath.Join("../../../../.etc/casbin/resources", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), b, 0o644))
+	}
+	policy := filepath.Join(dir, "policy.csv")
+	enforcer, err := casbin.NewSyncedEnforcer(filepath.Join(dir, "model.conf"), policy)
+	require.NoError(t, err)
+	return repository.NewRoleRepository(nil, enforcer), enforcer, policy
+}
+
+func TestRoleRepository_Hierarchy(t *testing.T) {
+	roles, _, _ := newResourceRoleRepository(t)
+	c := newUserTestContext()
+
+	h, err := roles.GetRoleHierarchy(c, "member")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer"}, h.Parents)
+	assert.Equal(t, []string{"viewer"}, h.Ancestors)
+	assert.Equal(t, []string{"maintainer"}, h.Descendants)
+
+	h, err = roles.GetRoleHierarchy(c, "maintainer")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"member"}, h.Parents)
+	assert.Equal(t, []string{"member", "viewer"}, h.Ancestors)
+	assert.Empty(t, h.Descendants)
+
+	assert.ErrorIs(t, roles.AddRoleParent(c, "viewer", "maintainer"), repository.ErrRoleInheritanceCycle)
+	assert.ErrorIs(t, roles.AddRoleParent(c, "member", "member"), repository.ErrRoleInheritanceCycle)
+	assert.ErrorIs(t, roles.AddRoleParent(c, "maintainer", "member"), repository.ErrRoleParentExists)
+	assert.ErrorIs(t, roles.AddRoleParent(c, "ghost", "member"), repository.ErrRoleNotFound)
+	assert.ErrorIs(t, roles.RemoveRoleParent(c, "owner", "member"), repository.ErrRoleParentNotFound)
+}
+
+func TestRoleRepository_InheritedPermissions(t *testing.T) {
+	roles, enforcer, policy := newResourceRoleRepository(t)
+	c := newUserTestContext()
+
+	require.NoError(t, roles.CreateRole(c, "auditor", []repository.RolePermission{{Resource: "secret", Action: "read"}}))
+	require.NoError(t, roles.AddRoleParent(c, "auditor", "viewer"))
+	saved, err := os.ReadFile(policy)
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), "g, auditor, viewer")
+
+	// Enforced through the g line
+	ok, err := enforcer.Enforce("auditor", "group_info", "read")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	perms, err := roles.GetRolePermissions(c, "auditor")
+	require.NoError(t, err)
+	assert.Equal(t, []repository.RolePermission{{Resource: "secret", Action: "read"}}, perms)
+	effective, err := roles.GetEffectiveRolePermissions(c, "auditor")
+	require.NoError(t, err)
+	assert.Equal(t, []repository.EffectiveRolePermission{
+		{Resource: "secret", Action: "read", Role: "auditor"},
+		{Resource: "group_info", Action: "read", Role: "viewer"},
+	}, effective)
+
+	require.NoError(t, roles.RemoveRoleParent(c, "auditor", "viewer"))
+	ok, err = enforcer.Enforce("auditor", "group_info", "read")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Deleting a role also drops its inheritance lines
+	require.NoError(t, roles.DeleteRole(c, "member"))
+	h, err := roles.GetRoleHierarchy(c, "viewer")
+	require.NoError(t, err)
+	assert.Empty(t, h.Descendants)
+	h, err = roles.GetRoleHierarchy(c, "maintainer")
+	require.NoError(t, err)
+	assert.Empty(t, h.Parents)
+}
+
+func TestRoleRepository_ConcurrentParentsCannotFormCycle(t *testing.T) {
+	roles, _, _ := newResourceRoleRepository(t)
+
+	for round := 0; round < 100; round++ {
+		start := make(chan struct{})
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		for i, edge := range [][2]string{{"owner", "viewer"}, {"viewer", "owner"}} {
+			wg.Add(1)
+			go func(i int, role, parent string) {
+				defer wg.Done()
+				<-start
+				errs[i] = roles.AddRoleParent(newUserTestContext(), role, parent)
+			}(i, edge[0], edge[1])
+		}
+		close(start)
+		wg.Wait()
+
+		// One of them wins, the other one sees its line and is refused
+		if errs[0] == nil {
+			assert.ErrorIs(t, errs[1], repository.ErrRoleInheritanceCycle)
+			require.NoError(t, roles.RemoveRoleParent(newUserTestContext(), "owner", "viewer"))
+		} else {
+			assert.ErrorIs(t, errs[0], repository.ErrRoleInheritanceCycle)
+			require.NoError(t, errs[1])
+			require.NoError(t, roles.RemoveRoleParent(newUserTestContext(), "viewer", "owner"))
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSCIM_AuthenticateToken(t *testing.T) {
+	sum := sha256.Sum256([]byte("okta-token"))
+	conf := config.BaseConfig{}
+	conf.YamlConfig.Application.Server.SCIM = config.SCIM{
+		Enabled: true,
+		Tokens: []config.SCIMToken{
+			{Name: "broken", TokenSHA256: "not-hex"},
+			{Name: "okta", TokenSHA256: " " + hex.EncodeToString(sum[:]) + "\n"},
+		},
+	}
+	repo := repository.NewSCIMRepository(conf)
+
+	client, err := repo.AuthenticateToken("okta-token")
+	require.NoError(t, err)
+	assert.Equal(t, "okta", client.Name)
+
+	for _, token := range []string{"", "other-token", hex.EncodeToString(sum[:])} {
+		_, err := repo.AuthenticateToken(token)
+		assert.ErrorIs(t, err, repository.ErrSCIMInvalidToken)
+	}
+}
+
+func TestSCIM_ConfigDefaults(t *testing.T) {
+	conf := config.BaseConfig{}
+	scim := repository.NewSCIMRepository(conf).GetConfig()
+	assert.Equal(t, repository.DefaultSCIMMemberRole, scim.MemberRole)
+	assert.Equal(t, repository.DefaultSCIMMaxFilterScan, scim.MaxFilterScan)
+
+	conf.YamlConfig.Application.Server.SCIM = config.SCIM{MemberRole: "viewer", MaxFilterScan: 500}
+	scim = repository.NewSCIMRepository(conf).GetConfig()
+	assert.Equal(t, "viewer", scim.MemberRole)
+	assert.Equal(t, 500, scim.MaxFilterScan)
+}
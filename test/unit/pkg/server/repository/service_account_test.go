@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func serviceAccountTestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+func expectCredential(th *TestHelper, secret string, expiresAt *time.Time) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	rows := sqlmock.NewRows([]string{"id", "service_account_uuid", "client_id", "secret_hash", "expires_at"}).
+		AddRow(1, "sa-uuid", "sa-0123", string(hash), expiresAt)
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `service_account_credentials` WHERE client_id = ?")).
+		WithArgs("sa-0123", 1).
+		WillReturnRows(rows)
+}
+
+func TestServiceAccountRepository_Authenticate(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewServiceAccountRepository(th.BaseConfig)
+
+	expectCredential(th, "s3cret", nil)
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `service_accounts` WHERE uuid = ? AND deleted_at IS NULL")).
+		WithArgs("sa-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "role"}).AddRow(3, "sa-uuid", "nightly-report", "user"))
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec(regexp.QuoteMeta("UPDATE `service_account_credentials` SET `last_used_at`=? WHERE id = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	th.MockDB.ExpectCommit()
+
+	sa, err := repo.Authenticate(serviceAccountTestContext(), "sa-0123", "s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, "sa-uuid", sa.UUID)
+	assert.Equal(t, "user", sa.Role)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestServiceAccountRepository_Authenticate_WrongSecret(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewServiceAccountRepository(th.BaseConfig)
+
+	expectCredential(th, "s3cret", nil)
+
+	_, err := repo.Authenticate(serviceAccountTestContext(), "sa-0123", "wrong")
+	assert.ErrorIs(t, err, repository.ErrServiceAccountInvalidClient)
+}
+
+func TestServiceAccountRepository_Authenticate_ExpiredAfterRotation(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewServiceAccountRepository(th.BaseConfig)
+
+	expired := time.Now().Add(-time.Minute)
+	expectCredential(th, "s3cret", &expired)
+
+	_, err := repo.Authenticate(serviceAccountTestContext(), "sa-0123", "s3cret")
+	assert.ErrorIs(t, err, repository.ErrServiceAccountInvalidClient)
+}
+
+func TestServiceAccountRepository_Authenticate_MissingCredentials(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewServiceAccountRepository(th.BaseConfig)
+
+	_, err := repo.Authenticate(serviceAccountTestContext(), "", "")
+	assert.ErrorIs(t, err, repository.ErrServiceAccountInvalidClient)
+}
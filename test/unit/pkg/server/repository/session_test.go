@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisSessionRepository(t *testing.T) (repository.CommonRepository, repository.SessionRepository) {
+	t.Helper()
+	common, mr := newRedisCommonRepository(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return common, repository.NewSessionRepository(common, client)
+}
+
+func TestSessionRepository_ListAndTouch(t *testing.T) {
+	ctx := context.Background()
+	common, sessions := newRedisSessionRepository(t)
+
+	pair, err := common.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+	claims, err := common.ValidateJWTToken(pair.AccessToken)
+	require.NoError(t, err)
+	_, err = common.GenerateTokenPair(2, "other-uuid", "other@example.com", "Other", "user")
+	require.NoError(t, err)
+
+	require.NoError(t, common.TouchSession(ctx, claims.FamilyID, "curl/8.0", "10.0.0.1"))
+
+	list, err := sessions.ListSessions(ctx, "user-uuid")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, claims.FamilyID, list[0].ID)
+	assert.Equal(t, claims.Jti, list[0].AccessJti)
+	assert.Equal(t, "curl/8.0", list[0].UserAgent)
+	assert.Equal(t, "10.0.0.1", list[0].IPAddress)
+	assert.NotZero(t, list[0].IssuedAt)
+	assert.NotZero(t, list[0].LastSeenAt)
+
+	// Rotation stays in the same session
+	_, err = common.RotateRefreshToken(ctx, pair.RefreshToken, "")
+	require.NoError(t, err)
+	list, err = sessions.ListSessions(ctx, "user-uuid")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestSessionRepository_RevokeSession(t *testing.T) {
+	ctx := context.Background()
+	common, sessions := newRedisSessionRepository(t)
+
+	pair, err := common.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+	claims, err := common.ValidateJWTToken(pair.AccessToken)
+	require.NoError(t, err)
+
+	// Another user's session id is not found
+	assert.ErrorIs(t, sessions.RevokeSession(ctx, "other-uuid", claims.FamilyID), repository.ErrSessionNotFound)
+
+	require.NoError(t, sessions.RevokeSession(ctx, "user-uuid", claims.FamilyID))
+
+	invalidated, err := common.IsTokenInvalidated(ctx, claims.Jti)
+	require.NoError(t, err)
+	assert.True(t, invalidated)
+	_, err = common.RotateRefreshToken(ctx, pair.RefreshToken, "")
+	assert.Error(t, err)
+
+	list, err := sessions.ListSessions(ctx, "user-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestSessionRepository_RevokeAllSessions(t *testing.T) {
+	ctx := context.Background()
+	common, sessions := newRedisSessionRepository(t)
+
+	var familyIDs []string
+	for i := 0; i < 3; i++ {
+		pair, err := common.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+		require.NoError(t, err)
+		claims, err := common.ValidateJWTToken(pair.AccessToken)
+		require.NoError(t, err)
+		familyIDs = append(familyIDs, claims.FamilyID)
+	}
+
+	revoked, err := sessions.RevokeAllSessions(ctx, "user-uuid", familyIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, 2, revoked)
+
+	list, err := sessions.ListSessions(ctx, "user-uuid")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, familyIDs[0], list[0].ID)
+
+	revoked, err = sessions.RevokeAllSessions(ctx, "user-uuid", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+}
+
+func TestSessionRepository_WithoutRedis(t *testing.T) {
+	common := repository.NewCommonRepository(newJWTTestConfig(config.JWT{}), nil)
+	sessions := repository.NewSessionRepository(common, nil)
+
+	list, err := sessions.ListSessions(context.Background(), "user-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	assert.ErrorIs(t, sessions.RevokeSession(context.Background(), "user-uuid", "x"), repository.ErrSessionNotFound)
+}
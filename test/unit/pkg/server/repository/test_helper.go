@@ -30,6 +30,7 @@ func CreateTestConfig() config.BaseConfig {
 			Application: config.Application{
 				Common: config.Common{},
 				Server: config.Server{
+					JWTSecret: "test-secret-key-for-jwt-that-is-long-enough",
 					Admin: config.Admin{
 						Emails: []string{"admin@test.com"},
 					},
@@ -89,7 +90,8 @@ func createMockDB() (*gorm.DB, sqlmock.Sqlmock, *sql.DB) {
 		Conn:                      sqlDB,
 		SkipInitializeWithVersion: false,
 	}), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
 	})
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create GORM database: %v", err))
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisCommonRepository(t *testing.T) (repository.CommonRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return repository.NewCommonRepository(newJWTTestConfig(config.JWT{}), client), mr
+}
+
+func TestGenerateTokenPair_TokenUse(t *testing.T) {
+	repo, _ := newRedisCommonRepository(t)
+
+	pair, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+
+	access, err := repo.ValidateJWTToken(pair.AccessToken)
+	require.NoError(t, err)
+	refresh, err := repo.ValidateJWTToken(pair.RefreshToken)
+	require.NoError(t, err)
+
+	assert.Equal(t, model.TokenUseAccess, access.TokenUse)
+	assert.Equal(t, model.TokenUseRefresh, refresh.TokenUse)
+	assert.NotEmpty(t, access.FamilyID)
+	assert.Equal(t, access.FamilyID, refresh.FamilyID)
+}
+
+func TestRotateRefreshToken_RejectsAccessToken(t *testing.T) {
+	repo, _ := newRedisCommonRepository(t)
+
+	pair, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+
+	_, err = repo.RotateRefreshToken(context.Background(), pair.AccessToken, "")
+	assert.ErrorIs(t, err, repository.ErrInvalidTokenUse)
+}
+
+func TestRotateRefreshToken_SingleUseAndReuseDetection(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newRedisCommonRepository(t)
+
+	first, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+
+	second, err := repo.RotateRefreshToken(ctx, first.RefreshToken, "")
+	require.NoError(t, err)
+	secondClaims, err := repo.ValidateJWTToken(second.AccessToken)
+	require.NoError(t, err)
+	firstClaims, _ := repo.ValidateJWTToken(first.AccessToken)
+	assert.Equal(t, firstClaims.FamilyID, secondClaims.FamilyID)
+
+	// Replaying the consumed refresh token revokes the family
+	_, err = repo.RotateRefreshToken(ctx, first.RefreshToken, "")
+	assert.ErrorIs(t, err, repository.ErrRefreshTokenReused)
+
+	// Every token of the family is now denylisted
+	for _, token := range []string{first.AccessToken, second.AccessToken, second.RefreshToken} {
+		claims, err := repo.ParseTokenUnverified(token)
+		require.NoError(t, err)
+		invalidated, err := repo.IsTokenInvalidated(ctx, claims.Jti)
+		require.NoError(t, err)
+		assert.True(t, invalidated)
+	}
+
+	// The legitimate holder cannot continue the family either
+	_, err = repo.RotateRefreshToken(ctx, second.RefreshToken, "")
+	assert.Error(t, err)
+}
+
+func TestRevokeTokenFamily_KillsPairedRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newRedisCommonRepository(t)
+
+	pair, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+	claims, err := repo.ValidateJWTToken(pair.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.RevokeTokenFamily(ctx, claims.FamilyID))
+
+	_, err = repo.RotateRefreshToken(ctx, pair.RefreshToken, "")
+	assert.Error(t, err)
+}
+
+func TestRotateRefreshToken_WithoutRedis(t *testing.T) {
+	repo := repository.NewCommonRepository(newJWTTestConfig(config.JWT{}), nil)
+
+	pair, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+
+	rotated, err := repo.RotateRefreshToken(context.Background(), pair.RefreshToken, "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, rotated.RefreshToken)
+}
+
+func TestRotateRefreshToken_BoundToClient(t *testing.T) {
+	repo, _ := newRedisCommonRepository(t)
+	ctx := context.Background()
+
+	pair, err := repo.GenerateAuthorizedTokenPair("web", 1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+	access, err := repo.ValidateJWTToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "web", access.AuthorizedParty)
+	assert.Empty(t, access.ClientID)
+
+	// Neither another client nor the first-party endpoint can redeem it,
+	// and the failed attempts do not consume the token
+	_, err = repo.RotateRefreshToken(ctx, pair.RefreshToken, "spa")
+	assert.ErrorIs(t, err, repository.ErrTokenClientMismatch)
+	_, err = repo.RotateRefreshToken(ctx, pair.RefreshToken, "")
+	assert.ErrorIs(t, err, repository.ErrTokenClientMismatch)
+
+	rotated, err := repo.RotateRefreshToken(ctx, pair.RefreshToken, "web")
+	require.NoError(t, err)
+	refresh, err := repo.ValidateJWTToken(rotated.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "web", refresh.AuthorizedParty)
+
+	// Tokens from password login are not any client's to redeem
+	login, err := repo.GenerateTokenPair(1, "user-uuid", "test@example.com", "Test", "user")
+	require.NoError(t, err)
+	_, err = repo.RotateRefreshToken(ctx, login.RefreshToken, "web")
+	assert.ErrorIs(t, err, repository.ErrTokenClientMismatch)
+}
+
+func TestValidateOIDCConfig_RequiresAsymmetricKeyring(t *testing.T) {
+	server := config.Server{OIDC: config.OIDC{Enabled: true}}
+	assert.ErrorIs(t, repository.ValidateOIDCConfig(server), repository.ErrOIDCSymmetricKeyring)
+	server.JWT.Algorithm = "HS256"
+	assert.ErrorIs(t, repository.ValidateOIDCConfig(server), repository.ErrOIDCSymmetricKeyring)
+	server.JWT.Algorithm = "ES256"
+	assert.NoError(t, repository.ValidateOIDCConfig(server))
+	assert.NoError(t, repository.ValidateOIDCConfig(config.Server{}))
+}
+
+func TestRotateRefreshToken_ReloadsUser(t *testing.T) {
+	ctx := context.Background()
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	repo := repository.NewCommonRepository(th.BaseConfig, client)
+	const selectUser = "SELECT * FROM `users` WHERE uuid = ? AND deleted_at IS NULL ORDER BY `users`.`id` LIMIT ?"
+
+	pair, err := repo.GenerateTokenPair(7, "alice-uuid", "alice@test.com", "Alice", "admin")
+	require.NoError(t, err)
+
+	// Roles and profile come from the current records, not the old token
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectUser)).
+		WithArgs("alice-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email", "name"}).AddRow(7, "alice-uuid", "alice@test.com", "Alice Smith"))
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectRoleAssignments)).
+		WithArgs("alice-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "role"}).AddRow(1, "alice-uuid", "auditor"))
+	rotated, err := repo.RotateRefreshToken(ctx, pair.RefreshToken, "")
+	require.NoError(t, err)
+	access, err := repo.ValidateJWTToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user", access.Role)
+	assert.Equal(t, []string{"user", "auditor"}, access.Roles)
+	assert.Equal(t, "Alice Smith", access.Name)
+
+	// A deleted or deactivated user ends the family
+	th.MockDB.ExpectQuery(regexp.QuoteMeta(selectUser)).
+		WithArgs("alice-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid"}))
+	_, err = repo.RotateRefreshToken(ctx, rotated.RefreshToken, "")
+	assert.ErrorIs(t, err, repository.ErrTokenFamilyRevoked)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+
+	invalidated, err := repo.IsTokenInvalidated(ctx, access.Jti)
+	require.NoError(t, err)
+	assert.True(t, invalidated)
+	status, err := client.HGet(ctx, "auth:family:"+access.FamilyID, "status").Result()
+	require.NoError(t, err)
+	assert.Equal(t, repository.TokenFamilyStatusRevoked, status)
+}
@@ -0,0 +1,96 @@
+//go:build mysql
+
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// These run against a real MySQL, by default the one of docker-compose.yaml:
+//
+//	docker-compose up -d mysql
+//	go test -tags mysql -run GetUserByEmail -bench GetUserByEmail ./test/unit/pkg/server/repository/
+//
+// LOCKY_TEST_MYSQL_DSN (without a database name, ending in "/") points them
+// elsewhere. The users are seeded into a separate locky_bench database.
+
+const benchUsers = 100000
+
+func openBenchDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	dsn := os.Getenv("LOCKY_TEST_MYSQL_DSN")
+	if dsn == "" {
+		dsn = "root:root@tcp(127.0.0.1:3306)/"
+	}
+	server, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(tb, err)
+	require.NoError(tb, server.Exec("CREATE DATABASE IF NOT EXISTS locky_bench").Error)
+	db, err := gorm.Open(mysql.Open(dsn+"locky_bench?parseTime=true"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(tb, err)
+	require.NoError(tb, db.AutoMigrate(&model.Users{}))
+	return db
+}
+
+// seedBenchUsers fills the users table up to n users
+func seedBenchUsers(tb testing.TB, db *gorm.DB, n int) {
+	tb.Helper()
+	var count int64
+	require.NoError(tb, db.Model(&model.Users{}).Count(&count).Error)
+	batch := make([]model.Users, 0, 1000)
+	for i := int(count); i < n; i++ {
+		email := "user" + strconv.Itoa(i) + "@example.com"
+		batch = append(batch, model.Users{UUID: fmt.Sprintf("bench-%08d", i), Email: email, EmailNormalized: email, Name: "User " + strconv.Itoa(i)})
+		if len(batch) == cap(batch) || i == n-1 {
+			require.NoError(tb, db.Create(&batch).Error)
+			batch = batch[:0]
+		}
+	}
+}
+
+func TestUserRepository_GetUserByEmailUsesIndex(t *testing.T) {
+	db := openBenchDB(t)
+	seedBenchUsers(t, db, benchUsers)
+
+	var plan []struct {
+		Key  *string
+		Rows int64
+	}
+	require.NoError(t, db.Raw("EXPLAIN SELECT * FROM `users` WHERE email_normalized = ? LIMIT 1", "user99999@example.com").Scan(&plan).Error)
+	require.Len(t, plan, 1)
+	require.NotNil(t, plan[0].Key)
+	assert.Equal(t, "idx_users_email_normalized", *plan[0].Key)
+	assert.Equal(t, int64(1), plan[0].Rows)
+}
+
+// BenchmarkUserRepository_GetUserByEmail looks up the user of a login at
+// growing user counts; the time per lookup should stay the same
+func BenchmarkUserRepository_GetUserByEmail(b *testing.B) {
+	db := openBenchDB(b)
+	conf := config.BaseConfig{DBConnection: db}
+	repo := repository.NewUserRepository(conf)
+	c := newUserTestContext()
+
+	for _, n := range []int{1000, 10000, benchUsers} {
+		seedBenchUsers(b, db, n)
+		email := "USER" + strconv.Itoa(n/2) + "@example.com"
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.GetUserByEmail(c, email); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
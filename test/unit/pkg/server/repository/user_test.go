@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/ryo-arima/locky/pkg/entity/model"
+	"github.com/ryo-arima/locky/pkg/server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUserTestContext() *gin.Context {
+	c := newMFATestContext()
+	c.Set("requestID", "test-request")
+	return c
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "alice@example.com", repository.NormalizeEmail("  Alice@Example.COM\n"))
+	assert.Equal(t, "", repository.NormalizeEmail(" "))
+}
+
+func TestUserRepository_GetUserByEmail(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewUserRepository(th.BaseConfig)
+
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE email_normalized = ? LIMIT ?")).
+		WithArgs("alice@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email", "email_normalized"}).AddRow(7, "alice-uuid", "Alice@Example.com", "alice@example.com"))
+	user, err := repo.GetUserByEmail(newUserTestContext(), " ALICE@example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "alice-uuid", user.UUID)
+
+	th.MockDB.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE email_normalized = ? LIMIT ?")).
+		WithArgs("nobody@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = repo.GetUserByEmail(newUserTestContext(), "nobody@example.com")
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
+
+func TestUserRepository_CreateUserDuplicateEmail(t *testing.T) {
+	th := NewTestHelper()
+	defer th.CleanupDB()
+	repo := repository.NewUserRepository(th.BaseConfig)
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("INSERT INTO `users`").
+		WillReturnError(&mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry 'alice@example.com' for key 'users.idx_users_email_normalized'"})
+	th.MockDB.ExpectRollback()
+	_, err := repo.CreateUser(newUserTestContext(), model.Users{UUID: "other-uuid", Email: "Alice@Example.com"})
+	assert.ErrorIs(t, err, repository.ErrEmailAlreadyExists)
+
+	th.MockDB.ExpectBegin()
+	th.MockDB.ExpectExec("INSERT INTO `users`").
+		WithArgs("new-uuid", "Bob@Example.com", "bob@example.com", "", "", nil, nil, "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	th.MockDB.ExpectCommit()
+	user, err := repo.CreateUser(newUserTestContext(), model.Users{UUID: "new-uuid", Email: "Bob@Example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(8), user.ID)
+	assert.Equal(t, "bob@example.com", user.EmailNormalized)
+	assert.NoError(t, th.MockDB.ExpectationsWereMet())
+}
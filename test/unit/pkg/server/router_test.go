@@ -0,0 +1,63 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/locky/pkg/config"
+	"github.com/ryo-arima/locky/pkg/server"
+	"github.com/ryo-arima/locky/pkg/server/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// credentialRoutes check passwords, second factors, tokens or client secrets
+// and have to share the auth rate limit tier
+var credentialRoutes = []string{
+	"/v1/share/common/auth/tokens",
+	"/v1/share/common/auth/tokens/mfa",
+	"/v1/share/common/auth/tokens/refresh",
+	"/v1/share/common/auth/oauth/token",
+	"/v1/share/common/auth/oauth/introspect",
+	"/v1/share/common/auth/oauth/revoke",
+	"/v1/share/common/oidc/authorize",
+	"/v1/share/common/oidc/token",
+}
+
+func TestInitRouter_CredentialRoutesShareTheAuthRateLimit(t *testing.T) {
+	// InitRouter reads the casbin model and policy from etc/
+	t.Chdir("../../../..")
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+
+	conf := config.BaseConfig{}
+	conf.Logger = middleware.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+	conf.YamlConfig.Redis.Host = mr.Host()
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	conf.YamlConfig.Redis.Port = port
+	conf.YamlConfig.Application.Server.JWTSecret = "unit-test-secret-with-at-least-32-chars"
+	conf.YamlConfig.Application.Server.JWT.Algorithm = "RS256"
+	conf.YamlConfig.Application.Server.OIDC.Enabled = true
+	conf.YamlConfig.Application.Server.RateLimit.Auth = config.RateLimitRule{Requests: 1, WindowSeconds: 60}
+	router := server.InitRouter(conf)
+
+	for i, path := range credentialRoutes {
+		// One client IP per route, the auth tier counts per IP
+		ip := "192.0.2." + strconv.Itoa(i+1)
+		codes := make([]int, 2)
+		for n := range codes {
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			req.RemoteAddr = ip + ":12345"
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes[n] = w.Code
+		}
+		assert.NotEqual(t, http.StatusTooManyRequests, codes[0], path)
+		assert.Equal(t, http.StatusTooManyRequests, codes[1], path)
+	}
+}
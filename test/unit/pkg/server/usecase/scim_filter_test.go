@@ -0,0 +1,112 @@
+package usecase_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ryo-arima/locky/pkg/server/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scimTestUser() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          "alice-uuid",
+		"userName":    "Alice@Example.com",
+		"displayName": "Alice Liddell",
+		"active":      true,
+		"emails":      []interface{}{map[string]interface{}{"value": "alice@example.com", "type": "work", "primary": true}},
+		"groups":      []interface{}{map[string]interface{}{"value": "admins-uuid", "display": "admins"}},
+		"meta":        map[string]interface{}{"resourceType": "User", "lastModified": "2026-03-01T10:00:00Z"},
+	}
+}
+
+func TestParseSCIMFilter_Matches(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "alice@example.com"`, true},
+		{`USERNAME Eq "alice@example.com"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice@example.com"`, true},
+		{`userName ne "alice@example.com"`, false},
+		{`userName sw "alice@"`, true},
+		{`userName ew ".org"`, false},
+		{`displayName co "liddell"`, true},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "home"]`, false},
+		{`emails.value eq "alice@example.com"`, true},
+		{`groups eq "admins-uuid"`, true},
+		{`groups.display eq "admins" and active eq true`, true},
+		{`active eq false or not (displayName sw "Bob")`, true},
+		{`not (active eq true)`, false},
+		{`title pr`, false},
+		{`displayName pr and (userName eq "x" or id eq "alice-uuid")`, true},
+		{`title eq null`, true},
+		{`meta.lastModified gt "2026-01-01T00:00:00Z"`, true},
+		{`meta.lastModified lt "2026-01-01T00:00:00+09:00"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := usecase.ParseSCIMFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.Matches(scimTestUser()))
+		})
+	}
+}
+
+func TestParseSCIMFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		`userName`,
+		`userName eq`,
+		`userName like "a"`,
+		`userName eq "a" and`,
+		`(userName eq "a"`,
+		`emails[type eq "work"`,
+		`userName eq "unterminated`,
+		`userName gt null`,
+		`"userName" eq "a"`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := usecase.ParseSCIMFilter(filter)
+			var scimErr *usecase.SCIMError
+			require.True(t, errors.As(err, &scimErr), "error %v", err)
+			assert.Equal(t, 400, scimErr.Status)
+			assert.Equal(t, "invalidFilter", scimErr.ScimType)
+		})
+	}
+}
+
+func TestParseSCIMFilter_Precedence(t *testing.T) {
+	// and binds tighter than or
+	f, err := usecase.ParseSCIMFilter(`id eq "a" or id eq "b" and id eq "c"`)
+	require.NoError(t, err)
+	assert.Equal(t, usecase.SCIMFilterOr, f.Op)
+	assert.Equal(t, usecase.SCIMFilterAnd, f.Children[1].Op)
+	assert.True(t, f.References("id"))
+	assert.False(t, f.References("groups"))
+}
+
+func TestParseSCIMPath(t *testing.T) {
+	path, err := usecase.ParseSCIMPath(`members[value eq "alice-uuid"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "members", path.Attr)
+	require.NotNil(t, path.Filter)
+	assert.True(t, path.Filter.Matches(map[string]interface{}{"value": "alice-uuid"}))
+
+	path, err = usecase.ParseSCIMPath(`emails[type eq "work"].value`)
+	require.NoError(t, err)
+	assert.Equal(t, "emails", path.Attr)
+	assert.Equal(t, "value", path.SubAttr)
+
+	path, err = usecase.ParseSCIMPath(`name.givenName`)
+	require.NoError(t, err)
+	assert.Equal(t, "name.givenname", path.Attr)
+
+	for _, invalid := range []string{``, `"members"`, `members[value eq "a"`, `members extra`} {
+		_, err := usecase.ParseSCIMPath(invalid)
+		var scimErr *usecase.SCIMError
+		require.True(t, errors.As(err, &scimErr), "path %q", invalid)
+		assert.Equal(t, "invalidPath", scimErr.ScimType)
+	}
+}
@@ -107,6 +107,7 @@ func TestCommonUsecase_VerifyPassword(t *testing.T) {
 
 func TestCommonUsecase_ValidatePasswordStrength(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 	uc := usecase.NewCommonUsecase(repo)
 
@@ -146,6 +147,7 @@ func TestCommonUsecase_ValidatePasswordStrength(t *testing.T) {
 
 func TestCommonUsecase_GenerateJWTSecret(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 	uc := usecase.NewCommonUsecase(repo)
 
@@ -157,6 +159,7 @@ func TestCommonUsecase_GenerateJWTSecret(t *testing.T) {
 
 func TestCommonUsecase_ValidateJWTSecretStrength(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 	uc := usecase.NewCommonUsecase(repo)
 
@@ -259,6 +262,7 @@ func TestCommonUsecase_ParseTokenUnverified(t *testing.T) {
 
 func TestCommonUsecase_IsTokenInvalidated(t *testing.T) {
 	cfg := config.BaseConfig{}
+	cfg.YamlConfig.Application.Server.JWTSecret = "test-secret-key-for-jwt-that-is-long-enough"
 	repo := repository.NewCommonRepository(cfg, nil)
 	uc := usecase.NewCommonUsecase(repo)
 